@@ -0,0 +1,387 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// Content type of ACME requests.
+	acmeContentType = "application/jose+json"
+
+	// Maximum size of an ACME response.
+	maxACMEResponseSize = 1 << 20
+
+	// Challenge type solved by the server itself, over TLS with ALPN.
+	acmeChallengeTLSALPN01 = "tls-alpn-01"
+
+	// Interval between polls of pending authorizations and orders.
+	acmePollInterval = 2 * time.Second
+
+	// Time given to authorizations and orders to become valid.
+	acmePollTimeout = 2 * time.Minute
+)
+
+// ACME statuses of authorizations and orders which are final.
+const (
+	acmeStatusValid   = "valid"
+	acmeStatusInvalid = "invalid"
+)
+
+// Problem type of requests with a stale nonce, retried with a new one.
+const acmeProblemBadNonce = "urn:ietf:params:acme:error:badNonce"
+
+// acmeProblem - error reported by an ACME server.
+type acmeProblem struct {
+	Type       string `json:"type"`
+	Detail     string `json:"detail"`
+	StatusCode int    `json:"-"`
+}
+
+func (p acmeProblem) Error() string {
+	return fmt.Sprintf("ACME server failed with %d %s: %s", p.StatusCode, p.Type, p.Detail)
+}
+
+// acmeDirectory - endpoints of an ACME server.
+type acmeDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// acmeIdentifier - identifier certificates are ordered for.
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// acmeOrder - certificate order.
+type acmeOrder struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+}
+
+// acmeChallenge - challenge proving control of an identifier.
+type acmeChallenge struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Status string `json:"status"`
+}
+
+// acmeAuthorization - authorization of an identifier of an order.
+type acmeAuthorization struct {
+	Status     string          `json:"status"`
+	Identifier acmeIdentifier  `json:"identifier"`
+	Challenges []acmeChallenge `json:"challenges"`
+}
+
+// acmeSolver - solves challenges, presenting the key authorization
+// of a challenge of domain until cleaned up.
+type acmeSolver interface {
+	present(domain, keyAuth string) error
+	cleanup(domain string)
+}
+
+// acmeClient - client of an ACME server, requests are signed with the
+// account key.
+type acmeClient struct {
+	directoryURL string
+	key          *ecdsa.PrivateKey
+	// Account URL, empty until registered.
+	kid        string
+	httpClient *http.Client
+	dir        *acmeDirectory
+	nonce      string
+}
+
+func newACMEClient(directoryURL string, key *ecdsa.PrivateKey, kid string) *acmeClient {
+	return &acmeClient{
+		directoryURL: directoryURL,
+		key:          key,
+		kid:          kid,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// base64url - unpadded base64url encoding of JOSE.
+func base64url(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// jwk - returns JSON web key of the public account key, its members
+// ordered as required for thumbprints.
+func (c *acmeClient) jwk() string {
+	size := (c.key.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	c.key.X.FillBytes(x)
+	c.key.Y.FillBytes(y)
+	return fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, c.key.Curve.Params().Name, base64url(x), base64url(y))
+}
+
+// keyAuthorization - returns key authorization of a challenge token.
+func (c *acmeClient) keyAuthorization(token string) string {
+	thumbprint := sha256.Sum256([]byte(c.jwk()))
+	return token + "." + base64url(thumbprint[:])
+}
+
+// discover - fetches the directory, once.
+func (c *acmeClient) discover() error {
+	if c.dir != nil {
+		return nil
+	}
+	resp, err := c.httpClient.Get(c.directoryURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return acmeProblem{StatusCode: resp.StatusCode, Detail: "Unable to fetch directory"}
+	}
+	dir := &acmeDirectory{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxACMEResponseSize)).Decode(dir); err != nil {
+		return err
+	}
+	c.dir = dir
+	return nil
+}
+
+// fetchNonce - returns a nonce for the next request.
+func (c *acmeClient) fetchNonce() (string, error) {
+	if c.nonce != "" {
+		nonce := c.nonce
+		c.nonce = ""
+		return nonce, nil
+	}
+	resp, err := c.httpClient.Head(c.dir.NewNonce)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("ACME server did not return a nonce")
+	}
+	return nonce, nil
+}
+
+// sign - returns JWS of payload for url, a nil payload is a POST as
+// GET request.
+func (c *acmeClient) sign(url string, payload interface{}) ([]byte, error) {
+	nonce, err := c.fetchNonce()
+	if err != nil {
+		return nil, err
+	}
+	protected := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": nonce,
+		"url":   url,
+	}
+	if c.kid != "" {
+		protected["kid"] = c.kid
+	} else {
+		protected["jwk"] = json.RawMessage(c.jwk())
+	}
+	protectedBytes, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	payloadBytes := []byte{}
+	if payload != nil {
+		if payloadBytes, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	signingInput := base64url(protectedBytes) + "." + base64url(payloadBytes)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	size := (c.key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return json.Marshal(map[string]string{
+		"protected": base64url(protectedBytes),
+		"payload":   base64url(payloadBytes),
+		"signature": base64url(signature),
+	})
+}
+
+// post - sends signed payload to url, retrying once with a new nonce
+// if the server rejected it. Returns the response with its body read.
+func (c *acmeClient) post(url string, payload interface{}) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, err := c.sign(url, payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := c.httpClient.Post(url, acmeContentType, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxACMEResponseSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.nonce = resp.Header.Get("Replay-Nonce")
+		if resp.StatusCode < 400 {
+			return resp, respBody, nil
+		}
+		problem := acmeProblem{StatusCode: resp.StatusCode}
+		json.Unmarshal(respBody, &problem)
+		if problem.Type == acmeProblemBadNonce && attempt == 0 {
+			continue
+		}
+		return nil, nil, problem
+	}
+}
+
+// postJSON - post, decoding the response into v.
+func (c *acmeClient) postJSON(url string, payload interface{}, v interface{}) (*http.Response, error) {
+	resp, body, err := c.post(url, payload)
+	if err != nil {
+		return nil, err
+	}
+	return resp, json.Unmarshal(body, v)
+}
+
+// register - registers the account key with email as contact, or
+// finds the account it is already registered with.
+func (c *acmeClient) register(email string) error {
+	if err := c.discover(); err != nil {
+		return err
+	}
+	payload := map[string]interface{}{"termsOfServiceAgreed": true}
+	if email != "" {
+		payload["contact"] = []string{"mailto:" + email}
+	}
+	resp, _, err := c.post(c.dir.NewAccount, payload)
+	if err != nil {
+		return err
+	}
+	kid := resp.Header.Get("Location")
+	if kid == "" {
+		return errors.New("ACME server did not return the account URL")
+	}
+	c.kid = kid
+	return nil
+}
+
+// obtain - orders a certificate of csr for domain, challenges are
+// solved by solver. Returns the PEM encoded certificate chain.
+func (c *acmeClient) obtain(domain string, csr []byte, solver acmeSolver) ([]byte, error) {
+	if err := c.discover(); err != nil {
+		return nil, err
+	}
+	order := acmeOrder{}
+	resp, err := c.postJSON(c.dir.NewOrder, map[string]interface{}{
+		"identifiers": []acmeIdentifier{{Type: "dns", Value: domain}},
+	}, &order)
+	if err != nil {
+		return nil, err
+	}
+	orderURL := resp.Header.Get("Location")
+	for _, authzURL := range order.Authorizations {
+		if err = c.authorize(authzURL, solver); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = c.postJSON(order.Finalize, map[string]string{"csr": base64url(csr)}, &order); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(acmePollTimeout)
+	for order.Status != acmeStatusValid {
+		if order.Status == acmeStatusInvalid || time.Now().After(deadline) {
+			return nil, fmt.Errorf("ACME order of %s is %s", domain, order.Status)
+		}
+		time.Sleep(acmePollInterval)
+		if _, err = c.postJSON(orderURL, nil, &order); err != nil {
+			return nil, err
+		}
+	}
+	_, chain, err := c.post(order.Certificate, nil)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(chain); block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("ACME server returned an invalid certificate chain")
+	}
+	return chain, nil
+}
+
+// authorize - solves a challenge of the authorization at authzURL,
+// unless it is already valid, and waits for it to become valid.
+func (c *acmeClient) authorize(authzURL string, solver acmeSolver) error {
+	authz := acmeAuthorization{}
+	if _, err := c.postJSON(authzURL, nil, &authz); err != nil {
+		return err
+	}
+	if authz.Status == acmeStatusValid {
+		return nil
+	}
+	var challenge *acmeChallenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == acmeChallengeTLSALPN01 {
+			challenge = &authz.Challenges[i]
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("ACME server offers no %s challenge for %s", acmeChallengeTLSALPN01, authz.Identifier.Value)
+	}
+	domain := authz.Identifier.Value
+	if err := solver.present(domain, c.keyAuthorization(challenge.Token)); err != nil {
+		return err
+	}
+	defer solver.cleanup(domain)
+	// An empty object tells the server the challenge is ready.
+	if _, err := c.postJSON(challenge.URL, struct{}{}, challenge); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(acmePollTimeout)
+	for {
+		if _, err := c.postJSON(authzURL, nil, &authz); err != nil {
+			return err
+		}
+		if authz.Status == acmeStatusValid {
+			return nil
+		}
+		if authz.Status == acmeStatusInvalid || time.Now().After(deadline) {
+			return fmt.Errorf("ACME authorization of %s is %s", domain, authz.Status)
+		}
+		time.Sleep(acmePollInterval)
+	}
+}
@@ -0,0 +1,1602 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	mux "github.com/gorilla/mux"
+	"github.com/klauspost/cpuid"
+	fastSha512 "github.com/minio/minio/pkg/crypto/sha512"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Health status values.
+const (
+	healthStatusOK      = "ok"
+	healthStatusWarning = "warning"
+)
+
+// healthInfo - response of the admin health API.
+type healthInfo struct {
+	Status string       `json:"status"`
+	Alarms []alarmInfo  `json:"alarms"`
+	Disks  []diskHealth `json:"disks"`
+}
+
+// serverInfo - response of the admin server info API.
+type serverInfo struct {
+	Version  string `json:"version"`
+	CommitID string `json:"commitID"`
+	// Uptime in seconds.
+	Uptime   int64        `json:"uptime"`
+	BootTime time.Time    `json:"bootTime"`
+	Disks    []DiskStatus `json:"disks"`
+	Heal     healInfo     `json:"heal"`
+	// Reads served from inlined data and erasure coded parts, of
+	// erasure coded storage only.
+	Reads *xlReadStats `json:"reads,omitempty"`
+	// Code paths selected for the CPU.
+	SIMD simdInfo `json:"simd"`
+}
+
+// simdInfo - instruction sets of erasure coding and of the checksums
+// of erasure coded parts, selected for the CPU at runtime.
+type simdInfo struct {
+	CPU           string `json:"cpu"`
+	ErasureCoding string `json:"erasureCoding"`
+	Checksum      string `json:"checksum"`
+}
+
+// isAdminReqAuthenticated - admin APIs only accept requests signed
+// with the server credentials.
+func isAdminReqAuthenticated(w http.ResponseWriter, r *http.Request) bool {
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return false
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return false
+		}
+		// Users are not admins, only the server credential is.
+		if !isRootAccessKey(getAuditRequester(r)) {
+			writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+			return false
+		}
+	}
+	return true
+}
+
+// writeAdminResponse - writes JSON encoded admin API response.
+func writeAdminResponse(w http.ResponseWriter, r *http.Request, response interface{}) {
+	responseBytes, e := json.Marshal(response)
+	if e != nil {
+		errorIf(probe.NewError(e), "Unable to marshal admin response.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	writeSuccessResponse(w, responseBytes)
+}
+
+// HealthHandler - GET /minio/admin/health
+// ----------
+// Returns overall server health along with all the raised capacity
+// and disk health alarms, and the health of each disk as of the last
+// disk health check.
+func (api adminAPIHandlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	health := healthInfo{
+		Status: healthStatusOK,
+		Alarms: append(globalAlarms.List(), globalDiskAlarms.List()...),
+		Disks:  globalDiskHealth.List(),
+	}
+	if len(health.Alarms) > 0 {
+		health.Status = healthStatusWarning
+	}
+	writeAdminResponse(w, r, health)
+}
+
+// OpenReadSessionHandler - POST /minio/admin/read-sessions?ttl=seconds
+// ----------
+// Opens a read session pinned to the current state of all objects,
+// GET and HEAD Object requests with the session id in the
+// X-Minio-Read-Session header read objects as of that state.
+func (api adminAPIHandlers) OpenReadSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	var ttl time.Duration
+	if ttlStr := r.URL.Query().Get("ttl"); ttlStr != "" {
+		seconds, e := strconv.ParseInt(ttlStr, 10, 64)
+		if e != nil || seconds <= 0 {
+			writeErrorResponse(w, r, ErrInvalidQueryParams, r.URL.Path)
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+	session, err := api.ObjectAPI.OpenReadSession(ttl)
+	if err != nil {
+		errorIf(err.Trace(), "OpenReadSession failed.", nil)
+		switch err.ToGoError().(type) {
+		case TooManyReadSessions:
+			writeErrorResponse(w, r, ErrTooManyReadSessions, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeAdminResponse(w, r, session)
+}
+
+// CloseReadSessionHandler - DELETE /minio/admin/read-sessions/{id}
+// ----------
+// Closes a read session, releasing all data preserved for it.
+func (api adminAPIHandlers) CloseReadSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if err := api.ObjectAPI.CloseReadSession(id); err != nil {
+		errorIf(err.Trace(id), "CloseReadSession failed.", nil)
+		switch err.ToGoError().(type) {
+		case ReadSessionNotFound:
+			writeErrorResponse(w, r, ErrNoSuchReadSession, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// PresignInfoHandler - GET /minio/admin/presign
+// ----------
+// Returns usage of presigned URLs and POST policies per signer and
+// bucket, recently flagged anomalies and revoked signers.
+func (api adminAPIHandlers) PresignInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalPresignMonitor.Info())
+}
+
+// MultipartInfoHandler - GET /minio/admin/multipart
+// ----------
+// Returns limits of in-progress multipart uploads along with uploads in
+// progress and rejected per bucket and access key.
+func (api adminAPIHandlers) MultipartInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, api.ObjectAPI.MultipartSessionsInfo())
+}
+
+// ListIncompleteUploadsHandler - GET /minio/admin/multipart/uploads?bucket=name&prefix=prefix&olderThan=seconds
+// ----------
+// Returns incomplete multipart uploads with prefix of bucket, of all
+// buckets if bucket is not set, initiated at least olderThan seconds
+// ago, along with the state of removals of stale uploads.
+func (api adminAPIHandlers) ListIncompleteUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	prefix := r.URL.Query().Get("prefix")
+	var olderThan time.Duration
+	if olderThanStr := r.URL.Query().Get("olderThan"); olderThanStr != "" {
+		seconds, e := strconv.ParseInt(olderThanStr, 10, 64)
+		if e != nil || seconds < 0 {
+			writeErrorResponse(w, r, ErrInvalidQueryParams, r.URL.Path)
+			return
+		}
+		olderThan = time.Duration(seconds) * time.Second
+	}
+	uploads, truncated, err := api.ObjectAPI.ListIncompleteUploads(bucket, prefix, olderThan)
+	if err != nil {
+		errorIf(err.Trace(bucket, prefix), "ListIncompleteUploads failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeAdminResponse(w, r, incompleteUploadsInfo{
+		Cleanup:   globalMultipartCleaner.Info(),
+		Uploads:   uploads,
+		Truncated: truncated,
+	})
+}
+
+// StartMultipartCleanupHandler - POST /minio/admin/multipart/cleanup
+// ----------
+// Starts removing stale multipart uploads now, unless a removal is
+// running already.
+func (api adminAPIHandlers) StartMultipartCleanupHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	globalMultipartCleaner.Start()
+	writeAdminResponse(w, r, globalMultipartCleaner.Info())
+}
+
+// AttestationKeyInfoHandler - GET /minio/admin/attestation-key
+// ----------
+// Returns the public key verifying object attestations, not found if
+// attestations are disabled.
+func (api adminAPIHandlers) AttestationKeyInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	keyInfo, ok := api.ObjectAPI.AttestationKeyInfo()
+	if !ok {
+		writeErrorResponse(w, r, ErrAttestationDisabled, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, keyInfo)
+}
+
+// RevokePresignSignerHandler - PUT /minio/admin/presign/revocations/{signer}
+// ----------
+// Revokes all presigned URLs and POST policies signed by signer so
+// far, requests signed afterwards are accepted again.
+func (api adminAPIHandlers) RevokePresignSignerHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	signer := mux.Vars(r)["signer"]
+	if err := globalPresignMonitor.Revoke(signer, time.Now().UTC()); err != nil {
+		errorIf(err.Trace(signer), "Unable to revoke presign signer.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// UnrevokePresignSignerHandler - DELETE /minio/admin/presign/revocations/{signer}
+// ----------
+// Removes revocation of signer.
+func (api adminAPIHandlers) UnrevokePresignSignerHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	signer := mux.Vars(r)["signer"]
+	if err := globalPresignMonitor.Unrevoke(signer); err != nil {
+		errorIf(err.Trace(signer), "Unable to unrevoke presign signer.", nil)
+		switch err.ToGoError().(type) {
+		case PresignRevocationNotFound:
+			writeErrorResponse(w, r, ErrNoSuchPresignRevocation, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// DiskSharesHandler - GET /minio/admin/disk-shares
+// ----------
+// Returns operations in progress on each disk, along with the
+// configured share and actual utilization of the disk by each bucket.
+func (api adminAPIHandlers) DiskSharesHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	statuses := []diskSchedulerStatus{}
+	if reporter, ok := api.ObjectAPI.storage.(diskShareReporter); ok {
+		statuses = reporter.DiskShares()
+	}
+	writeAdminResponse(w, r, statuses)
+}
+
+// TopLocksHandler - GET /minio/admin/locks
+// ----------
+// Returns namespace locks currently held, longest held first, along
+// with the operation holding each of them and the number of operations
+// waiting for them.
+func (api adminAPIHandlers) TopLocksHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	locks := []nameSpaceLockInfo{}
+	if reporter, ok := api.ObjectAPI.storage.(lockReporter); ok {
+		locks = reporter.Locks()
+	}
+	writeAdminResponse(w, r, locks)
+}
+
+// ListBandwidthLimitsHandler - GET /minio/admin/bandwidth
+// ----------
+// Returns upload and download bandwidth limits of all buckets and
+// access keys.
+func (api adminAPIHandlers) ListBandwidthLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalBandwidthLimiter.List())
+}
+
+// parseBandwidthRate - parses rate in bytes per second, empty is
+// unlimited.
+func parseBandwidthRate(rate string) (int64, error) {
+	if rate == "" {
+		return 0, nil
+	}
+	bytes, e := humanize.ParseBytes(rate)
+	if e != nil {
+		return 0, e
+	}
+	return int64(bytes), nil
+}
+
+// SetBandwidthLimitHandler - PUT /minio/admin/bandwidth/{target}/{name}?upload=rate&download=rate
+// ----------
+// Limits uploads and downloads of all requests for bucket or by access
+// key name, target is either bucket or access-key. Rates are in bytes
+// per second, unlimited if not set.
+func (api adminAPIHandlers) SetBandwidthLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	limit := bandwidthLimit{Target: vars["target"], Name: vars["name"]}
+	if !isValidBandwidthTarget(limit.Target) {
+		writeErrorResponse(w, r, ErrInvalidBandwidthLimit, r.URL.Path)
+		return
+	}
+	var e error
+	if limit.UploadRate, e = parseBandwidthRate(r.URL.Query().Get("upload")); e != nil {
+		writeErrorResponse(w, r, ErrInvalidBandwidthLimit, r.URL.Path)
+		return
+	}
+	if limit.DownloadRate, e = parseBandwidthRate(r.URL.Query().Get("download")); e != nil {
+		writeErrorResponse(w, r, ErrInvalidBandwidthLimit, r.URL.Path)
+		return
+	}
+	if err := globalBandwidthLimiter.Set(limit); err != nil {
+		errorIf(err.Trace(limit.Target, limit.Name), "Unable to set bandwidth limit.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, limit)
+}
+
+// RemoveBandwidthLimitHandler - DELETE /minio/admin/bandwidth/{target}/{name}
+// ----------
+// Removes bandwidth limit of bucket or access key name.
+func (api adminAPIHandlers) RemoveBandwidthLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	target, name := vars["target"], vars["name"]
+	if err := globalBandwidthLimiter.Remove(target, name); err != nil {
+		errorIf(err.Trace(target, name), "Unable to remove bandwidth limit.", nil)
+		switch err.ToGoError().(type) {
+		case BandwidthLimitNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBandwidthLimit, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// ListBucketQuotasHandler - GET /minio/admin/quotas
+// ----------
+// Returns quotas of all buckets, along with the usage they are
+// enforced against.
+func (api adminAPIHandlers) ListBucketQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalBucketQuotas.List())
+}
+
+// SetBucketQuotaHandler - PUT /minio/admin/quotas/{bucket}?quota=size
+// ----------
+// Limits size of all objects of bucket to quota bytes, writes which
+// would exceed it are refused. Usage of all buckets is crawled again
+// so that the quota is enforced against current usage.
+func (api adminAPIHandlers) SetBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	quota := bucketQuota{Bucket: mux.Vars(r)["bucket"]}
+	size, e := humanize.ParseBytes(r.URL.Query().Get("quota"))
+	if e != nil || size == 0 || size > math.MaxInt64 {
+		writeErrorResponse(w, r, ErrInvalidBucketQuota, r.URL.Path)
+		return
+	}
+	quota.Quota = int64(size)
+	if _, err := api.ObjectAPI.GetBucketInfo(quota.Bucket); err != nil {
+		errorIf(err.Trace(quota.Bucket), "Unable to set bucket quota.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound, BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketQuota, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	if err := globalBucketQuotas.Set(quota); err != nil {
+		errorIf(err.Trace(quota.Bucket), "Unable to set bucket quota.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	globalDataUsage.Start()
+	writeAdminResponse(w, r, quota)
+}
+
+// RemoveBucketQuotaHandler - DELETE /minio/admin/quotas/{bucket}
+// ----------
+// Removes quota of bucket.
+func (api adminAPIHandlers) RemoveBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket := mux.Vars(r)["bucket"]
+	if err := globalBucketQuotas.Remove(bucket); err != nil {
+		errorIf(err.Trace(bucket), "Unable to remove bucket quota.", nil)
+		switch err.ToGoError().(type) {
+		case BucketQuotaNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucketQuota, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// ListUsersHandler - GET /minio/admin/users
+// ----------
+// Returns access keys of all users and the policies attached to them.
+func (api adminAPIHandlers) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalIAMUsers.ListUsers())
+}
+
+// writeIAMErrorResponse - writes error response for user and policy
+// errors.
+func writeIAMErrorResponse(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case UserNotFound:
+		writeErrorResponse(w, r, ErrNoSuchUser, r.URL.Path)
+	case PolicyNotFound:
+		writeErrorResponse(w, r, ErrNoSuchIAMPolicy, r.URL.Path)
+	case PolicyInUse:
+		writeErrorResponse(w, r, ErrIAMPolicyInUse, r.URL.Path)
+	case ServiceAccountNotFound:
+		writeErrorResponse(w, r, ErrNoSuchServiceAccount, r.URL.Path)
+	case TooManyServiceAccounts:
+		writeErrorResponse(w, r, ErrTooManyServiceAccounts, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// SetUserHandler - PUT /minio/admin/users/{accessKey}
+// ----------
+// Creates user with access key, or replaces its secret key and policy
+// if it exists. The request body is a JSON object with the secretKey
+// and the optional policy of the user.
+func (api adminAPIHandlers) SetUserHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	user := iamUser{}
+	if e := json.NewDecoder(io.LimitReader(r.Body, maxAccessPolicySize)).Decode(&user); e != nil {
+		writeErrorResponse(w, r, ErrInvalidUser, r.URL.Path)
+		return
+	}
+	user.AccessKey = mux.Vars(r)["accessKey"]
+	if !isValidAccessKey.MatchString(user.AccessKey) || !isValidSecretKey.MatchString(user.SecretKey) ||
+		isRootAccessKey(user.AccessKey) || globalIAMUsers.IsServiceAccount(user.AccessKey) {
+		writeErrorResponse(w, r, ErrInvalidUser, r.URL.Path)
+		return
+	}
+	if err := globalIAMUsers.SetUser(user); err != nil {
+		errorIf(err.Trace(user.AccessKey), "Unable to set user.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	user.SecretKey = ""
+	writeAdminResponse(w, r, user)
+}
+
+// RemoveUserHandler - DELETE /minio/admin/users/{accessKey}
+// ----------
+// Removes user with access key, its requests are rejected from now on.
+func (api adminAPIHandlers) RemoveUserHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	accessKey := mux.Vars(r)["accessKey"]
+	if err := globalIAMUsers.RemoveUser(accessKey); err != nil {
+		errorIf(err.Trace(accessKey), "Unable to remove user.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// AttachUserPolicyHandler - PUT /minio/admin/users/{accessKey}/policy/{policy}
+// ----------
+// Attaches canned or custom policy to user with access key, replacing
+// its previous policy.
+func (api adminAPIHandlers) AttachUserPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	accessKey, policy := vars["accessKey"], vars["policy"]
+	if err := globalIAMUsers.AttachPolicy(accessKey, policy); err != nil {
+		errorIf(err.Trace(accessKey, policy), "Unable to attach policy.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// isServiceAccountReqAuthenticated - service account APIs accept
+// requests signed by users and the server credential, returns the
+// access key of the signer.
+func isServiceAccountReqAuthenticated(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authType := getRequestAuthType(r)
+	if authType != authTypePresigned && authType != authTypeSigned {
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return "", false
+	}
+	if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return "", false
+	}
+	// Service accounts and temporary credentials cannot manage
+	// service accounts.
+	accessKey := getAuditRequester(r)
+	if globalIAMUsers.IsServiceAccount(accessKey) || globalIAMUsers.IsTempCredential(accessKey) {
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return "", false
+	}
+	// Service accounts of the server credential outlive its rotations.
+	if isRootAccessKey(accessKey) {
+		accessKey = serverConfig.GetCredential().AccessKeyID
+	}
+	return accessKey, true
+}
+
+// serviceAccountRequest - request body of the add service account API.
+type serviceAccountRequest struct {
+	Policy string `json:"policy"`
+}
+
+// AddServiceAccountHandler - POST /minio/admin/service-accounts
+// ----------
+// Creates a service account of the signer of the request. The
+// optional request body is a JSON object with the policy restricting
+// the service account further than its parent. The response has the
+// secret key of the service account, which is not returned again.
+func (api adminAPIHandlers) AddServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	parent, ok := isServiceAccountReqAuthenticated(w, r)
+	if !ok {
+		return
+	}
+	request := serviceAccountRequest{}
+	if e := json.NewDecoder(io.LimitReader(r.Body, maxAccessPolicySize)).Decode(&request); e != nil && e != io.EOF {
+		writeErrorResponse(w, r, ErrInvalidRequestBody, r.URL.Path)
+		return
+	}
+	account, err := globalIAMUsers.AddServiceAccount(parent, request.Policy)
+	if err != nil {
+		errorIf(err.Trace(parent), "Unable to add service account.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeAdminResponse(w, r, account)
+}
+
+// ListServiceAccountsHandler - GET /minio/admin/service-accounts
+// ----------
+// Returns service accounts of the signer of the request, of all users
+// if signed by the server credential.
+func (api adminAPIHandlers) ListServiceAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	parent, ok := isServiceAccountReqAuthenticated(w, r)
+	if !ok {
+		return
+	}
+	if isRootAccessKey(parent) {
+		parent = ""
+	}
+	writeAdminResponse(w, r, globalIAMUsers.ListServiceAccounts(parent))
+}
+
+// RemoveServiceAccountHandler - DELETE /minio/admin/service-accounts/{accessKey}
+// ----------
+// Removes a service account of the signer of the request, of any user
+// if signed by the server credential. Requests signed with it are
+// rejected from now on.
+func (api adminAPIHandlers) RemoveServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	parent, ok := isServiceAccountReqAuthenticated(w, r)
+	if !ok {
+		return
+	}
+	if isRootAccessKey(parent) {
+		parent = ""
+	}
+	accessKey := mux.Vars(r)["accessKey"]
+	if err := globalIAMUsers.RemoveServiceAccount(parent, accessKey); err != nil {
+		errorIf(err.Trace(accessKey), "Unable to remove service account.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// credentialInfo - server credential, without secret keys, and the
+// previous one while it is accepted.
+type credentialInfo struct {
+	AccessKey          string     `json:"accessKey"`
+	PreviousAccessKey  string     `json:"previousAccessKey,omitempty"`
+	PreviousExpiration *time.Time `json:"previousExpiration,omitempty"`
+}
+
+// getCredentialInfo - returns current credentialInfo.
+func getCredentialInfo() credentialInfo {
+	info := credentialInfo{AccessKey: serverConfig.GetCredential().AccessKeyID}
+	if prev, ok := serverConfig.GetPreviousCredential(); ok {
+		info.PreviousAccessKey = prev.AccessKeyID
+		info.PreviousExpiration = &prev.Expiration
+	}
+	return info
+}
+
+// CredentialInfoHandler - GET /minio/admin/credential
+// ----------
+// Returns access keys of the server credential and of the previous one
+// while it is accepted.
+func (api adminAPIHandlers) CredentialInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, getCredentialInfo())
+}
+
+// rotateCredentialRequest - request body of the rotate credential API.
+type rotateCredentialRequest struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	// Seconds the current credential is accepted for, the default
+	// grace period if zero.
+	GracePeriod int64 `json:"gracePeriod"`
+}
+
+// rotateCredentialResponse - new server credential.
+type rotateCredentialResponse struct {
+	credentialInfo
+	SecretKey string `json:"secretKey"`
+}
+
+// RotateCredentialHandler - POST /minio/admin/credential/rotate
+// ----------
+// Replaces the server credential, requests signed with the current one
+// are accepted for a grace period so clients can move to the new one
+// without a flag day. The optional request body is a JSON object with
+// the new access key and secret key, generated if empty, and the grace
+// period in seconds. The response has the new secret key.
+func (api adminAPIHandlers) RotateCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	request := rotateCredentialRequest{}
+	if e := json.NewDecoder(io.LimitReader(r.Body, maxAccessPolicySize)).Decode(&request); e != nil && e != io.EOF {
+		writeErrorResponse(w, r, ErrInvalidRequestBody, r.URL.Path)
+		return
+	}
+	cred := credential{AccessKeyID: request.AccessKey, SecretAccessKey: request.SecretKey}
+	if cred.AccessKeyID == "" || cred.SecretAccessKey == "" {
+		generated, err := genAccessKeys()
+		if err != nil {
+			errorIf(err.Trace(), "Unable to generate access keys.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			return
+		}
+		if cred.AccessKeyID == "" {
+			cred.AccessKeyID = generated.AccessKeyID
+		}
+		if cred.SecretAccessKey == "" {
+			cred.SecretAccessKey = generated.SecretAccessKey
+		}
+	}
+	gracePeriod := defaultCredentialGracePeriod
+	if request.GracePeriod != 0 {
+		gracePeriod = time.Duration(request.GracePeriod) * time.Second
+	}
+	if err := rotateCredential(cred, gracePeriod); err != nil {
+		if err.ToGoError() == errInvalidCredentialRotation {
+			writeErrorResponse(w, r, ErrInvalidCredentialRotation, r.URL.Path)
+			return
+		}
+		errorIf(err.Trace(cred.AccessKeyID), "Unable to rotate server credential.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, rotateCredentialResponse{
+		credentialInfo: getCredentialInfo(),
+		SecretKey:      cred.SecretAccessKey,
+	})
+}
+
+// RemovePreviousCredentialHandler - DELETE /minio/admin/credential/previous
+// ----------
+// Ends the grace period of the previous server credential, requests
+// signed with it are rejected from now on.
+func (api adminAPIHandlers) RemovePreviousCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	if err := removePreviousCredential(); err != nil {
+		errorIf(err.Trace(), "Unable to remove previous server credential.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// ListPoliciesHandler - GET /minio/admin/policies
+// ----------
+// Returns canned and custom policies by name.
+func (api adminAPIHandlers) ListPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalIAMUsers.ListPolicies())
+}
+
+// SetPolicyHandler - PUT /minio/admin/policies/{policy}
+// ----------
+// Creates custom policy with name, or replaces it if it exists. The
+// request body is the JSON policy, users it is attached to are
+// restricted by the new policy from now on.
+func (api adminAPIHandlers) SetPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	name := mux.Vars(r)["policy"]
+	policy := iamPolicy{}
+	if e := json.NewDecoder(io.LimitReader(r.Body, maxAccessPolicySize)).Decode(&policy); e != nil {
+		writeErrorResponse(w, r, ErrInvalidIAMPolicy, r.URL.Path)
+		return
+	}
+	if isCannedPolicy(name) || isValidIAMPolicy(policy) != nil {
+		writeErrorResponse(w, r, ErrInvalidIAMPolicy, r.URL.Path)
+		return
+	}
+	if err := globalIAMUsers.SetPolicy(name, policy); err != nil {
+		errorIf(err.Trace(name), "Unable to set policy.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeAdminResponse(w, r, policy)
+}
+
+// RemovePolicyHandler - DELETE /minio/admin/policies/{policy}
+// ----------
+// Removes custom policy with name, which is not attached to any user.
+func (api adminAPIHandlers) RemovePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	name := mux.Vars(r)["policy"]
+	if err := globalIAMUsers.RemovePolicy(name); err != nil {
+		errorIf(err.Trace(name), "Unable to remove policy.", nil)
+		writeIAMErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// ListQuarantineHandler - GET /minio/admin/quarantine
+// ----------
+// Returns uploads kept in quarantine after failing verification,
+// oldest first.
+func (api adminAPIHandlers) ListQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	infos, err := api.ObjectAPI.ListQuarantine()
+	if err != nil {
+		errorIf(err.Trace(), "Unable to list quarantine.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, infos)
+}
+
+// writeQuarantineErrorResponse - writes error response for quarantine
+// errors.
+func writeQuarantineErrorResponse(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case QuarantineNotFound:
+		writeErrorResponse(w, r, ErrNoSuchQuarantine, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case BucketNameInvalid:
+		writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+	case ObjectNameInvalid:
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	case InvalidUploadID:
+		writeErrorResponse(w, r, ErrNoSuchUpload, r.URL.Path)
+	case ObjectLocked:
+		writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+	case BadDigest:
+		writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
+	case StorageFull:
+		writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+	case QuotaExceeded:
+		writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// GetQuarantineDataHandler - GET /minio/admin/quarantine/{id}/data
+// ----------
+// Returns the bytes received of a quarantined upload.
+func (api adminAPIHandlers) GetQuarantineDataHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	info, err := api.ObjectAPI.GetQuarantine(id)
+	if err != nil {
+		errorIf(err.Trace(id), "Unable to get quarantined upload.", nil)
+		writeQuarantineErrorResponse(w, r, err)
+		return
+	}
+	readCloser, err := api.ObjectAPI.WithContext(r.Context()).GetQuarantineData(id)
+	if err != nil {
+		errorIf(err.Trace(id), "Unable to read quarantined upload.", nil)
+		writeQuarantineErrorResponse(w, r, err)
+		return
+	}
+	defer readCloser.Close()
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, e := io.Copy(w, readCloser); e != nil {
+		errorIf(probe.NewError(e).Trace(id), "Writing quarantined upload failed.", nil)
+	}
+}
+
+// quarantineRecovery - response of the admin recover quarantine API.
+type quarantineRecovery struct {
+	Bucket string `json:"bucket"`
+	Object string `json:"object"`
+	ETag   string `json:"etag"`
+}
+
+// RecoverQuarantineHandler - POST /minio/admin/quarantine/{id}/recover?bucket=name&object=name
+// ----------
+// Writes the bytes received of a quarantined upload as the object, by
+// default the one originally uploaded. Quarantined parts of multipart
+// uploads in progress are written as the part by default. The upload
+// is removed from quarantine once recovered.
+func (api adminAPIHandlers) RecoverQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	bucket, object := r.URL.Query().Get("bucket"), r.URL.Query().Get("object")
+	info, err := api.ObjectAPI.GetQuarantine(id)
+	if err != nil {
+		errorIf(err.Trace(id), "Unable to get quarantined upload.", nil)
+		writeQuarantineErrorResponse(w, r, err)
+		return
+	}
+	etag, err := api.ObjectAPI.WithContext(r.Context()).RecoverQuarantine(id, bucket, object)
+	if err != nil {
+		errorIf(err.Trace(id, bucket, object), "Unable to recover quarantined upload.", nil)
+		writeQuarantineErrorResponse(w, r, err)
+		return
+	}
+	if bucket == "" {
+		bucket = info.Bucket
+	}
+	if object == "" {
+		object = info.Object
+	}
+	writeAdminResponse(w, r, quarantineRecovery{Bucket: bucket, Object: object, ETag: etag})
+}
+
+// RemoveQuarantineHandler - DELETE /minio/admin/quarantine/{id}
+// ----------
+// Discards a quarantined upload.
+func (api adminAPIHandlers) RemoveQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if err := api.ObjectAPI.RemoveQuarantine(id); err != nil {
+		errorIf(err.Trace(id), "Unable to remove quarantined upload.", nil)
+		writeQuarantineErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// ListTrashHandler - GET /minio/admin/trash?bucket=name
+// ----------
+// Returns deleted objects kept in trash, of the bucket if given,
+// oldest first.
+func (api adminAPIHandlers) ListTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	infos, err := api.ObjectAPI.ListTrash(bucket)
+	if err != nil {
+		errorIf(err.Trace(bucket), "Unable to list trash.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, infos)
+}
+
+// writeTrashErrorResponse - writes error response for trash errors.
+func writeTrashErrorResponse(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case TrashNotFound:
+		writeErrorResponse(w, r, ErrNoSuchTrash, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case BucketNameInvalid:
+		writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+	case ObjectNameInvalid:
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	case ObjectLocked:
+		writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+	case BadDigest:
+		writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
+	case StorageFull:
+		writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+	case QuotaExceeded:
+		writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// trashRestore - response of the admin restore trash API.
+type trashRestore struct {
+	Bucket string `json:"bucket"`
+	Object string `json:"object"`
+	ETag   string `json:"etag"`
+}
+
+// RestoreTrashHandler - POST /minio/admin/trash/{id}/restore?bucket=name&object=name
+// ----------
+// Writes a deleted object kept in trash back, by default where it was
+// deleted from, replacing any object written there since. The object
+// is removed from trash once restored.
+func (api adminAPIHandlers) RestoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	bucket, object := r.URL.Query().Get("bucket"), r.URL.Query().Get("object")
+	info, err := api.ObjectAPI.GetTrash(id)
+	if err != nil {
+		errorIf(err.Trace(id), "Unable to get trashed object.", nil)
+		writeTrashErrorResponse(w, r, err)
+		return
+	}
+	etag, err := api.ObjectAPI.WithContext(r.Context()).RestoreTrash(id, bucket, object)
+	if err != nil {
+		errorIf(err.Trace(id, bucket, object), "Unable to restore trashed object.", nil)
+		writeTrashErrorResponse(w, r, err)
+		return
+	}
+	if bucket == "" {
+		bucket = info.Bucket
+	}
+	if object == "" {
+		object = info.Object
+	}
+	writeAdminResponse(w, r, trashRestore{Bucket: bucket, Object: object, ETag: etag})
+}
+
+// RemoveTrashHandler - DELETE /minio/admin/trash/{id}
+// ----------
+// Discards a deleted object kept in trash.
+func (api adminAPIHandlers) RemoveTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if err := api.ObjectAPI.RemoveTrash(id); err != nil {
+		errorIf(err.Trace(id), "Unable to remove trashed object.", nil)
+		writeTrashErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// trashPurge - response of the admin purge trash API.
+type trashPurge struct {
+	Removed int64 `json:"removed"`
+}
+
+// PurgeTrashHandler - DELETE /minio/admin/trash?bucket=name
+// ----------
+// Discards all deleted objects kept in trash, of the bucket if given,
+// before their retention ends.
+func (api adminAPIHandlers) PurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	removed, err := api.ObjectAPI.PurgeTrash(bucket, true)
+	if err != nil {
+		errorIf(err.Trace(bucket), "Unable to purge trash.", nil)
+		writeTrashErrorResponse(w, r, err)
+		return
+	}
+	writeAdminResponse(w, r, trashPurge{Removed: removed})
+}
+
+// ListEventBacklogsHandler - GET /minio/admin/events/backlog
+// ----------
+// Returns the number of undelivered events of each notification
+// target, along with the last failure keeping them.
+func (api adminAPIHandlers) ListEventBacklogsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalEventStores.List())
+}
+
+// GetEventBacklogHandler - GET /minio/admin/events/backlog/{target}
+// ----------
+// Returns the oldest undelivered events of a notification target.
+func (api adminAPIHandlers) GetEventBacklogHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	targetID := mux.Vars(r)["target"]
+	store, err := globalEventStores.Lookup(targetID)
+	if err != nil {
+		writeErrorResponse(w, r, ErrNoSuchEventTarget, r.URL.Path)
+		return
+	}
+	events, truncated, e := store.List(maxEventStoreList)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(targetID), "Unable to list undelivered events.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, eventBacklog{Info: store.Info(), Events: events, Truncated: truncated})
+}
+
+// ReplayEventBacklogHandler - POST /minio/admin/events/backlog/{target}/replay
+// ----------
+// Replays undelivered events of a notification target in background
+// now, instead of at the next periodic replay.
+func (api adminAPIHandlers) ReplayEventBacklogHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	targetID := mux.Vars(r)["target"]
+	if err := globalEventNotifier.ReplayTarget(targetID); err != nil {
+		writeErrorResponse(w, r, ErrNoSuchEventTarget, r.URL.Path)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// eventBacklogDiscard - response of the admin discard event backlog
+// API.
+type eventBacklogDiscard struct {
+	Removed int `json:"removed"`
+}
+
+// DiscardEventBacklogHandler - DELETE /minio/admin/events/backlog/{target}
+// ----------
+// Drops all undelivered events of a notification target.
+func (api adminAPIHandlers) DiscardEventBacklogHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	targetID := mux.Vars(r)["target"]
+	store, err := globalEventStores.Lookup(targetID)
+	if err != nil {
+		writeErrorResponse(w, r, ErrNoSuchEventTarget, r.URL.Path)
+		return
+	}
+	removed, e := store.Discard()
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(targetID), "Unable to discard undelivered events.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, eventBacklogDiscard{Removed: removed})
+}
+
+// GetConfigHandler - GET /minio/admin/config
+// ----------
+// Returns effective values of all settings along with their type,
+// source and environment variable. Secrets are redacted.
+func (api adminAPIHandlers) GetConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, serverConfig.GetConfigValues())
+}
+
+// GetConfigKeyHandler - GET /minio/admin/config/{key}
+// ----------
+// Returns effective value of a setting.
+func (api adminAPIHandlers) GetConfigKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	value, e := serverConfig.GetConfigValue(mux.Vars(r)["key"])
+	if e != nil {
+		writeErrorResponse(w, r, ErrNoSuchConfigKey, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, value)
+}
+
+// SetConfigKeyHandler - PUT /minio/admin/config/{key}
+// ----------
+// Sets a setting to the value in the request body and saves the
+// config. Settings overridden by environment variables cannot be set,
+// dynamic settings take effect right away, others on restart.
+func (api adminAPIHandlers) SetConfigKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	prev := serverConfig.snapshot()
+	key := mux.Vars(r)["key"]
+	valueBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxConfigValueSize))
+	if e != nil {
+		writeErrorResponse(w, r, ErrInvalidConfigValue, r.URL.Path)
+		return
+	}
+	switch e = serverConfig.SetConfigValue(key, string(valueBytes)); e {
+	case nil:
+	case errConfigKeyNotFound:
+		writeErrorResponse(w, r, ErrNoSuchConfigKey, r.URL.Path)
+		return
+	case errConfigOverriddenEnv:
+		writeErrorResponse(w, r, ErrConfigOverriddenByEnv, r.URL.Path)
+		return
+	default:
+		writeErrorResponse(w, r, ErrInvalidConfigValue, r.URL.Path)
+		return
+	}
+	if err := serverConfig.Save(); err != nil {
+		errorIf(err.Trace(key), "Unable to save config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if err := reloadConfigSubsystems(api.ObjectAPI, prev); err != nil {
+		errorIf(err.Trace(key), "Unable to reload config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	value, _ := serverConfig.GetConfigValue(key)
+	writeAdminResponse(w, r, value)
+}
+
+// GetConfigDocumentHandler - GET /minio/admin/config?document
+// ----------
+// Returns the saved config document, without the values of
+// environment variables, credentials and secrets.
+func (api adminAPIHandlers) GetConfigDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	configBytes, e := serverConfig.GetConfigDocument()
+	if e != nil {
+		errorIf(probe.NewError(e), "Unable to marshal config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	writeSuccessResponse(w, configBytes)
+}
+
+// SetConfigDocumentHandler - PUT /minio/admin/config
+// ----------
+// Replaces the config with the config document in the request body,
+// as returned by GET, and saves it. Credentials are kept, so are
+// secrets left redacted. Dynamic settings and notification targets
+// take effect right away, others on restart.
+func (api adminAPIHandlers) SetConfigDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	configBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+	if e != nil {
+		writeErrorResponse(w, r, ErrInvalidConfigDocument, r.URL.Path)
+		return
+	}
+	prev := serverConfig.snapshot()
+	if e = serverConfig.SetConfigDocument(configBytes); e != nil {
+		errorIf(probe.NewError(e), "Invalid config document.", nil)
+		writeErrorResponse(w, r, ErrInvalidConfigDocument, r.URL.Path)
+		return
+	}
+	if err := serverConfig.Save(); err != nil {
+		errorIf(err.Trace(), "Unable to save config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if err := reloadConfigSubsystems(api.ObjectAPI, prev); err != nil {
+		errorIf(err.Trace(), "Unable to reload config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, serverConfig.GetConfigValues())
+}
+
+// ReloadConfigHandler - POST /minio/admin/config/reload
+// ----------
+// Reloads the config saved by another server of the deployment.
+// Dynamic settings and notification targets take effect right away,
+// others on restart.
+func (api adminAPIHandlers) ReloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	prev := serverConfig.snapshot()
+	if e := serverConfig.ReloadConfigDocument(api.ObjectAPI); e != nil {
+		errorIf(probe.NewError(e), "Unable to reload config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if err := reloadConfigSubsystems(api.ObjectAPI, prev); err != nil {
+		errorIf(err.Trace(), "Unable to reload config.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, serverConfig.GetConfigValues())
+}
+
+// ServerInfoHandler - GET /minio/admin/info
+// ----------
+// Returns server version and uptime, online state and space usage of
+// each disk, the state of the last heal operation and how reads of
+// erasure coded storage were served.
+func (api adminAPIHandlers) ServerInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	info := serverInfo{
+		Version:  minioVersion,
+		CommitID: minioCommitID,
+		Uptime:   int64(time.Since(globalBootTime) / time.Second),
+		BootTime: globalBootTime,
+		Disks:    []DiskStatus{},
+		Heal:     globalHealControl.Info(),
+		SIMD: simdInfo{
+			CPU:           cpuid.CPU.BrandName,
+			ErasureCoding: erasureImplementation(),
+			Checksum:      fastSha512.Implementation(),
+		},
+	}
+	if reporter, ok := api.ObjectAPI.storage.(diskStatusReporter); ok {
+		info.Disks = reporter.DiskStatus()
+	}
+	if reporter, ok := api.ObjectAPI.storage.(readStatsReporter); ok {
+		reads := reporter.ReadStats()
+		info.Reads = &reads
+	}
+	writeAdminResponse(w, r, info)
+}
+
+// CapabilitiesHandler - GET /minio/admin/capabilities
+// ----------
+// Returns version of the object layer interface and the optional
+// features supported by the storage backend, requests needing others
+// fail with NotImplemented.
+func (api adminAPIHandlers) CapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, api.ObjectAPI.Capabilities())
+}
+
+// CacheStatsHandler - GET /minio/admin/cache
+// ----------
+// Returns usage of the local disk cache, and its hits and misses
+// since the server started.
+func (api adminAPIHandlers) CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, api.ObjectAPI.CacheStats())
+}
+
+// writeHealErrorResponse - writes error response for heal control and
+// object verification errors.
+func writeHealErrorResponse(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case BucketNameInvalid:
+		writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case ObjectNameInvalid, ObjectNotFound:
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	case StorageInsufficientReadResources:
+		writeReadQuorumErrorResponse(w, r, err)
+	case HealNotSupported:
+		writeErrorResponse(w, r, ErrHealNotSupported, r.URL.Path)
+	case HealAlreadyRunning:
+		writeErrorResponse(w, r, ErrHealAlreadyRunning, r.URL.Path)
+	case HealNotRunning:
+		writeErrorResponse(w, r, ErrHealNotRunning, r.URL.Path)
+	case VerifyNotSupported:
+		writeErrorResponse(w, r, ErrVerifyNotSupported, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// HealInfoHandler - GET /minio/admin/heal
+// ----------
+// Returns the state of the last heal operation.
+func (api adminAPIHandlers) HealInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalHealControl.Info())
+}
+
+// StartHealHandler - POST /minio/admin/heal?bucket=bucket&prefix=prefix
+// ----------
+// Starts healing objects with prefix of bucket in background, all
+// buckets are healed if bucket is not set. Only one heal operation
+// runs at a time.
+func (api adminAPIHandlers) StartHealHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	prefix := r.URL.Query().Get("prefix")
+	if err := globalHealControl.Start(api.ObjectAPI, bucket, prefix); err != nil {
+		errorIf(err.Trace(bucket, prefix), "Unable to start heal.", nil)
+		writeHealErrorResponse(w, r, err)
+		return
+	}
+	writeAdminResponse(w, r, globalHealControl.Info())
+}
+
+// StopHealHandler - DELETE /minio/admin/heal
+// ----------
+// Stops the running heal operation.
+func (api adminAPIHandlers) StopHealHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	if err := globalHealControl.Stop(); err != nil {
+		errorIf(err.Trace(), "Unable to stop heal.", nil)
+		writeHealErrorResponse(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// FormatMigrationInfoHandler - GET /minio/admin/migrate
+// ----------
+// Returns state of the last started format migration.
+func (api adminAPIHandlers) FormatMigrationInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalFormatMigration.Info())
+}
+
+// StartFormatMigrationHandler - POST /minio/admin/migrate?dryRun=true
+// ----------
+// Starts rewriting metadata of older formats of all files in the
+// current format in background, files are only counted with dryRun.
+// Only one format migration runs at a time.
+func (api adminAPIHandlers) StartFormatMigrationHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if err := globalFormatMigration.Start(api.ObjectAPI.storage, dryRun); err != nil {
+		errorIf(err.Trace(), "Unable to start format migration.", nil)
+		switch err.ToGoError().(type) {
+		case MigrationNotSupported:
+			writeErrorResponse(w, r, ErrMigrationNotSupported, r.URL.Path)
+		case MigrationAlreadyRunning:
+			writeErrorResponse(w, r, ErrMigrationAlreadyRunning, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeAdminResponse(w, r, globalFormatMigration.Info())
+}
+
+// DataUsageInfoHandler - GET /minio/admin/usage
+// ----------
+// Returns number of objects and size of all buckets found by the last
+// data usage crawl.
+func (api adminAPIHandlers) DataUsageInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, globalDataUsage.Info())
+}
+
+// StartDataUsageCrawlHandler - POST /minio/admin/usage
+// ----------
+// Starts crawling usage of all buckets now, unless a crawl is running
+// already. Usage of the last crawl is returned meanwhile.
+func (api adminAPIHandlers) StartDataUsageCrawlHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	globalDataUsage.Start()
+	writeAdminResponse(w, r, globalDataUsage.Info())
+}
+
+// VerifyObjectHandler - GET /minio/admin/verify?bucket=name&object=name
+// ----------
+// Reads the parts of an object on all disks and returns which of them
+// are healthy, corrupt, missing or outdated.
+func (api adminAPIHandlers) VerifyObjectHandler(w http.ResponseWriter, r *http.Request) {
+	api.verifyObject(w, r, false)
+}
+
+// RepairObjectHandler - POST /minio/admin/verify?bucket=name&object=name
+// ----------
+// Verifies the parts of an object as above, and rebuilds those which
+// are not healthy from the others.
+func (api adminAPIHandlers) RepairObjectHandler(w http.ResponseWriter, r *http.Request) {
+	api.verifyObject(w, r, true)
+}
+
+func (api adminAPIHandlers) verifyObject(w http.ResponseWriter, r *http.Request, repair bool) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	bucket, object := r.URL.Query().Get("bucket"), r.URL.Query().Get("object")
+	verification, err := api.ObjectAPI.VerifyObject(bucket, object, repair)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "Unable to verify object.", nil)
+		writeHealErrorResponse(w, r, err)
+		return
+	}
+	writeAdminResponse(w, r, verification)
+}
+
+// ListPurgeJobsHandler - GET /minio/admin/purge
+// ----------
+// Returns running and recently ended purge jobs of force deleted
+// buckets.
+func (api adminAPIHandlers) ListPurgeJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	writeAdminResponse(w, r, api.ObjectAPI.ListPurgeJobs())
+}
+
+// GetPurgeJobHandler - GET /minio/admin/purge/{id}
+// ----------
+// Returns the state of a purge job.
+func (api adminAPIHandlers) GetPurgeJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	job, ok := api.ObjectAPI.GetPurgeJob(mux.Vars(r)["id"])
+	if !ok {
+		writeErrorResponse(w, r, ErrNoSuchPurgeJob, r.URL.Path)
+		return
+	}
+	writeAdminResponse(w, r, job)
+}
+
+// TraceHandler - GET /minio/admin/trace
+// ----------
+// Streams traced requests along with their responses as newline
+// delimited JSON until the client disconnects. Requests can be
+// filtered by "bucket" and object name "prefix", up to "body" bytes of
+// request and response bodies are traced.
+func (api adminAPIHandlers) TraceHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminReqAuthenticated(w, r) {
+		return
+	}
+	var bodySize int
+	if bodySizeStr := r.URL.Query().Get("body"); bodySizeStr != "" {
+		var e error
+		bodySize, e = strconv.Atoi(bodySizeStr)
+		if e != nil || bodySize < 0 || bodySize > maxTraceBodySize {
+			writeErrorResponse(w, r, ErrInvalidTraceBodySize, r.URL.Path)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
+		return
+	}
+	var closeNotify <-chan bool
+	if closeNotifier, ok := w.(http.CloseNotifier); ok {
+		closeNotify = closeNotifier.CloseNotify()
+	}
+
+	// Register before replying, so that the client receives all the
+	// requests served after the response headers.
+	listener := globalHTTPTracer.AddListener(r.URL.Query().Get("bucket"), r.URL.Query().Get("prefix"), bodySize)
+	defer globalHTTPTracer.RemoveListener(listener)
+
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(listenKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case trace := <-listener.ch:
+			// Encode terminates every trace with a newline.
+			if e := encoder.Encode(trace); e != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, e := w.Write([]byte(" ")); e != nil {
+				return
+			}
+		case <-closeNotify:
+			return
+		}
+		flusher.Flush()
+	}
+}
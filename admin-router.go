@@ -0,0 +1,164 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import router "github.com/gorilla/mux"
+
+// Admin API path prefix, served under the reserved bucket.
+const adminAPIPathPrefix = reservedBucket + "/admin"
+
+// adminAPIHandlers implements and provides http handlers for the
+// admin API.
+type adminAPIHandlers struct {
+	ObjectAPI objectAPI
+}
+
+// registerAdminRouter - registers admin APIs, needs to be registered
+// before the web router which serves all other reserved bucket paths.
+func registerAdminRouter(mux *router.Router, api adminAPIHandlers) {
+	// Admin router
+	adminRouter := mux.NewRoute().PathPrefix(adminAPIPathPrefix).Subrouter()
+
+	// Health
+	adminRouter.Methods("GET").Path("/health").HandlerFunc(api.HealthHandler)
+	// ServerInfo
+	adminRouter.Methods("GET").Path("/info").HandlerFunc(api.ServerInfoHandler)
+	// Capabilities
+	adminRouter.Methods("GET").Path("/capabilities").HandlerFunc(api.CapabilitiesHandler)
+	// CacheStats
+	adminRouter.Methods("GET").Path("/cache").HandlerFunc(api.CacheStatsHandler)
+	// HealInfo
+	adminRouter.Methods("GET").Path("/heal").HandlerFunc(api.HealInfoHandler)
+	// StartHeal
+	adminRouter.Methods("POST").Path("/heal").HandlerFunc(api.StartHealHandler)
+	// StopHeal
+	adminRouter.Methods("DELETE").Path("/heal").HandlerFunc(api.StopHealHandler)
+	// FormatMigrationInfo
+	adminRouter.Methods("GET").Path("/migrate").HandlerFunc(api.FormatMigrationInfoHandler)
+	// StartFormatMigration
+	adminRouter.Methods("POST").Path("/migrate").HandlerFunc(api.StartFormatMigrationHandler)
+	// DataUsageInfo
+	adminRouter.Methods("GET").Path("/usage").HandlerFunc(api.DataUsageInfoHandler)
+	// StartDataUsageCrawl
+	adminRouter.Methods("POST").Path("/usage").HandlerFunc(api.StartDataUsageCrawlHandler)
+	// VerifyObject
+	adminRouter.Methods("GET").Path("/verify").HandlerFunc(api.VerifyObjectHandler)
+	// RepairObject
+	adminRouter.Methods("POST").Path("/verify").HandlerFunc(api.RepairObjectHandler)
+	// ListPurgeJobs
+	adminRouter.Methods("GET").Path("/purge").HandlerFunc(api.ListPurgeJobsHandler)
+	// GetPurgeJob
+	adminRouter.Methods("GET").Path("/purge/{id}").HandlerFunc(api.GetPurgeJobHandler)
+	// Trace
+	adminRouter.Methods("GET").Path("/trace").HandlerFunc(api.TraceHandler)
+	// MultipartInfo
+	adminRouter.Methods("GET").Path("/multipart").HandlerFunc(api.MultipartInfoHandler)
+	// ListIncompleteUploads
+	adminRouter.Methods("GET").Path("/multipart/uploads").HandlerFunc(api.ListIncompleteUploadsHandler)
+	// StartMultipartCleanup
+	adminRouter.Methods("POST").Path("/multipart/cleanup").HandlerFunc(api.StartMultipartCleanupHandler)
+	// AttestationKeyInfo
+	adminRouter.Methods("GET").Path("/attestation-key").HandlerFunc(api.AttestationKeyInfoHandler)
+	// ListBandwidthLimits
+	adminRouter.Methods("GET").Path("/bandwidth").HandlerFunc(api.ListBandwidthLimitsHandler)
+	// SetBandwidthLimit
+	adminRouter.Methods("PUT").Path("/bandwidth/{target}/{name}").HandlerFunc(api.SetBandwidthLimitHandler)
+	// RemoveBandwidthLimit
+	adminRouter.Methods("DELETE").Path("/bandwidth/{target}/{name}").HandlerFunc(api.RemoveBandwidthLimitHandler)
+	// ListBucketQuotas
+	adminRouter.Methods("GET").Path("/quotas").HandlerFunc(api.ListBucketQuotasHandler)
+	// SetBucketQuota
+	adminRouter.Methods("PUT").Path("/quotas/{bucket}").HandlerFunc(api.SetBucketQuotaHandler)
+	// RemoveBucketQuota
+	adminRouter.Methods("DELETE").Path("/quotas/{bucket}").HandlerFunc(api.RemoveBucketQuotaHandler)
+	// DiskShares
+	adminRouter.Methods("GET").Path("/disk-shares").HandlerFunc(api.DiskSharesHandler)
+	// TopLocks
+	adminRouter.Methods("GET").Path("/locks").HandlerFunc(api.TopLocksHandler)
+	// ListUsers
+	adminRouter.Methods("GET").Path("/users").HandlerFunc(api.ListUsersHandler)
+	// SetUser
+	adminRouter.Methods("PUT").Path("/users/{accessKey}").HandlerFunc(api.SetUserHandler)
+	// RemoveUser
+	adminRouter.Methods("DELETE").Path("/users/{accessKey}").HandlerFunc(api.RemoveUserHandler)
+	// AttachUserPolicy
+	adminRouter.Methods("PUT").Path("/users/{accessKey}/policy/{policy}").HandlerFunc(api.AttachUserPolicyHandler)
+	// AddServiceAccount
+	adminRouter.Methods("POST").Path("/service-accounts").HandlerFunc(api.AddServiceAccountHandler)
+	// ListServiceAccounts
+	adminRouter.Methods("GET").Path("/service-accounts").HandlerFunc(api.ListServiceAccountsHandler)
+	// RemoveServiceAccount
+	adminRouter.Methods("DELETE").Path("/service-accounts/{accessKey}").HandlerFunc(api.RemoveServiceAccountHandler)
+	// CredentialInfo
+	adminRouter.Methods("GET").Path("/credential").HandlerFunc(api.CredentialInfoHandler)
+	// RotateCredential
+	adminRouter.Methods("POST").Path("/credential/rotate").HandlerFunc(api.RotateCredentialHandler)
+	// RemovePreviousCredential
+	adminRouter.Methods("DELETE").Path("/credential/previous").HandlerFunc(api.RemovePreviousCredentialHandler)
+	// ListPolicies
+	adminRouter.Methods("GET").Path("/policies").HandlerFunc(api.ListPoliciesHandler)
+	// SetPolicy
+	adminRouter.Methods("PUT").Path("/policies/{policy}").HandlerFunc(api.SetPolicyHandler)
+	// RemovePolicy
+	adminRouter.Methods("DELETE").Path("/policies/{policy}").HandlerFunc(api.RemovePolicyHandler)
+	// ListQuarantine
+	adminRouter.Methods("GET").Path("/quarantine").HandlerFunc(api.ListQuarantineHandler)
+	// GetQuarantineData
+	adminRouter.Methods("GET").Path("/quarantine/{id}/data").HandlerFunc(api.GetQuarantineDataHandler)
+	// RecoverQuarantine
+	adminRouter.Methods("POST").Path("/quarantine/{id}/recover").HandlerFunc(api.RecoverQuarantineHandler)
+	// RemoveQuarantine
+	adminRouter.Methods("DELETE").Path("/quarantine/{id}").HandlerFunc(api.RemoveQuarantineHandler)
+	// ListTrash
+	adminRouter.Methods("GET").Path("/trash").HandlerFunc(api.ListTrashHandler)
+	// RestoreTrash
+	adminRouter.Methods("POST").Path("/trash/{id}/restore").HandlerFunc(api.RestoreTrashHandler)
+	// RemoveTrash
+	adminRouter.Methods("DELETE").Path("/trash/{id}").HandlerFunc(api.RemoveTrashHandler)
+	// PurgeTrash
+	adminRouter.Methods("DELETE").Path("/trash").HandlerFunc(api.PurgeTrashHandler)
+	// ListEventBacklogs
+	adminRouter.Methods("GET").Path("/events/backlog").HandlerFunc(api.ListEventBacklogsHandler)
+	// GetEventBacklog
+	adminRouter.Methods("GET").Path("/events/backlog/{target}").HandlerFunc(api.GetEventBacklogHandler)
+	// ReplayEventBacklog
+	adminRouter.Methods("POST").Path("/events/backlog/{target}/replay").HandlerFunc(api.ReplayEventBacklogHandler)
+	// DiscardEventBacklog
+	adminRouter.Methods("DELETE").Path("/events/backlog/{target}").HandlerFunc(api.DiscardEventBacklogHandler)
+	// GetConfigDocument
+	adminRouter.Methods("GET").Path("/config").HandlerFunc(api.GetConfigDocumentHandler).Queries("document", "")
+	// GetConfig
+	adminRouter.Methods("GET").Path("/config").HandlerFunc(api.GetConfigHandler)
+	// SetConfigDocument
+	adminRouter.Methods("PUT").Path("/config").HandlerFunc(api.SetConfigDocumentHandler)
+	// ReloadConfig
+	adminRouter.Methods("POST").Path("/config/reload").HandlerFunc(api.ReloadConfigHandler)
+	// GetConfigKey
+	adminRouter.Methods("GET").Path("/config/{key}").HandlerFunc(api.GetConfigKeyHandler)
+	// SetConfigKey
+	adminRouter.Methods("PUT").Path("/config/{key}").HandlerFunc(api.SetConfigKeyHandler)
+	// OpenReadSession
+	adminRouter.Methods("POST").Path("/read-sessions").HandlerFunc(api.OpenReadSessionHandler)
+	// CloseReadSession
+	adminRouter.Methods("DELETE").Path("/read-sessions/{id}").HandlerFunc(api.CloseReadSessionHandler)
+	// PresignInfo
+	adminRouter.Methods("GET").Path("/presign").HandlerFunc(api.PresignInfoHandler)
+	// RevokePresignSigner
+	adminRouter.Methods("PUT").Path("/presign/revocations/{signer}").HandlerFunc(api.RevokePresignSignerHandler)
+	// UnrevokePresignSigner
+	adminRouter.Methods("DELETE").Path("/presign/revocations/{signer}").HandlerFunc(api.UnrevokePresignSignerHandler)
+}
@@ -61,6 +61,9 @@ const (
 	ErrInvalidMaxUploads
 	ErrInvalidMaxParts
 	ErrInvalidPartNumberMarker
+	ErrInvalidEncodingMethod
+	ErrInvalidPartNumber
+	ErrInvalidObjectName
 	ErrInvalidRequestBody
 	ErrInvalidCopySource
 	ErrInvalidCopyDest
@@ -104,6 +107,88 @@ const (
 	ErrMissingDateHeader
 	ErrInvalidQuerySignatureAlgo
 	ErrInvalidQueryParams
+	ErrEventNotification
+	ErrNoSuchLifecycleConfiguration
+	ErrInvalidLifecycleRule
+	ErrInvalidStorageClass
+	ErrInvalidTag
+	ErrNoSuchReadSession
+	ErrTooManyReadSessions
+	ErrInvalidArchive
+	ErrObjectLocked
+	ErrInvalidRetention
+	ErrObjectLockConfigurationNotFound
+	ErrNoSuchObjectLockConfiguration
+	ErrInvalidLegalHold
+	ErrBucketObjectLocked
+	ErrPresignRevoked
+	ErrNoSuchPresignRevocation
+	ErrReplicationConfigurationNotFound
+	ErrHealNotSupported
+	ErrHealAlreadyRunning
+	ErrHealNotRunning
+	ErrVerifyNotSupported
+	ErrMigrationNotSupported
+	ErrMigrationAlreadyRunning
+	ErrPlacementNotSupported
+	ErrInvalidPlacement
+	ErrInvalidPartMapParts
+	ErrBucketPurging
+	ErrNoSuchPurgeJob
+	ErrInvalidTraceBodySize
+	ErrTooManyMultipartUploads
+	ErrSlowDown
+	ErrNoSuchAttestation
+	ErrAttestationDisabled
+	ErrNoSuchBandwidthLimit
+	ErrInvalidBandwidthLimit
+	ErrNoSuchUser
+	ErrInvalidUser
+	ErrNoSuchIAMPolicy
+	ErrInvalidIAMPolicy
+	ErrIAMPolicyInUse
+	ErrInvalidToken
+	ErrExpiredToken
+	ErrInvalidSTSRequest
+	ErrLDAPDisabled
+	ErrLDAPUnavailable
+	ErrNoSuchQuarantine
+	ErrNoSuchTrash
+	ErrNoSuchEventTarget
+	ErrNoSuchConfigKey
+	ErrInvalidConfigValue
+	ErrConfigOverriddenByEnv
+	ErrNoSuchServiceAccount
+	ErrTooManyServiceAccounts
+	ErrInvalidCredentialRotation
+	ErrInvalidTargetBucketForLogging
+	ErrQuotaExceeded
+	ErrNoSuchBucketQuota
+	ErrInvalidBucketQuota
+	ErrServerNotReady
+	ErrInvalidConfigDocument
+	ErrInvalidUploadIDMarker
+	ErrInvalidCopyPartRange
+	ErrPreconditionFailed
+	ErrMetadataTooLarge
+	ErrInvalidMetadataDirective
+	ErrPostPolicyExtraInputFields
+	ErrPostPolicyConditionFailed
+	ErrNoSuchCORSConfiguration
+	ErrCORSNotEnabled
+	ErrCORSForbidden
+	ErrInvalidCORSRequestMethod
+	ErrInvalidExpressionType
+	ErrInvalidSelectExpression
+	ErrInvalidCompressionFormat
+	ErrInvalidDataSource
+	ErrInvalidFileHeaderInfo
+	ErrInvalidJSONType
+	ErrInvalidQuoteFields
+	ErrInvalidRequestParameter
+	ErrOperationTimedOut
+	ErrInvalidChecksum
+	ErrBadChecksum
 	// Add new error codes here.
 
 	// Extended errors.
@@ -144,6 +229,21 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Argument maxParts must be an integer between 1 and 10000.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrInvalidEncodingMethod: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid Encoding Method specified in Request",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidPartNumber: {
+		Code:           "InvalidArgument",
+		Description:    "Part number must be an integer between 1 and the maximum number of parts, inclusive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidObjectName: {
+		Code:           "XMinioInvalidObjectName",
+		Description:    "Object name contains empty, '.' or '..' elements, which cannot be stored.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	ErrInvalidPartNumberMarker: {
 		Code:           "InvalidArgument",
 		Description:    "Argument partNumberMarker must be an integer.",
@@ -356,7 +456,7 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 	},
 	ErrInvalidService: {
 		Code:           "AccessDenied",
-		Description:    "Service scope should be of value 's3'.",
+		Description:    "Service scope should be of value 's3', or 'sts' for STS requests.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
 	ErrInvalidRequestVersion: {
@@ -409,6 +509,416 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrEventNotification: {
+		Code:           "InvalidArgument",
+		Description:    "A specified event is not supported for notifications.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchLifecycleConfiguration: {
+		Code:           "NoSuchLifecycleConfiguration",
+		Description:    "The lifecycle configuration does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidLifecycleRule: {
+		Code:           "InvalidArgument",
+		Description:    "The lifecycle configuration contains an invalid rule.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidStorageClass: {
+		Code:           "InvalidStorageClass",
+		Description:    "The storage class you specified is not valid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTag: {
+		Code:           "InvalidTag",
+		Description:    "The tag provided was not a valid tag.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchReadSession: {
+		Code:           "NoSuchReadSession",
+		Description:    "The specified read session does not exist or has expired.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrTooManyReadSessions: {
+		Code:           "TooManyReadSessions",
+		Description:    "Maximum number of read sessions are open, close unused sessions.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrInvalidArchive: {
+		Code:           "InvalidArchive",
+		Description:    "The archive you provided is not a valid tar, gzip compressed tar or zip archive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLocked: {
+		Code:           "AccessDenied",
+		Description:    "Object is WORM protected and cannot be overwritten or deleted.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrInvalidRetention: {
+		Code:           "InvalidArgument",
+		Description:    "Retention should have a mode of GOVERNANCE or COMPLIANCE and a retain until date in the future.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLockConfigurationNotFound: {
+		Code:           "ObjectLockConfigurationNotFoundError",
+		Description:    "Object Lock configuration does not exist for this bucket.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		Code:           "NoSuchObjectLockConfiguration",
+		Description:    "The specified object does not have an ObjectLock configuration.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidLegalHold: {
+		Code:           "InvalidArgument",
+		Description:    "Legal hold status should be either ON or OFF.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrBucketObjectLocked: {
+		Code:           "InvalidBucketState",
+		Description:    "Object lock is enabled on the bucket, it cannot be force deleted.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrPresignRevoked: {
+		Code:           "AccessDenied",
+		Description:    "Presigned requests of this signer have been revoked.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrNoSuchPresignRevocation: {
+		Code:           "NoSuchPresignRevocation",
+		Description:    "The specified signer is not revoked.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrReplicationConfigurationNotFound: {
+		Code:           "ReplicationConfigurationNotFoundError",
+		Description:    "The replication configuration was not found.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrHealNotSupported: {
+		Code:           "NotImplemented",
+		Description:    "Heal is not supported by the storage backend.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
+	ErrHealAlreadyRunning: {
+		Code:           "HealAlreadyRunning",
+		Description:    "A heal operation is already running.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrHealNotRunning: {
+		Code:           "HealNotRunning",
+		Description:    "No heal operation is running.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrVerifyNotSupported: {
+		Code:           "NotImplemented",
+		Description:    "Object verification is not supported by the storage backend.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
+	ErrMigrationNotSupported: {
+		Code:           "NotImplemented",
+		Description:    "Format migration is not supported by the storage backend.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
+	ErrMigrationAlreadyRunning: {
+		Code:           "MigrationAlreadyRunning",
+		Description:    "Format migration is already running.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrPlacementNotSupported: {
+		Code:           "NotImplemented",
+		Description:    "Bucket placement is not supported by the storage backend.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
+	ErrInvalidPlacement: {
+		Code:           "InvalidArgument",
+		Description:    "Placement should be an even number of distinct disks.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidPartMapParts: {
+		Code:           "InvalidArgument",
+		Description:    "Number of parts should be between 1 and 10000.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrBucketPurging: {
+		Code:           "OperationAborted",
+		Description:    "A previous bucket with this name is still being deleted. Please try again.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrNoSuchPurgeJob: {
+		Code:           "NoSuchPurgeJob",
+		Description:    "The specified purge job does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidTraceBodySize: {
+		Code:           "InvalidArgument",
+		Description:    "Trace body size should be between 0 and 65536.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrTooManyMultipartUploads: {
+		Code:           "TooManyMultipartUploads",
+		Description:    "Maximum number of in-progress multipart uploads reached for the bucket or access key, complete or abort unused uploads.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrSlowDown: {
+		Code:           "SlowDown",
+		Description:    "Please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrNoSuchAttestation: {
+		Code:           "NoSuchAttestation",
+		Description:    "The object was written while attestations were disabled and has no attestation.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrAttestationDisabled: {
+		Code:           "AttestationDisabled",
+		Description:    "Object attestations are not enabled on this server.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchBandwidthLimit: {
+		Code:           "NoSuchBandwidthLimit",
+		Description:    "No bandwidth limit is set for the specified bucket or access key.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBandwidthLimit: {
+		Code:           "InvalidArgument",
+		Description:    "Bandwidth limits should be set for a bucket or an access key, with upload and download rates in bytes per second, e.g. 10MiB.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchUser: {
+		Code:           "NoSuchUser",
+		Description:    "The specified user does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidUser: {
+		Code:           "InvalidArgument",
+		Description:    "Users should have a valid access key, other than the server access key, and a secret key of 8 to 40 characters.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchIAMPolicy: {
+		Code:           "NoSuchPolicy",
+		Description:    "The specified policy does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidIAMPolicy: {
+		Code:           "MalformedPolicy",
+		Description:    "Policies should have Allow or Deny statements of s3: actions on arn:aws:s3::: resources, canned policies cannot be replaced.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrIAMPolicyInUse: {
+		Code:           "PolicyInUse",
+		Description:    "The specified policy is attached to a user.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrInvalidToken: {
+		Code:           "InvalidToken",
+		Description:    "The provided token is malformed or otherwise invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrExpiredToken: {
+		Code:           "ExpiredToken",
+		Description:    "The provided token has expired.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidSTSRequest: {
+		Code:           "InvalidParameterValue",
+		Description:    "STS requests should be form encoded with Action=AssumeRole or AssumeRoleWithLDAPIdentity, Version=2011-06-15 and an optional DurationSeconds of at least 900 up to the configured maximum.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrLDAPDisabled: {
+		Code:           "NotImplemented",
+		Description:    "LDAP identity is not configured.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
+	ErrLDAPUnavailable: {
+		Code:           "ServiceUnavailable",
+		Description:    "Unable to authenticate against the LDAP directory, please retry.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrNoSuchQuarantine: {
+		Code:           "NoSuchQuarantine",
+		Description:    "The specified quarantined upload does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchTrash: {
+		Code:           "NoSuchTrash",
+		Description:    "The specified deleted object is not in trash.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchEventTarget: {
+		Code:           "NoSuchEventTarget",
+		Description:    "The specified notification target does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchConfigKey: {
+		Code:           "NoSuchConfigKey",
+		Description:    "The specified config key does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidConfigValue: {
+		Code:           "InvalidConfigValue",
+		Description:    "The value is not valid for the config key.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrConfigOverriddenByEnv: {
+		Code:           "ConfigOverriddenByEnv",
+		Description:    "The config key is overridden by an environment variable and cannot be set.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrNoSuchServiceAccount: {
+		Code:           "NoSuchServiceAccount",
+		Description:    "The specified service account does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrTooManyServiceAccounts: {
+		Code:           "TooManyServiceAccounts",
+		Description:    "The maximum number of service accounts has been reached.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrInvalidCredentialRotation: {
+		Code:           "InvalidArgument",
+		Description:    "The new server credential should have an unused access key and a secret key of 8 to 40 characters, and a grace period of at most 30 days.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTargetBucketForLogging: {
+		Code:           "InvalidTargetBucketForLogging",
+		Description:    "The target bucket for logging does not exist.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrQuotaExceeded: {
+		Code:           "QuotaExceeded",
+		Description:    "Bucket quota exceeded, the object does not fit in the space left in the bucket.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrNoSuchBucketQuota: {
+		Code:           "NoSuchBucketQuota",
+		Description:    "No quota is set for the specified bucket.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBucketQuota: {
+		Code:           "InvalidArgument",
+		Description:    "Bucket quotas should be set for an existing bucket, in bytes greater than zero, e.g. 10GiB.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrServerNotReady: {
+		Code:           "ServiceUnavailable",
+		Description:    "Server is not ready, write quorum of disks is not online.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrInvalidConfigDocument: {
+		Code:           "InvalidConfigDocument",
+		Description:    "The config document is malformed, of another config version or has invalid values.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidUploadIDMarker: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid uploadId marker, it must be a valid upload id along with a key marker of an object.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidCopyPartRange: {
+		Code:           "InvalidArgument",
+		Description:    "The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrPreconditionFailed: {
+		Code:           "PreconditionFailed",
+		Description:    "At least one of the pre-conditions you specified did not hold",
+		HTTPStatusCode: http.StatusPreconditionFailed,
+	},
+	ErrMetadataTooLarge: {
+		Code:           "MetadataTooLarge",
+		Description:    "Your metadata headers exceed the maximum allowed metadata size.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidMetadataDirective: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown metadata directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrPostPolicyExtraInputFields: {
+		Code:           "AccessDenied",
+		Description:    "Invalid according to Policy: Extra input fields",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrPostPolicyConditionFailed: {
+		Code:           "AccessDenied",
+		Description:    "Invalid according to Policy: Policy Condition failed",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrNoSuchCORSConfiguration: {
+		Code:           "NoSuchCORSConfiguration",
+		Description:    "The CORS configuration does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrCORSNotEnabled: {
+		Code:           "AccessForbidden",
+		Description:    "CORSResponse: CORS is not enabled for this bucket.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrCORSForbidden: {
+		Code:           "AccessForbidden",
+		Description:    "CORSResponse: This CORS request is not allowed. This is usually because the evalution of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrInvalidCORSRequestMethod: {
+		Code:           "BadRequest",
+		Description:    "Invalid Access-Control-Request-Method.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidExpressionType: {
+		Code:           "InvalidExpressionType",
+		Description:    "The ExpressionType is invalid. Only SQL expressions are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidSelectExpression: {
+		Code:           "ParseUnsupportedSyntax",
+		Description:    "The SQL expression contains unsupported syntax.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidCompressionFormat: {
+		Code:           "InvalidCompressionFormat",
+		Description:    "The file is not in a supported compression format. Only GZIP and BZIP2 are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidDataSource: {
+		Code:           "InvalidDataSource",
+		Description:    "Invalid data source type. Only CSV and JSON are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidFileHeaderInfo: {
+		Code:           "InvalidFileHeaderInfo",
+		Description:    "The FileHeaderInfo is invalid. Only NONE, USE, and IGNORE are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidJSONType: {
+		Code:           "InvalidJsonType",
+		Description:    "The JsonType is invalid. Only DOCUMENT and LINES are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidQuoteFields: {
+		Code:           "InvalidQuoteFields",
+		Description:    "The QuoteFields is invalid. Only ALWAYS and ASNEEDED are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidRequestParameter: {
+		Code:           "InvalidRequestParameter",
+		Description:    "The value of a parameter in SelectRequest element is invalid. Check the service API documentation and try again.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrOperationTimedOut: {
+		Code:           "OperationTimedOut",
+		Description:    "A timeout occurred while trying to lock a resource, please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrInvalidChecksum: {
+		Code:           "InvalidRequest",
+		Description:    "Expecting a single valid x-amz-checksum header, sent as a header or a trailer of a chunked body.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrBadChecksum: {
+		Code:           "BadDigest",
+		Description:    "The checksum you specified did not match what we received.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	// Add your error structure here.
 }
 
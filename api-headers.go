@@ -73,9 +73,26 @@ func setObjectHeaders(w http.ResponseWriter, objInfo ObjectInfo, contentRange *h
 
 	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
 
+	// set user defined metadata
+	for key, value := range objInfo.UserDefined {
+		w.Header().Set(key, value)
+	}
+
+	if objInfo.StorageClass != "" && objInfo.StorageClass != storageClassStandard {
+		w.Header().Set(amzStorageClassHeader, objInfo.StorageClass)
+	}
+
+	if objInfo.ReplicationStatus != "" {
+		w.Header().Set(replicationStatusKey, objInfo.ReplicationStatus)
+	}
+
 	// for providing ranged content
 	if contentRange != nil {
 		if contentRange.start > 0 || contentRange.length > 0 {
+			// Checksums are of the whole object, not of ranges.
+			for key := range objectChecksumHashes {
+				w.Header().Del(key)
+			}
 			// override content-length
 			w.Header().Set("Content-Length", strconv.FormatInt(contentRange.length, 10))
 			w.Header().Set("Content-Range", contentRange.String())
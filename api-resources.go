@@ -19,6 +19,7 @@ package main
 import (
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 // Parse bucket url queries
@@ -68,3 +69,36 @@ func getUploadID(values url.Values) (uploadID string) {
 	uploadID, _, _, _ = getObjectResources(values)
 	return
 }
+
+// Keys of listings are percent-encoded if requested with this
+// encoding-type, so that keys with characters XML cannot carry are
+// listed.
+const encodingTypeURL = "url"
+
+// isValidEncodingType - returns true if encodingType is empty or url.
+func isValidEncodingType(encodingType string) bool {
+	return encodingType == "" || strings.EqualFold(encodingType, encodingTypeURL)
+}
+
+// s3EncodeName - returns name percent-encoded if encodingType is url,
+// as is otherwise. Unreserved characters and '/' are kept, spaces are
+// encoded as '+', as clients decode keys as form values.
+func s3EncodeName(name, encodingType string) string {
+	if !strings.EqualFold(encodingType, encodingTypeURL) {
+		return name
+	}
+	encoded := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			encoded = append(encoded, c)
+		case c == ' ':
+			encoded = append(encoded, '+')
+		default:
+			encoded = append(encoded, '%', "0123456789ABCDEF"[c>>4], "0123456789ABCDEF"[c&15])
+		}
+	}
+	return string(encoded)
+}
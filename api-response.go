@@ -156,6 +156,10 @@ type Object struct {
 
 	// The class of storage used to store the object.
 	StorageClass string
+
+	// Orders mutations of the key, same as the sequencer of its
+	// event notifications.
+	Sequencer string `xml:",omitempty"`
 }
 
 // CopyObjectResponse container returns ETag and LastModified of the
@@ -166,6 +170,24 @@ type CopyObjectResponse struct {
 	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
 }
 
+// CopyObjectPartResponse container returns ETag and LastModified of the
+// successfully copied part
+type CopyObjectPartResponse struct {
+	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyPartResult" json:"-"`
+	ETag         string
+	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
+}
+
+// PostResponse container returns location, bucket, key and ETag of the
+// object uploaded by a POST form
+type PostResponse struct {
+	XMLName  xml.Name `xml:"PostResponse" json:"-"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
 // Initiator inherit from Owner struct, fields are same
 type Initiator Owner
 
@@ -212,6 +234,17 @@ type DeleteObjectsResponse struct {
 	Errors []DeleteError `xml:"Error,omitempty"`
 }
 
+// ExtractArchiveResponse container for extracted archive response.
+type ExtractArchiveResponse struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ExtractArchiveResult" json:"-"`
+
+	Bucket string
+	Prefix string
+	// Number and total size of extracted objects.
+	Objects int
+	Size    int64
+}
+
 // getLocation get URL location.
 func getLocation(r *http.Request) string {
 	return r.URL.Path
@@ -245,7 +278,8 @@ func generateListBucketsResponse(buckets []BucketInfo) ListBucketsResponse {
 }
 
 // generates an ListObjects response for the said bucket with other enumerated options.
-func generateListObjectsResponse(bucket, prefix, marker, delimiter string, maxKeys int, resp ListObjectsInfo) ListObjectsResponse {
+// Keys are percent-encoded if encodingType is url.
+func generateListObjectsResponse(bucket, prefix, marker, delimiter, encodingType string, maxKeys int, resp ListObjectsInfo) ListObjectsResponse {
 	var contents []Object
 	var prefixes []CommonPrefix
 	var owner = Owner{}
@@ -259,30 +293,33 @@ func generateListObjectsResponse(bucket, prefix, marker, delimiter string, maxKe
 		if object.Name == "" {
 			continue
 		}
-		content.Key = object.Name
+		content.Key = s3EncodeName(object.Name, encodingType)
 		content.LastModified = object.ModTime.UTC().Format(timeFormatAMZ)
 		if object.MD5Sum != "" {
 			content.ETag = "\"" + object.MD5Sum + "\""
 		}
 		content.Size = object.Size
-		content.StorageClass = "STANDARD"
+		content.StorageClass = object.StorageClass
 		content.Owner = owner
+		if object.Sequence != 0 {
+			content.Sequencer = formatSequencer(object.Sequence)
+		}
 		contents = append(contents, content)
 	}
-	// TODO - support EncodingType in xml decoding
 	data.Name = bucket
 	data.Contents = contents
 
-	data.Prefix = prefix
-	data.Marker = marker
-	data.Delimiter = delimiter
+	data.EncodingType = encodingType
+	data.Prefix = s3EncodeName(prefix, encodingType)
+	data.Marker = s3EncodeName(marker, encodingType)
+	data.Delimiter = s3EncodeName(delimiter, encodingType)
 	data.MaxKeys = maxKeys
 
-	data.NextMarker = resp.NextMarker
+	data.NextMarker = s3EncodeName(resp.NextMarker, encodingType)
 	data.IsTruncated = resp.IsTruncated
 	for _, prefix := range resp.Prefixes {
 		var prefixItem = CommonPrefix{}
-		prefixItem.Prefix = prefix
+		prefixItem.Prefix = s3EncodeName(prefix, encodingType)
 		prefixes = append(prefixes, prefixItem)
 	}
 	data.CommonPrefixes = prefixes
@@ -297,6 +334,24 @@ func generateCopyObjectResponse(etag string, lastModified time.Time) CopyObjectR
 	}
 }
 
+// generateCopyObjectPartResponse
+func generateCopyObjectPartResponse(etag string, lastModified time.Time) CopyObjectPartResponse {
+	return CopyObjectPartResponse{
+		ETag:         "\"" + etag + "\"",
+		LastModified: lastModified.UTC().Format(timeFormatAMZ),
+	}
+}
+
+// generatePostResponse
+func generatePostResponse(location, bucket, key, etag string) PostResponse {
+	return PostResponse{
+		Location: location,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     "\"" + etag + "\"",
+	}
+}
+
 // generateInitiateMultipartUploadResponse
 func generateInitiateMultipartUploadResponse(bucket, key, uploadID string) InitiateMultipartUploadResponse {
 	return InitiateMultipartUploadResponse{
@@ -326,6 +381,10 @@ func generateListPartsResponse(partsInfo ListPartsInfo) ListPartsResponse {
 	listPartsResponse.StorageClass = "STANDARD"
 	listPartsResponse.Initiator.ID = "minio"
 	listPartsResponse.Initiator.DisplayName = "minio"
+	if partsInfo.Initiator != "" {
+		listPartsResponse.Initiator.ID = partsInfo.Initiator
+		listPartsResponse.Initiator.DisplayName = partsInfo.Initiator
+	}
 	listPartsResponse.Owner.ID = "minio"
 	listPartsResponse.Owner.DisplayName = "minio"
 
@@ -350,26 +409,36 @@ func generateListPartsResponse(partsInfo ListPartsInfo) ListPartsResponse {
 func generateListMultipartUploadsResponse(bucket string, multipartsInfo ListMultipartsInfo) ListMultipartUploadsResponse {
 	listMultipartUploadsResponse := ListMultipartUploadsResponse{}
 	listMultipartUploadsResponse.Bucket = bucket
-	listMultipartUploadsResponse.Delimiter = multipartsInfo.Delimiter
+	encodingType := multipartsInfo.EncodingType
+	listMultipartUploadsResponse.Delimiter = s3EncodeName(multipartsInfo.Delimiter, encodingType)
 	listMultipartUploadsResponse.IsTruncated = multipartsInfo.IsTruncated
-	listMultipartUploadsResponse.EncodingType = multipartsInfo.EncodingType
-	listMultipartUploadsResponse.Prefix = multipartsInfo.Prefix
-	listMultipartUploadsResponse.KeyMarker = multipartsInfo.KeyMarker
-	listMultipartUploadsResponse.NextKeyMarker = multipartsInfo.NextKeyMarker
+	listMultipartUploadsResponse.EncodingType = encodingType
+	listMultipartUploadsResponse.Prefix = s3EncodeName(multipartsInfo.Prefix, encodingType)
+	listMultipartUploadsResponse.KeyMarker = s3EncodeName(multipartsInfo.KeyMarker, encodingType)
+	listMultipartUploadsResponse.NextKeyMarker = s3EncodeName(multipartsInfo.NextKeyMarker, encodingType)
 	listMultipartUploadsResponse.MaxUploads = multipartsInfo.MaxUploads
 	listMultipartUploadsResponse.NextUploadIDMarker = multipartsInfo.NextUploadIDMarker
 	listMultipartUploadsResponse.UploadIDMarker = multipartsInfo.UploadIDMarker
 	listMultipartUploadsResponse.CommonPrefixes = make([]CommonPrefix, len(multipartsInfo.CommonPrefixes))
 	for index, commonPrefix := range multipartsInfo.CommonPrefixes {
 		listMultipartUploadsResponse.CommonPrefixes[index] = CommonPrefix{
-			Prefix: commonPrefix,
+			Prefix: s3EncodeName(commonPrefix, encodingType),
 		}
 	}
 	listMultipartUploadsResponse.Uploads = make([]Upload, len(multipartsInfo.Uploads))
 	for index, upload := range multipartsInfo.Uploads {
 		newUpload := Upload{}
 		newUpload.UploadID = upload.UploadID
-		newUpload.Key = upload.Object
+		newUpload.Key = s3EncodeName(upload.Object, encodingType)
+		newUpload.StorageClass = "STANDARD"
+		newUpload.Initiator.ID = "minio"
+		newUpload.Initiator.DisplayName = "minio"
+		if upload.Initiator != "" {
+			newUpload.Initiator.ID = upload.Initiator
+			newUpload.Initiator.DisplayName = upload.Initiator
+		}
+		newUpload.Owner.ID = "minio"
+		newUpload.Owner.DisplayName = "minio"
 		newUpload.Initiated = upload.Initiated.UTC().Format(timeFormatAMZ)
 		listMultipartUploadsResponse.Uploads[index] = newUpload
 	}
@@ -420,3 +489,16 @@ func writeErrorResponse(w http.ResponseWriter, req *http.Request, errorCode APIE
 		w.(http.Flusher).Flush()
 	}
 }
+
+// generateExtractArchiveResponse - generates response for extracted archive.
+func generateExtractArchiveResponse(bucket, prefix string, objects []extractedObject) ExtractArchiveResponse {
+	resp := ExtractArchiveResponse{
+		Bucket:  bucket,
+		Prefix:  prefix,
+		Objects: len(objects),
+	}
+	for _, object := range objects {
+		resp.Size += object.Size
+	}
+	return resp
+}
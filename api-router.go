@@ -23,7 +23,8 @@ type objectAPIHandlers struct {
 	ObjectAPI objectAPI
 }
 
-// registerAPIRouter - registers S3 compatible APIs.
+// registerAPIRouter - registers S3 compatible APIs. Routes are named
+// after the S3 action they serve, names are recorded in the audit log.
 func registerAPIRouter(mux *router.Router, api objectAPIHandlers) {
 	// API Router
 	apiRouter := mux.NewRoute().PathPrefix("/").Subrouter()
@@ -34,53 +35,107 @@ func registerAPIRouter(mux *router.Router, api objectAPIHandlers) {
 	/// Object operations
 
 	// HeadObject
-	bucket.Methods("HEAD").Path("/{object:.+}").HandlerFunc(api.HeadObjectHandler)
+	bucket.Methods("HEAD").Path("/{object:.+}").HandlerFunc(api.HeadObjectHandler).Name("HeadObject")
+	// GetObjectAttestation
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectAttestationHandler).Queries("attestation", "").Name("GetObjectAttestation")
+	// PutObjectTagging
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectTaggingHandler).Queries("tagging", "").Name("PutObjectTagging")
+	// GetObjectTagging
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectTaggingHandler).Queries("tagging", "").Name("GetObjectTagging")
+	// DeleteObjectTagging
+	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectTaggingHandler).Queries("tagging", "").Name("DeleteObjectTagging")
+	// PutObjectRetention
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectRetentionHandler).Queries("retention", "").Name("PutObjectRetention")
+	// GetObjectRetention
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectRetentionHandler).Queries("retention", "").Name("GetObjectRetention")
+	// PutObjectLegalHold
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectLegalHoldHandler).Queries("legal-hold", "").Name("PutObjectLegalHold")
+	// GetObjectLegalHold
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectLegalHoldHandler).Queries("legal-hold", "").Name("GetObjectLegalHold")
+	// GetObjectPartMap
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectPartMapHandler).Queries("partmap", "").Name("GetObjectPartMap")
+	// CopyObjectPart
+	bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/).*?").HandlerFunc(api.CopyObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}").Name("CopyObjectPart")
 	// PutObjectPart
-	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}")
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}").Name("PutObjectPart")
 	// ListObjectPxarts
-	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.ListObjectPartsHandler).Queries("uploadId", "{uploadId:.*}")
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.ListObjectPartsHandler).Queries("uploadId", "{uploadId:.*}").Name("ListObjectParts")
 	// CompleteMultipartUpload
-	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.CompleteMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}")
+	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.CompleteMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}").Name("CompleteMultipartUpload")
 	// NewMultipartUpload
-	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.NewMultipartUploadHandler).Queries("uploads", "")
+	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.NewMultipartUploadHandler).Queries("uploads", "").Name("NewMultipartUpload")
+	// SelectObjectContent
+	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.SelectObjectContentHandler).Queries("select", "", "select-type", "2").Name("SelectObjectContent")
+	// RestoreObject
+	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.RestoreObjectHandler).Queries("restore", "").Name("RestoreObject")
 	// AbortMultipartUpload
-	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.AbortMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}")
+	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.AbortMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}").Name("AbortMultipartUpload")
 	// GetObject
-	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectHandler)
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectHandler).Name("GetObject")
 	// CopyObject
-	bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/).*?").HandlerFunc(api.CopyObjectHandler)
+	bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/).*?").HandlerFunc(api.CopyObjectHandler).Name("CopyObject")
+	// ExtractArchive
+	bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp(extractArchiveHeader, "(?i)^true$").HandlerFunc(api.ExtractArchiveHandler).Name("ExtractArchive")
 	// PutObject
-	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectHandler)
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectHandler).Name("PutObject")
 	// DeleteObject
-	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectHandler)
+	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectHandler).Name("DeleteObject")
 
 	/// Bucket operations
 
 	// GetBucketLocation
-	bucket.Methods("GET").HandlerFunc(api.GetBucketLocationHandler).Queries("location", "")
+	bucket.Methods("GET").HandlerFunc(api.GetBucketLocationHandler).Queries("location", "").Name("GetBucketLocation")
 	// GetBucketPolicy
-	bucket.Methods("GET").HandlerFunc(api.GetBucketPolicyHandler).Queries("policy", "")
+	bucket.Methods("GET").HandlerFunc(api.GetBucketPolicyHandler).Queries("policy", "").Name("GetBucketPolicy")
+	// GetBucketLifecycle
+	bucket.Methods("GET").HandlerFunc(api.GetBucketLifecycleHandler).Queries("lifecycle", "").Name("GetBucketLifecycle")
+	// GetBucketObjectLockConfig
+	bucket.Methods("GET").HandlerFunc(api.GetBucketObjectLockConfigHandler).Queries("object-lock", "").Name("GetBucketObjectLockConfig")
+	// GetBucketReplication
+	bucket.Methods("GET").HandlerFunc(api.GetBucketReplicationHandler).Queries("replication", "").Name("GetBucketReplication")
+	// GetBucketLogging
+	bucket.Methods("GET").HandlerFunc(api.GetBucketLoggingHandler).Queries("logging", "").Name("GetBucketLogging")
+	// GetBucketCors
+	bucket.Methods("GET").HandlerFunc(api.GetBucketCorsHandler).Queries("cors", "").Name("GetBucketCors")
+	// ListenBucketNotification
+	bucket.Methods("GET").HandlerFunc(api.ListenBucketNotificationHandler).Queries("events", "{events:.*}").Name("ListenBucketNotification")
 	// ListMultipartUploads
-	bucket.Methods("GET").HandlerFunc(api.ListMultipartUploadsHandler).Queries("uploads", "")
+	bucket.Methods("GET").HandlerFunc(api.ListMultipartUploadsHandler).Queries("uploads", "").Name("ListMultipartUploads")
 	// ListObjects
-	bucket.Methods("GET").HandlerFunc(api.ListObjectsHandler)
+	bucket.Methods("GET").HandlerFunc(api.ListObjectsHandler).Name("ListObjects")
 	// PutBucketPolicy
-	bucket.Methods("PUT").HandlerFunc(api.PutBucketPolicyHandler).Queries("policy", "")
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketPolicyHandler).Queries("policy", "").Name("PutBucketPolicy")
+	// PutBucketLifecycle
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketLifecycleHandler).Queries("lifecycle", "").Name("PutBucketLifecycle")
+	// PutBucketObjectLockConfig
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketObjectLockConfigHandler).Queries("object-lock", "").Name("PutBucketObjectLockConfig")
+	// PutBucketReplication
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketReplicationHandler).Queries("replication", "").Name("PutBucketReplication")
+	// PutBucketLogging
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketLoggingHandler).Queries("logging", "").Name("PutBucketLogging")
+	// PutBucketCors
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketCorsHandler).Queries("cors", "").Name("PutBucketCors")
 	// PutBucket
-	bucket.Methods("PUT").HandlerFunc(api.PutBucketHandler)
+	bucket.Methods("PUT").HandlerFunc(api.PutBucketHandler).Name("PutBucket")
 	// HeadBucket
-	bucket.Methods("HEAD").HandlerFunc(api.HeadBucketHandler)
+	bucket.Methods("HEAD").HandlerFunc(api.HeadBucketHandler).Name("HeadBucket")
 	// PostPolicy
-	bucket.Methods("POST").HeadersRegexp("Content-Type", "multipart/form-data*").HandlerFunc(api.PostPolicyBucketHandler)
+	bucket.Methods("POST").HeadersRegexp("Content-Type", "multipart/form-data*").HandlerFunc(api.PostPolicyBucketHandler).Name("PostPolicyBucket")
 	// DeleteMultipleObjects
-	bucket.Methods("POST").HandlerFunc(api.DeleteMultipleObjectsHandler)
+	bucket.Methods("POST").HandlerFunc(api.DeleteMultipleObjectsHandler).Name("DeleteMultipleObjects")
 	// DeleteBucketPolicy
-	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketPolicyHandler).Queries("policy", "")
+	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketPolicyHandler).Queries("policy", "").Name("DeleteBucketPolicy")
+	// DeleteBucketLifecycle
+	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketLifecycleHandler).Queries("lifecycle", "").Name("DeleteBucketLifecycle")
+	// DeleteBucketReplication
+	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketReplicationHandler).Queries("replication", "").Name("DeleteBucketReplication")
+	// DeleteBucketCors
+	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketCorsHandler).Queries("cors", "").Name("DeleteBucketCors")
 	// DeleteBucket
-	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketHandler)
+	bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketHandler).Name("DeleteBucket")
 
 	/// Root operation
 
 	// ListBuckets
-	apiRouter.Methods("GET").HandlerFunc(api.ListBucketsHandler)
+	apiRouter.Methods("GET").HandlerFunc(api.ListBucketsHandler).Name("ListBuckets")
 }
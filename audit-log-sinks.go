@@ -0,0 +1,156 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Timeout of a single audit webhook request.
+const auditWebhookTimeout = 10 * time.Second
+
+// Suffix format of rotated audit log files, sorts by rotation time.
+const auditRotateTimeFormat = "20060102T150405.000000000"
+
+// auditFileSink - writes entries as JSON lines to a file, rotating it
+// once it grows beyond the configured size.
+type auditFileSink struct {
+	config auditFile
+	file   *os.File
+	size   int64
+}
+
+// newAuditFileSink - opens the audit log file for appending.
+func newAuditFileSink(config auditFile) (*auditFileSink, error) {
+	sink := &auditFileSink{config: config}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// open - opens the audit log file, size is that of existing entries.
+func (s *auditFileSink) open() error {
+	file, err := os.OpenFile(s.config.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	st, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = st.Size()
+	return nil
+}
+
+// Send - appends entry to the file.
+func (s *auditFileSink) Send(entry auditEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	entryBytes = append(entryBytes, '\n')
+	if s.config.MaxSize > 0 && s.size > 0 && s.size+int64(len(entryBytes)) > s.config.MaxSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(entryBytes)
+	s.size += int64(n)
+	return err
+}
+
+// rotate - renames the file with its rotation time as suffix, starts a
+// new file and removes the oldest rotated files beyond MaxBackups.
+func (s *auditFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	rotatedName := s.config.Filename + "." + time.Now().UTC().Format(auditRotateTimeFormat)
+	if err := os.Rename(s.config.Filename, rotatedName); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	if s.config.MaxBackups <= 0 {
+		return nil
+	}
+	rotatedNames, err := filepath.Glob(s.config.Filename + ".*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(rotatedNames)
+	for len(rotatedNames) > s.config.MaxBackups {
+		if err = os.Remove(rotatedNames[0]); err != nil {
+			return err
+		}
+		rotatedNames = rotatedNames[1:]
+	}
+	return nil
+}
+
+// Close - closes the file.
+func (s *auditFileSink) Close() error {
+	return s.file.Close()
+}
+
+// auditWebhookSink - posts entries as JSON to an HTTP endpoint.
+type auditWebhookSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// newAuditWebhookSink - returns sink posting to the configured
+// endpoint.
+func newAuditWebhookSink(config auditWebhook) *auditWebhookSink {
+	return &auditWebhookSink{
+		endpoint: config.Endpoint,
+		client:   &http.Client{Timeout: auditWebhookTimeout},
+	}
+}
+
+// Send - posts entry to the endpoint.
+func (s *auditWebhookSink) Send(entry auditEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewReader(entryBytes))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Audit webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Close - nothing to release.
+func (s *auditWebhookSink) Close() error {
+	return nil
+}
@@ -0,0 +1,270 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	router "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Maximum number of entries queued for a sink.
+	maxAuditQueueSize = 10000
+
+	// Default size in bytes audit log file is rotated at.
+	defaultAuditMaxSize = 100 * 1024 * 1024
+
+	// Default number of rotated audit log files kept.
+	defaultAuditMaxBackups = 10
+
+	// Requester of anonymous requests.
+	auditAnonymousRequester = "anonymous"
+)
+
+// errAuditQueueFull - audit sink is not keeping up.
+var errAuditQueueFull = errors.New("Audit sink queue is full, entry dropped")
+
+// auditFile - audit log file sink configuration.
+type auditFile struct {
+	Enable   bool   `json:"enable"`
+	Filename string `json:"fileName"`
+	// File is rotated once it grows beyond this size in bytes, zero
+	// disables rotation.
+	MaxSize int64 `json:"maxSize"`
+	// Number of rotated files kept, zero keeps all.
+	MaxBackups int `json:"maxBackups"`
+}
+
+// auditWebhook - audit log webhook sink configuration, entries are
+// posted as JSON to the endpoint.
+type auditWebhook struct {
+	Enable   bool   `json:"enable"`
+	Endpoint string `json:"endpoint"`
+}
+
+// auditConfig - audit log configuration.
+type auditConfig struct {
+	File    auditFile    `json:"file"`
+	Webhook auditWebhook `json:"webhook"`
+}
+
+// newAuditConfig - audit log configuration for fresh and migrated
+// configs.
+func newAuditConfig() auditConfig {
+	return auditConfig{
+		File: auditFile{
+			Filename:   "audit.log",
+			MaxSize:    defaultAuditMaxSize,
+			MaxBackups: defaultAuditMaxBackups,
+		},
+	}
+}
+
+// auditEntry - audit record of a single S3 request.
+type auditEntry struct {
+	Time time.Time `json:"time"`
+	// Access key of signed requests, anonymous otherwise.
+	Requester  string `json:"requester"`
+	RemoteAddr string `json:"remoteAddr"`
+	Action     string `json:"action"`
+	Bucket     string `json:"bucket,omitempty"`
+	Object     string `json:"object,omitempty"`
+	StatusCode int    `json:"statusCode"`
+	BytesIn    int64  `json:"bytesIn"`
+	BytesOut   int64  `json:"bytesOut"`
+	// Time taken to serve the request in nanoseconds.
+	Latency time.Duration `json:"latency"`
+}
+
+// auditSink - a destination audit entries are written to.
+type auditSink interface {
+	Send(entry auditEntry) error
+	// Close releases all resources held by the sink.
+	Close() error
+}
+
+// queuedAuditSink - sink along with its queue, a single routine drains
+// the queue so that slow sinks do not delay requests.
+type queuedAuditSink struct {
+	name  string
+	sink  auditSink
+	queue chan auditEntry
+}
+
+func newQueuedAuditSink(name string, sink auditSink) *queuedAuditSink {
+	qs := &queuedAuditSink{
+		name:  name,
+		sink:  sink,
+		queue: make(chan auditEntry, maxAuditQueueSize),
+	}
+	go func() {
+		for entry := range qs.queue {
+			if e := qs.sink.Send(entry); e != nil {
+				errorIf(probe.NewError(e), "Unable to write audit entry.", logrus.Fields{
+					"sink": name,
+				})
+			}
+		}
+		qs.sink.Close()
+	}()
+	return qs
+}
+
+// auditLogger - writes audit entries to all configured sinks.
+type auditLogger struct {
+	sinks []*queuedAuditSink
+}
+
+// Global audit logger, initialized at server startup.
+var globalAuditLogger *auditLogger
+
+// newAuditLogger - returns logger writing to sinks, keyed by name.
+func newAuditLogger(sinks map[string]auditSink) *auditLogger {
+	logger := &auditLogger{}
+	for name, sink := range sinks {
+		logger.sinks = append(logger.sinks, newQueuedAuditSink(name, sink))
+	}
+	return logger
+}
+
+// initAuditLog - initializes all enabled audit sinks from server
+// config.
+func initAuditLog() error {
+	config := serverConfig.GetAudit()
+	sinks := make(map[string]auditSink)
+	if config.File.Enable {
+		sink, e := newAuditFileSink(config.File)
+		if e != nil {
+			return fmt.Errorf("Unable to initialize audit log file ‘%s’: %s", config.File.Filename, e)
+		}
+		sinks["file"] = sink
+	}
+	if config.Webhook.Enable {
+		sinks["webhook"] = newAuditWebhookSink(config.Webhook)
+	}
+	globalAuditLogger = newAuditLogger(sinks)
+	return nil
+}
+
+// IsActive - returns true if at least one sink is configured.
+func (l *auditLogger) IsActive() bool {
+	return l != nil && len(l.sinks) > 0
+}
+
+// Log - queues entry for all sinks, entries are dropped for sinks
+// which are not keeping up.
+func (l *auditLogger) Log(entry auditEntry) {
+	for _, qs := range l.sinks {
+		select {
+		case qs.queue <- entry:
+		default:
+			errorIf(probe.NewError(errAuditQueueFull), "Unable to queue audit entry.", logrus.Fields{
+				"sink": qs.name,
+			})
+		}
+	}
+}
+
+// Close - closes all sinks once their queued entries are written.
+func (l *auditLogger) Close() {
+	for _, qs := range l.sinks {
+		close(qs.queue)
+	}
+}
+
+// getAuditRequester - returns access key the request claims to be
+// signed with, requests failing authentication are audited too.
+func getAuditRequester(r *http.Request) string {
+	switch getRequestAuthType(r) {
+	case authTypeSigned:
+		if signV4Values, s3Error := parseSignV4(r.Header.Get("Authorization")); s3Error == ErrNone {
+			return signV4Values.Credential.accessKey
+		}
+	case authTypePresigned:
+		if preSignV4Values, s3Error := parsePreSignV4(r.URL.Query()); s3Error == ErrNone {
+			return preSignV4Values.Credential.accessKey
+		}
+	case authTypeAnonymous:
+		return auditAnonymousRequester
+	}
+	return ""
+}
+
+// auditReadCounter - counts bytes read from the request body.
+type auditReadCounter struct {
+	io.ReadCloser
+	count int64
+}
+
+func (c *auditReadCounter) Read(p []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// auditLogHandler - records every request routed to a S3 API handler
+// in the audit log, after it is served.
+type auditLogHandler struct {
+	handler http.Handler
+	mux     *router.Router
+}
+
+// setAuditLogHandler - returns handler function auditing requests
+// matching named routes of mux.
+func setAuditLogHandler(mux *router.Router) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return auditLogHandler{handler: h, mux: mux}
+	}
+}
+
+func (h auditLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !globalAuditLogger.IsActive() {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	var match router.RouteMatch
+	if !h.mux.Match(r, &match) || match.Route.GetName() == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	startTime := time.Now().UTC()
+	body := &auditReadCounter{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	aw := newStatusResponseWriter(w)
+	h.handler.ServeHTTP(aw, r)
+	globalAuditLogger.Log(auditEntry{
+		Time:       startTime,
+		Requester:  getAuditRequester(r),
+		RemoteAddr: r.RemoteAddr,
+		Action:     match.Route.GetName(),
+		Bucket:     match.Vars["bucket"],
+		Object:     match.Vars["object"],
+		StatusCode: aw.statusCode,
+		BytesIn:    body.count,
+		BytesOut:   aw.count,
+		Latency:    time.Now().UTC().Sub(startTime),
+	})
+}
@@ -0,0 +1,142 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	router "github.com/gorilla/mux"
+)
+
+// auditRecorder - audit sink recording entries on a channel.
+type auditRecorder chan auditEntry
+
+func (r auditRecorder) Send(entry auditEntry) error {
+	r <- entry
+	return nil
+}
+
+func (r auditRecorder) Close() error {
+	return nil
+}
+
+// Tests requests to named routes are audited.
+func TestAuditLogHandler(t *testing.T) {
+	recorder := make(auditRecorder, 10)
+	savedLogger := globalAuditLogger
+	globalAuditLogger = newAuditLogger(map[string]auditSink{"recorder": recorder})
+	defer func() {
+		globalAuditLogger.Close()
+		globalAuditLogger = savedLogger
+	}()
+
+	mux := router.NewRouter()
+	mux.Methods("PUT").Path("/{bucket}/{object:.+}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}).Name("PutObject")
+	mux.Methods("GET").Path("/unnamed").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := setAuditLogHandler(mux)(mux)
+
+	req, e := http.NewRequest("GET", "/unnamed", nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	req, e = http.NewRequest("PUT", "/bucket/dir/object", strings.NewReader("some data"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	req.RemoteAddr = "10.0.0.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := <-recorder
+	if entry.Action != "PutObject" || entry.Bucket != "bucket" || entry.Object != "dir/object" {
+		t.Fatalf("Unexpected entry %+v", entry)
+	}
+	if entry.Requester != auditAnonymousRequester || entry.RemoteAddr != "10.0.0.1:1234" {
+		t.Fatalf("Unexpected requester %+v", entry)
+	}
+	if entry.StatusCode != http.StatusCreated || entry.BytesIn != 9 || entry.BytesOut != 5 {
+		t.Fatalf("Unexpected response %+v", entry)
+	}
+}
+
+// Tests audit log files are rotated and old ones removed.
+func TestAuditFileSinkRotation(t *testing.T) {
+	dir, e := ioutil.TempDir("", "minio-audit-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "audit.log")
+	sink, e := newAuditFileSink(auditFile{
+		Enable:     true,
+		Filename:   filename,
+		MaxSize:    1024,
+		MaxBackups: 2,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	for i := 0; i < 50; i++ {
+		if e = sink.Send(auditEntry{Action: "GetObject", Bucket: "bucket", Object: "object"}); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = sink.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	rotatedNames, e := filepath.Glob(filename + ".*")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(rotatedNames) != 2 {
+		t.Fatalf("Expected 2 rotated files, got %v", rotatedNames)
+	}
+	for _, name := range append(rotatedNames, filename) {
+		st, e := os.Stat(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if st.Size() > 1024 {
+			t.Fatalf("File %s grew beyond max size to %d", name, st.Size())
+		}
+		file, e := os.Open(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry auditEntry
+			if e = json.Unmarshal(scanner.Bytes(), &entry); e != nil || entry.Action != "GetObject" {
+				t.Fatalf("Unexpected entry %s, %v", scanner.Text(), e)
+			}
+		}
+		file.Close()
+	}
+}
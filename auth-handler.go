@@ -133,7 +133,22 @@ func isReqAuthenticated(r *http.Request) (s3Error APIErrorCode) {
 	if isRequestSignatureV4(r) {
 		return doesSignatureMatch(hex.EncodeToString(sum256(payload)), r, validateRegion)
 	} else if isRequestPresignedSignatureV4(r) {
-		return doesPresignedSignatureMatch(hex.EncodeToString(sum256(payload)), r, validateRegion)
+		s3Error := doesPresignedSignatureMatch(hex.EncodeToString(sum256(payload)), r, validateRegion)
+		recordPresignedRequest(r, s3Error)
+		return s3Error
+	}
+	return ErrAccessDenied
+}
+
+// isReqHeaderAuthenticated - verifies the signature of r against the
+// payload hash r claims, without reading its payload, which needs to be
+// verified as it is read.
+func isReqHeaderAuthenticated(r *http.Request) (s3Error APIErrorCode) {
+	validateRegion := true // Validate region.
+	if isRequestSignatureV4(r) {
+		return doesSignatureMatch(r.Header.Get("X-Amz-Content-Sha256"), r, validateRegion)
+	} else if isRequestPresignedSignatureV4(r) {
+		return doesPresignedSignatureMatch(r.URL.Query().Get("X-Amz-Content-Sha256"), r, validateRegion)
 	}
 	return ErrAccessDenied
 }
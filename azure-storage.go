@@ -0,0 +1,745 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	// Version of the Blob service REST API requests are made with.
+	azureAPIVersion = "2019-12-12"
+
+	// minioMetaVolume is not a valid container name, its files are
+	// kept in this container instead.
+	azureMetaContainer = "minio-meta"
+
+	// Files are uploaded in blocks of this size, committed as a
+	// block blob once they are closed.
+	azureBlockSize = 4 * 1024 * 1024
+
+	// Maximum number of blobs listed in a single request.
+	azureListLimit = 1000
+
+	// Maximum number of listings continued from their last marker.
+	azureMaxListMarkers = 1024
+)
+
+var errAzureNoAccountKey = errors.New("Azure account key should be set with MINIO_AZURE_ACCOUNT_KEY")
+
+// azureListParams - listing continued from the last name it returned.
+type azureListParams struct {
+	container string
+	prefix    string
+	marker    string
+	recursive bool
+}
+
+// azureStorage - storage API of an Azure Blob Storage account,
+// volumes are containers and files are block blobs.
+type azureStorage struct {
+	endpoint   *url.URL
+	account    string
+	key        []byte
+	httpClient *http.Client
+	// Azure markers of listings, by the name they continue from.
+	listMutex   *sync.Mutex
+	listMarkers map[azureListParams]string
+}
+
+// newAzureStorage - initialize storage API of the account at
+// endpoint, for example https://myaccount.blob.core.windows.net.
+// Account name defaults to the first label of the endpoint host.
+func newAzureStorage(endpoint string) (StorageAPI, error) {
+	u, e := url.Parse(endpoint)
+	if e != nil {
+		return nil, e
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Azure endpoint %s should be a http or https URL", endpoint)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	account := os.Getenv("MINIO_AZURE_ACCOUNT_NAME")
+	if account == "" {
+		account = strings.SplitN(u.Hostname(), ".", 2)[0]
+	}
+	encodedKey := os.Getenv("MINIO_AZURE_ACCOUNT_KEY")
+	if encodedKey == "" {
+		return nil, errAzureNoAccountKey
+	}
+	key, e := base64.StdEncoding.DecodeString(encodedKey)
+	if e != nil {
+		return nil, fmt.Errorf("Azure account key should be base64 encoded: %s", e)
+	}
+	log.WithFields(logrus.Fields{
+		"endpoint": u.String(),
+		"account":  account,
+	}).Debugf("Successfully configured Azure storage API.")
+	return &azureStorage{
+		endpoint:    u,
+		account:     account,
+		key:         key,
+		httpClient:  &http.Client{},
+		listMutex:   &sync.Mutex{},
+		listMarkers: make(map[azureListParams]string),
+	}, nil
+}
+
+// isValidAzureContainer - returns true if name is a valid container
+// name, lowercase letters, digits and single hyphens.
+func isValidAzureContainer(name string) bool {
+	if len(name) < 3 || len(name) > 63 || strings.Contains(name, "--") {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// container - returns container of volume.
+func (s *azureStorage) container(volume string) (string, error) {
+	if volume == minioMetaVolume {
+		return azureMetaContainer, nil
+	}
+	if volume == azureMetaContainer || !isValidAzureContainer(volume) {
+		return "", errInvalidVolumeName
+	}
+	return volume, nil
+}
+
+// volume - returns volume of container.
+func (s *azureStorage) volume(container string) string {
+	if container == azureMetaContainer {
+		return minioMetaVolume
+	}
+	return container
+}
+
+// azureStringToSign - returns string signed by the Shared Key
+// authorization of req.
+func azureStringToSign(account string, req *http.Request) string {
+	contentLength := req.Header.Get("Content-Length")
+	if contentLength == "0" {
+		contentLength = ""
+	}
+	lines := []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}
+
+	// Canonicalized headers, all x-ms- headers sorted by name.
+	var msHeaders []string
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, name := range msHeaders {
+		lines = append(lines, name+":"+strings.TrimSpace(req.Header.Get(name)))
+	}
+
+	// Canonicalized resource, the path with query parameters sorted
+	// by name.
+	resource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var names []string
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+	return strings.Join(append(lines, resource), "\n")
+}
+
+// sign - sets Shared Key authorization of req.
+func (s *azureStorage) sign(req *http.Request) {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(azureStringToSign(s.account, req)))
+	req.Header.Set("Authorization", "SharedKey "+s.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// azureError - error response of the Blob service.
+type azureError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// toStorageError - returns storage error of a failed response.
+func (s *azureStorage) toStorageError(resp *http.Response) error {
+	// Error codes are also sent as a header, responses to HEAD
+	// requests have no body.
+	code := resp.Header.Get("x-ms-error-code")
+	apiErr := azureError{}
+	if body, e := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024)); e == nil && len(body) > 0 {
+		if xml.Unmarshal(body, &apiErr) == nil && code == "" {
+			code = apiErr.Code
+		}
+	}
+	switch code {
+	case "ContainerNotFound", "ContainerBeingDeleted":
+		return errVolumeNotFound
+	case "ContainerAlreadyExists":
+		return errVolumeExists
+	case "BlobNotFound", "InvalidBlockList":
+		// Block lists are invalid once their blocks are discarded.
+		return errFileNotFound
+	case "InvalidResourceName":
+		return errInvalidVolumeName
+	case "AuthenticationFailed", "AuthorizationFailure", "InsufficientAccountPermissions":
+		return errVolumeAccessDenied
+	}
+	if code == "" {
+		code = resp.Status
+	}
+	return fmt.Errorf("Azure request failed with %s: %s", code, strings.TrimSpace(apiErr.Message))
+}
+
+// do - sends a signed request on blob of container, the container
+// itself if blob is empty, and returns its response if successful.
+func (s *azureStorage) do(ctx context.Context, method, container, blob string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	if container != "" {
+		u.Path += "/" + container
+	}
+	if blob != "" {
+		u.Path += "/" + blob
+	}
+	u.RawQuery = query.Encode()
+	req, e := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+	req = req.WithContext(ctx)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if len(body) > 0 || method == "PUT" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	s.sign(req)
+	resp, e := s.httpClient.Do(req)
+	if e != nil {
+		return nil, e
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s.toStorageError(resp)
+	}
+	return resp, nil
+}
+
+// doClose - sends a request whose response has no body of interest.
+func (s *azureStorage) doClose(ctx context.Context, method, container, blob string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	resp, e := s.do(ctx, method, container, blob, query, header, body)
+	if e != nil {
+		return nil, e
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, nil
+}
+
+// azureTime - returns time of a Last-Modified header or element, zero
+// if it is malformed.
+func azureTime(value string) time.Time {
+	t, _ := http.ParseTime(value)
+	return t.UTC()
+}
+
+// UnsupportedFeatures - retention can not be enforced on blobs, the
+// account can delete them directly.
+func (s *azureStorage) UnsupportedFeatures() []string {
+	return []string{featureLocking}
+}
+
+/// Volume operations.
+
+// MakeVol - creates the container of volume.
+func (s *azureStorage) MakeVol(volume string) error {
+	container, e := s.container(volume)
+	if e != nil {
+		return e
+	}
+	_, e = s.doClose(context.Background(), "PUT", container, "", url.Values{"restype": {"container"}}, nil, nil)
+	return e
+}
+
+// azureContainerList - response of List Containers.
+type azureContainerList struct {
+	Containers []struct {
+		Name         string `xml:"Name"`
+		LastModified string `xml:"Properties>Last-Modified"`
+	} `xml:"Containers>Container"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// ListVols - lists volumes of all containers.
+func (s *azureStorage) ListVols() ([]VolInfo, error) {
+	var vols []VolInfo
+	marker := ""
+	for {
+		query := url.Values{"comp": {"list"}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, e := s.do(context.Background(), "GET", "", "", query, nil, nil)
+		if e != nil {
+			return nil, e
+		}
+		list := azureContainerList{}
+		e = xml.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if e != nil {
+			return nil, e
+		}
+		for _, container := range list.Containers {
+			vols = append(vols, VolInfo{
+				Name:    s.volume(container.Name),
+				Created: azureTime(container.LastModified),
+			})
+		}
+		if list.NextMarker == "" {
+			return vols, nil
+		}
+		marker = list.NextMarker
+	}
+}
+
+// StatVol - returns info of the container of volume.
+func (s *azureStorage) StatVol(volume string) (VolInfo, error) {
+	container, e := s.container(volume)
+	if e != nil {
+		return VolInfo{}, e
+	}
+	resp, e := s.doClose(context.Background(), "HEAD", container, "", url.Values{"restype": {"container"}}, nil, nil)
+	if e != nil {
+		return VolInfo{}, e
+	}
+	return VolInfo{Name: volume, Created: azureTime(resp.Header.Get("Last-Modified"))}, nil
+}
+
+// StatVFS - containers have no fixed capacity.
+func (s *azureStorage) StatVFS(volume string) (VFSInfo, error) {
+	return VFSInfo{Total: -1, Free: -1}, nil
+}
+
+// DeleteVol - deletes the container of volume if it is empty.
+func (s *azureStorage) DeleteVol(volume string) error {
+	container, e := s.container(volume)
+	if e != nil {
+		return e
+	}
+	// Containers are deleted along with their blobs.
+	list, e := s.listBlobs(container, "", "", "", 1)
+	if e != nil {
+		return e
+	}
+	if len(list.Blobs) > 0 || len(list.Prefixes) > 0 {
+		return errVolumeNotEmpty
+	}
+	_, e = s.doClose(context.Background(), "DELETE", container, "", url.Values{"restype": {"container"}}, nil, nil)
+	return e
+}
+
+/// File operations.
+
+// azureBlobList - response of List Blobs.
+type azureBlobList struct {
+	Blobs []struct {
+		Name          string `xml:"Name"`
+		LastModified  string `xml:"Properties>Last-Modified"`
+		ContentLength int64  `xml:"Properties>Content-Length"`
+	} `xml:"Blobs>Blob"`
+	Prefixes []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>BlobPrefix"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// listBlobs - lists at most count blobs of container from the Azure
+// marker, blobs sharing a prefix up to delimiter are listed once.
+func (s *azureStorage) listBlobs(container, prefix, marker, delimiter string, count int) (azureBlobList, error) {
+	query := url.Values{
+		"restype":    {"container"},
+		"comp":       {"list"},
+		"maxresults": {strconv.Itoa(count)},
+	}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	list := azureBlobList{}
+	resp, e := s.do(context.Background(), "GET", container, "", query, nil, nil)
+	if e != nil {
+		return list, e
+	}
+	defer resp.Body.Close()
+	e = xml.NewDecoder(resp.Body).Decode(&list)
+	return list, e
+}
+
+// ListFiles - lists files of volume after marker, directories are
+// listed with a trailing slash unless listing is recursive.
+func (s *azureStorage) ListFiles(volume, prefix, marker string, recursive bool, count int) ([]FileInfo, bool, error) {
+	container, e := s.container(volume)
+	if e != nil {
+		return nil, true, e
+	}
+	if marker != "" && !strings.HasPrefix(marker, prefix) {
+		return nil, true, errInvalidArgument
+	}
+	// Return empty response for a valid request when count is 0.
+	if count == 0 {
+		return nil, true, nil
+	}
+	if count < 0 || count > azureListLimit {
+		count = azureListLimit
+	}
+	delimiter := ""
+	if !recursive {
+		delimiter = slashSeparator
+	}
+
+	// Azure markers are opaque, listings continue from the marker
+	// saved with their last name. Others are listed from the start,
+	// skipping names up to marker.
+	params := azureListParams{container, prefix, marker, recursive}
+	azureMarker := ""
+	if marker != "" {
+		s.listMutex.Lock()
+		azureMarker = s.listMarkers[params]
+		delete(s.listMarkers, params)
+		s.listMutex.Unlock()
+	}
+
+	var fileInfos []FileInfo
+	for {
+		list, e := s.listBlobs(container, prefix, azureMarker, delimiter, count-len(fileInfos))
+		if e != nil {
+			return nil, true, e
+		}
+		var page []FileInfo
+		for _, blob := range list.Blobs {
+			page = append(page, FileInfo{
+				Volume:  volume,
+				Name:    blob.Name,
+				ModTime: azureTime(blob.LastModified),
+				Size:    blob.ContentLength,
+			})
+		}
+		for _, blobPrefix := range list.Prefixes {
+			page = append(page, FileInfo{
+				Volume: volume,
+				Name:   blobPrefix.Name,
+				Mode:   os.ModeDir,
+			})
+		}
+		sort.Slice(page, func(i, j int) bool { return page[i].Name < page[j].Name })
+		for _, fileInfo := range page {
+			if fileInfo.Name > marker {
+				fileInfos = append(fileInfos, fileInfo)
+			}
+		}
+		azureMarker = list.NextMarker
+		if azureMarker == "" {
+			return fileInfos, true, nil
+		}
+		if len(fileInfos) >= count {
+			break
+		}
+	}
+
+	// Pages are consumed whole, the next one starts after the last
+	// name returned.
+	params.marker = fileInfos[len(fileInfos)-1].Name
+	s.listMutex.Lock()
+	if len(s.listMarkers) >= azureMaxListMarkers {
+		for key := range s.listMarkers {
+			delete(s.listMarkers, key)
+			break
+		}
+	}
+	s.listMarkers[params] = azureMarker
+	s.listMutex.Unlock()
+	return fileInfos, false, nil
+}
+
+// ReadFile - reads blob of path from offset.
+func (s *azureStorage) ReadFile(ctx context.Context, volume string, path string, offset int64) (io.ReadCloser, error) {
+	container, e := s.container(volume)
+	if e != nil {
+		return nil, e
+	}
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("x-ms-range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, e := s.do(ctx, "GET", container, path, nil, header, nil)
+	if e != nil {
+		// Reading from the end of a blob reads nothing.
+		if offset > 0 && strings.Contains(e.Error(), "InvalidRange") {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, e
+	}
+	return resp.Body, nil
+}
+
+// StatFile - returns info of blob of path.
+func (s *azureStorage) StatFile(volume string, path string) (FileInfo, error) {
+	container, e := s.container(volume)
+	if e != nil {
+		return FileInfo{}, e
+	}
+	resp, e := s.doClose(context.Background(), "HEAD", container, path, nil, nil, nil)
+	if e != nil {
+		return FileInfo{}, e
+	}
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	return FileInfo{
+		Volume:  volume,
+		Name:    path,
+		ModTime: azureTime(resp.Header.Get("Last-Modified")),
+		Size:    size,
+	}, nil
+}
+
+// DeleteFile - deletes blob of path.
+func (s *azureStorage) DeleteFile(ctx context.Context, volume, path string) error {
+	container, e := s.container(volume)
+	if e != nil {
+		return e
+	}
+	_, e = s.doClose(ctx, "DELETE", container, path, nil, nil, nil)
+	return e
+}
+
+// CreateFile - returns writer of the block blob of path, data is
+// uploaded in blocks and committed once the writer is closed.
+func (s *azureStorage) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	return s.newBlobWriter(ctx, volume, path)
+}
+
+// newBlobWriter - returns writer of blocks of the block blob of path.
+func (s *azureStorage) newBlobWriter(ctx context.Context, volume, path string) (*azureBlobWriter, error) {
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+	container, e := s.container(volume)
+	if e != nil {
+		return nil, e
+	}
+	idBytes := make([]byte, 8)
+	if _, e = rand.Read(idBytes); e != nil {
+		return nil, e
+	}
+	return &azureBlobWriter{
+		storage:   s,
+		ctx:       ctx,
+		container: container,
+		blob:      path,
+		uploadID:  hex.EncodeToString(idBytes),
+	}, nil
+}
+
+// StagePart - returns writer of blocks of the block blob of path, they
+// are left uncommitted once closed. Committing the blob otherwise
+// discards them.
+func (s *azureStorage) StagePart(ctx context.Context, volume, path string) (partWriter, error) {
+	w, e := s.newBlobWriter(ctx, volume, path)
+	if e != nil {
+		return nil, e
+	}
+	w.staged = true
+	return w, nil
+}
+
+// CommitParts - commits the blocks of the parts of tokens as the blob
+// of path.
+func (s *azureStorage) CommitParts(ctx context.Context, volume, path string, tokens []string) error {
+	container, e := s.container(volume)
+	if e != nil {
+		return e
+	}
+	var blockIDs []string
+	for _, token := range tokens {
+		// Tokens are the upload id of the writer and its block count.
+		i := strings.LastIndex(token, "-")
+		if i < 0 {
+			return errInvalidArgument
+		}
+		count, e := strconv.Atoi(token[i+1:])
+		if e != nil {
+			return errInvalidArgument
+		}
+		for index := 0; index < count; index++ {
+			blockIDs = append(blockIDs, azureBlockID(token[:i], index))
+		}
+	}
+	return s.putBlockList(ctx, container, path, blockIDs)
+}
+
+// RemoveParts - uncommitted blocks are discarded by Azure, there is
+// nothing to remove.
+func (s *azureStorage) RemoveParts(ctx context.Context, volume, path string, tokens []string) error {
+	return nil
+}
+
+// azureBlobWriter - uploads data written as uncommitted blocks of a
+// blob, they are committed as the blob on Close. Blocks of writers
+// which are not closed are discarded by Azure.
+type azureBlobWriter struct {
+	storage   *azureStorage
+	ctx       context.Context
+	container string
+	blob      string
+	// Blocks of concurrent writers of the same blob are kept apart.
+	uploadID string
+	// Blocks of staged parts are not committed on Close.
+	staged   bool
+	blockIDs []string
+	buf      []byte
+	err      error
+}
+
+// azureBlockID - returns id of the block at index of a writer, ids of
+// all blocks of a blob have the same length.
+func azureBlockID(uploadID string, index int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%08d", uploadID, index)))
+}
+
+// putBlock - uploads data as the next block.
+func (w *azureBlobWriter) putBlock(data []byte) error {
+	blockID := azureBlockID(w.uploadID, len(w.blockIDs))
+	query := url.Values{"comp": {"block"}, "blockid": {blockID}}
+	if _, e := w.storage.doClose(w.ctx, "PUT", w.container, w.blob, query, nil, data); e != nil {
+		return e
+	}
+	w.blockIDs = append(w.blockIDs, blockID)
+	return nil
+}
+
+func (w *azureBlobWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= azureBlockSize {
+		if w.err = w.putBlock(w.buf[:azureBlockSize]); w.err != nil {
+			return 0, w.err
+		}
+		w.buf = append(w.buf[:0], w.buf[azureBlockSize:]...)
+	}
+	return len(p), nil
+}
+
+// azureBlockList - request of Put Block List.
+type azureBlockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+// Close - uploads the last block and commits all blocks as the blob,
+// blocks of staged parts are left uncommitted.
+func (w *azureBlobWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) > 0 {
+		if w.err = w.putBlock(w.buf); w.err != nil {
+			return w.err
+		}
+		w.buf = nil
+	}
+	if !w.staged {
+		w.err = w.storage.putBlockList(w.ctx, w.container, w.blob, w.blockIDs)
+	}
+	if w.err == nil {
+		// Closing twice is an error, same as files.
+		w.err = os.ErrClosed
+		return nil
+	}
+	return w.err
+}
+
+// Token - returns the upload id of the writer and its block count.
+func (w *azureBlobWriter) Token() string {
+	return fmt.Sprintf("%s-%d", w.uploadID, len(w.blockIDs))
+}
+
+// putBlockList - commits blocks of blockIDs as the blob, in order.
+func (s *azureStorage) putBlockList(ctx context.Context, container, blob string, blockIDs []string) error {
+	body, e := xml.Marshal(azureBlockList{Latest: blockIDs})
+	if e != nil {
+		return e
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml")
+	_, e = s.doClose(ctx, "PUT", container, blob, url.Values{"comp": {"blocklist"}}, header, append([]byte(xml.Header), body...))
+	return e
+}
@@ -0,0 +1,489 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// fakeAzureContainer - container of the fake Blob service.
+type fakeAzureContainer struct {
+	created time.Time
+	blobs   map[string][]byte
+	// Uncommitted blocks by blob and block id.
+	blocks map[string]map[string][]byte
+}
+
+// fakeAzureBlobService - in memory Blob service of a single account,
+// requests must be signed with its key.
+type fakeAzureBlobService struct {
+	account    string
+	key        []byte
+	mutex      *sync.Mutex
+	containers map[string]*fakeAzureContainer
+	requests   int
+}
+
+func newFakeAzureBlobService() *fakeAzureBlobService {
+	return &fakeAzureBlobService{
+		account:    "devstoreaccount1",
+		key:        []byte("fake azure account key"),
+		mutex:      &sync.Mutex{},
+		containers: make(map[string]*fakeAzureContainer),
+	}
+}
+
+func (f *fakeAzureBlobService) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeAzureBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests++
+
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(azureStringToSign(f.account, r)))
+	if r.Header.Get("Authorization") != "SharedKey "+f.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		f.fail(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	// Paths are /account/container/blob, the blob may contain slashes.
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"+f.account), "/", 3)
+	containerName, blobName := "", ""
+	if len(parts) > 1 {
+		containerName = parts[1]
+	}
+	if len(parts) > 2 {
+		blobName = parts[2]
+	}
+	query := r.URL.Query()
+	if containerName == "" {
+		f.listContainers(w, query)
+		return
+	}
+	container, ok := f.containers[containerName]
+	if blobName == "" && query.Get("restype") == "container" && query.Get("comp") == "" {
+		switch r.Method {
+		case "PUT":
+			if ok {
+				f.fail(w, http.StatusConflict, "ContainerAlreadyExists")
+				return
+			}
+			f.containers[containerName] = &fakeAzureContainer{
+				created: time.Now().UTC(),
+				blobs:   make(map[string][]byte),
+				blocks:  make(map[string]map[string][]byte),
+			}
+			w.WriteHeader(http.StatusCreated)
+		case "HEAD":
+			if !ok {
+				f.fail(w, http.StatusNotFound, "ContainerNotFound")
+				return
+			}
+			w.Header().Set("Last-Modified", container.created.Format(http.TimeFormat))
+		case "DELETE":
+			if !ok {
+				f.fail(w, http.StatusNotFound, "ContainerNotFound")
+				return
+			}
+			delete(f.containers, containerName)
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return
+	}
+	if !ok {
+		f.fail(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+	if blobName == "" {
+		f.listBlobs(w, container, query)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == "PUT" && query.Get("comp") == "block":
+		if container.blocks[blobName] == nil {
+			container.blocks[blobName] = make(map[string][]byte)
+		}
+		container.blocks[blobName][query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && query.Get("comp") == "blocklist":
+		list := azureBlockList{}
+		if e := xml.Unmarshal(body, &list); e != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidXmlDocument")
+			return
+		}
+		var data []byte
+		for _, blockID := range list.Latest {
+			block, ok := container.blocks[blobName][blockID]
+			if !ok {
+				f.fail(w, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		container.blobs[blobName] = data
+		delete(container.blocks, blobName)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "GET" || r.Method == "HEAD":
+		data, ok := container.blobs[blobName]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		if rangeHeader := r.Header.Get("x-ms-range"); rangeHeader != "" {
+			offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			if offset >= len(data) {
+				f.fail(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			data = data[offset:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", container.created.Format(http.TimeFormat))
+		if r.Method == "GET" {
+			w.Write(data)
+		}
+	case r.Method == "DELETE":
+		if _, ok := container.blobs[blobName]; !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(container.blobs, blobName)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		f.fail(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+func (f *fakeAzureBlobService) listContainers(w http.ResponseWriter, query url.Values) {
+	var names []string
+	for name := range f.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprint(w, "<EnumerationResults><Containers>")
+	for _, name := range names {
+		fmt.Fprintf(w, "<Container><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified></Properties></Container>", name, f.containers[name].created.Format(http.TimeFormat))
+	}
+	fmt.Fprint(w, "</Containers><NextMarker/></EnumerationResults>")
+}
+
+// listBlobs - lists blobs, markers are the encoded name of the next
+// entry.
+func (f *fakeAzureBlobService) listBlobs(w http.ResponseWriter, container *fakeAzureContainer, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxResults, _ := strconv.Atoi(query.Get("maxresults"))
+	marker, _ := base64.StdEncoding.DecodeString(query.Get("marker"))
+
+	var names []string
+	seen := make(map[string]bool)
+	for name := range container.blobs {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				name = name[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if !seen[name] && name >= string(marker) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	nextMarker := ""
+	if len(names) > maxResults {
+		nextMarker = base64.StdEncoding.EncodeToString([]byte(names[maxResults]))
+		names = names[:maxResults]
+	}
+	fmt.Fprint(w, "<EnumerationResults><Blobs>")
+	for _, name := range names {
+		if delimiter != "" && strings.HasSuffix(name, delimiter) {
+			fmt.Fprintf(w, "<BlobPrefix><Name>%s</Name></BlobPrefix>", name)
+			continue
+		}
+		fmt.Fprintf(w, "<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Content-Length>%d</Content-Length></Properties></Blob>",
+			name, container.created.Format(http.TimeFormat), len(container.blobs[name]))
+	}
+	fmt.Fprintf(w, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", nextMarker)
+}
+
+// newTestAzureStorage - returns storage API of the account served by
+// service at endpoint.
+func newTestAzureStorage(service *fakeAzureBlobService, endpoint string) (StorageAPI, error) {
+	savedName, savedKey := os.Getenv("MINIO_AZURE_ACCOUNT_NAME"), os.Getenv("MINIO_AZURE_ACCOUNT_KEY")
+	defer func() {
+		os.Setenv("MINIO_AZURE_ACCOUNT_NAME", savedName)
+		os.Setenv("MINIO_AZURE_ACCOUNT_KEY", savedKey)
+	}()
+	os.Setenv("MINIO_AZURE_ACCOUNT_NAME", service.account)
+	os.Setenv("MINIO_AZURE_ACCOUNT_KEY", base64.StdEncoding.EncodeToString(service.key))
+	return newStorageBackend(storageBackendAzure, endpoint+"/"+service.account)
+}
+
+func (s *MySuite) TestAzureAPISuite(c *C) {
+	var servers []*httptest.Server
+	create := func() objectAPI {
+		service := newFakeAzureBlobService()
+		server := httptest.NewServer(service)
+		servers = append(servers, server)
+		storageAPI, err := newTestAzureStorage(service, server.URL)
+		c.Check(err, IsNil)
+		return newObjectLayer(storageAPI)
+	}
+	APITestSuite(c, create)
+	for _, server := range servers {
+		server.Close()
+	}
+}
+
+// Tests the string signed for a request, from the example of the
+// Blob service documentation.
+func TestAzureStringToSign(t *testing.T) {
+	req, e := http.NewRequest("PUT", "https://myaccount.blob.core.windows.net/mycontainer/dir/my%20blob?comp=block&blockid=QUFB", nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	req.Header.Set("Content-Length", "0")
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", "Sun, 11 Oct 2009 21:49:13 GMT")
+	req.Header.Set("X-Ms-Meta-Name", " value ")
+	expected := strings.Join([]string{
+		"PUT", "", "", "", "", "text/plain", "", "", "", "", "", "",
+		"x-ms-date:Sun, 11 Oct 2009 21:49:13 GMT",
+		"x-ms-meta-name:value",
+		"x-ms-version:" + azureAPIVersion,
+		"/myaccount/mycontainer/dir/my%20blob\nblockid:QUFB\ncomp:block",
+	}, "\n")
+	if got := azureStringToSign("myaccount", req); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+}
+
+// Tests files are uploaded in blocks, listings continue from their
+// saved Azure marker and errors map to storage errors.
+func TestAzureStorage(t *testing.T) {
+	service := newFakeAzureBlobService()
+	server := httptest.NewServer(service)
+	defer server.Close()
+	storage, e := newTestAzureStorage(service, server.URL)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if e = storage.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	if e = storage.MakeVol("bucket"); e != errVolumeExists {
+		t.Fatalf("Expected %v, got %v", errVolumeExists, e)
+	}
+	for _, volume := range []string{"my.bucket", azureMetaContainer, "a--b"} {
+		if e = storage.MakeVol(volume); e != errInvalidVolumeName {
+			t.Fatalf("%s: Expected %v, got %v", volume, errInvalidVolumeName, e)
+		}
+	}
+	// The meta volume is kept in its own container.
+	if e = storage.MakeVol(minioMetaVolume); e != nil {
+		t.Fatal(e)
+	}
+	vols, e := storage.ListVols()
+	if e != nil || len(vols) != 2 || vols[0].Name != "bucket" || vols[1].Name != minioMetaVolume {
+		t.Fatalf("Unexpected volumes %+v %v", vols, e)
+	}
+
+	// Files larger than a block are committed once closed.
+	data := bytes.Repeat([]byte("0123456789"), azureBlockSize/5)
+	w, e := storage.CreateFile(context.Background(), "bucket", "dir/large")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = storage.StatFile("bucket", "dir/large"); e != errFileNotFound {
+		t.Fatalf("Expected uncommitted file to be missing, got %v", e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if blocks := len(service.containers["bucket"].blobs); blocks != 1 {
+		t.Fatalf("Expected one blob, got %d", blocks)
+	}
+	fileInfo, e := storage.StatFile("bucket", "dir/large")
+	if e != nil || fileInfo.Size != int64(len(data)) {
+		t.Fatalf("Unexpected file info %+v %v", fileInfo, e)
+	}
+	r, e := storage.ReadFile(context.Background(), "bucket", "dir/large", int64(len(data))-10)
+	if e != nil {
+		t.Fatal(e)
+	}
+	tail, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(tail) != "0123456789" {
+		t.Fatalf("Unexpected data read from offset %q", tail)
+	}
+	if r, e = storage.ReadFile(context.Background(), "bucket", "dir/large", int64(len(data))); e != nil {
+		t.Fatalf("Expected empty read from the end, got %v", e)
+	}
+	r.Close()
+
+	for i := 0; i < 5; i++ {
+		w, e = storage.CreateFile(context.Background(), "bucket", fmt.Sprintf("file%d", i))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = storage.DeleteVol("bucket"); e != errVolumeNotEmpty {
+		t.Fatalf("Expected %v, got %v", errVolumeNotEmpty, e)
+	}
+
+	// Directories are listed once, listings continue after their last
+	// name.
+	var names []string
+	marker, eof := "", false
+	for !eof {
+		var fileInfos []FileInfo
+		fileInfos, eof, e = storage.ListFiles("bucket", "", marker, false, 2)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, fileInfo := range fileInfos {
+			names = append(names, fileInfo.Name)
+			marker = fileInfo.Name
+		}
+	}
+	if strings.Join(names, ",") != "dir/,file0,file1,file2,file3,file4" {
+		t.Fatalf("Unexpected listing %v", names)
+	}
+	// Listings without a saved marker skip names up to the marker.
+	requests := service.requests
+	fileInfos, eof, e := storage.ListFiles("bucket", "", "file2", true, 10)
+	if e != nil || !eof || len(fileInfos) != 2 || fileInfos[0].Name != "file3" {
+		t.Fatalf("Unexpected listing %+v %v %v", fileInfos, eof, e)
+	}
+	if service.requests != requests+1 {
+		t.Fatalf("Expected a single list request, got %d", service.requests-requests)
+	}
+
+	if e = storage.DeleteFile(context.Background(), "bucket", "missing"); e != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+	if _, e = storage.StatVol("missing"); e != errVolumeNotFound {
+		t.Fatalf("Expected %v, got %v", errVolumeNotFound, e)
+	}
+	if _, e = storage.ReadFile(context.Background(), "missing", "file", 0); e != errVolumeNotFound {
+		t.Fatalf("Expected %v, got %v", errVolumeNotFound, e)
+	}
+
+	// Requests signed with another key are denied.
+	service.key = []byte("another key")
+	if _, e = storage.StatVol("bucket"); e != errVolumeAccessDenied {
+		t.Fatalf("Expected %v, got %v", errVolumeAccessDenied, e)
+	}
+}
+
+// Tests parts of multipart uploads are staged as blocks of the object
+// and committed without copying them.
+func TestAzureMultipart(t *testing.T) {
+	service := newFakeAzureBlobService()
+	server := httptest.NewServer(service)
+	defer server.Close()
+	storage, e := newTestAzureStorage(service, server.URL)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(storage)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), azureBlockSize/10+1)
+	var parts []completePart
+	for i, part := range [][]byte{data, []byte("old"), []byte("tail")} {
+		partID := i + 1
+		if partID > 2 {
+			// Parts uploaded again replace their blocks.
+			partID = 2
+			parts = parts[:1]
+		}
+		md5Hex, err := obj.PutObjectPart("bucket", "object", uploadID, partID, int64(len(part)), bytes.NewReader(part), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: partID, ETag: md5Hex})
+	}
+	if blocks := len(service.containers["bucket"].blocks["object"]); blocks != 4 {
+		t.Fatalf("Expected 4 blocks staged, got %d", blocks)
+	}
+	for name, blob := range service.containers[azureMetaContainer].blobs {
+		if len(blob) > 64 {
+			t.Fatalf("Expected only records of parts, got %s of %d bytes", name, len(blob))
+		}
+	}
+	listed, err := obj.ListObjectParts("bucket", "object", uploadID, 0, 10)
+	if err != nil || len(listed.Parts) != 2 || listed.Parts[0].Size != int64(len(data)) || listed.Parts[1].Size != 4 {
+		t.Fatalf("Unexpected parts %+v %v", listed.Parts, err)
+	}
+
+	if _, err = obj.CompleteMultipartUpload("bucket", "object", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(service.containers["bucket"].blobs["object"], append(data, "tail"...)) {
+		t.Fatal("Unexpected data of the completed object")
+	}
+	if blocks := len(service.containers["bucket"].blocks); blocks != 0 {
+		t.Fatalf("Expected no uncommitted blocks, got %d", blocks)
+	}
+	for name := range service.containers[azureMetaContainer].blobs {
+		if strings.HasPrefix(name, "bucket/object/") {
+			t.Fatalf("Expected records of parts removed, got %s", name)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Bandwidth limits are saved in this file in the config path.
+const bandwidthLimitsFile = "bandwidth-limits.json"
+
+// Kinds of bandwidth limit targets.
+const (
+	bandwidthTargetBucket    = "bucket"
+	bandwidthTargetAccessKey = "access-key"
+)
+
+// bandwidthLimit - upload and download throughput of all requests for
+// a bucket or by an access key, in bytes per second. Zero is
+// unlimited.
+type bandwidthLimit struct {
+	Target       string `json:"target"`
+	Name         string `json:"name"`
+	UploadRate   int64  `json:"uploadRate"`
+	DownloadRate int64  `json:"downloadRate"`
+}
+
+// key - returns key of the limit target.
+func (l bandwidthLimit) key() string {
+	return l.Target + "/" + l.Name
+}
+
+// bandwidthThrottles - throttles shared by all requests of a limit
+// target, nil if unlimited.
+type bandwidthThrottles struct {
+	upload   *bandwidthThrottle
+	download *bandwidthThrottle
+}
+
+// bandwidthLimiter - bandwidth limits set by admins.
+type bandwidthLimiter struct {
+	mutex     *sync.Mutex
+	limits    map[string]bandwidthLimit
+	throttles map[string]bandwidthThrottles
+}
+
+func newBandwidthLimiter() *bandwidthLimiter {
+	return &bandwidthLimiter{
+		mutex:     &sync.Mutex{},
+		limits:    make(map[string]bandwidthLimit),
+		throttles: make(map[string]bandwidthThrottles),
+	}
+}
+
+// Global bandwidth limiter, driven by the admin API.
+var globalBandwidthLimiter = newBandwidthLimiter()
+
+// isValidBandwidthTarget - returns true if target is a known kind of
+// limit target.
+func isValidBandwidthTarget(target string) bool {
+	return target == bandwidthTargetBucket || target == bandwidthTargetAccessKey
+}
+
+// set - sets limit, callers hold the mutex.
+func (b *bandwidthLimiter) set(limit bandwidthLimit) {
+	throttles := bandwidthThrottles{}
+	if limit.UploadRate > 0 {
+		throttles.upload = newBandwidthThrottle(limit.UploadRate)
+	}
+	if limit.DownloadRate > 0 {
+		throttles.download = newBandwidthThrottle(limit.DownloadRate)
+	}
+	b.limits[limit.key()] = limit
+	b.throttles[limit.key()] = throttles
+}
+
+// Set - sets limit of its target, replacing the previous limit.
+// Requests in progress keep the limit they started with.
+func (b *bandwidthLimiter) Set(limit bandwidthLimit) *probe.Error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.set(limit)
+	return b.saveLimits().Trace(limit.Target, limit.Name)
+}
+
+// Remove - removes limit of target with name.
+func (b *bandwidthLimiter) Remove(target, name string) *probe.Error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := bandwidthLimit{Target: target, Name: name}.key()
+	if _, ok := b.limits[key]; !ok {
+		return probe.NewError(BandwidthLimitNotFound{Target: target, Name: name})
+	}
+	delete(b.limits, key)
+	delete(b.throttles, key)
+	return b.saveLimits().Trace(target, name)
+}
+
+// List - returns all limits sorted by target and name.
+func (b *bandwidthLimiter) List() []bandwidthLimit {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.listLimits()
+}
+
+// listLimits - returns all limits sorted by target and name, callers
+// hold the mutex.
+func (b *bandwidthLimiter) listLimits() []bandwidthLimit {
+	limits := []bandwidthLimit{}
+	for _, limit := range b.limits {
+		limits = append(limits, limit)
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i].key() < limits[j].key() })
+	return limits
+}
+
+// requestThrottles - returns upload and download throttles of requests
+// for bucket by accessKey.
+func (b *bandwidthLimiter) requestThrottles(bucket, accessKey string) (upload, download []*bandwidthThrottle) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	keys := []string{
+		bandwidthLimit{Target: bandwidthTargetBucket, Name: bucket}.key(),
+		bandwidthLimit{Target: bandwidthTargetAccessKey, Name: accessKey}.key(),
+	}
+	for _, key := range keys {
+		throttles, ok := b.throttles[key]
+		if !ok {
+			continue
+		}
+		if throttles.upload != nil {
+			upload = append(upload, throttles.upload)
+		}
+		if throttles.download != nil {
+			download = append(download, throttles.download)
+		}
+	}
+	return upload, download
+}
+
+// getBandwidthLimitsFile - get bandwidth limits file path.
+func getBandwidthLimitsFile() (string, *probe.Error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(configPath, bandwidthLimitsFile), nil
+}
+
+// saveLimits - saves limits, callers hold the mutex.
+func (b *bandwidthLimiter) saveLimits() *probe.Error {
+	limitsFile, err := getBandwidthLimitsFile()
+	if err != nil {
+		return err.Trace()
+	}
+	limitsBytes, e := json.Marshal(b.listLimits())
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.MkdirAll(filepath.Dir(limitsFile), 0700); e != nil {
+		return probe.NewError(e)
+	}
+	if e = ioutil.WriteFile(limitsFile, limitsBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// loadLimits - loads saved limits, replacing current ones.
+func (b *bandwidthLimiter) loadLimits() *probe.Error {
+	limitsFile, err := getBandwidthLimitsFile()
+	if err != nil {
+		return err.Trace()
+	}
+	limitsBytes, e := ioutil.ReadFile(limitsFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil
+		}
+		return probe.NewError(e)
+	}
+	var limits []bandwidthLimit
+	if e = json.Unmarshal(limitsBytes, &limits); e != nil {
+		return probe.NewError(e)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.limits = make(map[string]bandwidthLimit)
+	b.throttles = make(map[string]bandwidthThrottles)
+	for _, limit := range limits {
+		b.set(limit)
+	}
+	return nil
+}
+
+// initBandwidthLimiter - loads bandwidth limits at server start.
+func initBandwidthLimiter() {
+	err := globalBandwidthLimiter.loadLimits()
+	fatalIf(err.Trace(), "Unable to load bandwidth limits.", nil)
+}
+
+// bandwidthLimitHandler - paces uploads and downloads of S3 requests
+// to the limits of their bucket and access key. Internode and admin
+// requests are not limited.
+type bandwidthLimitHandler struct {
+	handler http.Handler
+}
+
+func setBandwidthLimitHandler(h http.Handler) http.Handler {
+	return bandwidthLimitHandler{handler: h}
+}
+
+func (h bandwidthLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	upload, download := globalBandwidthLimiter.requestThrottles(getRequestBucket(r.URL.Path), getAuditRequester(r))
+	if len(upload) > 0 && r.Body != nil {
+		r.Body = throttledReader{ReadCloser: r.Body, ctx: r.Context(), throttles: upload}
+	}
+	if len(download) > 0 {
+		w = newThrottledResponseWriter(r.Context(), w, download)
+	}
+	h.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// Tests bandwidth limits apply to requests of their bucket or access
+// key and are saved across restarts.
+func TestBandwidthLimiter(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-bandwidth-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	b := newBandwidthLimiter()
+	if err := b.Remove(bandwidthTargetBucket, "bucket"); err == nil {
+		t.Fatal("Expected error removing limit which is not set")
+	}
+	limits := []bandwidthLimit{
+		{Target: bandwidthTargetBucket, Name: "bucket", UploadRate: 1024},
+		{Target: bandwidthTargetAccessKey, Name: "alice", UploadRate: 2048, DownloadRate: 4096},
+	}
+	for _, limit := range limits {
+		if err := b.Set(limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		bucket    string
+		accessKey string
+		upload    int
+		download  int
+	}{
+		{"bucket", "alice", 2, 1},
+		{"bucket", "bob", 1, 0},
+		{"other", "alice", 1, 1},
+		{"other", "bob", 0, 0},
+	}
+	for i, testCase := range testCases {
+		upload, download := b.requestThrottles(testCase.bucket, testCase.accessKey)
+		if len(upload) != testCase.upload || len(download) != testCase.download {
+			t.Errorf("Test %d: Expected %d upload and %d download throttles, got %d and %d",
+				i+1, testCase.upload, testCase.download, len(upload), len(download))
+		}
+	}
+
+	restarted := newBandwidthLimiter()
+	if err := restarted.loadLimits(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restarted.List(), b.List()) {
+		t.Fatalf("Expected %+v, got %+v", b.List(), restarted.List())
+	}
+	if err := restarted.Remove(bandwidthTargetBucket, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if upload, _ := restarted.requestThrottles("bucket", "bob"); len(upload) != 0 {
+		t.Fatal("Expected removed limit to no longer apply")
+	}
+}
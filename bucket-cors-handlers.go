@@ -0,0 +1,178 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// PutBucketCorsHandler - PUT Bucket cors
+// -----------------
+// This implementation of the PUT operation uses the cors subresource
+// to set the CORS configuration of a bucket, replacing the existing
+// one. Cross origin requests on the bucket are allowed as its rules
+// say.
+func (api objectAPIHandlers) PutBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxBucketCorsConfigSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	// CORS can only be configured on existing buckets.
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err.Trace(bucket), "GetBucketInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	configBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxBucketCorsConfigSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket), "Reading CORS configuration failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	// Parse and validate CORS configuration.
+	if _, e = parseBucketCors(configBytes); e != nil {
+		errorIf(probe.NewError(e), "Invalid CORS configuration.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+
+	// Save bucket CORS configuration.
+	if err := writeBucketCors(bucket, configBytes); err != nil {
+		errorIf(err.Trace(bucket), "SaveBucketCors failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetBucketCorsHandler - GET Bucket cors
+// -----------------
+// This operation uses the cors subresource to return the CORS
+// configuration of a specified bucket.
+func (api objectAPIHandlers) GetBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Read bucket CORS configuration.
+	configBytes, err := readBucketCors(bucket)
+	if err != nil {
+		errorIf(err.Trace(bucket), "GetBucketCors failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketCorsNotFound:
+			writeErrorResponse(w, r, ErrNoSuchCORSConfiguration, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w)
+	writeSuccessResponse(w, configBytes)
+}
+
+// DeleteBucketCorsHandler - DELETE Bucket cors
+// -----------------
+// This implementation of the DELETE operation uses the cors
+// subresource to remove the CORS configuration of a bucket, cross
+// origin requests on the bucket are no longer allowed.
+func (api objectAPIHandlers) DeleteBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Delete bucket CORS configuration.
+	if err := removeBucketCors(bucket); err != nil {
+		errorIf(err.Trace(bucket), "DeleteBucketCors failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketCorsNotFound:
+			writeErrorResponse(w, r, ErrNoSuchCORSConfiguration, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
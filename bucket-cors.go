@@ -0,0 +1,355 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// CORS configuration file name, saved in bucket config path.
+	bucketCorsConfigFile = "cors.xml"
+
+	// Maximum size of CORS configuration.
+	maxBucketCorsConfigSize = 64 * 1024
+
+	// Maximum number of rules of a CORS configuration.
+	maxBucketCorsRules = 100
+)
+
+var (
+	errCorsNoRules         = errors.New("CORS configuration should have at least one rule")
+	errCorsTooManyRules    = errors.New("CORS configuration should have at most 100 rules")
+	errCorsNoOrigin        = errors.New("CORS rule should have at least one AllowedOrigin")
+	errCorsNoMethod        = errors.New("CORS rule should have at least one AllowedMethod")
+	errCorsInvalidMethod   = errors.New("CORS rule AllowedMethod should be one of GET, PUT, HEAD, POST or DELETE")
+	errCorsInvalidWildcard = errors.New("CORS rule AllowedOrigin and AllowedHeader can have at most one wildcard")
+	errCorsInvalidMaxAge   = errors.New("CORS rule MaxAgeSeconds should not be negative")
+)
+
+// Methods CORS rules can allow.
+var corsMethods = map[string]bool{
+	"GET":    true,
+	"PUT":    true,
+	"HEAD":   true,
+	"POST":   true,
+	"DELETE": true,
+}
+
+// corsRule - origins, methods and headers of cross origin requests
+// allowed on a bucket.
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds  int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// corsConfiguration - bucket CORS configuration.
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+// Validate - validates CORS configuration.
+func (config corsConfiguration) Validate() error {
+	if len(config.Rules) == 0 {
+		return errCorsNoRules
+	}
+	if len(config.Rules) > maxBucketCorsRules {
+		return errCorsTooManyRules
+	}
+	for _, rule := range config.Rules {
+		if len(rule.AllowedOrigins) == 0 {
+			return errCorsNoOrigin
+		}
+		if len(rule.AllowedMethods) == 0 {
+			return errCorsNoMethod
+		}
+		for _, method := range rule.AllowedMethods {
+			if !corsMethods[method] {
+				return errCorsInvalidMethod
+			}
+		}
+		for _, pattern := range append(rule.AllowedOrigins, rule.AllowedHeaders...) {
+			if strings.Count(pattern, "*") > 1 {
+				return errCorsInvalidWildcard
+			}
+		}
+		if rule.MaxAgeSeconds < 0 {
+			return errCorsInvalidMaxAge
+		}
+	}
+	return nil
+}
+
+// corsWildcardMatch - returns if value matches pattern, in which a
+// "*" matches any characters.
+func corsWildcardMatch(pattern, value string) bool {
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return pattern == value
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(value) >= len(prefix)+len(suffix) && strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// allowsOrigin - returns if rule allows requests from origin.
+func (rule corsRule) allowsOrigin(origin string) bool {
+	for _, pattern := range rule.AllowedOrigins {
+		if corsWildcardMatch(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod - returns if rule allows requests of method.
+func (rule corsRule) allowsMethod(method string) bool {
+	for _, allowed := range rule.AllowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeader - returns if rule allows requests carrying header,
+// header names are case insensitive.
+func (rule corsRule) allowsHeader(header string) bool {
+	for _, pattern := range rule.AllowedHeaders {
+		if corsWildcardMatch(strings.ToLower(pattern), strings.ToLower(header)) {
+			return true
+		}
+	}
+	return false
+}
+
+// match - returns first rule allowing requests of method from origin,
+// carrying headers, nil if there is none.
+func (config corsConfiguration) match(origin, method string, headers []string) *corsRule {
+	for i, rule := range config.Rules {
+		if !rule.allowsOrigin(origin) || !rule.allowsMethod(method) {
+			continue
+		}
+		allowed := true
+		for _, header := range headers {
+			if !rule.allowsHeader(header) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+// setCorsHeaders - sets headers of responses to requests from origin
+// allowed by rule.
+func (rule corsRule) setCorsHeaders(w http.ResponseWriter, origin string) {
+	header := w.Header()
+	if len(rule.AllowedOrigins) == 1 && rule.AllowedOrigins[0] == "*" {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+	if len(rule.ExposeHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+	if rule.MaxAgeSeconds > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+	}
+}
+
+// parseBucketCors - parses and validates CORS configuration.
+func parseBucketCors(configBytes []byte) (corsConfiguration, error) {
+	var config corsConfiguration
+	if e := xml.Unmarshal(configBytes, &config); e != nil {
+		return corsConfiguration{}, e
+	}
+	if e := config.Validate(); e != nil {
+		return corsConfiguration{}, e
+	}
+	return config, nil
+}
+
+// getBucketCorsFile - get CORS configuration file path.
+func getBucketCorsFile(bucket string) (string, *probe.Error) {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(bucketConfigPath, bucketCorsConfigFile), nil
+}
+
+// readBucketCors - read bucket CORS configuration.
+func readBucketCors(bucket string) ([]byte, *probe.Error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	corsFile, err := getBucketCorsFile(bucket)
+	if err != nil {
+		return nil, err.Trace(bucket)
+	}
+	configBytes, e := ioutil.ReadFile(corsFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, probe.NewError(BucketCorsNotFound{Bucket: bucket})
+		}
+		return nil, probe.NewError(e)
+	}
+	return configBytes, nil
+}
+
+// removeBucketCors - remove bucket CORS configuration.
+func removeBucketCors(bucket string) *probe.Error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	corsFile, err := getBucketCorsFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := os.Remove(corsFile); e != nil {
+		if os.IsNotExist(e) {
+			return probe.NewError(BucketCorsNotFound{Bucket: bucket})
+		}
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// writeBucketCors - save bucket CORS configuration.
+func writeBucketCors(bucket string, configBytes []byte) *probe.Error {
+	// Verify if bucket path legal.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	// Create bucket config path.
+	if err := createBucketConfigPath(bucket); err != nil {
+		return err.Trace()
+	}
+
+	corsFile, err := getBucketCorsFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := ioutil.WriteFile(corsFile, configBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// corsHandler - answers preflight requests and sets CORS headers of
+// cross origin requests as allowed by the CORS configuration of their
+// bucket.
+type corsHandler struct {
+	handler http.Handler
+}
+
+// setCorsHandler handler for CORS (Cross Origin Resource Sharing)
+func setCorsHandler(h http.Handler) http.Handler {
+	return corsHandler{h}
+}
+
+func (h corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	bucket := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+	if origin == "" || bucket == "" || "/"+bucket == reservedBucket {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	preflight := r.Method == "OPTIONS"
+	// Responses depend on the origin of requests, caches need to know.
+	w.Header().Add("Vary", "Origin")
+	if preflight {
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	var config corsConfiguration
+	configBytes, err := readBucketCors(bucket)
+	if err == nil {
+		var e error
+		if config, e = parseBucketCors(configBytes); e != nil {
+			err = probe.NewError(e)
+		}
+	}
+	if !preflight {
+		// Cross origin requests not allowed are served without
+		// CORS headers, browsers hide their responses.
+		if err == nil {
+			if rule := config.match(origin, r.Method, nil); rule != nil {
+				rule.setCorsHeaders(w, origin)
+			}
+		}
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if err != nil {
+		switch err.ToGoError().(type) {
+		case BucketCorsNotFound, BucketNameInvalid:
+			writeErrorResponse(w, r, ErrCORSNotEnabled, r.URL.Path)
+		default:
+			errorIf(err.Trace(bucket), "Unable to read bucket CORS configuration.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !corsMethods[method] {
+		writeErrorResponse(w, r, ErrInvalidCORSRequestMethod, r.URL.Path)
+		return
+	}
+	var headers []string
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	rule := config.match(origin, method, headers)
+	if rule == nil {
+		writeErrorResponse(w, r, ErrCORSForbidden, r.URL.Path)
+		return
+	}
+	rule.setCorsHeaders(w, origin)
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	writeSuccessResponse(w, nil)
+}
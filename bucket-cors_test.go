@@ -0,0 +1,73 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "testing"
+
+// Tests CORS configuration validation.
+func TestParseBucketCors(t *testing.T) {
+	testCases := []struct {
+		cors string
+		err  error
+	}{
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`, nil},
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>https://*.example.com</AllowedOrigin><AllowedMethod>PUT</AllowedMethod><AllowedHeader>x-amz-*</AllowedHeader><MaxAgeSeconds>3000</MaxAgeSeconds></CORSRule></CORSConfiguration>`, nil},
+		{`<CORSConfiguration></CORSConfiguration>`, errCorsNoRules},
+		{`<CORSConfiguration><CORSRule><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`, errCorsNoOrigin},
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin></CORSRule></CORSConfiguration>`, errCorsNoMethod},
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>PATCH</AllowedMethod></CORSRule></CORSConfiguration>`, errCorsInvalidMethod},
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>*.*</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`, errCorsInvalidWildcard},
+		{`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>GET</AllowedMethod><MaxAgeSeconds>-1</MaxAgeSeconds></CORSRule></CORSConfiguration>`, errCorsInvalidMaxAge},
+	}
+	for i, testCase := range testCases {
+		if _, e := parseBucketCors([]byte(testCase.cors)); e != testCase.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, e)
+		}
+	}
+}
+
+// Tests matching of cross origin requests against CORS rules.
+func TestCorsConfigurationMatch(t *testing.T) {
+	config := corsConfiguration{Rules: []corsRule{
+		{ID: "uploads", AllowedOrigins: []string{"https://*.example.com"}, AllowedMethods: []string{"PUT", "POST"}, AllowedHeaders: []string{"Content-*", "x-amz-date"}},
+		{ID: "downloads", AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+	}}
+	testCases := []struct {
+		origin  string
+		method  string
+		headers []string
+		ruleID  string
+	}{
+		{"https://www.example.com", "PUT", nil, "uploads"},
+		{"https://www.example.com", "PUT", []string{"content-type", "X-Amz-Date"}, "uploads"},
+		{"https://www.example.com", "PUT", []string{"authorization"}, ""},
+		{"http://www.example.com", "PUT", nil, ""},
+		{"https://example.com", "POST", nil, ""},
+		{"http://other.org", "GET", nil, "downloads"},
+		{"http://other.org", "GET", []string{"range"}, ""},
+		{"http://other.org", "DELETE", nil, ""},
+	}
+	for i, testCase := range testCases {
+		ruleID := ""
+		if rule := config.match(testCase.origin, testCase.method, testCase.headers); rule != nil {
+			ruleID = rule.ID
+		}
+		if ruleID != testCase.ruleID {
+			t.Errorf("Test %d: Expected rule %q, got %q", i+1, testCase.ruleID, ruleID)
+		}
+	}
+}
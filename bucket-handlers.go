@@ -21,11 +21,13 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	mux "github.com/gorilla/mux"
@@ -174,26 +176,31 @@ func (api objectAPIHandlers) ListMultipartUploadsHandler(w http.ResponseWriter,
 		}
 	}
 
-	prefix, keyMarker, uploadIDMarker, delimiter, maxUploads, _ := getBucketMultipartResources(r.URL.Query())
+	prefix, keyMarker, uploadIDMarker, delimiter, maxUploads, encodingType := getBucketMultipartResources(r.URL.Query())
 	if maxUploads < 0 {
 		writeErrorResponse(w, r, ErrInvalidMaxUploads, r.URL.Path)
 		return
 	}
+	if !isValidEncodingType(encodingType) {
+		writeErrorResponse(w, r, ErrInvalidEncodingMethod, r.URL.Path)
+		return
+	}
+	// At most maxUploadsList uploads are returned.
+	if maxUploads > maxUploadsList {
+		maxUploads = maxUploadsList
+	}
+	// Verify if delimiter is anything other than '/', which we do not support.
+	if delimiter != "" && delimiter != "/" {
+		writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
+		return
+	}
+	// keyMarker is already unescaped along with the query, it is not
+	// unescaped again so that keys with '%' and '+' are listed.
 	if keyMarker != "" {
-		// Unescape keyMarker string
-		keyMarkerUnescaped, e := url.QueryUnescape(keyMarker)
-		if e != nil {
-			if e != nil {
-				// Return 'NoSuchKey' to indicate invalid marker key.
-				writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
-				return
-			}
-			keyMarker = keyMarkerUnescaped
-			// Marker not common with prefix is not implemented.
-			if !strings.HasPrefix(keyMarker, prefix) {
-				writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
-				return
-			}
+		// Marker not common with prefix is not implemented.
+		if !strings.HasPrefix(keyMarker, prefix) {
+			writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
+			return
 		}
 	}
 
@@ -201,14 +208,21 @@ func (api objectAPIHandlers) ListMultipartUploadsHandler(w http.ResponseWriter,
 	if err != nil {
 		errorIf(err.Trace(), "ListMultipartUploads failed.", nil)
 		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
 			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case ObjectNameInvalid:
+			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		case InvalidUploadIDKeyCombination, MalformedUploadID:
+			writeErrorResponse(w, r, ErrInvalidUploadIDMarker, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
 		return
 	}
 	// generate response
+	listMultipartsInfo.EncodingType = encodingType
 	response := generateListMultipartUploadsResponse(bucket, listMultipartsInfo)
 	encodedSuccessResponse := encodeResponse(response)
 	// write headers.
@@ -245,27 +259,23 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	// TODO handle encoding type.
-	prefix, marker, delimiter, maxkeys, _ := getBucketResources(r.URL.Query())
+	prefix, marker, delimiter, maxkeys, encodingType := getBucketResources(r.URL.Query())
 	if maxkeys < 0 {
 		writeErrorResponse(w, r, ErrInvalidMaxKeys, r.URL.Path)
 		return
 	}
+	if !isValidEncodingType(encodingType) {
+		writeErrorResponse(w, r, ErrInvalidEncodingMethod, r.URL.Path)
+		return
+	}
 	// Verify if delimiter is anything other than '/', which we do not support.
 	if delimiter != "" && delimiter != "/" {
 		writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
 		return
 	}
-	// If marker is set unescape.
+	// marker is already unescaped along with the query, it is not
+	// unescaped again so that keys with '%' and '+' are listed.
 	if marker != "" {
-		// Try to unescape marker.
-		markerUnescaped, e := url.QueryUnescape(marker)
-		if e != nil {
-			// Return 'NoSuchKey' to indicate invalid marker key.
-			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
-			return
-		}
-		marker = markerUnescaped
 		// Marker not common with prefix is not implemented.
 		if !strings.HasPrefix(marker, prefix) {
 			writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
@@ -276,7 +286,7 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 	listObjectsInfo, err := api.ObjectAPI.ListObjects(bucket, prefix, marker, delimiter, maxkeys)
 	if err == nil {
 		// generate response
-		response := generateListObjectsResponse(bucket, prefix, marker, delimiter, maxkeys, listObjectsInfo)
+		response := generateListObjectsResponse(bucket, prefix, marker, delimiter, encodingType, maxkeys, listObjectsInfo)
 		encodedSuccessResponse := encodeResponse(response)
 		// Write headers
 		setCommonHeaders(w)
@@ -337,6 +347,16 @@ func (api objectAPIHandlers) ListBucketsHandler(w http.ResponseWriter, r *http.R
 	}
 
 	bucketsInfo, err := api.ObjectAPI.ListBuckets()
+	if err == nil && globalFederation != nil {
+		// Buckets of other federated deployments are listed too.
+		remoteBuckets, e := globalFederation.remoteBuckets()
+		if e != nil {
+			errorIf(probe.NewError(e), "Listing federated buckets failed.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			return
+		}
+		bucketsInfo = mergeFederatedBuckets(bucketsInfo, remoteBuckets)
+	}
 	if err == nil {
 		// generate response
 		response := generateListBucketsResponse(bucketsInfo)
@@ -414,16 +434,36 @@ func (api objectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 
 	var deleteErrors []DeleteError
 	var deletedObjects []ObjectIdentifier
+	accessKey := getAuditRequester(r)
 	// Loop through all the objects and delete them sequentially.
 	for _, object := range deleteObjects.Objects {
-		err := api.ObjectAPI.DeleteObject(bucket, object.ObjectName)
+		// Policies of users apply to each key on its own.
+		if !globalIAMUsers.IsAllowed(accessKey, "s3:DeleteObject", getIAMResource(bucket, object.ObjectName)) ||
+			checkBypassGovernance(r, bucket, object.ObjectName) != ErrNone {
+			deleteErrors = append(deleteErrors, DeleteError{
+				Code:    errorCodeResponse[ErrAccessDenied].Code,
+				Message: errorCodeResponse[ErrAccessDenied].Description,
+				Key:     object.ObjectName,
+			})
+			continue
+		}
+		var sequence uint64
+		err := api.ObjectAPI.WithSequence(&sequence).DeleteObject(bucket, object.ObjectName, isBypassGovernance(r))
 		if err == nil {
 			deletedObjects = append(deletedObjects, ObjectIdentifier{
 				ObjectName: object.ObjectName,
 			})
+			// Notify object removed event.
+			notifyObjectRemoved(api.ObjectAPI, r, bucket, object.ObjectName, sequence)
 		} else {
 			errorIf(err.Trace(object.ObjectName), "DeleteObject failed.", nil)
 			switch err.ToGoError().(type) {
+			case ObjectLocked:
+				deleteErrors = append(deleteErrors, DeleteError{
+					Code:    errorCodeResponse[ErrObjectLocked].Code,
+					Message: errorCodeResponse[ErrObjectLocked].Description,
+					Key:     object.ObjectName,
+				})
 			case BucketNameInvalid:
 				deleteErrors = append(deleteErrors, DeleteError{
 					Code:    errorCodeResponse[ErrInvalidBucketName].Code,
@@ -466,6 +506,23 @@ func (api objectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 	writeSuccessResponse(w, encodedSuccessResponse)
 }
 
+// placementDisksHeader - comma separated indexes of the disks a new
+// bucket is pinned to, as reported by the admin server info API.
+const placementDisksHeader = "X-Minio-Placement-Disks"
+
+// parsePlacementDisks - parses disk indexes of placementDisksHeader.
+func parsePlacementDisks(placement string) ([]int, error) {
+	var disks []int
+	for _, index := range strings.Split(placement, ",") {
+		disk, e := strconv.Atoi(strings.TrimSpace(index))
+		if e != nil {
+			return nil, e
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
 // PutBucketHandler - PUT Bucket
 // ----------
 // This implementation of the PUT operation creates a new bucket for authenticated request
@@ -495,15 +552,46 @@ func (api objectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 		writeErrorResponse(w, r, errCode, r.URL.Path)
 		return
 	}
-	// Make bucket.
-	err := api.ObjectAPI.MakeBucket(bucket)
+	// Federated buckets are owned by the deployment registering them
+	// first.
+	if globalFederation != nil && IsValidBucketName(bucket) {
+		if e := globalFederation.register(bucket); e != nil {
+			if e == errBucketOwnedElsewhere {
+				writeErrorResponse(w, r, ErrBucketAlreadyExists, r.URL.Path)
+				return
+			}
+			errorIf(probe.NewError(e).Trace(bucket), "Registering federated bucket failed.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			return
+		}
+	}
+	// Make bucket, pinned to a subset of disks if requested.
+	var err *probe.Error
+	if placement := r.Header.Get(placementDisksHeader); placement != "" {
+		disks, e := parsePlacementDisks(placement)
+		if e != nil {
+			writeErrorResponse(w, r, ErrInvalidPlacement, r.URL.Path)
+			return
+		}
+		err = api.ObjectAPI.MakeBucketOnDisks(bucket, disks)
+	} else {
+		err = api.ObjectAPI.MakeBucket(bucket)
+	}
 	if err != nil {
 		errorIf(err.Trace(), "MakeBucket failed.", nil)
+		// Registration of buckets which were not created is undone.
+		if _, ok := err.ToGoError().(BucketExists); !ok {
+			unregisterFederatedBucket(bucket)
+		}
 		switch err.ToGoError().(type) {
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketExists:
 			writeErrorResponse(w, r, ErrBucketAlreadyExists, r.URL.Path)
+		case PlacementNotSupported:
+			writeErrorResponse(w, r, ErrPlacementNotSupported, r.URL.Path)
+		case InvalidPlacement:
+			writeErrorResponse(w, r, ErrInvalidPlacement, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
@@ -514,38 +602,72 @@ func (api objectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 	writeSuccessResponse(w, nil)
 }
 
-func extractHTTPFormValues(reader *multipart.Reader) (io.Reader, map[string]string, *probe.Error) {
+// Maximum size of the form fields of a POST policy request, the file
+// excluded.
+const maxFormFieldsSize = 1024 * 1024
+
+// extractHTTPFormValues - reads form fields of reader up to the file,
+// which is returned for reading along with its file name. Fields after
+// the file are ignored.
+func extractHTTPFormValues(reader *multipart.Reader) (*multipart.Part, map[string]string, *probe.Error) {
 	/// HTML Form values
 	formValues := make(map[string]string)
-	filePart := new(bytes.Buffer)
-	var e error
-	for e == nil {
-		var part *multipart.Part
-		part, e = reader.NextPart()
-		if part != nil {
-			if part.FileName() == "" {
-				buffer, e := ioutil.ReadAll(part)
-				if e != nil {
-					return nil, nil, probe.NewError(e)
-				}
-				formValues[http.CanonicalHeaderKey(part.FormName())] = string(buffer)
-			} else {
-				if _, e := io.Copy(filePart, part); e != nil {
-					return nil, nil, probe.NewError(e)
-				}
-			}
+	left := int64(maxFormFieldsSize)
+	for {
+		part, e := reader.NextPart()
+		if e == io.EOF {
+			return nil, nil, probe.NewError(errors.New("POST requires exactly one file upload per request"))
+		}
+		if e != nil {
+			return nil, nil, probe.NewError(e)
+		}
+		if part.FileName() != "" || strings.EqualFold(part.FormName(), "file") {
+			return part, formValues, nil
 		}
+		buffer, e := ioutil.ReadAll(io.LimitReader(part, left+1))
+		if e != nil {
+			return nil, nil, probe.NewError(e)
+		}
+		if left -= int64(len(buffer)); left < 0 {
+			return nil, nil, probe.NewError(errors.New("form fields are too large"))
+		}
+		formValues[http.CanonicalHeaderKey(part.FormName())] = string(buffer)
 	}
-	return filePart, formValues, nil
+}
+
+// errContentLengthRange - file of a POST policy request is out of the
+// content length range of the policy.
+var errContentLengthRange = errors.New("file size is out of the content length range of the policy")
+
+// contentLengthRangeReader - reads the file of a POST policy request,
+// fails once it is larger than max, or ends before min bytes.
+type contentLengthRangeReader struct {
+	io.Reader
+	min, max int64
+	n        int64
+	s3Error  APIErrorCode
+}
+
+func (r *contentLengthRangeReader) Read(p []byte) (int, error) {
+	n, e := r.Reader.Read(p)
+	r.n += int64(n)
+	if r.max > 0 && r.n > r.max {
+		r.s3Error = ErrEntityTooLarge
+		return n, errContentLengthRange
+	}
+	if e == io.EOF && r.n < r.min {
+		r.s3Error = ErrEntityTooSmall
+		return n, errContentLengthRange
+	}
+	return n, e
 }
 
 // PostPolicyBucketHandler - POST policy
 // ----------
 // This implementation of the POST operation handles object creation with a specified
-// signature policy in multipart/form-data
+// signature policy in multipart/form-data. Form fields are verified
+// before the file, which is streamed to the object.
 func (api objectAPIHandlers) PostPolicyBucketHandler(w http.ResponseWriter, r *http.Request) {
-	// Here the parameter is the size of the form data that should
-	// be loaded in memory, the remaining being put in temporary files.
 	reader, e := r.MultipartReader()
 	if e != nil {
 		errorIf(probe.NewError(e), "Unable to initialize multipart reader.", nil)
@@ -553,7 +675,7 @@ func (api objectAPIHandlers) PostPolicyBucketHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	fileBody, formValues, err := extractHTTPFormValues(reader)
+	filePart, formValues, err := extractHTTPFormValues(reader)
 	if err != nil {
 		errorIf(err.Trace(), "Unable to parse form values.", nil)
 		writeErrorResponse(w, r, ErrMalformedPOSTRequest, r.URL.Path)
@@ -563,39 +685,119 @@ func (api objectAPIHandlers) PostPolicyBucketHandler(w http.ResponseWriter, r *h
 	formValues["Bucket"] = bucket
 	object := formValues["Key"]
 
-	// Verify policy signature.
+	// Verify policy signature, and the form fields against the
+	// policy.
 	apiErr := doesPolicySignatureMatch(formValues)
+	if apiErr == ErrNone && !isPostPolicyAllowed(formValues, bucket) {
+		apiErr = ErrAccessDenied
+	}
+	if apiErr == ErrNone {
+		apiErr = checkPostPolicy(formValues, -1)
+	}
+	if apiErr == ErrNone && object == "" {
+		apiErr = ErrMissingFields
+	}
+	var metadata map[string]string
+	if apiErr == ErrNone {
+		header := make(http.Header)
+		for key, value := range formValues {
+			header.Set(key, value)
+		}
+		metadata, apiErr = extractUserMetadata(header)
+	}
 	if apiErr != ErrNone {
+		recordPostPolicyRequest(formValues, 0, apiErr)
 		writeErrorResponse(w, r, apiErr, r.URL.Path)
 		return
 	}
-	if apiErr = checkPostPolicy(formValues); apiErr != ErrNone {
-		writeErrorResponse(w, r, apiErr, r.URL.Path)
+	// Keys may name objects after the uploaded file.
+	object = strings.Replace(object, "${filename}", filePart.FileName(), -1)
+
+	// Size of the file is verified while it is written.
+	fileBody := &contentLengthRangeReader{Reader: filePart}
+	if fileBody.min, fileBody.max, err = getPostPolicyContentLengthRange(formValues); err != nil {
+		recordPostPolicyRequest(formValues, 0, ErrMalformedPOSTRequest)
+		writeErrorResponse(w, r, ErrMalformedPOSTRequest, r.URL.Path)
 		return
 	}
-	md5Sum, err := api.ObjectAPI.PutObject(bucket, object, -1, fileBody, nil)
+	var sequence uint64
+	md5Sum, err := api.ObjectAPI.WithContext(r.Context()).WithSequence(&sequence).PutObject(bucket, object, -1, fileBody, metadata)
 	if err != nil {
 		errorIf(err.Trace(), "PutObject failed.", nil)
+		apiErr = ErrInternalError
 		switch err.ToGoError().(type) {
 		case StorageFull:
-			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+			apiErr = ErrStorageFull
+		case QuotaExceeded:
+			apiErr = ErrQuotaExceeded
 		case BucketNotFound:
-			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+			apiErr = ErrNoSuchBucket
 		case BucketNameInvalid:
-			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+			apiErr = ErrInvalidBucketName
+		case ObjectNameInvalid:
+			apiErr = ErrNoSuchKey
 		case BadDigest:
-			writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
+			apiErr = ErrBadDigest
 		case IncompleteBody:
-			writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
-		default:
-			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			apiErr = ErrIncompleteBody
+		case ObjectTooLarge:
+			apiErr = ErrEntityTooLarge
+		}
+		if fileBody.s3Error != ErrNone {
+			apiErr = fileBody.s3Error
 		}
+		recordPostPolicyRequest(formValues, fileBody.n, apiErr)
+		writeErrorResponse(w, r, apiErr, r.URL.Path)
 		return
 	}
+	recordPostPolicyRequest(formValues, fileBody.n, ErrNone)
+	writePostPolicyResponse(w, r, formValues, bucket, object, md5Sum)
+
+	// Notify object created event.
+	notifyObjectCreated(api.ObjectAPI, r, ObjectCreatedPost, bucket, object, md5Sum, sequence)
+}
+
+// writePostPolicyResponse - writes response of an object uploaded by a
+// POST policy request. Browsers are redirected to
+// success_action_redirect if set, the status is success_action_status
+// otherwise, 204 by default.
+func writePostPolicyResponse(w http.ResponseWriter, r *http.Request, formValues map[string]string, bucket, object, md5Sum string) {
 	if md5Sum != "" {
 		w.Header().Set("ETag", "\""+md5Sum+"\"")
 	}
-	writeSuccessResponse(w, nil)
+	location := getObjectLocation(r, bucket, object)
+	w.Header().Set("Location", location)
+	if redirect, e := url.Parse(formValues["Success_action_redirect"]); e == nil && redirect.IsAbs() {
+		query := redirect.Query()
+		query.Set("bucket", bucket)
+		query.Set("key", object)
+		query.Set("etag", "\""+md5Sum+"\"")
+		redirect.RawQuery = query.Encode()
+		setCommonHeaders(w)
+		http.Redirect(w, r, redirect.String(), http.StatusSeeOther)
+		return
+	}
+	switch formValues["Success_action_status"] {
+	case "200":
+		writeSuccessResponse(w, nil)
+	case "201":
+		encodedSuccessResponse := encodeResponse(generatePostResponse(location, bucket, object, md5Sum))
+		setCommonHeaders(w)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(encodedSuccessResponse)
+	default:
+		writeSuccessNoContent(w)
+	}
+}
+
+// getObjectLocation - returns URL of object of bucket served by r.
+func getObjectLocation(r *http.Request, bucket, object string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: "/" + bucket + "/" + object}
+	return u.String()
 }
 
 // HeadBucketHandler - HEAD Bucket
@@ -641,9 +843,26 @@ func (api objectAPIHandlers) HeadBucketHandler(w http.ResponseWriter, r *http.Re
 		}
 		return
 	}
+	// Usage found by the last crawl, if the bucket was crawled.
+	if usage, lastUpdate, ok := globalDataUsage.Bucket(bucket); ok {
+		w.Header().Set(bucketObjectsHeader, strconv.FormatInt(usage.Objects, 10))
+		w.Header().Set(bucketSizeHeader, strconv.FormatInt(usage.Size, 10))
+		w.Header().Set(bucketActualSizeHeader, strconv.FormatInt(usage.ActualSize, 10))
+		w.Header().Set(bucketUsageUpdatedHeader, lastUpdate.Format(http.TimeFormat))
+	}
 	writeSuccessResponse(w, nil)
 }
 
+const (
+	// forceDeleteHeader - deletes a bucket along with its objects,
+	// which are purged asynchronously.
+	forceDeleteHeader = "X-Minio-Force-Delete"
+
+	// purgeJobIDHeader - id of the purge job of a force deleted
+	// bucket, progress is reported by the admin API.
+	purgeJobIDHeader = "X-Minio-Purge-Job-Id"
+)
+
 // DeleteBucketHandler - Delete bucket
 func (api objectAPIHandlers) DeleteBucketHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -661,6 +880,31 @@ func (api objectAPIHandlers) DeleteBucketHandler(w http.ResponseWriter, r *http.
 		}
 	}
 
+	// Force delete hides the bucket right away, its objects are
+	// purged in background.
+	if strings.EqualFold(r.Header.Get(forceDeleteHeader), "true") {
+		jobID, err := api.ObjectAPI.PurgeBucket(bucket)
+		if err != nil {
+			errorIf(err.Trace(bucket), "PurgeBucket failed.", nil)
+			switch err.ToGoError().(type) {
+			case BucketNameInvalid:
+				writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+			case BucketNotFound:
+				writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+			case BucketObjectLocked:
+				writeErrorResponse(w, r, ErrBucketObjectLocked, r.URL.Path)
+			default:
+				writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			}
+			return
+		}
+		unregisterFederatedBucket(bucket)
+		setCommonHeaders(w)
+		w.Header().Set(purgeJobIDHeader, jobID)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	err := api.ObjectAPI.DeleteBucket(bucket)
 	if err != nil {
 		errorIf(err.Trace(), "DeleteBucket failed.", nil)
@@ -678,6 +922,19 @@ func (api objectAPIHandlers) DeleteBucketHandler(w http.ResponseWriter, r *http.
 	// Delete bucket access policy, if present - ignore any errors.
 	removeBucketPolicy(bucket)
 
+	unregisterFederatedBucket(bucket)
+
 	// Write success response.
 	writeSuccessNoContent(w)
 }
+
+// unregisterFederatedBucket - removes deleted bucket from the
+// federation directory, its name can be reused by any deployment.
+func unregisterFederatedBucket(bucket string) {
+	if globalFederation == nil {
+		return
+	}
+	if e := globalFederation.unregister(bucket); e != nil {
+		errorIf(probe.NewError(e).Trace(bucket), "Unregistering federated bucket failed.", nil)
+	}
+}
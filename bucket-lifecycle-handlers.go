@@ -0,0 +1,240 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// PutBucketLifecycleHandler - PUT Bucket lifecycle
+// -----------------
+// This implementation of the PUT operation uses the lifecycle
+// subresource to add to or replace the lifecycle configuration of a
+// bucket, expiration and transition rules are supported. Transition
+// storage class is the name of a remote tier in server config.
+func (api objectAPIHandlers) PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxLifecycleConfigSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	// Lifecycle can only be set on existing buckets.
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err.Trace(bucket), "GetBucketInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	lifecycleBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxLifecycleConfigSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket), "Reading lifecycle configuration failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	// Parse and validate lifecycle configuration.
+	var lc lifecycleConfiguration
+	if e = xml.Unmarshal(lifecycleBytes, &lc); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse lifecycle configuration.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	if e = lc.Validate(); e != nil {
+		errorIf(probe.NewError(e), "Invalid lifecycle configuration.", nil)
+		writeErrorResponse(w, r, ErrInvalidLifecycleRule, r.URL.Path)
+		return
+	}
+
+	for _, rule := range lc.Rules {
+		if rule.Transition == nil {
+			continue
+		}
+		if _, ok := serverConfig.GetTier(rule.Transition.StorageClass); !ok {
+			writeErrorResponse(w, r, ErrInvalidStorageClass, r.URL.Path)
+			return
+		}
+	}
+
+	// Save bucket lifecycle configuration.
+	if err := writeBucketLifecycle(bucket, lifecycleBytes); err != nil {
+		errorIf(err.Trace(bucket), "SaveBucketLifecycle failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetBucketLifecycleHandler - GET Bucket lifecycle
+// -----------------
+// This operation uses the lifecycle subresource to return the
+// lifecycle configuration of a specified bucket.
+func (api objectAPIHandlers) GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Read bucket lifecycle configuration.
+	lifecycleBytes, err := readBucketLifecycle(bucket)
+	if err != nil {
+		errorIf(err.Trace(bucket), "GetBucketLifecycle failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketLifecycleNotFound:
+			writeErrorResponse(w, r, ErrNoSuchLifecycleConfiguration, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	setCommonHeaders(w)
+	writeSuccessResponse(w, lifecycleBytes)
+}
+
+// DeleteBucketLifecycleHandler - DELETE Bucket lifecycle
+// -----------------
+// This implementation of the DELETE operation uses the lifecycle
+// subresource to remove the lifecycle configuration of a bucket.
+func (api objectAPIHandlers) DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Delete bucket lifecycle configuration.
+	if err := removeBucketLifecycle(bucket); err != nil {
+		errorIf(err.Trace(bucket), "DeleteBucketLifecycle failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketLifecycleNotFound:
+			writeErrorResponse(w, r, ErrNoSuchLifecycleConfiguration, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// RestoreObjectHandler - POST Object restore
+// -----------------
+// This operation copies data of an object transitioned to a remote
+// tier back to the server, restored objects are no longer subject to
+// the transition until they age again.
+func (api objectAPIHandlers) RestoreObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	restored, err := api.ObjectAPI.RestoreObject(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "RestoreObject failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case ObjectNotFound, ObjectNameInvalid:
+			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	// Object data is already local.
+	if !restored {
+		writeSuccessResponse(w, nil)
+		return
+	}
+	setCommonHeaders(w)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,215 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Default interval between two lifecycle scans.
+	defaultLifecycleScanInterval = 1 * time.Hour
+
+	// Default maximum number of expired objects deleted per second.
+	defaultLifecycleDeletesPerSecond = 100
+
+	// Number of objects listed at a time while scanning.
+	lifecycleListBatchSize = 1000
+)
+
+// lifecycleScanner - configuration of the background worker
+// expiring and transitioning objects as per bucket lifecycle rules.
+type lifecycleScanner struct {
+	// Interval between two scans in seconds.
+	ScanInterval int64 `json:"scanInterval"`
+	// Maximum objects expired or transitioned per second, limits the
+	// load lifecycle rules put on the disks.
+	DeletesPerSecond int `json:"deletesPerSecond"`
+}
+
+// newLifecycleScanner - lifecycle configuration for fresh and
+// migrated configs.
+func newLifecycleScanner() lifecycleScanner {
+	return lifecycleScanner{
+		ScanInterval:     int64(defaultLifecycleScanInterval / time.Second),
+		DeletesPerSecond: defaultLifecycleDeletesPerSecond,
+	}
+}
+
+// interval - returns scan interval, defaults if not configured.
+func (l lifecycleScanner) interval() time.Duration {
+	if l.ScanInterval <= 0 {
+		return defaultLifecycleScanInterval
+	}
+	return time.Duration(l.ScanInterval) * time.Second
+}
+
+// deleteInterval - returns minimum interval between two deletes.
+func (l lifecycleScanner) deleteInterval() time.Duration {
+	deletesPerSecond := l.DeletesPerSecond
+	if deletesPerSecond <= 0 {
+		deletesPerSecond = defaultLifecycleDeletesPerSecond
+	}
+	return time.Second / time.Duration(deletesPerSecond)
+}
+
+// lifecycleWorker - scans all buckets with lifecycle configuration,
+// deletes expired objects and transitions objects to remote tiers.
+type lifecycleWorker struct {
+	objAPI objectAPI
+}
+
+// initLifecycleWorker - starts the background lifecycle worker.
+func initLifecycleWorker(objAPI objectAPI) {
+	lw := lifecycleWorker{objAPI: objAPI}
+	go func() {
+		for {
+			lw.scan()
+			time.Sleep(serverConfig.GetLifecycle().interval())
+		}
+	}()
+}
+
+// scan - applies lifecycle rules to all buckets once.
+func (lw lifecycleWorker) scan() {
+	buckets, err := lw.objAPI.ListBuckets()
+	if err != nil {
+		errorIf(err.Trace(), "Unable to list buckets for lifecycle scan.", nil)
+		return
+	}
+	pacer := time.NewTicker(serverConfig.GetLifecycle().deleteInterval())
+	defer pacer.Stop()
+	for _, bucket := range buckets {
+		lifecycleBytes, err := readBucketLifecycle(bucket.Name)
+		if err != nil {
+			if _, ok := err.ToGoError().(BucketLifecycleNotFound); !ok {
+				errorIf(err.Trace(bucket.Name), "Unable to read bucket lifecycle.", nil)
+			}
+			continue
+		}
+		lc, e := parseBucketLifecycle(lifecycleBytes)
+		if e != nil {
+			errorIf(probe.NewError(e).Trace(bucket.Name), "Unable to parse bucket lifecycle.", nil)
+			continue
+		}
+		if err = lw.applyRules(bucket.Name, lc, pacer.C); err != nil {
+			errorIf(err.Trace(bucket.Name), "Lifecycle scan failed.", nil)
+		}
+	}
+}
+
+// applyRules - deletes all expired objects in bucket and transitions
+// objects due, waiting on pacer before every object.
+func (lw lifecycleWorker) applyRules(bucket string, lc lifecycleConfiguration, pacer <-chan time.Time) *probe.Error {
+	marker := ""
+	for {
+		result, err := lw.objAPI.ListObjects(bucket, "", marker, "", lifecycleListBatchSize)
+		if err != nil {
+			return err.Trace(bucket, marker)
+		}
+		for _, object := range result.Objects {
+			var tags map[string]string
+			if lc.hasTagFilters() {
+				tags = lw.objectTags(bucket, object.Name)
+			}
+			now := time.Now().UTC()
+			if !lc.isExpired(object.Name, tags, object.ModTime, now) {
+				if tier := lc.transitionTier(object.Name, tags, object.ModTime, now); tier != "" {
+					<-pacer
+					err = lw.transitionObject(bucket, object.Name, tier)
+					errorIf(err.Trace(bucket, object.Name, tier), "Unable to transition object.", nil)
+				}
+				continue
+			}
+			<-pacer
+			if err = lw.expireObject(bucket, object.Name, tags, lc); err != nil {
+				// Write quorum is not available, stop here rather than
+				// failing every delete, the next scan resumes.
+				if _, ok := err.ToGoError().(StorageInsufficientWriteResources); ok {
+					return err.Trace(bucket, object.Name)
+				}
+				errorIf(err.Trace(bucket, object.Name), "Unable to expire object.", nil)
+			}
+		}
+		if !result.IsTruncated || len(result.Objects) == 0 {
+			return nil
+		}
+		marker = result.Objects[len(result.Objects)-1].Name
+	}
+}
+
+// objectTags - returns tags of object, objects with unreadable tags
+// match no tag filters.
+func (lw lifecycleWorker) objectTags(bucket, object string) map[string]string {
+	tags, e := lw.objAPI.readObjectTags(bucket, object)
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to read object tags.", nil)
+	}
+	return tags
+}
+
+// expireObject - deletes object if it is still expired, the object
+// may have been overwritten since it was listed.
+func (lw lifecycleWorker) expireObject(bucket, object string, tags map[string]string, lc lifecycleConfiguration) *probe.Error {
+	objInfo, err := lw.objAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.ToGoError().(ObjectNotFound); ok {
+			return nil
+		}
+		return err.Trace(bucket, object)
+	}
+	if !lc.isExpired(object, tags, objInfo.ModTime, time.Now().UTC()) {
+		return nil
+	}
+	if err = lw.objAPI.DeleteObject(bucket, object, false); err != nil {
+		switch err.ToGoError().(type) {
+		case ObjectNotFound:
+			return nil
+		case ObjectLocked:
+			// Retained objects expire once their retention ends.
+			return nil
+		}
+		return err.Trace(bucket, object)
+	}
+	log.WithFields(logrus.Fields{
+		"bucket": bucket,
+		"object": object,
+	}).Debug("Expired object as per bucket lifecycle.")
+	return nil
+}
+
+// transitionObject - transitions object to tier if it is still due,
+// transitioned objects are skipped by the object layer.
+func (lw lifecycleWorker) transitionObject(bucket, object, tier string) *probe.Error {
+	objInfo, err := lw.objAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.ToGoError().(ObjectNotFound); ok {
+			return nil
+		}
+		return err.Trace(bucket, object)
+	}
+	if err = lw.objAPI.TransitionObject(bucket, object, objInfo.ModTime, tier); err != nil {
+		if _, ok := err.ToGoError().(ObjectNotFound); ok {
+			return nil
+		}
+		return err.Trace(bucket, object)
+	}
+	return nil
+}
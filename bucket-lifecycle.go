@@ -0,0 +1,346 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Lifecycle configuration file name, saved in bucket config path.
+	bucketLifecycleConfigFile = "lifecycle.xml"
+
+	// Maximum size of lifecycle configuration, same as S3.
+	maxLifecycleConfigSize = 20 * 1024
+
+	// Maximum number of rules per lifecycle configuration, same as S3.
+	maxLifecycleRules = 1000
+)
+
+var (
+	errLifecycleNoRules        = errors.New("Lifecycle configuration should have at least one rule")
+	errLifecycleTooManyRules   = errors.New("Lifecycle configuration allows a maximum of 1000 rules")
+	errLifecycleDuplicateID    = errors.New("Lifecycle configuration has duplicate rule ids")
+	errLifecycleInvalidStatus  = errors.New("Lifecycle rule status should be either Enabled or Disabled")
+	errLifecycleNoAction       = errors.New("Lifecycle rule should have an expiration or a transition")
+	errLifecycleBothExpiration = errors.New("Lifecycle rule cannot have both expiration days and date")
+	errLifecycleInvalidDays    = errors.New("Lifecycle rule expiration days should be a positive integer")
+	errLifecycleInvalidDate    = errors.New("Lifecycle rule expiration date should be midnight UTC in ISO 8601 format")
+	errLifecycleTransitionDays = errors.New("Lifecycle rule transition days should be a positive integer")
+	errLifecycleNoStorageClass = errors.New("Lifecycle rule transition should have a storage class")
+	errLifecycleInvalidFilter  = errors.New("Lifecycle rule filter should have only one of prefix, tag or and")
+)
+
+// lifecycleExpiration - expiration action of a rule, either after
+// days since object creation or on a date.
+type lifecycleExpiration struct {
+	Days int    `xml:"Days,omitempty"`
+	Date string `xml:"Date,omitempty"`
+}
+
+// lifecycleTransition - transition action of a rule, moves objects
+// to the remote tier named by storage class after days since object
+// creation.
+type lifecycleTransition struct {
+	Days         int    `xml:"Days"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// lifecycleAnd - combines prefix and tags of a rule filter.
+type lifecycleAnd struct {
+	Prefix string      `xml:"Prefix,omitempty"`
+	Tags   []objectTag `xml:"Tag"`
+}
+
+// lifecycleFilter - newer form of rule prefix, matches objects by
+// either prefix, a single tag or prefix and tags combined.
+type lifecycleFilter struct {
+	Prefix string        `xml:"Prefix,omitempty"`
+	Tag    *objectTag    `xml:"Tag,omitempty"`
+	And    *lifecycleAnd `xml:"And,omitempty"`
+}
+
+// lifecycleRule - single lifecycle rule.
+type lifecycleRule struct {
+	ID         string               `xml:"ID,omitempty"`
+	Prefix     string               `xml:"Prefix,omitempty"`
+	Filter     *lifecycleFilter     `xml:"Filter,omitempty"`
+	Status     string               `xml:"Status"`
+	Expiration lifecycleExpiration  `xml:"Expiration"`
+	Transition *lifecycleTransition `xml:"Transition,omitempty"`
+
+	// Parsed expiration date.
+	expirationDate time.Time
+}
+
+// lifecycleConfiguration - bucket lifecycle configuration, expiration
+// and transition rules are supported.
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+// prefix - returns the object name prefix the rule applies to.
+func (rule lifecycleRule) prefix() string {
+	if rule.Filter != nil {
+		if rule.Filter.And != nil {
+			return rule.Filter.And.Prefix
+		}
+		return rule.Filter.Prefix
+	}
+	return rule.Prefix
+}
+
+// tags - returns the object tags the rule applies to.
+func (rule lifecycleRule) tags() []objectTag {
+	switch {
+	case rule.Filter == nil:
+		return nil
+	case rule.Filter.Tag != nil:
+		return []objectTag{*rule.Filter.Tag}
+	case rule.Filter.And != nil:
+		return rule.Filter.And.Tags
+	}
+	return nil
+}
+
+// matches - returns true if the rule is enabled and applies to the
+// object with tags.
+func (rule lifecycleRule) matches(object string, tags map[string]string) bool {
+	if rule.Status != "Enabled" || !strings.HasPrefix(object, rule.prefix()) {
+		return false
+	}
+	for _, tag := range rule.tags() {
+		if value, ok := tags[tag.Key]; !ok || value != tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// isExpired - returns true if object with tags last modified at
+// modTime has expired as of now according to the rule.
+func (rule lifecycleRule) isExpired(object string, tags map[string]string, modTime, now time.Time) bool {
+	if !rule.matches(object, tags) {
+		return false
+	}
+	if rule.Expiration.Days > 0 {
+		return !now.Before(modTime.Add(time.Duration(rule.Expiration.Days) * 24 * time.Hour))
+	}
+	if rule.Expiration.Date == "" {
+		// Transition only rule.
+		return false
+	}
+	return !now.Before(rule.expirationDate)
+}
+
+// transitionTier - returns the tier object with tags last modified at
+// modTime is due to be transitioned to as of now, empty if none.
+func (rule lifecycleRule) transitionTier(object string, tags map[string]string, modTime, now time.Time) string {
+	if rule.Transition == nil || !rule.matches(object, tags) {
+		return ""
+	}
+	if now.Before(modTime.Add(time.Duration(rule.Transition.Days) * 24 * time.Hour)) {
+		return ""
+	}
+	return rule.Transition.StorageClass
+}
+
+// isExpired - returns true if any of the rules expires the object.
+func (lc lifecycleConfiguration) isExpired(object string, tags map[string]string, modTime, now time.Time) bool {
+	for _, rule := range lc.Rules {
+		if rule.isExpired(object, tags, modTime, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionTier - returns the tier of the first rule transitioning
+// the object, empty if none.
+func (lc lifecycleConfiguration) transitionTier(object string, tags map[string]string, modTime, now time.Time) string {
+	for _, rule := range lc.Rules {
+		if tier := rule.transitionTier(object, tags, modTime, now); tier != "" {
+			return tier
+		}
+	}
+	return ""
+}
+
+// hasTagFilters - returns true if any of the rules filters by tags,
+// object tags need to be read only then.
+func (lc lifecycleConfiguration) hasTagFilters() bool {
+	for _, rule := range lc.Rules {
+		if len(rule.tags()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBucketLifecycle - parses and validates lifecycle configuration.
+func parseBucketLifecycle(lifecycleBytes []byte) (lifecycleConfiguration, error) {
+	var lc lifecycleConfiguration
+	if e := xml.Unmarshal(lifecycleBytes, &lc); e != nil {
+		return lifecycleConfiguration{}, e
+	}
+	if e := lc.Validate(); e != nil {
+		return lifecycleConfiguration{}, e
+	}
+	return lc, nil
+}
+
+// Validate - validates all the rules, parsed expiration dates are
+// saved in the rules.
+func (lc *lifecycleConfiguration) Validate() error {
+	if len(lc.Rules) == 0 {
+		return errLifecycleNoRules
+	}
+	if len(lc.Rules) > maxLifecycleRules {
+		return errLifecycleTooManyRules
+	}
+	ids := make(map[string]struct{})
+	for i, rule := range lc.Rules {
+		if rule.ID != "" {
+			if _, ok := ids[rule.ID]; ok {
+				return errLifecycleDuplicateID
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if rule.Status != "Enabled" && rule.Status != "Disabled" {
+			return errLifecycleInvalidStatus
+		}
+		if rule.Filter != nil {
+			filter := rule.Filter
+			if (filter.Prefix != "" && (filter.Tag != nil || filter.And != nil)) || (filter.Tag != nil && filter.And != nil) {
+				return errLifecycleInvalidFilter
+			}
+			if e := (objectTagging{TagSet: rule.tags()}).Validate(); e != nil {
+				return e
+			}
+		}
+		if rule.Transition != nil {
+			if rule.Transition.Days <= 0 {
+				return errLifecycleTransitionDays
+			}
+			if rule.Transition.StorageClass == "" {
+				return errLifecycleNoStorageClass
+			}
+		}
+		switch {
+		case rule.Expiration.Days == 0 && rule.Expiration.Date == "":
+			if rule.Transition == nil {
+				return errLifecycleNoAction
+			}
+		case rule.Expiration.Days != 0 && rule.Expiration.Date != "":
+			return errLifecycleBothExpiration
+		case rule.Expiration.Days < 0:
+			return errLifecycleInvalidDays
+		case rule.Expiration.Date != "":
+			date, e := time.Parse(time.RFC3339, rule.Expiration.Date)
+			if e != nil {
+				return errLifecycleInvalidDate
+			}
+			date = date.UTC()
+			if !date.Equal(date.Truncate(24 * time.Hour)) {
+				return errLifecycleInvalidDate
+			}
+			lc.Rules[i].expirationDate = date
+		}
+	}
+	return nil
+}
+
+// getBucketLifecycleFile - get lifecycle configuration file path.
+func getBucketLifecycleFile(bucket string) (string, *probe.Error) {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(bucketConfigPath, bucketLifecycleConfigFile), nil
+}
+
+// readBucketLifecycle - read bucket lifecycle configuration.
+func readBucketLifecycle(bucket string) ([]byte, *probe.Error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	lifecycleFile, err := getBucketLifecycleFile(bucket)
+	if err != nil {
+		return nil, err.Trace(bucket)
+	}
+	lifecycleBytes, e := ioutil.ReadFile(lifecycleFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, probe.NewError(BucketLifecycleNotFound{Bucket: bucket})
+		}
+		return nil, probe.NewError(e)
+	}
+	return lifecycleBytes, nil
+}
+
+// removeBucketLifecycle - remove bucket lifecycle configuration.
+func removeBucketLifecycle(bucket string) *probe.Error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	lifecycleFile, err := getBucketLifecycleFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := os.Remove(lifecycleFile); e != nil {
+		if os.IsNotExist(e) {
+			return probe.NewError(BucketLifecycleNotFound{Bucket: bucket})
+		}
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// writeBucketLifecycle - save bucket lifecycle configuration.
+func writeBucketLifecycle(bucket string, lifecycleBytes []byte) *probe.Error {
+	// Verify if bucket path legal.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	// Create bucket config path.
+	if err := createBucketConfigPath(bucket); err != nil {
+		return err.Trace()
+	}
+
+	lifecycleFile, err := getBucketLifecycleFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := ioutil.WriteFile(lifecycleFile, lifecycleBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
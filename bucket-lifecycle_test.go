@@ -0,0 +1,172 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests lifecycle configuration validation.
+func TestParseBucketLifecycle(t *testing.T) {
+	testCases := []struct {
+		lifecycle string
+		err       error
+	}{
+		{`<LifecycleConfiguration><Rule><ID>1</ID><Prefix>logs/</Prefix><Status>Enabled</Status><Expiration><Days>30</Days></Expiration></Rule></LifecycleConfiguration>`, nil},
+		{`<LifecycleConfiguration><Rule><Filter><Prefix>logs/</Prefix></Filter><Status>Disabled</Status><Expiration><Date>2016-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, nil},
+		{`<LifecycleConfiguration></LifecycleConfiguration>`, errLifecycleNoRules},
+		{`<LifecycleConfiguration><Rule><ID>1</ID><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>1</ID><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`, errLifecycleDuplicateID},
+		{`<LifecycleConfiguration><Rule><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, errLifecycleInvalidStatus},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`, errLifecycleNoAction},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days><Date>2016-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, errLifecycleBothExpiration},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>-1</Days></Expiration></Rule></LifecycleConfiguration>`, errLifecycleInvalidDays},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2016-01-01T10:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, errLifecycleInvalidDate},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>cold</StorageClass></Transition></Rule></LifecycleConfiguration>`, nil},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>0</Days><StorageClass>cold</StorageClass></Transition></Rule></LifecycleConfiguration>`, errLifecycleTransitionDays},
+		{`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>30</Days></Transition></Rule></LifecycleConfiguration>`, errLifecycleNoStorageClass},
+		{`<LifecycleConfiguration><Rule><Filter><And><Prefix>logs/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></And></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, nil},
+		{`<LifecycleConfiguration><Rule><Filter><Prefix>logs/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, errLifecycleInvalidFilter},
+		{`<LifecycleConfiguration><Rule><Filter><And><Tag><Key>k</Key></Tag><Tag><Key>k</Key></Tag></And></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, errDuplicateTagKey},
+	}
+	for i, testCase := range testCases {
+		if _, e := parseBucketLifecycle([]byte(testCase.lifecycle)); e != testCase.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, e)
+		}
+	}
+}
+
+// Tests expiration of objects as per lifecycle rules.
+func TestLifecycleExpireObjects(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-lifecycle-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	objects := []string{"logs/1", "logs/2", "data/1"}
+	for _, object := range objects {
+		if _, err := obj.PutObject("bucket", object, int64(len("hello")), bytes.NewBufferString("hello"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lc, e := parseBucketLifecycle([]byte(`<LifecycleConfiguration>
+<Rule><Prefix>logs/</Prefix><Status>Enabled</Status><Expiration><Date>2016-01-01T00:00:00Z</Date></Expiration></Rule>
+<Rule><Prefix>data/</Prefix><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>
+</LifecycleConfiguration>`))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// Do not wait between deletes.
+	pacer := make(chan time.Time)
+	close(pacer)
+	if err := (lifecycleWorker{objAPI: obj}).applyRules("bucket", lc, pacer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects under "logs/" are expired, "data/1" is yet to expire.
+	result, err := obj.ListObjects("bucket", "", "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "data/1" {
+		t.Fatalf("Unexpected objects after expiration %v", result.Objects)
+	}
+}
+
+// Tests transition tier selection as per lifecycle rules.
+func TestLifecycleTransitionTier(t *testing.T) {
+	lc, e := parseBucketLifecycle([]byte(`<LifecycleConfiguration>
+<Rule><Prefix>logs/</Prefix><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>cold</StorageClass></Transition><Expiration><Days>365</Days></Expiration></Rule>
+<Rule><Prefix>data/</Prefix><Status>Disabled</Status><Transition><Days>1</Days><StorageClass>cold</StorageClass></Transition></Rule>
+</LifecycleConfiguration>`))
+	if e != nil {
+		t.Fatal(e)
+	}
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	testCases := []struct {
+		object  string
+		modTime time.Time
+		tier    string
+		expired bool
+	}{
+		{"logs/1", now.Add(-10 * day), "", false},
+		{"logs/1", now.Add(-30 * day), "cold", false},
+		{"logs/1", now.Add(-365 * day), "cold", true},
+		{"data/1", now.Add(-30 * day), "", false},
+		{"other", now.Add(-365 * day), "", false},
+	}
+	for i, testCase := range testCases {
+		if tier := lc.transitionTier(testCase.object, nil, testCase.modTime, now); tier != testCase.tier {
+			t.Errorf("Test %d: expected tier %q, got %q", i+1, testCase.tier, tier)
+		}
+		if expired := lc.isExpired(testCase.object, nil, testCase.modTime, now); expired != testCase.expired {
+			t.Errorf("Test %d: expected expired %v, got %v", i+1, testCase.expired, expired)
+		}
+	}
+}
+
+// Tests rules filtering objects by tags.
+func TestLifecycleTagFilter(t *testing.T) {
+	lc, e := parseBucketLifecycle([]byte(`<LifecycleConfiguration>
+<Rule><Filter><Tag><Key>temp</Key><Value>true</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>
+<Rule><Filter><And><Prefix>logs/</Prefix><Tag><Key>class</Key><Value>archive</Value></Tag><Tag><Key>team</Key><Value>ops</Value></Tag></And></Filter><Status>Enabled</Status><Transition><Days>1</Days><StorageClass>cold</StorageClass></Transition></Rule>
+</LifecycleConfiguration>`))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !lc.hasTagFilters() {
+		t.Fatal("Expected tag filters")
+	}
+	now := time.Now().UTC()
+	modTime := now.Add(-2 * 24 * time.Hour)
+	testCases := []struct {
+		object  string
+		tags    map[string]string
+		tier    string
+		expired bool
+	}{
+		{"data/1", nil, "", false},
+		{"data/1", map[string]string{"temp": "true"}, "", true},
+		{"data/1", map[string]string{"temp": "false"}, "", false},
+		{"logs/1", map[string]string{"class": "archive"}, "", false},
+		{"logs/1", map[string]string{"class": "archive", "team": "ops", "other": "x"}, "cold", false},
+		{"data/1", map[string]string{"class": "archive", "team": "ops"}, "", false},
+	}
+	for i, testCase := range testCases {
+		if tier := lc.transitionTier(testCase.object, testCase.tags, modTime, now); tier != testCase.tier {
+			t.Errorf("Test %d: expected tier %q, got %q", i+1, testCase.tier, tier)
+		}
+		if expired := lc.isExpired(testCase.object, testCase.tags, modTime, now); expired != testCase.expired {
+			t.Errorf("Test %d: expected expired %v, got %v", i+1, testCase.expired, expired)
+		}
+	}
+}
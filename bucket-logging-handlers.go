@@ -0,0 +1,163 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// PutBucketLoggingHandler - PUT Bucket logging
+// -----------------
+// This implementation of the PUT operation uses the logging
+// subresource to set the logging parameters of a bucket, requests on
+// the bucket are written in the S3 server access log format to
+// objects under the target prefix of the target bucket. An empty
+// BucketLoggingStatus disables logging.
+func (api objectAPIHandlers) PutBucketLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxBucketLoggingConfigSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	// Logging can only be configured on existing buckets.
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err.Trace(bucket), "GetBucketInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	configBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxBucketLoggingConfigSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket), "Reading logging configuration failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	var status bucketLoggingStatus
+	if e = xml.Unmarshal(configBytes, &status); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse logging configuration.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	status.Xmlns = ""
+
+	// Access logs can only be written to existing buckets.
+	if status.LoggingEnabled != nil {
+		if _, err := api.ObjectAPI.GetBucketInfo(status.LoggingEnabled.TargetBucket); err != nil {
+			writeErrorResponse(w, r, ErrInvalidTargetBucketForLogging, r.URL.Path)
+			return
+		}
+	}
+
+	// Save bucket logging configuration.
+	if err := writeBucketLogging(bucket, status); err != nil {
+		errorIf(err.Trace(bucket), "SaveBucketLogging failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	if globalBucketLogger != nil {
+		globalBucketLogger.setTarget(bucket, status.LoggingEnabled)
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetBucketLoggingHandler - GET Bucket logging
+// -----------------
+// This operation uses the logging subresource to return the logging
+// status of a bucket, an empty BucketLoggingStatus if logging is not
+// enabled.
+func (api objectAPIHandlers) GetBucketLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err.Trace(bucket), "GetBucketInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	// Read bucket logging configuration.
+	status, err := readBucketLogging(bucket)
+	if err != nil {
+		errorIf(err.Trace(bucket), "GetBucketLogging failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	status.Xmlns = "http://doc.s3.amazonaws.com/2006-03-01"
+	setCommonHeaders(w)
+	writeSuccessResponse(w, encodeResponse(status))
+}
@@ -0,0 +1,478 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	router "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Logging configuration file name, saved in bucket config path.
+	bucketLoggingConfigFile = "logging.xml"
+
+	// Maximum size of logging configuration.
+	maxBucketLoggingConfigSize = 20 * 1024
+
+	// Interval between flushes of access logs to target buckets.
+	bucketLogFlushInterval = 5 * time.Minute
+
+	// Access logs of a target are flushed early once they grow beyond
+	// this size.
+	maxBucketLogBufferSize = 4 * 1024 * 1024
+
+	// Time format of access log lines.
+	bucketLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+	// Maximum size of error responses error codes are looked up in.
+	maxBucketLogErrorSize = 4096
+)
+
+// Subresources named in the operation of access log lines, in the
+// order they are looked up.
+var bucketLogSubresources = []string{
+	"acl", "attestation", "cors", "legal-hold", "lifecycle", "location", "logging",
+	"object-lock", "partmap", "policy", "replication", "restore", "retention", "select",
+	"tagging",
+}
+
+// Error code of S3 error responses.
+var bucketLogErrorCode = regexp.MustCompile("<Code>([^<]*)</Code>")
+
+// bucketLoggingEnabled - target bucket and key prefix access logs of
+// a bucket are written to.
+type bucketLoggingEnabled struct {
+	TargetBucket string `xml:"TargetBucket"`
+	TargetPrefix string `xml:"TargetPrefix"`
+}
+
+// bucketLoggingStatus - bucket logging configuration, logging is
+// disabled without LoggingEnabled.
+type bucketLoggingStatus struct {
+	XMLName        xml.Name              `xml:"BucketLoggingStatus"`
+	Xmlns          string                `xml:"xmlns,attr,omitempty"`
+	LoggingEnabled *bucketLoggingEnabled `xml:"LoggingEnabled,omitempty"`
+}
+
+// getBucketLoggingFile - get logging configuration file path.
+func getBucketLoggingFile(bucket string) (string, *probe.Error) {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(bucketConfigPath, bucketLoggingConfigFile), nil
+}
+
+// readBucketLogging - read bucket logging configuration, disabled if
+// never configured.
+func readBucketLogging(bucket string) (bucketLoggingStatus, *probe.Error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return bucketLoggingStatus{}, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	loggingFile, err := getBucketLoggingFile(bucket)
+	if err != nil {
+		return bucketLoggingStatus{}, err.Trace(bucket)
+	}
+	configBytes, e := ioutil.ReadFile(loggingFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return bucketLoggingStatus{}, nil
+		}
+		return bucketLoggingStatus{}, probe.NewError(e)
+	}
+	var status bucketLoggingStatus
+	if e = xml.Unmarshal(configBytes, &status); e != nil {
+		return bucketLoggingStatus{}, probe.NewError(e)
+	}
+	return status, nil
+}
+
+// writeBucketLogging - save bucket logging configuration, disabling
+// logging removes it.
+func writeBucketLogging(bucket string, status bucketLoggingStatus) *probe.Error {
+	// Verify if bucket path legal.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	loggingFile, err := getBucketLoggingFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if status.LoggingEnabled == nil {
+		if e := os.Remove(loggingFile); e != nil && !os.IsNotExist(e) {
+			return probe.NewError(e)
+		}
+		return nil
+	}
+
+	// Create bucket config path.
+	if err = createBucketConfigPath(bucket); err != nil {
+		return err.Trace()
+	}
+	configBytes, e := xml.Marshal(status)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = ioutil.WriteFile(loggingFile, configBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// bucketLogger - buffers access log lines of buckets with logging
+// enabled, and writes them as objects to their target buckets.
+type bucketLogger struct {
+	objAPI objectAPI
+	mutex  *sync.Mutex
+	// Logging configuration of buckets looked up, nil for buckets
+	// without logging.
+	targets map[string]*bucketLoggingEnabled
+	// Lines not flushed yet, by target.
+	buffers map[bucketLoggingEnabled]*bytes.Buffer
+}
+
+// Global bucket logger, initialized at server startup.
+var globalBucketLogger *bucketLogger
+
+func newBucketLogger(objAPI objectAPI) *bucketLogger {
+	return &bucketLogger{
+		objAPI:  objAPI,
+		mutex:   &sync.Mutex{},
+		targets: make(map[string]*bucketLoggingEnabled),
+		buffers: make(map[bucketLoggingEnabled]*bytes.Buffer),
+	}
+}
+
+// initBucketLogging - starts writing access logs to target buckets
+// periodically.
+func initBucketLogging(objAPI objectAPI) {
+	globalBucketLogger = newBucketLogger(objAPI)
+	go func() {
+		for {
+			time.Sleep(bucketLogFlushInterval)
+			globalBucketLogger.Flush()
+		}
+	}()
+}
+
+// target - returns target access logs of bucket are written to, nil
+// if logging is disabled.
+func (l *bucketLogger) target(bucket string) *bucketLoggingEnabled {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if target, ok := l.targets[bucket]; ok {
+		return target
+	}
+	status, err := readBucketLogging(bucket)
+	if err != nil {
+		// Buckets which do not exist are not logged, nor cached.
+		return nil
+	}
+	l.targets[bucket] = status.LoggingEnabled
+	return status.LoggingEnabled
+}
+
+// setTarget - sets target access logs of bucket are written to from
+// now on, nil disables logging.
+func (l *bucketLogger) setTarget(bucket string, target *bucketLoggingEnabled) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.targets[bucket] = target
+}
+
+// Log - buffers line for target, flushing the target once its lines
+// grow large.
+func (l *bucketLogger) Log(target bucketLoggingEnabled, line string) {
+	l.mutex.Lock()
+	buffer, ok := l.buffers[target]
+	if !ok {
+		buffer = &bytes.Buffer{}
+		l.buffers[target] = buffer
+	}
+	buffer.WriteString(line)
+	buffer.WriteByte('\n')
+	full := buffer.Len() >= maxBucketLogBufferSize
+	if full {
+		delete(l.buffers, target)
+	}
+	l.mutex.Unlock()
+	if full {
+		go l.write(target, buffer.Bytes())
+	}
+}
+
+// Flush - writes lines of all targets not written yet.
+func (l *bucketLogger) Flush() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	buffers := l.buffers
+	l.buffers = make(map[bucketLoggingEnabled]*bytes.Buffer)
+	l.mutex.Unlock()
+	for target, buffer := range buffers {
+		l.write(target, buffer.Bytes())
+	}
+}
+
+// write - writes lines as a new object of target, named after the
+// time it is written at as S3 does.
+func (l *bucketLogger) write(target bucketLoggingEnabled, lines []byte) {
+	unique := make([]byte, 8)
+	rand.Read(unique)
+	object := target.TargetPrefix + time.Now().UTC().Format("2006-01-02-15-04-05") + "-" + strings.ToUpper(hex.EncodeToString(unique))
+	_, err := l.objAPI.PutObject(target.TargetBucket, object, int64(len(lines)), bytes.NewReader(lines), map[string]string{
+		"content-type": "text/plain",
+	})
+	errorIf(err.Trace(target.TargetBucket, object), "Unable to write bucket access log.", logrus.Fields{
+		"lines": bytes.Count(lines, []byte{'\n'}),
+	})
+}
+
+// bucketLogResponseWriter - records the response along with the error
+// code of error responses.
+type bucketLogResponseWriter struct {
+	*statusResponseWriter
+	errorBody *bytes.Buffer
+}
+
+func (w bucketLogResponseWriter) Write(p []byte) (int, error) {
+	if w.statusCode >= 300 && w.errorBody.Len() < maxBucketLogErrorSize {
+		w.errorBody.Write(p)
+	}
+	return w.statusResponseWriter.Write(p)
+}
+
+// ReadFrom - writes data read from r, error responses are recorded.
+func (w bucketLogResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.statusCode >= 300 {
+		return io.Copy(writerOnly{w}, r)
+	}
+	return w.statusResponseWriter.ReadFrom(r)
+}
+
+// bucketLogHandler - records requests on buckets with logging enabled
+// in their access logs, after they are served.
+type bucketLogHandler struct {
+	handler http.Handler
+	mux     *router.Router
+}
+
+// setBucketLogHandler - returns handler function logging requests
+// matching named routes of mux.
+func setBucketLogHandler(mux *router.Router) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return bucketLogHandler{handler: h, mux: mux}
+	}
+}
+
+func (h bucketLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if globalBucketLogger == nil {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	var match router.RouteMatch
+	if !h.mux.Match(r, &match) || match.Route.GetName() == "" || match.Vars["bucket"] == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	target := globalBucketLogger.target(match.Vars["bucket"])
+	if target == nil {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	startTime := time.Now().UTC()
+	body := &auditReadCounter{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	lw := bucketLogResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(w),
+		errorBody:            &bytes.Buffer{},
+	}
+	h.handler.ServeHTTP(lw, r)
+	globalBucketLogger.Log(*target, formatBucketLogLine(bucketLogRecord{
+		Time:      startTime,
+		Request:   r,
+		Bucket:    match.Vars["bucket"],
+		Object:    match.Vars["object"],
+		Response:  lw.statusResponseWriter,
+		ErrorBody: lw.errorBody.Bytes(),
+		BytesIn:   body.count,
+		Duration:  time.Now().UTC().Sub(startTime),
+	}))
+}
+
+// bucketLogRecord - a served request, logged as an access log line.
+type bucketLogRecord struct {
+	Time      time.Time
+	Request   *http.Request
+	Bucket    string
+	Object    string
+	Response  *statusResponseWriter
+	ErrorBody []byte
+	BytesIn   int64
+	Duration  time.Duration
+}
+
+// bucketLogField - returns value, "-" if empty.
+func bucketLogField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// bucketLogQuoted - returns value quoted, "-" if empty.
+func bucketLogQuoted(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
+
+// bucketLogOperation - returns operation of the request as named by
+// S3 access logs, REST.METHOD.RESOURCE.
+func bucketLogOperation(r *http.Request, object string) string {
+	query := r.URL.Query()
+	resource := "BUCKET"
+	if object != "" {
+		resource = "OBJECT"
+	}
+	switch {
+	case query.Get("partNumber") != "":
+		resource = "PART"
+	case query["uploadId"] != nil || query["uploads"] != nil:
+		resource = "UPLOAD"
+	default:
+		for _, name := range bucketLogSubresources {
+			if _, ok := query[name]; !ok {
+				continue
+			}
+			resource = strings.ToUpper(strings.Replace(name, "-", "_", -1))
+			if object != "" {
+				resource = "OBJECT_" + resource
+			}
+			break
+		}
+	}
+	return "REST." + r.Method + "." + resource
+}
+
+// tlsVersionNames - names of TLS versions in access logs.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLSv1",
+	tls.VersionTLS11: "TLSv1.1",
+	tls.VersionTLS12: "TLSv1.2",
+	tls.VersionTLS13: "TLSv1.3",
+}
+
+// formatBucketLogLine - returns record in the S3 server access log
+// format, so that existing log analysis tools can read it.
+func formatBucketLogLine(record bucketLogRecord) string {
+	r := record.Request
+	remoteIP, _, e := net.SplitHostPort(r.RemoteAddr)
+	if e != nil {
+		remoteIP = r.RemoteAddr
+	}
+	requester := getAuditRequester(r)
+	if requester == auditAnonymousRequester {
+		requester = ""
+	}
+	key := ""
+	if record.Object != "" {
+		key = (&url.URL{Path: record.Object}).EscapedPath()
+	}
+	errorCode := ""
+	if match := bucketLogErrorCode.FindSubmatch(record.ErrorBody); match != nil {
+		errorCode = string(match[1])
+	}
+	bytesSent := ""
+	if record.Response.count > 0 {
+		bytesSent = strconv.FormatInt(record.Response.count, 10)
+	}
+	objectSize := ""
+	switch {
+	case record.Object == "":
+	case r.Method == "PUT" && record.BytesIn > 0:
+		objectSize = strconv.FormatInt(record.BytesIn, 10)
+	case r.Method == "GET" || r.Method == "HEAD":
+		objectSize = record.Response.Header().Get("Content-Length")
+	}
+	var sigVersion, authType string
+	switch getRequestAuthType(r) {
+	case authTypeSigned:
+		sigVersion, authType = "SigV4", "AuthHeader"
+	case authTypePresigned:
+		sigVersion, authType = "SigV4", "QueryString"
+	}
+	var cipherSuite, tlsVersion string
+	if r.TLS != nil {
+		cipherSuite = tls.CipherSuiteName(r.TLS.CipherSuite)
+		tlsVersion = tlsVersionNames[r.TLS.Version]
+	}
+	fields := []string{
+		"minio",
+		record.Bucket,
+		"[" + record.Time.Format(bucketLogTimeFormat) + "]",
+		bucketLogField(remoteIP),
+		bucketLogField(requester),
+		bucketLogField(record.Response.Header().Get("X-Amz-Request-Id")),
+		bucketLogOperation(r, record.Object),
+		bucketLogField(key),
+		bucketLogQuoted(fmt.Sprintf("%s %s %s", r.Method, r.RequestURI, r.Proto)),
+		strconv.Itoa(record.Response.statusCode),
+		bucketLogField(errorCode),
+		bucketLogField(bytesSent),
+		bucketLogField(objectSize),
+		strconv.FormatInt(int64(record.Duration/time.Millisecond), 10),
+		"-", // Turn-around time.
+		bucketLogQuoted(r.Referer()),
+		bucketLogQuoted(r.UserAgent()),
+		"-", // Version id.
+		"-", // Host id.
+		bucketLogField(sigVersion),
+		bucketLogField(cipherSuite),
+		bucketLogField(authType),
+		bucketLogField(r.Host),
+		bucketLogField(tlsVersion),
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,136 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	router "github.com/gorilla/mux"
+)
+
+// Tests requests on buckets with logging enabled are written as S3
+// access log lines to objects of their target bucket.
+func TestBucketLogging(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-logging-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	directory, e := ioutil.TempDir("", "minio-logging")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	for _, bucket := range []string{"source", "logs", "quiet"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := bucketLoggingStatus{LoggingEnabled: &bucketLoggingEnabled{TargetBucket: "logs", TargetPrefix: "source/"}}
+	if err := writeBucketLogging("source", status); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := readBucketLogging("source"); err != nil || *saved.LoggingEnabled != *status.LoggingEnabled {
+		t.Fatalf("Unexpected saved logging status %+v %v", saved, err)
+	}
+
+	savedLogger := globalBucketLogger
+	defer func() { globalBucketLogger = savedLogger }()
+	globalBucketLogger = newBucketLogger(obj)
+
+	mux := router.NewRouter()
+	bucket := mux.PathPrefix("/{bucket}").Subrouter()
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	}).Name("GetObject")
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		writeSuccessResponse(w, nil)
+	}).Name("PutObject")
+	handler := setBucketLogHandler(mux)(mux)
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/source/missing%20key", nil),
+		httptest.NewRequest("PUT", "/source/dir/object?tagging", strings.NewReader("hello")),
+		httptest.NewRequest("PUT", "/quiet/object", strings.NewReader("hello")),
+	}
+	requests[0].Header.Set("User-Agent", "test-agent")
+	for _, r := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	globalBucketLogger.Flush()
+
+	result, err := obj.ListObjects("logs", "source/", "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 {
+		t.Fatalf("Expected 1 access log object, got %d", len(result.Objects))
+	}
+	r, err := obj.GetObject("logs", result.Objects[0].Name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	logBytes, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	lines := strings.Split(strings.TrimSpace(string(logBytes)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 access log lines, got %q", lines)
+	}
+	expected := [][]string{
+		{"minio source [", " 192.0.2.1 - ", " REST.GET.OBJECT missing%20key \"GET /source/missing%20key HTTP/1.1\" 404 NoSuchKey ", "\"test-agent\""},
+		{" REST.PUT.OBJECT_TAGGING dir/object \"PUT /source/dir/object?tagging HTTP/1.1\" 200 - - 5 "},
+	}
+	for i, line := range lines {
+		for _, part := range expected[i] {
+			if !strings.Contains(line, part) {
+				t.Errorf("Line %d: Expected %q in %q", i+1, part, line)
+			}
+		}
+	}
+
+	// Disabling logging stops logging right away.
+	if err = writeBucketLogging("source", bucketLoggingStatus{}); err != nil {
+		t.Fatal(err)
+	}
+	globalBucketLogger.setTarget("source", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/source/object", nil))
+	globalBucketLogger.Flush()
+	if result, err = obj.ListObjects("logs", "", "", "", 10); err != nil || len(result.Objects) != 1 {
+		t.Fatalf("Expected no more access logs, got %+v %v", result.Objects, err)
+	}
+	if saved, err := readBucketLogging("source"); err != nil || saved.LoggingEnabled != nil {
+		t.Fatalf("Expected logging disabled, got %+v %v", saved, err)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	mux "github.com/gorilla/mux"
+)
+
+// Interval at which whitespace is sent to listening clients to keep
+// idle connections from being closed by proxies.
+const listenKeepAliveInterval = 10 * time.Second
+
+// ListenBucketNotificationHandler - GET Bucket?events=...
+// ----------
+// This implementation of the GET operation streams live events on a
+// bucket as newline delimited JSON until the client disconnects.
+// Events can be filtered with the "prefix" and "suffix" of the object
+// name, and with one or more "events" names.
+func (api objectAPIHandlers) ListenBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Validate requested event names, an empty value means all events.
+	var events []string
+	for _, event := range r.URL.Query()["events"] {
+		if event == "" {
+			continue
+		}
+		if !isValidEventName(event) {
+			writeErrorResponse(w, r, ErrEventNotification, r.URL.Path)
+			return
+		}
+		events = append(events, event)
+	}
+	prefix := r.URL.Query().Get("prefix")
+	suffix := r.URL.Query().Get("suffix")
+
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err.Trace(bucket), "GetBucketInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || globalEventNotifier == nil {
+		writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
+		return
+	}
+	var closeNotify <-chan bool
+	if closeNotifier, ok := w.(http.CloseNotifier); ok {
+		closeNotify = closeNotifier.CloseNotify()
+	}
+
+	// Register before replying, so that the client receives all the
+	// events generated after the response headers.
+	listener := globalEventNotifier.AddListener(bucket, prefix, suffix, events)
+	defer globalEventNotifier.RemoveListener(listener)
+
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(listenKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case entry := <-listener.ch:
+			// Encode terminates every entry with a newline.
+			if e := encoder.Encode(entry); e != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, e := w.Write([]byte(" ")); e != nil {
+				return
+			}
+		case <-closeNotify:
+			return
+		}
+		flusher.Flush()
+	}
+}
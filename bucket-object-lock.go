@@ -0,0 +1,182 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Object lock configuration file name, saved in bucket config path.
+	bucketObjectLockConfigFile = "object-lock.xml"
+
+	// Maximum size of object lock configuration.
+	maxObjectLockConfigSize = 20 * 1024
+
+	// Object lock enabled status, object lock cannot be disabled once
+	// enabled.
+	objectLockEnabled = "Enabled"
+)
+
+var (
+	errObjectLockNotEnabled         = errors.New("Object lock configuration should have ObjectLockEnabled set to Enabled")
+	errObjectLockInvalidPeriod      = errors.New("Default retention should have either positive days or years")
+	errObjectLockNoDefaultRetention = errors.New("Object lock rule should have a default retention")
+)
+
+// objectLockDefaultRetention - retention applied to new objects
+// created without a retention of their own.
+type objectLockDefaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+// objectLockRule - object lock rule of a bucket.
+type objectLockRule struct {
+	DefaultRetention *objectLockDefaultRetention `xml:"DefaultRetention"`
+}
+
+// objectLockConfiguration - bucket object lock configuration.
+type objectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled"`
+	Rule              *objectLockRule `xml:"Rule,omitempty"`
+}
+
+// Validate - validates object lock configuration.
+func (config objectLockConfiguration) Validate() error {
+	if config.ObjectLockEnabled != objectLockEnabled {
+		return errObjectLockNotEnabled
+	}
+	if config.Rule == nil {
+		return nil
+	}
+	retention := config.Rule.DefaultRetention
+	if retention == nil {
+		return errObjectLockNoDefaultRetention
+	}
+	if !isValidRetentionMode(retention.Mode) {
+		return errInvalidRetentionMode
+	}
+	if (retention.Days > 0) == (retention.Years > 0) || retention.Days < 0 || retention.Years < 0 {
+		return errObjectLockInvalidPeriod
+	}
+	return nil
+}
+
+// defaultRetention - returns default retention of objects created at
+// now, nil without a default retention.
+func (config objectLockConfiguration) defaultRetention(now time.Time) *objectRetention {
+	if config.Rule == nil || config.Rule.DefaultRetention == nil {
+		return nil
+	}
+	retention := config.Rule.DefaultRetention
+	return &objectRetention{
+		Mode:            retention.Mode,
+		RetainUntilDate: now.AddDate(retention.Years, 0, retention.Days).UTC(),
+	}
+}
+
+// parseBucketObjectLock - parses and validates object lock
+// configuration.
+func parseBucketObjectLock(configBytes []byte) (objectLockConfiguration, error) {
+	var config objectLockConfiguration
+	if e := xml.Unmarshal(configBytes, &config); e != nil {
+		return objectLockConfiguration{}, e
+	}
+	if e := config.Validate(); e != nil {
+		return objectLockConfiguration{}, e
+	}
+	return config, nil
+}
+
+// getBucketObjectLockFile - get object lock configuration file path.
+func getBucketObjectLockFile(bucket string) (string, *probe.Error) {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(bucketConfigPath, bucketObjectLockConfigFile), nil
+}
+
+// readBucketObjectLock - read bucket object lock configuration.
+func readBucketObjectLock(bucket string) ([]byte, *probe.Error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	objectLockFile, err := getBucketObjectLockFile(bucket)
+	if err != nil {
+		return nil, err.Trace(bucket)
+	}
+	configBytes, e := ioutil.ReadFile(objectLockFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+		}
+		return nil, probe.NewError(e)
+	}
+	return configBytes, nil
+}
+
+// writeBucketObjectLock - save bucket object lock configuration.
+func writeBucketObjectLock(bucket string, configBytes []byte) *probe.Error {
+	// Verify if bucket path legal.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	// Create bucket config path.
+	if err := createBucketConfigPath(bucket); err != nil {
+		return err.Trace()
+	}
+
+	objectLockFile, err := getBucketObjectLockFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := ioutil.WriteFile(objectLockFile, configBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// getBucketObjectLock - returns parsed object lock configuration of
+// bucket, ok is false if object lock is not enabled on bucket.
+func getBucketObjectLock(bucket string) (config objectLockConfiguration, ok bool, err *probe.Error) {
+	configBytes, err := readBucketObjectLock(bucket)
+	if err != nil {
+		if _, notFound := err.ToGoError().(BucketObjectLockNotFound); notFound {
+			return objectLockConfiguration{}, false, nil
+		}
+		return objectLockConfiguration{}, false, err.Trace(bucket)
+	}
+	config, e := parseBucketObjectLock(configBytes)
+	if e != nil {
+		return objectLockConfiguration{}, false, probe.NewError(e)
+	}
+	return config, true, nil
+}
@@ -114,6 +114,9 @@ func removeBucketPolicy(bucket string) *probe.Error {
 		}
 		return probe.NewError(e)
 	}
+	if e := os.Remove(bucketPolicyFile); e != nil {
+		return probe.NewError(e)
+	}
 	return nil
 }
 
@@ -0,0 +1,195 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Bucket quotas are saved in this file in the config path.
+const bucketQuotasFile = "bucket-quotas.json"
+
+// bucketQuota - hard limit of the size of all objects of a bucket, in
+// bytes.
+type bucketQuota struct {
+	Bucket string `json:"bucket"`
+	Quota  int64  `json:"quota"`
+	// Usage the quota is enforced against, reported by List.
+	Usage int64 `json:"usage"`
+}
+
+// bucketQuotas - bucket quotas set by admins. Writes are checked
+// against usage of the last data usage crawl along with the bytes
+// written since, deleted objects free their space once the next crawl
+// ends. Buckets which were not crawled yet only count bytes written
+// since the server started, and writes in progress are not counted, so
+// buckets may overshoot their quota until usage is refreshed.
+type bucketQuotas struct {
+	mutex  *sync.Mutex
+	quotas map[string]bucketQuota
+}
+
+func newBucketQuotas() *bucketQuotas {
+	return &bucketQuotas{
+		mutex:  &sync.Mutex{},
+		quotas: make(map[string]bucketQuota),
+	}
+}
+
+// Global bucket quotas, driven by the admin API.
+var globalBucketQuotas = newBucketQuotas()
+
+// Set - sets quota of its bucket, replacing the previous quota.
+func (q *bucketQuotas) Set(quota bucketQuota) *probe.Error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	quota.Usage = 0
+	q.quotas[quota.Bucket] = quota
+	return q.saveQuotas().Trace(quota.Bucket)
+}
+
+// Remove - removes quota of bucket.
+func (q *bucketQuotas) Remove(bucket string) *probe.Error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if _, ok := q.quotas[bucket]; !ok {
+		return probe.NewError(BucketQuotaNotFound{Bucket: bucket})
+	}
+	delete(q.quotas, bucket)
+	return q.saveQuotas().Trace(bucket)
+}
+
+// List - returns all quotas sorted by bucket, along with the usage
+// they are enforced against.
+func (q *bucketQuotas) List() []bucketQuota {
+	q.mutex.Lock()
+	quotas := q.listQuotas()
+	q.mutex.Unlock()
+	for index := range quotas {
+		quotas[index].Usage = globalDataUsage.Estimate(quotas[index].Bucket)
+	}
+	return quotas
+}
+
+// listQuotas - returns all quotas sorted by bucket, callers hold the
+// mutex.
+func (q *bucketQuotas) listQuotas() []bucketQuota {
+	quotas := []bucketQuota{}
+	for _, quota := range q.quotas {
+		quotas = append(quotas, quota)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Bucket < quotas[j].Bucket })
+	return quotas
+}
+
+// Check - returns QuotaExceeded if writing size bytes to bucket would
+// exceed its quota, size is negative if unknown.
+func (q *bucketQuotas) Check(bucket string, size int64) *probe.Error {
+	q.mutex.Lock()
+	quota, ok := q.quotas[bucket]
+	q.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+	usage := globalDataUsage.Estimate(bucket)
+	// Writes of unknown size are refused once the quota is reached.
+	if (size < 0 && usage >= quota.Quota) || usage+size > quota.Quota {
+		return probe.NewError(QuotaExceeded{Bucket: bucket, Quota: quota.Quota})
+	}
+	return nil
+}
+
+// getBucketQuotasFile - get bucket quotas file path.
+func getBucketQuotasFile() (string, *probe.Error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(configPath, bucketQuotasFile), nil
+}
+
+// saveQuotas - saves quotas, callers hold the mutex.
+func (q *bucketQuotas) saveQuotas() *probe.Error {
+	quotasFile, err := getBucketQuotasFile()
+	if err != nil {
+		return err.Trace()
+	}
+	quotasBytes, e := json.Marshal(q.listQuotas())
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.MkdirAll(filepath.Dir(quotasFile), 0700); e != nil {
+		return probe.NewError(e)
+	}
+	if e = ioutil.WriteFile(quotasFile, quotasBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// loadQuotas - loads saved quotas, replacing current ones.
+func (q *bucketQuotas) loadQuotas() *probe.Error {
+	quotasFile, err := getBucketQuotasFile()
+	if err != nil {
+		return err.Trace()
+	}
+	quotasBytes, e := ioutil.ReadFile(quotasFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil
+		}
+		return probe.NewError(e)
+	}
+	var quotas []bucketQuota
+	if e = json.Unmarshal(quotasBytes, &quotas); e != nil {
+		return probe.NewError(e)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.quotas = make(map[string]bucketQuota)
+	for _, quota := range quotas {
+		q.quotas[quota.Bucket] = quota
+	}
+	return nil
+}
+
+// initBucketQuotas - loads bucket quotas at server start.
+func initBucketQuotas() {
+	err := globalBucketQuotas.loadQuotas()
+	fatalIf(err.Trace(), "Unable to load bucket quotas.", nil)
+}
+
+// quotaCountingReader - counts bytes of objects and parts written to a
+// bucket, until usage of the bucket is crawled again.
+type quotaCountingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *quotaCountingReader) Read(p []byte) (int, error) {
+	n, e := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, e
+}
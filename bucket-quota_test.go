@@ -0,0 +1,139 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Tests writes exceeding the quota of their bucket are refused, and
+// quotas are saved across restarts.
+func TestBucketQuotas(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-quota-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	directory, e := ioutil.TempDir("", "minio-quota-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	for _, bucket := range []string{"bucket", "other"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Use private quotas and usage for the test.
+	savedQuotas, savedUsage := globalBucketQuotas, globalDataUsage
+	globalBucketQuotas, globalDataUsage = newBucketQuotas(), newDataUsageCrawler()
+	defer func() { globalBucketQuotas, globalDataUsage = savedQuotas, savedUsage }()
+	if err := globalDataUsage.Load(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := globalBucketQuotas.Remove("bucket"); err == nil {
+		t.Fatal("Expected error removing quota which is not set")
+	}
+	if err := globalBucketQuotas.Set(bucketQuota{Bucket: "bucket", Quota: 100}); err != nil {
+		t.Fatal(err)
+	}
+	isQuotaExceeded := func(err *probe.Error) bool {
+		if err == nil {
+			return false
+		}
+		_, ok := err.ToGoError().(QuotaExceeded)
+		return ok
+	}
+	put := func(bucket, object string, size int, knownSize bool) *probe.Error {
+		data := bytes.Repeat([]byte("a"), size)
+		objectSize := int64(size)
+		if !knownSize {
+			objectSize = -1
+		}
+		_, err := obj.PutObject(bucket, object, objectSize, bytes.NewReader(data), nil)
+		return err
+	}
+
+	if err := put("bucket", "a", 60, true); err != nil {
+		t.Fatal(err)
+	}
+	// Bytes written since the last crawl are counted.
+	if err := put("bucket", "b", 50, true); !isQuotaExceeded(err) {
+		t.Fatalf("Expected QuotaExceeded, got %v", err)
+	}
+	if err := put("bucket", "b", 40, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("bucket", "c", 1, false); !isQuotaExceeded(err) {
+		t.Fatalf("Expected QuotaExceeded once the quota is reached, got %v", err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "multipart", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.PutObjectPart("bucket", "multipart", uploadID, 1, 1, bytes.NewReader([]byte("a")), ""); !isQuotaExceeded(err) {
+		t.Fatalf("Expected QuotaExceeded uploading a part, got %v", err)
+	}
+	// Buckets without a quota are not limited.
+	if err = put("other", "a", 200, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleted objects free their space once usage is crawled again.
+	if err = obj.DeleteObject("bucket", "a", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = put("bucket", "c", 50, true); !isQuotaExceeded(err) {
+		t.Fatalf("Expected QuotaExceeded before the next crawl, got %v", err)
+	}
+	globalDataUsage.crawl()
+	if err = put("bucket", "c", 50, true); err != nil {
+		t.Fatal(err)
+	}
+	quotas := globalBucketQuotas.List()
+	if len(quotas) != 1 || quotas[0].Usage != 90 {
+		t.Fatalf("Expected usage 90 of bucket, got %+v", quotas)
+	}
+
+	restarted := newBucketQuotas()
+	if err = restarted.loadQuotas(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restarted.List(), globalBucketQuotas.List()) {
+		t.Fatalf("Expected %+v, got %+v", globalBucketQuotas.List(), restarted.List())
+	}
+	if err = restarted.Remove("bucket"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio/pkg/probe"
+	"github.com/minio/minio/pkg/s3"
+)
+
+const (
+	// Replication configuration file name, saved in bucket config path.
+	bucketReplicationConfigFile = "replication.xml"
+
+	// Maximum size of replication configuration.
+	maxReplicationConfigSize = 20 * 1024
+)
+
+var (
+	errReplicationNoEndpoint    = errors.New("Replication destination should have an endpoint")
+	errReplicationNoCredentials = errors.New("Replication destination should have both an access key and a secret key")
+	errReplicationInvalidBucket = errors.New("Replication destination should have a valid bucket name")
+)
+
+// replicationDestination - minio or S3 compatible endpoint objects
+// are replicated to.
+type replicationDestination struct {
+	Endpoint  string `xml:"Endpoint"`
+	AccessKey string `xml:"AccessKey"`
+	SecretKey string `xml:"SecretKey,omitempty"`
+	Region    string `xml:"Region,omitempty"`
+	Secure    bool   `xml:"Secure"`
+	Bucket    string `xml:"Bucket"`
+}
+
+// newClient - returns a client for the destination endpoint.
+func (d replicationDestination) newClient() (*s3.Client, error) {
+	return s3.New(s3.Config{
+		Endpoint:  d.Endpoint,
+		AccessKey: d.AccessKey,
+		SecretKey: d.SecretKey,
+		Region:    d.Region,
+		Secure:    d.Secure,
+	})
+}
+
+// replicationConfiguration - bucket replication configuration, all
+// objects with prefix are replicated to the destination. Replication
+// is active-passive, changes on the destination are not replicated
+// back.
+type replicationConfiguration struct {
+	XMLName     xml.Name               `xml:"ReplicationConfiguration"`
+	Prefix      string                 `xml:"Prefix,omitempty"`
+	Destination replicationDestination `xml:"Destination"`
+}
+
+// Validate - validates replication configuration.
+func (config replicationConfiguration) Validate() error {
+	if config.Destination.Endpoint == "" {
+		return errReplicationNoEndpoint
+	}
+	if config.Destination.AccessKey == "" || config.Destination.SecretKey == "" {
+		return errReplicationNoCredentials
+	}
+	if !IsValidBucketName(config.Destination.Bucket) {
+		return errReplicationInvalidBucket
+	}
+	return nil
+}
+
+// matches - returns true if object is replicated.
+func (config replicationConfiguration) matches(object string) bool {
+	return strings.HasPrefix(object, config.Prefix)
+}
+
+// parseBucketReplication - parses and validates replication
+// configuration.
+func parseBucketReplication(configBytes []byte) (replicationConfiguration, error) {
+	var config replicationConfiguration
+	if e := xml.Unmarshal(configBytes, &config); e != nil {
+		return replicationConfiguration{}, e
+	}
+	if e := config.Validate(); e != nil {
+		return replicationConfiguration{}, e
+	}
+	return config, nil
+}
+
+// getBucketReplicationFile - get replication configuration file path.
+func getBucketReplicationFile(bucket string) (string, *probe.Error) {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(bucketConfigPath, bucketReplicationConfigFile), nil
+}
+
+// readBucketReplication - read bucket replication configuration.
+func readBucketReplication(bucket string) ([]byte, *probe.Error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	replicationFile, err := getBucketReplicationFile(bucket)
+	if err != nil {
+		return nil, err.Trace(bucket)
+	}
+	configBytes, e := ioutil.ReadFile(replicationFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, probe.NewError(BucketReplicationNotFound{Bucket: bucket})
+		}
+		return nil, probe.NewError(e)
+	}
+	return configBytes, nil
+}
+
+// removeBucketReplication - remove bucket replication configuration.
+func removeBucketReplication(bucket string) *probe.Error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	replicationFile, err := getBucketReplicationFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := os.Remove(replicationFile); e != nil {
+		if os.IsNotExist(e) {
+			return probe.NewError(BucketReplicationNotFound{Bucket: bucket})
+		}
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// writeBucketReplication - save bucket replication configuration.
+func writeBucketReplication(bucket string, configBytes []byte) *probe.Error {
+	// Verify if bucket path legal.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+
+	// Create bucket config path.
+	if err := createBucketConfigPath(bucket); err != nil {
+		return err.Trace()
+	}
+
+	replicationFile, err := getBucketReplicationFile(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if e := ioutil.WriteFile(replicationFile, configBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// getBucketReplication - returns parsed replication configuration of
+// bucket, ok is false if bucket is not replicated.
+func getBucketReplication(bucket string) (config replicationConfiguration, ok bool, err *probe.Error) {
+	configBytes, err := readBucketReplication(bucket)
+	if err != nil {
+		if _, notFound := err.ToGoError().(BucketReplicationNotFound); notFound {
+			return replicationConfiguration{}, false, nil
+		}
+		return replicationConfiguration{}, false, err.Trace(bucket)
+	}
+	config, e := parseBucketReplication(configBytes)
+	if e != nil {
+		return replicationConfiguration{}, false, probe.NewError(e)
+	}
+	return config, true, nil
+}
@@ -0,0 +1,268 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Default interval between two capacity checks.
+	defaultAlarmCheckInterval = 5 * time.Minute
+
+	// Default minimum free space percentage on the backend.
+	defaultMinFreeSpacePercent = 10
+
+	// Number of objects listed at a time while computing bucket usage.
+	alarmListBatchSize = 1000
+)
+
+// Supported alarm types.
+const (
+	alarmBucketSize  = "bucketSize"
+	alarmObjectCount = "objectCount"
+	alarmFreeSpace   = "freeSpace"
+	alarmDiskHealth  = "diskHealth"
+)
+
+// bucketAlarm - thresholds for a single bucket, zero disables a
+// threshold.
+type bucketAlarm struct {
+	MaxSize    int64 `json:"maxSize"` // In bytes.
+	MaxObjects int64 `json:"maxObjects"`
+}
+
+// alarms - capacity alarms configuration.
+type alarms struct {
+	Enable bool `json:"enable"`
+	// Interval between two checks in seconds.
+	CheckInterval int64 `json:"checkInterval"`
+	// Alarm is raised when free space on the backend falls below
+	// this percentage, zero disables the alarm.
+	MinFreeSpacePercent float64 `json:"minFreeSpacePercent"`
+	// Per bucket thresholds, keyed by bucket name.
+	Buckets map[string]bucketAlarm `json:"buckets"`
+}
+
+// newAlarmsConfig - alarms configuration for fresh and migrated
+// configs.
+func newAlarmsConfig() alarms {
+	return alarms{
+		CheckInterval:       int64(defaultAlarmCheckInterval / time.Second),
+		MinFreeSpacePercent: defaultMinFreeSpacePercent,
+		Buckets:             make(map[string]bucketAlarm),
+	}
+}
+
+// interval - returns check interval, defaults if not configured.
+func (a alarms) interval() time.Duration {
+	if a.CheckInterval <= 0 {
+		return defaultAlarmCheckInterval
+	}
+	return time.Duration(a.CheckInterval) * time.Second
+}
+
+// alarmInfo - a raised alarm, reported by the admin health endpoint.
+type alarmInfo struct {
+	Type   string `json:"type"`
+	Bucket string `json:"bucket,omitempty"`
+	// Disk and the tripped indicator of disk health alarms.
+	Disk      string    `json:"disk,omitempty"`
+	Indicator string    `json:"indicator,omitempty"`
+	Threshold string    `json:"threshold,omitempty"`
+	Value     string    `json:"value"`
+	Since     time.Time `json:"since"`
+}
+
+// id - unique id of the alarm condition.
+func (a alarmInfo) id() string {
+	return a.Type + ":" + a.Bucket + ":" + a.Disk + ":" + a.Indicator
+}
+
+// alarmState - currently raised alarms.
+type alarmState struct {
+	mutex  *sync.RWMutex
+	raised map[string]alarmInfo
+}
+
+// Global alarm state, updated by the capacity checker.
+var globalAlarms = &alarmState{
+	mutex:  &sync.RWMutex{},
+	raised: make(map[string]alarmInfo),
+}
+
+// List - returns all raised alarms sorted by id.
+func (s *alarmState) List() []alarmInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var ids []string
+	for id := range s.raised {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	alarmInfos := []alarmInfo{}
+	for _, id := range ids {
+		alarmInfos = append(alarmInfos, s.raised[id])
+	}
+	return alarmInfos
+}
+
+// update - replaces raised alarms with the ones found by the latest
+// check, returns alarms which were newly raised and cleared.
+func (s *alarmState) update(current []alarmInfo) (raised, cleared []alarmInfo) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	next := make(map[string]alarmInfo)
+	for _, alarm := range current {
+		if previous, ok := s.raised[alarm.id()]; ok {
+			// Alarm is still raised, keep the time it was first seen.
+			alarm.Since = previous.Since
+		} else {
+			raised = append(raised, alarm)
+		}
+		next[alarm.id()] = alarm
+	}
+	for id, alarm := range s.raised {
+		if _, ok := next[id]; !ok {
+			cleared = append(cleared, alarm)
+		}
+	}
+	s.raised = next
+	return raised, cleared
+}
+
+// capacityChecker - periodically checks configured thresholds.
+type capacityChecker struct {
+	objAPI objectAPI
+}
+
+// initCapacityAlarms - starts the background capacity checker.
+func initCapacityAlarms(objAPI objectAPI) {
+	cc := capacityChecker{objAPI: objAPI}
+	go func() {
+		for {
+			config := serverConfig.GetAlarms()
+			if config.Enable {
+				cc.check(config)
+			}
+			time.Sleep(config.interval())
+		}
+	}()
+}
+
+// check - evaluates all thresholds once, notifying on alarms which
+// were raised or cleared since the previous check.
+func (cc capacityChecker) check(config alarms) {
+	var current []alarmInfo
+	now := time.Now().UTC()
+
+	buckets, err := cc.objAPI.ListBuckets()
+	if err != nil {
+		errorIf(err.Trace(), "Unable to list buckets for capacity check.", nil)
+		return
+	}
+	// All buckets report the free space of the backend.
+	if config.MinFreeSpacePercent > 0 && len(buckets) > 0 && buckets[0].Total > 0 {
+		freePercent := float64(buckets[0].Free) * 100 / float64(buckets[0].Total)
+		if freePercent < config.MinFreeSpacePercent {
+			current = append(current, alarmInfo{
+				Type:      alarmFreeSpace,
+				Threshold: strconv.FormatFloat(config.MinFreeSpacePercent, 'f', 2, 64),
+				Value:     strconv.FormatFloat(freePercent, 'f', 2, 64),
+				Since:     now,
+			})
+		}
+	}
+	for bucket, thresholds := range config.Buckets {
+		if thresholds.MaxSize <= 0 && thresholds.MaxObjects <= 0 {
+			continue
+		}
+		size, count, err := cc.bucketUsage(bucket)
+		if err != nil {
+			errorIf(err.Trace(bucket), "Unable to compute bucket usage.", nil)
+			continue
+		}
+		if thresholds.MaxSize > 0 && size >= thresholds.MaxSize {
+			current = append(current, alarmInfo{
+				Type:      alarmBucketSize,
+				Bucket:    bucket,
+				Threshold: strconv.FormatInt(thresholds.MaxSize, 10),
+				Value:     strconv.FormatInt(size, 10),
+				Since:     now,
+			})
+		}
+		if thresholds.MaxObjects > 0 && count >= thresholds.MaxObjects {
+			current = append(current, alarmInfo{
+				Type:      alarmObjectCount,
+				Bucket:    bucket,
+				Threshold: strconv.FormatInt(thresholds.MaxObjects, 10),
+				Value:     strconv.FormatInt(count, 10),
+				Since:     now,
+			})
+		}
+	}
+
+	raised, cleared := globalAlarms.update(current)
+	for _, alarm := range raised {
+		notifyAlarm(AlarmRaised, alarm)
+	}
+	for _, alarm := range cleared {
+		notifyAlarm(AlarmCleared, alarm)
+	}
+}
+
+// bucketUsage - returns total size and number of objects in bucket.
+func (cc capacityChecker) bucketUsage(bucket string) (size int64, count int64, err *probe.Error) {
+	marker := ""
+	for {
+		result, err := cc.objAPI.ListObjects(bucket, "", marker, "", alarmListBatchSize)
+		if err != nil {
+			return 0, 0, err.Trace(bucket, marker)
+		}
+		for _, object := range result.Objects {
+			size += object.Size
+			count++
+		}
+		if !result.IsTruncated || len(result.Objects) == 0 {
+			return size, count, nil
+		}
+		marker = result.Objects[len(result.Objects)-1].Name
+	}
+}
+
+// notifyAlarm - sends alarm state change to all notification targets.
+func notifyAlarm(eventType EventName, alarm alarmInfo) {
+	reqParams := map[string]string{
+		"alarm":     alarm.Type,
+		"threshold": alarm.Threshold,
+		"value":     alarm.Value,
+	}
+	if alarm.Disk != "" {
+		reqParams["disk"] = alarm.Disk
+		reqParams["indicator"] = alarm.Indicator
+	}
+	eventNotify(eventData{
+		Type:      eventType,
+		Bucket:    alarm.Bucket,
+		ReqParams: reqParams,
+	})
+}
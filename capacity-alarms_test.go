@@ -0,0 +1,89 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Tests bucket usage thresholds and alarm state transitions.
+func TestCapacityAlarms(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-alarms-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for _, object := range []string{"a", "b", "c"} {
+		if _, err := obj.PutObject("bucket", object, int64(len("hello")), bytes.NewBufferString("hello"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cc := capacityChecker{objAPI: obj}
+	size, count, err := cc.bucketUsage("bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 15 || count != 3 {
+		t.Fatalf("Expected size 15 and count 3, got %d and %d", size, count)
+	}
+
+	// Use a private alarm state for the test.
+	savedAlarms := globalAlarms
+	globalAlarms = &alarmState{mutex: &sync.RWMutex{}, raised: make(map[string]alarmInfo)}
+	defer func() { globalAlarms = savedAlarms }()
+
+	config := alarms{
+		Enable: true,
+		Buckets: map[string]bucketAlarm{
+			"bucket": {MaxSize: 100, MaxObjects: 3},
+		},
+	}
+	cc.check(config)
+	alarmInfos := globalAlarms.List()
+	if len(alarmInfos) != 1 || alarmInfos[0].Type != alarmObjectCount || alarmInfos[0].Value != "3" {
+		t.Fatalf("Expected object count alarm, got %v", alarmInfos)
+	}
+	since := alarmInfos[0].Since
+
+	// Alarm stays raised, time it was first raised is preserved.
+	cc.check(config)
+	alarmInfos = globalAlarms.List()
+	if len(alarmInfos) != 1 || !alarmInfos[0].Since.Equal(since) {
+		t.Fatalf("Expected alarm to be unchanged, got %v", alarmInfos)
+	}
+
+	// Alarm is cleared once the condition goes away.
+	config.Buckets["bucket"] = bucketAlarm{MaxObjects: 10}
+	cc.check(config)
+	if alarmInfos = globalAlarms.List(); len(alarmInfos) != 0 {
+		t.Fatalf("Expected alarms to be cleared, got %v", alarmInfos)
+	}
+}
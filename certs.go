@@ -53,20 +53,26 @@ func mustGetCertsPath() string {
 	return certsPath
 }
 
-// mustGetCertFile must get cert file.
+// mustGetCertFile must get cert file, configured or in the certs path.
 func mustGetCertFile() string {
+	if certFile := serverConfig.GetTLS().CertFile; certFile != "" {
+		return certFile
+	}
 	return filepath.Join(mustGetCertsPath(), globalMinioCertFile)
 }
 
-// mustGetKeyFile must get key file.
+// mustGetKeyFile must get key file, configured or in the certs path.
 func mustGetKeyFile() string {
+	if keyFile := serverConfig.GetTLS().KeyFile; keyFile != "" {
+		return keyFile
+	}
 	return filepath.Join(mustGetCertsPath(), globalMinioKeyFile)
 }
 
 // isCertFileExists verifies if cert file exists, returns true if
 // found, false otherwise.
 func isCertFileExists() bool {
-	st, e := os.Stat(filepath.Join(mustGetCertsPath(), globalMinioCertFile))
+	st, e := os.Stat(mustGetCertFile())
 	// If file exists and is regular return true.
 	if e == nil && st.Mode().IsRegular() {
 		return true
@@ -77,7 +83,7 @@ func isCertFileExists() bool {
 // isKeyFileExists verifies if key file exists, returns true if found,
 // false otherwise.
 func isKeyFileExists() bool {
-	st, e := os.Stat(filepath.Join(mustGetCertsPath(), globalMinioKeyFile))
+	st, e := os.Stat(mustGetKeyFile())
 	// If file exists and is regular return true.
 	if e == nil && st.Mode().IsRegular() {
 		return true
@@ -85,8 +91,12 @@ func isKeyFileExists() bool {
 	return false
 }
 
-// isSSL - returns true with both cert and key exists.
+// isSSL - returns true with both cert and key exists, or if
+// certificates are obtained by ACME.
 func isSSL() bool {
+	if serverConfig.GetACME().Enable {
+		return true
+	}
 	if isCertFileExists() && isKeyFileExists() {
 		return true
 	}
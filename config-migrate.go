@@ -33,6 +33,8 @@ func migrateConfig() {
 	migrateV2ToV3()
 	// Migrate version '3' to '4'.
 	migrateV3ToV4()
+	// Migrate version '4' to '5'.
+	migrateV4ToV5()
 }
 
 // Version '1' is not supported anymore and deprecated, safe to delete.
@@ -127,8 +129,8 @@ func migrateV3ToV4() {
 	}
 
 	// Save only the new fields, ignore the rest.
-	srvConfig := &serverConfigV4{}
-	srvConfig.Version = globalMinioConfigVersion
+	srvConfig := &configV4{}
+	srvConfig.Version = "4"
 	srvConfig.Credential = cv3.Credential
 	srvConfig.Region = cv3.Region
 	srvConfig.Logger.Console = cv3.Logger.Console
@@ -145,3 +147,52 @@ func migrateV3ToV4() {
 
 	console.Println("Migration from version ‘" + cv3.Version + "’ to ‘" + srvConfig.Version + "’ completed successfully.")
 }
+
+// Version '4' to '5' migrates config, adds new fields related to
+// bucket event notification targets.
+func migrateV4ToV5() {
+	cv4, err := loadConfigV4()
+	if err != nil {
+		if os.IsNotExist(err.ToGoError()) {
+			return
+		}
+	}
+	fatalIf(err.Trace(), "Unable to load config version ‘4’.", nil)
+	if cv4.Version != "4" {
+		return
+	}
+
+	// Save only the new fields, ignore the rest.
+	srvConfig := &serverConfigV5{}
+	srvConfig.Version = globalMinioConfigVersion
+	srvConfig.Credential = cv4.Credential
+	srvConfig.Region = cv4.Region
+	srvConfig.Logger = cv4.Logger
+	srvConfig.Notify.Kafka = make(map[string]kafkaNotify)
+	srvConfig.Notify.Kafka["1"] = kafkaNotify{}
+	srvConfig.Security = newSecurityConfig()
+	srvConfig.TLS = newTLSConfig()
+	srvConfig.ACME = newACMEConfig()
+	srvConfig.Federation = newFederationConfig()
+	srvConfig.Lifecycle = newLifecycleScanner()
+	srvConfig.Alarms = newAlarmsConfig()
+	srvConfig.DiskHealth = newDiskHealthConfig()
+	srvConfig.Tiers = make(map[string]remoteTier)
+	srvConfig.RPC = newRPCAuthConfig()
+	srvConfig.Audit = newAuditConfig()
+	srvConfig.Multipart = newMultipartConfig()
+	srvConfig.Heal = newHealConfig()
+	srvConfig.DiskShares = newDiskSharesConfig()
+	srvConfig.STS = newSTSConfig()
+	srvConfig.LDAP = newLDAPConfig()
+
+	qc, err := quick.New(srvConfig)
+	fatalIf(err.Trace(), "Unable to initialize the quick config.", nil)
+	configFile, err := getConfigFile()
+	fatalIf(err.Trace(), "Unable to get config file.", nil)
+
+	err = qc.Save(configFile)
+	fatalIf(err.Trace(), "Migrating from version ‘"+cv4.Version+"’ to ‘"+srvConfig.Version+"’ failed.", nil)
+
+	console.Println("Migration from version ‘" + cv4.Version + "’ to ‘" + srvConfig.Version + "’ completed successfully.")
+}
@@ -0,0 +1,489 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// This file describes every scalar setting of serverConfigV5 as a
+// typed key, the dot separated JSON path of the setting, which can be
+// overridden by environment variables and set through the admin API.
+// Effective values are resolved in order of precedence:
+//
+//   environment variable > admin API > config file > default
+//
+// Values set through the admin API are saved to the config file,
+// values of environment variables never are.
+
+// Sources of effective config values.
+const (
+	configSourceDefault = "default"
+	configSourceFile    = "config"
+	configSourceAdmin   = "admin"
+	configSourceEnv     = "env"
+)
+
+// Types of config values.
+const (
+	configTypeBool   = "bool"
+	configTypeInt    = "int"
+	configTypeFloat  = "float"
+	configTypeString = "string"
+)
+
+// Environment variables overriding settings are prefixed with this.
+const configEnvPrefix = "MINIO_"
+
+// Maximum size of a value set through the admin API.
+const maxConfigValueSize = 4096
+
+// Value reported for sensitive settings instead of their value.
+const configRedactedValue = "REDACTED"
+
+var (
+	errConfigKeyNotFound   = errors.New("Unknown config key")
+	errConfigOverriddenEnv = errors.New("Config key is overridden by an environment variable")
+)
+
+// configKey - typed setting of serverConfigV5.
+type configKey struct {
+	Key  string
+	Type string
+	Env  string
+	// Index of the field, for reflect.Value.FieldByIndex.
+	index     []int
+	sensitive bool
+}
+
+// configValue - effective value of a setting and where it came from.
+type configValue struct {
+	Key    string `json:"key"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env"`
+	// Takes effect without a restart.
+	Dynamic bool `json:"dynamic"`
+}
+
+// configOverrides - state of settings not coming from the config file.
+type configOverrides struct {
+	// Values of the config file of settings overridden by
+	// environment variables, saved instead of the effective values.
+	env map[string]string
+	// Settings set through the admin API since the server started.
+	admin map[string]bool
+}
+
+// Settings which are not exposed, credentials are managed by their own
+// environment variables.
+var configExcludedKeys = map[string]bool{
+	"version":            true,
+	"credential":         true,
+	"previousCredential": true,
+}
+
+// configValidators - validates values of settings beyond their type.
+var configValidators = map[string]func(value string) error{
+	"region":                               nonEmptyConfigValue,
+	"domain":                               validDomain,
+	"logger.console.level":                 validLogLevel,
+	"logger.file.level":                    validLogLevel,
+	"logger.syslog.level":                  validLogLevel,
+	"sts.defaultDuration":                  validSTSDuration,
+	"sts.maxDuration":                      validSTSDuration,
+	"heal.maxIOPS":                         nonNegativeConfigValue,
+	"heal.maxBandwidth":                    nonNegativeConfigValue,
+	"heal.windows":                         validHealWindows,
+	"multipart.staleUploadExpiry":          nonNegativeConfigValue,
+	"multipart.staleUploadCleanupInterval": nonNegativeConfigValue,
+	"quarantine.maxSize":                   nonNegativeConfigValue,
+	"trash.retention":                      nonNegativeConfigValue,
+	"trash.maxSize":                        nonNegativeConfigValue,
+	"cache.maxSize":                        nonNegativeConfigValue,
+	"cache.maxObjectSize":                  nonNegativeConfigValue,
+	"cache.highWatermark":                  validPercentConfigValue,
+	"cache.lowWatermark":                   validPercentConfigValue,
+	"blockCache.maxSize":                   nonNegativeConfigValue,
+	"blockCache.maxFileSize":               nonNegativeConfigValue,
+	"blockCache.eviction":                  validBlockCacheEviction,
+	"ldap.timeout":                         nonNegativeConfigValue,
+	"ldap.userDNFormat":                    validLDAPUserDNFormat,
+	"tls.minVersion":                       validTLSVersion,
+	"tls.reloadInterval":                   nonNegativeConfigValue,
+	"acme.renewBefore":                     nonNegativeConfigValue,
+	"diskHealth.checkInterval":             nonNegativeConfigValue,
+	"diskIO.readAhead":                     nonNegativeConfigValue,
+	"diskIO.metadataEncoding":              validMetadataEncoding,
+	"diskIO.durability":                    validDurability,
+	"diskIO.reservedSpace":                 validPercentConfigValue,
+	"dataUsage.crawlInterval":              nonNegativeConfigValue,
+	"locks.acquireTimeout":                 nonNegativeConfigValue,
+	"locks.heldThreshold":                  nonNegativeConfigValue,
+	"limits.maxObjectSize":                 nonNegativeConfigValue,
+	"limits.maxPartSize":                   nonNegativeConfigValue,
+	"limits.maxParts":                      nonNegativeConfigValue,
+	"federation.directory":                 validFederationDirectory,
+	"federation.mode":                      validFederationMode,
+}
+
+func nonEmptyConfigValue(value string) error {
+	if value == "" {
+		return errors.New("Value cannot be empty")
+	}
+	return nil
+}
+
+func nonNegativeConfigValue(value string) error {
+	if strings.HasPrefix(value, "-") {
+		return errors.New("Value cannot be negative")
+	}
+	return nil
+}
+
+func validPercentConfigValue(value string) error {
+	if percent, e := strconv.Atoi(value); e != nil || percent < 0 || percent > 100 {
+		return errors.New("Value must be a percent between 0 and 100")
+	}
+	return nil
+}
+
+func validMetadataEncoding(value string) error {
+	if _, ok := fileMetadataCodecs[value]; !ok && value != "" {
+		return fmt.Errorf("Metadata encoding must be %s or %s", fileMetadataEncodingJSON, fileMetadataEncodingMsgpack)
+	}
+	return nil
+}
+
+func validBlockCacheEviction(value string) error {
+	switch value {
+	case "", blockCacheEvictionLRU, blockCacheEvictionFIFO:
+		return nil
+	}
+	return fmt.Errorf("Eviction must be %s or %s", blockCacheEvictionLRU, blockCacheEvictionFIFO)
+}
+
+func validDomain(value string) error {
+	if strings.ContainsAny(value, "/:*") || strings.HasPrefix(value, ".") || strings.HasSuffix(value, ".") {
+		return errors.New("Domain must be a host name without scheme, port or wildcards")
+	}
+	return nil
+}
+
+func validHealWindows(value string) error {
+	_, e := parseHealWindows(value)
+	return e
+}
+
+func validLogLevel(value string) error {
+	_, e := logrus.ParseLevel(value)
+	return e
+}
+
+func validSTSDuration(value string) error {
+	if seconds, _ := strconv.ParseInt(value, 10, 64); seconds < minSTSDuration {
+		return fmt.Errorf("Duration cannot be less than %d seconds", minSTSDuration)
+	}
+	return nil
+}
+
+func validLDAPUserDNFormat(value string) error {
+	if !strings.Contains(value, "%s") {
+		return errors.New("Format must contain %s for the username")
+	}
+	return nil
+}
+
+// configEnvName - returns environment variable of key, upper snake
+// case of its path, "ldap.userDNFormat" is MINIO_LDAP_USER_DN_FORMAT.
+func configEnvName(key string) string {
+	var name []rune
+	runes := []rune(key)
+	for i, r := range runes {
+		if r == '.' {
+			name = append(name, '_')
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				name = append(name, '_')
+			}
+		}
+		name = append(name, unicode.ToUpper(r))
+	}
+	return configEnvPrefix + string(name)
+}
+
+// isSensitiveConfigKey - values of secrets are never reported.
+func isSensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "secret") || strings.Contains(key, "password")
+}
+
+// configKeysOf - appends scalar settings of struct type t to keys.
+func configKeysOf(t reflect.Type, prefix string, index []int, keys []configKey) []configKey {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		if configExcludedKeys[key] {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		var typ string
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = configKeysOf(field.Type, key+".", fieldIndex, keys)
+			continue
+		case reflect.Bool:
+			typ = configTypeBool
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			typ = configTypeInt
+		case reflect.Float32, reflect.Float64:
+			typ = configTypeFloat
+		case reflect.String:
+			typ = configTypeString
+		default:
+			// Maps and lists are managed by their own APIs.
+			continue
+		}
+		keys = append(keys, configKey{
+			Key:       key,
+			Type:      typ,
+			Env:       configEnvName(key),
+			index:     fieldIndex,
+			sensitive: isSensitiveConfigKey(key),
+		})
+	}
+	return keys
+}
+
+// configSchema - all settings of serverConfigV5 sorted by key.
+var configSchema = func() []configKey {
+	keys := configKeysOf(reflect.TypeOf(serverConfigV5{}), "", nil, nil)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
+}()
+
+// getConfigKey - returns setting with key.
+func getConfigKey(key string) (configKey, bool) {
+	i := sort.Search(len(configSchema), func(i int) bool { return configSchema[i].Key >= key })
+	if i < len(configSchema) && configSchema[i].Key == key {
+		return configSchema[i], true
+	}
+	return configKey{}, false
+}
+
+// formatConfigValue - returns value of the setting as a string.
+func formatConfigValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	return v.String()
+}
+
+// parseConfigValue - parses value of the type of the setting into v.
+func parseConfigValue(k configKey, v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, e := strconv.ParseBool(value)
+		if e != nil {
+			return fmt.Errorf("Invalid %s value %q for %s", k.Type, value, k.Key)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, e := strconv.ParseInt(value, 10, v.Type().Bits())
+		if e != nil {
+			return fmt.Errorf("Invalid %s value %q for %s", k.Type, value, k.Key)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, e := strconv.ParseUint(value, 10, v.Type().Bits())
+		if e != nil {
+			return fmt.Errorf("Invalid %s value %q for %s", k.Type, value, k.Key)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, e := strconv.ParseFloat(value, v.Type().Bits())
+		if e != nil {
+			return fmt.Errorf("Invalid %s value %q for %s", k.Type, value, k.Key)
+		}
+		v.SetFloat(f)
+	default:
+		v.SetString(value)
+	}
+	return nil
+}
+
+// validateConfigValue - validates value of the setting beyond its type.
+func validateConfigValue(k configKey, value string) error {
+	if validate, ok := configValidators[k.Key]; ok {
+		if e := validate(value); e != nil {
+			return fmt.Errorf("Invalid value %q for %s: %s", value, k.Key, e)
+		}
+	}
+	return nil
+}
+
+// setConfigField - sets the setting of s, s is left as is if value is
+// not of the type of the setting.
+func setConfigField(s *serverConfigV5, k configKey, value string) error {
+	field := reflect.ValueOf(s).Elem().FieldByIndex(k.index)
+	v := reflect.New(field.Type()).Elem()
+	if e := parseConfigValue(k, v, value); e != nil {
+		return e
+	}
+	field.Set(v)
+	return nil
+}
+
+// getConfigField - returns the setting of s as a string.
+func getConfigField(s *serverConfigV5, k configKey) string {
+	return formatConfigValue(reflect.ValueOf(s).Elem().FieldByIndex(k.index))
+}
+
+// ApplyEnvOverrides - overrides settings with the values of their
+// environment variables from lookupEnv, settings of the config file
+// are kept for saving. Fails on the first invalid value.
+func (s *serverConfigV5) ApplyEnvOverrides(lookupEnv func(string) (string, bool)) error {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	for _, k := range configSchema {
+		value, ok := lookupEnv(k.Env)
+		if !ok {
+			continue
+		}
+		if e := validateConfigValue(k, value); e != nil {
+			return fmt.Errorf("%s: %s", k.Env, e)
+		}
+		fileValue := getConfigField(s, k)
+		if e := setConfigField(s, k, value); e != nil {
+			return fmt.Errorf("%s: %s", k.Env, e)
+		}
+		if s.overrides.env == nil {
+			s.overrides.env = make(map[string]string)
+		}
+		// Overridden again, the config file value was saved already.
+		if _, overridden := s.overrides.env[k.Key]; !overridden {
+			s.overrides.env[k.Key] = fileValue
+		}
+	}
+	return nil
+}
+
+// initEnvOverrides - overrides settings with the environment.
+func initEnvOverrides() error {
+	return serverConfig.ApplyEnvOverrides(os.LookupEnv)
+}
+
+// configValueOf - returns effective value of setting k and its source.
+func (s *serverConfigV5) configValueOf(k configKey, defaults *serverConfigV5) configValue {
+	value := getConfigField(s, k)
+	source := configSourceFile
+	if _, ok := s.overrides.env[k.Key]; ok {
+		source = configSourceEnv
+	} else if s.overrides.admin[k.Key] {
+		source = configSourceAdmin
+	} else if value == getConfigField(defaults, k) {
+		source = configSourceDefault
+	}
+	if k.sensitive && value != "" {
+		value = configRedactedValue
+	}
+	return configValue{Key: k.Key, Type: k.Type, Value: value, Source: source, Env: k.Env, Dynamic: isDynamicConfigKey(k.Key)}
+}
+
+// GetConfigValues - returns effective values of all settings.
+func (s *serverConfigV5) GetConfigValues() []configValue {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	defaults := newServerConfigV5()
+	values := make([]configValue, 0, len(configSchema))
+	for _, k := range configSchema {
+		values = append(values, s.configValueOf(k, defaults))
+	}
+	return values
+}
+
+// GetConfigValue - returns effective value of setting with key.
+func (s *serverConfigV5) GetConfigValue(key string) (configValue, error) {
+	k, ok := getConfigKey(key)
+	if !ok {
+		return configValue{}, errConfigKeyNotFound
+	}
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.configValueOf(k, newServerConfigV5()), nil
+}
+
+// SetConfigValue - validates and sets setting with key, settings
+// overridden by environment variables cannot be set. The config needs
+// to be saved afterwards.
+func (s *serverConfigV5) SetConfigValue(key, value string) error {
+	k, ok := getConfigKey(key)
+	if !ok {
+		return errConfigKeyNotFound
+	}
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if _, ok = s.overrides.env[k.Key]; ok {
+		return errConfigOverriddenEnv
+	}
+	if e := validateConfigValue(k, value); e != nil {
+		return e
+	}
+	if e := setConfigField(s, k, value); e != nil {
+		return e
+	}
+	if s.overrides.admin == nil {
+		s.overrides.admin = make(map[string]bool)
+	}
+	s.overrides.admin[k.Key] = true
+	return nil
+}
+
+// withoutEnvOverrides - sets settings of s overridden by environment
+// variables back to the values of the config file.
+func (s *serverConfigV5) withoutEnvOverrides() {
+	for key, value := range s.overrides.env {
+		k, _ := getConfigKey(key)
+		setConfigField(s, k, value)
+	}
+}
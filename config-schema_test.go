@@ -0,0 +1,157 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests environment variables of config keys.
+func TestConfigEnvName(t *testing.T) {
+	testCases := []struct {
+		key string
+		env string
+	}{
+		{"region", "MINIO_REGION"},
+		{"quarantine.maxSize", "MINIO_QUARANTINE_MAX_SIZE"},
+		{"ldap.userDNFormat", "MINIO_LDAP_USER_DN_FORMAT"},
+		{"ldap.tls", "MINIO_LDAP_TLS"},
+		{"security.hsts.maxAge", "MINIO_SECURITY_HSTS_MAX_AGE"},
+	}
+	for i, testCase := range testCases {
+		if env := configEnvName(testCase.key); env != testCase.env {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.env, env)
+		}
+	}
+}
+
+// Tests effective config values follow the precedence of their sources
+// and environment variables are never saved.
+func TestConfigOverrides(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-config-schema")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+	savedConfig := serverConfig
+	defer func() {
+		serverConfig = savedConfig
+	}()
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if e = serverConfig.SetConfigValue("heal.maxWorkers", "3"); e != nil {
+		t.Fatal(e)
+	}
+	if err := serverConfig.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"MINIO_REGION":            "eu-west-1",
+		"MINIO_QUARANTINE_ENABLE": "true",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	if e = serverConfig.ApplyEnvOverrides(lookupEnv); e != nil {
+		t.Fatal(e)
+	}
+	if e = serverConfig.SetConfigValue("quarantine.maxSize", "1024"); e != nil {
+		t.Fatal(e)
+	}
+
+	testCases := []struct {
+		key    string
+		value  string
+		source string
+	}{
+		{"region", "eu-west-1", configSourceEnv},
+		{"quarantine.enable", "true", configSourceEnv},
+		{"quarantine.maxSize", "1024", configSourceAdmin},
+		{"heal.maxWorkers", "3", configSourceFile},
+		{"logger.console.level", "fatal", configSourceDefault},
+	}
+	for i, testCase := range testCases {
+		value, e := serverConfig.GetConfigValue(testCase.key)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if value.Value != testCase.value || value.Source != testCase.source {
+			t.Errorf("Test %d: Expected %s from %s, got %s from %s", i+1, testCase.value, testCase.source, value.Value, value.Source)
+		}
+	}
+	if serverConfig.GetRegion() != "eu-west-1" || !serverConfig.GetQuarantine().Enable {
+		t.Fatal("Expected environment variables to override settings")
+	}
+
+	setTestCases := []struct {
+		key   string
+		value string
+		err   bool
+	}{
+		{"region", "us-west-1", true},
+		{"unknown", "1", true},
+		{"heal.maxWorkers", "many", true},
+		{"logger.console.level", "loud", true},
+		{"sts.maxDuration", "60", true},
+		{"quarantine.maxSize", "-1", true},
+		{"domain", "https://s3.example.com", true},
+		{"heal.windows", "nights", true},
+		{"heal.windows", "Mon-Fri 22:00-06:00", false},
+		{"logger.console.level", "error", false},
+	}
+	for i, testCase := range setTestCases {
+		if e = serverConfig.SetConfigValue(testCase.key, testCase.value); (e != nil) != testCase.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, e)
+		}
+	}
+	if _, e = serverConfig.GetConfigValue("unknown"); e != errConfigKeyNotFound {
+		t.Fatalf("Expected %v, got %v", errConfigKeyNotFound, e)
+	}
+	if e = serverConfig.ApplyEnvOverrides(func(name string) (string, bool) {
+		return "maybe", name == "MINIO_LDAP_ENABLE"
+	}); e == nil {
+		t.Fatal("Expected invalid environment variable to fail")
+	}
+
+	// Environment variables are not saved, admin settings are.
+	if err := serverConfig.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if region := serverConfig.GetRegion(); region != "us-east-1" {
+		t.Errorf("Expected saved region us-east-1, got %s", region)
+	}
+	if quarantine := serverConfig.GetQuarantine(); quarantine.Enable || quarantine.MaxSize != 1024 {
+		t.Errorf("Unexpected saved quarantine config %+v", quarantine)
+	}
+	if value, _ := serverConfig.GetConfigValue("logger.console.level"); value.Value != "error" || value.Source != configSourceFile {
+		t.Errorf("Unexpected saved console logger level %+v", value)
+	}
+}
@@ -0,0 +1,328 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// The config is saved in minioMetaVolume, shared by all servers of a
+// deployment, along with the config file which keeps the settings read
+// before the object layer is initialized. The config of the object
+// layer wins over the config file once it is loaded.
+const (
+	configMetaPath = "config/config.json"
+	maxConfigSize  = 1024 * 1024
+)
+
+// Object layer the config is saved to, set once it is loaded.
+var globalConfigStore *objectAPI
+
+// Settings of subsystems which are reconfigured as soon as the config
+// changes, all other settings take effect on restart. Notification
+// targets, which are not single settings, are reconfigured as well.
+var configDynamicPrefixes = []string{
+	"domain",
+	"logger.console.",
+	"multipart.",
+	"heal.",
+	"diskShares.",
+	"diskIO.",
+	"quarantine.",
+	"trash.",
+	"cache.",
+	"blockCache.",
+	"dataUsage.",
+	"locks.",
+	"limits.",
+}
+
+// isDynamicConfigKey - returns true if setting with key takes effect
+// without a restart.
+func isDynamicConfigKey(key string) bool {
+	for _, prefix := range configDynamicPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// initConfigStore - loads the config saved in o, the config file is
+// saved to o on first start.
+func initConfigStore(o objectAPI) {
+	configBytes, e := o.readMetaFile(configMetaPath, maxConfigSize)
+	if e != nil {
+		if errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+			fatalIf(probe.NewError(e), "Unable to read config.", nil)
+		}
+		globalConfigStore = &o
+		fatalIf(serverConfig.Save().Trace(), "Unable to save config.", nil)
+		return
+	}
+	srvCfg, e := parseConfigDocument(configBytes)
+	fatalIf(probe.NewError(e), "Unable to parse config.", nil)
+	e = serverConfig.replaceWith(srvCfg, false)
+	fatalIf(probe.NewError(e), "Invalid config environment variable.", nil)
+	globalConfigStore = &o
+	// Keep the config file in sync for the next start.
+	fatalIf(serverConfig.Save().Trace(), "Unable to save config.", nil)
+	enableConsoleLogger()
+}
+
+// saveConfigDocument - saves config document of s to o.
+func saveConfigDocument(o objectAPI, s *serverConfigV5) error {
+	configBytes, e := json.MarshalIndent(s, "", "\t")
+	if e != nil {
+		return e
+	}
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(configMetaPath, configBytes)
+}
+
+// parseConfigDocument - parses a config document of the current
+// config version.
+func parseConfigDocument(configBytes []byte) (*serverConfigV5, error) {
+	srvCfg := &serverConfigV5{rwMutex: &sync.RWMutex{}}
+	if e := json.Unmarshal(configBytes, srvCfg); e != nil {
+		return nil, e
+	}
+	if srvCfg.Version != globalMinioConfigVersion {
+		return nil, fmt.Errorf("Unsupported config version %q, expected %q", srvCfg.Version, globalMinioConfigVersion)
+	}
+	return srvCfg, nil
+}
+
+// GetConfigDocument - returns config document of s as saved, without
+// environment variables, credentials and secrets.
+func (s *serverConfigV5) GetConfigDocument() ([]byte, error) {
+	current := s.snapshot()
+	current.withoutEnvOverrides()
+	// Parse a copy, which shares no lists and maps with s.
+	configBytes, e := json.Marshal(current)
+	if e != nil {
+		return nil, e
+	}
+	doc, e := parseConfigDocument(configBytes)
+	if e != nil {
+		return nil, e
+	}
+	doc.Credential = credential{}
+	doc.PreviousCredential = nil
+	walkConfigSecrets(reflect.ValueOf(doc), reflect.Value{}, func(secret, _ reflect.Value) {
+		if secret.String() != "" {
+			secret.SetString(configRedactedValue)
+		}
+	})
+	return json.MarshalIndent(doc, "", "\t")
+}
+
+// walkConfigSecrets - calls fn with every secret of v, along with the
+// secret at the same place of current, which is invalid if there is
+// none. Secrets of v are settable.
+func walkConfigSecrets(v, current reflect.Value, fn func(secret, current reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if current.IsValid() && !current.IsNil() {
+			current = current.Elem()
+		} else {
+			current = reflect.Value{}
+		}
+		walkConfigSecrets(v.Elem(), current, fn)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			var currentField reflect.Value
+			if current.IsValid() {
+				currentField = current.Field(i)
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.Type.Kind() == reflect.String && isSensitiveConfigKey(name) {
+				fn(v.Field(i), currentField)
+				continue
+			}
+			walkConfigSecrets(v.Field(i), currentField, fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			var currentElem reflect.Value
+			if current.IsValid() && i < current.Len() {
+				currentElem = current.Index(i)
+			}
+			walkConfigSecrets(v.Index(i), currentElem, fn)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// Values of maps are not settable, walk a copy.
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			var currentElem reflect.Value
+			if current.IsValid() {
+				currentElem = current.MapIndex(key)
+			}
+			walkConfigSecrets(elem, currentElem, fn)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// SetConfigDocument - validates settings changed by config document
+// and replaces settings of s with it. Credentials are kept, secrets
+// left redacted are kept as well. The config needs to be saved
+// afterwards.
+func (s *serverConfigV5) SetConfigDocument(configBytes []byte) error {
+	srvCfg, e := parseConfigDocument(configBytes)
+	if e != nil {
+		return e
+	}
+	current := s.snapshot()
+	current.withoutEnvOverrides()
+	walkConfigSecrets(reflect.ValueOf(srvCfg), reflect.ValueOf(current), func(secret, current reflect.Value) {
+		if secret.String() == configRedactedValue && current.IsValid() {
+			secret.SetString(current.String())
+		}
+	})
+	for _, k := range configSchema {
+		value := getConfigField(srvCfg, k)
+		if value == getConfigField(current, k) {
+			continue
+		}
+		if e = validateConfigValue(k, value); e != nil {
+			return fmt.Errorf("%s: %s", k.Key, e)
+		}
+	}
+	return s.replaceWith(srvCfg, true)
+}
+
+// ReloadConfigDocument - replaces settings of s with the config saved
+// in o, by another server or before a restart.
+func (s *serverConfigV5) ReloadConfigDocument(o objectAPI) error {
+	configBytes, e := o.readMetaFile(configMetaPath, maxConfigSize)
+	if e != nil {
+		return e
+	}
+	srvCfg, e := parseConfigDocument(configBytes)
+	if e != nil {
+		return e
+	}
+	return s.replaceWith(srvCfg, false)
+}
+
+// replaceWith - replaces settings of s with those of srvCfg along with
+// environment variables overriding them, credentials of s are kept.
+// Settings changed by srvCfg are reported as set by admins if admin is
+// true, sources of settings are reset otherwise.
+func (s *serverConfigV5) replaceWith(srvCfg *serverConfigV5, admin bool) error {
+	srvCfg.overrides = configOverrides{}
+	if e := srvCfg.ApplyEnvOverrides(os.LookupEnv); e != nil {
+		return e
+	}
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	srvCfg.Credential = s.Credential
+	srvCfg.PreviousCredential = s.PreviousCredential
+	if admin {
+		srvCfg.overrides.admin = make(map[string]bool)
+		for key := range s.overrides.admin {
+			srvCfg.overrides.admin[key] = true
+		}
+		for _, k := range configSchema {
+			if getConfigField(srvCfg, k) != getConfigField(s, k) {
+				srvCfg.overrides.admin[k.Key] = true
+			}
+		}
+	}
+	rwMutex := s.rwMutex
+	*s = *srvCfg
+	s.rwMutex = rwMutex
+	return nil
+}
+
+// snapshot - returns a copy of s, settings of s replaced afterwards
+// are not changed in the copy.
+func (s *serverConfigV5) snapshot() *serverConfigV5 {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	srvCfg := *s
+	srvCfg.rwMutex = &sync.RWMutex{}
+	return &srvCfg
+}
+
+// reloadConfigSubsystems - reconfigures subsystems of o whose settings
+// changed from prev.
+func reloadConfigSubsystems(o objectAPI, prev *serverConfigV5) *probe.Error {
+	if !reflect.DeepEqual(prev.GetConsoleLogger(), serverConfig.GetConsoleLogger()) {
+		enableConsoleLogger()
+	}
+	if !reflect.DeepEqual(prev.GetKafka(), serverConfig.GetKafka()) ||
+		!reflect.DeepEqual(prev.GetNATS(), serverConfig.GetNATS()) ||
+		!reflect.DeepEqual(prev.GetMQTT(), serverConfig.GetMQTT()) ||
+		!reflect.DeepEqual(prev.GetPostgreSQL(), serverConfig.GetPostgreSQL()) ||
+		!reflect.DeepEqual(prev.GetMySQL(), serverConfig.GetMySQL()) {
+		globalEventNotifier.SetConfigTargets(newConfigNotificationTargets())
+	}
+	if !reflect.DeepEqual(prev.GetMultipart(), serverConfig.GetMultipart()) {
+		o.SetMultipartLimits(serverConfig.GetMultipart())
+	}
+	if !reflect.DeepEqual(prev.GetHeal(), serverConfig.GetHeal()) {
+		globalHealControl.SetConfig(serverConfig.GetHeal())
+	}
+	if !reflect.DeepEqual(prev.GetDiskShares(), serverConfig.GetDiskShares()) {
+		globalDiskShares.Set(serverConfig.GetDiskShares())
+	}
+	if !reflect.DeepEqual(prev.GetDiskIO(), serverConfig.GetDiskIO()) {
+		globalDiskIO.Set(serverConfig.GetDiskIO())
+	}
+	if !reflect.DeepEqual(prev.GetLocks(), serverConfig.GetLocks()) {
+		globalNameSpaceLocks.Set(serverConfig.GetLocks())
+	}
+	if !reflect.DeepEqual(prev.GetLimits(), serverConfig.GetLimits()) {
+		globalObjectLimits.Set(serverConfig.GetLimits())
+	}
+	if !reflect.DeepEqual(prev.GetQuarantine(), serverConfig.GetQuarantine()) {
+		o.SetQuarantine(serverConfig.GetQuarantine())
+	}
+	if !reflect.DeepEqual(prev.GetTrash(), serverConfig.GetTrash()) {
+		o.SetTrash(serverConfig.GetTrash())
+	}
+	if !reflect.DeepEqual(prev.GetCache(), serverConfig.GetCache()) {
+		if e := o.SetCache(serverConfig.GetCache()); e != nil {
+			return probe.NewError(e)
+		}
+	}
+	if cacher, ok := o.storage.(blockCacher); ok && !reflect.DeepEqual(prev.GetBlockCache(), serverConfig.GetBlockCache()) {
+		cacher.SetBlockCache(serverConfig.GetBlockCache())
+	}
+	// Data usage crawler and multipart cleaner read their settings
+	// before each run.
+	return nil
+}
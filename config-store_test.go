@@ -0,0 +1,146 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Tests the config is saved to the object layer, edited as a document
+// and reloaded by other servers.
+func TestConfigStore(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-config-store")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+	savedConfig, savedStore, savedLevel, savedOut := serverConfig, globalConfigStore, log.Level, log.Out
+	defer func() {
+		serverConfig, globalConfigStore, log.Level, log.Out = savedConfig, savedStore, savedLevel, savedOut
+	}()
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	serverConfig.SetRPC(rpcAuth{Secrets: []rpcSecret{{ID: "1", Secret: "cluster-secret"}}})
+
+	directory, e := ioutil.TempDir("", "minio-config-store-fs")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+
+	// Config file is saved to the object layer on first start.
+	initConfigStore(obj)
+	if _, e = obj.readMetaFile(configMetaPath, maxConfigSize); e != nil {
+		t.Fatal(e)
+	}
+
+	// Documents carry no credentials and secrets.
+	doc, e := serverConfig.GetConfigDocument()
+	if e != nil {
+		t.Fatal(e)
+	}
+	cred := serverConfig.GetCredential()
+	for _, secret := range []string{cred.SecretAccessKey, "cluster-secret"} {
+		if bytes.Contains(doc, []byte(secret)) {
+			t.Fatalf("Expected %s to be redacted from %s", secret, doc)
+		}
+	}
+
+	if e = serverConfig.SetConfigDocument([]byte(`{"version": "1"}`)); e == nil {
+		t.Fatal("Expected document of another version to be refused")
+	}
+	srvCfg, e := parseConfigDocument(doc)
+	if e != nil {
+		t.Fatal(e)
+	}
+	srvCfg.Region = ""
+	if e = serverConfig.SetConfigDocument(mustMarshalConfig(t, srvCfg)); e == nil {
+		t.Fatal("Expected document with invalid values to be refused")
+	}
+
+	// Secrets left redacted and credentials are kept.
+	prev := serverConfig.snapshot()
+	srvCfg.Region = "eu-west-1"
+	srvCfg.Logger.Console.Level = "debug"
+	if e = serverConfig.SetConfigDocument(mustMarshalConfig(t, srvCfg)); e != nil {
+		t.Fatal(e)
+	}
+	if err := serverConfig.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadConfigSubsystems(obj, prev); err != nil {
+		t.Fatal(err)
+	}
+	if log.Level != logrus.DebugLevel {
+		t.Fatalf("Expected console logger to be reloaded, got level %s", log.Level)
+	}
+	if serverConfig.GetCredential() != cred || serverConfig.GetRPCSecrets()[0].Secret != "cluster-secret" {
+		t.Fatal("Expected credentials and secrets to be kept")
+	}
+	value, e := serverConfig.GetConfigValue("region")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if value.Value != "eu-west-1" || value.Source != configSourceAdmin || value.Dynamic {
+		t.Fatalf("Unexpected region %+v", value)
+	}
+
+	// Another server saves a change, which is reloaded.
+	other := serverConfig.snapshot()
+	other.Heal.MaxWorkers = 7
+	if e = saveConfigDocument(obj, other); e != nil {
+		t.Fatal(e)
+	}
+	if e = serverConfig.ReloadConfigDocument(obj); e != nil {
+		t.Fatal(e)
+	}
+	if serverConfig.GetHeal().MaxWorkers != 7 {
+		t.Fatalf("Expected reloaded config, got %+v", serverConfig.GetHeal())
+	}
+
+	// Config of the object layer wins over the config file on restart.
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	serverConfig.SetRegion("us-east-1")
+	initConfigStore(obj)
+	if serverConfig.GetRegion() != "eu-west-1" || serverConfig.GetHeal().MaxWorkers != 7 {
+		t.Fatal("Expected config of the object layer to be loaded")
+	}
+}
+
+func mustMarshalConfig(t *testing.T, s *serverConfigV5) []byte {
+	doc, e := s.GetConfigDocument()
+	if e != nil {
+		t.Fatal(e)
+	}
+	return doc
+}
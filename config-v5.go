@@ -0,0 +1,914 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+	"github.com/minio/minio/pkg/quick"
+)
+
+// serverConfigV5 server configuration version '5'.
+type serverConfigV5 struct {
+	Version string `json:"version"`
+
+	// S3 API configuration.
+	Credential credential `json:"credential"`
+	Region     string     `json:"region"`
+
+	// Domain of virtual host style requests, which name their bucket
+	// in the host as in bucket.domain. Disabled if empty.
+	Domain string `json:"domain"`
+
+	// Server credential replaced by the last rotation, accepted until
+	// it expires.
+	PreviousCredential *previousCredential `json:"previousCredential,omitempty"`
+
+	// Additional error logging configuration.
+	Logger logger `json:"logger"`
+
+	// Notification queue configuration.
+	Notify notifier `json:"notify"`
+
+	// Security headers and redirect configuration.
+	Security security `json:"security"`
+
+	// HTTPS configuration.
+	TLS tlsConfig `json:"tls"`
+
+	// Automatic certificates configuration.
+	ACME acmeConfig `json:"acme"`
+
+	// Federation of deployments configuration.
+	Federation federationConfig `json:"federation"`
+
+	// Bucket lifecycle expiration configuration.
+	Lifecycle lifecycleScanner `json:"lifecycle"`
+
+	// Capacity alarms configuration.
+	Alarms alarms `json:"alarms"`
+
+	// Disk health checks configuration.
+	DiskHealth diskHealthConfig `json:"diskHealth"`
+
+	// Remote tiers for lifecycle transitions, keyed by tier name.
+	Tiers map[string]remoteTier `json:"tiers"`
+
+	// Storage classes of objects, keyed by class name.
+	StorageClasses map[string]storageClass `json:"storageClasses"`
+
+	// Storage RPC authentication configuration.
+	RPC rpcAuth `json:"rpc"`
+
+	// Audit log configuration.
+	Audit auditConfig `json:"audit"`
+
+	// Multipart upload limits configuration.
+	Multipart multipartConfig `json:"multipart"`
+
+	// Object attestation configuration.
+	Attestation attestationConfig `json:"attestation"`
+
+	// Heal concurrency, rate and schedule configuration.
+	Heal healConfig `json:"heal"`
+
+	// Per bucket disk shares configuration.
+	DiskShares diskSharesConfig `json:"diskShares"`
+
+	// Temporary credentials configuration.
+	STS stsConfig `json:"sts"`
+
+	// LDAP identity configuration.
+	LDAP ldapConfig `json:"ldap"`
+
+	// Upload quarantine configuration.
+	Quarantine quarantineConfig `json:"quarantine"`
+
+	// Deleted objects trash configuration.
+	Trash trashConfig `json:"trash"`
+
+	// Local disk cache configuration.
+	Cache cacheConfig `json:"cache"`
+
+	// In memory block cache configuration.
+	BlockCache blockCacheConfig `json:"blockCache"`
+
+	// Local disk I/O configuration.
+	DiskIO diskIOConfig `json:"diskIO"`
+
+	// Data usage crawler configuration.
+	DataUsage dataUsageConfig `json:"dataUsage"`
+
+	// Namespace lock timeouts configuration.
+	Locks nameSpaceLockConfig `json:"locks"`
+
+	// Object and part size limits configuration.
+	Limits objectLimitsConfig `json:"limits"`
+
+	// Settings overridden by environment variables or set by admins.
+	overrides configOverrides
+
+	// Read Write mutex.
+	rwMutex *sync.RWMutex
+}
+
+// newServerConfigV5 - returns config of a fresh run, without
+// credentials.
+func newServerConfigV5() *serverConfigV5 {
+	srvCfg := &serverConfigV5{}
+	srvCfg.Version = globalMinioConfigVersion
+	srvCfg.Region = "us-east-1"
+	// Enable console logger by default on a fresh run.
+	srvCfg.Logger.Console = consoleLogger{
+		Enable: true,
+		Level:  "fatal",
+	}
+	// Make sure to initialize notification configs.
+	srvCfg.Notify.Kafka = make(map[string]kafkaNotify)
+	srvCfg.Notify.Kafka["1"] = kafkaNotify{}
+	srvCfg.Notify.NATS = make(map[string]natsNotify)
+	srvCfg.Notify.NATS["1"] = natsNotify{}
+	srvCfg.Notify.MQTT = make(map[string]mqttNotify)
+	srvCfg.Notify.MQTT["1"] = mqttNotify{}
+	srvCfg.Notify.PostgreSQL = make(map[string]postgreSQLNotify)
+	srvCfg.Notify.PostgreSQL["1"] = postgreSQLNotify{}
+	srvCfg.Notify.MySQL = make(map[string]mySQLNotify)
+	srvCfg.Notify.MySQL["1"] = mySQLNotify{}
+	srvCfg.Security = newSecurityConfig()
+	srvCfg.TLS = newTLSConfig()
+	srvCfg.ACME = newACMEConfig()
+	srvCfg.Federation = newFederationConfig()
+	srvCfg.Lifecycle = newLifecycleScanner()
+	srvCfg.Alarms = newAlarmsConfig()
+	srvCfg.DiskHealth = newDiskHealthConfig()
+	srvCfg.Tiers = make(map[string]remoteTier)
+	srvCfg.StorageClasses = make(map[string]storageClass)
+	srvCfg.RPC = newRPCAuthConfig()
+	srvCfg.Audit = newAuditConfig()
+	srvCfg.Multipart = newMultipartConfig()
+	srvCfg.Heal = newHealConfig()
+	srvCfg.DiskShares = newDiskSharesConfig()
+	srvCfg.STS = newSTSConfig()
+	srvCfg.LDAP = newLDAPConfig()
+	srvCfg.rwMutex = &sync.RWMutex{}
+	return srvCfg
+}
+
+// initConfig - initialize server config. config version (called only once).
+func initConfig() *probe.Error {
+	if !isConfigFileExists() {
+		srvCfg := newServerConfigV5()
+		srvCfg.Credential = mustGenAccessKeys()
+		// Create config path.
+		err := createConfigPath()
+		if err != nil {
+			return err.Trace()
+		}
+
+		// Create certs path.
+		err = createCertsPath()
+		if err != nil {
+			return err.Trace()
+		}
+
+		// Save the new config globally.
+		serverConfig = srvCfg
+
+		// Save config into file.
+		err = serverConfig.Save()
+		if err != nil {
+			return err.Trace()
+		}
+		return nil
+	}
+	configFile, err := getConfigFile()
+	if err != nil {
+		return err.Trace()
+	}
+	if _, e := os.Stat(configFile); err != nil {
+		return probe.NewError(e)
+	}
+	srvCfg := &serverConfigV5{}
+	srvCfg.Version = globalMinioConfigVersion
+	srvCfg.rwMutex = &sync.RWMutex{}
+	qc, err := quick.New(srvCfg)
+	if err != nil {
+		return err.Trace()
+	}
+	if err := qc.Load(configFile); err != nil {
+		return err.Trace()
+	}
+	// Save the loaded config globally.
+	serverConfig = qc.Data().(*serverConfigV5)
+	// Set the version properly after the unmarshalled json is loaded.
+	serverConfig.Version = globalMinioConfigVersion
+	return nil
+}
+
+// serverConfig server config.
+var serverConfig *serverConfigV5
+
+// GetVersion get current config version.
+func (s serverConfigV5) GetVersion() string {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Version
+}
+
+/// Logger related.
+
+// SetFileLogger set new file logger.
+func (s *serverConfigV5) SetFileLogger(flogger fileLogger) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Logger.File = flogger
+}
+
+// GetFileLogger get current file logger.
+func (s serverConfigV5) GetFileLogger() fileLogger {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Logger.File
+}
+
+// SetConsoleLogger set new console logger.
+func (s *serverConfigV5) SetConsoleLogger(clogger consoleLogger) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Logger.Console = clogger
+}
+
+// GetConsoleLogger get current console logger.
+func (s serverConfigV5) GetConsoleLogger() consoleLogger {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Logger.Console
+}
+
+// SetSyslogLogger set new syslog logger.
+func (s *serverConfigV5) SetSyslogLogger(slogger syslogLogger) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Logger.Syslog = slogger
+}
+
+// GetSyslogLogger get current syslog logger.
+func (s *serverConfigV5) GetSyslogLogger() syslogLogger {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Logger.Syslog
+}
+
+/// Notification related.
+
+// GetKafka get current kafka notification targets.
+func (s serverConfigV5) GetKafka() map[string]kafkaNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.Kafka
+}
+
+// GetKafkaNotifyByID get kafka notification target by its account id.
+func (s serverConfigV5) GetKafkaNotifyByID(accountID string) kafkaNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.Kafka[accountID]
+}
+
+// SetKafkaNotifyByID set new kafka notification target for account id.
+func (s *serverConfigV5) SetKafkaNotifyByID(accountID string, kNotify kafkaNotify) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Notify.Kafka == nil {
+		s.Notify.Kafka = make(map[string]kafkaNotify)
+	}
+	s.Notify.Kafka[accountID] = kNotify
+}
+
+// GetNATS get current NATS notification targets.
+func (s serverConfigV5) GetNATS() map[string]natsNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.NATS
+}
+
+// GetNATSNotifyByID get NATS notification target by its account id.
+func (s serverConfigV5) GetNATSNotifyByID(accountID string) natsNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.NATS[accountID]
+}
+
+// SetNATSNotifyByID set new NATS notification target for account id.
+func (s *serverConfigV5) SetNATSNotifyByID(accountID string, nNotify natsNotify) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Notify.NATS == nil {
+		s.Notify.NATS = make(map[string]natsNotify)
+	}
+	s.Notify.NATS[accountID] = nNotify
+}
+
+// GetMQTT get current MQTT notification targets.
+func (s serverConfigV5) GetMQTT() map[string]mqttNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.MQTT
+}
+
+// GetMQTTNotifyByID get MQTT notification target by its account id.
+func (s serverConfigV5) GetMQTTNotifyByID(accountID string) mqttNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.MQTT[accountID]
+}
+
+// SetMQTTNotifyByID set new MQTT notification target for account id.
+func (s *serverConfigV5) SetMQTTNotifyByID(accountID string, mNotify mqttNotify) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Notify.MQTT == nil {
+		s.Notify.MQTT = make(map[string]mqttNotify)
+	}
+	s.Notify.MQTT[accountID] = mNotify
+}
+
+// GetPostgreSQL get current PostgreSQL notification targets.
+func (s serverConfigV5) GetPostgreSQL() map[string]postgreSQLNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.PostgreSQL
+}
+
+// GetPostgreSQLNotifyByID get PostgreSQL notification target by its account id.
+func (s serverConfigV5) GetPostgreSQLNotifyByID(accountID string) postgreSQLNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.PostgreSQL[accountID]
+}
+
+// SetPostgreSQLNotifyByID set new PostgreSQL notification target for account id.
+func (s *serverConfigV5) SetPostgreSQLNotifyByID(accountID string, pgNotify postgreSQLNotify) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Notify.PostgreSQL == nil {
+		s.Notify.PostgreSQL = make(map[string]postgreSQLNotify)
+	}
+	s.Notify.PostgreSQL[accountID] = pgNotify
+}
+
+// GetMySQL get current MySQL notification targets.
+func (s serverConfigV5) GetMySQL() map[string]mySQLNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.MySQL
+}
+
+// GetMySQLNotifyByID get MySQL notification target by its account id.
+func (s serverConfigV5) GetMySQLNotifyByID(accountID string) mySQLNotify {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Notify.MySQL[accountID]
+}
+
+// SetMySQLNotifyByID set new MySQL notification target for account id.
+func (s *serverConfigV5) SetMySQLNotifyByID(accountID string, myNotify mySQLNotify) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Notify.MySQL == nil {
+		s.Notify.MySQL = make(map[string]mySQLNotify)
+	}
+	s.Notify.MySQL[accountID] = myNotify
+}
+
+/// Security related.
+
+// GetSecurity get current security configuration.
+func (s serverConfigV5) GetSecurity() security {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Security
+}
+
+// SetSecurity set new security configuration.
+func (s *serverConfigV5) SetSecurity(sec security) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Security = sec
+}
+
+/// TLS related.
+
+// GetTLS get current TLS configuration.
+func (s serverConfigV5) GetTLS() tlsConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.TLS
+}
+
+// SetTLS set new TLS configuration.
+func (s *serverConfigV5) SetTLS(config tlsConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.TLS = config
+}
+
+/// ACME related.
+
+// GetACME get current ACME configuration.
+func (s serverConfigV5) GetACME() acmeConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.ACME
+}
+
+// SetACME set new ACME configuration.
+func (s *serverConfigV5) SetACME(config acmeConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.ACME = config
+}
+
+/// Federation related.
+
+// GetFederation get current federation configuration.
+func (s serverConfigV5) GetFederation() federationConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Federation
+}
+
+// SetFederation set new federation configuration.
+func (s *serverConfigV5) SetFederation(config federationConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Federation = config
+}
+
+/// Lifecycle related.
+
+// GetLifecycle get current lifecycle expiration configuration.
+func (s serverConfigV5) GetLifecycle() lifecycleScanner {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Lifecycle
+}
+
+// SetLifecycle set new lifecycle expiration configuration.
+func (s *serverConfigV5) SetLifecycle(lifecycle lifecycleScanner) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Lifecycle = lifecycle
+}
+
+/// Alarms related.
+
+// GetAlarms get current capacity alarms configuration.
+func (s serverConfigV5) GetAlarms() alarms {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Alarms
+}
+
+// SetAlarms set new capacity alarms configuration.
+func (s *serverConfigV5) SetAlarms(a alarms) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Alarms = a
+}
+
+/// Disk health related.
+
+// GetDiskHealth get current disk health checks configuration.
+func (s serverConfigV5) GetDiskHealth() diskHealthConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.DiskHealth
+}
+
+// SetDiskHealth set new disk health checks configuration.
+func (s *serverConfigV5) SetDiskHealth(config diskHealthConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.DiskHealth = config
+}
+
+/// Tiers related.
+
+// GetTier get remote tier by its name.
+func (s serverConfigV5) GetTier(name string) (remoteTier, bool) {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	tier, ok := s.Tiers[name]
+	return tier, ok
+}
+
+// SetTier set new remote tier for name.
+func (s *serverConfigV5) SetTier(name string, tier remoteTier) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.Tiers == nil {
+		s.Tiers = make(map[string]remoteTier)
+	}
+	s.Tiers[name] = tier
+}
+
+/// Storage classes related.
+
+// GetStorageClass get configured storage class by its name.
+func (s serverConfigV5) GetStorageClass(name string) (storageClass, bool) {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	class, ok := s.StorageClasses[name]
+	return class, ok
+}
+
+// SetStorageClass set new storage class for name.
+func (s *serverConfigV5) SetStorageClass(name string, class storageClass) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	if s.StorageClasses == nil {
+		s.StorageClasses = make(map[string]storageClass)
+	}
+	s.StorageClasses[name] = class
+}
+
+/// RPC related.
+
+// GetRPCSecrets get current storage RPC secrets, secrets derived from
+// credentials, the previous one while it is accepted, if none are
+// configured.
+func (s serverConfigV5) GetRPCSecrets() []rpcSecret {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	if len(s.RPC.Secrets) == 0 {
+		secrets := []rpcSecret{credentialRPCSecret(s.Credential)}
+		if prev := s.PreviousCredential; prev != nil && prev.Expiration.After(time.Now().UTC()) {
+			secrets = append(secrets, credentialRPCSecret(prev.credential()))
+		}
+		return secrets
+	}
+	return s.RPC.Secrets
+}
+
+// SetRPC set new storage RPC authentication configuration.
+func (s *serverConfigV5) SetRPC(auth rpcAuth) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.RPC = auth
+}
+
+/// Audit related.
+
+// GetAudit get current audit log configuration.
+func (s serverConfigV5) GetAudit() auditConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Audit
+}
+
+// SetAudit set new audit log configuration.
+func (s *serverConfigV5) SetAudit(audit auditConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Audit = audit
+}
+
+/// Multipart related.
+
+// GetMultipart get current multipart upload limits.
+func (s serverConfigV5) GetMultipart() multipartConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Multipart
+}
+
+// SetMultipart set new multipart upload limits.
+func (s *serverConfigV5) SetMultipart(multipart multipartConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Multipart = multipart
+}
+
+/// Attestation related.
+
+// GetAttestation get current object attestation configuration.
+func (s serverConfigV5) GetAttestation() attestationConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Attestation
+}
+
+// SetAttestation set new object attestation configuration.
+func (s *serverConfigV5) SetAttestation(attestation attestationConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Attestation = attestation
+}
+
+/// Heal related.
+
+// GetHeal get current heal configuration.
+func (s serverConfigV5) GetHeal() healConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Heal
+}
+
+// SetHeal set new heal configuration.
+func (s *serverConfigV5) SetHeal(heal healConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Heal = heal
+}
+
+/// Disk shares related.
+
+// GetDiskShares get current per bucket disk shares configuration.
+func (s serverConfigV5) GetDiskShares() diskSharesConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.DiskShares
+}
+
+// SetDiskShares set new per bucket disk shares configuration.
+func (s *serverConfigV5) SetDiskShares(diskShares diskSharesConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.DiskShares = diskShares
+}
+
+/// STS related.
+
+// GetSTS get current temporary credentials configuration.
+func (s serverConfigV5) GetSTS() stsConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.STS
+}
+
+// SetSTS set new temporary credentials configuration.
+func (s *serverConfigV5) SetSTS(sts stsConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.STS = sts
+}
+
+/// LDAP related.
+
+// GetLDAP get current LDAP identity configuration.
+func (s serverConfigV5) GetLDAP() ldapConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.LDAP
+}
+
+// SetLDAP set new LDAP identity configuration.
+func (s *serverConfigV5) SetLDAP(ldap ldapConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.LDAP = ldap
+}
+
+/// Quarantine related.
+
+// GetQuarantine get current upload quarantine configuration.
+func (s serverConfigV5) GetQuarantine() quarantineConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Quarantine
+}
+
+// SetQuarantine set new upload quarantine configuration.
+func (s *serverConfigV5) SetQuarantine(quarantine quarantineConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Quarantine = quarantine
+}
+
+/// Trash related.
+
+// GetTrash get current deleted objects trash configuration.
+func (s serverConfigV5) GetTrash() trashConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Trash
+}
+
+// SetTrash set new deleted objects trash configuration.
+func (s *serverConfigV5) SetTrash(trash trashConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Trash = trash
+}
+
+/// Cache related.
+
+// GetCache get current local disk cache configuration.
+func (s serverConfigV5) GetCache() cacheConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Cache
+}
+
+// SetCache set new local disk cache configuration.
+func (s *serverConfigV5) SetCache(cache cacheConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Cache = cache
+}
+
+/// Block cache related.
+
+// GetBlockCache get current in memory block cache configuration.
+func (s serverConfigV5) GetBlockCache() blockCacheConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.BlockCache
+}
+
+// SetBlockCache set new in memory block cache configuration.
+func (s *serverConfigV5) SetBlockCache(blockCache blockCacheConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.BlockCache = blockCache
+}
+
+/// Disk I/O related.
+
+// GetDiskIO get current local disk I/O configuration.
+func (s serverConfigV5) GetDiskIO() diskIOConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.DiskIO
+}
+
+// SetDiskIO set new local disk I/O configuration.
+func (s *serverConfigV5) SetDiskIO(diskIO diskIOConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.DiskIO = diskIO
+}
+
+/// Data usage related.
+
+// GetDataUsage get current data usage crawler configuration.
+func (s serverConfigV5) GetDataUsage() dataUsageConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.DataUsage
+}
+
+// SetDataUsage set new data usage crawler configuration.
+func (s *serverConfigV5) SetDataUsage(dataUsage dataUsageConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.DataUsage = dataUsage
+}
+
+/// Namespace locks related.
+
+// GetLocks get current namespace lock timeouts configuration.
+func (s serverConfigV5) GetLocks() nameSpaceLockConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Locks
+}
+
+// SetLocks set new namespace lock timeouts configuration.
+func (s *serverConfigV5) SetLocks(locks nameSpaceLockConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Locks = locks
+}
+
+/// Object limits related.
+
+// GetLimits get current object and part size limits configuration.
+func (s serverConfigV5) GetLimits() objectLimitsConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Limits
+}
+
+// SetLimits set new object and part size limits configuration.
+func (s *serverConfigV5) SetLimits(limits objectLimitsConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Limits = limits
+}
+
+// SetRegion set new region.
+func (s *serverConfigV5) SetRegion(region string) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Region = region
+}
+
+// GetRegion get current region.
+func (s serverConfigV5) GetRegion() string {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Region
+}
+
+// SetDomain set new domain of virtual host style requests.
+func (s *serverConfigV5) SetDomain(domain string) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Domain = domain
+}
+
+// GetDomain get current domain of virtual host style requests.
+func (s serverConfigV5) GetDomain() string {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Domain
+}
+
+// SetCredentials set new credentials.
+func (s *serverConfigV5) SetCredential(creds credential) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.Credential = creds
+}
+
+// GetCredentials get current credentials.
+func (s serverConfigV5) GetCredential() credential {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.Credential
+}
+
+// GetPreviousCredential get credential replaced by the last rotation,
+// false if there is none or it expired.
+func (s serverConfigV5) GetPreviousCredential() (previousCredential, bool) {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	prev := s.PreviousCredential
+	if prev == nil || !prev.Expiration.After(time.Now().UTC()) {
+		return previousCredential{}, false
+	}
+	return *prev, true
+}
+
+// SetPreviousCredential set credential replaced by the last rotation,
+// nil to stop accepting it.
+func (s *serverConfigV5) SetPreviousCredential(prev *previousCredential) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.PreviousCredential = prev
+}
+
+// Save config.
+func (s serverConfigV5) Save() *probe.Error {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+
+	// get config file.
+	configFile, err := getConfigFile()
+	if err != nil {
+		return err.Trace()
+	}
+
+	// Environment variables are never saved.
+	s.withoutEnvOverrides()
+
+	// initialize quick.
+	qc, err := quick.New(&s)
+	if err != nil {
+		return err.Trace()
+	}
+
+	// Save config file.
+	if err := qc.Save(configFile); err != nil {
+		return err.Trace()
+	}
+
+	// Save config shared by all servers.
+	if globalConfigStore != nil {
+		if e := saveConfigDocument(*globalConfigStore, &s); e != nil {
+			return probe.NewError(e)
+		}
+	}
+
+	// Return success.
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Previous server credential is accepted this long after a
+	// rotation unless asked otherwise.
+	defaultCredentialGracePeriod = 24 * time.Hour
+
+	// Longest grace period of a previous server credential.
+	maxCredentialGracePeriod = 30 * 24 * time.Hour
+)
+
+// errInvalidCredentialRotation - rotation to the requested credential
+// is not possible.
+var errInvalidCredentialRotation = errors.New("Invalid credential rotation")
+
+// previousCredential - server credential replaced by a rotation, still
+// accepted until its expiration so that clients can move to the new
+// one without a flag day.
+type previousCredential struct {
+	AccessKeyID     string    `json:"accessKey"`
+	SecretAccessKey string    `json:"secretKey"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+func (p previousCredential) credential() credential {
+	return credential{AccessKeyID: p.AccessKeyID, SecretAccessKey: p.SecretAccessKey}
+}
+
+// isRootAccessKey - returns true if accessKey is of the server
+// credential, or of the previous one while it is accepted.
+func isRootAccessKey(accessKey string) bool {
+	if accessKey == serverConfig.GetCredential().AccessKeyID {
+		return true
+	}
+	prev, ok := serverConfig.GetPreviousCredential()
+	return ok && accessKey == prev.AccessKeyID
+}
+
+// getRootSecretKey - returns secret key of the server credential, or
+// of the previous one while it is accepted, with accessKey.
+func getRootSecretKey(accessKey string) (string, bool) {
+	if cred := serverConfig.GetCredential(); accessKey == cred.AccessKeyID {
+		return cred.SecretAccessKey, true
+	}
+	if prev, ok := serverConfig.GetPreviousCredential(); ok && accessKey == prev.AccessKeyID {
+		return prev.SecretAccessKey, true
+	}
+	return "", false
+}
+
+// isKnownAccessKey - returns true if accessKey is already of a
+// credential.
+func isKnownAccessKey(accessKey string) bool {
+	_, ok := globalIAMUsers.GetSecretKey(accessKey)
+	return ok
+}
+
+// getPreviousCredential - returns previous server credential while it
+// is accepted, nil otherwise.
+func getPreviousCredential() *previousCredential {
+	if prev, ok := serverConfig.GetPreviousCredential(); ok {
+		return &prev
+	}
+	return nil
+}
+
+// rotateCredential - makes cred the server credential, accepting the
+// current one for gracePeriod, and saves the config. A previous
+// credential still accepted from an earlier rotation is no longer
+// accepted. Temporary credentials and service accounts of the current
+// credential are moved to cred.
+func rotateCredential(cred credential, gracePeriod time.Duration) *probe.Error {
+	if gracePeriod < 0 || gracePeriod > maxCredentialGracePeriod {
+		return probe.NewError(errInvalidCredentialRotation)
+	}
+	if !isValidAccessKey.MatchString(cred.AccessKeyID) || !isValidSecretKey.MatchString(cred.SecretAccessKey) {
+		return probe.NewError(errInvalidCredentialRotation)
+	}
+	// Both credentials are accepted during the grace period, their
+	// access keys select the secret key requests are verified with.
+	current := serverConfig.GetCredential()
+	if cred.AccessKeyID == current.AccessKeyID || isKnownAccessKey(cred.AccessKeyID) {
+		return probe.NewError(errInvalidCredentialRotation)
+	}
+	if err := globalIAMUsers.ReparentCredentials(current.AccessKeyID, cred.AccessKeyID); err != nil {
+		return err.Trace(cred.AccessKeyID)
+	}
+
+	savedPrev := getPreviousCredential()
+	var prev *previousCredential
+	if gracePeriod > 0 {
+		prev = &previousCredential{
+			AccessKeyID:     current.AccessKeyID,
+			SecretAccessKey: current.SecretAccessKey,
+			Expiration:      time.Now().UTC().Add(gracePeriod),
+		}
+	}
+	serverConfig.SetPreviousCredential(prev)
+	serverConfig.SetCredential(cred)
+	if err := serverConfig.Save(); err != nil {
+		serverConfig.SetCredential(current)
+		serverConfig.SetPreviousCredential(savedPrev)
+		errorIf(globalIAMUsers.ReparentCredentials(cred.AccessKeyID, current.AccessKeyID), "Unable to restore service accounts.", nil)
+		return err.Trace(cred.AccessKeyID)
+	}
+	return nil
+}
+
+// removePreviousCredential - stops accepting the previous server
+// credential before its expiration and saves the config.
+func removePreviousCredential() *probe.Error {
+	savedPrev := getPreviousCredential()
+	serverConfig.SetPreviousCredential(nil)
+	if err := serverConfig.Save(); err != nil {
+		serverConfig.SetPreviousCredential(savedPrev)
+		return err.Trace()
+	}
+	return nil
+}
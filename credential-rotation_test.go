@@ -0,0 +1,132 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests the previous server credential is accepted for its grace
+// period after a rotation, and credentials issued to it move along.
+func TestRotateCredential(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-rotate-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(directory)
+	defer setGlobalConfigPath(savedConfigPath)
+	savedConfig := serverConfig
+	savedIAMUsers := globalIAMUsers
+	defer func() {
+		serverConfig = savedConfig
+		globalIAMUsers = savedIAMUsers
+	}()
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	globalIAMUsers = newIAMUsers()
+	if err := globalIAMUsers.Load(newObjectLayer(fs)); err != nil {
+		t.Fatal(err)
+	}
+	if err := globalIAMUsers.SetUser(iamUser{AccessKey: "ci", SecretKey: "ci-secret", Policy: iamPolicyReadOnly}); err != nil {
+		t.Fatal(err)
+	}
+	old := serverConfig.GetCredential()
+	account, err := globalIAMUsers.AddServiceAccount(old.AccessKeyID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalidTestCases := []struct {
+		cred        credential
+		gracePeriod time.Duration
+	}{
+		{old, time.Hour},
+		{credential{AccessKeyID: "ci", SecretAccessKey: "new-secret"}, time.Hour},
+		{credential{AccessKeyID: account.AccessKey, SecretAccessKey: "new-secret"}, time.Hour},
+		{credential{AccessKeyID: "NEWACCESSKEY", SecretAccessKey: "short"}, time.Hour},
+		{credential{AccessKeyID: "NEWACCESSKEY", SecretAccessKey: "new-secret"}, -time.Hour},
+		{credential{AccessKeyID: "NEWACCESSKEY", SecretAccessKey: "new-secret"}, maxCredentialGracePeriod + time.Hour},
+	}
+	for i, testCase := range invalidTestCases {
+		if err = rotateCredential(testCase.cred, testCase.gracePeriod); err == nil || err.ToGoError() != errInvalidCredentialRotation {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, errInvalidCredentialRotation, err)
+		}
+	}
+
+	cred := credential{AccessKeyID: "NEWACCESSKEY", SecretAccessKey: "new-secret"}
+	if err = rotateCredential(cred, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []credential{old, cred} {
+		if !isRootAccessKey(c.AccessKeyID) {
+			t.Errorf("Expected %s to be the server credential", c.AccessKeyID)
+		}
+		if secretKey, ok := globalIAMUsers.GetSecretKey(c.AccessKeyID); !ok || secretKey != c.SecretAccessKey {
+			t.Errorf("Expected secret key of %s accepted", c.AccessKeyID)
+		}
+	}
+	if accounts := globalIAMUsers.ListServiceAccounts(cred.AccessKeyID); len(accounts) != 1 || accounts[0].AccessKey != account.AccessKey {
+		t.Errorf("Expected service account moved to the new credential, got %v", accounts)
+	}
+	if isRootAccessKey("ci") {
+		t.Error("Expected users not to be the server credential")
+	}
+
+	// Storage RPC signed with either credential is accepted.
+	auth := newRPCAuthenticator()
+	for _, c := range []credential{old, cred} {
+		args := signRPC(credentialRPCSecret(c), "Storage.StatVolHandler", "bucket")
+		if e = auth.Verify(serverConfig.GetRPCSecrets(), args, "Storage.StatVolHandler", "bucket"); e != nil {
+			t.Errorf("Expected RPC signed with %s accepted, got %v", c.AccessKeyID, e)
+		}
+	}
+
+	// Rotation is saved.
+	if err = initConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if serverConfig.GetCredential() != cred || !isRootAccessKey(old.AccessKeyID) {
+		t.Fatal("Expected rotation to be saved")
+	}
+
+	if err = removePreviousCredential(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := globalIAMUsers.GetSecretKey(old.AccessKeyID); ok {
+		t.Error("Expected previous credential no longer accepted")
+	}
+
+	// Previous credential expires with its grace period.
+	serverConfig.SetPreviousCredential(&previousCredential{
+		AccessKeyID:     old.AccessKeyID,
+		SecretAccessKey: old.SecretAccessKey,
+		Expiration:      time.Now().UTC().Add(-time.Second),
+	})
+	if isRootAccessKey(old.AccessKeyID) || len(serverConfig.GetRPCSecrets()) != 1 {
+		t.Error("Expected expired previous credential no longer accepted")
+	}
+}
@@ -0,0 +1,325 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Default interval between two crawls of all buckets.
+	defaultDataUsageCrawlInterval = time.Hour
+
+	// Number of objects listed at a time while crawling.
+	dataUsageListBatchSize = 1000
+
+	// Usage of the last crawl, saved in minioMetaVolume so that it is
+	// served right after a restart.
+	dataUsageMetaPath    = ".usage/data-usage.json"
+	maxDataUsageMetaSize = 16 * 1024 * 1024
+)
+
+// Headers of HEAD bucket responses reporting usage of the bucket found
+// by the last crawl.
+const (
+	bucketObjectsHeader      = "X-Minio-Bucket-Objects"
+	bucketSizeHeader         = "X-Minio-Bucket-Size"
+	bucketActualSizeHeader   = "X-Minio-Bucket-Actual-Size"
+	bucketUsageUpdatedHeader = "X-Minio-Bucket-Usage-Updated"
+)
+
+// dataUsageConfig - data usage crawler configuration.
+type dataUsageConfig struct {
+	// Interval between two crawls in seconds.
+	CrawlInterval int64 `json:"crawlInterval"`
+}
+
+// interval - returns crawl interval, defaults if not configured.
+func (c dataUsageConfig) interval() time.Duration {
+	if c.CrawlInterval <= 0 {
+		return defaultDataUsageCrawlInterval
+	}
+	return time.Duration(c.CrawlInterval) * time.Second
+}
+
+// bucketUsage - usage of a bucket. Size is the sum of the sizes of its
+// objects, ActualSize the space they take on the backend along with
+// parity. Uploads in progress are not counted.
+type bucketUsage struct {
+	Bucket     string `json:"bucket"`
+	Objects    int64  `json:"objects"`
+	Size       int64  `json:"size"`
+	ActualSize int64  `json:"actualSize"`
+}
+
+// dataUsageInfo - usage of all buckets found by the last crawl.
+type dataUsageInfo struct {
+	Crawling   bool          `json:"crawling"`
+	LastUpdate time.Time     `json:"lastUpdate"`
+	Objects    int64         `json:"objects"`
+	Size       int64         `json:"size"`
+	ActualSize int64         `json:"actualSize"`
+	Buckets    []bucketUsage `json:"buckets"`
+	LastError  string        `json:"lastError,omitempty"`
+}
+
+// dataUsageCrawler - periodically computes usage of all buckets, the
+// last results are cached until the next crawl ends.
+type dataUsageCrawler struct {
+	mutex   *sync.Mutex
+	objAPI  *objectAPI
+	info    dataUsageInfo
+	buckets map[string]bucketUsage
+	// Bytes written to each bucket since the last crawl started.
+	written map[string]int64
+	// Signals a crawl was requested before the next one is due.
+	triggerCh chan struct{}
+}
+
+// Global data usage crawler, results are served by the admin API and
+// HEAD bucket.
+var globalDataUsage = newDataUsageCrawler()
+
+// newDataUsageCrawler - returns a crawler with no usage computed yet.
+func newDataUsageCrawler() *dataUsageCrawler {
+	return &dataUsageCrawler{
+		mutex:     &sync.Mutex{},
+		info:      dataUsageInfo{Buckets: []bucketUsage{}},
+		buckets:   make(map[string]bucketUsage),
+		written:   make(map[string]int64),
+		triggerCh: make(chan struct{}, 1),
+	}
+}
+
+// initDataUsageCrawler - loads usage of the last crawl and starts the
+// background crawler.
+func initDataUsageCrawler(objAPI objectAPI) {
+	err := globalDataUsage.Load(objAPI)
+	errorIf(err.Trace(), "Unable to load data usage of the last crawl.", nil)
+	go globalDataUsage.run()
+}
+
+// Load - loads usage saved by the last crawl of o, crawls are saved to
+// o from now on.
+func (c *dataUsageCrawler) Load(o objectAPI) *probe.Error {
+	c.mutex.Lock()
+	c.objAPI = &o
+	c.mutex.Unlock()
+
+	usageBytes, e := o.readMetaFile(dataUsageMetaPath, maxDataUsageMetaSize)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return nil
+		}
+		return probe.NewError(e)
+	}
+	info := dataUsageInfo{}
+	if e = json.Unmarshal(usageBytes, &info); e != nil {
+		return probe.NewError(e)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.set(info)
+	return nil
+}
+
+// Info - returns usage of all buckets found by the last crawl.
+func (c *dataUsageCrawler) Info() dataUsageInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.info
+}
+
+// Bucket - returns usage of bucket found by the last crawl, ok is false
+// if the bucket was not crawled yet.
+func (c *dataUsageCrawler) Bucket(bucket string) (usage bucketUsage, lastUpdate time.Time, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	usage, ok = c.buckets[bucket]
+	return usage, c.info.LastUpdate, ok
+}
+
+// AddWritten - counts size bytes written to bucket, until the next
+// crawl ends.
+func (c *dataUsageCrawler) AddWritten(bucket string, size int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.written[bucket] += size
+}
+
+// Estimate - returns size of bucket found by the last crawl, along
+// with the bytes written since.
+func (c *dataUsageCrawler) Estimate(bucket string) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.buckets[bucket].Size + c.written[bucket]
+}
+
+// Start - requests a crawl now, unless one is running already.
+func (c *dataUsageCrawler) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.info.Crawling {
+		return
+	}
+	c.info.Crawling = true
+	select {
+	case c.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// run - crawls all buckets once the last crawl is older than the
+// configured interval, or a crawl is requested.
+func (c *dataUsageCrawler) run() {
+	for {
+		wait := serverConfig.GetDataUsage().interval() - time.Since(c.Info().LastUpdate)
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-c.triggerCh:
+				timer.Stop()
+			}
+		}
+		c.crawl()
+	}
+}
+
+// crawl - computes usage of all buckets, and saves it.
+func (c *dataUsageCrawler) crawl() {
+	c.mutex.Lock()
+	c.info.Crawling = true
+	objAPI := c.objAPI
+	// Bytes written meanwhile may not be listed, they are counted
+	// until the next crawl.
+	written := make(map[string]int64)
+	for bucket, size := range c.written {
+		written[bucket] = size
+	}
+	c.mutex.Unlock()
+	if objAPI == nil {
+		return
+	}
+
+	info, err := crawlDataUsage(*objAPI)
+	if err != nil {
+		errorIf(err.Trace(), "Unable to crawl data usage.", nil)
+		c.mutex.Lock()
+		c.info.Crawling = false
+		c.info.LastError = err.ToGoError().Error()
+		c.mutex.Unlock()
+		return
+	}
+	c.mutex.Lock()
+	c.set(info)
+	for bucket, size := range written {
+		if c.written[bucket] -= size; c.written[bucket] <= 0 {
+			delete(c.written, bucket)
+		}
+	}
+	c.mutex.Unlock()
+	errorIf(saveDataUsage(*objAPI, info), "Unable to save data usage.", nil)
+}
+
+// set - replaces cached usage with info, callers hold the lock.
+func (c *dataUsageCrawler) set(info dataUsageInfo) {
+	c.info = info
+	c.buckets = make(map[string]bucketUsage)
+	for _, usage := range info.Buckets {
+		c.buckets[usage.Bucket] = usage
+	}
+}
+
+// saveDataUsage - saves usage of the last crawl to o.
+func saveDataUsage(o objectAPI, info dataUsageInfo) *probe.Error {
+	usageBytes, e := json.Marshal(info)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return probe.NewError(e)
+	}
+	if e = o.writeMetaFile(dataUsageMetaPath, usageBytes); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// crawlDataUsage - lists all objects of all buckets of o, and returns
+// their usage.
+func crawlDataUsage(o objectAPI) (dataUsageInfo, *probe.Error) {
+	bucketsInfo, err := o.ListBuckets()
+	if err != nil {
+		return dataUsageInfo{}, err.Trace()
+	}
+	info := dataUsageInfo{Buckets: []bucketUsage{}}
+	for _, bucketInfo := range bucketsInfo {
+		usage, err := crawlBucketUsage(o, bucketInfo.Name)
+		if err != nil {
+			// Bucket was removed meanwhile.
+			if _, ok := err.ToGoError().(BucketNotFound); ok {
+				continue
+			}
+			return dataUsageInfo{}, err.Trace(bucketInfo.Name)
+		}
+		info.Objects += usage.Objects
+		info.Size += usage.Size
+		info.ActualSize += usage.ActualSize
+		info.Buckets = append(info.Buckets, usage)
+	}
+	sort.Slice(info.Buckets, func(i, j int) bool { return info.Buckets[i].Bucket < info.Buckets[j].Bucket })
+	info.LastUpdate = time.Now().UTC()
+	return info, nil
+}
+
+// crawlBucketUsage - lists all objects of bucket, batch by batch, and
+// returns their usage. Storage which does not report the space taken
+// by files is assumed to store them as is.
+func crawlBucketUsage(o objectAPI, bucket string) (bucketUsage, *probe.Error) {
+	reporter, _ := o.storage.(usageReporter)
+	usage := bucketUsage{Bucket: bucket}
+	marker := ""
+	for {
+		result, err := o.ListObjects(bucket, "", marker, "", dataUsageListBatchSize)
+		if err != nil {
+			return bucketUsage{}, err.Trace(bucket, marker)
+		}
+		for _, object := range result.Objects {
+			usage.Objects++
+			usage.Size += object.Size
+			if reporter == nil {
+				usage.ActualSize += object.Size
+				continue
+			}
+			actualSize, e := reporter.usedSize(bucket, object.Size)
+			if e != nil {
+				return bucketUsage{}, probe.NewError(e).Trace(bucket, object.Name)
+			}
+			usage.ActualSize += actualSize
+		}
+		if !result.IsTruncated || len(result.Objects) == 0 {
+			return usage, nil
+		}
+		marker = result.Objects[len(result.Objects)-1].Name
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests usage of buckets is crawled along with parity, and reloaded.
+func TestDataUsageCrawler(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-usage-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(xl)
+	for _, bucket := range []string{"bucket", "empty"} {
+		if err := objAPI.MakeBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	small := []byte("hello")
+	// Large enough to be erasure coded.
+	large := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	for object, data := range map[string][]byte{"small": small, "dir/large": large} {
+		if _, err := objAPI.PutObject("bucket", object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := newDataUsageCrawler()
+	if err := c.Load(objAPI); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := c.Bucket("bucket"); ok {
+		t.Fatal("Expected no usage before the first crawl")
+	}
+	c.crawl()
+	info := c.Info()
+	if info.Crawling || info.LastError != "" || info.LastUpdate.IsZero() {
+		t.Fatalf("Unexpected crawl state %+v", info)
+	}
+	if len(info.Buckets) != 2 || info.Buckets[0].Bucket != "bucket" || info.Buckets[1].Bucket != "empty" {
+		t.Fatalf("Unexpected buckets %+v", info.Buckets)
+	}
+
+	// Actual size is the size of the parts on all disks, without their
+	// metadata trailer, and of the inlined data in metadata of all disks.
+	var partsSize int64
+	for index, disk := range disks {
+		fi, e := os.Stat(filepath.Join(disk, "bucket", "dir", "large", fmt.Sprintf("part.%d", index)))
+		if e != nil {
+			t.Fatal(e)
+		}
+		trailer := readMetadataBytes(t, xl.(*XL), index, "dir/large")
+		partsSize += fi.Size() - int64(len(encodePartTrailer(trailer)))
+	}
+	actualSize := partsSize + int64(len(disks)*base64.StdEncoding.EncodedLen(len(small)))
+	expected := bucketUsage{
+		Bucket:     "bucket",
+		Objects:    2,
+		Size:       int64(len(small) + len(large)),
+		ActualSize: actualSize,
+	}
+	if info.Buckets[0] != expected {
+		t.Fatalf("Expected usage %+v, got %+v", expected, info.Buckets[0])
+	}
+	if info.Objects != 2 || info.Size != expected.Size || info.ActualSize != actualSize {
+		t.Fatalf("Unexpected total usage %+v", info)
+	}
+
+	// Usage of the last crawl is served after a restart.
+	c = newDataUsageCrawler()
+	if err := c.Load(objAPI); err != nil {
+		t.Fatal(err)
+	}
+	usage, lastUpdate, ok := c.Bucket("bucket")
+	if !ok || usage != expected || !lastUpdate.Equal(info.LastUpdate) {
+		t.Fatalf("Expected usage %+v of %s, got %+v of %s", expected, info.LastUpdate, usage, lastUpdate)
+	}
+
+	// Removed buckets are dropped by the next crawl.
+	if err := objAPI.DeleteBucket("empty"); err != nil {
+		t.Fatal(err)
+	}
+	c.crawl()
+	if _, _, ok = c.Bucket("empty"); ok {
+		t.Fatal("Expected usage of removed bucket to be dropped")
+	}
+}
@@ -0,0 +1,496 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Default interval between two disk health checks.
+	defaultDiskHealthCheckInterval = time.Hour
+
+	// Time given to smartctl to report a disk.
+	smartctlTimeout = 30 * time.Second
+
+	// Mounts of the server process, to find the device of disks.
+	procMountsFile = "/proc/self/mounts"
+
+	// Disks scoring below this are only read when other disks do not
+	// hold enough parts to decode.
+	diskHealthReadMinScore = 60
+
+	// Score lost per critical and per warning indicator.
+	diskHealthCriticalPenalty = 50
+	diskHealthWarningPenalty  = 10
+)
+
+// Disk health status values.
+const (
+	diskHealthOK      = "ok"
+	diskHealthWarning = "warning"
+	diskHealthFailing = "failing"
+	diskHealthOffline = "offline"
+)
+
+// Raw values of these ATA attributes above zero predict failures.
+var smartPredictiveAttributes = map[int]bool{
+	5:   true, // Reallocated sectors.
+	187: true, // Reported uncorrectable errors.
+	188: true, // Command timeouts.
+	197: true, // Pending sectors.
+	198: true, // Offline uncorrectable sectors.
+}
+
+// diskHealthConfig - disk health checks configuration.
+type diskHealthConfig struct {
+	Enable bool `json:"enable"`
+	// Interval between two checks in seconds.
+	CheckInterval int64 `json:"checkInterval"`
+	// Path of smartctl, SMART attributes are collected with it where
+	// it runs and can access the device.
+	Smartctl string `json:"smartctl"`
+}
+
+// newDiskHealthConfig - disk health configuration for fresh and
+// migrated configs.
+func newDiskHealthConfig() diskHealthConfig {
+	return diskHealthConfig{
+		CheckInterval: int64(defaultDiskHealthCheckInterval / time.Second),
+		Smartctl:      "smartctl",
+	}
+}
+
+// interval - returns check interval, defaults if not configured.
+func (c diskHealthConfig) interval() time.Duration {
+	if c.CheckInterval <= 0 {
+		return defaultDiskHealthCheckInterval
+	}
+	return time.Duration(c.CheckInterval) * time.Second
+}
+
+// smartAttribute - ATA SMART attribute, normalized values are failing
+// at or below the threshold.
+type smartAttribute struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Value     int    `json:"value"`
+	Worst     int    `json:"worst"`
+	Threshold int    `json:"threshold"`
+	Raw       int64  `json:"raw"`
+}
+
+// smartInfo - SMART status of a device.
+type smartInfo struct {
+	Passed     bool             `json:"passed"`
+	Attributes []smartAttribute `json:"attributes,omitempty"`
+	// NVMe health log, of NVMe devices only.
+	CriticalWarning int   `json:"criticalWarning,omitempty"`
+	MediaErrors     int64 `json:"mediaErrors,omitempty"`
+	PercentageUsed  int   `json:"percentageUsed,omitempty"`
+}
+
+// diskHealthIndicator - tripped indicator of a disk failure.
+type diskHealthIndicator struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Critical bool   `json:"critical"`
+}
+
+// diskHealth - health of a disk, reported by the admin health API.
+type diskHealth struct {
+	Path   string `json:"path"`
+	Device string `json:"device,omitempty"`
+	Status string `json:"status"`
+	// From 0 for failed disks to 100 for disks without any tripped
+	// indicator.
+	Score      int                   `json:"score"`
+	SMART      *smartInfo            `json:"smart,omitempty"`
+	FSErrors   int64                 `json:"fsErrors"`
+	Indicators []diskHealthIndicator `json:"indicators"`
+	CheckedAt  time.Time             `json:"checkedAt"`
+	// Why SMART or filesystem counters could not be collected.
+	Error string `json:"error,omitempty"`
+}
+
+// scoreIndicators - sets score and status of the tripped indicators.
+func (h *diskHealth) scoreIndicators() {
+	h.Score = 100
+	h.Status = diskHealthOK
+	for _, indicator := range h.Indicators {
+		if indicator.Critical {
+			h.Score -= diskHealthCriticalPenalty
+			h.Status = diskHealthFailing
+		} else {
+			h.Score -= diskHealthWarningPenalty
+			if h.Status == diskHealthOK {
+				h.Status = diskHealthWarning
+			}
+		}
+	}
+	if h.Score < 0 {
+		h.Score = 0
+	}
+}
+
+// diskHealthState - latest health of each disk, by disk path.
+type diskHealthState struct {
+	mutex *sync.RWMutex
+	disks map[string]diskHealth
+}
+
+// Global disk health, updated by the disk health checker.
+var globalDiskHealth = &diskHealthState{
+	mutex: &sync.RWMutex{},
+	disks: make(map[string]diskHealth),
+}
+
+// Alarms of tripped disk health indicators, kept apart from capacity
+// alarms which are replaced on each capacity check.
+var globalDiskAlarms = &alarmState{
+	mutex:  &sync.RWMutex{},
+	raised: make(map[string]alarmInfo),
+}
+
+// Score - returns health score of the disk at diskPath, disks not
+// checked yet are healthy.
+func (s *diskHealthState) Score(diskPath string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if health, ok := s.disks[diskPath]; ok {
+		return health.Score
+	}
+	return 100
+}
+
+// List - returns health of all checked disks sorted by path.
+func (s *diskHealthState) List() []diskHealth {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	disks := []diskHealth{}
+	for _, health := range s.disks {
+		disks = append(disks, health)
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Path < disks[j].Path })
+	return disks
+}
+
+// set - replaces health of checked disks.
+func (s *diskHealthState) set(disks []diskHealth) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.disks = make(map[string]diskHealth)
+	for _, health := range disks {
+		s.disks[health.Path] = health
+	}
+}
+
+// initDiskHealth - starts the background disk health checker of the
+// local disks of storage.
+func initDiskHealth(storage StorageAPI) {
+	reporter, ok := storage.(diskStatusReporter)
+	if !ok {
+		return
+	}
+	go func() {
+		for {
+			config := serverConfig.GetDiskHealth()
+			if config.Enable {
+				checkDiskHealth(config, reporter.DiskStatus())
+			}
+			time.Sleep(config.interval())
+		}
+	}()
+}
+
+// checkDiskHealth - checks health of all disks once, notifying on
+// indicators which tripped or cleared since the previous check.
+func checkDiskHealth(config diskHealthConfig, disksStatus []DiskStatus) {
+	var disks []diskHealth
+	var current []alarmInfo
+	for _, status := range disksStatus {
+		health := collectDiskHealth(config, status)
+		for _, indicator := range health.Indicators {
+			current = append(current, alarmInfo{
+				Type:      alarmDiskHealth,
+				Disk:      health.Path,
+				Indicator: indicator.Name,
+				Value:     indicator.Value,
+				Since:     health.CheckedAt,
+			})
+		}
+		disks = append(disks, health)
+	}
+	globalDiskHealth.set(disks)
+	raised, cleared := globalDiskAlarms.update(current)
+	for _, alarm := range raised {
+		notifyAlarm(AlarmRaised, alarm)
+	}
+	for _, alarm := range cleared {
+		notifyAlarm(AlarmCleared, alarm)
+	}
+}
+
+// collectDiskHealth - returns health of the disk of status, from its
+// SMART attributes and filesystem error counters where accessible.
+func collectDiskHealth(config diskHealthConfig, status DiskStatus) diskHealth {
+	health := diskHealth{
+		Path:       status.Path,
+		Indicators: []diskHealthIndicator{},
+		CheckedAt:  time.Now().UTC(),
+	}
+	if !status.Online {
+		health.Status = diskHealthOffline
+		health.Error = status.Error
+		return health
+	}
+	device, fsType, err := findDiskDevice(status.Path)
+	if err != nil {
+		health.Error = err.Error()
+		health.scoreIndicators()
+		return health
+	}
+	health.Device = device
+	var errs []string
+	if config.Smartctl != "" {
+		smart, err := readSMART(config.Smartctl, wholeDiskDevice(device))
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			health.SMART = smart
+			health.Indicators = append(health.Indicators, smart.indicators()...)
+		}
+	}
+	if fsType == "ext4" {
+		count, err := readFSErrors(device)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if health.FSErrors = count; count > 0 {
+			health.Indicators = append(health.Indicators, diskHealthIndicator{
+				Name:  "fs.errors",
+				Value: strconv.FormatInt(count, 10),
+			})
+		}
+	}
+	health.Error = strings.Join(errs, "; ")
+	health.scoreIndicators()
+	return health
+}
+
+// findDiskDevice - returns device and filesystem type of the mount
+// holding diskPath.
+func findDiskDevice(diskPath string) (device, fsType string, err error) {
+	path, err := filepath.EvalSymlinks(diskPath)
+	if err != nil {
+		return "", "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", "", err
+	}
+	mounts, err := os.Open(procMountsFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer mounts.Close()
+	return parseMounts(mounts, path)
+}
+
+// parseMounts - returns device and filesystem type of the longest
+// mount point holding path, of mounts in /proc/mounts format.
+func parseMounts(mounts io.Reader, path string) (device, fsType string, err error) {
+	longest := -1
+	scanner := bufio.NewScanner(mounts)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		// Spaces in mount points are octal escaped.
+		mountPoint := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\134`, `\`).Replace(fields[1])
+		if path != mountPoint && !strings.HasPrefix(path, strings.TrimSuffix(mountPoint, "/")+"/") {
+			continue
+		}
+		if len(mountPoint) > longest {
+			longest = len(mountPoint)
+			device, fsType = fields[0], fields[2]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", "", err
+	}
+	if longest < 0 {
+		return "", "", errors.New("No device mounted at " + path)
+	}
+	return device, fsType, nil
+}
+
+// wholeDiskDevice - returns the disk of a partition device, the device
+// itself if it is not a partition.
+func wholeDiskDevice(device string) string {
+	name := filepath.Base(device)
+	if _, err := os.Stat(filepath.Join("/sys/class/block", name, "partition")); err != nil {
+		return device
+	}
+	sysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", name))
+	if err != nil {
+		return device
+	}
+	return filepath.Join(filepath.Dir(device), filepath.Base(filepath.Dir(sysPath)))
+}
+
+// readFSErrors - returns number of errors ext4 recorded on device.
+func readFSErrors(device string) (int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/sys/fs/ext4", filepath.Base(device), "errors_count"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readSMART - returns SMART status of device reported by smartctl.
+func readSMART(smartctl, device string) (*smartInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), smartctlTimeout)
+	defer cancel()
+	// Exit status bits are set for failing disks, the JSON output
+	// is what tells whether the device could be read.
+	output, err := exec.CommandContext(ctx, smartctl, "-j", "-H", "-A", device).Output()
+	if len(output) == 0 && err != nil {
+		return nil, err
+	}
+	return parseSmartctl(output)
+}
+
+// smartctlOutput - parts of smartctl JSON output health is derived
+// from.
+type smartctlOutput struct {
+	Smartctl struct {
+		Messages []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	ATAAttributes struct {
+		Table []struct {
+			ID     int    `json:"id"`
+			Name   string `json:"name"`
+			Value  int    `json:"value"`
+			Worst  int    `json:"worst"`
+			Thresh int    `json:"thresh"`
+			Raw    struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeLog *struct {
+		CriticalWarning int   `json:"critical_warning"`
+		MediaErrors     int64 `json:"media_errors"`
+		PercentageUsed  int   `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// parseSmartctl - returns SMART status of smartctl JSON output.
+func parseSmartctl(output []byte) (*smartInfo, error) {
+	parsed := smartctlOutput{}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, err
+	}
+	if parsed.SmartStatus == nil {
+		for _, message := range parsed.Smartctl.Messages {
+			if message.Severity == "error" {
+				return nil, errors.New(message.String)
+			}
+		}
+		return nil, errors.New("SMART status not available")
+	}
+	smart := &smartInfo{Passed: parsed.SmartStatus.Passed}
+	for _, attr := range parsed.ATAAttributes.Table {
+		smart.Attributes = append(smart.Attributes, smartAttribute{
+			ID:        attr.ID,
+			Name:      attr.Name,
+			Value:     attr.Value,
+			Worst:     attr.Worst,
+			Threshold: attr.Thresh,
+			Raw:       attr.Raw.Value,
+		})
+	}
+	if parsed.NVMeLog != nil {
+		smart.CriticalWarning = parsed.NVMeLog.CriticalWarning
+		smart.MediaErrors = parsed.NVMeLog.MediaErrors
+		smart.PercentageUsed = parsed.NVMeLog.PercentageUsed
+	}
+	return smart, nil
+}
+
+// indicators - returns tripped failure indicators. Failed self
+// assessments and attributes at their threshold are critical, growing
+// error counts are warnings.
+func (s smartInfo) indicators() []diskHealthIndicator {
+	var indicators []diskHealthIndicator
+	if !s.Passed {
+		indicators = append(indicators, diskHealthIndicator{Name: "smart.status", Value: "failed", Critical: true})
+	}
+	for _, attr := range s.Attributes {
+		name := "smart." + attr.Name
+		switch {
+		case attr.Threshold > 0 && attr.Value <= attr.Threshold:
+			indicators = append(indicators, diskHealthIndicator{Name: name, Value: strconv.Itoa(attr.Value), Critical: true})
+		case smartPredictiveAttributes[attr.ID] && attr.Raw > 0:
+			indicators = append(indicators, diskHealthIndicator{Name: name, Value: strconv.FormatInt(attr.Raw, 10)})
+		}
+	}
+	if s.CriticalWarning != 0 {
+		indicators = append(indicators, diskHealthIndicator{Name: "smart.criticalWarning", Value: strconv.Itoa(s.CriticalWarning), Critical: true})
+	}
+	if s.MediaErrors > 0 {
+		indicators = append(indicators, diskHealthIndicator{Name: "smart.mediaErrors", Value: strconv.FormatInt(s.MediaErrors, 10)})
+	}
+	if s.PercentageUsed >= 100 {
+		indicators = append(indicators, diskHealthIndicator{Name: "smart.percentageUsed", Value: strconv.Itoa(s.PercentageUsed)})
+	}
+	return indicators
+}
+
+// readOrder - returns indices of disks ordered by health score,
+// healthiest first.
+func (xl XL) readOrder() []int {
+	order := make([]int, len(xl.storageDisks))
+	scores := make([]int, len(xl.storageDisks))
+	for index := range order {
+		order[index] = index
+		if index < len(xl.diskPaths) {
+			scores[index] = globalDiskHealth.Score(xl.diskPaths[index])
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order
+}
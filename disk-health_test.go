@@ -0,0 +1,210 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Tests devices of disks are found from the longest mount point.
+func TestParseMounts(t *testing.T) {
+	mounts := `sysfs /sys sysfs rw 0 0
+/dev/sda1 / ext4 rw,relatime 0 0
+/dev/sdb1 /mnt/export ext4 rw 0 0
+/dev/sdc /mnt/export\040two xfs rw 0 0
+tmpfs /mnt/export/tmp tmpfs rw 0 0
+`
+	testCases := []struct {
+		path   string
+		device string
+		fsType string
+	}{
+		{"/home/minio", "/dev/sda1", "ext4"},
+		{"/mnt/export", "/dev/sdb1", "ext4"},
+		{"/mnt/export/tmp/disk", "/dev/sdb1", "ext4"},
+		{"/mnt/exports", "/dev/sda1", "ext4"},
+		{"/mnt/export two/disk", "/dev/sdc", "xfs"},
+	}
+	for i, testCase := range testCases {
+		device, fsType, e := parseMounts(strings.NewReader(mounts), testCase.path)
+		if e != nil || device != testCase.device || fsType != testCase.fsType {
+			t.Errorf("Test %d: Expected %s %s, got %s %s %v", i+1, testCase.device, testCase.fsType, device, fsType, e)
+		}
+	}
+	if _, _, e := parseMounts(strings.NewReader("proc /proc proc rw 0 0\n"), "/proc/1"); e == nil {
+		t.Error("Expected no device for virtual filesystems")
+	}
+}
+
+// Tests SMART output is turned into indicators and health scores.
+func TestSmartIndicators(t *testing.T) {
+	testCases := []struct {
+		output     string
+		indicators []string
+		score      int
+		status     string
+	}{
+		// Healthy ATA disk.
+		{`{"smart_status":{"passed":true},"ata_smart_attributes":{"table":[
+			{"id":5,"name":"Reallocated_Sector_Ct","value":100,"worst":100,"thresh":10,"raw":{"value":0}},
+			{"id":9,"name":"Power_On_Hours","value":90,"worst":90,"thresh":0,"raw":{"value":8000}}]}}`,
+			nil, 100, diskHealthOK},
+		// Reallocated and pending sectors predict failures.
+		{`{"smart_status":{"passed":true},"ata_smart_attributes":{"table":[
+			{"id":5,"name":"Reallocated_Sector_Ct","value":99,"worst":99,"thresh":10,"raw":{"value":8}},
+			{"id":197,"name":"Current_Pending_Sector","value":100,"worst":100,"thresh":0,"raw":{"value":2}}]}}`,
+			[]string{"smart.Reallocated_Sector_Ct", "smart.Current_Pending_Sector"}, 80, diskHealthWarning},
+		// Failed self assessment with an attribute at its threshold.
+		{`{"smart_status":{"passed":false},"ata_smart_attributes":{"table":[
+			{"id":5,"name":"Reallocated_Sector_Ct","value":5,"worst":5,"thresh":10,"raw":{"value":2000}}]}}`,
+			[]string{"smart.status", "smart.Reallocated_Sector_Ct"}, 0, diskHealthFailing},
+		// NVMe critical warning and media errors.
+		{`{"smart_status":{"passed":true},"nvme_smart_health_information_log":{"critical_warning":4,"media_errors":3,"percentage_used":20}}`,
+			[]string{"smart.criticalWarning", "smart.mediaErrors"}, 40, diskHealthFailing},
+	}
+	for i, testCase := range testCases {
+		smart, e := parseSmartctl([]byte(testCase.output))
+		if e != nil {
+			t.Fatalf("Test %d: %s", i+1, e)
+		}
+		health := diskHealth{Indicators: smart.indicators()}
+		health.scoreIndicators()
+		var names []string
+		for _, indicator := range health.Indicators {
+			names = append(names, indicator.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(testCase.indicators, ",") {
+			t.Errorf("Test %d: Expected indicators %v, got %v", i+1, testCase.indicators, names)
+		}
+		if health.Score != testCase.score || health.Status != testCase.status {
+			t.Errorf("Test %d: Expected %d %s, got %d %s", i+1, testCase.score, testCase.status, health.Score, health.Status)
+		}
+	}
+	if _, e := parseSmartctl([]byte(`{"smartctl":{"messages":[{"string":"Permission denied","severity":"error"}]}}`)); e == nil || e.Error() != "Permission denied" {
+		t.Errorf("Expected smartctl error, got %v", e)
+	}
+}
+
+// Tests offline disks raise alarms which clear once they are back.
+func TestCheckDiskHealth(t *testing.T) {
+	savedHealth, savedAlarms := globalDiskHealth, globalDiskAlarms
+	defer func() { globalDiskHealth, globalDiskAlarms = savedHealth, savedAlarms }()
+	globalDiskHealth = &diskHealthState{mutex: &sync.RWMutex{}, disks: make(map[string]diskHealth)}
+	globalDiskAlarms = &alarmState{mutex: &sync.RWMutex{}, raised: make(map[string]alarmInfo)}
+
+	dir, e := ioutil.TempDir("", "minio-health-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	config := diskHealthConfig{Enable: true}
+
+	checkDiskHealth(config, []DiskStatus{{Path: dir, Online: true}, {Path: "/missing", Error: "not found"}})
+	disks := globalDiskHealth.List()
+	if len(disks) != 2 || disks[0].Path != "/missing" || disks[0].Status != diskHealthOffline || disks[0].Score != 0 {
+		t.Fatalf("Unexpected disk health %+v", disks)
+	}
+	if disks[1].Status != diskHealthOK || globalDiskHealth.Score(dir) != 100 {
+		t.Fatalf("Unexpected disk health %+v", disks[1])
+	}
+	if globalDiskHealth.Score("/unchecked") != 100 {
+		t.Fatal("Expected unchecked disks to be healthy")
+	}
+
+	// Offline disks carry no indicators, only tripped indicators raise
+	// alarms.
+	if alarms := globalDiskAlarms.List(); len(alarms) != 0 {
+		t.Fatalf("Unexpected alarms %+v", alarms)
+	}
+	globalDiskAlarms.update([]alarmInfo{{Type: alarmDiskHealth, Disk: dir, Indicator: "fs.errors", Value: "1"}})
+	checkDiskHealth(config, []DiskStatus{{Path: dir, Online: true}})
+	if alarms := globalDiskAlarms.List(); len(alarms) != 0 {
+		t.Fatalf("Expected cleared alarms, got %+v", alarms)
+	}
+}
+
+// Tests parts on disks failing health checks are not read when other
+// disks hold enough parts to decode.
+func TestXLReadOrder(t *testing.T) {
+	savedHealth := globalDiskHealth
+	defer func() { globalDiskHealth = savedHealth }()
+	globalDiskHealth = &diskHealthState{mutex: &sync.RWMutex{}, disks: make(map[string]diskHealth)}
+
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	// Large enough to be erasure coded.
+	data := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	// Silently corrupt the part on the first disk.
+	partPath := filepath.Join(disks[0], "bucket", "object", "part.0")
+	part, e := ioutil.ReadFile(partPath)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(partPath, bytes.Repeat([]byte("z"), len(part)), 0644); e != nil {
+		t.Fatal(e)
+	}
+	globalDiskHealth.set([]diskHealth{{Path: disks[0], Score: 0, Status: diskHealthFailing}})
+	if order := xl.readOrder(); order[len(order)-1] != 0 {
+		t.Fatalf("Expected failing disk read last, got %v", order)
+	}
+
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	readData, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(readData, data) {
+		t.Fatal("Expected data decoded without the failing disk")
+	}
+}
@@ -0,0 +1,383 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default number of operations a disk serves at once, further
+// operations are queued per bucket.
+const defaultDiskSlots = 4
+
+// Operations not attributable to a bucket are queued as this tenant.
+const diskTenantSystem = ".system"
+
+// diskSharesConfig - share of disk operations each bucket gets while
+// disks are busy, relative to the weight of other busy buckets.
+// Buckets without a weight have a weight of 1, idle shares are used by
+// busy buckets.
+type diskSharesConfig struct {
+	// Number of operations a disk serves at once.
+	Slots   int            `json:"slots"`
+	Weights map[string]int `json:"weights"`
+}
+
+// newDiskSharesConfig - disk shares configuration for fresh and
+// migrated configs.
+func newDiskSharesConfig() diskSharesConfig {
+	return diskSharesConfig{
+		Slots:   defaultDiskSlots,
+		Weights: make(map[string]int),
+	}
+}
+
+// slots - returns number of operations served at once, defaults if
+// not configured.
+func (c diskSharesConfig) slots() int {
+	if c.Slots <= 0 {
+		return defaultDiskSlots
+	}
+	return c.Slots
+}
+
+// weight - returns weight of tenant.
+func (c diskSharesConfig) weight(tenant string) int {
+	if weight, ok := c.Weights[tenant]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// diskShares - current disk shares configuration, shared by the
+// schedulers of all disks.
+type diskShares struct {
+	rwMutex *sync.RWMutex
+	config  diskSharesConfig
+}
+
+// Global disk shares, configured at server start.
+var globalDiskShares = &diskShares{rwMutex: &sync.RWMutex{}, config: newDiskSharesConfig()}
+
+// Get - returns current configuration.
+func (s *diskShares) Get() diskSharesConfig {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+	return s.config
+}
+
+// Set - sets configuration, applies to operations queued afterwards.
+func (s *diskShares) Set(config diskSharesConfig) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+	s.config = config
+}
+
+// diskTenant - returns bucket an operation on path of volume is done
+// for. Multipart uploads are kept in minioMetaVolume under their bucket,
+// other metadata under a '.' prefix followed by the bucket.
+func diskTenant(volume, path string) string {
+	if volume != minioMetaVolume {
+		return volume
+	}
+	elements := strings.SplitN(path, slashSeparator, 3)
+	if strings.HasPrefix(elements[0], ".") {
+		if len(elements) < 3 {
+			return diskTenantSystem
+		}
+		return elements[1]
+	}
+	if len(elements) < 2 {
+		return diskTenantSystem
+	}
+	return elements[0]
+}
+
+// diskTenantQueue - queued operations of a single tenant.
+type diskTenantQueue struct {
+	// Virtual time of the tenant, advanced by 1/weight per operation
+	// served. The busy tenant with the lowest pass is served next.
+	pass    float64
+	waiters []chan struct{}
+	served  int64
+}
+
+// diskScheduler - admits operations on a single disk, serving busy
+// tenants in proportion to their weights (stride scheduling).
+type diskScheduler struct {
+	mutex   *sync.Mutex
+	shares  *diskShares
+	active  int
+	tenants map[string]*diskTenantQueue
+	// Lowest pass of all tenants served so far, tenants becoming busy
+	// start from it so that idle time is not banked.
+	pass float64
+}
+
+func newDiskScheduler(shares *diskShares) *diskScheduler {
+	return &diskScheduler{
+		mutex:   &sync.Mutex{},
+		shares:  shares,
+		tenants: make(map[string]*diskTenantQueue),
+	}
+}
+
+// serve - accounts an operation of tenant as being served, callers
+// hold the mutex.
+func (s *diskScheduler) serve(config diskSharesConfig, tenant string, queue *diskTenantQueue) {
+	s.active++
+	queue.served++
+	if queue.pass < s.pass {
+		queue.pass = s.pass
+	}
+	s.pass = queue.pass
+	queue.pass += 1 / float64(config.weight(tenant))
+}
+
+// acquire - waits until an operation of tenant may be served.
+func (s *diskScheduler) acquire(tenant string) {
+	config := s.shares.Get()
+	s.mutex.Lock()
+	queue, ok := s.tenants[tenant]
+	if !ok {
+		queue = &diskTenantQueue{pass: s.pass}
+		s.tenants[tenant] = queue
+	}
+	if s.active < config.slots() && s.waiting() == 0 {
+		s.serve(config, tenant, queue)
+		s.mutex.Unlock()
+		return
+	}
+	if len(queue.waiters) == 0 && queue.pass < s.pass {
+		queue.pass = s.pass
+	}
+	waiter := make(chan struct{})
+	queue.waiters = append(queue.waiters, waiter)
+	s.mutex.Unlock()
+	<-waiter
+}
+
+// release - ends an operation, queued operations are served in order
+// of their tenant's pass.
+func (s *diskScheduler) release() {
+	config := s.shares.Get()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.active--
+	for s.active < config.slots() {
+		var next string
+		var nextQueue *diskTenantQueue
+		for tenant, queue := range s.tenants {
+			if len(queue.waiters) == 0 {
+				continue
+			}
+			if nextQueue == nil || queue.pass < nextQueue.pass || (queue.pass == nextQueue.pass && tenant < next) {
+				next, nextQueue = tenant, queue
+			}
+		}
+		if nextQueue == nil {
+			return
+		}
+		waiter := nextQueue.waiters[0]
+		nextQueue.waiters = nextQueue.waiters[1:]
+		s.serve(config, next, nextQueue)
+		close(waiter)
+	}
+}
+
+// waiting - returns number of queued operations, callers hold the
+// mutex.
+func (s *diskScheduler) waiting() (waiting int) {
+	for _, queue := range s.tenants {
+		waiting += len(queue.waiters)
+	}
+	return waiting
+}
+
+// diskShareStatus - configured share and utilization of a disk by a
+// bucket. Share is the configured fraction of operations among buckets
+// which used the disk, Utilization the fraction actually served.
+type diskShareStatus struct {
+	Bucket      string  `json:"bucket"`
+	Weight      int     `json:"weight"`
+	Share       float64 `json:"share"`
+	Utilization float64 `json:"utilization"`
+	Served      int64   `json:"served"`
+	Waiting     int     `json:"waiting"`
+}
+
+// diskSchedulerStatus - operations in progress and shares of a disk.
+type diskSchedulerStatus struct {
+	Disk   string            `json:"disk"`
+	Active int               `json:"active"`
+	Shares []diskShareStatus `json:"shares"`
+}
+
+// diskShareReporter - implemented by storage which schedules
+// operations on its disks per bucket.
+type diskShareReporter interface {
+	DiskShares() []diskSchedulerStatus
+}
+
+// status - returns shares and utilization of all tenants.
+func (s *diskScheduler) status(disk string) diskSchedulerStatus {
+	config := s.shares.Get()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status := diskSchedulerStatus{Disk: disk, Active: s.active, Shares: []diskShareStatus{}}
+	var totalWeight int
+	var totalServed int64
+	for tenant, queue := range s.tenants {
+		totalWeight += config.weight(tenant)
+		totalServed += queue.served
+	}
+	for tenant, queue := range s.tenants {
+		share := diskShareStatus{
+			Bucket:  tenant,
+			Weight:  config.weight(tenant),
+			Served:  queue.served,
+			Waiting: len(queue.waiters),
+		}
+		share.Share = float64(share.Weight) / float64(totalWeight)
+		if totalServed > 0 {
+			share.Utilization = float64(queue.served) / float64(totalServed)
+		}
+		status.Shares = append(status.Shares, share)
+	}
+	sort.Slice(status.Shares, func(i, j int) bool { return status.Shares[i].Bucket < status.Shares[j].Bucket })
+	return status
+}
+
+// scheduledDisk - disk whose operations, including each read and
+// write of file data, are admitted by its scheduler.
+type scheduledDisk struct {
+	StorageAPI
+	scheduler *diskScheduler
+}
+
+func newScheduledDisk(disk StorageAPI) scheduledDisk {
+	return scheduledDisk{StorageAPI: disk, scheduler: newDiskScheduler(globalDiskShares)}
+}
+
+// MakeVol - schedules making a volume.
+func (d scheduledDisk) MakeVol(volume string) error {
+	d.scheduler.acquire(diskTenant(volume, ""))
+	defer d.scheduler.release()
+	return d.StorageAPI.MakeVol(volume)
+}
+
+// ListVols - schedules listing volumes.
+func (d scheduledDisk) ListVols() ([]VolInfo, error) {
+	d.scheduler.acquire(diskTenantSystem)
+	defer d.scheduler.release()
+	return d.StorageAPI.ListVols()
+}
+
+// StatVol - schedules a volume stat.
+func (d scheduledDisk) StatVol(volume string) (VolInfo, error) {
+	d.scheduler.acquire(diskTenant(volume, ""))
+	defer d.scheduler.release()
+	return d.StorageAPI.StatVol(volume)
+}
+
+// DeleteVol - schedules deleting a volume.
+func (d scheduledDisk) DeleteVol(volume string) error {
+	d.scheduler.acquire(diskTenant(volume, ""))
+	defer d.scheduler.release()
+	return d.StorageAPI.DeleteVol(volume)
+}
+
+// ListFiles - schedules listing files.
+func (d scheduledDisk) ListFiles(volume, prefix, marker string, recursive bool, count int) ([]FileInfo, bool, error) {
+	d.scheduler.acquire(diskTenant(volume, prefix))
+	defer d.scheduler.release()
+	return d.StorageAPI.ListFiles(volume, prefix, marker, recursive, count)
+}
+
+// StatFile - schedules a file stat.
+func (d scheduledDisk) StatFile(volume, path string) (FileInfo, error) {
+	d.scheduler.acquire(diskTenant(volume, path))
+	defer d.scheduler.release()
+	return d.StorageAPI.StatFile(volume, path)
+}
+
+// DeleteFile - schedules deleting a file.
+func (d scheduledDisk) DeleteFile(ctx context.Context, volume, path string) error {
+	d.scheduler.acquire(diskTenant(volume, path))
+	defer d.scheduler.release()
+	return d.StorageAPI.DeleteFile(ctx, volume, path)
+}
+
+// ReadFile - schedules opening a file and each read of its data.
+func (d scheduledDisk) ReadFile(ctx context.Context, volume, path string, offset int64) (io.ReadCloser, error) {
+	tenant := diskTenant(volume, path)
+	d.scheduler.acquire(tenant)
+	r, e := d.StorageAPI.ReadFile(ctx, volume, path, offset)
+	d.scheduler.release()
+	if e != nil {
+		return nil, e
+	}
+	return scheduledReader{ReadCloser: r, scheduler: d.scheduler, tenant: tenant}, nil
+}
+
+// CreateFile - schedules creating a file and each write of its data.
+func (d scheduledDisk) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	tenant := diskTenant(volume, path)
+	d.scheduler.acquire(tenant)
+	w, e := d.StorageAPI.CreateFile(ctx, volume, path)
+	d.scheduler.release()
+	if e != nil {
+		return nil, e
+	}
+	return &scheduledWriter{WriteCloser: w, scheduler: d.scheduler, tenant: tenant}, nil
+}
+
+// scheduledReader - file data reads admitted by the disk scheduler.
+type scheduledReader struct {
+	io.ReadCloser
+	scheduler *diskScheduler
+	tenant    string
+}
+
+func (r scheduledReader) Read(p []byte) (int, error) {
+	r.scheduler.acquire(r.tenant)
+	defer r.scheduler.release()
+	return r.ReadCloser.Read(p)
+}
+
+// scheduledWriter - file data writes admitted by the disk scheduler.
+type scheduledWriter struct {
+	io.WriteCloser
+	scheduler *diskScheduler
+	tenant    string
+}
+
+func (w *scheduledWriter) Write(p []byte) (int, error) {
+	w.scheduler.acquire(w.tenant)
+	defer w.scheduler.release()
+	return w.WriteCloser.Write(p)
+}
+
+func (w *scheduledWriter) Close() error {
+	w.scheduler.acquire(w.tenant)
+	defer w.scheduler.release()
+	return w.WriteCloser.Close()
+}
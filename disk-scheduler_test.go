@@ -0,0 +1,93 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests operations are attributed to the bucket they are done for.
+func TestDiskTenant(t *testing.T) {
+	testCases := []struct {
+		volume string
+		path   string
+		tenant string
+	}{
+		{"bucket", "object", "bucket"},
+		{minioMetaVolume, "bucket/object/uploadID.1.etag", "bucket"},
+		{minioMetaVolume, ".tagging/bucket/object", "bucket"},
+		{minioMetaVolume, ".purge/job", diskTenantSystem},
+		{minioMetaVolume, "", diskTenantSystem},
+	}
+	for i, testCase := range testCases {
+		if tenant := diskTenant(testCase.volume, testCase.path); tenant != testCase.tenant {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.tenant, tenant)
+		}
+	}
+}
+
+// Tests busy buckets are served in proportion to their weights.
+func TestDiskScheduler(t *testing.T) {
+	shares := &diskShares{rwMutex: &sync.RWMutex{}}
+	shares.Set(diskSharesConfig{Slots: 1, Weights: map[string]int{"heavy": 3}})
+	s := newDiskScheduler(shares)
+
+	// Hold the only slot while operations of both buckets queue up.
+	s.acquire("holder")
+	served := make(chan string)
+	for _, tenant := range []string{"heavy", "light"} {
+		for i := 0; i < 8; i++ {
+			go func(tenant string) {
+				s.acquire(tenant)
+				served <- tenant
+			}(tenant)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		s.mutex.Lock()
+		waiting := s.waiting()
+		s.mutex.Unlock()
+		if waiting == 16 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		s.release()
+		counts[<-served]++
+	}
+	if counts["heavy"] != 6 || counts["light"] != 2 {
+		t.Fatalf("Expected 6 heavy and 2 light operations served, got %v", counts)
+	}
+	for i := 0; i < 8; i++ {
+		s.release()
+		<-served
+	}
+	s.release()
+
+	status := s.status("disk")
+	if status.Active != 0 || len(status.Shares) != 3 {
+		t.Fatalf("Unexpected status %+v", status)
+	}
+	if heavy := status.Shares[0]; heavy.Bucket != "heavy" || heavy.Share != 0.6 || heavy.Served != 8 {
+		t.Fatalf("Unexpected share %+v", heavy)
+	}
+}
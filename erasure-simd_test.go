@@ -0,0 +1,228 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"testing"
+
+	"github.com/klauspost/cpuid"
+	"github.com/klauspost/reedsolomon"
+	fastSha512 "github.com/minio/minio/pkg/crypto/sha512"
+)
+
+// simdCodePaths - code paths of erasure coding and checksums, run by
+// hiding CPU features from runtime selection. Paths the CPU does not
+// support are skipped.
+var simdCodePaths = []struct {
+	name     string
+	required cpuid.Flags
+	hidden   cpuid.Flags
+}{
+	{"avx2", cpuid.AVX2, 0},
+	{"avx", cpuid.AVX, cpuid.AVX2},
+	{"ssse3", cpuid.SSSE3, cpuid.AVX2 | cpuid.AVX},
+	{"generic", 0, cpuid.AVX2 | cpuid.AVX | cpuid.SSSE3},
+}
+
+// runSIMDCodePaths - runs benchmark for each code path.
+func runSIMDCodePaths(b *testing.B, benchmark func(b *testing.B)) {
+	features := cpuid.CPU.Features
+	defer func() { cpuid.CPU.Features = features }()
+	for _, path := range simdCodePaths {
+		b.Run(path.name, func(b *testing.B) {
+			if features&path.required != path.required {
+				b.Skipf("%s not supported by this CPU", path.name)
+			}
+			cpuid.CPU.Features = features &^ path.hidden
+			benchmark(b)
+		})
+	}
+}
+
+// Tests every code path encodes the same parity and checksums as the
+// one selected for the CPU.
+func TestSIMDCodePaths(t *testing.T) {
+	rs, e := reedsolomon.New(8, 8)
+	if e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, 1024*1024+17)
+	rand.Read(data)
+	encode := func() ([][]byte, [fastSha512.Size]byte) {
+		shards, e := rs.Split(append([]byte{}, data...))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = rs.Encode(shards); e != nil {
+			t.Fatal(e)
+		}
+		return shards, fastSha512.Sum512(shards[len(shards)-1])
+	}
+	expectedShards, expectedSum := encode()
+
+	features := cpuid.CPU.Features
+	defer func() { cpuid.CPU.Features = features }()
+	for _, path := range simdCodePaths {
+		if features&path.required != path.required {
+			continue
+		}
+		cpuid.CPU.Features = features &^ path.hidden
+		shards, sum := encode()
+		for index := range shards {
+			if string(shards[index]) != string(expectedShards[index]) {
+				t.Fatalf("%s: Unexpected shard %d", path.name, index)
+			}
+		}
+		if sum != expectedSum {
+			t.Fatalf("%s: Unexpected checksum", path.name)
+		}
+	}
+}
+
+// Benchmarks encoding parity of a 4MiB block, 8 data and 8 parity.
+func BenchmarkErasureEncode(b *testing.B) {
+	rs, e := reedsolomon.New(8, 8)
+	if e != nil {
+		b.Fatal(e)
+	}
+	shards, e := rs.Split(make([]byte, erasureBlockSize))
+	if e != nil {
+		b.Fatal(e)
+	}
+	runSIMDCodePaths(b, func(b *testing.B) {
+		b.SetBytes(erasureBlockSize)
+		for i := 0; i < b.N; i++ {
+			if e := rs.Encode(shards); e != nil {
+				b.Fatal(e)
+			}
+		}
+	})
+}
+
+// Benchmarks checksums of erasure coded parts.
+func BenchmarkPartChecksum(b *testing.B) {
+	data := make([]byte, erasureBlockSize/8)
+	runSIMDCodePaths(b, func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			hasher := fastSha512.New()
+			hasher.Write(data)
+			hasher.Sum(nil)
+		}
+	})
+	// Compared with the standard library, used where assembly of
+	// pkg/crypto/sha512 is not built.
+	b.Run("stdlib", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			sha512.Sum512(data)
+		}
+	})
+}
+
+// erasureBenchmarkSizes - sizes of blocks erasure coded, from blocks
+// of small files up to full blocks.
+var erasureBenchmarkSizes = []int{64 * 1024, 1024 * 1024, erasureBlockSize}
+
+// runErasureBenchmarks - runs benchmark for XL on 4, 8 and 16 disks
+// and each block size.
+func runErasureBenchmarks(b *testing.B, benchmark func(b *testing.B, xl *XL, size int)) {
+	for _, totalDisks := range []int{4, 8, 16} {
+		xl := &XL{}
+		if e := xl.initErasure(totalDisks); e != nil {
+			b.Fatal(e)
+		}
+		for _, size := range erasureBenchmarkSizes {
+			b.Run(fmt.Sprintf("disks-%d/size-%dKiB", totalDisks, size/1024), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				benchmark(b, xl, size)
+			})
+		}
+	}
+}
+
+// Benchmarks splitting blocks into data shards.
+func BenchmarkErasureSplit(b *testing.B) {
+	runErasureBenchmarks(b, func(b *testing.B, xl *XL, size int) {
+		buffer := xl.buffers.get()
+		defer xl.buffers.put(buffer)
+		shards := make([][]byte, xl.DataBlocks+xl.ParityBlocks)
+		for i := 0; i < b.N; i++ {
+			xl.splitBlock(*buffer, size, shards)
+		}
+	})
+}
+
+// Benchmarks splitting and encoding parity of blocks.
+func BenchmarkErasureSplitEncode(b *testing.B) {
+	runErasureBenchmarks(b, func(b *testing.B, xl *XL, size int) {
+		buffer := xl.buffers.get()
+		defer xl.buffers.put(buffer)
+		shards := make([][]byte, xl.DataBlocks+xl.ParityBlocks)
+		for i := 0; i < b.N; i++ {
+			if e := xl.ReedSolomon.Encode(xl.splitBlock(*buffer, size, shards)); e != nil {
+				b.Fatal(e)
+			}
+		}
+	})
+}
+
+// Benchmarks reconstructing blocks missing as many data shards as
+// there are parity shards, the worst case of reads.
+func BenchmarkErasureReconstruct(b *testing.B) {
+	runErasureBenchmarks(b, func(b *testing.B, xl *XL, size int) {
+		buffer := xl.buffers.get()
+		defer xl.buffers.put(buffer)
+		encoded := make([][]byte, xl.DataBlocks+xl.ParityBlocks)
+		if e := xl.ReedSolomon.Encode(xl.splitBlock(*buffer, size, encoded)); e != nil {
+			b.Fatal(e)
+		}
+		shards := make([][]byte, len(encoded))
+		for i := 0; i < b.N; i++ {
+			copy(shards, encoded)
+			for index := 0; index < xl.ParityBlocks; index++ {
+				shards[index] = nil
+			}
+			if e := xl.ReedSolomon.Reconstruct(shards); e != nil {
+				b.Fatal(e)
+			}
+		}
+	})
+}
+
+// Benchmarks checksums of encoded blocks written to each disk, as
+// the sha512 writers of CreateFile do.
+func BenchmarkErasureChecksumWriters(b *testing.B) {
+	runErasureBenchmarks(b, func(b *testing.B, xl *XL, size int) {
+		buffer := xl.buffers.get()
+		defer xl.buffers.put(buffer)
+		shards := xl.splitBlock(*buffer, size, make([][]byte, xl.DataBlocks+xl.ParityBlocks))
+		hashers := make([]hash.Hash, len(shards))
+		for index := range hashers {
+			hashers[index] = fastSha512.New()
+		}
+		for i := 0; i < b.N; i++ {
+			for index, shard := range shards {
+				hashers[index].Write(shard)
+			}
+		}
+	})
+}
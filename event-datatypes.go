@@ -0,0 +1,158 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "strings"
+
+// EventName is AWS S3 event type:
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+type EventName int
+
+const (
+	// ObjectCreatedPut is s3:ObjectCreated:Put
+	ObjectCreatedPut EventName = iota
+	// ObjectCreatedPost is s3:ObjectCreated:Post
+	ObjectCreatedPost
+	// ObjectCreatedCopy is s3:ObjectCreated:Copy
+	ObjectCreatedCopy
+	// ObjectCreatedCompleteMultipartUpload is s3:ObjectCreated:CompleteMultipartUpload
+	ObjectCreatedCompleteMultipartUpload
+	// ObjectRemovedDelete is s3:ObjectRemoved:Delete
+	ObjectRemovedDelete
+	// AlarmRaised is minio:Alarm:Raised
+	AlarmRaised
+	// AlarmCleared is minio:Alarm:Cleared
+	AlarmCleared
+)
+
+// Stringer interface for event name.
+func (eventName EventName) String() string {
+	switch eventName {
+	case ObjectCreatedPut:
+		return "s3:ObjectCreated:Put"
+	case ObjectCreatedPost:
+		return "s3:ObjectCreated:Post"
+	case ObjectCreatedCopy:
+		return "s3:ObjectCreated:Copy"
+	case ObjectCreatedCompleteMultipartUpload:
+		return "s3:ObjectCreated:CompleteMultipartUpload"
+	case ObjectRemovedDelete:
+		return "s3:ObjectRemoved:Delete"
+	case AlarmRaised:
+		return "minio:Alarm:Raised"
+	case AlarmCleared:
+		return "minio:Alarm:Cleared"
+	default:
+		return "s3:Unknown"
+	}
+}
+
+// isValidEventName - returns true for event names which can be used
+// to filter notifications, all supported event names along with the
+// "s3:ObjectCreated:*", "s3:ObjectRemoved:*" and "minio:Alarm:*"
+// wildcards.
+func isValidEventName(eventName string) bool {
+	switch eventName {
+	case "s3:ObjectCreated:*", "s3:ObjectRemoved:*", "minio:Alarm:*":
+		return true
+	}
+	for _, name := range []EventName{
+		ObjectCreatedPut,
+		ObjectCreatedPost,
+		ObjectCreatedCopy,
+		ObjectCreatedCompleteMultipartUpload,
+		ObjectRemovedDelete,
+		AlarmRaised,
+		AlarmCleared,
+	} {
+		if name.String() == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+// eventMatch - returns true if eventName matches one of the filters,
+// a filter ending with "*" matches all events with the same prefix.
+// Empty filters match all events.
+func eventMatch(eventName string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if strings.HasSuffix(filter, "*") {
+			if strings.HasPrefix(eventName, strings.TrimSuffix(filter, "*")) {
+				return true
+			}
+			continue
+		}
+		if filter == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+// Indentity represents the user id, this is a compliance field.
+type identity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+// Notification event bucket metadata.
+type bucketMeta struct {
+	Name          string   `json:"name"`
+	OwnerIdentity identity `json:"ownerIdentity"`
+	ARN           string   `json:"arn"`
+}
+
+// Notification event object metadata.
+type objectMeta struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// Notification event server specific metadata.
+type eventMeta struct {
+	SchemaVersion   string     `json:"s3SchemaVersion"`
+	ConfigurationID string     `json:"configurationId"`
+	Bucket          bucketMeta `json:"bucket"`
+	Object          objectMeta `json:"object"`
+}
+
+// notificationEvent represents an Amazon an S3 bucket notification event.
+type notificationEvent struct {
+	EventVersion      string            `json:"eventVersion"`
+	EventSource       string            `json:"eventSource"`
+	AwsRegion         string            `json:"awsRegion"`
+	EventTime         string            `json:"eventTime"`
+	EventName         string            `json:"eventName"`
+	UserIdentity      identity          `json:"userIdentity"`
+	RequestParameters map[string]string `json:"requestParameters"`
+	ResponseElements  map[string]string `json:"responseElements"`
+	S3                eventMeta         `json:"s3"`
+}
+
+// eventLogEntry - the message delivered to notification targets, a
+// set of records along with the event type and the "bucket/object"
+// key the records refer to.
+type eventLogEntry struct {
+	EventType string              `json:"EventType"`
+	Key       string              `json:"Key"`
+	Records   []notificationEvent `json:"Records"`
+}
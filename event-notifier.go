@@ -0,0 +1,540 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Maximum number of events queued per target, events beyond
+	// this limit are dropped until the target catches up.
+	maxTargetQueueSize = 10000
+
+	// Maximum number of events queued per listener.
+	maxListenerQueueSize = 1000
+)
+
+var (
+	// errTargetQueueFull - notification target is not keeping up.
+	errTargetQueueFull = errors.New("Notification target queue is full, event dropped")
+	// errListenerQueueFull - listening client is not keeping up.
+	errListenerQueueFull = errors.New("Listener queue is full, event dropped")
+)
+
+// eventData - collection of fields describing an event, filled in
+// by the API handlers.
+type eventData struct {
+	Type      EventName
+	Bucket    string
+	ObjInfo   ObjectInfo
+	ReqParams map[string]string
+}
+
+// notificationTarget - a destination bucket events are delivered to.
+type notificationTarget interface {
+	// Send delivers the entry, entry.Key is "bucket/object" and
+	// can be used by targets which support keyed partitioning.
+	Send(entry eventLogEntry) error
+	// Close releases all resources held by the target.
+	Close() error
+}
+
+// queuedTarget - target along with its delivery queue, a single
+// routine drains the queue so events for a target are delivered in
+// the order they were generated. Events the target fails to receive
+// are saved to its event store and replayed once it recovers, events
+// queued meanwhile are saved behind them.
+type queuedTarget struct {
+	target   notificationTarget
+	queue    chan eventLogEntry
+	store    *eventStore
+	replayCh chan struct{}
+}
+
+func newQueuedTarget(targetID string, target notificationTarget) *queuedTarget {
+	store, e := globalEventStores.Get(targetID)
+	if e != nil {
+		errorIf(probe.NewError(e), "Unable to open event store, undelivered events are dropped.", logrus.Fields{
+			"target": targetID,
+		})
+	}
+	return newStoredTarget(targetID, target, store)
+}
+
+// newStoredTarget - returns target saving undelivered events to store,
+// dropping them if store is nil.
+func newStoredTarget(targetID string, target notificationTarget, store *eventStore) *queuedTarget {
+	qt := &queuedTarget{
+		target:   target,
+		queue:    make(chan eventLogEntry, maxTargetQueueSize),
+		store:    store,
+		replayCh: make(chan struct{}, 1),
+	}
+	go func() {
+		ticker := time.NewTicker(eventReplayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case entry, ok := <-qt.queue:
+				if !ok {
+					qt.target.Close()
+					return
+				}
+				qt.deliver(targetID, entry)
+			case <-ticker.C:
+				qt.replay(targetID)
+			case <-qt.replayCh:
+				qt.replay(targetID)
+			}
+		}
+	}()
+	return qt
+}
+
+// deliver - sends entry, or saves it if the target is failing or
+// still has undelivered events.
+func (qt *queuedTarget) deliver(targetID string, entry eventLogEntry) {
+	if qt.store == nil || qt.store.Len() == 0 {
+		e := qt.target.Send(entry)
+		if e == nil {
+			return
+		}
+		errorIf(probe.NewError(e), "Unable to deliver event notification.", logrus.Fields{
+			"target": targetID,
+			"key":    entry.Key,
+		})
+		if qt.store == nil {
+			return
+		}
+		qt.store.setFailure(e)
+	}
+	if e := qt.store.Put(entry); e != nil {
+		errorIf(probe.NewError(e), "Unable to save undelivered event notification.", logrus.Fields{
+			"target": targetID,
+			"key":    entry.Key,
+		})
+	}
+}
+
+// replay - sends undelivered events, if any.
+func (qt *queuedTarget) replay(targetID string) {
+	if qt.store == nil || qt.store.Len() == 0 {
+		return
+	}
+	replayed, e := qt.store.Replay(qt.target.Send)
+	if e != nil {
+		errorIf(probe.NewError(e), "Unable to replay undelivered event notifications.", logrus.Fields{
+			"target":   targetID,
+			"replayed": replayed,
+		})
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"target":   targetID,
+		"replayed": replayed,
+	}).Info("Replayed undelivered event notifications.")
+}
+
+// Replay - requests a replay of undelivered events now.
+func (qt *queuedTarget) Replay() {
+	select {
+	case qt.replayCh <- struct{}{}:
+	default:
+	}
+}
+
+// listenChan - a client listening for live events on a bucket,
+// events are filtered by object name prefix, suffix and event name.
+type listenChan struct {
+	bucket string
+	prefix string
+	suffix string
+	events []string
+	ch     chan eventLogEntry
+}
+
+// matches - returns true if the event on object should be sent to
+// the listener.
+func (l *listenChan) matches(bucket, object, eventName string) bool {
+	if l.bucket != bucket {
+		return false
+	}
+	if !strings.HasPrefix(object, l.prefix) || !strings.HasSuffix(object, l.suffix) {
+		return false
+	}
+	return eventMatch(eventName, l.events)
+}
+
+// eventNotifier - carries all the initialized notification targets,
+// keyed by target id "<accountID>:<type>", along with all the
+// currently connected listeners.
+type eventNotifier struct {
+	rwMutex *sync.RWMutex
+	targets map[string]*queuedTarget
+	// Ids of targets initialized from server config.
+	configTargets map[string]bool
+	listeners     map[*listenChan]struct{}
+}
+
+// Global event notifier, initialized at server startup.
+var globalEventNotifier *eventNotifier
+
+// initEventNotifier - initializes all enabled notification targets
+// from server config.
+func initEventNotifier() {
+	targets := newConfigNotificationTargets()
+	globalEventNotifier = &eventNotifier{
+		rwMutex:       &sync.RWMutex{},
+		targets:       targets,
+		configTargets: make(map[string]bool),
+		listeners:     make(map[*listenChan]struct{}),
+	}
+	for targetID := range targets {
+		globalEventNotifier.configTargets[targetID] = true
+	}
+}
+
+// newConfigNotificationTargets - initializes all enabled notification
+// targets of server config. Targets which cannot be initialized, for
+// instance because their server is down, are initialized again on
+// delivery, events are saved until then.
+func newConfigNotificationTargets() map[string]*queuedTarget {
+	targets := make(map[string]*queuedTarget)
+	// addTarget - initializes target of kind for account id.
+	addTarget := func(accountID, kind string, newTarget func() (notificationTarget, error)) {
+		targetID := accountID + ":" + kind
+		target, e := newTarget()
+		if e != nil {
+			errorIf(probe.NewError(e), "Unable to initialize notification target, events are saved until it recovers.", logrus.Fields{
+				"target": targetID,
+			})
+			target = &pendingTarget{newTarget: newTarget}
+		}
+		targets[targetID] = newQueuedTarget(targetID, target)
+	}
+	for accountID, kNotify := range serverConfig.GetKafka() {
+		if !kNotify.Enable {
+			continue
+		}
+		kNotify := kNotify
+		addTarget(accountID, "kafka", func() (notificationTarget, error) {
+			return newKafkaNotify(kNotify)
+		})
+	}
+	for accountID, nNotify := range serverConfig.GetNATS() {
+		if !nNotify.Enable {
+			continue
+		}
+		nNotify := nNotify
+		addTarget(accountID, "nats", func() (notificationTarget, error) {
+			return newNATSNotify(nNotify)
+		})
+	}
+	for accountID, mNotify := range serverConfig.GetMQTT() {
+		if !mNotify.Enable {
+			continue
+		}
+		mNotify := mNotify
+		addTarget(accountID, "mqtt", func() (notificationTarget, error) {
+			return newMQTTNotify(mNotify)
+		})
+	}
+	for accountID, pgNotify := range serverConfig.GetPostgreSQL() {
+		if !pgNotify.Enable {
+			continue
+		}
+		pgNotify := pgNotify
+		addTarget(accountID, "postgresql", func() (notificationTarget, error) {
+			return newPostgreSQLNotify(pgNotify)
+		})
+	}
+	for accountID, myNotify := range serverConfig.GetMySQL() {
+		if !myNotify.Enable {
+			continue
+		}
+		myNotify := myNotify
+		addTarget(accountID, "mysql", func() (notificationTarget, error) {
+			return newMySQLNotify(myNotify)
+		})
+	}
+	return targets
+}
+
+// pendingTarget - target which could not be initialized, it is
+// initialized on the next delivery or replay. Like other targets it is
+// only used by the routine draining its queue.
+type pendingTarget struct {
+	newTarget func() (notificationTarget, error)
+	target    notificationTarget
+}
+
+func (t *pendingTarget) Send(entry eventLogEntry) error {
+	if t.target == nil {
+		target, e := t.newTarget()
+		if e != nil {
+			return e
+		}
+		t.target = target
+	}
+	return t.target.Send(entry)
+}
+
+func (t *pendingTarget) Close() error {
+	if t.target == nil {
+		return nil
+	}
+	return t.target.Close()
+}
+
+// SetConfigTargets - replaces targets of the server config with
+// targets, once the config changed. Events queued on replaced targets
+// are still delivered.
+func (en *eventNotifier) SetConfigTargets(targets map[string]*queuedTarget) {
+	en.rwMutex.Lock()
+	defer en.rwMutex.Unlock()
+	for targetID := range en.configTargets {
+		close(en.targets[targetID].queue)
+		delete(en.targets, targetID)
+	}
+	en.configTargets = make(map[string]bool)
+	for targetID, qt := range targets {
+		en.targets[targetID] = qt
+		en.configTargets[targetID] = true
+	}
+}
+
+// IsActive - returns true if at least one notification target is
+// configured or a listener is connected, callers can use this to
+// avoid preparing events which will never be delivered.
+func (en *eventNotifier) IsActive() bool {
+	if en == nil {
+		return false
+	}
+	en.rwMutex.RLock()
+	defer en.rwMutex.RUnlock()
+	return len(en.targets) > 0 || len(en.listeners) > 0
+}
+
+// AddListener - registers a new listener for events on bucket, the
+// listener must be removed with RemoveListener once done.
+func (en *eventNotifier) AddListener(bucket, prefix, suffix string, events []string) *listenChan {
+	listener := &listenChan{
+		bucket: bucket,
+		prefix: prefix,
+		suffix: suffix,
+		events: events,
+		ch:     make(chan eventLogEntry, maxListenerQueueSize),
+	}
+	en.rwMutex.Lock()
+	defer en.rwMutex.Unlock()
+	en.listeners[listener] = struct{}{}
+	return listener
+}
+
+// RemoveListener - unregisters a listener, no more events are sent
+// to it afterwards.
+func (en *eventNotifier) RemoveListener(listener *listenChan) {
+	en.rwMutex.Lock()
+	defer en.rwMutex.Unlock()
+	delete(en.listeners, listener)
+}
+
+// AddTarget - registers a target which is not part of the server
+// config, events are queued on it like on any configured target.
+func (en *eventNotifier) AddTarget(targetID string, target notificationTarget) {
+	en.rwMutex.Lock()
+	defer en.rwMutex.Unlock()
+	en.targets[targetID] = newQueuedTarget(targetID, target)
+}
+
+// GetTargetIDs - returns sorted ids of all active targets.
+func (en *eventNotifier) GetTargetIDs() []string {
+	if en == nil {
+		return nil
+	}
+	en.rwMutex.RLock()
+	defer en.rwMutex.RUnlock()
+	var targetIDs []string
+	for targetID := range en.targets {
+		targetIDs = append(targetIDs, targetID)
+	}
+	sort.Strings(targetIDs)
+	return targetIDs
+}
+
+// ReplayTarget - requests a replay of undelivered events of the active
+// target with id.
+func (en *eventNotifier) ReplayTarget(targetID string) *probe.Error {
+	if en == nil {
+		return probe.NewError(EventTargetNotFound{Target: targetID})
+	}
+	en.rwMutex.RLock()
+	defer en.rwMutex.RUnlock()
+	qt, ok := en.targets[targetID]
+	if !ok {
+		return probe.NewError(EventTargetNotFound{Target: targetID})
+	}
+	qt.Replay()
+	return nil
+}
+
+// notify - queues entry on all targets and matching listeners, never
+// blocks.
+func (en *eventNotifier) notify(bucket, object string, entry eventLogEntry) {
+	en.rwMutex.RLock()
+	defer en.rwMutex.RUnlock()
+	for listener := range en.listeners {
+		if !listener.matches(bucket, object, entry.EventType) {
+			continue
+		}
+		select {
+		case listener.ch <- entry:
+		default:
+			errorIf(probe.NewError(errListenerQueueFull), "Unable to queue event notification.", logrus.Fields{
+				"bucket": bucket,
+				"key":    entry.Key,
+			})
+		}
+	}
+	for targetID, qt := range en.targets {
+		select {
+		case qt.queue <- entry:
+		default:
+			errorIf(probe.NewError(errTargetQueueFull), "Unable to queue event notification.", logrus.Fields{
+				"target": targetID,
+				"key":    entry.Key,
+			})
+		}
+	}
+}
+
+// newNotificationEvent - builds an S3 compatible event record.
+func newNotificationEvent(event eventData) notificationEvent {
+	region := serverConfig.GetRegion()
+	// Time when Minio finished processing the request.
+	eventTime := time.Now().UTC()
+	// Sequencer lets consumers order events for a given key, events
+	// of objects carry the sequence number of their mutation.
+	sequencer := fmt.Sprintf("%X", eventTime.UnixNano())
+	if event.ObjInfo.Sequence != 0 {
+		sequencer = formatSequencer(event.ObjInfo.Sequence)
+	}
+	// Object names are sent URL encoded, same as S3.
+	escapedObj := url.QueryEscape(event.ObjInfo.Name)
+
+	nEvent := notificationEvent{
+		EventVersion:      "2.0",
+		EventSource:       "aws:s3",
+		AwsRegion:         region,
+		EventTime:         eventTime.Format(timeFormatAMZ),
+		EventName:         event.Type.String(),
+		UserIdentity:      identity{PrincipalID: serverConfig.GetCredential().AccessKeyID},
+		RequestParameters: event.ReqParams,
+		ResponseElements:  map[string]string{},
+		S3: eventMeta{
+			SchemaVersion:   "1.0",
+			ConfigurationID: "Config",
+			Bucket: bucketMeta{
+				Name:          event.Bucket,
+				OwnerIdentity: identity{PrincipalID: serverConfig.GetCredential().AccessKeyID},
+				ARN:           AWSResourcePrefix + event.Bucket,
+			},
+			Object: objectMeta{
+				Key:       escapedObj,
+				Sequencer: sequencer,
+			},
+		},
+	}
+	// Removal events carry no size or etag.
+	if event.Type != ObjectRemovedDelete {
+		nEvent.S3.Object.Size = event.ObjInfo.Size
+		nEvent.S3.Object.ETag = event.ObjInfo.MD5Sum
+	}
+	return nEvent
+}
+
+// eventNotify - queues the event on all configured targets.
+func eventNotify(event eventData) {
+	if !globalEventNotifier.IsActive() {
+		return
+	}
+	globalEventNotifier.notify(event.Bucket, event.ObjInfo.Name, eventLogEntry{
+		EventType: event.Type.String(),
+		Key:       event.Bucket + "/" + event.ObjInfo.Name,
+		Records:   []notificationEvent{newNotificationEvent(event)},
+	})
+}
+
+// Request parameters carried along with every event.
+func eventReqParams(r *http.Request) map[string]string {
+	return map[string]string{
+		"sourceIPAddress": r.RemoteAddr,
+	}
+}
+
+// notifyObjectCreated - notifies object created event of the mutation
+// with sequence, object info is only looked up if there are active
+// targets.
+func notifyObjectCreated(objAPI objectAPI, r *http.Request, eventType EventName, bucket, object, md5Sum string, sequence uint64) {
+	if !globalEventNotifier.IsActive() {
+		return
+	}
+	objInfo, err := objAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectInfo failed, unable to send event notification.", nil)
+		return
+	}
+	objInfo.MD5Sum = md5Sum
+	// Object info may already reflect a later mutation.
+	if sequence != 0 {
+		objInfo.Sequence = sequence
+	}
+	eventNotify(eventData{
+		Type:      eventType,
+		Bucket:    bucket,
+		ObjInfo:   objInfo,
+		ReqParams: eventReqParams(r),
+	})
+}
+
+// notifyObjectRemoved - notifies object removed event of the mutation
+// with sequence, the last one of the key if sequence is 0.
+func notifyObjectRemoved(objAPI objectAPI, r *http.Request, bucket, object string, sequence uint64) {
+	if !globalEventNotifier.IsActive() {
+		return
+	}
+	if sequence == 0 {
+		sequence = objAPI.getObjectSequence(bucket, object)
+	}
+	eventNotify(eventData{
+		Type:      ObjectRemovedDelete,
+		Bucket:    bucket,
+		ObjInfo:   ObjectInfo{Bucket: bucket, Name: object, Sequence: sequence},
+		ReqParams: eventReqParams(r),
+	})
+}
@@ -0,0 +1,327 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Events a target failed to receive are kept under this
+	// directory of the config path, one directory per target.
+	eventStoreDir = "events"
+
+	// Maximum number of undelivered events kept per target, events
+	// beyond this limit are dropped until the backlog is replayed.
+	maxEventStoreSize = 100000
+
+	// Interval between two replays of undelivered events.
+	eventReplayInterval = 30 * time.Second
+
+	// Maximum number of undelivered events listed by the admin API.
+	maxEventStoreList = 1000
+)
+
+// errEventStoreFull - notification target is down for too long.
+var errEventStoreFull = errors.New("Notification target event store is full, event dropped")
+
+// eventBacklogInfo - state of the undelivered events of a target.
+type eventBacklogInfo struct {
+	Target string `json:"target"`
+	Events int    `json:"events"`
+	// Last delivery or replay which failed, the reason the events
+	// are kept.
+	LastFailure time.Time `json:"lastFailure,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	// Last replay, and number of events delivered by it.
+	LastReplay     time.Time `json:"lastReplay,omitempty"`
+	LastReplayed   int       `json:"lastReplayed"`
+	ReplayedEvents int64     `json:"replayedEvents"`
+}
+
+// eventBacklog - response of the admin event backlog API.
+type eventBacklog struct {
+	Info      eventBacklogInfo `json:"info"`
+	Events    []eventLogEntry  `json:"events"`
+	Truncated bool             `json:"truncated"`
+}
+
+// eventStore - undelivered events of a target, each saved as its own
+// file named after its position so that they are replayed in the order
+// they were generated.
+type eventStore struct {
+	mutex *sync.Mutex
+	// Replays are run one at a time.
+	replayMutex *sync.Mutex
+	dir         string
+	// Names of saved events, oldest first.
+	names []string
+	next  uint64
+	info  eventBacklogInfo
+}
+
+// newEventStore - opens the event store of target in dir, loading
+// names of the events saved before.
+func newEventStore(targetID, dir string) (*eventStore, error) {
+	if e := os.MkdirAll(dir, 0700); e != nil {
+		return nil, e
+	}
+	fis, e := ioutil.ReadDir(dir)
+	if e != nil {
+		return nil, e
+	}
+	s := &eventStore{
+		mutex:       &sync.Mutex{},
+		replayMutex: &sync.Mutex{},
+		dir:         dir,
+		next:        uint64(time.Now().UnixNano()),
+		info:        eventBacklogInfo{Target: targetID},
+	}
+	for _, fi := range fis {
+		position, e := strconv.ParseUint(strings.TrimSuffix(fi.Name(), ".json"), 10, 64)
+		if e != nil || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		if position >= s.next {
+			s.next = position + 1
+		}
+		s.names = append(s.names, fi.Name())
+	}
+	sort.Strings(s.names)
+	return s, nil
+}
+
+// Len - returns the number of undelivered events.
+func (s *eventStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.names)
+}
+
+// Info - returns state of the undelivered events.
+func (s *eventStore) Info() eventBacklogInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	info := s.info
+	info.Events = len(s.names)
+	return info
+}
+
+// setFailure - records failed delivery or replay.
+func (s *eventStore) setFailure(e error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.info.LastFailure = time.Now().UTC()
+	s.info.LastError = e.Error()
+}
+
+// Put - saves an undelivered event after all others.
+func (s *eventStore) Put(entry eventLogEntry) error {
+	entryBytes, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.names) >= maxEventStoreSize {
+		return errEventStoreFull
+	}
+	name := fmt.Sprintf("%020d.json", s.next)
+	if e = ioutil.WriteFile(filepath.Join(s.dir, name), entryBytes, 0600); e != nil {
+		return e
+	}
+	s.next++
+	s.names = append(s.names, name)
+	return nil
+}
+
+// read - returns the saved event with name.
+func (s *eventStore) read(name string) (eventLogEntry, error) {
+	entryBytes, e := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if e != nil {
+		return eventLogEntry{}, e
+	}
+	entry := eventLogEntry{}
+	if e = json.Unmarshal(entryBytes, &entry); e != nil {
+		return eventLogEntry{}, e
+	}
+	return entry, nil
+}
+
+// remove - removes the saved event with name, if still the oldest.
+func (s *eventStore) remove(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.names) == 0 || s.names[0] != name {
+		return nil
+	}
+	if e := os.Remove(filepath.Join(s.dir, name)); e != nil && !os.IsNotExist(e) {
+		return e
+	}
+	s.names = s.names[1:]
+	return nil
+}
+
+// List - returns at most max undelivered events, oldest first, and
+// whether there are more.
+func (s *eventStore) List(max int) ([]eventLogEntry, bool, error) {
+	s.mutex.Lock()
+	names := s.names
+	s.mutex.Unlock()
+	truncated := len(names) > max
+	if truncated {
+		names = names[:max]
+	}
+	entries := []eventLogEntry{}
+	for _, name := range names {
+		entry, e := s.read(name)
+		if e != nil {
+			// Replayed or discarded meanwhile.
+			if os.IsNotExist(e) {
+				continue
+			}
+			return nil, false, e
+		}
+		entries = append(entries, entry)
+	}
+	return entries, truncated, nil
+}
+
+// Replay - sends undelivered events oldest first, until all are
+// delivered or one fails. Events which cannot be read back are
+// dropped. Returns the number of events delivered.
+func (s *eventStore) Replay(send func(entry eventLogEntry) error) (int, error) {
+	s.replayMutex.Lock()
+	defer s.replayMutex.Unlock()
+	replayed := 0
+	defer func() {
+		s.mutex.Lock()
+		s.info.LastReplay = time.Now().UTC()
+		s.info.LastReplayed = replayed
+		s.info.ReplayedEvents += int64(replayed)
+		s.mutex.Unlock()
+	}()
+	for {
+		s.mutex.Lock()
+		if len(s.names) == 0 {
+			s.mutex.Unlock()
+			return replayed, nil
+		}
+		name := s.names[0]
+		s.mutex.Unlock()
+		entry, e := s.read(name)
+		if e == nil {
+			if e = send(entry); e != nil {
+				s.setFailure(e)
+				return replayed, e
+			}
+			replayed++
+		} else if !os.IsNotExist(e) {
+			errorIf(probe.NewError(e).Trace(name), "Unable to read undelivered event notification, dropped.", nil)
+		}
+		if e = s.remove(name); e != nil {
+			return replayed, e
+		}
+	}
+}
+
+// Discard - removes all undelivered events, returns the number of
+// events removed.
+func (s *eventStore) Discard() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	removed := 0
+	for len(s.names) > 0 {
+		if e := os.Remove(filepath.Join(s.dir, s.names[0])); e != nil && !os.IsNotExist(e) {
+			return removed, e
+		}
+		s.names = s.names[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// eventStores - event stores of all targets, shared by the targets
+// replacing each other on config changes.
+type eventStores struct {
+	mutex  *sync.Mutex
+	stores map[string]*eventStore
+}
+
+// Global event stores, opened along with their targets.
+var globalEventStores = &eventStores{
+	mutex:  &sync.Mutex{},
+	stores: make(map[string]*eventStore),
+}
+
+// Get - returns the event store of target, opening it if needed.
+func (es *eventStores) Get(targetID string) (*eventStore, error) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	if s, ok := es.stores[targetID]; ok {
+		return s, nil
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err.ToGoError()
+	}
+	// Target ids hold characters which are not valid in file names
+	// on all systems.
+	s, e := newEventStore(targetID, filepath.Join(configPath, eventStoreDir, url.QueryEscape(targetID)))
+	if e != nil {
+		return nil, e
+	}
+	es.stores[targetID] = s
+	return s, nil
+}
+
+// Lookup - returns the event store of target, if opened.
+func (es *eventStores) Lookup(targetID string) (*eventStore, *probe.Error) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	s, ok := es.stores[targetID]
+	if !ok {
+		return nil, probe.NewError(EventTargetNotFound{Target: targetID})
+	}
+	return s, nil
+}
+
+// List - returns state of the undelivered events of all targets,
+// sorted by target.
+func (es *eventStores) List() []eventBacklogInfo {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	infos := []eventBacklogInfo{}
+	for _, s := range es.stores {
+		infos = append(infos, s.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Target < infos[j].Target })
+	return infos
+}
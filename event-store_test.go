@@ -0,0 +1,215 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyTarget - notification target failing while down.
+type flakyTarget struct {
+	mutex    *sync.Mutex
+	down     bool
+	attempts int
+	keys     []string
+}
+
+func (t *flakyTarget) Send(entry eventLogEntry) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.attempts++
+	if t.down {
+		return errors.New("target down")
+	}
+	t.keys = append(t.keys, entry.Key)
+	return nil
+}
+
+func (t *flakyTarget) Close() error {
+	return nil
+}
+
+func (t *flakyTarget) setDown(down bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.down = down
+}
+
+func (t *flakyTarget) received() ([]string, int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]string(nil), t.keys...), t.attempts
+}
+
+// waitFor - waits until cond holds, fails the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests events a target fails to receive are saved, along with events
+// queued behind them, and replayed in order once it recovers.
+func TestEventStoreReplay(t *testing.T) {
+	dir, e := ioutil.TempDir("", "minio-events-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	store, e := newEventStore("1:kafka", dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	target := &flakyTarget{mutex: &sync.Mutex{}, down: true}
+	qt := newStoredTarget("1:kafka", target, store)
+	defer close(qt.queue)
+
+	qt.queue <- eventLogEntry{Key: "bucket/a"}
+	qt.queue <- eventLogEntry{Key: "bucket/b"}
+	waitFor(t, "undelivered events saved", func() bool { return store.Len() == 2 })
+	if _, attempts := target.received(); attempts != 1 {
+		t.Fatalf("Expected events queued behind a failed one saved right away, got %d attempts", attempts)
+	}
+	if info := store.Info(); info.Events != 2 || info.LastError != "target down" {
+		t.Fatalf("Unexpected backlog info %+v", info)
+	}
+
+	// Saved events survive a restart.
+	reopened, e := newEventStore("1:kafka", dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	entries, truncated, e := reopened.List(1)
+	if e != nil || !truncated || len(entries) != 1 || entries[0].Key != "bucket/a" {
+		t.Fatalf("Expected the oldest event listed, got %v %v %v", entries, truncated, e)
+	}
+
+	// Replays stop at the first failure.
+	qt.Replay()
+	waitFor(t, "failed replay", func() bool { return store.Info().LastReplay != time.Time{} })
+	if store.Len() != 2 {
+		t.Fatalf("Expected events kept while the target is down, got %d", store.Len())
+	}
+
+	target.setDown(false)
+	qt.Replay()
+	waitFor(t, "replay", func() bool { return store.Len() == 0 })
+	qt.queue <- eventLogEntry{Key: "bucket/c"}
+	waitFor(t, "delivery", func() bool {
+		keys, _ := target.received()
+		return len(keys) == 3
+	})
+	if keys, _ := target.received(); !reflect.DeepEqual(keys, []string{"bucket/a", "bucket/b", "bucket/c"}) {
+		t.Fatalf("Expected events delivered in order, got %v", keys)
+	}
+	if info := store.Info(); info.ReplayedEvents != 2 {
+		t.Fatalf("Expected two replayed events, got %+v", info)
+	}
+
+	for _, key := range []string{"bucket/d", "bucket/e"} {
+		if e = store.Put(eventLogEntry{Key: key}); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if removed, e := store.Discard(); e != nil || removed != 2 || store.Len() != 0 {
+		t.Fatalf("Expected two events discarded, got %d, %v", removed, e)
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 0 {
+		t.Fatalf("Expected no saved events left, got %d", len(fis))
+	}
+}
+
+// Tests targets which cannot be initialized at start, like a Kafka
+// broker which is down, save events and deliver them once they can.
+func TestUnreachableTarget(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-events-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+	savedConfig := serverConfig
+	defer func() { serverConfig = savedConfig }()
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Address nothing listens on.
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	broker := listener.Addr().String()
+	listener.Close()
+	serverConfig.SetKafkaNotifyByID("1", kafkaNotify{Enable: true, Brokers: []string{broker}, Topic: "events"})
+
+	targets := newConfigNotificationTargets()
+	defer func() {
+		for targetID, qt := range targets {
+			close(qt.queue)
+			delete(globalEventStores.stores, targetID)
+		}
+	}()
+	qt, ok := targets["1:kafka"]
+	if !ok {
+		t.Fatalf("Expected unreachable target to be registered, got %v", targets)
+	}
+	qt.queue <- eventLogEntry{Key: "bucket/a"}
+	waitFor(t, "undelivered event saved", func() bool { return qt.store.Len() == 1 })
+
+	// Once initialized, the target receives saved events.
+	recovered := &flakyTarget{mutex: &sync.Mutex{}}
+	reachable := false
+	pending := &pendingTarget{newTarget: func() (notificationTarget, error) {
+		if !reachable {
+			return nil, errors.New("target down")
+		}
+		return recovered, nil
+	}}
+	dir, e := ioutil.TempDir("", "minio-events-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	store, e := newEventStore("2:kafka", dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	stored := newStoredTarget("2:kafka", pending, store)
+	defer close(stored.queue)
+	stored.queue <- eventLogEntry{Key: "bucket/b"}
+	waitFor(t, "undelivered event saved", func() bool { return store.Len() == 1 })
+	reachable = true
+	stored.Replay()
+	waitFor(t, "replay", func() bool { return store.Len() == 0 })
+	if keys, _ := recovered.received(); !reflect.DeepEqual(keys, []string{"bucket/b"}) {
+		t.Fatalf("Expected saved event delivered, got %v", keys)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Keys of buckets registered in etcd.
+const etcdFederationPrefix = "minio/federation/buckets/"
+
+// etcdKeyValue - key and value, base64 encoded by the gateway.
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// etcdRangeRequest - keys from Key up to RangeEnd, only Key if
+// RangeEnd is empty.
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+// etcdRangeResponse - keys found by a range request.
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+// etcdCompare - condition of a transaction.
+type etcdCompare struct {
+	Key            []byte `json:"key"`
+	Result         string `json:"result"`
+	Target         string `json:"target"`
+	CreateRevision string `json:"create_revision,omitempty"`
+	Value          []byte `json:"value,omitempty"`
+}
+
+// etcdRequestOp - operation of a transaction.
+type etcdRequestOp struct {
+	RequestPut         *etcdKeyValue     `json:"request_put,omitempty"`
+	RequestRange       *etcdRangeRequest `json:"request_range,omitempty"`
+	RequestDeleteRange *etcdRangeRequest `json:"request_delete_range,omitempty"`
+}
+
+// etcdTxnRequest - operations applied if all conditions hold, the
+// failure operations otherwise.
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdRequestOp `json:"success"`
+	Failure []etcdRequestOp `json:"failure,omitempty"`
+}
+
+// etcdTxnResponse - result of a transaction.
+type etcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"`
+	Responses []struct {
+		ResponseRange *etcdRangeResponse `json:"response_range"`
+	} `json:"responses"`
+}
+
+// etcdBucketDirectory - directory kept in etcd, through its v3 JSON
+// gateway. Registrations are atomic, the first deployment registering
+// a bucket owns it.
+type etcdBucketDirectory struct {
+	endpoints  []string
+	httpClient *http.Client
+}
+
+// newEtcdBucketDirectory - returns directory kept by the etcd cluster
+// at endpoints.
+func newEtcdBucketDirectory(endpoints []string) etcdBucketDirectory {
+	return etcdBucketDirectory{
+		endpoints:  endpoints,
+		httpClient: &http.Client{Timeout: federationClientTimeout},
+	}
+}
+
+// call - sends request to the gateway API, trying endpoints in order
+// until one is reachable, and decodes its response into reply.
+func (d etcdBucketDirectory) call(api string, request, reply interface{}) error {
+	data, e := json.Marshal(request)
+	if e != nil {
+		return e
+	}
+	for _, endpoint := range d.endpoints {
+		var resp *http.Response
+		resp, e = d.httpClient.Post(strings.TrimRight(endpoint, "/")+"/v3/kv/"+api, "application/json", bytes.NewReader(data))
+		if e != nil {
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+			return fmt.Errorf("etcd %s returned %s: %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+		}
+		return json.NewDecoder(io.LimitReader(resp.Body, maxFederationMetaSize)).Decode(reply)
+	}
+	return e
+}
+
+// key - returns key of bucket.
+func (d etcdBucketDirectory) key(bucket string) []byte {
+	return []byte(etcdFederationPrefix + bucket)
+}
+
+// get - returns registered bucket and its saved value, nil if it is
+// not registered.
+func (d etcdBucketDirectory) get(bucket string) (*federatedBucket, []byte, error) {
+	reply := etcdRangeResponse{}
+	if e := d.call("range", etcdRangeRequest{Key: d.key(bucket)}, &reply); e != nil {
+		return nil, nil, e
+	}
+	if len(reply.Kvs) == 0 {
+		return nil, nil, nil
+	}
+	entry := &federatedBucket{}
+	if e := json.Unmarshal(reply.Kvs[0].Value, entry); e != nil {
+		return nil, nil, e
+	}
+	return entry, reply.Kvs[0].Value, nil
+}
+
+func (d etcdBucketDirectory) Register(bucket, endpoint string) error {
+	value, e := json.Marshal(federatedBucket{Bucket: bucket, Endpoint: endpoint, Created: time.Now().UTC()})
+	if e != nil {
+		return e
+	}
+	key := d.key(bucket)
+	reply := etcdTxnResponse{}
+	if e = d.call("txn", etcdTxnRequest{
+		// Keys which were never created have a create revision of 0.
+		Compare: []etcdCompare{{Key: key, Result: "EQUAL", Target: "CREATE", CreateRevision: "0"}},
+		Success: []etcdRequestOp{{RequestPut: &etcdKeyValue{Key: key, Value: value}}},
+		Failure: []etcdRequestOp{{RequestRange: &etcdRangeRequest{Key: key}}},
+	}, &reply); e != nil {
+		return e
+	}
+	if reply.Succeeded {
+		return nil
+	}
+	// Already registered, by this deployment or another one.
+	for _, resp := range reply.Responses {
+		if resp.ResponseRange == nil || len(resp.ResponseRange.Kvs) == 0 {
+			continue
+		}
+		entry := federatedBucket{}
+		if e = json.Unmarshal(resp.ResponseRange.Kvs[0].Value, &entry); e != nil {
+			return e
+		}
+		if entry.Endpoint == endpoint {
+			return nil
+		}
+	}
+	return errBucketOwnedElsewhere
+}
+
+func (d etcdBucketDirectory) Unregister(bucket, endpoint string) error {
+	entry, value, e := d.get(bucket)
+	if e != nil || entry == nil {
+		return e
+	}
+	if entry.Endpoint != endpoint {
+		return errBucketOwnedElsewhere
+	}
+	// Deleted only if it was not registered again meanwhile.
+	key := d.key(bucket)
+	reply := etcdTxnResponse{}
+	if e = d.call("txn", etcdTxnRequest{
+		Compare: []etcdCompare{{Key: key, Result: "EQUAL", Target: "VALUE", Value: value}},
+		Success: []etcdRequestOp{{RequestDeleteRange: &etcdRangeRequest{Key: key}}},
+	}, &reply); e != nil {
+		return e
+	}
+	if !reply.Succeeded {
+		return errBucketOwnedElsewhere
+	}
+	return nil
+}
+
+func (d etcdBucketDirectory) Lookup(bucket string) (string, error) {
+	entry, _, e := d.get(bucket)
+	if e != nil || entry == nil {
+		return "", e
+	}
+	return entry.Endpoint, nil
+}
+
+func (d etcdBucketDirectory) List() ([]federatedBucket, error) {
+	// All keys with the prefix, up to the prefix with its last byte
+	// incremented.
+	prefix := []byte(etcdFederationPrefix)
+	rangeEnd := append([]byte{}, prefix...)
+	rangeEnd[len(rangeEnd)-1]++
+	reply := etcdRangeResponse{}
+	if e := d.call("range", etcdRangeRequest{Key: prefix, RangeEnd: rangeEnd}, &reply); e != nil {
+		return nil, e
+	}
+	var entries []federatedBucket
+	for _, kv := range reply.Kvs {
+		entry := federatedBucket{}
+		if e := json.Unmarshal(kv.Value, &entry); e != nil {
+			return nil, e
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
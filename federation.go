@@ -0,0 +1,637 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	router "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Directories of bucket owners.
+	federationDirectoryBuiltin = "builtin"
+	federationDirectoryEtcd    = "etcd"
+
+	// Ways requests for buckets of other deployments are served.
+	federationModeProxy    = "proxy"
+	federationModeRedirect = "redirect"
+
+	// Header set on requests forwarded to the deployment owning their
+	// bucket, they are served there and never forwarded again.
+	federationForwardedHeader = "X-Minio-Federation-Forwarded"
+
+	// Owners of buckets are cached for this long, a bucket recreated
+	// on another deployment is routed there once its owner expires.
+	federationLookupTTL = 5 * time.Second
+
+	// Built-in directory, saved by the deployment hosting it.
+	federationMetaPath      = ".federation/buckets.json"
+	maxFederationMetaSize   = 16 * 1024 * 1024
+	federationRPCPath       = reservedBucket + "/federation"
+	federationClientTimeout = 10 * time.Second
+)
+
+var (
+	errBucketOwnedElsewhere   = errors.New("Bucket is owned by another federated deployment")
+	errFederationNoEndpoint   = errors.New("Federation endpoint cannot be empty")
+	errFederationNotDirectory = errors.New("Deployment does not host the federation directory")
+)
+
+// federationConfig - federation of independent deployments presenting
+// a single namespace, buckets are registered in a shared directory
+// and requests for buckets of other deployments are proxied or
+// redirected to them. Federated deployments must share credentials.
+type federationConfig struct {
+	Enable bool `json:"enable"`
+	// Endpoint clients reach this deployment at, registered as owner
+	// of its buckets, for example https://minio1.example.com:9000.
+	Endpoint string `json:"endpoint"`
+	// Directory of bucket owners, "builtin" or "etcd".
+	Directory string `json:"directory"`
+	// Endpoint of the deployment hosting the built-in directory,
+	// empty if this deployment hosts it.
+	DirectoryEndpoint string `json:"directoryEndpoint"`
+	// Endpoints of the etcd v3 JSON gateway, tried in order.
+	EtcdEndpoints []string `json:"etcdEndpoints"`
+	// Requests for buckets of other deployments are either served
+	// through this deployment, "proxy", or redirected, "redirect".
+	Mode string `json:"mode"`
+}
+
+// newFederationConfig - federation configuration for fresh and
+// migrated configs.
+func newFederationConfig() federationConfig {
+	return federationConfig{
+		Directory:     federationDirectoryBuiltin,
+		EtcdEndpoints: []string{},
+		Mode:          federationModeProxy,
+	}
+}
+
+// federatedBucket - bucket registered in the federation directory.
+type federatedBucket struct {
+	Bucket   string    `json:"bucket"`
+	Endpoint string    `json:"endpoint"`
+	Created  time.Time `json:"created"`
+}
+
+// bucketDirectory - shared directory of the deployments owning
+// buckets.
+type bucketDirectory interface {
+	// Register - registers endpoint as owner of bucket, fails with
+	// errBucketOwnedElsewhere if another endpoint owns it.
+	Register(bucket, endpoint string) error
+	// Unregister - removes bucket owned by endpoint.
+	Unregister(bucket, endpoint string) error
+	// Lookup - returns owner of bucket, empty if not registered.
+	Lookup(bucket string) (string, error)
+	// List - returns all registered buckets.
+	List() ([]federatedBucket, error)
+}
+
+// federationOwner - cached owner of a bucket.
+type federationOwner struct {
+	endpoint string
+	expires  time.Time
+}
+
+// federation - directory and cached owners of buckets of this
+// deployment.
+type federation struct {
+	config    federationConfig
+	endpoint  string
+	directory bucketDirectory
+	mutex     *sync.Mutex
+	owners    map[string]federationOwner
+}
+
+// Global federation, nil unless federation is enabled.
+var globalFederation *federation
+
+// normalizeFederationEndpoint - returns endpoint without trailing
+// slashes, endpoints are compared as strings.
+func normalizeFederationEndpoint(endpoint string) string {
+	return strings.TrimRight(endpoint, "/")
+}
+
+// initFederation - joins the federation and registers the buckets of
+// this deployment.
+func initFederation(o objectAPI) {
+	config := serverConfig.GetFederation()
+	if !config.Enable {
+		return
+	}
+	f, err := newFederation(config, o)
+	fatalIf(err.Trace(config.Endpoint), "Unable to initialize federation.", nil)
+	globalFederation = f
+
+	// Buckets created before federation was enabled, or while the
+	// directory was unreachable, are registered now.
+	buckets, err := o.ListBuckets()
+	if err != nil {
+		errorIf(err.Trace(), "Unable to list buckets to register.", nil)
+		return
+	}
+	for _, bucket := range buckets {
+		if e := f.register(bucket.Name); e != nil {
+			errorIf(probe.NewError(e).Trace(bucket.Name), "Unable to register bucket in federation directory.", nil)
+		}
+	}
+}
+
+// newFederation - returns federation of config, with the directory it
+// selects.
+func newFederation(config federationConfig, o objectAPI) (*federation, *probe.Error) {
+	if config.Endpoint == "" {
+		return nil, probe.NewError(errFederationNoEndpoint)
+	}
+	if e := validFederationMode(config.Mode); e != nil {
+		return nil, probe.NewError(e)
+	}
+	f := &federation{
+		config:   config,
+		endpoint: normalizeFederationEndpoint(config.Endpoint),
+		mutex:    &sync.Mutex{},
+		owners:   make(map[string]federationOwner),
+	}
+	switch config.Directory {
+	case federationDirectoryEtcd:
+		if len(config.EtcdEndpoints) == 0 {
+			return nil, probe.NewError(errors.New("Federation etcd endpoints cannot be empty"))
+		}
+		f.directory = newEtcdBucketDirectory(config.EtcdEndpoints)
+	case federationDirectoryBuiltin:
+		directoryEndpoint := normalizeFederationEndpoint(config.DirectoryEndpoint)
+		if directoryEndpoint != "" && directoryEndpoint != f.endpoint {
+			f.directory = newRemoteBucketDirectory(directoryEndpoint)
+			break
+		}
+		directory, e := newLocalBucketDirectory(o)
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		f.directory = directory
+	default:
+		return nil, probe.NewError(validFederationDirectory(config.Directory))
+	}
+	return f, nil
+}
+
+// owner - returns endpoint of the deployment owning bucket, empty if
+// it is not registered.
+func (f *federation) owner(bucket string) (string, error) {
+	f.mutex.Lock()
+	owner, ok := f.owners[bucket]
+	f.mutex.Unlock()
+	if ok && time.Now().UTC().Before(owner.expires) {
+		return owner.endpoint, nil
+	}
+	endpoint, e := f.directory.Lookup(bucket)
+	if e != nil {
+		return "", e
+	}
+	endpoint = normalizeFederationEndpoint(endpoint)
+	f.setOwner(bucket, endpoint)
+	return endpoint, nil
+}
+
+// setOwner - caches endpoint as owner of bucket.
+func (f *federation) setOwner(bucket, endpoint string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.owners[bucket] = federationOwner{
+		endpoint: endpoint,
+		expires:  time.Now().UTC().Add(federationLookupTTL),
+	}
+}
+
+// register - registers bucket as owned by this deployment.
+func (f *federation) register(bucket string) error {
+	if e := f.directory.Register(bucket, f.endpoint); e != nil {
+		return e
+	}
+	f.setOwner(bucket, f.endpoint)
+	return nil
+}
+
+// unregister - removes bucket of this deployment from the directory.
+func (f *federation) unregister(bucket string) error {
+	if e := f.directory.Unregister(bucket, f.endpoint); e != nil {
+		return e
+	}
+	f.setOwner(bucket, "")
+	return nil
+}
+
+// remoteBuckets - returns buckets owned by other deployments.
+func (f *federation) remoteBuckets() ([]BucketInfo, error) {
+	entries, e := f.directory.List()
+	if e != nil {
+		return nil, e
+	}
+	var buckets []BucketInfo
+	for _, entry := range entries {
+		if normalizeFederationEndpoint(entry.Endpoint) == f.endpoint {
+			continue
+		}
+		buckets = append(buckets, BucketInfo{Name: entry.Bucket, Created: entry.Created})
+	}
+	return buckets, nil
+}
+
+// mergeFederatedBuckets - returns local buckets with buckets of other
+// deployments, sorted by name.
+func mergeFederatedBuckets(local, remote []BucketInfo) []BucketInfo {
+	seen := make(map[string]bool)
+	var buckets []BucketInfo
+	for _, bucket := range append(local, remote...) {
+		if seen[bucket.Name] {
+			continue
+		}
+		seen[bucket.Name] = true
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Name < buckets[j].Name })
+	return buckets
+}
+
+/// Routing of requests to bucket owners.
+
+// federationHandler - proxies or redirects requests for buckets owned
+// by other deployments to them.
+type federationHandler struct {
+	handler http.Handler
+}
+
+// setFederationHandler - returns handler routing requests to the
+// deployments owning their bucket.
+func setFederationHandler(h http.Handler) http.Handler {
+	return federationHandler{handler: h}
+}
+
+func (h federationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f := globalFederation
+	if f == nil || r.Header.Get(federationForwardedHeader) != "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	// Skip the first element which is usually '/' and split the rest.
+	splits := strings.SplitN(r.URL.Path[1:], "/", 2)
+	bucketName := splits[0]
+	// Creating a bucket registers it, buckets of other deployments
+	// fail to be created here.
+	isMakeBucket := r.Method == "PUT" && (len(splits) == 1 || splits[1] == "") && len(r.URL.Query()) == 0
+	if bucketName == "" || "/"+bucketName == reservedBucket || isMakeBucket {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	owner, e := f.owner(bucketName)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucketName), "Unable to look up bucket owner.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if owner == "" || owner == f.endpoint {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	target, e := url.Parse(owner)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucketName, owner), "Invalid bucket owner endpoint.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if f.config.Mode == federationModeRedirect {
+		http.Redirect(w, r, owner+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		// The Host header and the path of the request are kept as
+		// sent, signatures of clients stay valid on the owner.
+		Director: func(req *http.Request) {
+			restoreVirtualHostPath(req)
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Header.Set(federationForwardedHeader, f.endpoint)
+		},
+		// Objects are streamed as they are read.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, e error) {
+			errorIf(probe.NewError(e).Trace(bucketName, owner), "Unable to proxy request to bucket owner.", nil)
+			writeErrorResponse(w, req, ErrInternalError, req.URL.Path)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+/// Built-in directory.
+
+// localBucketDirectory - directory hosted by this deployment, saved in
+// minioMetaVolume.
+type localBucketDirectory struct {
+	objAPI  objectAPI
+	mutex   *sync.Mutex
+	buckets map[string]federatedBucket
+}
+
+// newLocalBucketDirectory - returns directory saved by o, empty if
+// none is saved yet.
+func newLocalBucketDirectory(o objectAPI) (*localBucketDirectory, error) {
+	d := &localBucketDirectory{
+		objAPI:  o,
+		mutex:   &sync.Mutex{},
+		buckets: make(map[string]federatedBucket),
+	}
+	data, e := o.readMetaFile(federationMetaPath, maxFederationMetaSize)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return d, nil
+		}
+		return nil, e
+	}
+	if e = json.Unmarshal(data, &d.buckets); e != nil {
+		return nil, e
+	}
+	return d, nil
+}
+
+// save - saves registered buckets, called with mutex held.
+func (d *localBucketDirectory) save() error {
+	data, e := json.Marshal(d.buckets)
+	if e != nil {
+		return e
+	}
+	if e = d.objAPI.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return d.objAPI.writeMetaFile(federationMetaPath, data)
+}
+
+func (d *localBucketDirectory) Register(bucket, endpoint string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if entry, ok := d.buckets[bucket]; ok {
+		if entry.Endpoint != endpoint {
+			return errBucketOwnedElsewhere
+		}
+		return nil
+	}
+	d.buckets[bucket] = federatedBucket{Bucket: bucket, Endpoint: endpoint, Created: time.Now().UTC()}
+	if e := d.save(); e != nil {
+		delete(d.buckets, bucket)
+		return e
+	}
+	return nil
+}
+
+func (d *localBucketDirectory) Unregister(bucket, endpoint string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	entry, ok := d.buckets[bucket]
+	if !ok {
+		return nil
+	}
+	if entry.Endpoint != endpoint {
+		return errBucketOwnedElsewhere
+	}
+	delete(d.buckets, bucket)
+	if e := d.save(); e != nil {
+		d.buckets[bucket] = entry
+		return e
+	}
+	return nil
+}
+
+func (d *localBucketDirectory) Lookup(bucket string) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.buckets[bucket].Endpoint, nil
+}
+
+func (d *localBucketDirectory) List() ([]federatedBucket, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var entries []federatedBucket
+	for _, entry := range d.buckets {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Bucket < entries[j].Bucket })
+	return entries, nil
+}
+
+// remoteBucketDirectory - built-in directory hosted by another
+// deployment, requests are signed with the storage RPC secrets.
+type remoteBucketDirectory struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// newRemoteBucketDirectory - returns client of the directory hosted at
+// endpoint.
+func newRemoteBucketDirectory(endpoint string) remoteBucketDirectory {
+	return remoteBucketDirectory{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: federationClientTimeout},
+	}
+}
+
+// do - sends a request for method, signed with args, and decodes its
+// JSON response into reply if any.
+func (d remoteBucketDirectory) do(httpMethod, method, bucket string, query url.Values, reply interface{}, args ...string) error {
+	reqURL := d.endpoint + federationRPCPath + "/buckets"
+	if bucket != "" {
+		reqURL += "/" + bucket
+	}
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	req, e := http.NewRequest(httpMethod, reqURL, nil)
+	if e != nil {
+		return e
+	}
+	signRPC(serverConfig.GetRPCSecrets()[0], method, args...).setHeaders(req.Header)
+	resp, e := d.httpClient.Do(req)
+	if e != nil {
+		return e
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return errBucketOwnedElsewhere
+	default:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Federation directory %s returned %s: %s", d.endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxFederationMetaSize)).Decode(reply)
+}
+
+func (d remoteBucketDirectory) Register(bucket, endpoint string) error {
+	return d.do("PUT", "Federation.Register", bucket, url.Values{"endpoint": {endpoint}}, nil, bucket, endpoint)
+}
+
+func (d remoteBucketDirectory) Unregister(bucket, endpoint string) error {
+	return d.do("DELETE", "Federation.Unregister", bucket, url.Values{"endpoint": {endpoint}}, nil, bucket, endpoint)
+}
+
+func (d remoteBucketDirectory) Lookup(bucket string) (string, error) {
+	entry := federatedBucket{}
+	if e := d.do("GET", "Federation.Lookup", bucket, nil, &entry, bucket); e != nil {
+		return "", e
+	}
+	return entry.Endpoint, nil
+}
+
+func (d remoteBucketDirectory) List() ([]federatedBucket, error) {
+	var entries []federatedBucket
+	if e := d.do("GET", "Federation.List", "", nil, &entries); e != nil {
+		return nil, e
+	}
+	return entries, nil
+}
+
+// federationServer - serves the built-in directory to other
+// deployments.
+type federationServer struct {
+	auth *rpcAuthenticator
+}
+
+// authenticate - verifies request for method with args is signed by a
+// federated deployment.
+func (s federationServer) authenticate(r *http.Request, method string, args ...string) error {
+	auth := rpcAuthFromHeaders(r.Header)
+	if err := s.auth.Verify(serverConfig.GetRPCSecrets(), auth, method, args...); err != nil {
+		log.WithFields(logrus.Fields{
+			"method":   method,
+			"secretID": auth.SecretID,
+		}).Errorf("Federation authentication failed with %s", err)
+		return errRPCAuthFailed
+	}
+	return nil
+}
+
+// directory - returns the built-in directory hosted by this
+// deployment.
+func (s federationServer) directory() (*localBucketDirectory, error) {
+	if f := globalFederation; f != nil {
+		if directory, ok := f.directory.(*localBucketDirectory); ok {
+			return directory, nil
+		}
+	}
+	return nil, errFederationNotDirectory
+}
+
+// serve - authenticates request for method and replies with the JSON
+// result of fn.
+func (s federationServer) serve(w http.ResponseWriter, r *http.Request, method string, fn func(d *localBucketDirectory) (interface{}, error), args ...string) {
+	if e := s.authenticate(r, method, args...); e != nil {
+		http.Error(w, e.Error(), http.StatusForbidden)
+		return
+	}
+	directory, e := s.directory()
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusNotFound)
+		return
+	}
+	reply, e := fn(directory)
+	if e != nil {
+		status := http.StatusInternalServerError
+		if e == errBucketOwnedElsewhere {
+			status = http.StatusConflict
+		}
+		http.Error(w, e.Error(), status)
+		return
+	}
+	data, e := json.Marshal(reply)
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// registerFederationRouter - register router of the built-in
+// federation directory.
+func registerFederationRouter(mux *router.Router) {
+	s := federationServer{auth: newRPCAuthenticator()}
+	federationRouter := mux.NewRoute().PathPrefix(federationRPCPath).Subrouter()
+
+	// List
+	federationRouter.Methods("GET").Path("/buckets").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, "Federation.List", func(d *localBucketDirectory) (interface{}, error) {
+			return d.List()
+		})
+	})
+	// Lookup
+	federationRouter.Methods("GET").Path("/buckets/{bucket}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := router.Vars(r)["bucket"]
+		s.serve(w, r, "Federation.Lookup", func(d *localBucketDirectory) (interface{}, error) {
+			endpoint, e := d.Lookup(bucket)
+			return federatedBucket{Bucket: bucket, Endpoint: endpoint}, e
+		}, bucket)
+	})
+	// Register
+	federationRouter.Methods("PUT").Path("/buckets/{bucket}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := router.Vars(r)["bucket"]
+		endpoint := r.URL.Query().Get("endpoint")
+		s.serve(w, r, "Federation.Register", func(d *localBucketDirectory) (interface{}, error) {
+			return struct{}{}, d.Register(bucket, endpoint)
+		}, bucket, endpoint)
+	})
+	// Unregister
+	federationRouter.Methods("DELETE").Path("/buckets/{bucket}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := router.Vars(r)["bucket"]
+		endpoint := r.URL.Query().Get("endpoint")
+		s.serve(w, r, "Federation.Unregister", func(d *localBucketDirectory) (interface{}, error) {
+			return struct{}{}, d.Unregister(bucket, endpoint)
+		}, bucket, endpoint)
+	})
+}
+
+func validFederationDirectory(value string) error {
+	switch value {
+	case federationDirectoryBuiltin, federationDirectoryEtcd:
+		return nil
+	}
+	return fmt.Errorf("Unknown federation directory %q, expected %q or %q", value, federationDirectoryBuiltin, federationDirectoryEtcd)
+}
+
+func validFederationMode(value string) error {
+	switch value {
+	case federationModeProxy, federationModeRedirect:
+		return nil
+	}
+	return fmt.Errorf("Unknown federation mode %q, expected %q or %q", value, federationModeProxy, federationModeRedirect)
+}
@@ -0,0 +1,345 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	router "github.com/gorilla/mux"
+)
+
+// fakeEtcdGateway - serves range and txn requests of the etcd v3 JSON
+// gateway used by the etcd directory.
+type fakeEtcdGateway struct {
+	mutex *sync.Mutex
+	kvs   map[string][]byte
+}
+
+func (g *fakeEtcdGateway) rangeKeys(req etcdRangeRequest) *etcdRangeResponse {
+	resp := &etcdRangeResponse{}
+	var keys []string
+	for key := range g.kvs {
+		if key == string(req.Key) || (len(req.RangeEnd) > 0 && key >= string(req.Key) && key < string(req.RangeEnd)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resp.Kvs = append(resp.Kvs, etcdKeyValue{Key: []byte(key), Value: g.kvs[key]})
+	}
+	return resp
+}
+
+func (g *fakeEtcdGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var reply interface{}
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		req := etcdRangeRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		reply = g.rangeKeys(req)
+	case "/v3/kv/txn":
+		req := etcdTxnRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		succeeded := true
+		for _, cmp := range req.Compare {
+			value, ok := g.kvs[string(cmp.Key)]
+			switch cmp.Target {
+			case "CREATE":
+				succeeded = succeeded && !ok && cmp.CreateRevision == "0"
+			case "VALUE":
+				succeeded = succeeded && ok && bytes.Equal(value, cmp.Value)
+			}
+		}
+		ops := req.Failure
+		if succeeded {
+			ops = req.Success
+		}
+		resp := etcdTxnResponse{Succeeded: succeeded}
+		for _, op := range ops {
+			switch {
+			case op.RequestPut != nil:
+				g.kvs[string(op.RequestPut.Key)] = op.RequestPut.Value
+			case op.RequestDeleteRange != nil:
+				delete(g.kvs, string(op.RequestDeleteRange.Key))
+			case op.RequestRange != nil:
+				resp.Responses = append(resp.Responses, struct {
+					ResponseRange *etcdRangeResponse `json:"response_range"`
+				}{g.rangeKeys(*op.RequestRange)})
+			}
+		}
+		reply = resp
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(reply)
+}
+
+// testBucketDirectory - tests ownership of buckets in directory.
+func testBucketDirectory(t *testing.T, name string, directory bucketDirectory) {
+	if e := directory.Register("photos", "http://minio1:9000"); e != nil {
+		t.Fatalf("%s: %s", name, e)
+	}
+	// Registering again is a no-op for the owner only.
+	if e := directory.Register("photos", "http://minio1:9000"); e != nil {
+		t.Fatalf("%s: %s", name, e)
+	}
+	if e := directory.Register("photos", "http://minio2:9000"); e != errBucketOwnedElsewhere {
+		t.Fatalf("%s: Expected %v, got %v", name, errBucketOwnedElsewhere, e)
+	}
+	if e := directory.Register("videos", "http://minio2:9000"); e != nil {
+		t.Fatalf("%s: %s", name, e)
+	}
+	if owner, e := directory.Lookup("photos"); e != nil || owner != "http://minio1:9000" {
+		t.Fatalf("%s: Unexpected owner %q %v", name, owner, e)
+	}
+	if owner, e := directory.Lookup("missing"); e != nil || owner != "" {
+		t.Fatalf("%s: Unexpected owner %q %v", name, owner, e)
+	}
+	entries, e := directory.List()
+	if e != nil || len(entries) != 2 || entries[0].Bucket != "photos" || entries[1].Endpoint != "http://minio2:9000" {
+		t.Fatalf("%s: Unexpected buckets %+v %v", name, entries, e)
+	}
+
+	// Only owners unregister their buckets, the name can be reused
+	// afterwards.
+	if e = directory.Unregister("videos", "http://minio1:9000"); e != errBucketOwnedElsewhere {
+		t.Fatalf("%s: Expected %v, got %v", name, errBucketOwnedElsewhere, e)
+	}
+	if e = directory.Unregister("videos", "http://minio2:9000"); e != nil {
+		t.Fatalf("%s: %s", name, e)
+	}
+	if e = directory.Register("videos", "http://minio1:9000"); e != nil {
+		t.Fatalf("%s: %s", name, e)
+	}
+}
+
+// Tests all directories agree on bucket ownership.
+func TestBucketDirectories(t *testing.T) {
+	savedConfig, savedFederation := serverConfig, globalFederation
+	defer func() { serverConfig, globalFederation = savedConfig, savedFederation }()
+	serverConfig = &serverConfigV5{
+		Credential: mustGenAccessKeys(),
+		RPC:        newRPCAuthConfig(),
+		rwMutex:    &sync.RWMutex{},
+	}
+
+	newDirectory := func() (*localBucketDirectory, objectAPI, string) {
+		dir, e := ioutil.TempDir("", "minio-federation-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		fs, e := newFS(dir)
+		if e != nil {
+			t.Fatal(e)
+		}
+		obj := newObjectLayer(fs)
+		directory, e := newLocalBucketDirectory(obj)
+		if e != nil {
+			t.Fatal(e)
+		}
+		return directory, obj, dir
+	}
+
+	local, obj, dir := newDirectory()
+	defer os.RemoveAll(dir)
+	testBucketDirectory(t, "builtin", local)
+	// The directory is saved.
+	reloaded, e := newLocalBucketDirectory(obj)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if owner, _ := reloaded.Lookup("videos"); owner != "http://minio1:9000" {
+		t.Fatalf("Expected saved directory, got owner %q", owner)
+	}
+
+	// Other deployments use the built-in directory over the network.
+	hosted, _, hostedDir := newDirectory()
+	defer os.RemoveAll(hostedDir)
+	globalFederation = &federation{directory: hosted}
+	mux := router.NewRouter()
+	registerFederationRouter(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	testBucketDirectory(t, "remote", newRemoteBucketDirectory(server.URL))
+
+	// Requests without a valid signature are rejected.
+	resp, e := http.Get(server.URL + federationRPCPath + "/buckets")
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected unsigned request to be forbidden, got %s", resp.Status)
+	}
+
+	etcd := httptest.NewServer(&fakeEtcdGateway{mutex: &sync.Mutex{}, kvs: make(map[string][]byte)})
+	defer etcd.Close()
+	// Unreachable endpoints are skipped.
+	testBucketDirectory(t, "etcd", newEtcdBucketDirectory([]string{"http://127.0.0.1:1", etcd.URL}))
+}
+
+// Tests requests for buckets of other deployments are proxied or
+// redirected to them.
+func TestFederationHandler(t *testing.T) {
+	savedFederation := globalFederation
+	defer func() { globalFederation = savedFederation }()
+
+	owner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-Host", r.Host)
+		w.Header().Set("X-Served-Forwarded", r.Header.Get(federationForwardedHeader))
+		w.Write([]byte("remote " + r.URL.RequestURI()))
+	}))
+	defer owner.Close()
+
+	directory := &localBucketDirectory{mutex: &sync.Mutex{}, buckets: map[string]federatedBucket{
+		"local":  {Bucket: "local", Endpoint: "http://minio1:9000"},
+		"remote": {Bucket: "remote", Endpoint: owner.URL + "/"},
+	}}
+	globalFederation = &federation{
+		config:    federationConfig{Mode: federationModeProxy},
+		endpoint:  "http://minio1:9000",
+		directory: directory,
+		mutex:     &sync.Mutex{},
+		owners:    make(map[string]federationOwner),
+	}
+	handler := setFederationHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local " + r.URL.RequestURI()))
+	}))
+
+	testCases := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"GET", "/", "local /"},
+		{"GET", "/local/object", "local /local/object"},
+		{"GET", "/unknown/object", "local /unknown/object"},
+		{"GET", "/remote/dir/object?uploads", "remote /remote/dir/object?uploads"},
+		{"DELETE", "/remote", "remote /remote"},
+		// Buckets are created locally, creation fails if they are
+		// registered elsewhere.
+		{"PUT", "/remote", "local /remote"},
+		{"GET", "/minio/admin/info", "local /minio/admin/info"},
+	}
+	for i, testCase := range testCases {
+		r := httptest.NewRequest(testCase.method, testCase.path, nil)
+		r.Host = "s3.example.com"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Body.String() != testCase.body {
+			t.Errorf("Test %d: Expected %q, got %q", i+1, testCase.body, w.Body.String())
+		}
+	}
+
+	// The owner sees the Host clients signed, and does not forward
+	// the request again.
+	r := httptest.NewRequest("GET", "/remote/object", nil)
+	r.Host = "s3.example.com"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("X-Served-Host") != "s3.example.com" || w.Header().Get("X-Served-Forwarded") != "http://minio1:9000" {
+		t.Fatalf("Unexpected proxied request headers %v", w.Header())
+	}
+	r = httptest.NewRequest("GET", "/remote/object", nil)
+	r.Header.Set(federationForwardedHeader, "http://minio2:9000")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Body.String() != "local /remote/object" {
+		t.Fatalf("Expected forwarded request served locally, got %q", w.Body.String())
+	}
+
+	globalFederation.config.Mode = federationModeRedirect
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/remote/object?versionId=1", nil))
+	if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != owner.URL+"/remote/object?versionId=1" {
+		t.Fatalf("Unexpected redirect %d %v", w.Code, w.Header())
+	}
+}
+
+// Tests virtual host style requests are proxied with the path sent by
+// clients, the owner moves the bucket into the path once.
+func TestFederationVirtualHost(t *testing.T) {
+	savedConfig, savedFederation := serverConfig, globalFederation
+	defer func() { serverConfig, globalFederation = savedConfig, savedFederation }()
+	serverConfig = &serverConfigV5{Domain: "s3.example.com", rwMutex: &sync.RWMutex{}}
+
+	owner := httptest.NewServer(setVirtualHostHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote " + r.URL.Path + " signed " + getSignedURLPath(r)))
+	})))
+	defer owner.Close()
+
+	globalFederation = &federation{
+		config:   federationConfig{Mode: federationModeProxy},
+		endpoint: "http://minio1:9000",
+		directory: &localBucketDirectory{mutex: &sync.Mutex{}, buckets: map[string]federatedBucket{
+			"remote": {Bucket: "remote", Endpoint: owner.URL + "/"},
+		}},
+		mutex:  &sync.Mutex{},
+		owners: make(map[string]federationOwner),
+	}
+	handler := setVirtualHostHandler(setFederationHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local " + r.URL.Path))
+	})))
+
+	testCases := []struct {
+		host string
+		path string
+		body string
+	}{
+		// Virtual host style.
+		{"remote.s3.example.com", "/dir/object", "remote /remote/dir/object signed /dir/object"},
+		{"remote.s3.example.com", "/", "remote /remote signed /"},
+		// Path style.
+		{"s3.example.com", "/remote/dir/object", "remote /remote/dir/object signed /remote/dir/object"},
+		{"local.s3.example.com", "/dir/object", "local /local/dir/object"},
+	}
+	for i, testCase := range testCases {
+		r := httptest.NewRequest("GET", testCase.path, nil)
+		r.Host = testCase.host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Body.String() != testCase.body {
+			t.Errorf("Test %d: Expected %q, got %q", i+1, testCase.body, w.Body.String())
+		}
+	}
+}
+
+// Tests buckets of other deployments are listed once, sorted by name.
+func TestMergeFederatedBuckets(t *testing.T) {
+	buckets := mergeFederatedBuckets(
+		[]BucketInfo{{Name: "b"}, {Name: "d"}},
+		[]BucketInfo{{Name: "c"}, {Name: "a"}, {Name: "b"}},
+	)
+	var names []string
+	for _, bucket := range buckets {
+		names = append(names, bucket.Name)
+	}
+	if len(names) != 4 || names[0] != "a" || names[3] != "d" {
+		t.Fatalf("Unexpected buckets %v", names)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Number of files listed at a time while migrating.
+const migrationListBatchSize = 1000
+
+// migrateFormat - migrates metadata of all files of all volumes of
+// storage to the current format, progress is called after each file.
+func migrateFormat(storage StorageAPI, migrator fileMigrator, dryRun bool, progress func(volume, path string, migrated bool, err error)) error {
+	vols, err := storage.ListVols()
+	if err != nil {
+		return err
+	}
+	for _, vol := range vols {
+		marker := ""
+		for {
+			files, eof, err := storage.ListFiles(vol.Name, "", marker, true, migrationListBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if file.Mode.IsDir() {
+					continue
+				}
+				migrated, err := migrator.migrateFile(vol.Name, file.Name, dryRun)
+				progress(vol.Name, file.Name, migrated, err)
+			}
+			if eof || len(files) == 0 {
+				break
+			}
+			marker = files[len(files)-1].Name
+		}
+	}
+	return nil
+}
+
+// Format migration status values.
+const (
+	migrationStatusIdle     = "idle"
+	migrationStatusRunning  = "running"
+	migrationStatusFinished = "finished"
+	migrationStatusFailed   = "failed"
+)
+
+// formatMigrationInfo - state of the last started format migration.
+type formatMigrationInfo struct {
+	Status    string    `json:"status"`
+	Format    string    `json:"format"`
+	DryRun    bool      `json:"dryRun"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Scanned   int64     `json:"scanned"`
+	// Number of files with metadata of an older format, rewritten
+	// unless DryRun is set.
+	Migrated  int64  `json:"migrated"`
+	Failed    int64  `json:"failed"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// formatMigration - runs at most one format migration at a time, in
+// background of a running server. Files are locked while migrated, and
+// metadata of older formats is read as the current one meanwhile.
+type formatMigration struct {
+	mutex *sync.Mutex
+	info  formatMigrationInfo
+}
+
+// Global format migration, driven by the admin API.
+var globalFormatMigration = &formatMigration{
+	mutex: &sync.Mutex{},
+	info:  formatMigrationInfo{Status: migrationStatusIdle},
+}
+
+// Info - returns state of the last started format migration.
+func (m *formatMigration) Info() formatMigrationInfo {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.info
+}
+
+// Start - starts migrating metadata of all files of storage in
+// background, files are only counted if dryRun is set.
+func (m *formatMigration) Start(storage StorageAPI, dryRun bool) *probe.Error {
+	migrator, ok := storage.(fileMigrator)
+	if !ok {
+		return probe.NewError(MigrationNotSupported{})
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.info.Status == migrationStatusRunning {
+		return probe.NewError(MigrationAlreadyRunning{})
+	}
+	m.info = formatMigrationInfo{
+		Status:    migrationStatusRunning,
+		Format:    xlFormatCurrent.String(),
+		DryRun:    dryRun,
+		StartTime: time.Now().UTC(),
+	}
+	go m.run(storage, migrator, dryRun)
+	return nil
+}
+
+// run - migrates all files, updating the state after each one.
+func (m *formatMigration) run(storage StorageAPI, migrator fileMigrator, dryRun bool) {
+	err := migrateFormat(storage, migrator, dryRun, func(volume, path string, migrated bool, err error) {
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+			}).Errorf("Format migration failed with %s", err)
+		}
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.info.Scanned++
+		if migrated {
+			m.info.Migrated++
+		}
+		if err != nil {
+			m.info.Failed++
+			m.info.LastError = err.Error()
+		}
+	})
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.info.Status = migrationStatusFinished
+	if err != nil {
+		errorIf(probe.NewError(err), "Unable to list files to migrate.", nil)
+		m.info.Status = migrationStatusFailed
+		m.info.LastError = err.Error()
+	}
+	m.info.EndTime = time.Now().UTC()
+}
@@ -0,0 +1,187 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/safe"
+)
+
+const (
+	// Direct writes are aligned to, and sized in multiples of, this
+	// many bytes, the logical block size of most disks.
+	directIOAlignment = 4096
+	// Data written directly is buffered up to this many bytes.
+	directIOBufferSize = 1024 * 1024 // 1MiB.
+)
+
+var errDirectIONotSupported = errors.New("Direct I/O is not supported on this platform")
+
+// diskIOConfig - how files are written to and read from local disks.
+type diskIOConfig struct {
+	// Files are written with O_DIRECT bypassing the page cache, so
+	// that data of dedicated storage nodes is not buffered twice.
+	// Filesystems not supporting it are written as usual.
+	DirectIO bool `json:"directIO"`
+	// Erasure coded files are read up to this many blocks ahead of the
+	// block being sent, decoding them in background. Zero decodes each
+	// block when it is sent.
+	ReadAhead int `json:"readAhead"`
+	// Encoding of erasure coded file metadata written, "json" if
+	// empty or "msgpack". Metadata of either encoding is read.
+	MetadataEncoding string `json:"metadataEncoding"`
+	// Files synced to disk before they are committed, "none" if
+	// empty, "metadata" or "full".
+	Durability string `json:"durability"`
+	// Percent of the space of each disk new files are not written
+	// into, 5 if zero. Reads and heals still work once disks are
+	// filled up to it.
+	ReservedSpace int `json:"reservedSpace"`
+}
+
+// diskIO - current disk I/O configuration, shared by all disks.
+type diskIO struct {
+	rwMutex *sync.RWMutex
+	config  diskIOConfig
+}
+
+// Global disk I/O configuration, configured at server start.
+var globalDiskIO = &diskIO{rwMutex: &sync.RWMutex{}}
+
+// Get - returns current configuration.
+func (d *diskIO) Get() diskIOConfig {
+	d.rwMutex.RLock()
+	defer d.rwMutex.RUnlock()
+	return d.config
+}
+
+// Set - sets configuration, applies to files created afterwards.
+func (d *diskIO) Set(config diskIOConfig) {
+	d.rwMutex.Lock()
+	defer d.rwMutex.Unlock()
+	d.config = config
+}
+
+// alignedBuffer - returns buffer of size bytes starting at an address
+// aligned to directIOAlignment.
+func alignedBuffer(size int) []byte {
+	buffer := make([]byte, size+directIOAlignment)
+	offset := int(uintptr(unsafe.Pointer(&buffer[0])) & (directIOAlignment - 1))
+	if offset != 0 {
+		offset = directIOAlignment - offset
+	}
+	return buffer[offset : offset+size]
+}
+
+// directFile - safe file written with O_DIRECT through an aligned
+// buffer. Writing falls back to the page cache if the filesystem does
+// not support direct I/O, and for the unaligned tail of the file.
+type directFile struct {
+	*safe.File
+	buffer []byte
+	// Bytes buffered.
+	n int
+	// O_DIRECT is set on the file.
+	direct bool
+	// Syncs the file before it is committed, if set.
+	sync func(*os.File) error
+}
+
+// newDirectFile - returns file writing to file with O_DIRECT, file is
+// written as is if O_DIRECT cannot be set.
+func newDirectFile(file *safe.File) *directFile {
+	f := &directFile{File: file}
+	if err := setDirectIO(file.File, true); err != nil {
+		log.WithFields(logrus.Fields{
+			"filePath": file.Name(),
+		}).Debugf("Direct I/O not enabled, %s", err)
+		return f
+	}
+	f.direct = true
+	f.buffer = alignedBuffer(directIOBufferSize)
+	return f
+}
+
+// Write - buffers p, full buffers are written directly.
+func (f *directFile) Write(p []byte) (int, error) {
+	if !f.direct {
+		return f.File.Write(p)
+	}
+	written := 0
+	for len(p) > 0 {
+		copied := copy(f.buffer[f.n:], p)
+		f.n += copied
+		written += copied
+		p = p[copied:]
+		if f.n == len(f.buffer) {
+			if err := f.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush - writes buffered data, falls back to writing through the
+// page cache if the filesystem rejects direct writes.
+func (f *directFile) flush() error {
+	data := f.buffer[:f.n]
+	if f.direct {
+		aligned := f.n &^ (directIOAlignment - 1)
+		n, err := f.File.Write(data[:aligned])
+		if err != nil {
+			if n != 0 || setDirectIO(f.File.File, false) != nil {
+				return err
+			}
+			log.WithFields(logrus.Fields{
+				"filePath": f.File.Name(),
+			}).Debugf("Direct write failed, writing through page cache, %s", err)
+		}
+		data = data[n:]
+		// The tail not aligned is written through the page cache.
+		if len(data) > 0 {
+			f.direct = false
+			if err = setDirectIO(f.File.File, false); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := f.File.Write(data)
+	f.n = 0
+	return err
+}
+
+// Close - writes buffered data and commits the file, synced first if
+// the file is to be.
+func (f *directFile) Close() error {
+	if f.n > 0 {
+		if err := f.flush(); err != nil {
+			return err
+		}
+	}
+	if f.sync != nil {
+		if err := f.sync(f.File.File); err != nil {
+			return err
+		}
+	}
+	return f.File.Close()
+}
@@ -0,0 +1,98 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+)
+
+// Tests files written directly, or through the page cache where
+// direct I/O is not supported, have the data written.
+func TestDirectIO(t *testing.T) {
+	if buffer := alignedBuffer(directIOAlignment); uintptr(unsafe.Pointer(&buffer[0]))%directIOAlignment != 0 {
+		t.Fatal("Expected buffer to be aligned")
+	}
+
+	directory, e := ioutil.TempDir("", "minio-directio")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = fs.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	globalDiskIO.Set(diskIOConfig{DirectIO: true})
+	defer globalDiskIO.Set(diskIOConfig{})
+
+	sizes := []int{0, 1, directIOAlignment, directIOBufferSize, directIOBufferSize + directIOAlignment + 1, 3*directIOBufferSize - 1}
+	for i, size := range sizes {
+		data := make([]byte, size)
+		for j := range data {
+			data[j] = byte(j % 251)
+		}
+		w, e := fs.CreateFile(context.Background(), "bucket", "object")
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, ok := w.(*directFile); !ok {
+			t.Fatalf("Test %d: Expected file to be written directly, got %T", i+1, w)
+		}
+		// Written in pieces not aligned to the buffer.
+		for written := 0; written < size; written += 1000 {
+			end := written + 1000
+			if end > size {
+				end = size
+			}
+			if _, e = w.Write(data[written:end]); e != nil {
+				t.Fatal(e)
+			}
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		read, e := ioutil.ReadFile(filepath.Join(directory, "bucket", "object"))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(read, data) {
+			t.Fatalf("Test %d: Unexpected data of %d bytes written", i+1, len(read))
+		}
+	}
+
+	// Aborted writes are removed.
+	w, e := fs.CreateFile(context.Background(), "bucket", "aborted")
+	if e != nil {
+		t.Fatal(e)
+	}
+	w.Write([]byte("data"))
+	if e = safeCloseAndRemove(w); e != nil {
+		t.Fatal(e)
+	}
+	if names, _ := ioutil.ReadDir(filepath.Join(directory, "bucket")); len(names) != 1 {
+		t.Fatalf("Expected aborted write to be removed, got %d files", len(names))
+	}
+}
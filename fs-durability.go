@@ -0,0 +1,83 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minio/minio/pkg/safe"
+)
+
+// Durability of files written to local disks, whether they are synced
+// to disk before the rename committing them.
+const (
+	// Files are committed as soon as written, their data is left to
+	// the page cache and may be lost in a crash.
+	durabilityNone = "none"
+	// Metadata files are synced, committed metadata survives a crash.
+	// Erasure coded parts carry the metadata of their file and are
+	// synced along.
+	durabilityMetadata = "metadata"
+	// All files are synced, committed files survive a crash.
+	durabilityFull = "full"
+)
+
+// validDurability - validates diskIO.durability, empty is none.
+func validDurability(value string) error {
+	switch value {
+	case "", durabilityNone, durabilityMetadata, durabilityFull:
+		return nil
+	}
+	return fmt.Errorf("Durability must be %s, %s or %s", durabilityNone, durabilityMetadata, durabilityFull)
+}
+
+// fileSyncer - returns function syncing a file at path written with
+// durability, nil if the file is not synced. Metadata is synced with
+// fsync, data of parts with fdatasync where supported.
+func fileSyncer(durability, path string) func(*os.File) error {
+	switch durability {
+	case durabilityFull:
+		if isMetadataFile(path) {
+			return (*os.File).Sync
+		}
+		return fdatasync
+	case durabilityMetadata:
+		if isMetadataFile(path) {
+			return (*os.File).Sync
+		}
+		if isErasurePart(path) {
+			return fdatasync
+		}
+	}
+	return nil
+}
+
+// syncedFile - safe file synced to disk before it is committed.
+type syncedFile struct {
+	*safe.File
+	sync func(*os.File) error
+}
+
+// Close - syncs and commits the file. The file is left to be removed
+// if it cannot be synced.
+func (f *syncedFile) Close() error {
+	if err := f.sync(f.File.File); err != nil {
+		return err
+	}
+	return f.File.Close()
+}
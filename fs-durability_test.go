@@ -0,0 +1,104 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests files are synced as their durability requires.
+func TestFileSyncer(t *testing.T) {
+	testCases := []struct {
+		durability string
+		path       string
+		synced     bool
+	}{
+		{"", "object/" + metadataFile, false},
+		{durabilityNone, "object/" + metadataFile, false},
+		{durabilityMetadata, "object/" + metadataFile, true},
+		{durabilityMetadata, "object/" + legacyMetadataFile, true},
+		{durabilityMetadata, "object/part.0", true},
+		{durabilityMetadata, "object/data", false},
+		{durabilityFull, "object/part.0", true},
+		{durabilityFull, "object/" + metadataFile, true},
+	}
+	for i, testCase := range testCases {
+		if synced := fileSyncer(testCase.durability, testCase.path) != nil; synced != testCase.synced {
+			t.Errorf("Test %d: %s %s expected synced %v", i+1, testCase.durability, testCase.path, testCase.synced)
+		}
+	}
+	if e := validDurability("always"); e == nil {
+		t.Fatal("Expected unknown durability to be invalid")
+	}
+}
+
+// Tests files synced before they are committed are written, and
+// aborted writes removed, written directly or not.
+func TestFileDurability(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-durability")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = fs.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	defer globalDiskIO.Set(diskIOConfig{})
+
+	data := bytes.Repeat([]byte("a"), directIOBufferSize+1)
+	for _, directIO := range []bool{false, true} {
+		globalDiskIO.Set(diskIOConfig{DirectIO: directIO, Durability: durabilityFull})
+		w, e := fs.CreateFile(context.Background(), "bucket", "object/part.0")
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		read, e := ioutil.ReadFile(filepath.Join(directory, "bucket", "object", "part.0"))
+		if e != nil || !bytes.Equal(read, data) {
+			t.Fatalf("Direct I/O %v: unexpected data written, %v", directIO, e)
+		}
+
+		w, e = fs.CreateFile(context.Background(), "bucket", "aborted/"+metadataFile)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, ok := w.(*syncedFile); !ok && !directIO {
+			t.Fatalf("Expected file to be synced, got %T", w)
+		}
+		w.Write([]byte("data"))
+		if e = safeCloseAndRemove(w); e != nil {
+			t.Fatal(e)
+		}
+		if names, _ := ioutil.ReadDir(filepath.Join(directory, "bucket", "aborted")); len(names) != 0 {
+			t.Fatalf("Direct I/O %v: expected aborted write to be removed, got %d files", directIO, len(names))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "context"
+
+// Default percent of the space of each disk kept free.
+const defaultReservedSpace = 5
+
+// reservedSpace - returns percent of the space of each disk new files
+// are not written into.
+func (c diskIOConfig) reservedSpace() int64 {
+	if c.ReservedSpace <= 0 {
+		return defaultReservedSpace
+	}
+	return int64(c.ReservedSpace)
+}
+
+type reservedSpaceKey struct{}
+
+// withReservedSpace - returns ctx of writes which may use the space
+// reserved on disks, those restoring data of existing files such as
+// heals. Disks filled up to their reservation are healed, rather than
+// left short of parts which cannot be rewritten.
+func withReservedSpace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reservedSpaceKey{}, true)
+}
+
+// usesReservedSpace - returns true if writes of ctx may use the space
+// reserved on disks.
+func usesReservedSpace(ctx context.Context) bool {
+	reserved, _ := ctx.Value(reservedSpaceKey{}).(bool)
+	return reserved
+}
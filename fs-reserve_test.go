@@ -0,0 +1,75 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests new files are not written into the space reserved on disks,
+// while files restoring data of existing ones are, and files are still
+// read.
+func TestFSReservedSpace(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-reserved-space")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = fs.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	writeFile := func(ctx context.Context, path string) error {
+		w, e := fs.CreateFile(ctx, "bucket", path)
+		if e != nil {
+			return e
+		}
+		w.Write([]byte("hello world"))
+		return w.Close()
+	}
+	if e = writeFile(context.Background(), "object"); e != nil {
+		t.Fatal(e)
+	}
+
+	// Disks are never emptier than all of their space reserved.
+	globalDiskIO.Set(diskIOConfig{ReservedSpace: 100})
+	defer globalDiskIO.Set(diskIOConfig{})
+	if e = writeFile(context.Background(), "new-object"); e != errDiskFull {
+		t.Fatalf("Expected %v, got %v", errDiskFull, e)
+	}
+	if vfsInfo, e := fs.StatVFS("bucket"); e != nil || vfsInfo.Free != 0 {
+		t.Fatalf("Expected no free space, got %+v, %v", vfsInfo, e)
+	}
+	if e = writeFile(withReservedSpace(context.Background()), "healed-object"); e != nil {
+		t.Fatal(e)
+	}
+	r, e := fs.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	r.Close()
+
+	if reserved := (diskIOConfig{}).reservedSpace(); reserved != defaultReservedSpace {
+		t.Fatalf("Expected default reserved space %d, got %d", defaultReservedSpace, reserved)
+	}
+}
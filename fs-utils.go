@@ -29,13 +29,19 @@ var validVolname = regexp.MustCompile(`^.{3,63}$`)
 // isValidVolname verifies a volname name in accordance with object
 // layer requirements.
 func isValidVolname(volname string) bool {
+	return isValidVolnameFor(runtime.GOOS, volname)
+}
+
+// isValidVolnameFor - verifies volname on operating system goos, so
+// that the rules of each are tested on all.
+func isValidVolnameFor(goos, volname string) bool {
 	if !validVolname.MatchString(volname) {
 		return false
 	}
-	switch runtime.GOOS {
+	switch goos {
 	case "windows":
 		// Volname shouldn't have reserved characters on windows in it.
-		return !strings.ContainsAny(volname, "/\\:*?\"<>|")
+		return !strings.ContainsAny(volname, "/") && isValidWindowsName(volname)
 	default:
 		// Volname shouldn't have '/' in it.
 		return !strings.ContainsAny(volname, "/")
@@ -47,16 +53,84 @@ const pathMax = 4096
 
 // isValidPath verifies if a path name is in accordance with FS limitations.
 func isValidPath(path string) bool {
-	// TODO: Make this FSType or Operating system specific.
+	return isValidPathFor(runtime.GOOS, path)
+}
+
+// isValidPathFor - verifies path on operating system goos, so that the
+// rules of each are tested on all.
+func isValidPathFor(goos, path string) bool {
 	if len(path) > pathMax || len(path) == 0 {
 		return false
 	}
 	if !utf8.ValidString(path) {
 		return false
 	}
+	if goos == "windows" {
+		// Paths are separated by '/' on all systems, each element is
+		// a file name on windows.
+		for _, name := range strings.Split(path, "/") {
+			if name != "" && !isValidWindowsName(name) {
+				return false
+			}
+		}
+	}
 	return true
 }
 
+// Device names reserved by windows, with or without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isValidWindowsName - returns true if name can be a file name on
+// windows. Names with reserved characters, of devices, or ending in
+// a dot or space, which windows strips, are not. Neither are "." and
+// "..".
+func isValidWindowsName(name string) bool {
+	if strings.ContainsAny(name, "\\:*?\"<>|") {
+		return false
+	}
+	for _, r := range name {
+		if r < 0x20 {
+			return false
+		}
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return false
+	}
+	base := name
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		base = name[:i]
+	}
+	return !windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+// isCaseInsensitiveOS - returns true if filesystems of goos ignore
+// case by default, names differing in case only are the same file.
+func isCaseInsensitiveOS(goos string) bool {
+	return goos == "windows" || goos == "darwin"
+}
+
+// windowsLongPath - returns the absolute windows path prefixed with
+// \\?\, so that paths below it are not limited to MAX_PATH. Other
+// paths are returned as is.
+func windowsLongPath(path string) string {
+	switch {
+	case strings.HasPrefix(path, `\\?\`), strings.HasPrefix(path, `\\.\`):
+		return path
+	case strings.HasPrefix(path, `\\`):
+		// UNC path, \\server\share.
+		return `\\?\UNC\` + path[2:]
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\':
+		return `\\?\` + path
+	}
+	return path
+}
+
 // isValidPrefix verifies where the prefix is a valid path.
 func isValidPrefix(prefix string) bool {
 	// Prefix can be empty.
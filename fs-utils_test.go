@@ -0,0 +1,100 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests volume names and paths are validated by the rules of each
+// operating system.
+func TestIsValidPathFor(t *testing.T) {
+	testCases := []struct {
+		name    string
+		path    string
+		linux   bool
+		windows bool
+	}{
+		{"plain", "dir/object.txt", true, true},
+		{"prefix", "dir/", true, true},
+		{"backslash", `dir\object`, true, false},
+		{"colon", "dir/c:object", true, false},
+		{"wildcard", "dir/obj*", true, false},
+		{"control", "dir/obj\x01", true, false},
+		{"device", "dir/con", true, false},
+		{"device extension", "dir/NUL.txt", true, false},
+		{"device prefix", "dir/console", true, true},
+		{"trailing dot", "dir./object", true, false},
+		{"trailing space", "dir/object ", true, false},
+		{"parent", "dir/../object", true, false},
+		{"hidden", ".minio/object", true, true},
+		{"empty", "", false, false},
+		{"too long", strings.Repeat("a", pathMax+1), false, false},
+		{"invalid utf8", "dir/\xff", false, false},
+	}
+	for _, testCase := range testCases {
+		if valid := isValidPathFor("linux", testCase.path); valid != testCase.linux {
+			t.Errorf("%s: expected valid on linux %v", testCase.name, testCase.linux)
+		}
+		if valid := isValidPathFor("windows", testCase.path); valid != testCase.windows {
+			t.Errorf("%s: expected valid on windows %v", testCase.name, testCase.windows)
+		}
+	}
+
+	volnameCases := []struct {
+		volname string
+		linux   bool
+		windows bool
+	}{
+		{"bucket", true, true},
+		{"bu", false, false},
+		{"buc/ket", false, false},
+		{"buc:ket", true, false},
+		{"aux", true, false},
+		{"COM1.bucket", true, false},
+		{"bucket.", true, false},
+	}
+	for _, testCase := range volnameCases {
+		if valid := isValidVolnameFor("linux", testCase.volname); valid != testCase.linux {
+			t.Errorf("%s: expected valid on linux %v", testCase.volname, testCase.linux)
+		}
+		if valid := isValidVolnameFor("windows", testCase.volname); valid != testCase.windows {
+			t.Errorf("%s: expected valid on windows %v", testCase.volname, testCase.windows)
+		}
+	}
+}
+
+// Tests absolute windows paths are prefixed for long paths.
+func TestWindowsLongPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		longPath string
+	}{
+		{`C:\disk`, `\\?\C:\disk`},
+		{`\\server\share\disk`, `\\?\UNC\server\share\disk`},
+		{`\\?\C:\disk`, `\\?\C:\disk`},
+		{`\\.\pipe\disk`, `\\.\pipe\disk`},
+		{`disk`, `disk`},
+		{`/disk`, `/disk`},
+	}
+	for _, testCase := range testCases {
+		if longPath := windowsLongPath(testCase.path); longPath != testCase.longPath {
+			t.Errorf("%s: expected %s, got %s", testCase.path, testCase.longPath, longPath)
+		}
+	}
+}
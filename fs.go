@@ -17,10 +17,12 @@
 package main
 
 import (
+	"context"
 	"io"
 	"os"
 	slashpath "path"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"syscall"
@@ -45,7 +47,6 @@ type listParams struct {
 // fsStorage - implements StorageAPI interface.
 type fsStorage struct {
 	diskPath           string
-	minFreeDisk        int64
 	listObjectMap      map[listParams][]*treeWalker
 	listObjectMapMutex *sync.Mutex
 }
@@ -75,12 +76,54 @@ func isDirExist(dirname string) (bool, error) {
 	return fi.IsDir(), nil
 }
 
+// Windows reports ERROR_DIR_NOT_EMPTY for non-empty directories, not
+// ENOTEMPTY.
+const errWindowsDirNotEmpty = syscall.Errno(145)
+
+// sysErrno - returns the system error number underlying err, zero if
+// err is not a system error.
+func sysErrno(err error) syscall.Errno {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	errno, _ := err.(syscall.Errno)
+	return errno
+}
+
+// isSysErrNotDir - returns true if err reports that a parent of the
+// path is not a directory.
+func isSysErrNotDir(err error) bool {
+	return sysErrno(err) == syscall.ENOTDIR
+}
+
+// isSysErrNotEmpty - returns true if err reports a non-empty directory.
+func isSysErrNotEmpty(err error) bool {
+	errno := sysErrno(err)
+	if runtime.GOOS == "windows" {
+		return errno == errWindowsDirNotEmpty
+	}
+	return errno == syscall.ENOTEMPTY
+}
+
 // Initialize a new storage disk.
 func newFS(diskPath string) (StorageAPI, error) {
 	if diskPath == "" {
 		log.Debug("Disk cannot be empty")
 		return nil, errInvalidArgument
 	}
+	if runtime.GOOS == "windows" {
+		// Long paths are only allowed below absolute paths.
+		absPath, err := filepath.Abs(diskPath)
+		if err != nil {
+			return nil, err
+		}
+		diskPath = absPath
+	}
 	st, err := os.Stat(diskPath)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -96,17 +139,37 @@ func newFS(diskPath string) (StorageAPI, error) {
 	}
 	fs := fsStorage{
 		diskPath:           diskPath,
-		minFreeDisk:        5, // Minimum 5% disk should be free.
 		listObjectMap:      make(map[listParams][]*treeWalker),
 		listObjectMapMutex: &sync.Mutex{},
 	}
 	log.WithFields(logrus.Fields{
-		"diskPath":    diskPath,
-		"minFreeDisk": 5,
+		"diskPath": diskPath,
 	}).Debugf("Successfully configured FS storage API.")
 	return fs, nil
 }
 
+// getDiskStatus - returns status of disk at diskPath, disks which
+// cannot be stat'ed are offline.
+func getDiskStatus(diskPath string) DiskStatus {
+	status := DiskStatus{Path: diskPath}
+	di, err := disk.GetInfo(diskPath)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Online = true
+	status.Total = di.Total
+	status.Free = di.Free
+	status.Used = di.Total - di.Free
+	status.FSType = di.FSType
+	return status
+}
+
+// DiskStatus - returns status of the disk.
+func (s fsStorage) DiskStatus() []DiskStatus {
+	return []DiskStatus{getDiskStatus(s.diskPath)}
+}
+
 // checkDiskFree verifies if disk path has sufficient minium free disk
 // space.
 func checkDiskFree(diskPath string, minFreeDisk int64) (err error) {
@@ -133,6 +196,29 @@ func checkDiskFree(diskPath string, minFreeDisk int64) (err error) {
 	return nil
 }
 
+// StatVFS - returns space of the disk, free space is left out of the
+// space reserved on the disk.
+func (s fsStorage) StatVFS(volume string) (vfsInfo VFSInfo, err error) {
+	if !isValidVolname(volume) {
+		return VFSInfo{}, errInvalidArgument
+	}
+	di, err := disk.GetInfo(s.diskPath)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"diskPath": s.diskPath,
+		}).Debugf("Failed to get disk info, %s", err)
+		return VFSInfo{}, err
+	}
+	// Same reservation as checkDiskFree, reserved percent of total
+	// space less 5% for journalling, inodes etc.
+	reserved := int64(float64(globalDiskIO.Get().reservedSpace()) / 100 * 0.95 * float64(di.Total))
+	vfsInfo = VFSInfo{Total: di.Total, Free: di.Free - reserved}
+	if vfsInfo.Free < 0 {
+		vfsInfo.Free = 0
+	}
+	return vfsInfo, nil
+}
+
 func removeDuplicateVols(volsInfo []VolInfo) []VolInfo {
 	// Use map to record duplicates as we find them.
 	result := []VolInfo{}
@@ -195,7 +281,12 @@ func (s fsStorage) getVolumeDir(volume string) (string, error) {
 	if !isValidVolname(volume) {
 		return "", errInvalidArgument
 	}
-	volumeDir := filepath.Join(s.diskPath, volume)
+	volumesPath := s.diskPath
+	if runtime.GOOS == "windows" {
+		// Files of volumes are not limited to MAX_PATH.
+		volumesPath = windowsLongPath(volumesPath)
+	}
+	volumeDir := filepath.Join(volumesPath, volume)
 	_, err := os.Stat(volumeDir)
 	if err == nil {
 		return volumeDir, nil
@@ -210,9 +301,11 @@ func (s fsStorage) getVolumeDir(volume string) (string, error) {
 			// Verify if lowercase version of
 			// the volume
 			// is equal to the incoming volume, then use the proper
-			// name.
-			if strings.ToLower(vol.Name) == volume {
-				volumeDir = filepath.Join(s.diskPath, vol.Name)
+			// name. Volumes differing in case only are the same on
+			// case insensitive filesystems.
+			if strings.ToLower(vol.Name) == volume ||
+				isCaseInsensitiveOS(runtime.GOOS) && strings.EqualFold(vol.Name, volume) {
+				volumeDir = filepath.Join(volumesPath, vol.Name)
 				return volumeDir, nil
 			}
 		}
@@ -232,7 +325,7 @@ func (s fsStorage) getVolumeDir(volume string) (string, error) {
 // Make a volume entry.
 func (s fsStorage) MakeVol(volume string) (err error) {
 	// Validate if disk is free.
-	if err = checkDiskFree(s.diskPath, s.minFreeDisk); err != nil {
+	if err = checkDiskFree(s.diskPath, globalDiskIO.Get().reservedSpace()); err != nil {
 		return err
 	}
 
@@ -243,7 +336,7 @@ func (s fsStorage) MakeVol(volume string) (err error) {
 	}
 
 	// If volume not found create it.
-	if err == errVolumeNotFound {
+	if errorCause(err) == errVolumeNotFound {
 		// Make a volume entry.
 		return os.Mkdir(volumeDir, 0700)
 	}
@@ -357,13 +450,7 @@ func (s fsStorage) DeleteVol(volume string) error {
 		}).Debugf("Volume remove failed with %s", err)
 		if os.IsNotExist(err) {
 			return errVolumeNotFound
-		} else if strings.Contains(err.Error(), "directory is not empty") {
-			// On windows the string is slightly different, handle it here.
-			return errVolumeNotEmpty
-		} else if strings.Contains(err.Error(), "directory not empty") {
-			// Hopefully for all other
-			// operating systems, this is
-			// assumed to be consistent.
+		} else if isSysErrNotEmpty(err) {
 			return errVolumeNotEmpty
 		}
 		return err
@@ -462,12 +549,12 @@ func (s fsStorage) ListFiles(volume, prefix, marker string, recursive bool, coun
 
 	// Verify if prefix exists.
 	prefixDir := slashpath.Dir(prefix)
-	prefixRootDir := slashpath.Join(volumeDir, prefixDir)
+	prefixRootDir := filepath.Join(volumeDir, filepath.FromSlash(prefixDir))
 	if status, err := isDirExist(prefixRootDir); !status {
 		if err == nil {
 			// Prefix does not exist, not an error just respond empty list response.
 			return nil, true, nil
-		} else if err.Error() == syscall.ENOTDIR.Error() {
+		} else if isSysErrNotDir(err) {
 			// Prefix exists as a file.
 			return nil, true, nil
 		}
@@ -530,8 +617,12 @@ func (s fsStorage) ListFiles(volume, prefix, marker string, recursive bool, coun
 	return fileInfos, false, nil
 }
 
-// ReadFile - read a file at a given offset.
-func (s fsStorage) ReadFile(volume string, path string, offset int64) (readCloser io.ReadCloser, err error) {
+// ReadFile - read a file at a given offset. Local reads do not block,
+// ctx is only checked before opening the file.
+func (s fsStorage) ReadFile(ctx context.Context, volume string, path string, offset int64) (readCloser io.ReadCloser, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
 	volumeDir, err := s.getVolumeDir(volume)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -584,8 +675,12 @@ func (s fsStorage) ReadFile(volume string, path string, offset int64) (readClose
 	return file, nil
 }
 
-// CreateFile - create a file at path.
-func (s fsStorage) CreateFile(volume, path string) (writeCloser io.WriteCloser, err error) {
+// CreateFile - create a file at path. Writes are driven by the caller,
+// ctx is only checked before creating the file.
+func (s fsStorage) CreateFile(ctx context.Context, volume, path string) (writeCloser io.WriteCloser, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
 	volumeDir, err := s.getVolumeDir(volume)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -594,10 +689,14 @@ func (s fsStorage) CreateFile(volume, path string) (writeCloser io.WriteCloser,
 		}).Debugf("getVolumeDir failed with %s", err)
 		return nil, err
 	}
-	if err := checkDiskFree(s.diskPath, s.minFreeDisk); err != nil {
-		return nil, err
+	// Files restoring data of existing files may be written into the
+	// reserved space, so that disks filled up can still be healed.
+	if !usesReservedSpace(ctx) {
+		if err := checkDiskFree(s.diskPath, globalDiskIO.Get().reservedSpace()); err != nil {
+			return nil, err
+		}
 	}
-	filePath := filepath.Join(volumeDir, path)
+	filePath := filepath.Join(volumeDir, filepath.FromSlash(path))
 	// Verify if the file already exists and is not of regular type.
 	if st, err := os.Stat(filePath); err == nil {
 		if st.IsDir() {
@@ -608,7 +707,21 @@ func (s fsStorage) CreateFile(volume, path string) (writeCloser io.WriteCloser,
 			return nil, errIsNotRegular
 		}
 	}
-	return safe.CreateFileWithPrefix(filePath, "$tmpfile")
+	file, err := safe.CreateFileWithPrefix(filePath, "$tmpfile")
+	if err != nil {
+		return nil, err
+	}
+	diskIO := globalDiskIO.Get()
+	sync := fileSyncer(diskIO.Durability, path)
+	if diskIO.DirectIO {
+		directFile := newDirectFile(file)
+		directFile.sync = sync
+		return directFile, nil
+	}
+	if sync != nil {
+		return &syncedFile{File: file, sync: sync}, nil
+	}
+	return file, nil
 }
 
 // StatFile - get file info.
@@ -636,7 +749,7 @@ func (s fsStorage) StatFile(volume, path string) (file FileInfo, err error) {
 		}
 
 		// File path cannot be verified since one of the parents is a file.
-		if strings.Contains(err.Error(), "not a directory") {
+		if isSysErrNotDir(err) {
 			return FileInfo{}, errFileNotFound
 		}
 
@@ -711,7 +824,10 @@ func deleteFile(basePath, deletePath string) error {
 }
 
 // DeleteFile - delete a file at path.
-func (s fsStorage) DeleteFile(volume, path string) error {
+func (s fsStorage) DeleteFile(ctx context.Context, volume, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	volumeDir, err := s.getVolumeDir(volume)
 	if err != nil {
 		log.WithFields(logrus.Fields{
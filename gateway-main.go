@@ -0,0 +1,143 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+
+	"github.com/minio/cli"
+)
+
+var gatewayCmd = cli.Command{
+	Name:        "gateway",
+	Usage:       "Start Minio as a S3 gateway to cloud storage.",
+	Subcommands: []cli.Command{gatewayGCSCmd, gatewayAzureCmd},
+	CustomHelpTemplate: `NAME:
+  minio {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio {{.Name}} BACKEND [OPTIONS] [ARGS]
+
+BACKENDS:
+  gcs: Google Cloud Storage.
+  azure: Azure Blob Storage.
+
+EXAMPLES:
+  1. Start minio gateway to the Google Cloud Storage project of a service account.
+      $ export GOOGLE_APPLICATION_CREDENTIALS=/etc/minio/service-account.json
+      $ minio {{.Name}} gcs
+`,
+}
+
+// gatewayFlags - flags of gateway commands, those of the server
+// except its backend.
+func gatewayFlags() []cli.Flag {
+	var flags []cli.Flag
+	for _, flag := range serverCmd.Flags {
+		if stringFlag, ok := flag.(cli.StringFlag); ok && stringFlag.Name == "backend" {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+var gatewayGCSCmd = cli.Command{
+	Name:   "gcs",
+	Usage:  "Start Minio as a S3 gateway to Google Cloud Storage.",
+	Flags:  gatewayFlags(),
+	Action: gatewayGCSMain,
+	CustomHelpTemplate: `NAME:
+  minio gateway {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio gateway {{.Name}} [OPTIONS] [PROJECTID]
+
+OPTIONS:
+  {{range .Flags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  GOOGLE_APPLICATION_CREDENTIALS: Path to the JSON key file of a service account.
+  MINIO_ACCESS_KEY: Access key string of 5 to 20 characters in length.
+  MINIO_SECRET_KEY: Secret key string of 8 to 40 characters in length.
+
+  Buckets are those of the project of the service account, unless PROJECTID is given.
+  Metadata is kept in bucket minio-meta-PROJECTID.
+
+EXAMPLES:
+  1. Start minio gateway to the project of a service account.
+      $ export GOOGLE_APPLICATION_CREDENTIALS=/etc/minio/service-account.json
+      $ minio gateway {{.Name}}
+
+  2. Start minio gateway to another project the service account has access to.
+      $ minio gateway {{.Name}} my-other-project
+`,
+}
+
+var gatewayAzureCmd = cli.Command{
+	Name:   "azure",
+	Usage:  "Start Minio as a S3 gateway to Azure Blob Storage.",
+	Flags:  gatewayFlags(),
+	Action: gatewayAzureMain,
+	CustomHelpTemplate: `NAME:
+  minio gateway {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio gateway {{.Name}} [OPTIONS] [ENDPOINT]
+
+OPTIONS:
+  {{range .Flags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  MINIO_AZURE_ACCOUNT_NAME: Storage account name.
+  MINIO_AZURE_ACCOUNT_KEY: Base64 encoded storage account key.
+  MINIO_ACCESS_KEY: Access key string of 5 to 20 characters in length.
+  MINIO_SECRET_KEY: Secret key string of 8 to 40 characters in length.
+
+  ENDPOINT defaults to the Blob service of the account, https://ACCOUNT.blob.core.windows.net.
+
+EXAMPLES:
+  1. Start minio gateway to a storage account.
+      $ export MINIO_AZURE_ACCOUNT_NAME=myaccount
+      $ export MINIO_AZURE_ACCOUNT_KEY=<base64 encoded account key>
+      $ minio gateway {{.Name}}
+`,
+}
+
+// gatewayGCSMain - serves buckets of a Google Cloud Storage project.
+func gatewayGCSMain(c *cli.Context) {
+	if len(c.Args()) > 1 || c.Args().First() == "help" {
+		cli.ShowCommandHelpAndExit(c, "gcs", 1)
+	}
+	startServer(c, storageBackendGCS, c.Args())
+}
+
+// gatewayAzureMain - serves containers of an Azure storage account.
+func gatewayAzureMain(c *cli.Context) {
+	if len(c.Args()) > 1 || c.Args().First() == "help" {
+		cli.ShowCommandHelpAndExit(c, "azure", 1)
+	}
+	endpoint := c.Args().First()
+	if endpoint == "" {
+		account := os.Getenv("MINIO_AZURE_ACCOUNT_NAME")
+		if account == "" {
+			cli.ShowCommandHelpAndExit(c, "azure", 1)
+		}
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	startServer(c, storageBackendAzure, []string{endpoint})
+}
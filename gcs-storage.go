@@ -0,0 +1,798 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Endpoint of the Cloud Storage JSON API.
+	gcsEndpoint = "https://storage.googleapis.com"
+
+	// Scope of the access tokens requested for the service account.
+	gcsScope = "https://www.googleapis.com/auth/devstorage.full_control"
+
+	// Token endpoint of service accounts which do not name one.
+	gcsDefaultTokenURI = "https://oauth2.googleapis.com/token"
+
+	// Files larger than this are uploaded in components of this size,
+	// composed into the object once they are closed.
+	gcsComponentSize = 16 * 1024 * 1024
+
+	// Maximum number of objects composed at once.
+	gcsMaxComposeComponents = 32
+
+	// Components of files being uploaded are kept under this prefix
+	// of their bucket, they are not listed.
+	gcsComposePrefix = ".minio-compose/"
+
+	// Maximum number of objects listed in a single request.
+	gcsListLimit = 1000
+)
+
+var errGCSNoCredentials = errors.New("Service account JSON file should be set with GOOGLE_APPLICATION_CREDENTIALS")
+
+// gcsServiceAccount - service account JSON key file.
+type gcsServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// gcsStorage - storage API of a Google Cloud Storage project, volumes
+// are buckets and files are objects.
+type gcsStorage struct {
+	endpoint   string
+	projectID  string
+	account    gcsServiceAccount
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+	// Bucket minioMetaVolume is kept in, bucket names are global.
+	metaBucket string
+	// Access token of the service account and its expiry.
+	tokenMutex  *sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// newGCSStorage - initialize storage API of the project of the
+// service account named by GOOGLE_APPLICATION_CREDENTIALS, projectID
+// overrides it if not empty.
+func newGCSStorage(projectID string) (StorageAPI, error) {
+	credentialsFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if credentialsFile == "" {
+		return nil, errGCSNoCredentials
+	}
+	data, e := ioutil.ReadFile(credentialsFile)
+	if e != nil {
+		return nil, e
+	}
+	account := gcsServiceAccount{}
+	if e = json.Unmarshal(data, &account); e != nil {
+		return nil, fmt.Errorf("Invalid service account file %s: %s", credentialsFile, e)
+	}
+	if account.Type != "service_account" || account.ClientEmail == "" {
+		return nil, fmt.Errorf("%s is not a service account JSON key file", credentialsFile)
+	}
+	key, e := jwtgo.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if e != nil {
+		return nil, fmt.Errorf("Invalid private key of service account %s: %s", account.ClientEmail, e)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = gcsDefaultTokenURI
+	}
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("Project of service account %s should be given", account.ClientEmail)
+	}
+	log.WithFields(logrus.Fields{
+		"project": projectID,
+		"account": account.ClientEmail,
+	}).Debugf("Successfully configured Google Cloud Storage API.")
+	return &gcsStorage{
+		endpoint:   gcsEndpoint,
+		projectID:  projectID,
+		account:    account,
+		key:        key,
+		httpClient: &http.Client{},
+		metaBucket: "minio-meta-" + projectID,
+		tokenMutex: &sync.Mutex{},
+	}, nil
+}
+
+// bucket - returns bucket of volume.
+func (s *gcsStorage) bucket(volume string) (string, error) {
+	if volume == minioMetaVolume {
+		return s.metaBucket, nil
+	}
+	if volume == s.metaBucket || volume == "" {
+		return "", errInvalidVolumeName
+	}
+	return volume, nil
+}
+
+// volume - returns volume of bucket.
+func (s *gcsStorage) volume(bucket string) string {
+	if bucket == s.metaBucket {
+		return minioMetaVolume
+	}
+	return bucket
+}
+
+// accessToken - returns access token of the service account, a new
+// one is requested with a signed assertion once it expires.
+func (s *gcsStorage) accessToken() (string, error) {
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+	now := time.Now().UTC()
+	if s.token != "" && now.Before(s.tokenExpiry) {
+		return s.token, nil
+	}
+	assertion := jwtgo.New(jwtgo.SigningMethodRS256)
+	assertion.Header["kid"] = s.account.PrivateKeyID
+	assertion.Claims["iss"] = s.account.ClientEmail
+	assertion.Claims["scope"] = gcsScope
+	assertion.Claims["aud"] = s.account.TokenURI
+	assertion.Claims["iat"] = now.Unix()
+	assertion.Claims["exp"] = now.Add(time.Hour).Unix()
+	signed, e := assertion.SignedString(s.key)
+	if e != nil {
+		return "", e
+	}
+	resp, e := s.httpClient.PostForm(s.account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	})
+	if e != nil {
+		return "", e
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Requesting access token failed with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	reply := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if e = json.NewDecoder(resp.Body).Decode(&reply); e != nil {
+		return "", e
+	}
+	// Renewed a minute before it expires.
+	s.token = reply.AccessToken
+	s.tokenExpiry = now.Add(time.Duration(reply.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+// gcsError - error response of the JSON API.
+type gcsError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toStorageError - returns storage error of a failed response.
+func (s *gcsStorage) toStorageError(resp *http.Response) error {
+	apiErr := gcsError{}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+	msg := apiErr.Error.Message
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		if strings.Contains(msg, "No such object") {
+			return errFileNotFound
+		}
+		return errVolumeNotFound
+	case http.StatusConflict:
+		if strings.Contains(msg, "not empty") {
+			return errVolumeNotEmpty
+		}
+		return errVolumeExists
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return errVolumeAccessDenied
+	case http.StatusBadRequest:
+		if strings.Contains(msg, "bucket name") {
+			return errInvalidVolumeName
+		}
+	}
+	return fmt.Errorf("Google Cloud Storage request failed with %s: %s", resp.Status, msg)
+}
+
+// do - sends an authorized request on path of the JSON API, escaped
+// by the caller, and returns its response if successful.
+func (s *gcsStorage) do(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	token, e := s.accessToken()
+	if e != nil {
+		return nil, e
+	}
+	u := s.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, e := http.NewRequest(method, u, bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+	req = req.WithContext(ctx)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, e := s.httpClient.Do(req)
+	if e != nil {
+		return nil, e
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s.toStorageError(resp)
+	}
+	return resp, nil
+}
+
+// doJSON - sends a request with a JSON body, if any, and decodes its
+// JSON response into reply, if not nil.
+func (s *gcsStorage) doJSON(ctx context.Context, method, path string, query url.Values, request, reply interface{}) error {
+	var body []byte
+	header := http.Header{}
+	if request != nil {
+		var e error
+		if body, e = json.Marshal(request); e != nil {
+			return e
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	resp, e := s.do(ctx, method, path, query, header, body)
+	if e != nil {
+		return e
+	}
+	defer resp.Body.Close()
+	if reply == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+// gcsBucketPath - returns API path of bucket.
+func gcsBucketPath(bucket string) string {
+	return "/storage/v1/b/" + url.PathEscape(bucket)
+}
+
+// gcsObjectPath - returns API path of object in bucket.
+func gcsObjectPath(bucket, object string) string {
+	return gcsBucketPath(bucket) + "/o/" + url.PathEscape(object)
+}
+
+// gcsTime - returns time of a RFC 3339 timestamp, zero if it is
+// malformed.
+func gcsTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, value)
+	return t.UTC()
+}
+
+// gcsBucketResource - bucket resource of the JSON API.
+type gcsBucketResource struct {
+	Name        string `json:"name"`
+	TimeCreated string `json:"timeCreated,omitempty"`
+}
+
+// gcsObjectResource - object resource of the JSON API.
+type gcsObjectResource struct {
+	Name    string `json:"name"`
+	Size    string `json:"size,omitempty"`
+	Updated string `json:"updated,omitempty"`
+}
+
+func (object gcsObjectResource) fileInfo(volume string) FileInfo {
+	size, _ := strconv.ParseInt(object.Size, 10, 64)
+	return FileInfo{
+		Volume:  volume,
+		Name:    object.Name,
+		ModTime: gcsTime(object.Updated),
+		Size:    size,
+	}
+}
+
+// UnsupportedFeatures - retention can not be enforced on objects, the
+// project can delete them directly.
+func (s *gcsStorage) UnsupportedFeatures() []string {
+	return []string{featureLocking}
+}
+
+/// Volume operations.
+
+// MakeVol - creates bucket of volume in the project.
+func (s *gcsStorage) MakeVol(volume string) error {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return e
+	}
+	return s.doJSON(context.Background(), "POST", "/storage/v1/b", url.Values{"project": {s.projectID}}, gcsBucketResource{Name: bucket}, nil)
+}
+
+// ListVols - lists volumes of all buckets of the project.
+func (s *gcsStorage) ListVols() ([]VolInfo, error) {
+	var vols []VolInfo
+	pageToken := ""
+	for {
+		query := url.Values{"project": {s.projectID}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		reply := struct {
+			Items         []gcsBucketResource `json:"items"`
+			NextPageToken string              `json:"nextPageToken"`
+		}{}
+		if e := s.doJSON(context.Background(), "GET", "/storage/v1/b", query, nil, &reply); e != nil {
+			return nil, e
+		}
+		for _, bucket := range reply.Items {
+			vols = append(vols, VolInfo{
+				Name:    s.volume(bucket.Name),
+				Created: gcsTime(bucket.TimeCreated),
+			})
+		}
+		if reply.NextPageToken == "" {
+			return vols, nil
+		}
+		pageToken = reply.NextPageToken
+	}
+}
+
+// StatVol - returns info of bucket of volume.
+func (s *gcsStorage) StatVol(volume string) (VolInfo, error) {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return VolInfo{}, e
+	}
+	reply := gcsBucketResource{}
+	if e = s.doJSON(context.Background(), "GET", gcsBucketPath(bucket), nil, nil, &reply); e != nil {
+		return VolInfo{}, e
+	}
+	return VolInfo{Name: volume, Created: gcsTime(reply.TimeCreated)}, nil
+}
+
+// StatVFS - buckets have no fixed capacity.
+func (s *gcsStorage) StatVFS(volume string) (VFSInfo, error) {
+	return VFSInfo{Total: -1, Free: -1}, nil
+}
+
+// DeleteVol - deletes bucket of volume if it is empty.
+func (s *gcsStorage) DeleteVol(volume string) error {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return e
+	}
+	return s.doJSON(context.Background(), "DELETE", gcsBucketPath(bucket), nil, nil, nil)
+}
+
+/// File operations.
+
+// gcsObjectList - response of listing objects.
+type gcsObjectList struct {
+	Items         []gcsObjectResource `json:"items"`
+	Prefixes      []string            `json:"prefixes"`
+	NextPageToken string              `json:"nextPageToken"`
+}
+
+// ListFiles - lists files of volume after marker, directories are
+// listed with a trailing slash unless listing is recursive.
+func (s *gcsStorage) ListFiles(volume, prefix, marker string, recursive bool, count int) ([]FileInfo, bool, error) {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return nil, true, e
+	}
+	if marker != "" && !strings.HasPrefix(marker, prefix) {
+		return nil, true, errInvalidArgument
+	}
+	// Return empty response for a valid request when count is 0.
+	if count == 0 {
+		return nil, true, nil
+	}
+	if count < 0 || count > gcsListLimit {
+		count = gcsListLimit
+	}
+
+	// Listings start at marker, which is skipped.
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if marker != "" {
+		query.Set("startOffset", marker)
+	}
+	if !recursive {
+		query.Set("delimiter", slashSeparator)
+	}
+	var fileInfos []FileInfo
+	for {
+		query.Set("maxResults", strconv.Itoa(count-len(fileInfos)))
+		list := gcsObjectList{}
+		if e = s.doJSON(context.Background(), "GET", gcsBucketPath(bucket)+"/o", query, nil, &list); e != nil {
+			return nil, true, e
+		}
+		var page []FileInfo
+		for _, object := range list.Items {
+			page = append(page, object.fileInfo(volume))
+		}
+		for _, objectPrefix := range list.Prefixes {
+			page = append(page, FileInfo{
+				Volume: volume,
+				Name:   objectPrefix,
+				Mode:   os.ModeDir,
+			})
+		}
+		sort.Slice(page, func(i, j int) bool { return page[i].Name < page[j].Name })
+		for _, fileInfo := range page {
+			if fileInfo.Name > marker && !strings.HasPrefix(fileInfo.Name, gcsComposePrefix) {
+				fileInfos = append(fileInfos, fileInfo)
+			}
+		}
+		if list.NextPageToken == "" {
+			return fileInfos, true, nil
+		}
+		if len(fileInfos) >= count {
+			return fileInfos, false, nil
+		}
+		query.Set("pageToken", list.NextPageToken)
+	}
+}
+
+// ReadFile - reads object of path from offset.
+func (s *gcsStorage) ReadFile(ctx context.Context, volume string, path string, offset int64) (io.ReadCloser, error) {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return nil, e
+	}
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, e := s.do(ctx, "GET", gcsObjectPath(bucket, path), url.Values{"alt": {"media"}}, header, nil)
+	if e != nil {
+		// Reading from the end of an object reads nothing.
+		if offset > 0 && strings.Contains(e.Error(), strconv.Itoa(http.StatusRequestedRangeNotSatisfiable)) {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, e
+	}
+	return resp.Body, nil
+}
+
+// StatFile - returns info of object of path.
+func (s *gcsStorage) StatFile(volume string, path string) (FileInfo, error) {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return FileInfo{}, e
+	}
+	object := gcsObjectResource{}
+	if e = s.doJSON(context.Background(), "GET", gcsObjectPath(bucket, path), nil, nil, &object); e != nil {
+		return FileInfo{}, e
+	}
+	return object.fileInfo(volume), nil
+}
+
+// DeleteFile - deletes object of path.
+func (s *gcsStorage) DeleteFile(ctx context.Context, volume, path string) error {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return e
+	}
+	return s.doJSON(ctx, "DELETE", gcsObjectPath(bucket, path), nil, nil, nil)
+}
+
+// upload - uploads data as object in bucket.
+func (s *gcsStorage) upload(ctx context.Context, bucket, object string, data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	resp, e := s.do(ctx, "POST", "/upload"+gcsBucketPath(bucket)+"/o", url.Values{
+		"uploadType": {"media"},
+		"name":       {object},
+	}, header, data)
+	if e != nil {
+		return e
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// compose - concatenates objects of bucket into object.
+func (s *gcsStorage) compose(ctx context.Context, bucket, object string, components []string) error {
+	request := struct {
+		SourceObjects []gcsObjectResource `json:"sourceObjects"`
+		Destination   struct {
+			ContentType string `json:"contentType"`
+		} `json:"destination"`
+	}{}
+	for _, component := range components {
+		request.SourceObjects = append(request.SourceObjects, gcsObjectResource{Name: component})
+	}
+	request.Destination.ContentType = "application/octet-stream"
+	return s.doJSON(ctx, "POST", gcsObjectPath(bucket, object)+"/compose", nil, request, nil)
+}
+
+// CreateFile - returns writer of the object of path, large files are
+// uploaded in components and composed once the writer is closed.
+func (s *gcsStorage) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	return s.newObjectWriter(ctx, volume, path)
+}
+
+// newGCSUploadID - returns a random id of an upload.
+func newGCSUploadID() (string, error) {
+	idBytes := make([]byte, 8)
+	if _, e := rand.Read(idBytes); e != nil {
+		return "", e
+	}
+	return hex.EncodeToString(idBytes), nil
+}
+
+// newObjectWriter - returns writer of components of the object of
+// path.
+func (s *gcsStorage) newObjectWriter(ctx context.Context, volume, path string) (*gcsObjectWriter, error) {
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return nil, e
+	}
+	uploadID, e := newGCSUploadID()
+	if e != nil {
+		return nil, e
+	}
+	return &gcsObjectWriter{
+		storage:       s,
+		ctx:           ctx,
+		bucket:        bucket,
+		object:        path,
+		uploadID:      uploadID,
+		componentSize: gcsComponentSize,
+	}, nil
+}
+
+// StagePart - returns writer of components in the bucket of the
+// object of path, they are left uncomposed once closed.
+func (s *gcsStorage) StagePart(ctx context.Context, volume, path string) (partWriter, error) {
+	w, e := s.newObjectWriter(ctx, volume, path)
+	if e != nil {
+		return nil, e
+	}
+	w.staged = true
+	return w, nil
+}
+
+// gcsTokenComponents - returns components of the part of token, tokens
+// are the upload id of the writer and its component count.
+func gcsTokenComponents(token string) ([]string, error) {
+	i := strings.LastIndex(token, "-")
+	if i < 0 {
+		return nil, errInvalidArgument
+	}
+	count, e := strconv.Atoi(token[i+1:])
+	if e != nil {
+		return nil, errInvalidArgument
+	}
+	var components []string
+	for index := 0; index < count; index++ {
+		components = append(components, gcsComponentName(token[:i], index))
+	}
+	return components, nil
+}
+
+// CommitParts - composes the components of the parts of tokens into
+// the object of path.
+func (s *gcsStorage) CommitParts(ctx context.Context, volume, path string, tokens []string) error {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return e
+	}
+	var components []string
+	for _, token := range tokens {
+		tokenComponents, e := gcsTokenComponents(token)
+		if e != nil {
+			return e
+		}
+		components = append(components, tokenComponents...)
+	}
+	if len(components) == 0 {
+		return s.upload(ctx, bucket, path, nil)
+	}
+	uploadID, e := newGCSUploadID()
+	if e != nil {
+		return e
+	}
+	intermediates, e := s.composeAll(ctx, bucket, path, uploadID, components)
+	s.removeComponents(bucket, intermediates)
+	return e
+}
+
+// RemoveParts - deletes the components of the parts of tokens.
+func (s *gcsStorage) RemoveParts(ctx context.Context, volume, path string, tokens []string) error {
+	bucket, e := s.bucket(volume)
+	if e != nil {
+		return e
+	}
+	for _, token := range tokens {
+		components, e := gcsTokenComponents(token)
+		if e != nil {
+			return e
+		}
+		s.removeComponents(bucket, components)
+	}
+	return nil
+}
+
+// gcsObjectWriter - uploads data written as component objects, they
+// are composed into the object on Close. Files smaller than a
+// component are uploaded at once.
+type gcsObjectWriter struct {
+	storage  *gcsStorage
+	ctx      context.Context
+	bucket   string
+	object   string
+	uploadID string
+	// Components of staged parts are not composed on Close.
+	staged bool
+	// Size of uploaded components.
+	componentSize int
+	components    []string
+	buf           []byte
+	err           error
+}
+
+// gcsComponentName - returns name of the component at index of an
+// upload.
+func gcsComponentName(uploadID string, index int) string {
+	return fmt.Sprintf("%s%s/%08d", gcsComposePrefix, uploadID, index)
+}
+
+// putComponent - uploads data as the next component.
+func (w *gcsObjectWriter) putComponent(data []byte) error {
+	component := gcsComponentName(w.uploadID, len(w.components))
+	if e := w.storage.upload(w.ctx, w.bucket, component, data); e != nil {
+		return e
+	}
+	w.components = append(w.components, component)
+	return nil
+}
+
+func (w *gcsObjectWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.componentSize {
+		if w.err = w.putComponent(w.buf[:w.componentSize]); w.err != nil {
+			return 0, w.err
+		}
+		w.buf = append(w.buf[:0], w.buf[w.componentSize:]...)
+	}
+	return len(p), nil
+}
+
+// Close - uploads the last component and composes all of them into
+// the object, components of staged parts are left uncomposed.
+func (w *gcsObjectWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.staged {
+		if len(w.buf) > 0 {
+			w.err = w.putComponent(w.buf)
+		}
+	} else if len(w.components) == 0 {
+		w.err = w.storage.upload(w.ctx, w.bucket, w.object, w.buf)
+	} else {
+		if len(w.buf) > 0 {
+			if w.err = w.putComponent(w.buf); w.err != nil {
+				return w.err
+			}
+		}
+		var intermediates []string
+		intermediates, w.err = w.storage.composeAll(w.ctx, w.bucket, w.object, w.uploadID, w.components)
+		w.components = append(w.components, intermediates...)
+		w.removeComponents()
+	}
+	w.buf = nil
+	if w.err == nil {
+		// Closing twice is an error, same as files.
+		w.err = os.ErrClosed
+		return nil
+	}
+	return w.err
+}
+
+// composeAll - composes components into object, at most
+// gcsMaxComposeComponents at once. Larger sets are composed into
+// intermediate components of uploadID first, which are returned.
+func (s *gcsStorage) composeAll(ctx context.Context, bucket, object, uploadID string, components []string) ([]string, error) {
+	var intermediates []string
+	for level := 0; len(components) > gcsMaxComposeComponents; level++ {
+		var composed []string
+		for i := 0; i < len(components); i += gcsMaxComposeComponents {
+			end := i + gcsMaxComposeComponents
+			if end > len(components) {
+				end = len(components)
+			}
+			component := fmt.Sprintf("%s%s/%d-%08d", gcsComposePrefix, uploadID, level+1, len(composed))
+			if e := s.compose(ctx, bucket, component, components[i:end]); e != nil {
+				return intermediates, e
+			}
+			intermediates = append(intermediates, component)
+			composed = append(composed, component)
+		}
+		components = composed
+	}
+	return intermediates, s.compose(ctx, bucket, object, components)
+}
+
+// removeComponents - deletes components of bucket.
+func (s *gcsStorage) removeComponents(bucket string, components []string) {
+	for _, component := range components {
+		e := s.doJSON(context.Background(), "DELETE", gcsObjectPath(bucket, component), nil, nil, nil)
+		if e != nil && e != errFileNotFound {
+			errorIf(probe.NewError(e).Trace(bucket, component), "Unable to remove uploaded component.", nil)
+		}
+	}
+}
+
+// removeComponents - deletes uploaded components.
+func (w *gcsObjectWriter) removeComponents() {
+	w.storage.removeComponents(w.bucket, w.components)
+	w.components = nil
+}
+
+// CloseAndRemove - aborts the upload, uploaded components are
+// deleted.
+func (w *gcsObjectWriter) CloseAndRemove() error {
+	w.removeComponents()
+	w.buf = nil
+	w.err = os.ErrClosed
+	return nil
+}
+
+// Token - returns the upload id of the writer and its component count.
+func (w *gcsObjectWriter) Token() string {
+	return fmt.Sprintf("%s-%d", w.uploadID, len(w.components))
+}
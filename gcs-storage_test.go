@@ -0,0 +1,507 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	. "gopkg.in/check.v1"
+)
+
+// fakeGCSObject - object of the fake JSON API.
+type fakeGCSObject struct {
+	data    []byte
+	updated time.Time
+}
+
+// fakeGCSService - in memory JSON API of a single project, with the
+// token endpoint of its service account.
+type fakeGCSService struct {
+	projectID string
+	key       *rsa.PrivateKey
+	mutex     *sync.Mutex
+	buckets   map[string]map[string]fakeGCSObject
+	tokens    int
+	composed  int
+}
+
+func newFakeGCSService(t interface {
+	Fatal(...interface{})
+}) *fakeGCSService {
+	key, e := rsa.GenerateKey(rand.Reader, 1024)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return &fakeGCSService{
+		projectID: "minio-project",
+		key:       key,
+		mutex:     &sync.Mutex{},
+		buckets:   make(map[string]map[string]fakeGCSObject),
+	}
+}
+
+func (f *fakeGCSService) fail(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": status, "message": msg},
+	})
+}
+
+func (f *fakeGCSService) objectResource(name string, object fakeGCSObject) gcsObjectResource {
+	return gcsObjectResource{
+		Name:    name,
+		Size:    strconv.Itoa(len(object.data)),
+		Updated: object.updated.Format(time.RFC3339Nano),
+	}
+}
+
+func (f *fakeGCSService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.URL.Path == "/token" {
+		token, e := jwtgo.Parse(r.FormValue("assertion"), func(*jwtgo.Token) (interface{}, error) {
+			return &f.key.PublicKey, nil
+		})
+		if e != nil || !token.Valid || token.Claims["scope"] != gcsScope || r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			f.fail(w, http.StatusUnauthorized, "invalid assertion")
+			return
+		}
+		f.tokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		f.fail(w, http.StatusUnauthorized, "Invalid Credentials")
+		return
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/") {
+		segment, _ = url.PathUnescape(segment)
+		segments = append(segments, segment)
+	}
+	query := r.URL.Query()
+	body, _ := ioutil.ReadAll(r.Body)
+
+	// Uploads, /upload/storage/v1/b/{bucket}/o
+	if segments[0] == "upload" {
+		objects, ok := f.buckets[segments[4]]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		objects[query.Get("name")] = fakeGCSObject{data: body, updated: time.Now().UTC()}
+		json.NewEncoder(w).Encode(f.objectResource(query.Get("name"), objects[query.Get("name")]))
+		return
+	}
+
+	// Buckets, /storage/v1/b[/{bucket}]
+	if len(segments) == 3 {
+		switch r.Method {
+		case "POST":
+			bucket := gcsBucketResource{}
+			json.Unmarshal(body, &bucket)
+			if query.Get("project") != f.projectID {
+				f.fail(w, http.StatusForbidden, "Forbidden")
+				return
+			}
+			if _, ok := f.buckets[bucket.Name]; ok {
+				f.fail(w, http.StatusConflict, "You already own this bucket. Please select another name.")
+				return
+			}
+			f.buckets[bucket.Name] = make(map[string]fakeGCSObject)
+			json.NewEncoder(w).Encode(bucket)
+		case "GET":
+			var names []string
+			for name := range f.buckets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			reply := struct {
+				Items []gcsBucketResource `json:"items"`
+			}{}
+			for _, name := range names {
+				reply.Items = append(reply.Items, gcsBucketResource{Name: name, TimeCreated: time.Now().UTC().Format(time.RFC3339)})
+			}
+			json.NewEncoder(w).Encode(reply)
+		}
+		return
+	}
+	objects, ok := f.buckets[segments[3]]
+	if !ok {
+		f.fail(w, http.StatusNotFound, "The specified bucket does not exist.")
+		return
+	}
+	if len(segments) == 4 {
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(gcsBucketResource{Name: segments[3], TimeCreated: time.Now().UTC().Format(time.RFC3339)})
+		case "DELETE":
+			if len(objects) > 0 {
+				f.fail(w, http.StatusConflict, "The bucket you tried to delete is not empty.")
+				return
+			}
+			delete(f.buckets, segments[3])
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	if len(segments) == 5 {
+		f.listObjects(w, objects, query)
+		return
+	}
+
+	// Objects, /storage/v1/b/{bucket}/o/{object}[/compose]
+	name := segments[5]
+	if len(segments) == 7 && segments[6] == "compose" {
+		request := struct {
+			SourceObjects []gcsObjectResource `json:"sourceObjects"`
+		}{}
+		json.Unmarshal(body, &request)
+		if len(request.SourceObjects) > gcsMaxComposeComponents {
+			f.fail(w, http.StatusBadRequest, "The number of source components provided exceeds the maximum")
+			return
+		}
+		var data []byte
+		for _, source := range request.SourceObjects {
+			object, ok := objects[source.Name]
+			if !ok {
+				f.fail(w, http.StatusNotFound, "No such object: "+source.Name)
+				return
+			}
+			data = append(data, object.data...)
+		}
+		f.composed++
+		objects[name] = fakeGCSObject{data: data, updated: time.Now().UTC()}
+		json.NewEncoder(w).Encode(f.objectResource(name, objects[name]))
+		return
+	}
+	object, ok := objects[name]
+	if !ok {
+		f.fail(w, http.StatusNotFound, "No such object: "+segments[3]+"/"+name)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if query.Get("alt") != "media" {
+			json.NewEncoder(w).Encode(f.objectResource(name, object))
+			return
+		}
+		data := object.data
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			if offset >= len(data) {
+				f.fail(w, http.StatusRequestedRangeNotSatisfiable, "Requested range not satisfiable")
+				return
+			}
+			data = data[offset:]
+		}
+		w.Write(data)
+	case "DELETE":
+		delete(objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listObjects - lists objects from startOffset, page tokens are the
+// name of the next entry.
+func (f *fakeGCSService) listObjects(w http.ResponseWriter, objects map[string]fakeGCSObject, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxResults, _ := strconv.Atoi(query.Get("maxResults"))
+	start := query.Get("startOffset")
+	if token := query.Get("pageToken"); token != "" {
+		start = token
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for name := range objects {
+		if !strings.HasPrefix(name, prefix) || name < query.Get("startOffset") {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				name = name[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if !seen[name] && name >= start {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	reply := gcsObjectList{}
+	if len(names) > maxResults {
+		reply.NextPageToken = names[maxResults]
+		names = names[:maxResults]
+	}
+	for _, name := range names {
+		if delimiter != "" && strings.HasSuffix(name, delimiter) {
+			reply.Prefixes = append(reply.Prefixes, name)
+			continue
+		}
+		reply.Items = append(reply.Items, f.objectResource(name, objects[name]))
+	}
+	json.NewEncoder(w).Encode(reply)
+}
+
+// newTestGCSStorage - returns storage API of the project served by
+// service at endpoint, with the credentials saved in directory.
+func newTestGCSStorage(service *fakeGCSService, endpoint, directory string) (StorageAPI, error) {
+	credentials, e := json.Marshal(gcsServiceAccount{
+		Type:         "service_account",
+		ProjectID:    service.projectID,
+		PrivateKeyID: "1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(service.key)})),
+		ClientEmail:  "minio@" + service.projectID + ".iam.gserviceaccount.com",
+		TokenURI:     endpoint + "/token",
+	})
+	if e != nil {
+		return nil, e
+	}
+	credentialsFile := filepath.Join(directory, "service-account.json")
+	if e = ioutil.WriteFile(credentialsFile, credentials, 0600); e != nil {
+		return nil, e
+	}
+	saved := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	defer os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", saved)
+	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialsFile)
+	storage, e := newStorageBackend(storageBackendGCS)
+	if e != nil {
+		return nil, e
+	}
+	storage.(*gcsStorage).endpoint = endpoint
+	return storage, nil
+}
+
+func (s *MySuite) TestGCSAPISuite(c *C) {
+	directory, err := ioutil.TempDir("", "minio-gcs")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(directory)
+	var servers []*httptest.Server
+	create := func() objectAPI {
+		service := newFakeGCSService(c)
+		server := httptest.NewServer(service)
+		servers = append(servers, server)
+		storageAPI, err := newTestGCSStorage(service, server.URL, directory)
+		c.Check(err, IsNil)
+		return newObjectLayer(storageAPI)
+	}
+	APITestSuite(c, create)
+	for _, server := range servers {
+		server.Close()
+	}
+}
+
+// Tests large files are composed from components, listings start
+// after their marker and errors map to storage errors.
+func TestGCSStorage(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-gcs")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	service := newFakeGCSService(t)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	storage, e := newTestGCSStorage(service, server.URL, directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if e = storage.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	if e = storage.MakeVol("bucket"); e != errVolumeExists {
+		t.Fatalf("Expected %v, got %v", errVolumeExists, e)
+	}
+	// The meta volume is kept in a bucket of the project.
+	if e = storage.MakeVol(minioMetaVolume); e != nil {
+		t.Fatal(e)
+	}
+	if _, ok := service.buckets["minio-meta-"+service.projectID]; !ok {
+		t.Fatalf("Expected meta bucket, got %v", service.buckets)
+	}
+	vols, e := storage.ListVols()
+	if e != nil || len(vols) != 2 || vols[0].Name != "bucket" || vols[1].Name != minioMetaVolume {
+		t.Fatalf("Unexpected volumes %+v %v", vols, e)
+	}
+
+	// More components than composed at once are composed in levels.
+	w, e := storage.CreateFile(context.Background(), "bucket", "dir/large")
+	if e != nil {
+		t.Fatal(e)
+	}
+	w.(*gcsObjectWriter).componentSize = 10
+	data := bytes.Repeat([]byte("0123456789"), 2*gcsMaxComposeComponents+1)
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if service.composed != 4 || len(service.buckets["bucket"]) != 1 {
+		t.Fatalf("Expected 4 compositions and components removed, got %d %d", service.composed, len(service.buckets["bucket"]))
+	}
+	r, e := storage.ReadFile(context.Background(), "bucket", "dir/large", 5)
+	if e != nil {
+		t.Fatal(e)
+	}
+	read, _ := ioutil.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(read, data[5:]) {
+		t.Fatalf("Unexpected data read, %d bytes", len(read))
+	}
+	if r, e = storage.ReadFile(context.Background(), "bucket", "dir/large", int64(len(data))); e != nil {
+		t.Fatalf("Expected empty read from the end, got %v", e)
+	}
+	r.Close()
+
+	// Aborted uploads remove their components.
+	w, e = storage.CreateFile(context.Background(), "bucket", "aborted")
+	if e != nil {
+		t.Fatal(e)
+	}
+	w.(*gcsObjectWriter).componentSize = 10
+	w.Write(data[:50])
+	if e = safeCloseAndRemove(w); e != nil || len(service.buckets["bucket"]) != 1 {
+		t.Fatalf("Expected components removed, got %d %v", len(service.buckets["bucket"]), e)
+	}
+
+	for i := 0; i < 5; i++ {
+		w, e = storage.CreateFile(context.Background(), "bucket", fmt.Sprintf("file%d", i))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e = storage.DeleteVol("bucket"); e != errVolumeNotEmpty {
+		t.Fatalf("Expected %v, got %v", errVolumeNotEmpty, e)
+	}
+	var names []string
+	marker, eof := "", false
+	for !eof {
+		var fileInfos []FileInfo
+		fileInfos, eof, e = storage.ListFiles("bucket", "", marker, false, 2)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, fileInfo := range fileInfos {
+			names = append(names, fileInfo.Name)
+			marker = fileInfo.Name
+		}
+	}
+	if strings.Join(names, ",") != "dir/,file0,file1,file2,file3,file4" {
+		t.Fatalf("Unexpected listing %v", names)
+	}
+
+	if e = storage.DeleteFile(context.Background(), "bucket", "missing"); e != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+	if _, e = storage.StatVol("missing"); e != errVolumeNotFound {
+		t.Fatalf("Expected %v, got %v", errVolumeNotFound, e)
+	}
+	if _, e = storage.StatFile("missing", "file"); e != errVolumeNotFound {
+		t.Fatalf("Expected %v, got %v", errVolumeNotFound, e)
+	}
+	// Access tokens are reused until they expire.
+	if service.tokens != 1 {
+		t.Fatalf("Expected a single access token, got %d", service.tokens)
+	}
+}
+
+// Tests parts of multipart uploads are staged as components in the
+// bucket of the object and composed without copying them.
+func TestGCSMultipart(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-gcs")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	service := newFakeGCSService(t)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	storage, e := newTestGCSStorage(service, server.URL, directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(storage)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), gcsComponentSize/10+1)
+	var parts []completePart
+	for i, part := range [][]byte{data, []byte("tail")} {
+		md5Hex, err := obj.PutObjectPart("bucket", "object", uploadID, i+1, int64(len(part)), bytes.NewReader(part), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Hex})
+	}
+	if components := len(service.buckets["bucket"]); components != 3 {
+		t.Fatalf("Expected 3 components staged, got %d", components)
+	}
+	for name, object := range service.buckets["minio-meta-"+service.projectID] {
+		if len(object.data) > 64 {
+			t.Fatalf("Expected only records of parts, got %s of %d bytes", name, len(object.data))
+		}
+	}
+	listed, err := obj.ListObjectParts("bucket", "object", uploadID, 0, 10)
+	if err != nil || len(listed.Parts) != 2 || listed.Parts[0].Size != int64(len(data)) || listed.Parts[1].Size != 4 {
+		t.Fatalf("Unexpected parts %+v %v", listed.Parts, err)
+	}
+
+	composed := service.composed
+	if _, err = obj.CompleteMultipartUpload("bucket", "object", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	if service.composed != composed+1 {
+		t.Fatalf("Expected a single composition, got %d", service.composed-composed)
+	}
+	if !bytes.Equal(service.buckets["bucket"]["object"].data, append(data, "tail"...)) {
+		t.Fatal("Unexpected data of the completed object")
+	}
+	if objects := len(service.buckets["bucket"]); objects != 1 {
+		t.Fatalf("Expected components removed, got %d objects", objects)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"path"
 	"regexp"
@@ -24,7 +25,6 @@ import (
 	"time"
 
 	router "github.com/gorilla/mux"
-	"github.com/rs/cors"
 )
 
 // HandlerFunc - useful to chain different middleware http.Handler
@@ -119,6 +119,89 @@ func (h minioPrivateBucketHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 	h.handler.ServeHTTP(w, r)
 }
 
+// objectPathHandler - rejects API requests for objects whose names the
+// router would clean into other names, with empty, '.' or '..'
+// elements, rather than redirecting them to another object.
+type objectPathHandler struct {
+	handler http.Handler
+}
+
+func setObjectPathHandler(h http.Handler) http.Handler {
+	return objectPathHandler{handler: h}
+}
+
+func (h objectPathHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := r.URL.Path
+	if urlPath != reservedBucket && !strings.HasPrefix(urlPath, reservedBucket+"/") {
+		cleanPath := path.Clean("/" + urlPath)
+		if strings.HasSuffix(urlPath, "/") && cleanPath != "/" {
+			cleanPath += "/"
+		}
+		if cleanPath != urlPath {
+			writeErrorResponse(w, r, ErrInvalidObjectName, urlPath)
+			return
+		}
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// bucketPurgeHandler - rejects requests for force deleted buckets
+// which are still being purged.
+type bucketPurgeHandler struct {
+	handler http.Handler
+	objAPI  objectAPI
+}
+
+// setBucketPurgeHandler - returns handler function rejecting requests
+// for buckets being purged by objAPI.
+func setBucketPurgeHandler(objAPI objectAPI) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return bucketPurgeHandler{handler: h, objAPI: objAPI}
+	}
+}
+
+func (h bucketPurgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Skip the first element which is usually '/' and split the rest.
+	splits := strings.SplitN(r.URL.Path[1:], "/", 2)
+	bucketName := splits[0]
+	if bucketName == "" || "/"+bucketName == reservedBucket || !h.objAPI.IsBucketPurging(bucketName) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	// Bucket is gone, except that its name can not be reused yet.
+	if r.Method == "PUT" && len(splits) == 1 && len(r.URL.Query()) == 0 {
+		writeErrorResponse(w, r, ErrBucketPurging, r.URL.Path)
+		return
+	}
+	writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+}
+
+// requestDeadlineHandler - aborts reads and writes of object data once
+// a request takes longer than timeout, internode requests are not
+// limited.
+type requestDeadlineHandler struct {
+	handler http.Handler
+	timeout time.Duration
+}
+
+// setRequestDeadlineHandler - returns handler function setting a
+// deadline of timeout on requests, zero disables the deadline.
+func setRequestDeadlineHandler(timeout time.Duration) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return requestDeadlineHandler{handler: h, timeout: timeout}
+	}
+}
+
+func (h requestDeadlineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.timeout <= 0 || strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	h.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // Supported Amz date formats.
 var amzDateFormats = []string{
 	time.RFC1123,
@@ -192,16 +275,6 @@ type resourceHandler struct {
 	handler http.Handler
 }
 
-// setCorsHandler handler for CORS (Cross Origin Resource Sharing)
-func setCorsHandler(h http.Handler) http.Handler {
-	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT"},
-		AllowedHeaders: []string{"*"},
-	})
-	return c.Handler(h)
-}
-
 // setIgnoreResourcesHandler -
 // Ignore resources handler is wrapper handler used for API request resource validation
 // Since we do not support all the S3 queries, it is necessary for us to throw back a
@@ -272,15 +345,9 @@ func ignoreNotImplementedObjectResources(req *http.Request) bool {
 // List of not implemented bucket queries
 var notimplementedBucketResourceNames = map[string]bool{
 	"acl":            true,
-	"cors":           true,
-	"lifecycle":      true,
-	"logging":        true,
 	"notification":   true,
-	"replication":    true,
 	"tagging":        true,
-	"versions":       true,
 	"requestPayment": true,
-	"versioning":     true,
 	"website":        true,
 }
 
@@ -17,6 +17,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/console"
@@ -24,12 +26,12 @@ import (
 
 // Global constants for Minio.
 const (
-	minGoVersion = ">= 1.6" // Minio requires at least Go v1.6
+	minGoVersion = ">= 1.13" // Minio requires at least Go v1.13
 )
 
 // minio configuration related constants.
 const (
-	globalMinioConfigVersion = "4"
+	globalMinioConfigVersion = "5"
 	globalMinioConfigDir     = ".minio"
 	globalMinioCertsDir      = ".minio/certs"
 	globalMinioCertFile      = "public.crt"
@@ -43,6 +45,9 @@ var (
 	// Add new global flags here.
 )
 
+// Time the server process started, reported as uptime by the admin API.
+var globalBootTime = time.Now().UTC()
+
 // global colors.
 var (
 	colorMagenta = color.New(color.FgMagenta, color.Bold).SprintfFunc()
@@ -0,0 +1,502 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Number of objects listed at a time while healing.
+const healListBatchSize = 1000
+
+const (
+	// Default maximum number of objects healed at once.
+	defaultHealMaxWorkers = 8
+
+	// Default target p99 latency of foreground requests in
+	// milliseconds, healing slows down once it is exceeded.
+	defaultHealLatencyTarget = 250
+
+	// Interval heal concurrency is adapted at, latencies of requests
+	// served during the last window are considered.
+	healAdaptInterval = time.Second
+	healLatencyWindow = 10 * time.Second
+
+	// Interval paused heal operations check whether one of their
+	// windows opened.
+	healWindowCheckInterval = time.Minute
+)
+
+// healConfig - heal concurrency, rate and schedule configuration.
+// Workers are added one at a time while foreground requests are idle
+// or well within the latency target, and halved once their p99
+// latency exceeds it.
+type healConfig struct {
+	MaxWorkers int `json:"maxWorkers"`
+	// Target p99 time to first byte of S3 requests in milliseconds.
+	LatencyTarget int64 `json:"latencyTarget"`
+	// Maximum objects checked per second, zero is unlimited.
+	MaxIOPS int64 `json:"maxIOPS"`
+	// Maximum bytes of objects checked per second, zero is
+	// unlimited.
+	MaxBandwidth int64 `json:"maxBandwidth"`
+	// Times of the week healing is allowed in, in server local
+	// time, such as "Mon-Fri 22:00-06:00, Sat-Sun 00:00-24:00".
+	// Healing is allowed at any time if empty.
+	Windows string `json:"windows"`
+}
+
+// newHealConfig - heal configuration for fresh and migrated configs.
+func newHealConfig() healConfig {
+	return healConfig{
+		MaxWorkers:    defaultHealMaxWorkers,
+		LatencyTarget: defaultHealLatencyTarget,
+	}
+}
+
+// maxWorkers - returns maximum number of workers, defaults if not
+// configured.
+func (c healConfig) maxWorkers() int {
+	if c.MaxWorkers <= 0 {
+		return defaultHealMaxWorkers
+	}
+	return c.MaxWorkers
+}
+
+// latencyTarget - returns target p99 latency, defaults if not
+// configured.
+func (c healConfig) latencyTarget() time.Duration {
+	if c.LatencyTarget <= 0 {
+		return defaultHealLatencyTarget * time.Millisecond
+	}
+	return time.Duration(c.LatencyTarget) * time.Millisecond
+}
+
+// windows - returns heal windows, healing is allowed at any time if
+// they are not valid.
+func (c healConfig) windows() []healWindow {
+	windows, e := parseHealWindows(c.Windows)
+	if e != nil {
+		log.WithFields(logrus.Fields{
+			"windows": c.Windows,
+		}).Errorf("Ignoring heal windows, %s", e)
+		return nil
+	}
+	return windows
+}
+
+// nextHealWorkers - returns number of heal workers given foreground
+// p99 latency, ok is false if no requests were served.
+func nextHealWorkers(workers, maxWorkers int, p99 time.Duration, ok bool, target time.Duration) int {
+	switch {
+	case ok && p99 > target:
+		workers /= 2
+	case !ok || p99 < target/2:
+		workers++
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > maxWorkers {
+		workers = maxWorkers
+	}
+	return workers
+}
+
+// Heal status values.
+const (
+	healStatusIdle     = "idle"
+	healStatusRunning  = "running"
+	healStatusStopped  = "stopped"
+	healStatusFinished = "finished"
+)
+
+// healInfo - state of the last started heal operation.
+type healInfo struct {
+	Status    string    `json:"status"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Prefix    string    `json:"prefix,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	// Number of listed objects waiting to be healed.
+	QueueDepth int `json:"queueDepth"`
+	// Number of objects healed at once, and the foreground p99
+	// latency it was adapted to.
+	Workers       int           `json:"workers"`
+	ForegroundP99 time.Duration `json:"foregroundP99"`
+	// Outside of the heal windows, waiting for the next one.
+	Paused    bool   `json:"paused"`
+	Scanned   int64  `json:"scanned"`
+	Failed    int64  `json:"failed"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// healControl - runs at most one heal operation at a time, healing
+// all objects of a bucket or of all buckets.
+type healControl struct {
+	mutex  *sync.Mutex
+	config healConfig
+	info   healInfo
+	stopCh chan struct{}
+	// Latencies heal concurrency adapts to.
+	latency *latencyTracker
+}
+
+// Global heal control, driven by the admin API.
+var globalHealControl = newHealControl()
+
+// newHealControl - returns an idle heal control.
+func newHealControl() *healControl {
+	return &healControl{
+		mutex:   &sync.Mutex{},
+		config:  newHealConfig(),
+		info:    healInfo{Status: healStatusIdle},
+		latency: globalRequestLatency,
+	}
+}
+
+// SetConfig - sets heal concurrency configuration, applies to heal
+// operations started afterwards.
+func (h *healControl) SetConfig(config healConfig) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.config = config
+}
+
+// Info - returns state of the last started heal operation.
+func (h *healControl) Info() healInfo {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.info
+}
+
+// Start - starts healing objects with prefix of bucket in background,
+// all buckets are healed if bucket is empty.
+func (h *healControl) Start(objAPI objectAPI, bucket, prefix string) *probe.Error {
+	healer, ok := objAPI.storage.(fileHealer)
+	if !ok {
+		return probe.NewError(HealNotSupported{})
+	}
+	if bucket != "" {
+		if _, err := objAPI.GetBucketInfo(bucket); err != nil {
+			return err.Trace(bucket)
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.info.Status == healStatusRunning {
+		return probe.NewError(HealAlreadyRunning{})
+	}
+	h.info = healInfo{
+		Status:    healStatusRunning,
+		Bucket:    bucket,
+		Prefix:    prefix,
+		StartTime: time.Now().UTC(),
+		Workers:   1,
+	}
+	h.stopCh = make(chan struct{})
+	go h.run(objAPI, healer, bucket, prefix, h.config, h.stopCh)
+	return nil
+}
+
+// Stop - stops the running heal operation, objects already healed
+// stay healed.
+func (h *healControl) Stop() *probe.Error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.info.Status != healStatusRunning {
+		return probe.NewError(HealNotRunning{})
+	}
+	close(h.stopCh)
+	h.finish(healStatusStopped)
+	return nil
+}
+
+// finish - marks the running heal operation as ended with status,
+// caller should hold the mutex.
+func (h *healControl) finish(status string) {
+	h.info.Status = status
+	h.info.EndTime = time.Now().UTC()
+	h.info.QueueDepth = 0
+}
+
+// healObject - object listed for healing.
+type healObject struct {
+	bucket string
+	object string
+	size   int64
+}
+
+// healLimiter - limits number of objects healed at once, the limit is
+// adapted while healing.
+type healLimiter struct {
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+// acquire - waits until another object may be healed.
+func (l *healLimiter) acquire() {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// release - ends healing of an object.
+func (l *healLimiter) release() {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	l.active--
+	l.cond.Broadcast()
+}
+
+// setLimit - sets number of objects healed at once.
+func (l *healLimiter) setLimit(limit int) {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// healPacer - paces objects checked to the rate limits and windows of
+// a heal operation.
+type healPacer struct {
+	ctx     context.Context
+	windows []healWindow
+	// Throttles of objects and of their bytes, nil if unlimited.
+	iops      *bandwidthThrottle
+	bandwidth *bandwidthThrottle
+}
+
+// newHealPacer - returns pacer of config, waits end once ctx is done.
+func newHealPacer(ctx context.Context, config healConfig) *healPacer {
+	p := &healPacer{ctx: ctx, windows: config.windows()}
+	if config.MaxIOPS > 0 {
+		p.iops = newBandwidthThrottle(config.MaxIOPS)
+	}
+	if config.MaxBandwidth > 0 {
+		p.bandwidth = newBandwidthThrottle(config.MaxBandwidth)
+	}
+	return p
+}
+
+// wait - waits until an object of size may be checked, returns false
+// once ctx is done.
+func (p *healPacer) wait(h *healControl, stopCh chan struct{}, size int64) bool {
+	for !inHealWindows(p.windows, time.Now()) {
+		h.update(stopCh, func(info *healInfo) {
+			info.Paused = true
+		})
+		timer := time.NewTimer(healWindowCheckInterval)
+		select {
+		case <-timer.C:
+		case <-p.ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+	h.update(stopCh, func(info *healInfo) {
+		info.Paused = false
+	})
+	if p.iops != nil && p.iops.wait(p.ctx, 1) != nil {
+		return false
+	}
+	if p.bandwidth != nil && size > 0 {
+		// Bytes are reserved a second of bandwidth at a time, large
+		// objects wait for all but their last second.
+		n := size
+		if n > p.bandwidth.rate {
+			n = p.bandwidth.rate
+		}
+		for ; size > 0; size -= n {
+			if size < n {
+				n = size
+			}
+			if p.bandwidth.wait(p.ctx, int(n)) != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// run - heals all objects of the operation, until stopCh is closed.
+func (h *healControl) run(objAPI objectAPI, healer fileHealer, bucket, prefix string, config healConfig, stopCh chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	pacer := newHealPacer(ctx, config)
+	limiter := &healLimiter{cond: sync.NewCond(&sync.Mutex{}), limit: 1}
+	objects := make(chan healObject)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < config.maxWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				limiter.acquire()
+				if pacer.wait(h, stopCh, object.size) {
+					h.healObject(healer, object, stopCh)
+				}
+				limiter.release()
+			}
+		}()
+	}
+	doneCh := make(chan struct{})
+	go h.adapt(limiter, config, stopCh, doneCh)
+
+	err := h.listObjects(objAPI, bucket, prefix, objects, stopCh)
+	close(objects)
+	wg.Wait()
+	close(doneCh)
+	h.ended(stopCh, err)
+}
+
+// adapt - adapts number of objects healed at once to the latency of
+// foreground requests, until doneCh or stopCh is closed.
+func (h *healControl) adapt(limiter *healLimiter, config healConfig, stopCh, doneCh chan struct{}) {
+	ticker := time.NewTicker(healAdaptInterval)
+	defer ticker.Stop()
+	workers := 1
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		case <-doneCh:
+			return
+		}
+		p99, ok := h.latency.Percentile(0.99, time.Now().UTC().Add(-healLatencyWindow))
+		workers = nextHealWorkers(workers, config.maxWorkers(), p99, ok, config.latencyTarget())
+		limiter.setLimit(workers)
+		h.update(stopCh, func(info *healInfo) {
+			info.Workers = workers
+			info.ForegroundP99 = p99
+		})
+	}
+}
+
+// listObjects - sends all objects of the operation to objects, batch
+// by batch, until stopCh is closed.
+func (h *healControl) listObjects(objAPI objectAPI, bucket, prefix string, objects chan<- healObject, stopCh chan struct{}) *probe.Error {
+	buckets := []string{bucket}
+	if bucket == "" {
+		bucketsInfo, err := objAPI.ListBuckets()
+		if err != nil {
+			errorIf(err.Trace(), "Unable to list buckets to heal.", nil)
+			return err
+		}
+		buckets = nil
+		for _, bucketInfo := range bucketsInfo {
+			buckets = append(buckets, bucketInfo.Name)
+		}
+	}
+	for _, bucket := range buckets {
+		if err := h.listBucket(objAPI, bucket, prefix, objects, stopCh); err != nil {
+			errorIf(err.Trace(bucket, prefix), "Unable to list objects to heal.", nil)
+			return err
+		}
+	}
+	return nil
+}
+
+// listBucket - sends all objects with prefix of bucket to objects,
+// batch by batch.
+func (h *healControl) listBucket(objAPI objectAPI, bucket, prefix string, objects chan<- healObject, stopCh chan struct{}) *probe.Error {
+	marker := ""
+	for {
+		result, err := objAPI.ListObjects(bucket, prefix, marker, "", healListBatchSize)
+		if err != nil {
+			return err.Trace(bucket, prefix, marker)
+		}
+		h.update(stopCh, func(info *healInfo) {
+			info.QueueDepth += len(result.Objects)
+		})
+		for _, object := range result.Objects {
+			select {
+			case objects <- healObject{bucket: bucket, object: object.Name, size: object.Size}:
+			case <-stopCh:
+				return nil
+			}
+		}
+		if !result.IsTruncated || len(result.Objects) == 0 {
+			return nil
+		}
+		marker = result.Objects[len(result.Objects)-1].Name
+	}
+}
+
+// healObject - heals a single object, unless the operation was
+// stopped.
+func (h *healControl) healObject(healer fileHealer, object healObject, stopCh chan struct{}) {
+	select {
+	case <-stopCh:
+		return
+	default:
+	}
+	e := healer.healFile(object.bucket, object.object)
+	if e != nil {
+		log.WithFields(logrus.Fields{
+			"bucket": object.bucket,
+			"object": object.object,
+		}).Errorf("Heal failed with %s", e)
+	}
+	h.update(stopCh, func(info *healInfo) {
+		info.QueueDepth--
+		info.Scanned++
+		if e != nil {
+			info.Failed++
+			info.LastError = e.Error()
+		}
+	})
+}
+
+// update - applies fn to the state of the operation, unless it was
+// stopped.
+func (h *healControl) update(stopCh chan struct{}, fn func(info *healInfo)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.stopCh != stopCh || h.info.Status != healStatusRunning {
+		return
+	}
+	fn(&h.info)
+}
+
+// ended - marks the operation as finished, unless it was stopped.
+func (h *healControl) ended(stopCh chan struct{}, err *probe.Error) {
+	h.update(stopCh, func(info *healInfo) {
+		if err != nil {
+			info.LastError = err.ToGoError().Error()
+		}
+		h.finish(healStatusFinished)
+	})
+}
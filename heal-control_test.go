@@ -0,0 +1,191 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests heal operations rebuild missing parts of objects.
+func TestHealControl(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-heal-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(xl)
+	if err := objAPI.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	objects := []string{"object1", "object2", "prefix/object3"}
+	for _, object := range objects {
+		// Large enough to be erasure coded.
+		data := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+		if _, err := objAPI.PutObject("bucket", object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := newHealControl()
+	if err := h.Stop(); err == nil {
+		t.Fatal("Expected error stopping heal which is not running")
+	}
+	if err := h.Start(objAPI, "missing-bucket", ""); err == nil {
+		t.Fatal("Expected error healing a missing bucket")
+	}
+
+	// Lose parts of all objects on the last disk.
+	for _, object := range objects {
+		if e = os.RemoveAll(filepath.Join(disks[3], "bucket", object)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if err := h.Start(objAPI, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var info healInfo
+	for i := 0; i < 100; i++ {
+		if info = h.Info(); info.Status != healStatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info.Status != healStatusFinished || info.Scanned != int64(len(objects)) || info.Failed != 0 {
+		t.Fatalf("Unexpected heal state %+v", info)
+	}
+	for _, object := range objects {
+		if _, e = os.Stat(filepath.Join(disks[3], "bucket", object, "part.3")); e != nil {
+			t.Fatalf("Expected part of %s to be healed, %s", object, e)
+		}
+	}
+
+	// Objects of a single bucket with prefix are healed.
+	if err := h.Start(objAPI, "bucket", "prefix/"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if info = h.Info(); info.Status != healStatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info.Status != healStatusFinished || info.Scanned != 1 {
+		t.Fatalf("Unexpected heal state %+v", info)
+	}
+}
+
+// Tests objects checked are paced to heal rate limits, and wait for
+// heal windows until the operation is stopped.
+func TestHealPacer(t *testing.T) {
+	h := newHealControl()
+	stopCh := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := newHealPacer(ctx, healConfig{MaxIOPS: 50})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if !p.wait(h, stopCh, 10) {
+			t.Fatal("Expected pacer to allow healing")
+		}
+	}
+	// First object is not delayed, following ones wait for their
+	// share of the second.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("Expected objects to be paced, took %s", elapsed)
+	}
+	p = newHealPacer(ctx, healConfig{MaxBandwidth: 10000})
+	start = time.Now()
+	if !p.wait(h, stopCh, 1000) || !p.wait(h, stopCh, 1) || time.Since(start) < 80*time.Millisecond {
+		t.Fatal("Expected bytes of objects to be paced")
+	}
+
+	// Outside of all windows objects wait until stopped.
+	now := time.Now()
+	closed := fmt.Sprintf("%02d:%02d-%02d:%02d", (now.Hour()+2)%24, now.Minute(), (now.Hour()+3)%24, now.Minute())
+	p = newHealPacer(ctx, healConfig{Windows: closed})
+	cancel()
+	if p.wait(h, stopCh, 0) {
+		t.Fatal("Expected pacer to wait outside of heal windows")
+	}
+}
+
+// Tests heal concurrency backs off once foreground latency exceeds
+// its target and grows while requests are idle or fast.
+func TestNextHealWorkers(t *testing.T) {
+	target := 100 * time.Millisecond
+	testCases := []struct {
+		workers  int
+		p99      time.Duration
+		ok       bool
+		expected int
+	}{
+		// Idle.
+		{1, 0, false, 2},
+		// Well within target.
+		{3, 10 * time.Millisecond, true, 4},
+		// Close to target.
+		{3, 80 * time.Millisecond, true, 3},
+		// Above target.
+		{6, 200 * time.Millisecond, true, 3},
+		{1, 200 * time.Millisecond, true, 1},
+		// Bounded by maximum.
+		{4, 0, false, 4},
+	}
+	for i, testCase := range testCases {
+		if workers := nextHealWorkers(testCase.workers, 4, testCase.p99, testCase.ok, target); workers != testCase.expected {
+			t.Errorf("Test %d: Expected %d workers, got %d", i+1, testCase.expected, workers)
+		}
+	}
+}
+
+// Tests percentiles of recent request latencies.
+func TestLatencyTracker(t *testing.T) {
+	l := newLatencyTracker()
+	start := time.Now().UTC()
+	if _, ok := l.Percentile(0.99, start); ok {
+		t.Fatal("Expected no latencies")
+	}
+	for i := 1; i <= maxLatencySamples+100; i++ {
+		l.Record(time.Duration(i) * time.Millisecond)
+	}
+	if len(l.samples) != maxLatencySamples {
+		t.Fatalf("Expected %d samples, got %d", maxLatencySamples, len(l.samples))
+	}
+	p99, ok := l.Percentile(0.99, start)
+	if !ok || p99 < time.Duration(maxLatencySamples)*time.Millisecond || p99 > time.Duration(maxLatencySamples+100)*time.Millisecond {
+		t.Fatalf("Unexpected p99 %s", p99)
+	}
+	if _, ok = l.Percentile(0.99, time.Now().UTC().Add(time.Second)); ok {
+		t.Fatal("Expected old latencies to be ignored")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errInvalidHealWindow = errors.New("Heal windows must be comma separated [Day[-Day] ]HH:MM-HH:MM ranges")
+
+// Abbreviated names of week days, as used by heal windows.
+var healWindowDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// healWindow - time of day range healing is allowed in on some days
+// of the week. Ranges ending before they start run past midnight,
+// into the next day.
+type healWindow struct {
+	days [7]bool
+	// Minutes since midnight, end is excluded.
+	start, end int
+}
+
+// parseHealWindowDays - parses a day or an inclusive range of days,
+// "Fri-Mon" wraps around the week.
+func parseHealWindowDays(value string) (days [7]bool, e error) {
+	from, to := value, value
+	if i := strings.Index(value, "-"); i >= 0 {
+		from, to = value[:i], value[i+1:]
+	}
+	fromDay, ok := healWindowDays[strings.ToLower(from)]
+	if !ok {
+		return days, errInvalidHealWindow
+	}
+	toDay, ok := healWindowDays[strings.ToLower(to)]
+	if !ok {
+		return days, errInvalidHealWindow
+	}
+	for day := fromDay; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == toDay {
+			return days, nil
+		}
+	}
+}
+
+// parseHealWindowTime - parses HH:MM as minutes since midnight, 24:00
+// ends a day.
+func parseHealWindowTime(value string) (int, error) {
+	var hours, minutes int
+	if n, e := fmt.Sscanf(value, "%d:%d", &hours, &minutes); e != nil || n != 2 || len(value) != 5 {
+		return 0, errInvalidHealWindow
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, errInvalidHealWindow
+	}
+	return hours*60 + minutes, nil
+}
+
+// parseHealWindows - parses comma separated heal windows such as
+// "Mon-Fri 22:00-06:00, Sat-Sun 00:00-24:00". Windows without days
+// apply to every day. Empty value allows healing at any time.
+func parseHealWindows(value string) ([]healWindow, error) {
+	var windows []healWindow
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		window := healWindow{days: [7]bool{true, true, true, true, true, true, true}}
+		parts := strings.Fields(field)
+		switch len(parts) {
+		case 1:
+		case 2:
+			days, e := parseHealWindowDays(parts[0])
+			if e != nil {
+				return nil, e
+			}
+			window.days = days
+			parts = parts[1:]
+		default:
+			return nil, errInvalidHealWindow
+		}
+		times := strings.Split(parts[0], "-")
+		if len(times) != 2 {
+			return nil, errInvalidHealWindow
+		}
+		var e error
+		if window.start, e = parseHealWindowTime(times[0]); e != nil {
+			return nil, e
+		}
+		if window.end, e = parseHealWindowTime(times[1]); e != nil {
+			return nil, e
+		}
+		if window.start == window.end || window.start == 24*60 {
+			return nil, errInvalidHealWindow
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// contains - returns true if t is within the window, in the location
+// of t.
+func (w healWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	// Ranges past midnight belong to the day they start.
+	yesterday := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
+
+// inHealWindows - returns true if healing is allowed at t, always if
+// there are no windows.
+func inHealWindows(windows []healWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests heal windows are parsed and matched in their days and times,
+// also past midnight.
+func TestHealWindows(t *testing.T) {
+	for _, value := range []string{"22:00", "Mon 22:00-", "Mon-Funday 01:00-02:00", "01:00-01:00", "25:00-26:00", "1:00-2:00", "Mon Tue 01:00-02:00", "24:00-01:00"} {
+		if _, e := parseHealWindows(value); e == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+
+	windows, e := parseHealWindows("Mon-Fri 22:00-06:00, Sat-Sun 00:00-24:00")
+	if e != nil {
+		t.Fatal(e)
+	}
+	// 2016-05-02 is a Monday.
+	day := func(d, hour, minute int) time.Time {
+		return time.Date(2016, 5, d, hour, minute, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		t        time.Time
+		expected bool
+	}{
+		// Monday morning, before any window of the week started.
+		{day(2, 3, 0), false},
+		{day(2, 12, 0), false},
+		{day(2, 22, 0), true},
+		// Tuesday morning, past midnight of the Monday window.
+		{day(3, 5, 59), true},
+		{day(3, 6, 0), false},
+		// Saturday morning, past midnight of the Friday window, and
+		// all weekend.
+		{day(7, 3, 0), true},
+		{day(8, 23, 59), true},
+		// Monday morning, the Sunday window ended at midnight.
+		{day(9, 3, 0), false},
+	}
+	for i, testCase := range testCases {
+		if in := inHealWindows(windows, testCase.t); in != testCase.expected {
+			t.Errorf("Test %d: Expected %v at %s, got %v", i+1, testCase.expected, testCase.t, in)
+		}
+	}
+
+	// No windows allows healing at any time, windows without days
+	// apply to every day.
+	if windows, e = parseHealWindows(""); e != nil || !inHealWindows(windows, day(2, 12, 0)) {
+		t.Fatalf("Unexpected windows %v, %v", windows, e)
+	}
+	if windows, e = parseHealWindows("Fri-Mon 01:00-02:00, 12:00-12:30"); e != nil {
+		t.Fatal(e)
+	}
+	if !inHealWindows(windows, day(3, 12, 15)) || !inHealWindows(windows, day(8, 1, 30)) || inHealWindows(windows, day(4, 1, 30)) {
+		t.Fatalf("Unexpected windows %v", windows)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "net/http"
+
+// LivenessHandler - GET /minio/health/live
+// ----------
+// Succeeds as long as the server serves requests.
+func (api healthAPIHandlers) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponse(w, nil)
+}
+
+// ReadinessHandler - GET /minio/health/ready
+// ----------
+// Succeeds once the object layer is initialized and write quorum of
+// its disks is online, fails with 503 otherwise so that requests are
+// routed to other servers meanwhile.
+func (api healthAPIHandlers) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if !isObjectLayerReady(api.ObjectAPI) {
+		writeErrorResponse(w, r, ErrServerNotReady, r.URL.Path)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// isObjectLayerReady - returns true if o is initialized and write
+// quorum of its disks is online. Storage which needs no quorum needs
+// all its disks online, storage which does not report its disks is
+// ready once initialized.
+func isObjectLayerReady(o objectAPI) bool {
+	if o.storage == nil {
+		return false
+	}
+	reporter, ok := o.storage.(diskStatusReporter)
+	if !ok {
+		return true
+	}
+	disksStatus := reporter.DiskStatus()
+	quorum := len(disksStatus)
+	if quorumer, ok := o.storage.(quorumReporter); ok {
+		quorum = quorumer.WriteQuorum()
+	}
+	online := 0
+	for _, status := range disksStatus {
+		if status.Online {
+			online++
+		}
+	}
+	return online >= quorum
+}
@@ -0,0 +1,63 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests erasure coded storage is ready as long as write quorum of its
+// disks is online.
+func TestObjectLayerReady(t *testing.T) {
+	if isObjectLayerReady(objectAPI{}) {
+		t.Fatal("Expected uninitialized object layer not to be ready")
+	}
+
+	var disks []string
+	for i := 0; i < 8; i++ {
+		disk, e := ioutil.TempDir("", "minio-ready-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(xl)
+	if !isObjectLayerReady(objAPI) {
+		t.Fatal("Expected object layer to be ready with all disks online")
+	}
+
+	// Write quorum of 8 disks is 7.
+	if e = os.RemoveAll(disks[0]); e != nil {
+		t.Fatal(e)
+	}
+	if !isObjectLayerReady(objAPI) {
+		t.Fatal("Expected object layer to be ready with write quorum of disks online")
+	}
+	if e = os.RemoveAll(disks[1]); e != nil {
+		t.Fatal(e)
+	}
+	if isObjectLayerReady(objAPI) {
+		t.Fatal("Expected object layer not to be ready without write quorum of disks online")
+	}
+}
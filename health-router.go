@@ -0,0 +1,38 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import router "github.com/gorilla/mux"
+
+// healthAPIHandlers implements and provides http handlers for the
+// health probes.
+type healthAPIHandlers struct {
+	ObjectAPI objectAPI
+}
+
+// registerHealthRouter - registers liveness and readiness probes,
+// served to anonymous requests for orchestrators and load balancers.
+// Registered ahead of the web router serving the rest of /minio/.
+func registerHealthRouter(mux *router.Router, api healthAPIHandlers) {
+	// Health router
+	healthRouter := mux.NewRoute().PathPrefix(reservedBucket + "/health").Subrouter()
+
+	// Liveness probe
+	healthRouter.Methods("GET", "HEAD").Path("/live").HandlerFunc(api.LivenessHandler)
+	// Readiness probe
+	healthRouter.Methods("GET", "HEAD").Path("/ready").HandlerFunc(api.ReadinessHandler)
+}
@@ -0,0 +1,38 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "io"
+
+// Response writers wrapping the one of net/http implement io.ReaderFrom
+// by handing the reader down, so that objects read from files reach
+// the connection's ReadFrom and are sent with sendfile, without being
+// copied through userspace.
+
+// writerOnly - hides io.ReaderFrom of a writer, so that io.Copy to it
+// uses Write.
+type writerOnly struct {
+	io.Writer
+}
+
+// readFrom - copies r to w, with ReadFrom of w if it implements it.
+func readFrom(w io.Writer, r io.Reader) (int64, error) {
+	if readerFrom, ok := w.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(r)
+	}
+	return io.Copy(writerOnly{w}, r)
+}
@@ -0,0 +1,80 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// readerFromRecorder - response recording readers passed to ReadFrom.
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readers []io.Reader
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.readers = append(w.readers, r)
+	return io.Copy(writerOnly{w.ResponseRecorder}, r)
+}
+
+// Tests files copied to wrapped responses reach ReadFrom of the
+// response of net/http, so that they can be sent with sendfile.
+func TestResponseReadFrom(t *testing.T) {
+	file, e := ioutil.TempFile("", "minio-readfrom")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	data := bytes.Repeat([]byte("a"), 4096)
+	if _, e = file.Write(data); e != nil {
+		t.Fatal(e)
+	}
+
+	recorder := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	audit := newStatusResponseWriter(recorder)
+	trace := traceResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(audit),
+		recorder:             &traceBodyRecorder{limit: 100},
+	}
+	w := bucketLogResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(trace),
+		errorBody:            &bytes.Buffer{},
+	}
+
+	file.Seek(0, 0)
+	if _, e = io.CopyN(w, file, int64(len(data))); e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), data) {
+		t.Fatal("Unexpected data written")
+	}
+	if len(recorder.readers) != 1 {
+		t.Fatalf("Expected one ReadFrom call, got %d", len(recorder.readers))
+	}
+	if limited, ok := recorder.readers[0].(*io.LimitedReader); !ok || limited.R != file {
+		t.Fatalf("Expected file to be passed down, got %T", recorder.readers[0])
+	}
+	if audit.count != int64(len(data)) || trace.recorder.buffer.Len() != 100 {
+		t.Fatalf("Unexpected counts %d and %d", audit.count, trace.recorder.buffer.Len())
+	}
+}
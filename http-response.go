@@ -0,0 +1,86 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// statusResponseWriter - records status code, bytes written and start
+// of the response. Handlers wrapping the response embed it and only
+// implement what they do on top of it.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	count       int64
+	// Time the status line or first byte of the response was written.
+	started time.Time
+}
+
+func newStatusResponseWriter(w http.ResponseWriter) *statusResponseWriter {
+	return &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+// start - records the start of the response, once.
+func (w *statusResponseWriter) start() {
+	w.wroteHeader = true
+	if w.started.IsZero() {
+		w.started = time.Now()
+	}
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+	}
+	w.start()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusResponseWriter) Write(p []byte) (int, error) {
+	w.start()
+	n, err := w.ResponseWriter.Write(p)
+	w.count += int64(n)
+	return n, err
+}
+
+// ReadFrom - writes data read from r, sent without copying if the
+// response supports it.
+func (w *statusResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.start()
+	n, err := readFrom(w.ResponseWriter, r)
+	w.count += n
+	return n, err
+}
+
+// Flush - flushes the response, if supported.
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify - notifies when the client goes away, if supported.
+func (w *statusResponseWriter) CloseNotify() <-chan bool {
+	if closeNotifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return closeNotifier.CloseNotify()
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum number of request and response body bytes traced.
+	maxTraceBodySize = 64 * 1024
+
+	// Maximum number of traces queued for a listener, traces are
+	// dropped for listeners which are not keeping up.
+	maxTraceQueueSize = 1000
+)
+
+// Signatures of traced Authorization headers and presigned queries
+// are redacted.
+var (
+	traceSignatureRegexp      = regexp.MustCompile(`Signature=[0-9a-fA-F]+`)
+	traceQuerySignatureRegexp = regexp.MustCompile(`X-Amz-Signature=[0-9a-fA-F]+`)
+)
+
+// traceInfo - a single traced request along with its response.
+type traceInfo struct {
+	Time           time.Time     `json:"time"`
+	RemoteAddr     string        `json:"remoteAddr"`
+	Method         string        `json:"method"`
+	Path           string        `json:"path"`
+	RawQuery       string        `json:"rawQuery,omitempty"`
+	RequestHeader  http.Header   `json:"requestHeader"`
+	RequestBody    string        `json:"requestBody,omitempty"`
+	StatusCode     int           `json:"statusCode"`
+	ResponseHeader http.Header   `json:"responseHeader"`
+	ResponseBody   string        `json:"responseBody,omitempty"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// traceListener - an operator tracing requests of a bucket, filtered
+// by object name prefix. Empty bucket traces all buckets.
+type traceListener struct {
+	bucket string
+	prefix string
+	// Number of body bytes traced, zero traces headers only.
+	bodySize int
+	ch       chan traceInfo
+}
+
+// matches - returns true if requests for object of bucket should be
+// traced for the listener.
+func (l *traceListener) matches(bucket, object string) bool {
+	if l.bucket == "" {
+		return true
+	}
+	return l.bucket == bucket && strings.HasPrefix(object, l.prefix)
+}
+
+// httpTracer - carries all connected trace listeners.
+type httpTracer struct {
+	rwMutex   *sync.RWMutex
+	listeners map[*traceListener]struct{}
+}
+
+// Global HTTP tracer, driven by the admin API.
+var globalHTTPTracer = &httpTracer{
+	rwMutex:   &sync.RWMutex{},
+	listeners: make(map[*traceListener]struct{}),
+}
+
+// AddListener - registers a new listener, the listener must be removed
+// with RemoveListener once done.
+func (t *httpTracer) AddListener(bucket, prefix string, bodySize int) *traceListener {
+	listener := &traceListener{
+		bucket:   bucket,
+		prefix:   prefix,
+		bodySize: bodySize,
+		ch:       make(chan traceInfo, maxTraceQueueSize),
+	}
+	t.rwMutex.Lock()
+	defer t.rwMutex.Unlock()
+	t.listeners[listener] = struct{}{}
+	return listener
+}
+
+// RemoveListener - unregisters a listener, no more traces are sent to
+// it afterwards.
+func (t *httpTracer) RemoveListener(listener *traceListener) {
+	t.rwMutex.Lock()
+	defer t.rwMutex.Unlock()
+	delete(t.listeners, listener)
+}
+
+// matchingListeners - returns listeners tracing requests for object of
+// bucket.
+func (t *httpTracer) matchingListeners(bucket, object string) (listeners []*traceListener) {
+	t.rwMutex.RLock()
+	defer t.rwMutex.RUnlock()
+	for listener := range t.listeners {
+		if listener.matches(bucket, object) {
+			listeners = append(listeners, listener)
+		}
+	}
+	return listeners
+}
+
+// traceBodyRecorder - records the first bytes of a body, up to its
+// limit.
+type traceBodyRecorder struct {
+	limit  int
+	buffer bytes.Buffer
+}
+
+func (b *traceBodyRecorder) record(p []byte) {
+	if left := b.limit - b.buffer.Len(); left > 0 {
+		if len(p) > left {
+			p = p[:left]
+		}
+		b.buffer.Write(p)
+	}
+}
+
+// traceRequestBody - records the request body while it is read.
+type traceRequestBody struct {
+	io.ReadCloser
+	recorder *traceBodyRecorder
+}
+
+func (b traceRequestBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	b.recorder.record(p[:n])
+	return n, err
+}
+
+// traceResponseWriter - records status code and body of the response.
+type traceResponseWriter struct {
+	*statusResponseWriter
+	recorder *traceBodyRecorder
+}
+
+func (w traceResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.statusResponseWriter.Write(p)
+	w.recorder.record(p[:n])
+	return n, err
+}
+
+// ReadFrom - writes data read from r, the part of it recorded is
+// written with Write, the rest is sent without copying if the response
+// supports it.
+func (w traceResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.CopyN(writerOnly{w}, r, int64(w.recorder.limit-w.recorder.buffer.Len()))
+	if err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		return n, err
+	}
+	rest, err := w.statusResponseWriter.ReadFrom(r)
+	return n + rest, err
+}
+
+// redactTraceHeader - returns copy of header with secrets redacted.
+func redactTraceHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for key, values := range header {
+		redacted[key] = append([]string{}, values...)
+	}
+	if auth := redacted.Get("Authorization"); auth != "" {
+		redacted.Set("Authorization", traceSignatureRegexp.ReplaceAllString(auth, "Signature=*REDACTED*"))
+	}
+	return redacted
+}
+
+// truncateTraceBody - returns the first size bytes of body.
+func truncateTraceBody(body []byte, size int) string {
+	if len(body) > size {
+		body = body[:size]
+	}
+	return string(body)
+}
+
+// httpTraceHandler - sends requests and their responses to matching
+// trace listeners, requests for the reserved bucket are not traced.
+type httpTraceHandler struct {
+	handler http.Handler
+}
+
+func setHTTPTraceHandler(h http.Handler) http.Handler {
+	return httpTraceHandler{handler: h}
+}
+
+func (h httpTraceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Skip the first element which is usually '/' and split the rest.
+	splits := strings.SplitN(r.URL.Path[1:], "/", 2)
+	bucketName, objectName := splits[0], ""
+	if len(splits) == 2 {
+		objectName = splits[1]
+	}
+	if "/"+bucketName == reservedBucket {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	listeners := globalHTTPTracer.matchingListeners(bucketName, objectName)
+	if len(listeners) == 0 {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	var bodySize int
+	for _, listener := range listeners {
+		if listener.bodySize > bodySize {
+			bodySize = listener.bodySize
+		}
+	}
+
+	trace := traceInfo{
+		Time:          time.Now().UTC(),
+		RemoteAddr:    r.RemoteAddr,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		RawQuery:      traceQuerySignatureRegexp.ReplaceAllString(r.URL.RawQuery, "X-Amz-Signature=*REDACTED*"),
+		RequestHeader: redactTraceHeader(r.Header),
+	}
+	requestRecorder := &traceBodyRecorder{limit: bodySize}
+	if r.Body != nil {
+		r.Body = traceRequestBody{ReadCloser: r.Body, recorder: requestRecorder}
+	}
+	responseRecorder := &traceBodyRecorder{limit: bodySize}
+	tw := traceResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(w),
+		recorder:             responseRecorder,
+	}
+	h.handler.ServeHTTP(tw, r)
+	trace.StatusCode = tw.statusCode
+	trace.ResponseHeader = redactTraceHeader(w.Header())
+	trace.Duration = time.Now().UTC().Sub(trace.Time)
+
+	for _, listener := range listeners {
+		listenerTrace := trace
+		listenerTrace.RequestBody = truncateTraceBody(requestRecorder.buffer.Bytes(), listener.bodySize)
+		listenerTrace.ResponseBody = truncateTraceBody(responseRecorder.buffer.Bytes(), listener.bodySize)
+		select {
+		case listener.ch <- listenerTrace:
+		default:
+			// Listener is not keeping up, drop the trace.
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests requests are traced for matching listeners only.
+func TestHTTPTraceHandler(t *testing.T) {
+	handler := setHTTPTraceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("ETag", "etag")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("response body"))
+	}))
+	headers := globalHTTPTracer.AddListener("bucket", "photos/", 0)
+	defer globalHTTPTracer.RemoveListener(headers)
+	bodies := globalHTTPTracer.AddListener("", "", 8)
+	defer globalHTTPTracer.RemoveListener(bodies)
+
+	for _, path := range []string{"/other/photos/a", "/bucket/videos/b", "/bucket/photos/c"} {
+		req, e := http.NewRequest("PUT", path+"?X-Amz-Signature=abcdef", strings.NewReader("request body"))
+		if e != nil {
+			t.Fatal(e)
+		}
+		req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=access/20160101/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=0123abcd")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	trace := <-headers.ch
+	if trace.Path != "/bucket/photos/c" || trace.Method != "PUT" || trace.StatusCode != http.StatusNotFound {
+		t.Fatalf("Unexpected trace %+v", trace)
+	}
+	if trace.RequestBody != "" || trace.ResponseBody != "" {
+		t.Fatalf("Expected no bodies, got %+v", trace)
+	}
+	if trace.ResponseHeader.Get("ETag") != "etag" {
+		t.Fatalf("Unexpected response header %v", trace.ResponseHeader)
+	}
+	if strings.Contains(trace.RequestHeader.Get("Authorization"), "0123abcd") || strings.Contains(trace.RawQuery, "abcdef") {
+		t.Fatalf("Expected signatures to be redacted, got %+v", trace)
+	}
+	if len(headers.ch) != 0 {
+		t.Fatal("Expected a single trace of matching requests")
+	}
+
+	if len(bodies.ch) != 3 {
+		t.Fatalf("Expected all requests to be traced, got %d", len(bodies.ch))
+	}
+	trace = <-bodies.ch
+	if trace.Path != "/other/photos/a" || trace.RequestBody != "request " || trace.ResponseBody != "response" {
+		t.Fatalf("Unexpected trace %+v", trace)
+	}
+}
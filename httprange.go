@@ -19,6 +19,8 @@ package main
 import (
 	"errors"
 	"fmt"
+	"mime/multipart"
+	"net/textproto"
 	"strconv"
 	"strings"
 
@@ -27,6 +29,9 @@ import (
 
 const (
 	b = "bytes="
+
+	// Maximum number of ranges of a Range header.
+	maxHTTPRanges = 100
 )
 
 // InvalidRange - invalid range
@@ -49,21 +54,32 @@ func (r *httpRange) String() string {
 	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, r.size)
 }
 
-// Grab new range from request header
-func getRequestedRange(hrange string, size int64) (*httpRange, *probe.Error) {
-	r := &httpRange{
-		start:  0,
-		length: 0,
-		size:   0,
+// getRequestedRanges - returns ranges of Range header hrange of an
+// object of size, none if hrange is empty.
+func getRequestedRanges(hrange string, size int64) ([]*httpRange, *probe.Error) {
+	if hrange == "" {
+		return nil, nil
 	}
-	r.size = size
-	if hrange != "" {
-		err := r.parseRange(hrange)
-		if err != nil {
-			return nil, err.Trace()
+	if !strings.HasPrefix(hrange, b) {
+		return nil, probe.NewError(InvalidRange{})
+	}
+	ras := strings.Split(hrange[len(b):], ",")
+	if len(ras) > maxHTTPRanges {
+		return nil, probe.NewError(errors.New("too many ranges specified"))
+	}
+	var ranges []*httpRange
+	for _, ra := range ras {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			return nil, probe.NewError(InvalidRange{})
 		}
+		r := &httpRange{size: size}
+		if err := r.parse(ra); err != nil {
+			return nil, err.Trace(ra)
+		}
+		ranges = append(ranges, r)
 	}
-	return r, nil
+	return ranges, nil
 }
 
 func (r *httpRange) parse(ra string) *probe.Error {
@@ -82,11 +98,14 @@ func (r *httpRange) parse(ra string) *probe.Error {
 		if i > r.size {
 			i = r.size
 		}
+		if i <= 0 {
+			return probe.NewError(InvalidRange{})
+		}
 		r.start = r.size - i
 		r.length = r.size - r.start
 	} else {
 		i, err := strconv.ParseInt(start, 10, 64)
-		if err != nil || i > r.size || i < 0 {
+		if err != nil || i >= r.size || i < 0 {
 			return probe.NewError(InvalidRange{})
 		}
 		r.start = i
@@ -107,27 +126,34 @@ func (r *httpRange) parse(ra string) *probe.Error {
 	return nil
 }
 
-// parseRange parses a Range header string as per RFC 2616.
-func (r *httpRange) parseRange(s string) *probe.Error {
-	if s == "" {
-		return probe.NewError(errors.New("header not present"))
-	}
-	if !strings.HasPrefix(s, b) {
-		return probe.NewError(InvalidRange{})
-	}
+// countWriter - counts bytes written to it, discards them.
+type countWriter int64
 
-	ras := strings.Split(s[len(b):], ",")
-	if len(ras) == 0 {
-		return probe.NewError(errors.New("invalid request"))
-	}
-	// Just pick the first one and ignore the rest, we only support one range per object
-	if len(ras) > 1 {
-		return probe.NewError(errors.New("multiple ranges specified"))
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
+
+// rangePartHeader - returns header of the part of a multipart/byteranges
+// response holding hrange of an object of contentType.
+func rangePartHeader(hrange *httpRange, contentType string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {hrange.String()},
+		"Content-Type":  {contentType},
 	}
+}
 
-	ra := strings.TrimSpace(ras[0])
-	if ra == "" {
-		return probe.NewError(InvalidRange{})
+// rangesMIMESize - returns size of the multipart/byteranges response
+// holding ranges of an object of contentType, parts separated by
+// boundary.
+func rangesMIMESize(ranges []*httpRange, contentType, boundary string) int64 {
+	var size countWriter
+	mimeWriter := multipart.NewWriter(&size)
+	mimeWriter.SetBoundary(boundary)
+	for _, hrange := range ranges {
+		mimeWriter.CreatePart(rangePartHeader(hrange, contentType))
+		size += countWriter(hrange.length)
 	}
-	return r.parse(ra)
+	mimeWriter.Close()
+	return int64(size)
 }
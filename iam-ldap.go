@@ -0,0 +1,170 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Default timeout of LDAP authentications in seconds.
+const defaultLDAPTimeout = 10
+
+var (
+	// errLDAPInvalidCredentials - user is unknown, or its password
+	// is wrong or empty.
+	errLDAPInvalidCredentials = errors.New("Invalid LDAP credentials")
+	// errLDAPNoPolicy - user authenticated but none of its groups is
+	// mapped to a policy.
+	errLDAPNoPolicy = errors.New("No policy is mapped to the groups of the LDAP user")
+)
+
+// ldapConfig - LDAP directory STS requests are authenticated against.
+// Users bind with their own credentials as UserDNFormat with the
+// escaped username substituted for %s. Their groups are the entries
+// under GroupSearchBase whose GroupMemberAttribute is the user DN, and
+// are mapped to policies by DN or by the value of GroupNameAttribute.
+type ldapConfig struct {
+	Enable               bool              `json:"enable"`
+	ServerAddr           string            `json:"serverAddr"`
+	TLS                  bool              `json:"tls"`
+	UserDNFormat         string            `json:"userDNFormat"`
+	GroupSearchBase      string            `json:"groupSearchBase"`
+	GroupMemberAttribute string            `json:"groupMemberAttribute"`
+	GroupNameAttribute   string            `json:"groupNameAttribute"`
+	GroupPolicies        map[string]string `json:"groupPolicies"`
+	// Timeout of authentications in seconds.
+	Timeout int64 `json:"timeout"`
+}
+
+// newLDAPConfig - LDAP configuration for fresh and migrated configs.
+func newLDAPConfig() ldapConfig {
+	return ldapConfig{
+		TLS:                  true,
+		GroupMemberAttribute: "member",
+		GroupNameAttribute:   "cn",
+		GroupPolicies:        make(map[string]string),
+		Timeout:              defaultLDAPTimeout,
+	}
+}
+
+// timeout - returns timeout of authentications, default if not
+// configured.
+func (c ldapConfig) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultLDAPTimeout * time.Second
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// ldapAuthenticate - authenticates username with password against the
+// directory, returns the DN of the user and the sorted policies
+// mapped to its groups.
+func ldapAuthenticate(config ldapConfig, username, password string) (string, []string, error) {
+	// Empty passwords are rejected, servers treat them as
+	// unauthenticated binds which succeed for any DN.
+	if username == "" || password == "" {
+		return "", nil, errLDAPInvalidCredentials
+	}
+	conn, e := dialLDAP(config)
+	if e != nil {
+		return "", nil, e
+	}
+	defer conn.Close()
+
+	userDN := strings.Replace(config.UserDNFormat, "%s", ldapEscapeDN(username), -1)
+	if e = conn.Bind(userDN, password); e != nil {
+		if ldap.IsErrorWithCode(e, ldap.LDAPResultInvalidCredentials) {
+			return "", nil, errLDAPInvalidCredentials
+		}
+		return "", nil, e
+	}
+	filter := fmt.Sprintf("(%s=%s)", config.GroupMemberAttribute, ldap.EscapeFilter(userDN))
+	result, e := conn.Search(ldap.NewSearchRequest(config.GroupSearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, []string{config.GroupNameAttribute}, nil))
+	if e != nil {
+		return "", nil, e
+	}
+	policySet := make(map[string]struct{})
+	for _, group := range result.Entries {
+		names := append([]string{group.DN}, group.GetAttributeValues(config.GroupNameAttribute)...)
+		for _, name := range names {
+			if policy, ok := config.GroupPolicies[name]; ok {
+				policySet[policy] = struct{}{}
+			}
+		}
+	}
+	if len(policySet) == 0 {
+		return "", nil, errLDAPNoPolicy
+	}
+	policies := []string{}
+	for policy := range policySet {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	return userDN, policies, nil
+}
+
+// dialLDAP - connects to the LDAP server, over TLS if configured. All
+// operations of the connection must complete within the timeout.
+func dialLDAP(config ldapConfig) (*ldap.Conn, error) {
+	host, _, e := net.SplitHostPort(config.ServerAddr)
+	if e != nil {
+		return nil, e
+	}
+	scheme := "ldap://"
+	if config.TLS {
+		scheme = "ldaps://"
+	}
+	conn, e := ldap.DialURL(scheme+config.ServerAddr,
+		ldap.DialWithDialer(&net.Dialer{Timeout: config.timeout()}),
+		ldap.DialWithTLSConfig(&tls.Config{ServerName: host}))
+	if e != nil {
+		return nil, e
+	}
+	conn.SetTimeout(config.timeout())
+	return conn, nil
+}
+
+// ldapEscapeDN - escapes special characters of an attribute value in
+// a distinguished name, RFC 4514.
+func ldapEscapeDN(value string) string {
+	var escaped strings.Builder
+	for i, c := range value {
+		switch {
+		case strings.ContainsRune(",+\"\\<>;=", c),
+			i == 0 && (c == '#' || c == ' '),
+			i+utf8.RuneLen(c) == len(value) && c == ' ':
+			escaped.WriteByte('\\')
+			escaped.WriteRune(c)
+		case c == 0:
+			escaped.WriteString("\\00")
+		default:
+			escaped.WriteRune(c)
+		}
+	}
+	return escaped.String()
+}
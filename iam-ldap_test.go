@@ -0,0 +1,198 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Tests special characters of usernames are escaped in DNs.
+func TestLDAPEscapeDN(t *testing.T) {
+	testCases := []struct {
+		value   string
+		escaped string
+	}{
+		{"alice", "alice"},
+		{"alice,ou=admins", "alice\\,ou\\=admins"},
+		{"#alice ", "\\#alice\\ "},
+		{"a+b\\c", "a\\+b\\\\c"},
+	}
+	for i, testCase := range testCases {
+		if escaped := ldapEscapeDN(testCase.value); escaped != testCase.escaped {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.escaped, escaped)
+		}
+	}
+}
+
+// fakeLDAPServer - serves binds of users with passwords, and searches
+// of groups by member.
+type fakeLDAPServer struct {
+	listener  net.Listener
+	passwords map[string]string
+	groups    map[string][]*ldap.Entry
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, e := s.listener.Accept()
+		if e != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func ldapTestResult(op ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return result
+}
+
+func ldapTestEntry(entry *ldap.Entry) *ber.Packet {
+	attributes := ber.NewSequence("")
+	for _, attribute := range entry.Attributes {
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range attribute.Values {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attr := ber.NewSequence("")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attribute.Name, ""))
+		attr.AppendChild(values)
+		attributes.AppendChild(attr)
+	}
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, ""))
+	op.AppendChild(attributes)
+	return op
+}
+
+func (s *fakeLDAPServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	bound := ""
+	for {
+		message, e := ber.ReadPacket(conn)
+		if e != nil {
+			return
+		}
+		msgID := message.Children[0].Value
+		op := message.Children[1]
+		reply := func(op *ber.Packet) {
+			envelope := ber.NewSequence("")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, ""))
+			envelope.AppendChild(op)
+			conn.Write(envelope.Bytes())
+		}
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+			if p, ok := s.passwords[dn]; !ok || p != password {
+				reply(ldapTestResult(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials))
+				continue
+			}
+			bound = dn
+			reply(ldapTestResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess))
+		case ldap.ApplicationSearchRequest:
+			if bound == "" {
+				reply(ldapTestResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultInappropriateAuthentication))
+				continue
+			}
+			// Filter is an equality match on the member attribute.
+			member := op.Children[6].Children[1].Data.String()
+			for _, group := range s.groups[member] {
+				reply(ldapTestEntry(group))
+			}
+			reply(ldapTestResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+// Tests LDAP users are authenticated with their directory password and
+// get the policies mapped to their groups.
+func TestLDAPAuthenticate(t *testing.T) {
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer listener.Close()
+	aliceDN := "uid=alice,ou=people,dc=example,dc=com"
+	bobDN := "uid=bob,ou=people,dc=example,dc=com"
+	server := &fakeLDAPServer{
+		listener: listener,
+		passwords: map[string]string{
+			aliceDN: "alice-password",
+			bobDN:   "bob-password",
+		},
+		groups: map[string][]*ldap.Entry{
+			aliceDN: {
+				ldap.NewEntry("cn=readers,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"readers"}}),
+				ldap.NewEntry("cn=photos,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"photos"}}),
+			},
+			bobDN: {
+				ldap.NewEntry("cn=guests,ou=groups,dc=example,dc=com", map[string][]string{"cn": {"guests"}}),
+			},
+		},
+	}
+	go server.serve()
+
+	config := newLDAPConfig()
+	config.Enable = true
+	config.TLS = false
+	config.ServerAddr = listener.Addr().String()
+	config.UserDNFormat = "uid=%s,ou=people,dc=example,dc=com"
+	config.GroupSearchBase = "ou=groups,dc=example,dc=com"
+	config.GroupPolicies = map[string]string{
+		"readers":                               iamPolicyReadOnly,
+		"cn=photos,ou=groups,dc=example,dc=com": "photos",
+	}
+
+	userDN, policies, e := ldapAuthenticate(config, "alice", "alice-password")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if userDN != aliceDN {
+		t.Fatalf("Expected %s, got %s", aliceDN, userDN)
+	}
+	if !reflect.DeepEqual(policies, []string{"photos", iamPolicyReadOnly}) {
+		t.Fatalf("Unexpected policies %v", policies)
+	}
+
+	testCases := []struct {
+		username string
+		password string
+		err      error
+	}{
+		{"alice", "wrong-password", errLDAPInvalidCredentials},
+		// Unauthenticated binds are not allowed.
+		{"alice", "", errLDAPInvalidCredentials},
+		{"", "alice-password", errLDAPInvalidCredentials},
+		{"bob", "bob-password", errLDAPNoPolicy},
+	}
+	for i, testCase := range testCases {
+		if _, _, e = ldapAuthenticate(config, testCase.username, testCase.password); e != testCase.err {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.err, e)
+		}
+	}
+}
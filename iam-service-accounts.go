@@ -0,0 +1,127 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Maximum number of service accounts of a parent.
+const maxServiceAccountsPerParent = 100
+
+// serviceAccount - long lived credential created by a user, or the
+// server credential, for an application. It is allowed what its parent
+// is allowed, restricted further by its own policy if set, and can be
+// removed without affecting the parent or its other service accounts.
+type serviceAccount struct {
+	AccessKey  string    `json:"accessKey"`
+	SecretKey  string    `json:"secretKey,omitempty"`
+	Parent     string    `json:"parent"`
+	Policy     string    `json:"policy,omitempty"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+// AddServiceAccount - creates a service account of parent, restricted
+// by policy if not empty. Returned service account has its secret key.
+func (u *iamUsers) AddServiceAccount(parent, policy string) (serviceAccount, *probe.Error) {
+	cred, err := genAccessKeys()
+	if err != nil {
+		return serviceAccount{}, err.Trace(parent)
+	}
+	account := serviceAccount{
+		AccessKey:  cred.AccessKeyID,
+		SecretKey:  cred.SecretAccessKey,
+		Parent:     parent,
+		Policy:     policy,
+		CreateTime: time.Now().UTC(),
+	}
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	if !isRootAccessKey(parent) {
+		if _, ok := u.users[parent]; !ok {
+			return serviceAccount{}, probe.NewError(UserNotFound{AccessKey: parent})
+		}
+	}
+	if policy != "" && !u.hasPolicy(policy) {
+		return serviceAccount{}, probe.NewError(PolicyNotFound{Policy: policy})
+	}
+	count := 0
+	for _, other := range u.accounts {
+		if other.Parent == parent {
+			count++
+		}
+	}
+	if count >= maxServiceAccountsPerParent {
+		return serviceAccount{}, probe.NewError(TooManyServiceAccounts{Parent: parent})
+	}
+	u.accounts[account.AccessKey] = account
+	if err = u.save(); err != nil {
+		delete(u.accounts, account.AccessKey)
+		return serviceAccount{}, err.Trace(parent)
+	}
+	return account, nil
+}
+
+// IsServiceAccount - returns true if accessKey is of a service account.
+func (u *iamUsers) IsServiceAccount(accessKey string) bool {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	_, ok := u.accounts[accessKey]
+	return ok
+}
+
+// ListServiceAccounts - returns service accounts of parent, of all
+// parents if parent is empty, sorted by access key and without their
+// secret keys.
+func (u *iamUsers) ListServiceAccounts(parent string) []serviceAccount {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	accounts := []serviceAccount{}
+	for _, account := range u.accounts {
+		if parent != "" && account.Parent != parent {
+			continue
+		}
+		account.SecretKey = ""
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].AccessKey < accounts[j].AccessKey })
+	return accounts
+}
+
+// RemoveServiceAccount - removes service account with accessKey of
+// parent, of any parent if parent is empty, along with the temporary
+// credentials issued to it.
+func (u *iamUsers) RemoveServiceAccount(parent, accessKey string) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	account, ok := u.accounts[accessKey]
+	if !ok || (parent != "" && account.Parent != parent) {
+		return probe.NewError(ServiceAccountNotFound{AccessKey: accessKey})
+	}
+	u.removeServiceAccount(accessKey)
+	return u.save().Trace(accessKey)
+}
+
+// removeServiceAccount - removes service account with accessKey and
+// the temporary credentials issued to it, callers hold the write lock.
+func (u *iamUsers) removeServiceAccount(accessKey string) {
+	delete(u.accounts, accessKey)
+	u.removeTempCredentials(accessKey)
+}
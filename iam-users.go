@@ -0,0 +1,706 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	router "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Users and policies are saved in this file in minioMetaVolume, which
+// is written to all disks.
+const iamConfigFile = ".iam/users.json"
+
+// Maximum size of the saved users and policies.
+const maxIAMConfigSize = 16 * 1024 * 1024
+
+// Number of random bytes of session tokens.
+const sessionTokenLength = 48
+
+// Canned policies, which can be attached to users without defining
+// them first.
+const (
+	iamPolicyReadOnly  = "readonly"
+	iamPolicyWriteOnly = "writeonly"
+	iamPolicyReadWrite = "readwrite"
+)
+
+// iamStatement - allows or denies actions on resources. Actions are
+// S3 API names prefixed with "s3:", resources are buckets and objects
+// in 'arn:aws:s3:::bucket/object' format, both may contain '*'
+// wildcards.
+type iamStatement struct {
+	Effect    string   `json:"effect"`
+	Actions   []string `json:"actions"`
+	Resources []string `json:"resources"`
+}
+
+// iamPolicy - statements evaluated for requests of users the policy is
+// attached to. Requests are allowed if any statement allows them and
+// none denies them.
+type iamPolicy struct {
+	Statements []iamStatement `json:"statements"`
+}
+
+// cannedPolicies - policies available without defining them.
+var cannedPolicies = map[string]iamPolicy{
+	iamPolicyReadOnly: {Statements: []iamStatement{{
+		Effect:    "Allow",
+		Actions:   []string{"s3:Get*", "s3:Head*", "s3:List*"},
+		Resources: []string{AWSResourcePrefix + "*"},
+	}}},
+	iamPolicyWriteOnly: {Statements: []iamStatement{{
+		Effect: "Allow",
+		Actions: []string{
+			"s3:PutObject",
+			"s3:PutObjectPart",
+			"s3:NewMultipartUpload",
+			"s3:CompleteMultipartUpload",
+			"s3:AbortMultipartUpload",
+			"s3:PostPolicyBucket",
+		},
+		Resources: []string{AWSResourcePrefix + "*"},
+	}}},
+	iamPolicyReadWrite: {Statements: []iamStatement{{
+		Effect:    "Allow",
+		Actions:   []string{"s3:*"},
+		Resources: []string{AWSResourcePrefix + "*"},
+	}}},
+}
+
+// isCannedPolicy - returns true if name is a canned policy.
+func isCannedPolicy(name string) bool {
+	_, ok := cannedPolicies[name]
+	return ok
+}
+
+// isValidIAMPolicy - validates statements of a custom policy.
+func isValidIAMPolicy(policy iamPolicy) error {
+	if len(policy.Statements) == 0 {
+		return errors.New("Policy has no statements")
+	}
+	for _, statement := range policy.Statements {
+		if statement.Effect != "Allow" && statement.Effect != "Deny" {
+			return errors.New("Unsupported effect " + statement.Effect)
+		}
+		if len(statement.Actions) == 0 || len(statement.Resources) == 0 {
+			return errors.New("Statement has no actions or resources")
+		}
+		for _, action := range statement.Actions {
+			if !strings.HasPrefix(action, "s3:") {
+				return errors.New("Unsupported action " + action)
+			}
+		}
+		for _, resource := range statement.Resources {
+			if !strings.HasPrefix(resource, AWSResourcePrefix) {
+				return errors.New("Unsupported resource " + resource)
+			}
+		}
+	}
+	return nil
+}
+
+// iamPatternMatch - returns true if name matches pattern, '*' in
+// pattern matches any sequence of characters.
+func iamPatternMatch(pattern, name string) bool {
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, pattern[:star]) {
+		return false
+	}
+	rest := pattern[star+1:]
+	for i := star; i <= len(name); i++ {
+		if iamPatternMatch(rest, name[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches - returns true if statement applies to action on resource.
+func (s iamStatement) matches(action, resource string) bool {
+	actionMatched := false
+	for _, pattern := range s.Actions {
+		if iamPatternMatch(pattern, action) {
+			actionMatched = true
+			break
+		}
+	}
+	if !actionMatched {
+		return false
+	}
+	for _, pattern := range s.Resources {
+		if iamPatternMatch(pattern, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed - returns true if policy allows action on resource.
+func (p iamPolicy) isAllowed(action, resource string) bool {
+	allowed := false
+	for _, statement := range p.Statements {
+		if !statement.matches(action, resource) {
+			continue
+		}
+		if statement.Effect == "Deny" {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// iamUser - credentials of a user and the name of the policy attached
+// to it. Users without a policy are denied all requests.
+type iamUser struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey,omitempty"`
+	Policy    string `json:"policy"`
+}
+
+// tempCredential - temporary credential issued by AssumeRole on behalf
+// of its parent, the user or server credential which assumed the role.
+// Requests signed with it carry its session token and are restricted by
+// the policy of the parent, or by its own policies if it was issued to
+// an external identity, e.g. an LDAP user.
+type tempCredential struct {
+	AccessKey    string    `json:"accessKey"`
+	SecretKey    string    `json:"secretKey"`
+	SessionToken string    `json:"sessionToken"`
+	Parent       string    `json:"parent"`
+	Policies     []string  `json:"policies,omitempty"`
+	Expiration   time.Time `json:"expiration"`
+}
+
+// iamConfig - users, custom policies, service accounts and unexpired
+// temporary credentials as saved.
+type iamConfig struct {
+	Users           []iamUser            `json:"users"`
+	Policies        map[string]iamPolicy `json:"policies"`
+	ServiceAccounts []serviceAccount     `json:"serviceAccounts"`
+	TempCredentials []tempCredential     `json:"tempCredentials"`
+}
+
+// iamUsers - users created by admins in addition to the credential in
+// the server config, which is always allowed all requests.
+type iamUsers struct {
+	rwMutex   *sync.RWMutex
+	objAPI    *objectAPI
+	users     map[string]iamUser
+	policies  map[string]iamPolicy
+	accounts  map[string]serviceAccount
+	tempCreds map[string]tempCredential
+}
+
+func newIAMUsers() *iamUsers {
+	return &iamUsers{
+		rwMutex:   &sync.RWMutex{},
+		users:     make(map[string]iamUser),
+		policies:  make(map[string]iamPolicy),
+		accounts:  make(map[string]serviceAccount),
+		tempCreds: make(map[string]tempCredential),
+	}
+}
+
+// Global users and policies, driven by the admin API.
+var globalIAMUsers = newIAMUsers()
+
+// Load - loads users and policies saved by o, changes are saved to o
+// from now on.
+func (u *iamUsers) Load(o objectAPI) *probe.Error {
+	configBytes, e := o.readMetaFile(iamConfigFile, maxIAMConfigSize)
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		return probe.NewError(e)
+	}
+	config := iamConfig{}
+	if e == nil {
+		if e = json.Unmarshal(configBytes, &config); e != nil {
+			return probe.NewError(e)
+		}
+	}
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	u.objAPI = &o
+	u.users = make(map[string]iamUser)
+	for _, user := range config.Users {
+		u.users[user.AccessKey] = user
+	}
+	u.policies = make(map[string]iamPolicy)
+	for name, policy := range config.Policies {
+		u.policies[name] = policy
+	}
+	u.accounts = make(map[string]serviceAccount)
+	for _, account := range config.ServiceAccounts {
+		u.accounts[account.AccessKey] = account
+	}
+	u.tempCreds = make(map[string]tempCredential)
+	now := time.Now().UTC()
+	for _, temp := range config.TempCredentials {
+		if temp.Expiration.After(now) {
+			u.tempCreds[temp.AccessKey] = temp
+		}
+	}
+	return nil
+}
+
+// save - saves users and policies, callers hold the write lock.
+func (u *iamUsers) save() *probe.Error {
+	if u.objAPI == nil {
+		return probe.NewError(errors.New("Users are not loaded"))
+	}
+	config := iamConfig{
+		Users:           []iamUser{},
+		Policies:        u.policies,
+		ServiceAccounts: []serviceAccount{},
+		TempCredentials: []tempCredential{},
+	}
+	for _, user := range u.users {
+		config.Users = append(config.Users, user)
+	}
+	sort.Slice(config.Users, func(i, j int) bool { return config.Users[i].AccessKey < config.Users[j].AccessKey })
+	for _, account := range u.accounts {
+		config.ServiceAccounts = append(config.ServiceAccounts, account)
+	}
+	sort.Slice(config.ServiceAccounts, func(i, j int) bool {
+		return config.ServiceAccounts[i].AccessKey < config.ServiceAccounts[j].AccessKey
+	})
+	// Expired temporary credentials are dropped.
+	now := time.Now().UTC()
+	for accessKey, temp := range u.tempCreds {
+		if !temp.Expiration.After(now) {
+			delete(u.tempCreds, accessKey)
+			continue
+		}
+		config.TempCredentials = append(config.TempCredentials, temp)
+	}
+	configBytes, e := json.Marshal(config)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = u.objAPI.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return probe.NewError(e)
+	}
+	if e = u.objAPI.writeMetaFile(iamConfigFile, configBytes); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// hasPolicy - returns true if policy with name exists, callers hold
+// the lock.
+func (u *iamUsers) hasPolicy(name string) bool {
+	_, ok := u.policies[name]
+	return ok || isCannedPolicy(name)
+}
+
+// SetUser - creates user, or replaces its secret key and policy if it
+// exists.
+func (u *iamUsers) SetUser(user iamUser) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	if user.Policy != "" && !u.hasPolicy(user.Policy) {
+		return probe.NewError(PolicyNotFound{Policy: user.Policy})
+	}
+	u.users[user.AccessKey] = user
+	return u.save().Trace(user.AccessKey)
+}
+
+// RemoveUser - removes user with accessKey along with its service
+// accounts and the temporary credentials issued to either.
+func (u *iamUsers) RemoveUser(accessKey string) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	if _, ok := u.users[accessKey]; !ok {
+		return probe.NewError(UserNotFound{AccessKey: accessKey})
+	}
+	delete(u.users, accessKey)
+	for accountAccessKey, account := range u.accounts {
+		if account.Parent == accessKey {
+			u.removeServiceAccount(accountAccessKey)
+		}
+	}
+	u.removeTempCredentials(accessKey)
+	return u.save().Trace(accessKey)
+}
+
+// ReparentCredentials - moves temporary credentials and service
+// accounts of parent to newParent.
+func (u *iamUsers) ReparentCredentials(parent, newParent string) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	moved := false
+	for accessKey, temp := range u.tempCreds {
+		if temp.Parent == parent {
+			temp.Parent = newParent
+			u.tempCreds[accessKey] = temp
+			moved = true
+		}
+	}
+	for accessKey, account := range u.accounts {
+		if account.Parent == parent {
+			account.Parent = newParent
+			u.accounts[accessKey] = account
+			moved = true
+		}
+	}
+	if !moved {
+		return nil
+	}
+	if err := u.save(); err != nil {
+		// Loaded again on the next start, undo in memory as well.
+		for accessKey, temp := range u.tempCreds {
+			if temp.Parent == newParent {
+				temp.Parent = parent
+				u.tempCreds[accessKey] = temp
+			}
+		}
+		for accessKey, account := range u.accounts {
+			if account.Parent == newParent {
+				account.Parent = parent
+				u.accounts[accessKey] = account
+			}
+		}
+		return err.Trace(parent, newParent)
+	}
+	return nil
+}
+
+// removeTempCredentials - removes temporary credentials issued to
+// parent, callers hold the write lock.
+func (u *iamUsers) removeTempCredentials(parent string) {
+	for tempAccessKey, temp := range u.tempCreds {
+		if temp.Parent == parent {
+			delete(u.tempCreds, tempAccessKey)
+		}
+	}
+}
+
+// AttachPolicy - attaches policy with name to user with accessKey,
+// replacing its previous policy.
+func (u *iamUsers) AttachPolicy(accessKey, name string) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	user, ok := u.users[accessKey]
+	if !ok {
+		return probe.NewError(UserNotFound{AccessKey: accessKey})
+	}
+	if !u.hasPolicy(name) {
+		return probe.NewError(PolicyNotFound{Policy: name})
+	}
+	user.Policy = name
+	u.users[accessKey] = user
+	return u.save().Trace(accessKey, name)
+}
+
+// ListUsers - returns all users sorted by access key, without their
+// secret keys.
+func (u *iamUsers) ListUsers() []iamUser {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	users := []iamUser{}
+	for _, user := range u.users {
+		user.SecretKey = ""
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].AccessKey < users[j].AccessKey })
+	return users
+}
+
+// SetPolicy - creates custom policy with name, or replaces it if it
+// exists. Callers verify name is not a canned policy.
+func (u *iamUsers) SetPolicy(name string, policy iamPolicy) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	u.policies[name] = policy
+	return u.save().Trace(name)
+}
+
+// RemovePolicy - removes custom policy with name, policies attached to
+// users cannot be removed.
+func (u *iamUsers) RemovePolicy(name string) *probe.Error {
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	if _, ok := u.policies[name]; !ok {
+		return probe.NewError(PolicyNotFound{Policy: name})
+	}
+	for _, user := range u.users {
+		if user.Policy == name {
+			return probe.NewError(PolicyInUse{Policy: name, AccessKey: user.AccessKey})
+		}
+	}
+	for _, account := range u.accounts {
+		if account.Policy == name {
+			return probe.NewError(PolicyInUse{Policy: name, AccessKey: account.AccessKey})
+		}
+	}
+	delete(u.policies, name)
+	return u.save().Trace(name)
+}
+
+// ListPolicies - returns canned and custom policies by name.
+func (u *iamUsers) ListPolicies() map[string]iamPolicy {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	policies := make(map[string]iamPolicy)
+	for name, policy := range cannedPolicies {
+		policies[name] = policy
+	}
+	for name, policy := range u.policies {
+		policies[name] = policy
+	}
+	return policies
+}
+
+// AssumeRole - issues a temporary credential to parent, valid for
+// duration. Credentials issued with policies are restricted by them
+// instead of the policy of parent.
+func (u *iamUsers) AssumeRole(parent string, policies []string, duration time.Duration) (tempCredential, *probe.Error) {
+	cred, err := genAccessKeys()
+	if err != nil {
+		return tempCredential{}, err.Trace(parent)
+	}
+	token := make([]byte, sessionTokenLength)
+	if _, e := rand.Read(token); e != nil {
+		return tempCredential{}, probe.NewError(e)
+	}
+	temp := tempCredential{
+		AccessKey:    cred.AccessKeyID,
+		SecretKey:    cred.SecretAccessKey,
+		SessionToken: base64.RawURLEncoding.EncodeToString(token),
+		Parent:       parent,
+		Policies:     policies,
+		Expiration:   time.Now().UTC().Add(duration),
+	}
+	u.rwMutex.Lock()
+	defer u.rwMutex.Unlock()
+	u.tempCreds[temp.AccessKey] = temp
+	if err = u.save(); err != nil {
+		delete(u.tempCreds, temp.AccessKey)
+		return tempCredential{}, err.Trace(parent)
+	}
+	return temp, nil
+}
+
+// IsTempCredential - returns true if accessKey is of a temporary
+// credential.
+func (u *iamUsers) IsTempCredential(accessKey string) bool {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	_, ok := u.tempCreds[accessKey]
+	return ok
+}
+
+// CheckSessionToken - verifies token sent with a request signed with
+// accessKey, only temporary credentials have session tokens.
+func (u *iamUsers) CheckSessionToken(accessKey, token string) APIErrorCode {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	temp, ok := u.tempCreds[accessKey]
+	if !ok {
+		if token != "" {
+			return ErrInvalidToken
+		}
+		return ErrNone
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(temp.SessionToken)) != 1 {
+		return ErrInvalidToken
+	}
+	if !temp.Expiration.After(time.Now().UTC()) {
+		return ErrExpiredToken
+	}
+	return ErrNone
+}
+
+// GetSecretKey - returns the secret key for accessKey, false if there
+// is none. The access key is looked up as the server credential, the
+// previous server credential while it is still accepted, a temporary
+// credential, a service account and finally a user.
+func (u *iamUsers) GetSecretKey(accessKey string) (string, bool) {
+	if secretKey, ok := getRootSecretKey(accessKey); ok {
+		return secretKey, true
+	}
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	if temp, ok := u.tempCreds[accessKey]; ok {
+		return temp.SecretKey, true
+	}
+	if account, ok := u.accounts[accessKey]; ok {
+		return account.SecretKey, true
+	}
+	user, ok := u.users[accessKey]
+	if !ok {
+		return "", false
+	}
+	return user.SecretKey, true
+}
+
+// IsAllowed - returns true if accessKey is allowed action on resource.
+// Access keys which are not users are not restricted here, requests
+// signed with them are either by the server credential or fail
+// authentication. Temporary credentials are allowed what their parent
+// is allowed, service accounts what their parent is allowed and their
+// own policy, if any, allows.
+func (u *iamUsers) IsAllowed(accessKey, action, resource string) bool {
+	u.rwMutex.RLock()
+	defer u.rwMutex.RUnlock()
+	return u.isAllowed(accessKey, action, resource)
+}
+
+// isAllowed - IsAllowed, callers hold the lock.
+func (u *iamUsers) isAllowed(accessKey, action, resource string) bool {
+	if temp, ok := u.tempCreds[accessKey]; ok {
+		if len(temp.Policies) > 0 {
+			return u.combinedPolicy(temp.Policies).isAllowed(action, resource)
+		}
+		return u.isParentAllowed(temp.Parent, action, resource)
+	}
+	if account, ok := u.accounts[accessKey]; ok {
+		if account.Policy != "" && !u.combinedPolicy([]string{account.Policy}).isAllowed(action, resource) {
+			return false
+		}
+		return u.isParentAllowed(account.Parent, action, resource)
+	}
+	user, ok := u.users[accessKey]
+	if !ok {
+		return true
+	}
+	policy, ok := cannedPolicies[user.Policy]
+	if !ok {
+		if policy, ok = u.policies[user.Policy]; !ok {
+			return false
+		}
+	}
+	return policy.isAllowed(action, resource)
+}
+
+// isParentAllowed - returns true if parent of a temporary credential
+// or service account is allowed action on resource, false if parent
+// no longer exists. Callers hold the lock.
+func (u *iamUsers) isParentAllowed(parent, action, resource string) bool {
+	if isRootAccessKey(parent) {
+		return true
+	}
+	_, isUser := u.users[parent]
+	_, isAccount := u.accounts[parent]
+	if !isUser && !isAccount {
+		return false
+	}
+	return u.isAllowed(parent, action, resource)
+}
+
+// combinedPolicy - returns the statements of all policies with names,
+// callers hold the lock. Policies which no longer exist are skipped.
+func (u *iamUsers) combinedPolicy(names []string) iamPolicy {
+	combined := iamPolicy{}
+	for _, name := range names {
+		policy, ok := cannedPolicies[name]
+		if !ok {
+			policy = u.policies[name]
+		}
+		combined.Statements = append(combined.Statements, policy.Statements...)
+	}
+	return combined
+}
+
+// initIAMUsers - loads users and policies at server start.
+func initIAMUsers(o objectAPI) {
+	err := globalIAMUsers.Load(o)
+	fatalIf(err.Trace(), "Unable to load users.", nil)
+}
+
+// getIAMResource - returns resource of object in bucket, of bucket if
+// object is empty.
+func getIAMResource(bucket, object string) string {
+	if object == "" {
+		return AWSResourcePrefix + bucket
+	}
+	return AWSResourcePrefix + bucket + "/" + object
+}
+
+// isPostPolicyAllowed - returns true if signer of the post policy form
+// is allowed to upload to bucket.
+func isPostPolicyAllowed(formValues map[string]string, bucket string) bool {
+	credHeader, s3Error := parseCredentialHeader("Credential=" + formValues["X-Amz-Credential"])
+	if s3Error != ErrNone {
+		return false
+	}
+	resource := getIAMResource(bucket, formValues["Key"])
+	return globalIAMUsers.IsAllowed(credHeader.accessKey, "s3:PostPolicyBucket", resource)
+}
+
+// iamPolicyHandler - enforces policies of users on S3 requests, actions
+// are the names of the routes of mux. Requests by the server credential,
+// anonymous requests and requests under the reserved bucket are not
+// restricted here.
+type iamPolicyHandler struct {
+	handler http.Handler
+	mux     *router.Router
+}
+
+// setIAMPolicyHandler - returns handler function enforcing policies on
+// requests matching named routes of mux.
+func setIAMPolicyHandler(mux *router.Router) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return iamPolicyHandler{handler: h, mux: mux}
+	}
+}
+
+func (h iamPolicyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	var match router.RouteMatch
+	if !h.mux.Match(r, &match) || match.Route.GetName() == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	accessKey := getAuditRequester(r)
+	action := "s3:" + match.Route.GetName()
+	// Selecting object content reads the object.
+	if action == "s3:SelectObjectContent" {
+		action = "s3:GetObject"
+	}
+	if !globalIAMUsers.IsAllowed(accessKey, action, getIAMResource(match.Vars["bucket"], match.Vars["object"])) {
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	}
+	// Copying an object, or a part of one, reads its source too.
+	if action == "s3:CopyObject" || action == "s3:CopyObjectPart" {
+		_, sourceBucket, sourceObject := getCopySource(r)
+		if !globalIAMUsers.IsAllowed(accessKey, "s3:GetObject", getIAMResource(sourceBucket, sourceObject)) {
+			writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+			return
+		}
+	}
+	h.handler.ServeHTTP(w, r)
+}
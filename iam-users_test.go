@@ -0,0 +1,285 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests wildcard patterns of policy actions and resources.
+func TestIAMPatternMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"s3:GetObject", "s3:GetObject", true},
+		{"s3:GetObject", "s3:GetObjectTagging", false},
+		{"s3:Get*", "s3:GetObjectTagging", true},
+		{"s3:*", "s3:PutObject", true},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos/2016/a.jpg", true},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos", false},
+		{"arn:aws:s3:::*/*.jpg", "arn:aws:s3:::photos/2016/a.jpg", true},
+		{"arn:aws:s3:::*/*.jpg", "arn:aws:s3:::photos/2016/a.png", false},
+	}
+	for i, testCase := range testCases {
+		if match := iamPatternMatch(testCase.pattern, testCase.name); match != testCase.match {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.match, match)
+		}
+	}
+}
+
+// Tests users are restricted by their policies, and users and policies
+// are saved across restarts.
+func TestIAMUsers(t *testing.T) {
+	savedConfig := serverConfig
+	defer func() {
+		serverConfig = savedConfig
+	}()
+	serverConfig = &serverConfigV5{
+		Credential: mustGenAccessKeys(),
+		rwMutex:    &sync.RWMutex{},
+	}
+
+	directory, e := ioutil.TempDir("", "minio-iam-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+
+	u := newIAMUsers()
+	if err := u.Load(obj); err != nil {
+		t.Fatal(err)
+	}
+	photos := iamPolicy{Statements: []iamStatement{
+		{Effect: "Allow", Actions: []string{"s3:*"}, Resources: []string{"arn:aws:s3:::photos", "arn:aws:s3:::photos/*"}},
+		{Effect: "Deny", Actions: []string{"s3:DeleteObject"}, Resources: []string{"arn:aws:s3:::photos/*"}},
+	}}
+	if e = isValidIAMPolicy(photos); e != nil {
+		t.Fatal(e)
+	}
+	if err := u.SetPolicy("photos", photos); err != nil {
+		t.Fatal(err)
+	}
+	users := []iamUser{
+		{AccessKey: "reader", SecretKey: "reader-secret", Policy: iamPolicyReadOnly},
+		{AccessKey: "writer", SecretKey: "writer-secret", Policy: iamPolicyWriteOnly},
+		{AccessKey: "photographer", SecretKey: "photographer-secret", Policy: "photos"},
+		{AccessKey: "nobody", SecretKey: "nobody-secret"},
+	}
+	for _, user := range users {
+		if err := u.SetUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.SetUser(iamUser{AccessKey: "other", SecretKey: "other-secret", Policy: "unknown"}); err == nil {
+		t.Fatal("Expected error setting user with unknown policy")
+	}
+
+	testCases := []struct {
+		accessKey string
+		action    string
+		resource  string
+		allowed   bool
+	}{
+		{"reader", "s3:GetObject", "arn:aws:s3:::bucket/object", true},
+		{"reader", "s3:ListObjects", "arn:aws:s3:::bucket", true},
+		{"reader", "s3:PutObject", "arn:aws:s3:::bucket/object", false},
+		{"writer", "s3:PutObject", "arn:aws:s3:::bucket/object", true},
+		{"writer", "s3:GetObject", "arn:aws:s3:::bucket/object", false},
+		{"photographer", "s3:PutObject", "arn:aws:s3:::photos/a.jpg", true},
+		{"photographer", "s3:ListObjects", "arn:aws:s3:::photos", true},
+		{"photographer", "s3:DeleteObject", "arn:aws:s3:::photos/a.jpg", false},
+		{"photographer", "s3:GetObject", "arn:aws:s3:::bucket/object", false},
+		{"nobody", "s3:GetObject", "arn:aws:s3:::bucket/object", false},
+		// Access keys which are not users are not restricted.
+		{"unknown", "s3:GetObject", "arn:aws:s3:::bucket/object", true},
+	}
+	for i, testCase := range testCases {
+		if allowed := u.IsAllowed(testCase.accessKey, testCase.action, testCase.resource); allowed != testCase.allowed {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.allowed, allowed)
+		}
+	}
+
+	if err := u.RemovePolicy("photos"); err == nil {
+		t.Fatal("Expected error removing policy attached to a user")
+	}
+	if err := u.AttachPolicy("photographer", iamPolicyReadWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RemovePolicy("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RemoveUser("nobody"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.RemoveUser("nobody"); err == nil {
+		t.Fatal("Expected error removing user which does not exist")
+	}
+
+	restarted := newIAMUsers()
+	if err := restarted.Load(obj); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restarted.ListUsers(), u.ListUsers()) {
+		t.Fatalf("Expected %+v, got %+v", u.ListUsers(), restarted.ListUsers())
+	}
+	if secretKey, ok := restarted.GetSecretKey("writer"); !ok || secretKey != "writer-secret" {
+		t.Fatal("Expected secret key of user to be saved")
+	}
+	if _, ok := restarted.GetSecretKey("nobody"); ok {
+		t.Fatal("Expected removed user to have no secret key")
+	}
+	cred := serverConfig.GetCredential()
+	if secretKey, ok := restarted.GetSecretKey(cred.AccessKeyID); !ok || secretKey != cred.SecretAccessKey {
+		t.Fatal("Expected secret key of the server credential")
+	}
+}
+
+// Tests service accounts are allowed what their parent is allowed,
+// restricted by their own policy, and are removed independently.
+func TestIAMServiceAccounts(t *testing.T) {
+	savedConfig := serverConfig
+	defer func() {
+		serverConfig = savedConfig
+	}()
+	serverConfig = &serverConfigV5{
+		Credential: mustGenAccessKeys(),
+		rwMutex:    &sync.RWMutex{},
+	}
+	rootAccessKey := serverConfig.GetCredential().AccessKeyID
+
+	directory, e := ioutil.TempDir("", "minio-iam-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+
+	u := newIAMUsers()
+	if err := u.Load(obj); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.SetUser(iamUser{AccessKey: "ci", SecretKey: "ci-secret", Policy: iamPolicyReadWrite}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.AddServiceAccount("unknown", ""); err == nil {
+		t.Fatal("Expected error adding service account of unknown user")
+	}
+	if _, err := u.AddServiceAccount("ci", "unknown"); err == nil {
+		t.Fatal("Expected error adding service account with unknown policy")
+	}
+	inherited, err := u.AddServiceAccount("ci", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := u.AddServiceAccount("ci", iamPolicyReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := u.AddServiceAccount(rootAccessKey, iamPolicyWriteOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secretKey, ok := u.GetSecretKey(inherited.AccessKey); !ok || secretKey != inherited.SecretKey {
+		t.Fatal("Expected secret key of service account")
+	}
+
+	testCases := []struct {
+		accessKey string
+		action    string
+		allowed   bool
+	}{
+		{inherited.AccessKey, "s3:PutObject", true},
+		{inherited.AccessKey, "s3:GetObject", true},
+		{restricted.AccessKey, "s3:GetObject", true},
+		{restricted.AccessKey, "s3:PutObject", false},
+		{root.AccessKey, "s3:PutObject", true},
+		{root.AccessKey, "s3:GetObject", false},
+	}
+	for i, testCase := range testCases {
+		if allowed := u.IsAllowed(testCase.accessKey, testCase.action, "arn:aws:s3:::bucket/object"); allowed != testCase.allowed {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.allowed, allowed)
+		}
+	}
+
+	// Restricting the parent restricts its service accounts.
+	if err = u.AttachPolicy("ci", iamPolicyWriteOnly); err != nil {
+		t.Fatal(err)
+	}
+	if u.IsAllowed(inherited.AccessKey, "s3:GetObject", "arn:aws:s3:::bucket/object") {
+		t.Fatal("Expected service account restricted by its parent")
+	}
+	if u.IsAllowed(restricted.AccessKey, "s3:PutObject", "arn:aws:s3:::bucket/object") ||
+		u.IsAllowed(restricted.AccessKey, "s3:GetObject", "arn:aws:s3:::bucket/object") {
+		t.Fatal("Expected service account restricted by its parent and its policy")
+	}
+
+	// Temporary credentials of service accounts are restricted alike.
+	temp, err := u.AssumeRole(inherited.AccessKey, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.IsAllowed(temp.AccessKey, "s3:PutObject", "arn:aws:s3:::bucket/object") {
+		t.Fatal("Expected temporary credential allowed what its service account is")
+	}
+
+	if err = u.RemoveServiceAccount("other", inherited.AccessKey); err == nil {
+		t.Fatal("Expected error removing service account of another parent")
+	}
+	if err = u.RemoveServiceAccount("ci", inherited.AccessKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := u.GetSecretKey(temp.AccessKey); ok {
+		t.Fatal("Expected temporary credentials removed with their service account")
+	}
+	if accounts := u.ListServiceAccounts("ci"); len(accounts) != 1 || accounts[0].AccessKey != restricted.AccessKey || accounts[0].SecretKey != "" {
+		t.Fatalf("Unexpected service accounts %+v", accounts)
+	}
+
+	restarted := newIAMUsers()
+	if err = restarted.Load(obj); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restarted.ListServiceAccounts(""), u.ListServiceAccounts("")) {
+		t.Fatalf("Expected %+v, got %+v", u.ListServiceAccounts(""), restarted.ListServiceAccounts(""))
+	}
+
+	if err = u.RemoveUser("ci"); err != nil {
+		t.Fatal(err)
+	}
+	if u.IsServiceAccount(restricted.AccessKey) {
+		t.Fatal("Expected service accounts removed with their parent")
+	}
+	if accounts := u.ListServiceAccounts(""); len(accounts) != 1 || accounts[0].AccessKey != root.AccessKey {
+		t.Fatalf("Unexpected service accounts %+v", accounts)
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"io/ioutil"
+	"os"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/minio/minio/pkg/probe"
@@ -37,8 +38,9 @@ func enableConsoleLogger() {
 		log.Out = ioutil.Discard
 		return
 	}
-	// log.Out and log.Formatter use the default versions.
-	// Only set specific log level.
+	// log.Formatter uses the default version, log.Out is restored
+	// if the console logger was disabled before.
+	log.Out = os.Stderr
 	lvl, e := logrus.ParseLevel(clogger.Level)
 	fatalIf(probe.NewError(e), "Unknown log level detected, please fix your console logger configuration.", nil)
 
@@ -140,8 +140,10 @@ func findClosestCommands(command string) []string {
 func registerApp() *cli.App {
 	// Register all commands.
 	registerCommand(serverCmd)
+	registerCommand(gatewayCmd)
 	registerCommand(versionCmd)
 	registerCommand(updateCmd)
+	registerCommand(migrateCmd)
 
 	// Set up app.
 	app := cli.NewApp()
@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/console"
+	"github.com/minio/minio/pkg/probe"
+)
+
+var migrateCmd = cli.Command{
+	Name:  "migrate",
+	Usage: "Migrate metadata of erasure coded disks to the current format.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only count files with metadata of an older format.",
+		},
+	},
+	Action: mainMigrate,
+	CustomHelpTemplate: `NAME:
+  minio {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio {{.Name}} [OPTIONS] PATH [PATH...]
+
+OPTIONS:
+  {{range .Flags}}{{.}}
+  {{end}}
+  Metadata of older formats is read as the current one, migrating it only
+  saves converting it on each read. Stop the server before migrating its
+  disks, or migrate them while it is running with POST /minio/admin/migrate.
+
+EXAMPLES:
+  1. Count files of 4 disks with metadata of an older format.
+      $ minio {{.Name}} --dry-run /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend /mnt/export4/backend
+
+  2. Migrate metadata of 4 disks.
+      $ minio {{.Name}} /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend /mnt/export4/backend
+`,
+}
+
+// mainMigrate - migrates metadata of all files of the disks.
+func mainMigrate(c *cli.Context) {
+	if len(c.Args()) < 2 || c.Args().First() == "help" {
+		cli.ShowCommandHelpAndExit(c, "migrate", 1)
+	}
+	storage, e := newXL(c.Args()...)
+	fatalIf(probe.NewError(e), "Unable to initialize disks.", nil)
+
+	dryRun := c.Bool("dry-run")
+	var scanned, migrated, failed int64
+	e = migrateFormat(storage, storage.(fileMigrator), dryRun, func(volume, path string, fileMigrated bool, e error) {
+		scanned++
+		if fileMigrated {
+			migrated++
+		}
+		if e != nil {
+			failed++
+			console.Println(fmt.Sprintf("Unable to migrate ‘%s/%s’, %s", volume, path, e))
+		}
+	})
+	fatalIf(probe.NewError(e), "Unable to list files to migrate.", nil)
+	if dryRun {
+		console.Println(fmt.Sprintf("%d of %d files have metadata older than format ‘%s’.", migrated, scanned, xlFormatCurrent))
+	} else {
+		console.Println(fmt.Sprintf("Migrated %d of %d files to format ‘%s’.", migrated, scanned, xlFormatCurrent))
+	}
+	if failed > 0 {
+		fatalIf(probe.NewError(fmt.Errorf("%d files failed", failed)), "Unable to migrate all files.", nil)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -51,29 +52,17 @@ func splitNetPath(networkPath string) (netAddr, netPath string) {
 	return netAddr, netPath
 }
 
-// Converts rpc.ServerError to underlying error. This function is
-// written so that the storageAPI errors are consistent across network
-// disks as well.
-func toStorageErr(err error) error {
-	switch err.Error() {
-	case errDiskFull.Error():
-		return errDiskFull
-	case errVolumeNotFound.Error():
-		return errVolumeNotFound
-	case errVolumeExists.Error():
-		return errVolumeExists
-	case errFileNotFound.Error():
-		return errFileNotFound
-	case errIsNotRegular.Error():
-		return errIsNotRegular
-	case errVolumeNotEmpty.Error():
-		return errVolumeNotEmpty
-	case errFileAccessDenied.Error():
-		return errFileAccessDenied
-	case errVolumeAccessDenied.Error():
-		return errVolumeAccessDenied
+// toStorageErr - converts error of operation op on volume and path to
+// a storage error, rpc.ServerError carries the storage error of the
+// remote end as text. This function is written so that the storageAPI
+// errors are consistent across network disks as well.
+func toStorageErr(op, volume, path string, err error) error {
+	if _, ok := err.(rpc.ServerError); ok {
+		if cause := rpcErrorCause(err.Error()); cause != nil {
+			err = cause
+		}
 	}
-	return err
+	return storageErr{Op: op, Disk: -1, Volume: volume, Path: path, Err: err}
 }
 
 // Initialize new network file system.
@@ -121,14 +110,23 @@ func newNetworkFS(networkPath string) (StorageAPI, error) {
 	return ndisk, nil
 }
 
+// signRPC - returns authentication of a request to the peer for method
+// with args, signed with the current secret of the cluster.
+func (n networkFS) signRPC(method string, args ...string) RPCAuthArgs {
+	return signRPC(serverConfig.GetRPCSecrets()[0], method, args...)
+}
+
 // MakeVol - make a volume.
 func (n networkFS) MakeVol(volume string) error {
 	reply := GenericReply{}
-	if err := n.rpcClient.Call("Storage.MakeVolHandler", volume, &reply); err != nil {
+	if err := n.rpcClient.Call("Storage.MakeVolHandler", VolArgs{
+		Auth: n.signRPC("Storage.MakeVolHandler", volume),
+		Vol:  volume,
+	}, &reply); err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 		}).Debugf("Storage.MakeVolHandler returned an error %s", err)
-		return toStorageErr(err)
+		return toStorageErr("MakeVol", volume, "", err)
 	}
 	return nil
 }
@@ -136,65 +134,107 @@ func (n networkFS) MakeVol(volume string) error {
 // ListVols - List all volumes.
 func (n networkFS) ListVols() (vols []VolInfo, err error) {
 	ListVols := ListVolsReply{}
-	err = n.rpcClient.Call("Storage.ListVolsHandler", "", &ListVols)
+	err = n.rpcClient.Call("Storage.ListVolsHandler", GenericArgs{
+		Auth: n.signRPC("Storage.ListVolsHandler"),
+	}, &ListVols)
 	if err != nil {
 		log.Debugf("Storage.ListVolsHandler returned an error %s", err)
-		return nil, err
+		return nil, toStorageErr("ListVols", "", "", err)
 	}
 	return ListVols.Vols, nil
 }
 
 // StatVol - get current Stat volume info.
 func (n networkFS) StatVol(volume string) (volInfo VolInfo, err error) {
-	if err = n.rpcClient.Call("Storage.StatVolHandler", volume, &volInfo); err != nil {
+	if err = n.rpcClient.Call("Storage.StatVolHandler", VolArgs{
+		Auth: n.signRPC("Storage.StatVolHandler", volume),
+		Vol:  volume,
+	}, &volInfo); err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 		}).Debugf("Storage.StatVolHandler returned an error %s", err)
-		return VolInfo{}, toStorageErr(err)
+		return VolInfo{}, toStorageErr("StatVol", volume, "", err)
 	}
 	return volInfo, nil
 }
 
+// StatVFS - get space of the remote disk holding volume.
+func (n networkFS) StatVFS(volume string) (vfsInfo VFSInfo, err error) {
+	if err = n.rpcClient.Call("Storage.StatVFSHandler", VolArgs{
+		Auth: n.signRPC("Storage.StatVFSHandler", volume),
+		Vol:  volume,
+	}, &vfsInfo); err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+		}).Debugf("Storage.StatVFSHandler returned an error %s", err)
+		return VFSInfo{}, toStorageErr("StatVFS", volume, "", err)
+	}
+	return vfsInfo, nil
+}
+
 // DeleteVol - Delete a volume.
 func (n networkFS) DeleteVol(volume string) error {
 	reply := GenericReply{}
-	if err := n.rpcClient.Call("Storage.DeleteVolHandler", volume, &reply); err != nil {
+	if err := n.rpcClient.Call("Storage.DeleteVolHandler", VolArgs{
+		Auth: n.signRPC("Storage.DeleteVolHandler", volume),
+		Vol:  volume,
+	}, &reply); err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 		}).Debugf("Storage.DeleteVolHandler returned an error %s", err)
-		return toStorageErr(err)
+		return toStorageErr("DeleteVol", volume, "", err)
 	}
 	return nil
 }
 
 // File operations.
 
-// CreateFile - create file.
-func (n networkFS) CreateFile(volume, path string) (writeCloser io.WriteCloser, err error) {
+// CreateFile - create file, the upload is aborted once ctx is done.
+func (n networkFS) CreateFile(ctx context.Context, volume, path string) (writeCloser io.WriteCloser, err error) {
 	writeURL := new(url.URL)
 	writeURL.Scheme = n.netScheme
 	writeURL.Host = n.netAddr
 	writeURL.Path = fmt.Sprintf("%s/upload/%s", storageRPCPath, urlpath.Join(volume, path))
+	// Writes using reserved space are signed as such.
+	authArgs := []string{volume, path}
+	if usesReservedSpace(ctx) {
+		writeURL.RawQuery = url.Values{"reserved": {"true"}}.Encode()
+		authArgs = append(authArgs, "reserved")
+	}
 
-	contentType := "application/octet-stream"
 	readCloser, writeCloser := io.Pipe()
+	req, err := http.NewRequest("POST", writeURL.String(), readCloser)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	n.signRPC("Storage.Upload", authArgs...).setHeaders(req.Header)
 	go func() {
-		resp, err := n.httpClient.Post(writeURL.String(), contentType, readCloser)
+		resp, err := n.httpClient.Do(req)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
 			}).Debugf("CreateFile HTTP POST failed to upload data with error %s", err)
-			readCloser.CloseWithError(err)
+			readCloser.CloseWithError(toStorageErr("CreateFile", volume, path, err))
 			return
 		}
 		if resp != nil {
 			if resp.StatusCode != http.StatusOK {
 				if resp.StatusCode == http.StatusNotFound {
-					readCloser.CloseWithError(errFileNotFound)
+					readCloser.CloseWithError(toStorageErr("CreateFile", volume, path, errFileNotFound))
+					return
+				}
+				if resp.StatusCode == http.StatusForbidden {
+					readCloser.CloseWithError(toStorageErr("CreateFile", volume, path, errRPCAuthFailed))
+					return
+				}
+				if resp.StatusCode == http.StatusInsufficientStorage {
+					readCloser.CloseWithError(toStorageErr("CreateFile", volume, path, errDiskFull))
 					return
 				}
-				readCloser.CloseWithError(errors.New("Invalid response."))
+				readCloser.CloseWithError(toStorageErr("CreateFile", volume, path, errors.New("Invalid response.")))
 				return
 			}
 			// Close the reader.
@@ -207,6 +247,7 @@ func (n networkFS) CreateFile(volume, path string) (writeCloser io.WriteCloser,
 // StatFile - get latest Stat information for a file at path.
 func (n networkFS) StatFile(volume, path string) (fileInfo FileInfo, err error) {
 	if err = n.rpcClient.Call("Storage.StatFileHandler", StatFileArgs{
+		Auth: n.signRPC("Storage.StatFileHandler", volume, path),
 		Vol:  volume,
 		Path: path,
 	}, &fileInfo); err != nil {
@@ -214,34 +255,45 @@ func (n networkFS) StatFile(volume, path string) (fileInfo FileInfo, err error)
 			"volume": volume,
 			"path":   path,
 		}).Debugf("Storage.StatFileHandler failed with %s", err)
-		return FileInfo{}, toStorageErr(err)
+		return FileInfo{}, toStorageErr("StatFile", volume, path, err)
 	}
 	return fileInfo, nil
 }
 
-// ReadFile - reads a file.
-func (n networkFS) ReadFile(volume string, path string, offset int64) (reader io.ReadCloser, err error) {
+// ReadFile - reads a file, the download is aborted once ctx is done.
+func (n networkFS) ReadFile(ctx context.Context, volume string, path string, offset int64) (reader io.ReadCloser, err error) {
 	readURL := new(url.URL)
 	readURL.Scheme = n.netScheme
 	readURL.Host = n.netAddr
 	readURL.Path = fmt.Sprintf("%s/download/%s", storageRPCPath, urlpath.Join(volume, path))
+	offsetStr := strconv.FormatInt(offset, 10)
 	readQuery := make(url.Values)
-	readQuery.Set("offset", strconv.FormatInt(offset, 10))
+	readQuery.Set("offset", offsetStr)
 	readURL.RawQuery = readQuery.Encode()
-	resp, err := n.httpClient.Get(readURL.String())
+	req, err := http.NewRequest("GET", readURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	n.signRPC("Storage.Download", volume, path, offsetStr).setHeaders(req.Header)
+	resp, err := n.httpClient.Do(req)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 			"path":   path,
 		}).Debugf("ReadFile http Get failed with error %s", err)
-		return nil, err
+		return nil, toStorageErr("ReadFile", volume, path, err)
 	}
 	if resp != nil {
 		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
 			if resp.StatusCode == http.StatusNotFound {
-				return nil, errFileNotFound
+				return nil, toStorageErr("ReadFile", volume, path, errFileNotFound)
+			}
+			if resp.StatusCode == http.StatusForbidden {
+				return nil, toStorageErr("ReadFile", volume, path, errRPCAuthFailed)
 			}
-			return nil, errors.New("Invalid response")
+			return nil, toStorageErr("ReadFile", volume, path, errors.New("Invalid response"))
 		}
 	}
 	return resp.Body, nil
@@ -251,6 +303,8 @@ func (n networkFS) ReadFile(volume string, path string, offset int64) (reader io
 func (n networkFS) ListFiles(volume, prefix, marker string, recursive bool, count int) (files []FileInfo, eof bool, err error) {
 	listFilesReply := ListFilesReply{}
 	if err = n.rpcClient.Call("Storage.ListFilesHandler", ListFilesArgs{
+		Auth: n.signRPC("Storage.ListFilesHandler", volume, prefix, marker,
+			strconv.FormatBool(recursive), strconv.Itoa(count)),
 		Vol:       volume,
 		Prefix:    prefix,
 		Marker:    marker,
@@ -264,24 +318,33 @@ func (n networkFS) ListFiles(volume, prefix, marker string, recursive bool, coun
 			"recursive": recursive,
 			"count":     count,
 		}).Debugf("Storage.ListFilesHandlers failed with %s", err)
-		return nil, true, toStorageErr(err)
+		return nil, true, toStorageErr("ListFiles", volume, prefix, err)
 	}
 	// Return successfully unmarshalled results.
 	return listFilesReply.Files, listFilesReply.EOF, nil
 }
 
-// DeleteFile - Delete a file at path.
-func (n networkFS) DeleteFile(volume, path string) (err error) {
+// DeleteFile - Delete a file at path, stops waiting for the reply once
+// ctx is done.
+func (n networkFS) DeleteFile(ctx context.Context, volume, path string) (err error) {
 	reply := GenericReply{}
-	if err = n.rpcClient.Call("Storage.DeleteFileHandler", DeleteFileArgs{
+	call := n.rpcClient.Go("Storage.DeleteFileHandler", DeleteFileArgs{
+		Auth: n.signRPC("Storage.DeleteFileHandler", volume, path),
 		Vol:  volume,
 		Path: path,
-	}, &reply); err != nil {
+	}, &reply, nil)
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 			"path":   path,
 		}).Debugf("Storage.DeleteFileHandler failed with %s", err)
-		return toStorageErr(err)
+		return toStorageErr("DeleteFile", volume, path, err)
 	}
 	return nil
 }
@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"time"
+)
+
+// notifier carries configuration of all supported notification
+// targets, each target type is keyed by an account id. Currently
+// supported targets are
+//
+//   - kafka
+//   - nats
+//   - mqtt
+//   - postgresql
+//   - mysql
+//
+type notifier struct {
+	Kafka      map[string]kafkaNotify      `json:"kafka"`
+	NATS       map[string]natsNotify       `json:"nats"`
+	MQTT       map[string]mqttNotify       `json:"mqtt"`
+	PostgreSQL map[string]postgreSQLNotify `json:"postgresql"`
+	MySQL      map[string]mySQLNotify      `json:"mysql"`
+	// Add new notification queues.
+}
+
+// notifyTimeout - timeout of connecting and publishing to notification
+// targets.
+const notifyTimeout = 10 * time.Second
+
+// errInvalidCACert - CA certificate file of a notification target has
+// no usable certificates.
+var errInvalidCACert = errors.New("No valid certificates found in CA certificate file")
+
+// notifyTLS - TLS settings for connections to notification targets.
+type notifyTLS struct {
+	Enable     bool   `json:"enable"`
+	SkipVerify bool   `json:"skipVerify"`
+	CACert     string `json:"caCert"`
+	ClientCert string `json:"clientCert"`
+	ClientKey  string `json:"clientKey"`
+}
+
+// getNotifyTLSConfig - builds TLS config from TLS settings of a
+// notification target.
+func getNotifyTLSConfig(nTLS notifyTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: nTLS.SkipVerify,
+	}
+	if nTLS.CACert != "" {
+		caCert, e := ioutil.ReadFile(nTLS.CACert)
+		if e != nil {
+			return nil, e
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, errInvalidCACert
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	if nTLS.ClientCert != "" || nTLS.ClientKey != "" {
+		cert, e := tls.LoadX509KeyPair(nTLS.ClientCert, nTLS.ClientKey)
+		if e != nil {
+			return nil, e
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// kafkaSASL - SASL/PLAIN credentials for kafka brokers.
+type kafkaSASL struct {
+	Enable   bool   `json:"enable"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// kafkaNotify - kafka notification target configuration.
+type kafkaNotify struct {
+	Enable  bool      `json:"enable"`
+	Brokers []string  `json:"brokers"`
+	Topic   string    `json:"topic"`
+	TLS     notifyTLS `json:"tls"`
+	SASL    kafkaSASL `json:"sasl"`
+}
+
+// kafkaConn - kafka notification target, events are published keyed
+// by "bucket/object" so that all events for an object land on the
+// same partition and are consumed in order. Keys are partitioned with
+// murmur2, as by the default partitioner of the Java producer.
+type kafkaConn struct {
+	writer *kafka.Writer
+}
+
+// newKafkaNotify - connects to the configured kafka brokers.
+func newKafkaNotify(kNotify kafkaNotify) (notificationTarget, error) {
+	dialer := &kafka.Dialer{
+		ClientID:  "minio",
+		Timeout:   notifyTimeout,
+		DualStack: true,
+	}
+	if kNotify.TLS.Enable {
+		tlsConfig, e := getNotifyTLSConfig(kNotify.TLS)
+		if e != nil {
+			return nil, e
+		}
+		dialer.TLS = tlsConfig
+	}
+	if kNotify.SASL.Enable {
+		dialer.SASLMechanism = plain.Mechanism{
+			Username: kNotify.SASL.Username,
+			Password: kNotify.SASL.Password,
+		}
+	}
+	config := kafka.WriterConfig{
+		Brokers:  kNotify.Brokers,
+		Topic:    kNotify.Topic,
+		Dialer:   dialer,
+		Balancer: kafka.Murmur2Balancer{},
+		// Events are written one at a time, retried once.
+		BatchSize:    1,
+		MaxAttempts:  2,
+		RequiredAcks: 1,
+		ReadTimeout:  notifyTimeout,
+		WriteTimeout: notifyTimeout,
+	}
+	if e := config.Validate(); e != nil {
+		return nil, e
+	}
+
+	// The writer connects lazily, look up the partitions of the topic
+	// so that unreachable brokers are reported right away.
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	var e error
+	for _, broker := range kNotify.Brokers {
+		if _, e = dialer.LookupPartitions(ctx, "tcp", broker, kNotify.Topic); e == nil {
+			break
+		}
+	}
+	if e != nil {
+		return nil, e
+	}
+	return kafkaConn{writer: kafka.NewWriter(config)}, nil
+}
+
+// Send - publishes the event entry as JSON, keyed by "bucket/object".
+func (k kafkaConn) Send(entry eventLogEntry) error {
+	value, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	return k.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(entry.Key),
+		Value: value,
+	})
+}
+
+// Close - closes connections to all brokers.
+func (k kafkaConn) Close() error {
+	return k.writer.Close()
+}
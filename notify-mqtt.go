@@ -0,0 +1,130 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// Default keep alive interval of MQTT connections.
+const defaultMQTTKeepAlive = time.Minute
+
+var (
+	// errInvalidMQTTQoS - MQTT QoS is neither 0, 1 nor 2.
+	errInvalidMQTTQoS = errors.New("MQTT QoS must be 0, 1 or 2")
+	// errMQTTTimeout - MQTT broker did not respond in time.
+	errMQTTTimeout = errors.New("Timed out waiting for MQTT broker")
+)
+
+// mqttNotify - MQTT notification target configuration.
+type mqttNotify struct {
+	Enable   bool     `json:"enable"`
+	Brokers  []string `json:"brokers"`
+	Topic    string   `json:"topic"`
+	QoS      byte     `json:"qos"`
+	ClientID string   `json:"clientID"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	// Keep alive interval in seconds, 0 uses the default of a minute.
+	KeepAlive int64     `json:"keepAlive"`
+	TLS       notifyTLS `json:"tls"`
+}
+
+// mqttConn - MQTT notification target, events are published at the
+// configured QoS and acknowledged by the broker unless QoS is 0.
+// Dropped connections are reestablished by the client in background.
+type mqttConn struct {
+	client mqtt.Client
+	topic  string
+	qos    byte
+}
+
+// newMQTTNotify - connects to the configured MQTT brokers.
+func newMQTTNotify(mNotify mqttNotify) (notificationTarget, error) {
+	if mNotify.QoS > 2 {
+		return nil, errInvalidMQTTQoS
+	}
+	options := mqtt.NewClientOptions().
+		SetClientID(mNotify.ClientID).
+		SetUsername(mNotify.Username).
+		SetPassword(mNotify.Password).
+		SetKeepAlive(defaultMQTTKeepAlive).
+		SetConnectTimeout(notifyTimeout).
+		SetWriteTimeout(notifyTimeout).
+		SetAutoReconnect(true).
+		SetCleanSession(true)
+	if mNotify.KeepAlive > 0 {
+		options.SetKeepAlive(time.Duration(mNotify.KeepAlive) * time.Second)
+	}
+	if mNotify.ClientID == "" {
+		var b [8]byte
+		rand.Read(b[:])
+		options.SetClientID("minio-" + hex.EncodeToString(b[:]))
+	}
+	scheme := "tcp://"
+	if mNotify.TLS.Enable {
+		tlsConfig, e := getNotifyTLSConfig(mNotify.TLS)
+		if e != nil {
+			return nil, e
+		}
+		options.SetTLSConfig(tlsConfig)
+		scheme = "ssl://"
+	}
+	// Brokers are configured in "host:port" form.
+	for _, broker := range mNotify.Brokers {
+		if !strings.Contains(broker, "://") {
+			broker = scheme + broker
+		}
+		options.AddBroker(broker)
+	}
+	client := mqtt.NewClient(options)
+	if e := mqttWait(client.Connect()); e != nil {
+		return nil, e
+	}
+	return mqttConn{client: client, topic: mNotify.Topic, qos: mNotify.QoS}, nil
+}
+
+// mqttWait - waits for the broker to complete the operation of token.
+func mqttWait(token mqtt.Token) error {
+	if !token.WaitTimeout(notifyTimeout) {
+		return errMQTTTimeout
+	}
+	return token.Error()
+}
+
+// Send - publishes the event entry as JSON to the configured topic.
+func (m mqttConn) Send(entry eventLogEntry) error {
+	payload, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	return mqttWait(m.client.Publish(m.topic, m.qos, false, payload))
+}
+
+// Close - disconnects from the broker, waiting up to a quarter of a
+// second for publishes in flight.
+func (m mqttConn) Close() error {
+	m.client.Disconnect(250)
+	return nil
+}
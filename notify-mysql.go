@@ -0,0 +1,111 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultMySQLPort = "3306"
+	// Layout of DATETIME(3) values.
+	mySQLTimeLayout = "2006-01-02 15:04:05.000"
+)
+
+// mySQLNotify - MySQL notification target configuration.
+type mySQLNotify struct {
+	Enable   bool      `json:"enable"`
+	Format   string    `json:"format"`
+	Host     string    `json:"host"`
+	Port     string    `json:"port"`
+	User     string    `json:"user"`
+	Password string    `json:"password"`
+	Database string    `json:"database"`
+	Table    string    `json:"table"`
+	TLS      notifyTLS `json:"tls"`
+}
+
+// mySQLStatements - statements on table of format, JSON columns need
+// MySQL 5.7.8 or later. Keys may be longer than an index allows, so
+// rows of the namespace format are keyed by a hash of the key.
+func mySQLStatements(format, table string) sqlStatements {
+	if format == sqlFormatAccess {
+		return sqlStatements{
+			createTable: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (event_time DATETIME(3) NOT NULL, event_data JSON) ROW_FORMAT = Dynamic", table),
+			insertRow:   fmt.Sprintf("INSERT INTO %s (event_time, event_data) VALUES (?, ?)", table),
+		}
+	}
+	return sqlStatements{
+		createTable: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key_name VARCHAR(2048) NOT NULL, key_hash CHAR(64) GENERATED ALWAYS AS (SHA2(key_name, 256)) STORED NOT NULL PRIMARY KEY, value JSON) CHARACTER SET = utf8mb4 COLLATE = utf8mb4_bin ROW_FORMAT = Dynamic", table),
+		upsertRow:   fmt.Sprintf("INSERT INTO %s (key_name, value) VALUES (?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value)", table),
+		deleteRow:   fmt.Sprintf("DELETE FROM %s WHERE key_hash = SHA2(?, 256)", table),
+	}
+}
+
+// newMySQLNotify - connects to the configured database and creates the
+// table if missing.
+func newMySQLNotify(myNotify mySQLNotify) (notificationTarget, error) {
+	if e := checkSQLTable(myNotify.Format, myNotify.Table); e != nil {
+		return nil, e
+	}
+	port := myNotify.Port
+	if port == "" {
+		port = defaultMySQLPort
+	}
+	config := mysql.NewConfig()
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(myNotify.Host, port)
+	config.User = myNotify.User
+	config.Passwd = myNotify.Password
+	config.DBName = myNotify.Database
+	config.Timeout = notifyTimeout
+	config.ReadTimeout = notifyTimeout
+	config.WriteTimeout = notifyTimeout
+	if myNotify.TLS.Enable {
+		tlsConfig, e := getNotifyTLSConfig(myNotify.TLS)
+		if e != nil {
+			return nil, e
+		}
+		// The driver looks TLS configurations up by name.
+		uuid, e := newUUID()
+		if e != nil {
+			return nil, e
+		}
+		config.TLSConfig = "minio-" + uuid
+		if e = mysql.RegisterTLSConfig(config.TLSConfig, tlsConfig); e != nil {
+			return nil, e
+		}
+	}
+	db, e := openSQLDB("mysql", config.FormatDSN())
+	if e != nil {
+		mysql.DeregisterTLSConfig(config.TLSConfig)
+		return nil, e
+	}
+	return newSQLTarget(sqlTarget{
+		format:     myNotify.Format,
+		statements: mySQLStatements(myNotify.Format, myNotify.Table),
+		timeLayout: mySQLTimeLayout,
+		exec:       sqlExec(db),
+		close: func() error {
+			mysql.DeregisterTLSConfig(config.TLSConfig)
+			return db.Close()
+		},
+	})
+}
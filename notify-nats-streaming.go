@@ -0,0 +1,177 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// NATS Streaming clusters are spoken to over core NATS, with protocol
+// buffer requests and responses. Only the messages and fields needed
+// to publish are encoded and decoded here.
+
+// Subject prefix streaming clusters are discovered on.
+const stanDiscoverPrefix = "_STAN.discover."
+
+var (
+	errNoNATSClusterID       = errors.New("NATS Streaming cluster id is not configured")
+	errMalformedSTANResponse = errors.New("Malformed response of NATS Streaming cluster")
+)
+
+// stanError - error returned by a NATS Streaming cluster.
+type stanError string
+
+func (e stanError) Error() string {
+	return "NATS Streaming error: " + string(e)
+}
+
+// stanConn - registration of a publisher with a streaming cluster.
+type stanConn struct {
+	conn          *nats.Conn
+	clientID      string
+	pubPrefix     string
+	closeRequests string
+	heartbeats    *nats.Subscription
+}
+
+// newSTANConn - registers with streaming cluster clusterID as
+// clientID, a random id if not set.
+func newSTANConn(conn *nats.Conn, clusterID, clientID string) (*stanConn, error) {
+	if clusterID == "" {
+		return nil, errNoNATSClusterID
+	}
+	if clientID == "" {
+		clientID = "minio-" + nuid.Next()
+	}
+	// The cluster drops publishers not answering its heartbeats.
+	heartbeats, e := conn.Subscribe(nats.NewInbox(), func(msg *nats.Msg) {
+		msg.Respond(nil)
+	})
+	if e != nil {
+		return nil, e
+	}
+	// ConnectRequest - clientID = 1, heartbeatInbox = 2.
+	request := stanAppendField(nil, 1, []byte(clientID))
+	request = stanAppendField(request, 2, []byte(heartbeats.Subject))
+	// ConnectResponse - pubPrefix = 1, closeRequests = 4, error = 5.
+	fields, e := stanRequest(conn, stanDiscoverPrefix+clusterID, request)
+	if e == nil && len(fields[5]) > 0 {
+		e = stanError(fields[5])
+	} else if e == nil && len(fields[1]) == 0 {
+		e = errMalformedSTANResponse
+	}
+	if e != nil {
+		heartbeats.Unsubscribe()
+		return nil, e
+	}
+	return &stanConn{
+		conn:          conn,
+		clientID:      clientID,
+		pubPrefix:     string(fields[1]),
+		closeRequests: string(fields[4]),
+		heartbeats:    heartbeats,
+	}, nil
+}
+
+// Publish - publishes data to subject, waits for the cluster to
+// acknowledge it was persisted.
+func (s *stanConn) Publish(subject string, data []byte) error {
+	guid := nuid.Next()
+	// PubMsg - clientID = 1, guid = 2, subject = 3, data = 5.
+	msg := stanAppendField(nil, 1, []byte(s.clientID))
+	msg = stanAppendField(msg, 2, []byte(guid))
+	msg = stanAppendField(msg, 3, []byte(subject))
+	msg = stanAppendField(msg, 5, data)
+	// PubAck - guid = 1, error = 2.
+	fields, e := stanRequest(s.conn, s.pubPrefix+"."+subject, msg)
+	if e != nil {
+		return e
+	}
+	if len(fields[2]) > 0 {
+		return stanError(fields[2])
+	}
+	if string(fields[1]) != guid {
+		return errMalformedSTANResponse
+	}
+	return nil
+}
+
+// Close - unregisters from the cluster. Unregistering is best effort,
+// the cluster drops publishers missing heartbeats eventually.
+func (s *stanConn) Close() error {
+	var e error
+	if s.closeRequests != "" {
+		// CloseRequest - clientID = 1, CloseResponse - error = 1.
+		var fields map[protowire.Number][]byte
+		fields, e = stanRequest(s.conn, s.closeRequests, stanAppendField(nil, 1, []byte(s.clientID)))
+		if e == nil && len(fields[1]) > 0 {
+			e = stanError(fields[1])
+		}
+	}
+	s.heartbeats.Unsubscribe()
+	return e
+}
+
+// stanRequest - sends request to subject, returns the length delimited
+// fields of the response.
+func stanRequest(conn *nats.Conn, subject string, request []byte) (map[protowire.Number][]byte, error) {
+	reply, e := conn.Request(subject, request, notifyTimeout)
+	if e != nil {
+		return nil, e
+	}
+	return stanDecodeFields(reply.Data)
+}
+
+// stanAppendField - appends a length delimited field, empty fields are
+// left out as their default.
+func stanAppendField(buf []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return buf
+	}
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	return protowire.AppendBytes(buf, value)
+}
+
+// stanDecodeFields - returns length delimited fields of a message by
+// field number, fields of other types are skipped.
+func stanDecodeFields(buf []byte) (map[protowire.Number][]byte, error) {
+	fields := make(map[protowire.Number][]byte)
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return nil, errMalformedSTANResponse
+		}
+		buf = buf[n:]
+		if typ == protowire.BytesType {
+			var value []byte
+			if value, n = protowire.ConsumeBytes(buf); n >= 0 {
+				fields[num] = value
+			}
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+		}
+		if n < 0 {
+			return nil, errMalformedSTANResponse
+		}
+		buf = buf[n:]
+	}
+	return fields, nil
+}
@@ -0,0 +1,52 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Tests length delimited fields of streaming responses are decoded,
+// and fields of other types skipped.
+func TestSTANDecodeFields(t *testing.T) {
+	// PubAck with a guid, an error and a varint and fixed field in
+	// between.
+	msg := stanAppendField(nil, 1, []byte("guid"))
+	msg = protowire.AppendTag(msg, 3, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 300)
+	msg = protowire.AppendTag(msg, 4, protowire.Fixed32Type)
+	msg = protowire.AppendFixed32(msg, 7)
+	msg = stanAppendField(msg, 2, []byte("invalid subject"))
+	msg = stanAppendField(msg, 5, nil)
+
+	fields, e := stanDecodeFields(msg)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(fields) != 2 || string(fields[1]) != "guid" || string(fields[2]) != "invalid subject" {
+		t.Fatalf("Unexpected fields %q", fields)
+	}
+
+	// Messages truncated within a field are rejected.
+	for _, size := range []int{5, 8, 12, len(msg) - 1} {
+		if _, e = stanDecodeFields(msg[:size]); e != errMalformedSTANResponse {
+			t.Errorf("Size %d: Expected %v, got %v", size, errMalformedSTANResponse, e)
+		}
+	}
+}
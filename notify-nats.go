@@ -0,0 +1,125 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsStreaming - NATS Streaming settings, events are published to
+// the streaming cluster and acknowledged once persisted.
+type natsStreaming struct {
+	Enable    bool   `json:"enable"`
+	ClusterID string `json:"clusterID"`
+	ClientID  string `json:"clientID"`
+}
+
+// natsNotify - NATS notification target configuration.
+type natsNotify struct {
+	Enable    bool          `json:"enable"`
+	Servers   []string      `json:"servers"`
+	Subject   string        `json:"subject"`
+	Username  string        `json:"username"`
+	Password  string        `json:"password"`
+	Token     string        `json:"token"`
+	TLS       notifyTLS     `json:"tls"`
+	Confirm   bool          `json:"confirm"`
+	Streaming natsStreaming `json:"streaming"`
+}
+
+// natsConn - NATS notification target. With confirm set each event is
+// flushed to the server before the next one is published. Dropped
+// connections are reestablished by the client in background.
+type natsConn struct {
+	conn    *nats.Conn
+	stream  *stanConn
+	subject string
+	confirm bool
+}
+
+// newNATSNotify - connects to the configured NATS servers, and
+// registers with the streaming cluster if enabled.
+func newNATSNotify(nNotify natsNotify) (notificationTarget, error) {
+	if len(nNotify.Servers) == 0 {
+		return nil, nats.ErrNoServers
+	}
+	options := []nats.Option{
+		nats.Name("minio"),
+		nats.Timeout(notifyTimeout),
+		// Keep reconnecting for as long as the target is configured.
+		nats.MaxReconnects(-1),
+	}
+	if nNotify.Username != "" {
+		options = append(options, nats.UserInfo(nNotify.Username, nNotify.Password))
+	}
+	if nNotify.Token != "" {
+		options = append(options, nats.Token(nNotify.Token))
+	}
+	if nNotify.TLS.Enable {
+		tlsConfig, e := getNotifyTLSConfig(nNotify.TLS)
+		if e != nil {
+			return nil, e
+		}
+		options = append(options, nats.Secure(tlsConfig))
+	}
+	conn, e := nats.Connect(strings.Join(nNotify.Servers, ","), options...)
+	if e != nil {
+		return nil, e
+	}
+	n := natsConn{conn: conn, subject: nNotify.Subject, confirm: nNotify.Confirm}
+	if nNotify.Streaming.Enable {
+		n.stream, e = newSTANConn(conn, nNotify.Streaming.ClusterID, nNotify.Streaming.ClientID)
+		if e != nil {
+			conn.Close()
+			return nil, e
+		}
+	}
+	return n, nil
+}
+
+// Send - publishes the event entry as JSON to the configured subject.
+func (n natsConn) Send(entry eventLogEntry) error {
+	data, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	if n.stream != nil {
+		return n.stream.Publish(n.subject, data)
+	}
+	if e = n.conn.Publish(n.subject, data); e != nil {
+		return e
+	}
+	if n.confirm {
+		// Servers process messages of a connection in order, the
+		// flush confirms the event was processed.
+		return n.conn.FlushTimeout(notifyTimeout)
+	}
+	return nil
+}
+
+// Close - unregisters from the streaming cluster and closes
+// connection to the server.
+func (n natsConn) Close() error {
+	if n.stream != nil {
+		n.stream.Close()
+	}
+	n.conn.Close()
+	return nil
+}
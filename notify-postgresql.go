@@ -0,0 +1,110 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	// PostgreSQL driver of database/sql.
+	_ "github.com/lib/pq"
+)
+
+const defaultPostgreSQLPort = "5432"
+
+// postgreSQLNotify - PostgreSQL notification target configuration.
+type postgreSQLNotify struct {
+	Enable   bool      `json:"enable"`
+	Format   string    `json:"format"`
+	Host     string    `json:"host"`
+	Port     string    `json:"port"`
+	User     string    `json:"user"`
+	Password string    `json:"password"`
+	Database string    `json:"database"`
+	Table    string    `json:"table"`
+	TLS      notifyTLS `json:"tls"`
+}
+
+// postgreSQLStatements - statements on table of format, upserts need
+// PostgreSQL 9.5 or later.
+func postgreSQLStatements(format, table string) sqlStatements {
+	if format == sqlFormatAccess {
+		return sqlStatements{
+			createTable: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (event_time TIMESTAMP WITH TIME ZONE NOT NULL, event_data JSONB)", table),
+			insertRow:   fmt.Sprintf("INSERT INTO %s (event_time, event_data) VALUES ($1, $2)", table),
+		}
+	}
+	return sqlStatements{
+		createTable: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key VARCHAR PRIMARY KEY, value JSONB)", table),
+		upsertRow:   fmt.Sprintf("INSERT INTO %s (key, value) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value", table),
+		deleteRow:   fmt.Sprintf("DELETE FROM %s WHERE key = $1", table),
+	}
+}
+
+// newPostgreSQLNotify - connects to the configured database and
+// creates the table if missing.
+func newPostgreSQLNotify(pgNotify postgreSQLNotify) (notificationTarget, error) {
+	if e := checkSQLTable(pgNotify.Format, pgNotify.Table); e != nil {
+		return nil, e
+	}
+	port := pgNotify.Port
+	if port == "" {
+		port = defaultPostgreSQLPort
+	}
+	// The driver takes TLS settings as connection parameters, with
+	// certificates and keys read from files.
+	params := url.Values{}
+	params.Set("connect_timeout", strconv.Itoa(int(notifyTimeout/time.Second)))
+	switch {
+	case !pgNotify.TLS.Enable:
+		params.Set("sslmode", "disable")
+	case pgNotify.TLS.SkipVerify:
+		params.Set("sslmode", "require")
+	default:
+		params.Set("sslmode", "verify-full")
+	}
+	if pgNotify.TLS.Enable {
+		if pgNotify.TLS.CACert != "" {
+			params.Set("sslrootcert", pgNotify.TLS.CACert)
+		}
+		if pgNotify.TLS.ClientCert != "" || pgNotify.TLS.ClientKey != "" {
+			params.Set("sslcert", pgNotify.TLS.ClientCert)
+			params.Set("sslkey", pgNotify.TLS.ClientKey)
+		}
+	}
+	dataSource := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(pgNotify.User, pgNotify.Password),
+		Host:     net.JoinHostPort(pgNotify.Host, port),
+		Path:     "/" + pgNotify.Database,
+		RawQuery: params.Encode(),
+	}
+	db, e := openSQLDB("postgres", dataSource.String())
+	if e != nil {
+		return nil, e
+	}
+	return newSQLTarget(sqlTarget{
+		format:     pgNotify.Format,
+		statements: postgreSQLStatements(pgNotify.Format, pgNotify.Table),
+		timeLayout: time.RFC3339Nano,
+		exec:       sqlExec(db),
+		close:      db.Close,
+	})
+}
@@ -0,0 +1,158 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Table formats of database notification targets.
+const (
+	// Table of objects keyed by "bucket/object", a row is upserted
+	// when an object is created and deleted when it is removed.
+	sqlFormatNamespace = "namespace"
+	// Table of events, a row is appended for every event.
+	sqlFormatAccess = "access"
+)
+
+var (
+	errInvalidSQLFormat = errors.New("Table format must be ‘namespace’ or ‘access’")
+	errInvalidSQLTable  = errors.New("Table name may only contain letters, digits and underscores")
+)
+
+// Table names are put into statements as is, so only plain
+// identifiers are accepted.
+var validSQLTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkSQLTable - validates table format and name of a database
+// target.
+func checkSQLTable(format, table string) error {
+	if format != sqlFormatNamespace && format != sqlFormatAccess {
+		return errInvalidSQLFormat
+	}
+	if !validSQLTable.MatchString(table) {
+		return errInvalidSQLTable
+	}
+	return nil
+}
+
+// sqlStatements - statements of a database target on its table.
+type sqlStatements struct {
+	createTable string
+	// Namespace format, args are key and event data.
+	upsertRow string
+	// Namespace format, arg is key.
+	deleteRow string
+	// Access format, args are event time and event data.
+	insertRow string
+}
+
+// sqlTarget - database notification target, keeping a table of either
+// format. Statements are executed with args as text.
+type sqlTarget struct {
+	format     string
+	statements sqlStatements
+	// Layout event times are passed in.
+	timeLayout string
+	exec       func(query string, args ...string) error
+	close      func() error
+}
+
+// newSQLTarget - returns target on the table of format, creating the
+// table if missing.
+func newSQLTarget(t sqlTarget) (notificationTarget, error) {
+	if e := t.exec(t.statements.createTable); e != nil {
+		t.close()
+		return nil, e
+	}
+	return t, nil
+}
+
+// Send - updates the table with the event entry.
+func (t sqlTarget) Send(entry eventLogEntry) error {
+	data, e := json.Marshal(entry)
+	if e != nil {
+		return e
+	}
+	if t.format == sqlFormatAccess {
+		return t.exec(t.statements.insertRow, sqlEventTime(entry).Format(t.timeLayout), string(data))
+	}
+	// Entry key is "bucket/object", alarms have no object.
+	bucketObject := strings.SplitN(entry.Key, "/", 2)
+	if len(bucketObject) != 2 || bucketObject[1] == "" {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(entry.EventType, "s3:ObjectCreated:"):
+		return t.exec(t.statements.upsertRow, entry.Key, string(data))
+	case strings.HasPrefix(entry.EventType, "s3:ObjectRemoved:"):
+		return t.exec(t.statements.deleteRow, entry.Key)
+	}
+	return nil
+}
+
+// Close - closes connection to the database.
+func (t sqlTarget) Close() error {
+	return t.close()
+}
+
+// openSQLDB - opens database at dataSource with driverName, and
+// connects so that misconfiguration is reported right away.
+func openSQLDB(driverName, dataSource string) (*sql.DB, error) {
+	db, e := sql.Open(driverName, dataSource)
+	if e != nil {
+		return nil, e
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if e = db.PingContext(ctx); e != nil {
+		db.Close()
+		return nil, e
+	}
+	return db, nil
+}
+
+// sqlExec - returns exec of a target executing statements on db.
+func sqlExec(db *sql.DB) func(query string, args ...string) error {
+	return func(query string, args ...string) error {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = arg
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		_, e := db.ExecContext(ctx, query, values...)
+		return e
+	}
+}
+
+// sqlEventTime - returns time of the event entry, entries replayed
+// from the event store keep their original time.
+func sqlEventTime(entry eventLogEntry) time.Time {
+	if len(entry.Records) > 0 {
+		if eventTime, e := time.Parse(timeFormatAMZ, entry.Records[0].EventTime); e == nil {
+			return eventTime
+		}
+	}
+	return time.Now().UTC()
+}
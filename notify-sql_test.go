@@ -0,0 +1,97 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests database targets keep their table in sync with the events of
+// either format.
+func TestSQLTarget(t *testing.T) {
+	if e := checkSQLTable("rows", "events"); e != errInvalidSQLFormat {
+		t.Fatalf("Expected %v, got %v", errInvalidSQLFormat, e)
+	}
+	if e := checkSQLTable(sqlFormatAccess, "events; DROP TABLE objects"); e != errInvalidSQLTable {
+		t.Fatalf("Expected %v, got %v", errInvalidSQLTable, e)
+	}
+
+	entries := []eventLogEntry{
+		{EventType: "s3:ObjectCreated:Put", Key: "bucket/a", Records: []notificationEvent{{EventTime: "2016-05-01T10:00:00.000Z"}}},
+		{EventType: "s3:ObjectCreated:Copy", Key: "bucket/b"},
+		{EventType: "s3:ObjectRemoved:Delete", Key: "bucket/a"},
+		{EventType: "s3:ObjectCreated:Put", Key: "bucket/"},
+	}
+	testCases := []struct {
+		format   string
+		expected []string
+	}{
+		{sqlFormatNamespace, []string{"create", "upsert bucket/a", "upsert bucket/b", "delete bucket/a"}},
+		{sqlFormatAccess, []string{"create", "insert 2016-05-01 10:00:00.000"}},
+	}
+	for i, testCase := range testCases {
+		var executed []string
+		closed := false
+		target, e := newSQLTarget(sqlTarget{
+			format: testCase.format,
+			statements: sqlStatements{
+				createTable: "create",
+				upsertRow:   "upsert",
+				deleteRow:   "delete",
+				insertRow:   "insert",
+			},
+			timeLayout: mySQLTimeLayout,
+			exec: func(query string, args ...string) error {
+				// Event data is left out, only keys and times are
+				// compared.
+				if len(args) > 0 {
+					query += " " + args[0]
+				}
+				executed = append(executed, query)
+				return nil
+			},
+			close: func() error {
+				closed = true
+				return nil
+			},
+		})
+		if e != nil {
+			t.Fatalf("Test %d: %v", i+1, e)
+		}
+		for _, entry := range entries {
+			if e = target.Send(entry); e != nil {
+				t.Fatalf("Test %d: %v", i+1, e)
+			}
+		}
+		if testCase.format == sqlFormatAccess {
+			// Entries without records are stamped with the current
+			// time, so only the first one is compared.
+			if len(executed) != 5 || !strings.HasPrefix(executed[2], "insert ") {
+				t.Fatalf("Test %d: Unexpected statements %v", i+1, executed)
+			}
+			executed = executed[:2]
+		}
+		if !reflect.DeepEqual(executed, testCase.expected) {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.expected, executed)
+		}
+		if target.Close(); !closed {
+			t.Errorf("Test %d: Expected connection to be closed", i+1)
+		}
+	}
+}
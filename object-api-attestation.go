@@ -0,0 +1,318 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Object attestations are saved under this prefix in
+	// minioMetaVolume, bucket names cannot start with a '.'.
+	objectAttestationPrefix = ".attestation"
+
+	// Maximum size of a saved attestation.
+	maxAttestationSize = 16 * 1024
+
+	// Attestation signing key, kept next to the TLS certificates.
+	attestationKeyFile = "attestation.key"
+
+	// Type of attestation payloads.
+	attestationPayloadType = "application/vnd.minio.object-attestation+json"
+)
+
+var errInvalidAttestationKey = errors.New("Attestation key is not a PEM encoded ed25519 private key")
+
+// attestationConfig - object attestation configuration, objects
+// written while enabled are attested with the key in the certs
+// directory, generated on first use.
+type attestationConfig struct {
+	Enable bool `json:"enable"`
+	// Server identity recorded in attestations, host name if empty.
+	Server string `json:"server"`
+}
+
+// attestationStatement - attested state of an object at ingest.
+type attestationStatement struct {
+	Bucket string    `json:"bucket"`
+	Object string    `json:"object"`
+	Size   int64     `json:"size"`
+	MD5    string    `json:"md5"`
+	SHA256 string    `json:"sha256"`
+	Time   time.Time `json:"time"`
+	Server string    `json:"server"`
+}
+
+// objectAttestation - signed attestation statement. Signature is the
+// ed25519 signature of the payload, the JSON encoded statement, by the
+// key with id KeyID.
+type objectAttestation struct {
+	PayloadType string `json:"payloadType"`
+	Payload     []byte `json:"payload"`
+	KeyID       string `json:"keyId"`
+	Signature   []byte `json:"signature"`
+}
+
+// attestationKeyInfo - public key verifying attestations.
+type attestationKeyInfo struct {
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"`
+}
+
+// objectAttestor - signs attestations of written objects, disabled
+// until a key is set.
+type objectAttestor struct {
+	rwMutex *sync.RWMutex
+	key     ed25519.PrivateKey
+	keyID   string
+	server  string
+}
+
+func newObjectAttestor() *objectAttestor {
+	return &objectAttestor{rwMutex: &sync.RWMutex{}}
+}
+
+// attestationKeyID - returns id of a public key, hex encoded SHA-256
+// of its DER encoding.
+func attestationKeyID(publicKey ed25519.PublicKey) string {
+	der, e := x509.MarshalPKIXPublicKey(publicKey)
+	if e != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadAttestationKey - loads the PEM encoded attestation key from
+// keyFile, generating a new key if it does not exist.
+func loadAttestationKey(keyFile string) (ed25519.PrivateKey, error) {
+	keyBytes, e := ioutil.ReadFile(keyFile)
+	if os.IsNotExist(e) {
+		_, key, e := ed25519.GenerateKey(rand.Reader)
+		if e != nil {
+			return nil, e
+		}
+		der, e := x509.MarshalPKCS8PrivateKey(key)
+		if e != nil {
+			return nil, e
+		}
+		if e = os.MkdirAll(filepath.Dir(keyFile), 0700); e != nil {
+			return nil, e
+		}
+		keyBytes = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if e = ioutil.WriteFile(keyFile, keyBytes, 0600); e != nil {
+			return nil, e
+		}
+		return key, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, errInvalidAttestationKey
+	}
+	parsedKey, e := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if e != nil {
+		return nil, e
+	}
+	key, ok := parsedKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errInvalidAttestationKey
+	}
+	return key, nil
+}
+
+// initAttestations - enables attestations of objects written from now
+// on, if configured.
+func initAttestations(o objectAPI) error {
+	config := serverConfig.GetAttestation()
+	if !config.Enable {
+		return nil
+	}
+	key, e := loadAttestationKey(filepath.Join(mustGetCertsPath(), attestationKeyFile))
+	if e != nil {
+		return e
+	}
+	server := config.Server
+	if server == "" {
+		if server, e = os.Hostname(); e != nil {
+			return e
+		}
+	}
+	o.SetAttestationKey(key, server)
+	return nil
+}
+
+// SetAttestationKey - attests objects written from now on with key on
+// behalf of server, nil key disables attestations.
+func (o objectAPI) SetAttestationKey(key ed25519.PrivateKey, server string) {
+	o.attestor.rwMutex.Lock()
+	defer o.attestor.rwMutex.Unlock()
+	o.attestor.key = key
+	o.attestor.server = server
+	o.attestor.keyID = ""
+	if key != nil {
+		o.attestor.keyID = attestationKeyID(key.Public().(ed25519.PublicKey))
+	}
+}
+
+// AttestationKeyInfo - returns the public key verifying attestations,
+// false if attestations are disabled.
+func (o objectAPI) AttestationKeyInfo() (attestationKeyInfo, bool) {
+	o.attestor.rwMutex.RLock()
+	defer o.attestor.rwMutex.RUnlock()
+	if o.attestor.key == nil {
+		return attestationKeyInfo{}, false
+	}
+	der, e := x509.MarshalPKIXPublicKey(o.attestor.key.Public())
+	if e != nil {
+		return attestationKeyInfo{}, false
+	}
+	return attestationKeyInfo{
+		KeyID:     o.attestor.keyID,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, true
+}
+
+// attestationHasher - counts and hashes object data while it is
+// written, nil if attestations are disabled.
+type attestationHasher struct {
+	hash.Hash
+	size int64
+}
+
+func (h *attestationHasher) Write(p []byte) (int, error) {
+	h.size += int64(len(p))
+	return h.Hash.Write(p)
+}
+
+// newAttestationHasher - returns hasher for object data written, nil if
+// attestations are disabled.
+func (o objectAPI) newAttestationHasher() *attestationHasher {
+	o.attestor.rwMutex.RLock()
+	defer o.attestor.rwMutex.RUnlock()
+	if o.attestor.key == nil {
+		return nil
+	}
+	return &attestationHasher{Hash: sha256.New()}
+}
+
+// hashObjectData - hashes data of an object written without hashing it.
+func (o objectAPI) hashObjectData(bucket, object string, hasher *attestationHasher) error {
+	r, e := o.storage.ReadFile(o.context(), bucket, object, 0)
+	if e != nil {
+		return e
+	}
+	defer r.Close()
+	_, e = io.Copy(hasher, r)
+	return e
+}
+
+// objectAttestationPath - returns attestation path in minioMetaVolume.
+func objectAttestationPath(bucket, object string) string {
+	return path.Join(objectAttestationPrefix, bucket, object)
+}
+
+// attestObject - saves a signed attestation of a newly written object,
+// stale attestations are removed if data was not hashed.
+func (o objectAPI) attestObject(bucket, object, md5Hex string, hasher *attestationHasher) error {
+	if hasher == nil {
+		o.removeObjectAttestation(bucket, object)
+		return nil
+	}
+	o.attestor.rwMutex.RLock()
+	key, keyID, server := o.attestor.key, o.attestor.keyID, o.attestor.server
+	o.attestor.rwMutex.RUnlock()
+	if key == nil {
+		o.removeObjectAttestation(bucket, object)
+		return nil
+	}
+	payload, e := json.Marshal(attestationStatement{
+		Bucket: bucket,
+		Object: object,
+		Size:   hasher.size,
+		MD5:    md5Hex,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+		Time:   time.Now().UTC(),
+		Server: server,
+	})
+	if e != nil {
+		return e
+	}
+	attestationBytes, e := json.Marshal(objectAttestation{
+		PayloadType: attestationPayloadType,
+		Payload:     payload,
+		KeyID:       keyID,
+		Signature:   ed25519.Sign(key, payload),
+	})
+	if e != nil {
+		return e
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(objectAttestationPath(bucket, object), attestationBytes)
+}
+
+// removeObjectAttestation - removes attestation of an overwritten or
+// deleted object.
+func (o objectAPI) removeObjectAttestation(bucket, object string) {
+	e := o.storage.DeleteFile(context.Background(), minioMetaVolume, objectAttestationPath(bucket, object))
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to remove object attestation.", nil)
+	}
+}
+
+// GetObjectAttestation - returns signed attestation of an object,
+// objects written while attestations were disabled have none.
+func (o objectAPI) GetObjectAttestation(bucket, object string) (objectAttestation, *probe.Error) {
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return objectAttestation{}, err.Trace(bucket, object)
+	}
+	attestationBytes, e := o.readMetaFile(objectAttestationPath(bucket, object), maxAttestationSize)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return objectAttestation{}, probe.NewError(AttestationNotFound{Bucket: bucket, Object: object})
+		}
+		return objectAttestation{}, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	attestation := objectAttestation{}
+	if e = json.Unmarshal(attestationBytes, &attestation); e != nil {
+		return objectAttestation{}, probe.NewError(e)
+	}
+	return attestation, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests objects are attested while attestations are enabled, and
+// attestations verify against the published key.
+func TestObjectAttestation(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-attestation-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	certsDir, e := ioutil.TempDir("", "minio-attestation-certs-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(certsDir)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := obj.AttestationKeyInfo(); ok {
+		t.Fatal("Expected attestations to be disabled")
+	}
+
+	// Key is generated on first use and loaded afterwards.
+	keyFile := filepath.Join(certsDir, "certs", attestationKeyFile)
+	key, e := loadAttestationKey(keyFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	loadedKey, e := loadAttestationKey(keyFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !key.Equal(loadedKey) {
+		t.Fatal("Expected saved key to be loaded")
+	}
+	obj.SetAttestationKey(key, "node1")
+
+	data := []byte("hello world")
+	md5Sum, err := obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestation, err := obj.GetObjectAttestation("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyInfo, ok := obj.AttestationKeyInfo()
+	if !ok || attestation.KeyID != keyInfo.KeyID {
+		t.Fatalf("Expected key id %s, got %s", keyInfo.KeyID, attestation.KeyID)
+	}
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), attestation.Payload, attestation.Signature) {
+		t.Fatal("Attestation signature does not verify")
+	}
+	statement := attestationStatement{}
+	if e = json.Unmarshal(attestation.Payload, &statement); e != nil {
+		t.Fatal(e)
+	}
+	sum := sha256.Sum256(data)
+	if statement.Bucket != "bucket" || statement.Object != "object" || statement.Size != int64(len(data)) ||
+		statement.MD5 != md5Sum || statement.SHA256 != hex.EncodeToString(sum[:]) || statement.Server != "node1" {
+		t.Fatalf("Unexpected statement %+v", statement)
+	}
+
+	// Multipart uploads are attested on completion.
+	uploadID, err := obj.NewMultipartUpload("bucket", "multipart", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag, err := obj.PutObjectPart("bucket", "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), md5Sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.CompleteMultipartUpload("bucket", "multipart", uploadID, []completePart{{PartNumber: 1, ETag: etag}}); err != nil {
+		t.Fatal(err)
+	}
+	if attestation, err = obj.GetObjectAttestation("bucket", "multipart"); err != nil {
+		t.Fatal(err)
+	}
+	if e = json.Unmarshal(attestation.Payload, &statement); e != nil {
+		t.Fatal(e)
+	}
+	if statement.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Unexpected statement %+v", statement)
+	}
+
+	// Objects overwritten while disabled are no longer attested.
+	obj.SetAttestationKey(nil, "")
+	if _, err = obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.GetObjectAttestation("bucket", "object"); err == nil {
+		t.Fatal("Expected stale attestation to be removed")
+	} else if _, ok = err.ToGoError().(AttestationNotFound); !ok {
+		t.Fatalf("Expected AttestationNotFound, got %s", err.ToGoError())
+	}
+
+	// Attestations of deleted objects are removed.
+	if err = obj.DeleteObject("bucket", "multipart", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, e = obj.readMetaFile(objectAttestationPath("bucket", "multipart"), maxAttestationSize); errorCause(e) != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, e)
+	}
+}
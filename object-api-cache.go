@@ -0,0 +1,549 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Objects being cached are written under this directory of the
+	// cache, removed on start.
+	cacheTmpDir = "tmp"
+
+	// Default eviction watermarks, percents of the cache size.
+	defaultCacheHighWatermark = 90
+	defaultCacheLowWatermark  = 70
+)
+
+var errCacheNoMaxSize = errors.New("Cache size should be set with cache.maxSize")
+
+// cacheConfig - read-through cache of objects on local disks, in
+// front of a slower backend or remote tier.
+type cacheConfig struct {
+	Enable bool `json:"enable"`
+	// Directory objects are cached in, on fast local disks.
+	Dir string `json:"dir"`
+	// Maximum bytes cached.
+	MaxSize int64 `json:"maxSize"`
+	// Objects larger than MaxObjectSize bytes are not cached, zero for
+	// no limit.
+	MaxObjectSize int64 `json:"maxObjectSize"`
+	// Least recently used objects are evicted once HighWatermark
+	// percent of MaxSize is used, until LowWatermark percent is.
+	HighWatermark int `json:"highWatermark"`
+	LowWatermark  int `json:"lowWatermark"`
+	// Objects written are cached as well.
+	CacheWrites bool `json:"cacheWrites"`
+}
+
+// cacheStats - cache usage and hits since start.
+type cacheStats struct {
+	Enabled   bool  `json:"enabled"`
+	Entries   int   `json:"entries"`
+	Usage     int64 `json:"usage"`
+	MaxSize   int64 `json:"maxSize"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	BytesRead int64 `json:"bytesRead"`
+	Fills     int64 `json:"fills"`
+	Evictions int64 `json:"evictions"`
+}
+
+// cacheEntry - cached object, valid while the object has the same size
+// and modification time. Served without checking the backend until it
+// expires, if its Cache-Control has a max-age.
+type cacheEntry struct {
+	Bucket  string    `json:"bucket"`
+	Object  string    `json:"object"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Expires time.Time `json:"expires,omitempty"`
+
+	element *list.Element
+}
+
+// objectCache - objects cached on local disks, evicted least recently
+// used first.
+type objectCache struct {
+	mutex   *sync.Mutex
+	config  cacheConfig
+	entries map[string]*cacheEntry
+	// Entries by use, most recent in front.
+	lru   *list.List
+	stats cacheStats
+}
+
+func newObjectCache() *objectCache {
+	return &objectCache{
+		mutex:   &sync.Mutex{},
+		entries: make(map[string]*cacheEntry),
+		lru:     list.New(),
+	}
+}
+
+// cacheControl - directives of a Cache-Control header relevant to the
+// cache.
+type cacheControl struct {
+	noStore bool
+	noCache bool
+	maxAge  time.Duration
+}
+
+// parseCacheControl - parses Cache-Control header value.
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store", directive == "private":
+			cc.noStore = true
+		case directive == "no-cache":
+			cc.noCache = true
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, e := strconv.ParseInt(strings.TrimPrefix(directive, "max-age="), 10, 64)
+			if e == nil && seconds > 0 {
+				cc.maxAge = time.Duration(seconds) * time.Second
+			} else {
+				cc.noCache = true
+			}
+		}
+	}
+	return cc
+}
+
+// WithCacheControl - returns copy of the object layer serving a
+// request with Cache-Control header value, objects are not read from
+// the cache without checking the backend if it has no-cache, and not
+// cached at all if it has no-store.
+func (o objectAPI) WithCacheControl(value string) objectAPI {
+	o.cacheControl = value
+	return o
+}
+
+// SetCache - sets cache configuration, objects cached in its directory
+// on a previous run are cached again.
+func (o objectAPI) SetCache(config cacheConfig) error {
+	c := o.cache
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.lru.Init()
+	c.stats = cacheStats{}
+	c.config = config
+	if !config.Enable {
+		return nil
+	}
+	if config.MaxSize <= 0 {
+		return errCacheNoMaxSize
+	}
+	if c.config.HighWatermark <= 0 || c.config.HighWatermark > 100 {
+		c.config.HighWatermark = defaultCacheHighWatermark
+	}
+	if c.config.LowWatermark <= 0 || c.config.LowWatermark > c.config.HighWatermark {
+		c.config.LowWatermark = defaultCacheLowWatermark
+		if c.config.LowWatermark > c.config.HighWatermark {
+			c.config.LowWatermark = c.config.HighWatermark
+		}
+	}
+	tmpDir := filepath.Join(config.Dir, cacheTmpDir)
+	if e := os.RemoveAll(tmpDir); e != nil {
+		return e
+	}
+	if e := os.MkdirAll(tmpDir, 0700); e != nil {
+		return e
+	}
+	return c.load()
+}
+
+// key - returns key of object in bucket.
+func cacheKey(bucket, object string) string {
+	sum := sha256.Sum256([]byte(bucket + slashSeparator + object))
+	return hex.EncodeToString(sum[:])
+}
+
+// dataPath - returns path of cached data of key.
+func (c *objectCache) dataPath(key string) string {
+	return filepath.Join(c.config.Dir, key+".data")
+}
+
+// entryPath - returns path of saved entry of key.
+func (c *objectCache) entryPath(key string) string {
+	return filepath.Join(c.config.Dir, key+".json")
+}
+
+// load - loads entries saved in the cache directory, least recently
+// modified ones are evicted first. Data without an entry is removed.
+func (c *objectCache) load() error {
+	dirents, e := ioutil.ReadDir(c.config.Dir)
+	if e != nil {
+		return e
+	}
+	var names []string
+	for _, dirent := range dirents {
+		names = append(names, dirent.Name())
+	}
+	var entries []*cacheEntry
+	var modTimes []time.Time
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		key := strings.TrimSuffix(name, ".json")
+		entry := &cacheEntry{}
+		data, e := ioutil.ReadFile(c.entryPath(key))
+		if e == nil {
+			e = json.Unmarshal(data, entry)
+		}
+		var fi os.FileInfo
+		if e == nil {
+			fi, e = os.Stat(c.dataPath(key))
+		}
+		if e != nil || fi.Size() != entry.Size || cacheKey(entry.Bucket, entry.Object) != key {
+			os.Remove(c.entryPath(key))
+			continue
+		}
+		entries = append(entries, entry)
+		modTimes = append(modTimes, fi.ModTime())
+	}
+	sort.Sort(byModTime{entries, modTimes})
+	for _, entry := range entries {
+		c.add(cacheKey(entry.Bucket, entry.Object), entry)
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, ".data") {
+			if _, ok := c.entries[strings.TrimSuffix(name, ".data")]; !ok {
+				os.Remove(filepath.Join(c.config.Dir, name))
+			}
+		}
+	}
+	c.evict()
+	return nil
+}
+
+// byModTime - sorts entries by modification time of their data, most
+// recent first.
+type byModTime struct {
+	entries  []*cacheEntry
+	modTimes []time.Time
+}
+
+func (s byModTime) Len() int           { return len(s.entries) }
+func (s byModTime) Less(i, j int) bool { return s.modTimes[i].After(s.modTimes[j]) }
+func (s byModTime) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+	s.modTimes[i], s.modTimes[j] = s.modTimes[j], s.modTimes[i]
+}
+
+// add - adds entry of key, least recently used. Callers hold mutex.
+func (c *objectCache) add(key string, entry *cacheEntry) {
+	entry.element = c.lru.PushBack(key)
+	c.entries[key] = entry
+	c.stats.Usage += entry.Size
+}
+
+// remove - removes entry of key and its data. Callers hold mutex.
+func (c *objectCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(entry.element)
+	delete(c.entries, key)
+	c.stats.Usage -= entry.Size
+	os.Remove(c.entryPath(key))
+	os.Remove(c.dataPath(key))
+}
+
+// evict - evicts least recently used entries once usage is above the
+// high watermark, until it is below the low watermark. Callers hold
+// mutex.
+func (c *objectCache) evict() {
+	if c.stats.Usage*100 <= c.config.MaxSize*int64(c.config.HighWatermark) {
+		return
+	}
+	for c.lru.Len() > 0 && c.stats.Usage*100 > c.config.MaxSize*int64(c.config.LowWatermark) {
+		c.remove(c.lru.Back().Value.(string))
+		c.stats.Evictions++
+	}
+}
+
+// Invalidate - removes cached object in bucket.
+func (c *objectCache) Invalidate(bucket, object string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.remove(cacheKey(bucket, object))
+}
+
+// InvalidateBucket - removes all cached objects in bucket.
+func (c *objectCache) InvalidateBucket(bucket string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, entry := range c.entries {
+		if entry.Bucket == bucket {
+			c.remove(key)
+		}
+	}
+}
+
+// Stats - returns cache usage and hits.
+func (c *objectCache) Stats() cacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Enabled = c.config.Enable
+	stats.Entries = len(c.entries)
+	stats.MaxSize = c.config.MaxSize
+	return stats
+}
+
+// cacheReader - reader of cached data, counts bytes served from the
+// cache.
+type cacheReader struct {
+	*os.File
+	cache *objectCache
+}
+
+func (r cacheReader) Read(p []byte) (int, error) {
+	n, e := r.File.Read(p)
+	r.cache.mutex.Lock()
+	r.cache.stats.BytesRead += int64(n)
+	r.cache.mutex.Unlock()
+	return n, e
+}
+
+// get - returns reader of cached object from startOffset, nil if it
+// is not cached. Cached objects are served if they match fi, or if
+// they did not expire if fi is nil.
+func (c *objectCache) get(bucket, object string, fi *FileInfo, startOffset int64, cc cacheControl) io.ReadCloser {
+	if cc.noStore || (fi == nil && cc.noCache) {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.config.Enable {
+		return nil
+	}
+	key := cacheKey(bucket, object)
+	entry, ok := c.entries[key]
+	if fi == nil {
+		if !ok || !time.Now().UTC().Before(entry.Expires) {
+			return nil
+		}
+	} else if !ok || entry.Size != fi.Size || !entry.ModTime.Equal(fi.ModTime) {
+		c.stats.Misses++
+		return nil
+	}
+	file, e := os.Open(c.dataPath(key))
+	if e == nil {
+		_, e = file.Seek(startOffset, 0)
+	}
+	if e != nil {
+		if file != nil {
+			file.Close()
+		}
+		c.remove(key)
+		c.stats.Misses++
+		return nil
+	}
+	c.lru.MoveToFront(entry.element)
+	c.stats.Hits++
+	return cacheReader{File: file, cache: c}
+}
+
+// cacheFill - writes object data to the cache, failing to do so never
+// fails reads or writes of the object, caching it is given up instead.
+type cacheFill struct {
+	cache   *objectCache
+	bucket  string
+	object  string
+	file    *os.File
+	written int64
+}
+
+// newFill - returns fill of object of size in bucket, nil if it is not
+// cached.
+func (c *objectCache) newFill(bucket, object string, size int64, cc cacheControl) *cacheFill {
+	c.mutex.Lock()
+	config := c.config
+	c.mutex.Unlock()
+	if !config.Enable || cc.noStore || size < 0 || size*100 > config.MaxSize*int64(config.LowWatermark) {
+		return nil
+	}
+	if config.MaxObjectSize > 0 && size > config.MaxObjectSize {
+		return nil
+	}
+	file, e := ioutil.TempFile(filepath.Join(config.Dir, cacheTmpDir), "fill-")
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to cache object.", nil)
+		return nil
+	}
+	return &cacheFill{cache: c, bucket: bucket, object: object, file: file}
+}
+
+func (f *cacheFill) Write(p []byte) (int, error) {
+	if f == nil || f.file == nil {
+		return len(p), nil
+	}
+	n, e := f.file.Write(p)
+	f.written += int64(n)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(f.bucket, f.object), "Unable to cache object.", nil)
+		f.abort()
+	}
+	return len(p), nil
+}
+
+// abort - gives up caching the object.
+func (f *cacheFill) abort() {
+	if f == nil || f.file == nil {
+		return
+	}
+	f.file.Close()
+	os.Remove(f.file.Name())
+	f.file = nil
+}
+
+// commit - caches the data written if it is the whole object of fi,
+// expiring after maxAge if not zero.
+func (f *cacheFill) commit(fi FileInfo, maxAge time.Duration) {
+	if f == nil || f.file == nil {
+		return
+	}
+	if f.written != fi.Size {
+		f.abort()
+		return
+	}
+	tmpPath := f.file.Name()
+	e := f.file.Close()
+	f.file = nil
+	entry := &cacheEntry{Bucket: f.bucket, Object: f.object, Size: fi.Size, ModTime: fi.ModTime}
+	if maxAge > 0 {
+		entry.Expires = time.Now().UTC().Add(maxAge)
+	}
+	var data []byte
+	if e == nil {
+		data, e = json.Marshal(entry)
+	}
+
+	c := f.cache
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := cacheKey(f.bucket, f.object)
+	c.remove(key)
+	if e == nil {
+		e = os.Rename(tmpPath, c.dataPath(key))
+	}
+	if e == nil {
+		e = ioutil.WriteFile(c.entryPath(key), data, 0600)
+	}
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(f.bucket, f.object), "Unable to cache object.", nil)
+		os.Remove(tmpPath)
+		os.Remove(c.dataPath(key))
+		return
+	}
+	c.add(key, entry)
+	c.lru.MoveToFront(entry.element)
+	c.stats.Fills++
+	c.evict()
+}
+
+// cacheFillReader - reader of object data from the backend, caches
+// the object once all of it is read.
+type cacheFillReader struct {
+	io.ReadCloser
+	fill   *cacheFill
+	fi     FileInfo
+	maxAge time.Duration
+}
+
+func (r *cacheFillReader) Read(p []byte) (int, error) {
+	n, e := r.ReadCloser.Read(p)
+	r.fill.Write(p[:n])
+	if e == io.EOF {
+		r.fill.commit(r.fi, r.maxAge)
+	}
+	return n, e
+}
+
+func (r *cacheFillReader) Close() error {
+	r.fill.abort()
+	return r.ReadCloser.Close()
+}
+
+// readThrough - returns reader of object data r read from the
+// backend, which caches the object of fi once all of it is read.
+// Objects with Cache-Control no-store or private are not cached.
+func (o objectAPI) readThrough(bucket, object string, fi FileInfo, startOffset int64, r io.ReadCloser) io.ReadCloser {
+	cc := parseCacheControl(o.cacheControl)
+	if startOffset != 0 || cc.noStore || !o.cache.Stats().Enabled {
+		return r
+	}
+	userMetadata, _ := o.getObjectMetadata(bucket, object)
+	objectCC := parseCacheControl(userMetadata["Cache-Control"])
+	fill := o.cache.newFill(bucket, object, fi.Size, objectCC)
+	if fill == nil {
+		return r
+	}
+	return &cacheFillReader{ReadCloser: r, fill: fill, fi: fi, maxAge: objectCC.maxAge}
+}
+
+// CacheStats - returns cache usage and hits.
+func (o objectAPI) CacheStats() cacheStats {
+	return o.cache.Stats()
+}
+
+// newWriteFill - returns fill of object of size written in bucket, nil
+// unless writes are cached.
+func (o objectAPI) newWriteFill(bucket, object string, size int64) *cacheFill {
+	o.cache.mutex.Lock()
+	cacheWrites := o.cache.config.CacheWrites
+	o.cache.mutex.Unlock()
+	if !cacheWrites {
+		return nil
+	}
+	return o.cache.newFill(bucket, object, size, parseCacheControl(o.cacheControl))
+}
+
+// commitWriteFill - caches object written in bucket.
+func (o objectAPI) commitWriteFill(fill *cacheFill, bucket, object string) {
+	if fill == nil {
+		return
+	}
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil {
+		fill.abort()
+		return
+	}
+	fill.commit(fi, 0)
+}
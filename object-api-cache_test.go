@@ -0,0 +1,188 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// readObject - reads all of object in bucket.
+func readObject(t *testing.T, obj objectAPI, bucket, object string) string {
+	r, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return string(data)
+}
+
+// Tests objects read are cached, validated against the backend and
+// evicted least recently used first.
+func TestObjectCache(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-cache")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	cacheDir, e := ioutil.TempDir("", "minio-cache")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(cacheDir)
+	obj := newObjectLayer(fs)
+	if e = obj.SetCache(cacheConfig{Enable: true, Dir: cacheDir}); e != errCacheNoMaxSize {
+		t.Fatalf("Expected %v, got %v", errCacheNoMaxSize, e)
+	}
+	config := cacheConfig{Enable: true, Dir: cacheDir, MaxSize: 100, MaxObjectSize: 40}
+	if e = obj.SetCache(config); e != nil {
+		t.Fatal(e)
+	}
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	put := func(object, data string) {
+		if _, err := obj.PutObject("bucket", object, int64(len(data)), bytes.NewBufferString(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("a", "aaaaaaaaaaaaaaaaaaaa")
+	if data := readObject(t, obj, "bucket", "a"); data != "aaaaaaaaaaaaaaaaaaaa" {
+		t.Fatalf("Unexpected data %q", data)
+	}
+	if stats := obj.CacheStats(); stats.Misses != 1 || stats.Hits != 0 || stats.Fills != 1 || stats.Usage != 20 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if data := readObject(t, obj, "bucket", "a"); data != "aaaaaaaaaaaaaaaaaaaa" {
+		t.Fatalf("Unexpected data %q", data)
+	}
+	if stats := obj.CacheStats(); stats.Hits != 1 || stats.BytesRead != 20 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Overwritten objects are read from the backend again.
+	put("a", "bbbbbbbbbbbbbbbbbbbb")
+	if data := readObject(t, obj, "bucket", "a"); data != "bbbbbbbbbbbbbbbbbbbb" {
+		t.Fatalf("Unexpected data %q", data)
+	}
+	if stats := obj.CacheStats(); stats.Hits != 1 || stats.Fills != 2 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Objects larger than the limit are not cached.
+	put("large", string(bytes.Repeat([]byte("l"), 50)))
+	readObject(t, obj, "bucket", "large")
+	if stats := obj.CacheStats(); stats.Fills != 2 || stats.Entries != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Requests with no-store are not cached.
+	put("b", "bbbbbbbbbbbbbbbbbbbb")
+	readObject(t, obj.WithCacheControl("no-store"), "bucket", "b")
+	if stats := obj.CacheStats(); stats.Entries != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Going above the high watermark evicts least recently used
+	// objects down to the low watermark.
+	readObject(t, obj, "bucket", "b")
+	for _, object := range []string{"c", "d", "e"} {
+		put(object, "cccccccccccccccccccc")
+		readObject(t, obj, "bucket", object)
+		readObject(t, obj, "bucket", "a")
+	}
+	stats := obj.CacheStats()
+	if stats.Usage != 60 || stats.Evictions != 2 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	for _, object := range []string{"a", "d", "e"} {
+		if _, ok := obj.cache.entries[cacheKey("bucket", object)]; !ok {
+			t.Fatalf("Expected %s to be cached", object)
+		}
+	}
+
+	// Cached objects are loaded again.
+	obj = newObjectLayer(fs)
+	if e = obj.SetCache(config); e != nil {
+		t.Fatal(e)
+	}
+	if stats := obj.CacheStats(); stats.Entries != 3 || stats.Usage != 60 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Deleted objects are not served from the cache.
+	if err := obj.DeleteObject("bucket", "a", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.GetObject("bucket", "a", 0); err == nil {
+		t.Fatal("Expected deleted object not to be found")
+	}
+}
+
+// Tests objects are served from the cache until their max-age
+// expires.
+func TestObjectCacheMaxAge(t *testing.T) {
+	cc := parseCacheControl("public, max-age=60")
+	if cc.maxAge != time.Minute || cc.noStore || cc.noCache {
+		t.Fatalf("Unexpected cache control %+v", cc)
+	}
+	if private := parseCacheControl("private"); !private.noStore {
+		t.Fatalf("Unexpected cache control %+v", private)
+	}
+
+	directory, e := ioutil.TempDir("", "minio-cache")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	c := newObjectCache()
+	obj := objectAPI{cache: c}
+	if e = obj.SetCache(cacheConfig{Enable: true, Dir: directory, MaxSize: 100}); e != nil {
+		t.Fatal(e)
+	}
+	fill := c.newFill("bucket", "object", 4, cc)
+	if fill == nil {
+		t.Fatal("Expected object to be cached")
+	}
+	fill.Write([]byte("data"))
+	fill.commit(FileInfo{Size: 4, ModTime: time.Now().UTC()}, time.Minute)
+
+	r := c.get("bucket", "object", nil, 0, cacheControl{})
+	if r == nil {
+		t.Fatal("Expected object to be served from the cache")
+	}
+	r.Close()
+	if r = c.get("bucket", "object", nil, 0, cacheControl{noCache: true}); r != nil {
+		t.Fatal("Expected no-cache requests to check the backend")
+	}
+	c.entries[cacheKey("bucket", "object")].Expires = time.Now().UTC().Add(-time.Second)
+	if r = c.get("bucket", "object", nil, 0, cacheControl{}); r != nil {
+		t.Fatal("Expected expired object to be checked against the backend")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Version of the object layer interface, incremented whenever
+// operations are added or change behavior.
+const objectLayerVersion = 1
+
+// Optional features of the object layer.
+const (
+	// Versions of objects, versioning configuration of buckets.
+	featureVersioning = "versioning"
+	// Tags of objects.
+	featureTagging = "tagging"
+	// Server side encryption of objects.
+	featureSSE = "sse"
+	// Queries on object content.
+	featureSelect = "select"
+	// Retention and legal holds of objects, object lock
+	// configuration of buckets.
+	featureLocking = "locking"
+)
+
+// objectLayerFeatures - optional features and whether the object
+// layer implements them, backends may not support all of them.
+var objectLayerFeatures = map[string]bool{
+	featureVersioning: false,
+	featureTagging:    true,
+	featureSSE:        false,
+	featureSelect:     true,
+	featureLocking:    true,
+}
+
+// ObjectCapabilities - capabilities of the object layer over its
+// active backend.
+type ObjectCapabilities struct {
+	// Version of the object layer interface.
+	Version int `json:"version"`
+	// Optional features and whether they are supported.
+	Features map[string]bool `json:"features"`
+}
+
+// Capabilities - returns capabilities of the object layer.
+func (o objectAPI) Capabilities() ObjectCapabilities {
+	features := make(map[string]bool, len(objectLayerFeatures))
+	for feature, supported := range objectLayerFeatures {
+		features[feature] = supported
+	}
+	if limiter, ok := o.storage.(featureLimiter); ok {
+		for _, feature := range limiter.UnsupportedFeatures() {
+			features[feature] = false
+		}
+	}
+	return ObjectCapabilities{Version: objectLayerVersion, Features: features}
+}
+
+// Supports - returns true if feature is supported.
+func (o objectAPI) Supports(feature string) bool {
+	if !objectLayerFeatures[feature] {
+		return false
+	}
+	if limiter, ok := o.storage.(featureLimiter); ok {
+		for _, unsupported := range limiter.UnsupportedFeatures() {
+			if unsupported == feature {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Query parameters of requests which need a feature.
+var featureQueries = map[string]string{
+	"versioning":  featureVersioning,
+	"versions":    featureVersioning,
+	"versionId":   featureVersioning,
+	"tagging":     featureTagging,
+	"select":      featureSelect,
+	"retention":   featureLocking,
+	"legal-hold":  featureLocking,
+	"object-lock": featureLocking,
+}
+
+// Header prefixes of requests which need a feature.
+var featureHeaderPrefixes = map[string]string{
+	"X-Amz-Server-Side-Encryption":     featureSSE,
+	"X-Amz-Object-Lock-":               featureLocking,
+	"X-Amz-Bucket-Object-Lock-Enabled": featureLocking,
+}
+
+// requestFeatures - returns optional features needed to serve r.
+func requestFeatures(r *http.Request) []string {
+	var features []string
+	for name := range r.URL.Query() {
+		if feature, ok := featureQueries[name]; ok {
+			features = append(features, feature)
+		}
+	}
+	for name := range r.Header {
+		for prefix, feature := range featureHeaderPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				features = append(features, feature)
+			}
+		}
+	}
+	return features
+}
+
+// capabilityHandler - rejects requests needing features which are not
+// supported, before any of their data is read.
+type capabilityHandler struct {
+	handler http.Handler
+	objAPI  objectAPI
+}
+
+// setCapabilityHandler - returns handler function rejecting requests
+// objAPI does not support.
+func setCapabilityHandler(objAPI objectAPI) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		return capabilityHandler{handler: h, objAPI: objAPI}
+	}
+}
+
+func (h capabilityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browser, admin and internode requests are not S3 requests.
+	if !strings.HasPrefix(r.URL.Path, reservedBucket+"/") && r.URL.Path != reservedBucket {
+		for _, feature := range requestFeatures(r) {
+			if !h.objAPI.Supports(feature) {
+				writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
+				return
+			}
+		}
+	}
+	h.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,96 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// limitedStorage - storage not supporting locking.
+type limitedStorage struct {
+	StorageAPI
+}
+
+func (limitedStorage) UnsupportedFeatures() []string {
+	return []string{featureLocking}
+}
+
+// Tests capabilities reflect the backend, and requests needing
+// unsupported features are rejected.
+func TestObjectCapabilities(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-capabilities")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	capabilities := obj.Capabilities()
+	if capabilities.Version != objectLayerVersion || !capabilities.Features[featureLocking] || capabilities.Features[featureVersioning] {
+		t.Fatalf("Unexpected capabilities %+v", capabilities)
+	}
+	limited := newObjectLayer(limitedStorage{fs})
+	if limited.Capabilities().Features[featureLocking] || limited.Supports(featureLocking) || !limited.Supports(featureTagging) {
+		t.Fatalf("Unexpected capabilities %+v", limited.Capabilities())
+	}
+
+	testCases := []struct {
+		method        string
+		path          string
+		header        string
+		status        int
+		limitedStatus int
+	}{
+		{"GET", "/bucket/object", "", http.StatusOK, http.StatusOK},
+		{"GET", "/bucket/object?tagging", "", http.StatusOK, http.StatusOK},
+		{"GET", "/bucket?versioning", "", http.StatusNotImplemented, http.StatusNotImplemented},
+		{"GET", "/bucket/object?versionId=1", "", http.StatusNotImplemented, http.StatusNotImplemented},
+		{"PUT", "/bucket/object?retention", "", http.StatusOK, http.StatusNotImplemented},
+		{"PUT", "/bucket/object", "X-Amz-Object-Lock-Mode", http.StatusOK, http.StatusNotImplemented},
+		{"PUT", "/bucket/object", "X-Amz-Server-Side-Encryption", http.StatusNotImplemented, http.StatusNotImplemented},
+		{"POST", "/bucket/object?select&select-type=2", "", http.StatusOK, http.StatusOK},
+		// Reserved paths are not S3 requests.
+		{"GET", reservedBucket + "/admin/info?versions", "", http.StatusOK, http.StatusOK},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for i, testCase := range testCases {
+		for _, handler := range []struct {
+			handler http.Handler
+			status  int
+		}{
+			{setCapabilityHandler(obj)(ok), testCase.status},
+			{setCapabilityHandler(limited)(ok), testCase.limitedStatus},
+		} {
+			r := httptest.NewRequest(testCase.method, testCase.path, nil)
+			if testCase.header != "" {
+				r.Header.Set(testCase.header, "value")
+			}
+			w := httptest.NewRecorder()
+			handler.handler.ServeHTTP(w, r)
+			if w.Code != handler.status {
+				t.Errorf("Test %d: Expected %d, got %d", i+1, handler.status, w.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Header requesting server side extraction of an uploaded archive.
+const extractArchiveHeader = "X-Minio-Extract"
+
+const (
+	// Metadata key of archive entry modification time.
+	extractMtimeMetadataKey = userMetadataKeyPrefix + "Mtime"
+
+	// Maximum number of objects extracted from a single archive.
+	maxExtractEntries = 10000
+
+	// Prefix of zip archives spooled in the meta volume.
+	extractSpoolPrefix = ".extract"
+)
+
+// Archive formats detected by their leading magic bytes.
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// extractedObject - object created from an archive entry.
+type extractedObject struct {
+	Name    string
+	Size    int64
+	MD5Sum  string
+	ModTime time.Time
+	// Sequence number of the mutation creating the object.
+	Sequence uint64
+}
+
+// archiveEntry - regular file entry of an archive.
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	open    func() (io.ReadCloser, error)
+}
+
+// archiveEntryObject - returns object name of archive entry under
+// prefix, entries which are not regular files are skipped and entry
+// names escaping prefix are invalid.
+func archiveEntryObject(prefix, name string, regular bool) (object string, skip bool, ok bool) {
+	if !regular {
+		return "", true, true
+	}
+	// Windows archivers may use backslashes.
+	name = strings.Replace(name, "\\", slashSeparator, -1)
+	if path.IsAbs(name) {
+		return "", false, false
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false, false
+	}
+	object = prefix + cleaned
+	if !IsValidObjectName(object) {
+		return "", false, false
+	}
+	return object, false, true
+}
+
+// walkTar - walks entries of tar stream, entry data is read from the
+// stream as it is walked.
+func walkTar(r io.Reader, fn func(entry archiveEntry) error) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, e := tarReader.Next()
+		if e == io.EOF {
+			return nil
+		}
+		if e != nil {
+			return InvalidArchive{Reason: e.Error()}
+		}
+		regular := header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA
+		entry := archiveEntry{
+			name:    header.Name,
+			size:    header.Size,
+			modTime: header.ModTime.UTC(),
+			open: func() (io.ReadCloser, error) {
+				return readCloser{tarReader}, nil
+			},
+		}
+		if !regular {
+			entry.open = nil
+		}
+		if e = fn(entry); e != nil {
+			return e
+		}
+	}
+}
+
+// walkZip - walks entries of zip archive.
+func walkZip(archive io.ReaderAt, size int64, fn func(entry archiveEntry) error) error {
+	zipReader, e := zip.NewReader(archive, size)
+	if e != nil {
+		return InvalidArchive{Reason: e.Error()}
+	}
+	for _, file := range zipReader.File {
+		entry := archiveEntry{
+			name:    file.Name,
+			size:    int64(file.UncompressedSize64),
+			modTime: file.ModTime().UTC(),
+			open:    file.Open,
+		}
+		if !file.Mode().IsRegular() {
+			entry.open = nil
+		}
+		if e = fn(entry); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// readCloser - wraps a reader owned by its archive, closing is a no-op.
+type readCloser struct {
+	io.Reader
+}
+
+func (r readCloser) Close() error {
+	return nil
+}
+
+// checkArchiveEntry - returns object name of entry under prefix, empty
+// for skipped entries. Entries escaping prefix, too large or not allowed
+// are refused.
+func checkArchiveEntry(bucket, prefix string, entry archiveEntry, allowed func(object string) bool) (string, error) {
+	object, skip, ok := archiveEntryObject(prefix, entry.name, entry.open != nil)
+	if !ok {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: entry.name}
+	}
+	if skip {
+		return "", nil
+	}
+	if isMaxObjectSize(entry.size) {
+		return "", ObjectTooLarge{Bucket: bucket, Object: entry.name}
+	}
+	if !allowed(object) {
+		return "", ArchiveEntryDenied{Bucket: bucket, Object: object}
+	}
+	return object, nil
+}
+
+// extractArchiveEntry - creates object from entry.
+func (o objectAPI) extractArchiveEntry(bucket, object string, entry archiveEntry) (extractedObject, error) {
+	r, e := entry.open()
+	if e != nil {
+		return extractedObject{}, InvalidArchive{Reason: e.Error()}
+	}
+	defer r.Close()
+	metadata := map[string]string{
+		extractMtimeMetadataKey: entry.modTime.Format(time.RFC3339Nano),
+	}
+	var sequence uint64
+	md5Sum, err := o.WithSequence(&sequence).PutObject(bucket, object, entry.size, r, metadata)
+	if err != nil {
+		return extractedObject{}, err.ToGoError()
+	}
+	return extractedObject{
+		Name:     object,
+		Size:     entry.size,
+		MD5Sum:   md5Sum,
+		ModTime:  entry.modTime,
+		Sequence: sequence,
+	}, nil
+}
+
+// ExtractArchive - creates an object under prefix for every regular
+// file of archive, saving entry modification times as metadata. Objects
+// are only created for entries allowed reports true for. Tar and gzip
+// compressed tar archives are extracted as they are read, zip archives
+// are read from their end and are spooled into the meta volume first,
+// all their entries are validated before any object is created.
+// Objects extracted before a failure are kept.
+func (o objectAPI) ExtractArchive(bucket, prefix string, archive io.Reader, allowed func(object string) bool) ([]extractedObject, *probe.Error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return nil, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: prefix})
+	}
+	if _, e := o.storage.StatVol(bucket); e != nil {
+		return nil, probe.NewError(toObjectErr(e, bucket))
+	}
+
+	reader := bufio.NewReader(archive)
+	magic, e := reader.Peek(len(zipMagic))
+	if e != nil && e != io.EOF {
+		return nil, probe.NewError(e)
+	}
+	if bytes.HasPrefix(magic, zipMagic) {
+		return o.extractZip(bucket, prefix, reader, allowed)
+	}
+	var r io.Reader = reader
+	if bytes.HasPrefix(magic, gzipMagic) {
+		gzipReader, e := gzip.NewReader(r)
+		if e != nil {
+			return nil, probe.NewError(InvalidArchive{Reason: e.Error()})
+		}
+		defer gzipReader.Close()
+		r = gzipReader
+	}
+
+	var objects []extractedObject
+	entries := 0
+	e = walkTar(r, func(entry archiveEntry) error {
+		object, e := checkArchiveEntry(bucket, prefix, entry, allowed)
+		if e != nil || object == "" {
+			return e
+		}
+		if entries++; entries > maxExtractEntries {
+			return InvalidArchive{Reason: "too many entries"}
+		}
+		extracted, e := o.extractArchiveEntry(bucket, object, entry)
+		if e != nil {
+			return e
+		}
+		objects = append(objects, extracted)
+		return nil
+	})
+	if e != nil {
+		return objects, probe.NewError(e)
+	}
+	return objects, nil
+}
+
+// extractZip - extracts zip archive, spooled into the meta volume.
+func (o objectAPI) extractZip(bucket, prefix string, archive io.Reader, allowed func(object string) bool) ([]extractedObject, *probe.Error) {
+	uid, e := uuid.New()
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	spoolPath := path.Join(extractSpoolPrefix, uid.String())
+	w, e := o.storage.CreateFile(o.context(), minioMetaVolume, spoolPath)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	size, e := io.Copy(w, archive)
+	if e != nil {
+		safeCloseAndRemove(w)
+		return nil, probe.NewError(e)
+	}
+	if e = w.Close(); e != nil {
+		return nil, probe.NewError(e)
+	}
+	defer o.storage.DeleteFile(context.Background(), minioMetaVolume, spoolPath)
+	spool := &storageReaderAt{ctx: o.context(), storage: o.storage, volume: minioMetaVolume, path: spoolPath}
+	defer spool.Close()
+
+	// Validate entries first.
+	entries := 0
+	e = walkZip(spool, size, func(entry archiveEntry) error {
+		object, e := checkArchiveEntry(bucket, prefix, entry, allowed)
+		if e != nil || object == "" {
+			return e
+		}
+		if entries++; entries > maxExtractEntries {
+			return InvalidArchive{Reason: "too many entries"}
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	var objects []extractedObject
+	e = walkZip(spool, size, func(entry archiveEntry) error {
+		object, skip, _ := archiveEntryObject(prefix, entry.name, entry.open != nil)
+		if skip {
+			return nil
+		}
+		extracted, e := o.extractArchiveEntry(bucket, object, entry)
+		if e != nil {
+			return e
+		}
+		objects = append(objects, extracted)
+		return nil
+	})
+	if e != nil {
+		return objects, probe.NewError(e)
+	}
+	return objects, nil
+}
+
+// storageReaderAt - reads a file of storage at offsets, a read continuing
+// where the last one ended reuses its reader. Not safe for concurrent
+// use.
+type storageReaderAt struct {
+	ctx     context.Context
+	storage StorageAPI
+	volume  string
+	path    string
+	reader  io.ReadCloser
+	offset  int64
+}
+
+func (s *storageReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if s.reader == nil || offset != s.offset {
+		s.Close()
+		reader, e := s.storage.ReadFile(s.ctx, s.volume, s.path, offset)
+		if e != nil {
+			return 0, e
+		}
+		s.reader, s.offset = reader, offset
+	}
+	n, e := io.ReadFull(s.reader, p)
+	s.offset += int64(n)
+	if e == io.ErrUnexpectedEOF {
+		e = io.EOF
+	}
+	return n, e
+}
+
+// Close - closes the reader of the last read.
+func (s *storageReaderAt) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+	e := s.reader.Close()
+	s.reader = nil
+	return e
+}
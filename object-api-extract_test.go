@@ -0,0 +1,244 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// testArchiveFile - archive entry used by tests.
+type testArchiveFile struct {
+	name string
+	data string
+	dir  bool
+}
+
+// newTestTar - returns a tar archive of files.
+func newTestTar(t *testing.T, files []testArchiveFile, modTime time.Time) []byte {
+	buffer := new(bytes.Buffer)
+	tarWriter := tar.NewWriter(buffer)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), ModTime: modTime, Typeflag: tar.TypeReg}
+		if file.dir {
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		if e := tarWriter.WriteHeader(header); e != nil {
+			t.Fatal(e)
+		}
+		if _, e := tarWriter.Write([]byte(file.data)); e != nil && !file.dir {
+			t.Fatal(e)
+		}
+	}
+	if e := tarWriter.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return buffer.Bytes()
+}
+
+// newTestZip - returns a zip archive of files.
+func newTestZip(t *testing.T, files []testArchiveFile, modTime time.Time) []byte {
+	buffer := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buffer)
+	for _, file := range files {
+		header := &zip.FileHeader{Name: file.name, Method: zip.Deflate}
+		header.SetModTime(modTime)
+		if file.dir {
+			header.SetMode(os.ModeDir | 0755)
+		}
+		w, e := zipWriter.CreateHeader(header)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write([]byte(file.data)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e := zipWriter.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return buffer.Bytes()
+}
+
+// gzipBytes - returns gzip compressed data.
+func gzipBytes(t *testing.T, data []byte) []byte {
+	buffer := new(bytes.Buffer)
+	gzipWriter := gzip.NewWriter(buffer)
+	if _, e := gzipWriter.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e := gzipWriter.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return buffer.Bytes()
+}
+
+// Tests entry name validation.
+func TestArchiveEntryObject(t *testing.T) {
+	testCases := []struct {
+		prefix  string
+		name    string
+		regular bool
+		object  string
+		skip    bool
+		ok      bool
+	}{
+		{"photos/", "a.jpg", true, "photos/a.jpg", false, true},
+		{"", "./dir/a.jpg", true, "dir/a.jpg", false, true},
+		{"", "dir\\a.jpg", true, "dir/a.jpg", false, true},
+		{"photos/", "dir/", false, "", true, true},
+		{"photos/", "../a.jpg", true, "", false, false},
+		{"photos/", "dir/../../a.jpg", true, "", false, false},
+		{"photos/", "/etc/passwd", true, "", false, false},
+		{"photos/", ".", true, "", false, false},
+		{"photos/", "a*.jpg", true, "photos/a*.jpg", false, true},
+		{"photos/", "a\x00.jpg", true, "", false, false},
+	}
+	for i, testCase := range testCases {
+		object, skip, ok := archiveEntryObject(testCase.prefix, testCase.name, testCase.regular)
+		if object != testCase.object || skip != testCase.skip || ok != testCase.ok {
+			t.Errorf("Test %d: Expected (%q, %v, %v), got (%q, %v, %v)", i+1,
+				testCase.object, testCase.skip, testCase.ok, object, skip, ok)
+		}
+	}
+}
+
+// allowAll - allows all archive entries.
+func allowAll(object string) bool {
+	return true
+}
+
+// Tests extracting tar, gzip compressed tar and zip archives.
+func TestExtractArchive(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-extract-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Date(2015, time.March, 10, 12, 30, 0, 0, time.UTC)
+	files := []testArchiveFile{
+		{name: "docs/", dir: true},
+		{name: "docs/readme.txt", data: "read me"},
+		{name: "index.html", data: "<html></html>"},
+	}
+	tarArchive := newTestTar(t, files, modTime)
+	archives := map[string][]byte{
+		"tar/":    tarArchive,
+		"tar.gz/": gzipBytes(t, tarArchive),
+		"zip/":    newTestZip(t, files, modTime),
+	}
+	for prefix, archive := range archives {
+		objects, err := obj.ExtractArchive("bucket", prefix, bytes.NewReader(archive), allowAll)
+		if err != nil {
+			t.Fatalf("%s: %s", prefix, err)
+		}
+		if len(objects) != 2 {
+			t.Fatalf("%s: Expected 2 objects, got %d", prefix, len(objects))
+		}
+		for _, file := range files[1:] {
+			objInfo, err := obj.GetObjectInfo("bucket", prefix+file.name)
+			if err != nil {
+				t.Fatalf("%s: %s", prefix, err)
+			}
+			if objInfo.Size != int64(len(file.data)) {
+				t.Errorf("%s: Expected size %d, got %d", prefix+file.name, len(file.data), objInfo.Size)
+			}
+			mtime, e := time.Parse(time.RFC3339Nano, objInfo.UserDefined[extractMtimeMetadataKey])
+			if e != nil || !mtime.Equal(modTime) {
+				t.Errorf("%s: Expected mtime %s, got %q", prefix+file.name, modTime, objInfo.UserDefined[extractMtimeMetadataKey])
+			}
+			r, err := obj.GetObject("bucket", prefix+file.name, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, e := ioutil.ReadAll(r)
+			r.Close()
+			if e != nil || string(data) != file.data {
+				t.Errorf("%s: Expected data %q, got %q", prefix+file.name, file.data, data)
+			}
+		}
+	}
+
+	// Entries escaping the prefix fail zip archives before any object
+	// is created, tar archives keep the objects extracted before.
+	unsafeFiles := []testArchiveFile{{name: "ok.txt", data: "ok"}, {name: "../escape.txt", data: "bad"}}
+	unsafeArchives := map[string]struct {
+		archive []byte
+		kept    bool
+	}{
+		"unsafe-zip/": {newTestZip(t, unsafeFiles, modTime), false},
+		"unsafe-tar/": {newTestTar(t, unsafeFiles, modTime), true},
+	}
+	for prefix, unsafe := range unsafeArchives {
+		_, err := obj.ExtractArchive("bucket", prefix, bytes.NewReader(unsafe.archive), allowAll)
+		if err == nil {
+			t.Fatalf("%s: Expected error for entry escaping prefix", prefix)
+		}
+		if _, ok := err.ToGoError().(ObjectNameInvalid); !ok {
+			t.Fatalf("%s: Expected ObjectNameInvalid, got %s", prefix, err)
+		}
+		if _, err = obj.GetObjectInfo("bucket", prefix+"ok.txt"); (err == nil) != unsafe.kept {
+			t.Fatalf("%s: Expected ok.txt kept %v, got %v", prefix, unsafe.kept, err)
+		}
+	}
+
+	// Entries not allowed are refused.
+	denied := func(object string) bool {
+		return object != "denied/index.html"
+	}
+	archive := newTestZip(t, files, modTime)
+	_, err := obj.ExtractArchive("bucket", "denied/", bytes.NewReader(archive), denied)
+	if err == nil {
+		t.Fatal("Expected error for denied entry")
+	}
+	if _, ok := err.ToGoError().(ArchiveEntryDenied); !ok {
+		t.Fatalf("Expected ArchiveEntryDenied, got %s", err)
+	}
+	if _, err = obj.GetObjectInfo("bucket", "denied/docs/readme.txt"); err == nil {
+		t.Fatal("Expected no objects extracted from denied archive")
+	}
+
+	// Spooled zip archives are removed.
+	if fileInfos, _, e := fs.ListFiles(minioMetaVolume, extractSpoolPrefix+slashSeparator, "", true, 10); e == nil && len(fileInfos) > 0 {
+		t.Fatalf("Expected no spooled archives, got %v", fileInfos)
+	}
+
+	// Garbage is not an archive.
+	archive = []byte("not an archive at all")
+	if _, err = obj.ExtractArchive("bucket", "", bytes.NewReader(archive), allowAll); err == nil {
+		t.Fatal("Expected error for invalid archive")
+	} else if _, ok := err.ToGoError().(InvalidArchive); !ok {
+		t.Fatalf("Expected InvalidArchive, got %s", err)
+	}
+}
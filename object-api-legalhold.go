@@ -0,0 +1,102 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Maximum size of legal hold document.
+	maxLegalHoldSize = 1024
+
+	// Legal hold statuses.
+	legalHoldOn  = "ON"
+	legalHoldOff = "OFF"
+)
+
+// objectLegalHold - legal hold document of PUT and GET Object
+// legal-hold.
+type objectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string   `xml:"Status"`
+}
+
+// isValidLegalHoldStatus - returns true for ON and OFF.
+func isValidLegalHoldStatus(status string) bool {
+	return status == legalHoldOn || status == legalHoldOff
+}
+
+// setObjectLegalHold - places or removes legal hold in metadata of
+// object.
+func setObjectLegalHold(metadata map[string]string, held bool) {
+	if held {
+		metadata[objectLegalHoldKey] = legalHoldOn
+	} else {
+		delete(metadata, objectLegalHoldKey)
+	}
+}
+
+// GetObjectLegalHold - returns legal hold status of an object.
+func (o objectAPI) GetObjectLegalHold(bucket, object string) (string, *probe.Error) {
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return "", err.Trace(bucket, object)
+	}
+	if _, enabled, err := getBucketObjectLock(bucket); err != nil {
+		return "", err.Trace(bucket)
+	} else if !enabled {
+		return "", probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+	}
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
+	if metadata[objectLegalHoldKey] == legalHoldOn {
+		return legalHoldOn, nil
+	}
+	return legalHoldOff, nil
+}
+
+// PutObjectLegalHold - places or removes legal hold of an object in a
+// bucket with object lock enabled. Held objects cannot be deleted or
+// overwritten regardless of their retention.
+func (o objectAPI) PutObjectLegalHold(bucket, object, status string) *probe.Error {
+	lock := o.sequences.lock(bucket, object)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return err.Trace(bucket, object)
+	}
+	if _, enabled, err := getBucketObjectLock(bucket); err != nil {
+		return err.Trace(bucket)
+	} else if !enabled {
+		return probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+	}
+	if !isValidLegalHoldStatus(status) {
+		return probe.NewError(InvalidLegalHold{Status: status})
+	}
+	e := o.updateObjectMetadata(bucket, object, func(metadata map[string]string) error {
+		setObjectLegalHold(metadata, status == legalHoldOn)
+		return nil
+	})
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return nil
+}
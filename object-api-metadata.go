@@ -0,0 +1,262 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Metadata of objects is saved under this prefix in
+	// minioMetaVolume, by storage which does not keep metadata with
+	// files. Bucket names cannot start with a '.'.
+	objectMetadataPrefix = ".metadata"
+
+	// Prefix of user defined metadata keys.
+	userMetadataKeyPrefix = "X-Amz-Meta-"
+
+	// Maximum size of saved object metadata.
+	maxObjectMetadataSize = 64 * 1024
+)
+
+// The state of an object is saved in its metadata along with its user
+// defined metadata, in a single document read once per object. Keys
+// of the state never collide with user defined keys, which are
+// canonical header keys.
+const (
+	// Key the md5sum of objects is saved under.
+	objectMD5SumKey = "md5Sum"
+	// Key the sequence number of the last mutation is saved under.
+	objectSequenceKey = "sequence"
+	// Key the stub of transitioned objects is saved under.
+	objectTierStubKey = "tierStub"
+	// Keys the retention and legal hold of objects are saved under.
+	objectRetentionKey = "retention"
+	objectLegalHoldKey = "legalHold"
+)
+
+// objectMetadataPath - returns object metadata path in
+// minioMetaVolume.
+func objectMetadataPath(bucket, object string) string {
+	return path.Join(objectMetadataPrefix, bucket, object)
+}
+
+// Standard headers saved along with user defined metadata, and
+// returned with objects.
+var objectStandardMetadataKeys = []string{
+	"Content-Type",
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	amzStorageClassHeader,
+}
+
+// isUserMetadataKey - returns true if canonical key is saved along
+// with objects, additional checksums are saved once verified.
+func isUserMetadataKey(key string) bool {
+	return strings.HasPrefix(key, userMetadataKeyPrefix) || contains(objectStandardMetadataKeys, key) || isChecksumMetadataKey(key)
+}
+
+// filterUserMetadata - returns user defined entries and standard
+// headers of metadata with canonical keys.
+func filterUserMetadata(metadata map[string]string) map[string]string {
+	userMetadata := make(map[string]string)
+	for key, value := range metadata {
+		key = http.CanonicalHeaderKey(key)
+		if isUserMetadataKey(key) {
+			userMetadata[key] = value
+		}
+	}
+	return userMetadata
+}
+
+// readObjectMetadata - reads metadata of object, along with its state.
+// Storage which keeps metadata reports it with the file, objects
+// written before keep it apart from their file.
+func (o objectAPI) readObjectMetadata(bucket, object string) (map[string]string, error) {
+	if _, ok := o.storage.(metadataKeeper); ok {
+		fi, e := o.storage.StatFile(bucket, object)
+		if e != nil {
+			return nil, e
+		}
+		if len(fi.Metadata) != 0 {
+			return fi.Metadata, nil
+		}
+	}
+	metadataBytes, e := o.readMetaFile(objectMetadataPath(bucket, object), maxObjectMetadataSize)
+	if e != nil {
+		return nil, e
+	}
+	metadata := make(map[string]string)
+	if e = json.Unmarshal(metadataBytes, &metadata); e != nil {
+		return nil, e
+	}
+	return metadata, nil
+}
+
+// lookupObjectMetadata - reads metadata of object, empty if none was
+// saved.
+func (o objectAPI) lookupObjectMetadata(bucket, object string) (map[string]string, error) {
+	metadata, e := o.readObjectMetadata(bucket, object)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return nil, nil
+		}
+		return nil, e
+	}
+	return metadata, nil
+}
+
+// statObjectMetadata - returns metadata of object, empty if none was
+// saved. Failures to read it are logged.
+func (o objectAPI) statObjectMetadata(bucket, object string) map[string]string {
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to read object metadata.", nil)
+	}
+	return metadata
+}
+
+// fileObjectMetadata - returns metadata of object as kept with its
+// file fi, read apart from it otherwise.
+func (o objectAPI) fileObjectMetadata(bucket, object string, fi FileInfo) map[string]string {
+	if len(fi.Metadata) != 0 {
+		return fi.Metadata
+	}
+	return o.statObjectMetadata(bucket, object)
+}
+
+// writeObjectMetadata - replaces metadata of object, kept with its file
+// by storage which keeps metadata, saved apart from it otherwise.
+func (o objectAPI) writeObjectMetadata(bucket, object string, metadata map[string]string) error {
+	if keeper, ok := o.storage.(metadataKeeper); ok {
+		if e := keeper.SetFileMetadata(bucket, object, metadata); e != nil {
+			return e
+		}
+		// Metadata saved apart from the object before is stale.
+		return o.removeObjectMetadata(bucket, object)
+	}
+	metadataBytes, e := json.Marshal(metadata)
+	if e != nil {
+		return e
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(objectMetadataPath(bucket, object), metadataBytes)
+}
+
+// updateObjectMetadata - applies update to metadata of object, and
+// saves it. Mutations of the object are serialized by the sequencer
+// lock of the key, which callers hold.
+func (o objectAPI) updateObjectMetadata(bucket, object string, update func(metadata map[string]string) error) error {
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		return e
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	if e = update(metadata); e != nil {
+		return e
+	}
+	return o.writeObjectMetadata(bucket, object, metadata)
+}
+
+// userObjectMetadata - returns user defined metadata, along with
+// standard headers, and md5sum of object metadata. The state of the
+// object is left out.
+func userObjectMetadata(metadata map[string]string) (map[string]string, string) {
+	var userMetadata map[string]string
+	for key, value := range metadata {
+		if !isUserMetadataKey(key) {
+			continue
+		}
+		if userMetadata == nil {
+			userMetadata = make(map[string]string)
+		}
+		userMetadata[key] = value
+	}
+	return userMetadata, metadata[objectMD5SumKey]
+}
+
+// getObjectMetadata - returns user defined metadata, along with
+// standard headers, and md5sum of object, empty if none was saved.
+func (o objectAPI) getObjectMetadata(bucket, object string) (map[string]string, string) {
+	return userObjectMetadata(o.statObjectMetadata(bucket, object))
+}
+
+// newObjectMetadata - returns metadata of a newly written object, its
+// user defined metadata, md5sum, object lock and the sequence number of
+// the write. Stale metadata, tags and stub are not carried over.
+func newObjectMetadata(md5Hex string, userMetadata map[string]string, lock objectLock, sequence uint64) (map[string]string, error) {
+	metadata := make(map[string]string, len(userMetadata)+4)
+	for key, value := range userMetadata {
+		metadata[key] = value
+	}
+	if md5Hex != "" {
+		metadata[objectMD5SumKey] = md5Hex
+	}
+	if e := setObjectLock(metadata, lock); e != nil {
+		return nil, e
+	}
+	setObjectSequence(metadata, sequence)
+	return metadata, nil
+}
+
+// commitObject - commits the file of object written by w along with
+// its metadata. Storage which keeps metadata commits both at once,
+// metadata is saved apart once the file is committed otherwise.
+func (o objectAPI) commitObject(bucket, object string, w io.WriteCloser, metadata map[string]string) error {
+	if committer, ok := w.(metadataCommitter); ok {
+		committer.SetMetadata(metadata)
+		return w.Close()
+	}
+	if e := w.Close(); e != nil {
+		return e
+	}
+	return o.saveObjectMetadata(bucket, object, metadata)
+}
+
+// saveObjectMetadata - saves metadata of a committed object. The object
+// is replaced once committed, failing to save its metadata does not
+// fail the write, it is logged and errSequenceNotSaved is returned.
+func (o objectAPI) saveObjectMetadata(bucket, object string, metadata map[string]string) error {
+	if e := o.writeObjectMetadata(bucket, object, metadata); e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to save object metadata.", nil)
+		return errSequenceNotSaved
+	}
+	return nil
+}
+
+// removeObjectMetadata - removes metadata of object saved apart from
+// its file, once it is deleted or its metadata is kept with it.
+func (o objectAPI) removeObjectMetadata(bucket, object string) error {
+	e := o.storage.DeleteFile(context.Background(), minioMetaVolume, objectMetadataPath(bucket, object))
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		return e
+	}
+	return nil
+}
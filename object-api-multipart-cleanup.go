@@ -0,0 +1,304 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Default age of incomplete multipart uploads which are removed.
+	defaultStaleUploadExpiry = 7 * 24 * time.Hour
+
+	// Default interval between two removals of stale uploads.
+	defaultStaleUploadCleanupInterval = time.Hour
+
+	// Cleanups requested by rejected uploads are not started again
+	// until this long after the last one ended.
+	minStaleUploadCleanupGap = time.Minute
+
+	// Maximum number of incomplete uploads listed by the admin API.
+	maxIncompleteUploadsList = 1000
+)
+
+// expiry - returns age of stale uploads, defaults if not configured.
+func (c multipartConfig) expiry() time.Duration {
+	if c.StaleUploadExpiry <= 0 {
+		return defaultStaleUploadExpiry
+	}
+	return time.Duration(c.StaleUploadExpiry) * time.Second
+}
+
+// cleanupInterval - returns interval between two removals of stale
+// uploads, defaults if not configured.
+func (c multipartConfig) cleanupInterval() time.Duration {
+	if c.StaleUploadCleanupInterval <= 0 {
+		return defaultStaleUploadCleanupInterval
+	}
+	return time.Duration(c.StaleUploadCleanupInterval) * time.Second
+}
+
+// incompleteUpload - multipart upload in progress, reported by the
+// admin API.
+type incompleteUpload struct {
+	Bucket    string    `json:"bucket"`
+	Object    string    `json:"object"`
+	UploadID  string    `json:"uploadId"`
+	Initiator string    `json:"initiator,omitempty"`
+	Initiated time.Time `json:"initiated"`
+	// Time the upload is removed at unless it completes.
+	Expires time.Time `json:"expires"`
+}
+
+// multipartCleanupInfo - state of removals of stale uploads.
+type multipartCleanupInfo struct {
+	Running     bool      `json:"running"`
+	LastCleanup time.Time `json:"lastCleanup"`
+	// Uploads removed by the last cleanup, and since the server
+	// started.
+	LastRemoved int64  `json:"lastRemoved"`
+	Removed     int64  `json:"removed"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+// incompleteUploadsInfo - response of the admin incomplete uploads
+// API.
+type incompleteUploadsInfo struct {
+	Cleanup   multipartCleanupInfo `json:"cleanup"`
+	Uploads   []incompleteUpload   `json:"uploads"`
+	Truncated bool                 `json:"truncated"`
+}
+
+// multipartCleaner - periodically aborts multipart uploads initiated
+// longer ago than the configured expiry, so that abandoned uploads stop
+// taking space. Uploads rejected for exceeding their limits request a
+// cleanup right away.
+type multipartCleaner struct {
+	mutex     *sync.Mutex
+	objAPI    *objectAPI
+	info      multipartCleanupInfo
+	triggerCh chan struct{}
+}
+
+// Global multipart cleaner, driven by rejected uploads and the admin
+// API.
+var globalMultipartCleaner = newMultipartCleaner()
+
+func newMultipartCleaner() *multipartCleaner {
+	return &multipartCleaner{
+		mutex:     &sync.Mutex{},
+		triggerCh: make(chan struct{}, 1),
+	}
+}
+
+// initMultipartCleaner - starts removing stale uploads of objAPI in
+// background.
+func initMultipartCleaner(objAPI objectAPI) {
+	globalMultipartCleaner.mutex.Lock()
+	globalMultipartCleaner.objAPI = &objAPI
+	globalMultipartCleaner.mutex.Unlock()
+	go globalMultipartCleaner.run()
+}
+
+// Info - returns state of removals of stale uploads.
+func (c *multipartCleaner) Info() multipartCleanupInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.info
+}
+
+// Trigger - requests a cleanup now, unless one is running already or
+// the last one ended moments ago.
+func (c *multipartCleaner) Trigger() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.info.Running || time.Since(c.info.LastCleanup) < minStaleUploadCleanupGap {
+		return
+	}
+	c.start()
+}
+
+// Start - requests a cleanup now, unless one is running already.
+func (c *multipartCleaner) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.info.Running {
+		return
+	}
+	c.start()
+}
+
+// start - requests a cleanup, callers hold the mutex.
+func (c *multipartCleaner) start() {
+	c.info.Running = true
+	select {
+	case c.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// run - removes stale uploads once the last cleanup is older than the
+// configured interval, or a cleanup is requested.
+func (c *multipartCleaner) run() {
+	for {
+		wait := serverConfig.GetMultipart().cleanupInterval() - time.Since(c.Info().LastCleanup)
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-c.triggerCh:
+				timer.Stop()
+			}
+		}
+		c.cleanup(serverConfig.GetMultipart().expiry())
+	}
+}
+
+// cleanup - aborts all uploads initiated longer than expiry ago.
+func (c *multipartCleaner) cleanup(expiry time.Duration) {
+	c.mutex.Lock()
+	c.info.Running = true
+	objAPI := c.objAPI
+	c.mutex.Unlock()
+	if objAPI == nil {
+		return
+	}
+
+	removed, err := removeStaleUploads(*objAPI, expiry)
+	errorIf(err.Trace(), "Unable to remove stale multipart uploads.", nil)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.info.Running = false
+	c.info.LastCleanup = time.Now().UTC()
+	c.info.LastRemoved = removed
+	c.info.Removed += removed
+	c.info.LastError = ""
+	if err != nil {
+		c.info.LastError = err.ToGoError().Error()
+	}
+}
+
+// removeStaleUploads - aborts all uploads of o initiated longer than
+// expiry ago, returns the number of uploads removed.
+func removeStaleUploads(o objectAPI, expiry time.Duration) (int64, *probe.Error) {
+	var removed int64
+	err := walkIncompleteUploads(o, "", "", func(upload incompleteUpload) bool {
+		if time.Since(upload.Initiated) < expiry {
+			return true
+		}
+		err := o.AbortMultipartUpload(upload.Bucket, upload.Object, upload.UploadID)
+		if err != nil {
+			// Completed or aborted meanwhile.
+			if _, ok := err.ToGoError().(InvalidUploadID); ok {
+				return true
+			}
+			errorIf(err.Trace(upload.Bucket, upload.Object, upload.UploadID), "Unable to remove stale multipart upload.", nil)
+			return true
+		}
+		log.WithFields(logrus.Fields{
+			"bucket":    upload.Bucket,
+			"object":    upload.Object,
+			"uploadID":  upload.UploadID,
+			"initiated": upload.Initiated,
+		}).Debug("Removed stale multipart upload.")
+		removed++
+		return true
+	})
+	return removed, err
+}
+
+// walkIncompleteUploads - calls fn with all uploads of bucket under
+// prefix, of all buckets if bucket is empty, until fn returns false.
+func walkIncompleteUploads(o objectAPI, bucket, prefix string, fn func(upload incompleteUpload) bool) *probe.Error {
+	buckets := []string{bucket}
+	if bucket != "" {
+		if !IsValidBucketName(bucket) {
+			return probe.NewError(BucketNameInvalid{Bucket: bucket})
+		}
+		isExist, e := o.isBucketExist(bucket)
+		if e != nil {
+			return probe.NewError(e)
+		}
+		if !isExist {
+			return probe.NewError(BucketNotFound{Bucket: bucket})
+		}
+	} else {
+		bucketsInfo, err := o.ListBuckets()
+		if err != nil {
+			return err.Trace()
+		}
+		buckets = nil
+		for _, bucketInfo := range bucketsInfo {
+			buckets = append(buckets, bucketInfo.Name)
+		}
+	}
+	for _, bucket := range buckets {
+		keyMarker, uploadIDMarker := "", ""
+		for {
+			result, err := o.ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, "", purgeListBatchSize)
+			if err != nil {
+				return err.Trace(bucket, keyMarker, uploadIDMarker)
+			}
+			for _, upload := range result.Uploads {
+				if !fn(incompleteUpload{
+					Bucket:    bucket,
+					Object:    upload.Object,
+					UploadID:  upload.UploadID,
+					Initiator: upload.Initiator,
+					Initiated: upload.Initiated,
+				}) {
+					return nil
+				}
+			}
+			if !result.IsTruncated || len(result.Uploads) == 0 {
+				break
+			}
+			keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+		}
+	}
+	return nil
+}
+
+// ListIncompleteUploads - returns uploads of bucket under prefix, of
+// all buckets if bucket is empty, initiated at least olderThan ago.
+// Truncated is true if there are more than maxIncompleteUploadsList.
+func (o objectAPI) ListIncompleteUploads(bucket, prefix string, olderThan time.Duration) (uploads []incompleteUpload, truncated bool, err *probe.Error) {
+	o.multiparts.mutex.Lock()
+	expiry := o.multiparts.limits.expiry()
+	o.multiparts.mutex.Unlock()
+	uploads = []incompleteUpload{}
+	err = walkIncompleteUploads(o, bucket, prefix, func(upload incompleteUpload) bool {
+		if time.Since(upload.Initiated) < olderThan {
+			return true
+		}
+		if len(uploads) == maxIncompleteUploadsList {
+			truncated = true
+			return false
+		}
+		upload.Expires = upload.Initiated.Add(expiry)
+		uploads = append(uploads, upload)
+		return true
+	})
+	if err != nil {
+		return nil, false, err.Trace(bucket, prefix)
+	}
+	return uploads, truncated, nil
+}
@@ -0,0 +1,207 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"path"
+	"sync"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Maximum size of the access key recorded in an upload id file.
+const maxUploadAccessKeySize = 1024
+
+// multipartConfig - limits of simultaneous in-progress multipart
+// uploads, zero is unlimited, and expiry of abandoned uploads.
+type multipartConfig struct {
+	MaxUploadsPerBucket    int `json:"maxUploadsPerBucket"`
+	MaxUploadsPerAccessKey int `json:"maxUploadsPerAccessKey"`
+	// Age in seconds of incomplete uploads which are removed, and
+	// interval in seconds between two removals.
+	StaleUploadExpiry          int64 `json:"staleUploadExpiry"`
+	StaleUploadCleanupInterval int64 `json:"staleUploadCleanupInterval"`
+}
+
+// Default multipart upload limits.
+func newMultipartConfig() multipartConfig {
+	return multipartConfig{
+		MaxUploadsPerBucket:    10000,
+		MaxUploadsPerAccessKey: 1000,
+	}
+}
+
+// multipartUsage - in-progress uploads and uploads rejected for
+// exceeding the limit, of a bucket or an access key.
+type multipartUsage struct {
+	InProgress int   `json:"inProgress"`
+	Rejected   int64 `json:"rejected"`
+}
+
+// multipartSessionsInfo - limits and usage of multipart uploads.
+type multipartSessionsInfo struct {
+	Limits     multipartConfig           `json:"limits"`
+	Buckets    map[string]multipartUsage `json:"buckets"`
+	AccessKeys map[string]multipartUsage `json:"accessKeys"`
+}
+
+// multipartSession - an in-progress multipart upload.
+type multipartSession struct {
+	bucket    string
+	accessKey string
+}
+
+// multipartSessions - counts in-progress multipart uploads per bucket
+// and per initiating access key.
+type multipartSessions struct {
+	mutex  *sync.Mutex
+	limits multipartConfig
+	// Uploads keyed by upload id.
+	uploads    map[string]multipartSession
+	buckets    map[string]*multipartUsage
+	accessKeys map[string]*multipartUsage
+}
+
+func newMultipartSessions() *multipartSessions {
+	return &multipartSessions{
+		mutex:      &sync.Mutex{},
+		uploads:    make(map[string]multipartSession),
+		buckets:    make(map[string]*multipartUsage),
+		accessKeys: make(map[string]*multipartUsage),
+	}
+}
+
+// usage - returns usage of key in usages, created if missing. Must be
+// called with mutex held.
+func (m *multipartSessions) usage(usages map[string]*multipartUsage, key string) *multipartUsage {
+	u, ok := usages[key]
+	if !ok {
+		u = &multipartUsage{}
+		usages[key] = u
+	}
+	return u
+}
+
+// add - registers upload of bucket initiated by accessKey, fails if
+// either the bucket or the access key reached its limit. Uploads from
+// before a restart are registered with check false.
+func (m *multipartSessions) add(uploadID, bucket, accessKey string, check bool) *probe.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.uploads[uploadID]; ok {
+		return nil
+	}
+	bucketUsage := m.usage(m.buckets, bucket)
+	if check && m.limits.MaxUploadsPerBucket > 0 && bucketUsage.InProgress >= m.limits.MaxUploadsPerBucket {
+		bucketUsage.Rejected++
+		return probe.NewError(TooManyMultipartUploads{Bucket: bucket, Limit: m.limits.MaxUploadsPerBucket})
+	}
+	if accessKey != "" {
+		keyUsage := m.usage(m.accessKeys, accessKey)
+		if check && m.limits.MaxUploadsPerAccessKey > 0 && keyUsage.InProgress >= m.limits.MaxUploadsPerAccessKey {
+			keyUsage.Rejected++
+			return probe.NewError(TooManyMultipartUploads{AccessKey: accessKey, Limit: m.limits.MaxUploadsPerAccessKey})
+		}
+		keyUsage.InProgress++
+	}
+	bucketUsage.InProgress++
+	m.uploads[uploadID] = multipartSession{bucket: bucket, accessKey: accessKey}
+	return nil
+}
+
+// remove - unregisters a completed or aborted upload.
+func (m *multipartSessions) remove(uploadID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	session, ok := m.uploads[uploadID]
+	if !ok {
+		return
+	}
+	delete(m.uploads, uploadID)
+	m.buckets[session.bucket].InProgress--
+	if session.accessKey != "" {
+		m.accessKeys[session.accessKey].InProgress--
+	}
+}
+
+// initiator - returns access key which initiated upload, empty if
+// unknown.
+func (m *multipartSessions) initiator(uploadID string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.uploads[uploadID].accessKey
+}
+
+// SetMultipartLimits - sets limits of in-progress multipart uploads,
+// uploads already in progress are kept.
+func (o objectAPI) SetMultipartLimits(limits multipartConfig) {
+	o.multiparts.mutex.Lock()
+	defer o.multiparts.mutex.Unlock()
+	o.multiparts.limits = limits
+}
+
+// MultipartSessionsInfo - returns limits and usage of multipart
+// uploads.
+func (o objectAPI) MultipartSessionsInfo() multipartSessionsInfo {
+	m := o.multiparts
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	info := multipartSessionsInfo{
+		Limits:     m.limits,
+		Buckets:    make(map[string]multipartUsage),
+		AccessKeys: make(map[string]multipartUsage),
+	}
+	for bucket, u := range m.buckets {
+		info.Buckets[bucket] = *u
+	}
+	for accessKey, u := range m.accessKeys {
+		info.AccessKeys[accessKey] = *u
+	}
+	return info
+}
+
+// LoadMultipartSessions - registers multipart uploads in progress
+// before a restart.
+func (o objectAPI) LoadMultipartSessions() *probe.Error {
+	buckets, err := o.ListBuckets()
+	if err != nil {
+		return err.Trace()
+	}
+	for _, bucket := range buckets {
+		keyMarker, uploadIDMarker := "", ""
+		for {
+			result, err := o.ListMultipartUploads(bucket.Name, "", keyMarker, uploadIDMarker, "", purgeListBatchSize)
+			if err != nil {
+				return err.Trace(bucket.Name, keyMarker, uploadIDMarker)
+			}
+			for _, upload := range result.Uploads {
+				// Uploads initiated by older servers record no
+				// access key and only count for the bucket.
+				accessKey, e := o.readMetaFile(path.Join(bucket.Name, upload.Object, upload.UploadID), maxUploadAccessKeySize)
+				if e != nil && errorCause(e) != errFileNotFound {
+					return probe.NewError(e).Trace(bucket.Name, upload.Object, upload.UploadID)
+				}
+				o.multiparts.add(upload.UploadID, bucket.Name, string(accessKey), false)
+			}
+			if !result.IsTruncated || len(result.Uploads) == 0 {
+				break
+			}
+			keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+		}
+	}
+	return nil
+}
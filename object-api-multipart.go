@@ -17,12 +17,14 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -194,16 +196,22 @@ func (o objectAPI) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarke
 			})
 		}
 		id, e := uuid.Parse(uploadIDMarker)
-		if e != nil {
-			return result, probe.NewError(e)
-		}
-		if id.IsZero() {
+		if e != nil || id.IsZero() {
 			return result, probe.NewError(MalformedUploadID{
 				UploadID: uploadIDMarker,
 			})
 		}
 	}
 
+	// Verify whether the bucket exists.
+	isExist, e := o.isBucketExist(bucket)
+	if e != nil {
+		return ListMultipartsInfo{}, probe.NewError(e)
+	}
+	if !isExist {
+		return ListMultipartsInfo{}, probe.NewError(BucketNotFound{Bucket: bucket})
+	}
+
 	recursive := true
 	if delimiter == slashSeparator {
 		recursive = false
@@ -211,6 +219,10 @@ func (o objectAPI) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarke
 
 	result.IsTruncated = true
 	result.MaxUploads = maxUploads
+	result.KeyMarker = keyMarker
+	result.UploadIDMarker = uploadIDMarker
+	result.Prefix = prefix
+	result.Delimiter = delimiter
 
 	// Not using path.Join() as it strips off the trailing '/'.
 	// Also bucket should always be followed by '/' even if prefix is empty.
@@ -243,11 +255,16 @@ func (o objectAPI) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarke
 		} else {
 			uploadID = path.Base(fi.Name)
 			objectName = strings.TrimPrefix(path.Dir(fi.Name), retainSlash(bucket))
-			result.Uploads = append(result.Uploads, uploadMetadata{
-				Object:    objectName,
-				UploadID:  uploadID,
-				Initiated: fi.ModTime,
-			})
+			// Without an upload id marker only uploads of keys after
+			// keyMarker are listed.
+			if uploadIDMarker != "" || objectName != keyMarker {
+				result.Uploads = append(result.Uploads, uploadMetadata{
+					Object:    objectName,
+					UploadID:  uploadID,
+					Initiator: o.multiparts.initiator(uploadID),
+					Initiated: fi.ModTime,
+				})
+			}
 		}
 		result.NextKeyMarker = objectName
 		result.NextUploadIDMarker = uploadID
@@ -260,7 +277,10 @@ func (o objectAPI) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarke
 	return result, nil
 }
 
-func (o objectAPI) NewMultipartUpload(bucket, object string) (string, *probe.Error) {
+// NewMultipartUpload - initiates a multipart upload on behalf of
+// accessKey, fails once too many uploads are in progress for the bucket
+// or the access key.
+func (o objectAPI) NewMultipartUpload(bucket, object, accessKey string) (string, *probe.Error) {
 	// Verify if bucket name is valid.
 	if !IsValidBucketName(bucket) {
 		return "", probe.NewError(BucketNameInvalid{Bucket: bucket})
@@ -279,10 +299,10 @@ func (o objectAPI) NewMultipartUpload(bucket, object string) (string, *probe.Err
 	}
 
 	if _, e := o.storage.StatVol(minioMetaVolume); e != nil {
-		if e == errVolumeNotFound {
+		if errorCause(e) == errVolumeNotFound {
 			e = o.storage.MakeVol(minioMetaVolume)
 			if e != nil {
-				if e == errDiskFull {
+				if errorCause(e) == errDiskFull {
 					return "", probe.NewError(StorageFull{})
 				}
 				return "", probe.NewError(e)
@@ -297,17 +317,30 @@ func (o objectAPI) NewMultipartUpload(bucket, object string) (string, *probe.Err
 		uploadID := uuid.String()
 		uploadIDPath := path.Join(bucket, object, uploadID)
 		if _, e = o.storage.StatFile(minioMetaVolume, uploadIDPath); e != nil {
-			if e != errFileNotFound {
+			if errorCause(e) != errFileNotFound {
 				return "", probe.NewError(toObjectErr(e, minioMetaVolume, uploadIDPath))
 			}
-			// uploadIDPath doesn't exist, so create empty file to reserve the name
+			if err := o.multiparts.add(uploadID, bucket, accessKey, true); err != nil {
+				// Make room by removing abandoned uploads.
+				globalMultipartCleaner.Trigger()
+				return "", err.Trace(bucket, accessKey)
+			}
+			// uploadIDPath doesn't exist, so create file recording the
+			// initiator to reserve the name
 			var w io.WriteCloser
-			if w, e = o.storage.CreateFile(minioMetaVolume, uploadIDPath); e == nil {
+			if w, e = o.storage.CreateFile(context.Background(), minioMetaVolume, uploadIDPath); e == nil {
+				if _, e = w.Write([]byte(accessKey)); e != nil {
+					safeCloseAndRemove(w)
+					o.multiparts.remove(uploadID)
+					return "", probe.NewError(toObjectErr(e, minioMetaVolume, uploadIDPath))
+				}
 				// Close the writer.
 				if e = w.Close(); e != nil {
+					o.multiparts.remove(uploadID)
 					return "", probe.NewError(e)
 				}
 			} else {
+				o.multiparts.remove(uploadID)
 				return "", probe.NewError(toObjectErr(e, minioMetaVolume, uploadIDPath))
 			}
 			return uploadID, nil
@@ -323,7 +356,7 @@ func (o objectAPI) isUploadIDExists(bucket, object, uploadID string) (bool, erro
 	st, e := o.storage.StatFile(minioMetaVolume, uploadIDPath)
 	if e != nil {
 		// Upload id does not exist.
-		if e == errFileNotFound {
+		if errorCause(e) == errFileNotFound {
 			return false, nil
 		}
 		return false, e
@@ -356,8 +389,34 @@ func (o objectAPI) PutObjectPart(bucket, object, uploadID string, partID int, si
 		return "", probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
 
-	partSuffix := fmt.Sprintf("%s.%d.%s", uploadID, partID, md5Hex)
-	fileWriter, e := o.storage.CreateFile(minioMetaVolume, path.Join(bucket, object, partSuffix))
+	if !isValidPartID(partID) {
+		return "", probe.NewError(InvalidPart{})
+	}
+	if isMaxPartSize(size) {
+		return "", probe.NewError(ObjectTooLarge{Bucket: bucket, Object: object})
+	}
+
+	// Parts which do not fit in the quota of their bucket are refused.
+	if err := globalBucketQuotas.Check(bucket, size); err != nil {
+		return "", err.Trace(bucket, object)
+	}
+	if e := o.checkFreeSpace(minioMetaVolume, size); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
+	counter := &quotaCountingReader{Reader: data}
+	data = counter
+
+	// Storage which stages parts next to the object keeps them there,
+	// only their record is written once they are uploaded.
+	stager, staged := o.storage.(partStager)
+	var fileWriter io.WriteCloser
+	var e error
+	if staged {
+		fileWriter, e = stager.StagePart(o.context(), bucket, object)
+	} else {
+		partSuffix := fmt.Sprintf("%s.%d.%s", uploadID, partID, md5Hex)
+		fileWriter, e = o.storage.CreateFile(o.context(), minioMetaVolume, path.Join(bucket, object, partSuffix))
+	}
 	if e != nil {
 		return "", probe.NewError(toObjectErr(e, bucket, object))
 	}
@@ -368,9 +427,17 @@ func (o objectAPI) PutObjectPart(bucket, object, uploadID string, partID int, si
 	// Instantiate a new multi writer.
 	multiWriter := io.MultiWriter(md5Writer, fileWriter)
 
+	// Keep received bytes in quarantine until they are verified.
+	quarantine := o.newQuarantineWriter()
+	if quarantine != nil {
+		data = io.TeeReader(data, quarantine)
+	}
+	quarantined := quarantineInfo{Bucket: bucket, Object: object, UploadID: uploadID, PartID: partID}
+
 	// Instantiate checksum hashers and create a multiwriter.
 	if size > 0 {
 		if _, e = io.CopyN(multiWriter, data, size); e != nil {
+			quarantine.settle(toObjectErr(e), quarantined)
 			safeCloseAndRemove(fileWriter)
 			return "", probe.NewError(toObjectErr(e))
 		}
@@ -378,11 +445,13 @@ func (o objectAPI) PutObjectPart(bucket, object, uploadID string, partID int, si
 		// reading one more byte from the reader to validate it.
 		// expected to fail, success validates existence of more data in the reader.
 		if _, e = io.CopyN(ioutil.Discard, data, 1); e == nil {
+			quarantine.abort()
 			safeCloseAndRemove(fileWriter)
 			return "", probe.NewError(UnExpectedDataSize{Size: int(size)})
 		}
 	} else {
 		if _, e = io.Copy(multiWriter, data); e != nil {
+			quarantine.settle(toObjectErr(e), quarantined)
 			safeCloseAndRemove(fileWriter)
 			return "", probe.NewError(toObjectErr(e))
 		}
@@ -391,17 +460,104 @@ func (o objectAPI) PutObjectPart(bucket, object, uploadID string, partID int, si
 	newMD5Hex := hex.EncodeToString(md5Writer.Sum(nil))
 	if md5Hex != "" {
 		if newMD5Hex != md5Hex {
+			quarantine.settle(BadDigest{md5Hex, newMD5Hex}, quarantined)
 			safeCloseAndRemove(fileWriter)
 			return "", probe.NewError(BadDigest{md5Hex, newMD5Hex})
 		}
 	}
+	quarantine.abort()
 	e = fileWriter.Close()
 	if e != nil {
 		return "", probe.NewError(e)
 	}
+	if staged {
+		token := fileWriter.(partWriter).Token()
+		if e = o.writeStagedPart(bucket, object, uploadID, partID, newMD5Hex, counter.n, token); e != nil {
+			stager.RemoveParts(context.Background(), bucket, object, []string{token})
+			return "", probe.NewError(toObjectErr(e, bucket, object))
+		}
+	}
+	globalDataUsage.AddWritten(bucket, counter.n)
 	return newMD5Hex, nil
 }
 
+// CopyObjectPart - writes length bytes of an existing object, starting
+// at startOffset, as a part of an upload. Source ranges which are not a
+// whole object with a known md5sum are read twice, first to compute
+// the md5sum naming the part.
+func (o objectAPI) CopyObjectPart(srcBucket, srcObject string, startOffset, length int64, bucket, object, uploadID string, partID int) (string, *probe.Error) {
+	objInfo, err := o.GetObjectInfo(srcBucket, srcObject)
+	if err != nil {
+		return "", err.Trace(srcBucket, srcObject)
+	}
+	if startOffset < 0 || length < 0 || startOffset+length > objInfo.Size {
+		return "", probe.NewError(InvalidRange{Start: startOffset, Length: length})
+	}
+	md5Hex := objInfo.MD5Sum
+	if startOffset != 0 || length != objInfo.Size || len(md5Hex) != hex.EncodedLen(md5.Size) {
+		reader, err := o.GetObject(srcBucket, srcObject, startOffset)
+		if err != nil {
+			return "", err.Trace(srcBucket, srcObject)
+		}
+		md5Writer := md5.New()
+		_, e := io.CopyN(md5Writer, reader, length)
+		reader.Close()
+		if e != nil {
+			return "", probe.NewError(toObjectErr(e, srcBucket, srcObject))
+		}
+		md5Hex = hex.EncodeToString(md5Writer.Sum(nil))
+	}
+	reader, err := o.GetObject(srcBucket, srcObject, startOffset)
+	if err != nil {
+		return "", err.Trace(srcBucket, srcObject)
+	}
+	defer reader.Close()
+	md5Hex, err = o.PutObjectPart(bucket, object, uploadID, partID, length, io.LimitReader(reader, length), md5Hex)
+	if err != nil {
+		return "", err.Trace(bucket, object, uploadID)
+	}
+	return md5Hex, nil
+}
+
+// stagedPartSuffix - returns name of the record of a part staged by
+// the storage, records are named with the size of the part as they
+// keep its token.
+func stagedPartSuffix(uploadID string, partID int, md5Hex string, size int64) string {
+	return fmt.Sprintf("%s.%d.%s.%d", uploadID, partID, md5Hex, size)
+}
+
+// writeStagedPart - writes the record of a part staged by the storage.
+func (o objectAPI) writeStagedPart(bucket, object, uploadID string, partID int, md5Hex string, size int64, token string) error {
+	recordPath := path.Join(bucket, object, stagedPartSuffix(uploadID, partID, md5Hex, size))
+	w, e := o.storage.CreateFile(context.Background(), minioMetaVolume, recordPath)
+	if e != nil {
+		return e
+	}
+	if _, e = w.Write([]byte(token)); e != nil {
+		safeCloseAndRemove(w)
+		return e
+	}
+	return w.Close()
+}
+
+// readStagedPart - returns token of a part staged by the storage from
+// its record.
+func (o objectAPI) readStagedPart(recordPath string) (string, error) {
+	r, e := o.storage.ReadFile(context.Background(), minioMetaVolume, recordPath, 0)
+	if e != nil {
+		return "", e
+	}
+	defer r.Close()
+	token, e := ioutil.ReadAll(r)
+	if e != nil {
+		return "", e
+	}
+	return string(token), nil
+}
+
+// ListObjectParts - lists parts of an upload in ascending part number
+// order, starting after partNumberMarker. Parts uploaded more than once
+// are listed as last uploaded.
 func (o objectAPI) ListObjectParts(bucket, object, uploadID string, partNumberMarker, maxParts int) (ListPartsInfo, *probe.Error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
@@ -410,57 +566,92 @@ func (o objectAPI) ListObjectParts(bucket, object, uploadID string, partNumberMa
 	if !IsValidObjectName(object) {
 		return ListPartsInfo{}, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
+	// Verify whether the bucket exists.
+	isExist, e := o.isBucketExist(bucket)
+	if e != nil {
+		return ListPartsInfo{}, probe.NewError(e)
+	}
+	if !isExist {
+		return ListPartsInfo{}, probe.NewError(BucketNotFound{Bucket: bucket})
+	}
 	if status, e := o.isUploadIDExists(bucket, object, uploadID); e != nil {
 		return ListPartsInfo{}, probe.NewError(e)
 	} else if !status {
 		return ListPartsInfo{}, probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
-	result := ListPartsInfo{}
+	parts, e := o.listUploadParts(bucket, object, uploadID)
+	if e != nil {
+		return ListPartsInfo{}, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	result := ListPartsInfo{
+		Bucket:           bucket,
+		Object:           object,
+		UploadID:         uploadID,
+		Initiator:        o.multiparts.initiator(uploadID),
+		PartNumberMarker: partNumberMarker,
+		MaxParts:         maxParts,
+	}
+	// Part files are named bucket/object/uploadID.partNum.md5sum, list
+	// parts after partNumberMarker, which needs not be uploaded.
+	index := sort.Search(len(parts), func(i int) bool { return parts[i].PartNumber > partNumberMarker })
+	for ; index < len(parts); index++ {
+		if len(result.Parts) == maxParts {
+			result.IsTruncated = true
+			break
+		}
+		result.Parts = append(result.Parts, parts[index])
+		result.NextPartNumberMarker = parts[index].PartNumber
+	}
+	return result, nil
+}
+
+// listUploadParts - returns all parts of an upload sorted by part
+// number, only the last uploaded of parts uploaded more than once.
+func (o objectAPI) listUploadParts(bucket, object, uploadID string) ([]partInfo, error) {
+	uploadIDPrefix := path.Join(bucket, object, uploadID) + "."
+	lastParts := make(map[int]partInfo)
 	var markerPath string
-	nextPartNumberMarker := 0
-	uploadIDPath := path.Join(bucket, object, uploadID)
-	// Figure out the marker for the next subsequent calls, if the
-	// partNumberMarker is already set.
-	if partNumberMarker > 0 {
-		partNumberMarkerPath := uploadIDPath + "." + strconv.Itoa(partNumberMarker) + "."
-		fileInfos, _, e := o.storage.ListFiles(minioMetaVolume, partNumberMarkerPath, "", false, 1)
+	for {
+		fileInfos, eof, e := o.storage.ListFiles(minioMetaVolume, uploadIDPrefix, markerPath, false, 1000)
 		if e != nil {
-			return result, probe.NewError(toObjectErr(e, minioMetaVolume, partNumberMarkerPath))
+			return nil, e
+		}
+		for _, fileInfo := range fileInfos {
+			markerPath = fileInfo.Name
+			splitResult := strings.Split(path.Base(fileInfo.Name), ".")
+			if len(splitResult) != 3 && len(splitResult) != 4 {
+				continue
+			}
+			partNum, e := strconv.Atoi(splitResult[1])
+			if e != nil {
+				continue
+			}
+			size := fileInfo.Size
+			if len(splitResult) == 4 {
+				if size, e = strconv.ParseInt(splitResult[3], 10, 64); e != nil {
+					continue
+				}
+			}
+			if last, ok := lastParts[partNum]; ok && last.LastModified.After(fileInfo.ModTime) {
+				continue
+			}
+			lastParts[partNum] = partInfo{
+				PartNumber:   partNum,
+				LastModified: fileInfo.ModTime,
+				ETag:         splitResult[2],
+				Size:         size,
+			}
 		}
-		if len(fileInfos) == 0 {
-			return result, probe.NewError(InvalidPart{})
+		if eof || len(fileInfos) == 0 {
+			break
 		}
-		markerPath = fileInfos[0].Name
 	}
-	uploadIDPrefix := uploadIDPath + "."
-	fileInfos, eof, e := o.storage.ListFiles(minioMetaVolume, uploadIDPrefix, markerPath, false, maxParts)
-	if e != nil {
-		return result, probe.NewError(InvalidPart{})
+	parts := make([]partInfo, 0, len(lastParts))
+	for _, part := range lastParts {
+		parts = append(parts, part)
 	}
-	for _, fileInfo := range fileInfos {
-		fileName := path.Base(fileInfo.Name)
-		splitResult := strings.Split(fileName, ".")
-		partNum, e := strconv.Atoi(splitResult[1])
-		if e != nil {
-			return result, probe.NewError(e)
-		}
-		md5sum := splitResult[2]
-		result.Parts = append(result.Parts, partInfo{
-			PartNumber:   partNum,
-			LastModified: fileInfo.ModTime,
-			ETag:         md5sum,
-			Size:         fileInfo.Size,
-		})
-		nextPartNumberMarker = partNum
-	}
-	result.Bucket = bucket
-	result.Object = object
-	result.UploadID = uploadID
-	result.PartNumberMarker = partNumberMarker
-	result.NextPartNumberMarker = nextPartNumberMarker
-	result.MaxParts = maxParts
-	result.IsTruncated = !eof
-	return result, nil
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
 }
 
 // Create an s3 compatible MD5sum for complete multipart transaction.
@@ -495,48 +686,163 @@ func (o objectAPI) CompleteMultipartUpload(bucket string, object string, uploadI
 	} else if !status {
 		return "", probe.NewError(InvalidUploadID{UploadID: uploadID})
 	}
+	// Retained or held objects cannot be overwritten, completed
+	// objects get the bucket default retention.
+	lock, err := o.newObjectLock(bucket, object, nil)
+	if err != nil {
+		return "", err.Trace(bucket, object)
+	}
 
-	fileWriter, e := o.storage.CreateFile(bucket, object)
-	if e != nil {
-		return "", probe.NewError(toObjectErr(e, bucket, object))
+	// Parts staged by the storage are looked up by their records, which
+	// are named with their size.
+	stager, staged := o.storage.(partStager)
+	var uploaded map[int]partInfo
+	if staged {
+		uploadedParts, e := o.listUploadParts(bucket, object, uploadID)
+		if e != nil {
+			return "", probe.NewError(toObjectErr(e, bucket, object))
+		}
+		uploaded = make(map[int]partInfo)
+		for _, part := range uploadedParts {
+			uploaded[part.PartNumber] = part
+		}
 	}
 
+	// The object needs as much space as all of its parts.
+	var size int64
 	var md5Sums []string
 	for _, part := range parts {
-		// Construct part suffix.
+		md5Sums = append(md5Sums, part.ETag)
+		if staged {
+			uploadedPart, ok := uploaded[part.PartNumber]
+			if !ok || uploadedPart.ETag != part.ETag {
+				return "", probe.NewError(InvalidPart{})
+			}
+			size += uploadedPart.Size
+			continue
+		}
 		partSuffix := fmt.Sprintf("%s.%d.%s", uploadID, part.PartNumber, part.ETag)
-		var fileReader io.ReadCloser
-		fileReader, e = o.storage.ReadFile(minioMetaVolume, path.Join(bucket, object, partSuffix), 0)
+		fileInfo, e := o.storage.StatFile(minioMetaVolume, path.Join(bucket, object, partSuffix))
 		if e != nil {
-			if e == errFileNotFound {
+			if errorCause(e) == errFileNotFound {
 				return "", probe.NewError(InvalidPart{})
 			}
 			return "", probe.NewError(e)
 		}
-		_, e = io.Copy(fileWriter, fileReader)
-		if e != nil {
-			return "", probe.NewError(e)
+		size += fileInfo.Size
+	}
+	if e := o.checkFreeSpace(bucket, size); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
+
+	hasher := o.newAttestationHasher()
+	// Commits the object along with its metadata.
+	var commit func(metadata map[string]string) error
+	// Writer of the object, nil for staged parts.
+	var fileWriter io.WriteCloser
+	if staged {
+		// Staged parts are committed as the object without copying.
+		var tokens []string
+		for _, part := range parts {
+			recordPath := path.Join(bucket, object, stagedPartSuffix(uploadID, part.PartNumber, part.ETag, uploaded[part.PartNumber].Size))
+			token, e := o.readStagedPart(recordPath)
+			if e != nil {
+				if errorCause(e) == errFileNotFound {
+					return "", probe.NewError(InvalidPart{})
+				}
+				return "", probe.NewError(e)
+			}
+			tokens = append(tokens, token)
+		}
+		commit = func(metadata map[string]string) error {
+			e := stager.CommitParts(o.context(), bucket, object, tokens)
+			if errorCause(e) == errFileNotFound {
+				return InvalidPart{}
+			}
+			if e != nil {
+				return e
+			}
+			return o.saveObjectMetadata(bucket, object, metadata)
 		}
-		e = fileReader.Close()
+	} else {
+		var e error
+		fileWriter, e = o.storage.CreateFile(o.context(), bucket, object)
 		if e != nil {
-			return "", probe.NewError(e)
+			return "", probe.NewError(toObjectErr(e, bucket, object))
 		}
-		md5Sums = append(md5Sums, part.ETag)
-	}
 
-	e = fileWriter.Close()
-	if e != nil {
-		return "", probe.NewError(e)
+		// Parts are hashed for the attestation as they are copied.
+		var dataWriter io.Writer = fileWriter
+		if hasher != nil {
+			dataWriter = io.MultiWriter(fileWriter, hasher)
+		}
+		for _, part := range parts {
+			// Construct part suffix.
+			partSuffix := fmt.Sprintf("%s.%d.%s", uploadID, part.PartNumber, part.ETag)
+			var fileReader io.ReadCloser
+			fileReader, e = o.storage.ReadFile(o.context(), minioMetaVolume, path.Join(bucket, object, partSuffix), 0)
+			if e != nil {
+				if errorCause(e) == errFileNotFound {
+					return "", probe.NewError(InvalidPart{})
+				}
+				return "", probe.NewError(e)
+			}
+			_, e = io.Copy(dataWriter, fileReader)
+			if e != nil {
+				return "", probe.NewError(e)
+			}
+			e = fileReader.Close()
+			if e != nil {
+				return "", probe.NewError(e)
+			}
+		}
+		commit = func(metadata map[string]string) error {
+			return o.commitObject(bucket, object, fileWriter, metadata)
+		}
 	}
 
-	// Save the s3 md5.
+	// The s3 md5 is saved with the object as it is committed.
 	s3MD5, err := makeS3MD5(md5Sums...)
 	if err != nil {
 		return "", err.Trace(md5Sums...)
 	}
+	endCommit := o.beginCommit(bucket, object)
+	e := o.sequenced(bucket, object, func(sequence uint64) error {
+		metadata, e := newObjectMetadata(s3MD5, nil, lock, sequence)
+		if e != nil {
+			if fileWriter != nil {
+				safeCloseAndRemove(fileWriter)
+			}
+			return e
+		}
+		previous := o.statObjectMetadata(bucket, object)
+		e = commit(metadata)
+		if e != nil && e != errSequenceNotSaved {
+			return e
+		}
+		o.removeTransitioned(bucket, object, previous)
+		return e
+	})
+	endCommit()
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	if staged && hasher != nil {
+		// Staged parts are hashed for the attestation once committed.
+		if e = o.hashObjectData(bucket, object, hasher); e != nil {
+			return "", probe.NewError(toObjectErr(e, bucket, object))
+		}
+	}
+	o.notFound.Invalidate(bucket, object)
+	o.cache.Invalidate(bucket, object)
+	o.markReplicationPending(bucket, object)
+	if e = o.attestObject(bucket, object, s3MD5, hasher); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
 
 	// Cleanup all the parts.
 	o.removeMultipartUpload(bucket, object, uploadID)
+	o.multiparts.remove(uploadID)
 
 	// Return md5sum.
 	return s3MD5, nil
@@ -551,6 +857,9 @@ func (o objectAPI) removeMultipartUpload(bucket, object, uploadID string) *probe
 		return probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
 
+	// Parts staged by the storage are removed along with their records.
+	stager, staged := o.storage.(partStager)
+	var tokens []string
 	marker := ""
 	for {
 		uploadIDPath := path.Join(bucket, object, uploadID)
@@ -560,13 +869,23 @@ func (o objectAPI) removeMultipartUpload(bucket, object, uploadID string) *probe
 			return probe.NewError(InvalidUploadID{UploadID: uploadID})
 		}
 		for _, fileInfo := range fileInfos {
-			o.storage.DeleteFile(minioMetaVolume, fileInfo.Name)
+			if staged && strings.Count(path.Base(fileInfo.Name), ".") == 3 {
+				if token, e := o.readStagedPart(fileInfo.Name); e == nil {
+					tokens = append(tokens, token)
+				}
+			}
+			o.storage.DeleteFile(context.Background(), minioMetaVolume, fileInfo.Name)
 			marker = fileInfo.Name
 		}
 		if eof {
 			break
 		}
 	}
+	if len(tokens) > 0 {
+		if e := stager.RemoveParts(context.Background(), bucket, object, tokens); e != nil {
+			errorIf(probe.NewError(e).Trace(bucket, object, uploadID), "Unable to remove staged parts.", nil)
+		}
+	}
 	return nil
 }
 
@@ -587,5 +906,6 @@ func (o objectAPI) AbortMultipartUpload(bucket, object, uploadID string) *probe.
 	if err != nil {
 		return err.Trace(bucket, object, uploadID)
 	}
+	o.multiparts.remove(uploadID)
 	return nil
 }
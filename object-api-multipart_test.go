@@ -18,11 +18,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
 )
 
 // Tests validate creation of new multipart upload instance.
@@ -47,7 +52,7 @@ func TestObjectNewMultipartUpload(t *testing.T) {
 
 	errMsg := "Bucket not found: minio-bucket"
 	// opearation expected to fail since the bucket on which NewMultipartUpload is being initiated doesn't exist.
-	uploadID, err := obj.NewMultipartUpload(bucket, object)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, "")
 	if err == nil {
 		t.Fatalf("Expcected to fail since the NewMultipartUpload is intialized on a non-existant bucket.")
 	}
@@ -62,7 +67,7 @@ func TestObjectNewMultipartUpload(t *testing.T) {
 		t.Fatal(err.ToGoError())
 	}
 
-	uploadID, err = obj.NewMultipartUpload(bucket, object)
+	uploadID, err = obj.NewMultipartUpload(bucket, object, "")
 	if err != nil {
 		t.Fatal(err.ToGoError())
 	}
@@ -73,7 +78,7 @@ func TestObjectNewMultipartUpload(t *testing.T) {
 		if e == errFileNotFound {
 			t.Fatalf("New Multipart upload failed to create uuid file.")
 		}
-		t.Fatalf(e.Error())
+		t.Fatal(e)
 	}
 }
 
@@ -110,7 +115,7 @@ func TestObjectAPIIsUploadIDExists(t *testing.T) {
 		t.Fatal("Expected uploadIDPath to not to exist.")
 	}
 
-	uploadID, err := obj.NewMultipartUpload(bucket, object)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, "")
 	if err != nil {
 		t.Fatal(err.ToGoError())
 	}
@@ -151,7 +156,7 @@ func TestObjectAPIPutObjectPart(t *testing.T) {
 		t.Fatal(err.ToGoError())
 	}
 	// Initiate Multipart Upload on the above created bucket.
-	uploadID, err := obj.NewMultipartUpload(bucket, object)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, "")
 	if err != nil {
 		// Failed to create NewMultipartUpload, abort.
 		t.Fatal(err.ToGoError())
@@ -263,3 +268,203 @@ func TestObjectAPIPutObjectPart(t *testing.T) {
 		}
 	}
 }
+
+// Tests in-progress multipart uploads are limited per bucket and access
+// key, and counted again after a restart.
+func TestObjectMultipartLimits(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-multipart-limits-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	obj.SetMultipartLimits(multipartConfig{MaxUploadsPerBucket: 2, MaxUploadsPerAccessKey: 1})
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.NewMultipartUpload("bucket", "object", "alice"); err == nil {
+		t.Fatal("Expected access key limit to be enforced")
+	} else if _, ok := err.ToGoError().(TooManyMultipartUploads); !ok {
+		t.Fatalf("Expected TooManyMultipartUploads, got %s", err.ToGoError())
+	}
+	if _, err = obj.NewMultipartUpload("bucket", "object", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.NewMultipartUpload("bucket", "object", "carol"); err == nil {
+		t.Fatal("Expected bucket limit to be enforced")
+	}
+	info := obj.MultipartSessionsInfo()
+	if info.Buckets["bucket"] != (multipartUsage{InProgress: 2, Rejected: 1}) {
+		t.Fatalf("Unexpected bucket usage %+v", info.Buckets["bucket"])
+	}
+	if info.AccessKeys["alice"] != (multipartUsage{InProgress: 1, Rejected: 1}) {
+		t.Fatalf("Unexpected access key usage %+v", info.AccessKeys["alice"])
+	}
+
+	// Uploads are counted again after a restart.
+	restarted := newObjectLayer(fs)
+	if err = restarted.LoadMultipartSessions(); err != nil {
+		t.Fatal(err)
+	}
+	info = restarted.MultipartSessionsInfo()
+	if info.Buckets["bucket"].InProgress != 2 || info.AccessKeys["alice"].InProgress != 1 {
+		t.Fatalf("Unexpected usage after restart %+v", info)
+	}
+
+	// Aborted uploads no longer count.
+	if err = obj.AbortMultipartUpload("bucket", "object", uploadID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.NewMultipartUpload("bucket", "object", "alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests incomplete uploads are listed, and removed once stale.
+func TestObjectRemoveStaleUploads(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-multipart-cleanup-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	obj.SetMultipartLimits(multipartConfig{StaleUploadExpiry: 3600})
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	staleID, err := obj.NewMultipartUpload("bucket", "stale", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freshID, err := obj.NewMultipartUpload("bucket", "fresh", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiated := time.Now().Add(-2 * time.Hour)
+	stalePath := filepath.Join(directory, minioMetaVolume, "bucket", "stale", staleID)
+	if e = os.Chtimes(stalePath, initiated, initiated); e != nil {
+		t.Fatal(e)
+	}
+
+	uploads, truncated, err := obj.ListIncompleteUploads("", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if truncated || len(uploads) != 1 || uploads[0].UploadID != staleID || uploads[0].Initiator != "alice" {
+		t.Fatalf("Expected stale upload to be listed, got %+v", uploads)
+	}
+	if expires := uploads[0].Initiated.Add(time.Hour); !uploads[0].Expires.Equal(expires) {
+		t.Fatalf("Expected upload to expire at %s, got %s", expires, uploads[0].Expires)
+	}
+	if _, _, err = obj.ListIncompleteUploads("missing", "", 0); err == nil {
+		t.Fatal("Expected error listing uploads of a missing bucket")
+	}
+
+	cleaner := newMultipartCleaner()
+	cleaner.objAPI = &obj
+	cleaner.cleanup(time.Hour)
+	if info := cleaner.Info(); info.Running || info.LastRemoved != 1 || info.Removed != 1 || info.LastError != "" {
+		t.Fatalf("Unexpected cleanup info %+v", info)
+	}
+	if _, err = obj.ListObjectParts("bucket", "stale", staleID, 0, 1000); err == nil {
+		t.Fatal("Expected stale upload to be removed")
+	}
+	if _, err = obj.ListObjectParts("bucket", "fresh", freshID, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if info := obj.MultipartSessionsInfo(); info.AccessKeys["alice"].InProgress != 0 || info.AccessKeys["bob"].InProgress != 1 {
+		t.Fatalf("Expected removed upload to no longer count, got %+v", info.AccessKeys)
+	}
+}
+
+// Tests parts are listed in part number order, after part number
+// markers which need not be uploaded.
+func TestObjectListParts(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-list-parts-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	putPart := func(partID int, data []byte) (string, *probe.Error) {
+		md5Hex := hex.EncodeToString(sumMD5(data))
+		return obj.PutObjectPart("bucket", "object", uploadID, partID, int64(len(data)), bytes.NewReader(data), md5Hex)
+	}
+	for _, partID := range []int{10, 2, 1, 11} {
+		if _, err = putPart(partID, []byte(fmt.Sprintf("part %d", partID))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Parts uploaded again replace their previous upload.
+	md5Hex, err := putPart(2, []byte("part 2 again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	partPath := filepath.Join(directory, minioMetaVolume, "bucket", "object", fmt.Sprintf("%s.2.%s", uploadID, md5Hex))
+	if e = os.Chtimes(partPath, later, later); e != nil {
+		t.Fatal(e)
+	}
+
+	testCases := []struct {
+		partNumberMarker int
+		maxParts         int
+		parts            []int
+		truncated        bool
+	}{
+		{0, 1000, []int{1, 2, 10, 11}, false},
+		{0, 2, []int{1, 2}, true},
+		{2, 2, []int{10, 11}, false},
+		{3, 1, []int{10}, true},
+		{11, 1000, nil, false},
+	}
+	for i, testCase := range testCases {
+		result, err := obj.ListObjectParts("bucket", "object", uploadID, testCase.partNumberMarker, testCase.maxParts)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		var parts []int
+		for _, part := range result.Parts {
+			parts = append(parts, part.PartNumber)
+			if part.PartNumber == 2 && part.ETag != md5Hex {
+				t.Errorf("Test %d: Expected reuploaded part 2, got %+v", i+1, part)
+			}
+		}
+		if fmt.Sprint(parts) != fmt.Sprint(testCase.parts) || result.IsTruncated != testCase.truncated {
+			t.Errorf("Test %d: Expected parts %v truncated %v, got %v truncated %v", i+1, testCase.parts, testCase.truncated, parts, result.IsTruncated)
+		}
+		if result.Initiator != "alice" {
+			t.Errorf("Test %d: Expected initiator alice, got %q", i+1, result.Initiator)
+		}
+	}
+
+	if _, err = obj.ListObjectParts("missing", "object", uploadID, 0, 1000); err == nil {
+		t.Fatal("Expected error listing parts in a missing bucket")
+	} else if _, ok := err.ToGoError().(BucketNotFound); !ok {
+		t.Fatalf("Expected BucketNotFound, got %s", err.ToGoError())
+	}
+}
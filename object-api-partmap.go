@@ -0,0 +1,116 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Number of parts planned when not requested.
+	defaultPartMapParts = 16
+
+	// Maximum number of parts of a part map.
+	maxPartMapParts = 10000
+
+	// Parts of objects on storage without erasure stripes are aligned
+	// to this size.
+	defaultPartMapAlignment = 1024 * 1024
+)
+
+// PartRange - byte range of a single part of a part map.
+type PartRange struct {
+	PartNumber int
+	Offset     int64
+	Length     int64
+	// Value of the Range header fetching the part.
+	Range string
+}
+
+// PartMap - split plan of an object into parts fetched by parallel
+// ranged GETs, parts are aligned to erasure stripes so that no stripe
+// is decoded by two requests.
+type PartMap struct {
+	XMLName    xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ PartMap" json:"-"`
+	Bucket     string
+	Key        string
+	Size       int64
+	ETag       string `xml:",omitempty"`
+	StripeSize int64
+	Parts      []PartRange `xml:"Part"`
+}
+
+// newPartMap - splits size bytes into at most parts ranges, each a
+// multiple of stripeSize except the last one.
+func newPartMap(size, stripeSize int64, parts int) []PartRange {
+	stripes := (size + stripeSize - 1) / stripeSize
+	if stripes == 0 {
+		return []PartRange{}
+	}
+	if int64(parts) > stripes {
+		parts = int(stripes)
+	}
+	// Spread stripes evenly, the first parts take one more stripe.
+	stripesPerPart := stripes / int64(parts)
+	extraStripes := stripes % int64(parts)
+	partRanges := make([]PartRange, parts)
+	offset := int64(0)
+	for i := range partRanges {
+		length := stripesPerPart * stripeSize
+		if int64(i) < extraStripes {
+			length += stripeSize
+		}
+		if offset+length > size {
+			length = size - offset
+		}
+		partRanges[i] = PartRange{
+			PartNumber: i + 1,
+			Offset:     offset,
+			Length:     length,
+			Range:      fmt.Sprintf("bytes=%d-%d", offset, offset+length-1),
+		}
+		offset += length
+	}
+	return partRanges
+}
+
+// GetObjectPartMap - returns split plan of object into at most parts
+// ranges sized to the erasure stripes of the storage.
+func (o objectAPI) GetObjectPartMap(bucket, object string, parts int) (PartMap, *probe.Error) {
+	if parts <= 0 || parts > maxPartMapParts {
+		return PartMap{}, probe.NewError(InvalidPartMapParts{Parts: parts})
+	}
+	objInfo, err := o.GetObjectInfo(bucket, object)
+	if err != nil {
+		return PartMap{}, err.Trace(bucket, object)
+	}
+	stripeSize := int64(defaultPartMapAlignment)
+	if sizer, ok := o.storage.(stripeSizer); ok {
+		stripeSize = sizer.StripeSize()
+	}
+	return PartMap{
+		Bucket:     bucket,
+		Key:        object,
+		Size:       objInfo.Size,
+		ETag:       objInfo.MD5Sum,
+		StripeSize: stripeSize,
+		Parts:      newPartMap(objInfo.Size, stripeSize, parts),
+	}, nil
+}
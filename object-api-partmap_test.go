@@ -0,0 +1,54 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "testing"
+
+// Tests objects are split into stripe aligned parts.
+func TestNewPartMap(t *testing.T) {
+	testCases := []struct {
+		size, stripeSize int64
+		parts            int
+		lengths          []int64
+	}{
+		{0, 4, 16, []int64{}},
+		{3, 4, 16, []int64{3}},
+		{16, 4, 16, []int64{4, 4, 4, 4}},
+		{17, 4, 2, []int64{12, 5}},
+		{40, 4, 3, []int64{16, 12, 12}},
+		{41, 4, 3, []int64{16, 16, 9}},
+	}
+	for i, testCase := range testCases {
+		partRanges := newPartMap(testCase.size, testCase.stripeSize, testCase.parts)
+		if len(partRanges) != len(testCase.lengths) {
+			t.Fatalf("Test %d: Expected %d parts, got %v", i+1, len(testCase.lengths), partRanges)
+		}
+		offset := int64(0)
+		for j, partRange := range partRanges {
+			if partRange.PartNumber != j+1 || partRange.Offset != offset || partRange.Length != testCase.lengths[j] {
+				t.Fatalf("Test %d: Unexpected part %+v", i+1, partRange)
+			}
+			if j < len(partRanges)-1 && partRange.Length%testCase.stripeSize != 0 {
+				t.Fatalf("Test %d: Part %+v is not stripe aligned", i+1, partRange)
+			}
+			offset += partRange.Length
+		}
+		if offset != testCase.size {
+			t.Fatalf("Test %d: Parts cover %d bytes, expected %d", i+1, offset, testCase.size)
+		}
+	}
+}
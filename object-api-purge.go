@@ -0,0 +1,324 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+const (
+	// Buckets being purged are recorded under this prefix in
+	// minioMetaVolume, purges are resumed on restart.
+	bucketPurgePrefix = ".purge"
+
+	// Maximum size of a saved purge job.
+	maxPurgeJobSize = 4096
+
+	// Number of objects and uploads listed at a time while purging.
+	purgeListBatchSize = 1000
+
+	// Number of ended purge jobs remembered for status queries.
+	maxEndedPurgeJobs = 100
+)
+
+// Purge job status values.
+const (
+	purgeStatusRunning  = "running"
+	purgeStatusFinished = "finished"
+	purgeStatusFailed   = "failed"
+)
+
+// purgeJob - asynchronous purge of a force deleted bucket.
+type purgeJob struct {
+	ID        string    `json:"id"`
+	Bucket    string    `json:"bucket"`
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Deleted   int64     `json:"deleted"`
+	Failed    int64     `json:"failed"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// bucketPurges - running and recently ended purge jobs.
+type bucketPurges struct {
+	mutex *sync.Mutex
+	// Jobs keyed by id.
+	jobs map[string]*purgeJob
+	// Running jobs keyed by bucket.
+	running map[string]*purgeJob
+	// Ids of ended jobs, oldest first.
+	ended []string
+}
+
+func newBucketPurges() *bucketPurges {
+	return &bucketPurges{
+		mutex:   &sync.Mutex{},
+		jobs:    make(map[string]*purgeJob),
+		running: make(map[string]*purgeJob),
+	}
+}
+
+// purgeJobPath - returns saved purge job path of bucket in
+// minioMetaVolume.
+func purgeJobPath(bucket string) string {
+	return path.Join(bucketPurgePrefix, bucket+".json")
+}
+
+// IsBucketPurging - returns true if bucket was force deleted and its
+// objects are still being purged.
+func (o objectAPI) IsBucketPurging(bucket string) bool {
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	_, ok := o.purges.running[bucket]
+	return ok
+}
+
+// PurgeBucket - marks bucket deleted and purges its objects in
+// background, returns id of the purge job. Purging a bucket already
+// being purged returns the running job. Buckets with object lock
+// enabled are not purged, their objects are deleted one by one.
+func (o objectAPI) PurgeBucket(bucket string) (string, *probe.Error) {
+	if !IsValidBucketName(bucket) {
+		return "", probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	if job, ok := o.purges.running[bucket]; ok {
+		return job.ID, nil
+	}
+	if _, e := o.storage.StatVol(bucket); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket))
+	}
+	if _, locked, err := getBucketObjectLock(bucket); err != nil {
+		return "", err.Trace(bucket)
+	} else if locked {
+		return "", probe.NewError(BucketObjectLocked{Bucket: bucket})
+	}
+	uid, e := uuid.New()
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	job := &purgeJob{
+		ID:        uid.String(),
+		Bucket:    bucket,
+		Status:    purgeStatusRunning,
+		StartTime: time.Now().UTC(),
+	}
+	// Save job first, bucket stays deleted across restarts.
+	if e = o.writePurgeJob(*job); e != nil {
+		return "", probe.NewError(e)
+	}
+	o.purges.jobs[job.ID] = job
+	o.purges.running[bucket] = job
+	go o.runPurge(job)
+	return job.ID, nil
+}
+
+// ResumeBucketPurges - resumes purging buckets force deleted before a
+// restart.
+func (o objectAPI) ResumeBucketPurges() *probe.Error {
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	marker := ""
+	for {
+		fileInfos, eof, e := o.storage.ListFiles(minioMetaVolume, bucketPurgePrefix+slashSeparator, marker, false, purgeListBatchSize)
+		if e != nil {
+			if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+				return nil
+			}
+			return probe.NewError(e)
+		}
+		for _, fileInfo := range fileInfos {
+			marker = fileInfo.Name
+			jobBytes, e := o.readMetaFile(fileInfo.Name, maxPurgeJobSize)
+			if e != nil {
+				return probe.NewError(e).Trace(fileInfo.Name)
+			}
+			job := &purgeJob{}
+			if e = json.Unmarshal(jobBytes, job); e != nil {
+				return probe.NewError(e).Trace(fileInfo.Name)
+			}
+			o.purges.jobs[job.ID] = job
+			o.purges.running[job.Bucket] = job
+			go o.runPurge(job)
+		}
+		if eof || len(fileInfos) == 0 {
+			return nil
+		}
+	}
+}
+
+// GetPurgeJob - returns state of purge job with id, false if the job
+// is unknown or ended long ago.
+func (o objectAPI) GetPurgeJob(id string) (purgeJob, bool) {
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	job, ok := o.purges.jobs[id]
+	if !ok {
+		return purgeJob{}, false
+	}
+	return *job, true
+}
+
+// byPurgeStartTime is a collection satisfying sort.Interface.
+type byPurgeStartTime []purgeJob
+
+func (d byPurgeStartTime) Len() int           { return len(d) }
+func (d byPurgeStartTime) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byPurgeStartTime) Less(i, j int) bool { return d[i].StartTime.Before(d[j].StartTime) }
+
+// ListPurgeJobs - returns running and recently ended purge jobs, oldest
+// first.
+func (o objectAPI) ListPurgeJobs() []purgeJob {
+	o.purges.mutex.Lock()
+	jobs := []purgeJob{}
+	for _, job := range o.purges.jobs {
+		jobs = append(jobs, *job)
+	}
+	o.purges.mutex.Unlock()
+	sort.Sort(byPurgeStartTime(jobs))
+	return jobs
+}
+
+// writePurgeJob - saves purge job of a bucket.
+func (o objectAPI) writePurgeJob(job purgeJob) error {
+	jobBytes, e := json.Marshal(job)
+	if e != nil {
+		return e
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(purgeJobPath(job.Bucket), jobBytes)
+}
+
+// updatePurgeJob - applies fn to the state of job.
+func (o objectAPI) updatePurgeJob(job *purgeJob, fn func(job *purgeJob)) {
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	fn(job)
+}
+
+// runPurge - deletes all objects and incomplete uploads of the bucket
+// of job, followed by the bucket itself. Bucket is visible again if
+// anything could not be deleted.
+func (o objectAPI) runPurge(job *purgeJob) {
+	err := o.purgeObjects(job)
+	if err == nil {
+		err = o.purgeUploads(job)
+	}
+	if err == nil && job.Failed == 0 {
+		if err = o.DeleteBucket(job.Bucket); err == nil {
+			removeBucketPolicy(job.Bucket)
+		}
+	}
+	if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, purgeJobPath(job.Bucket)); e != nil {
+		errorIf(probe.NewError(e).Trace(job.Bucket), "Unable to remove purge job.", nil)
+	}
+
+	o.purges.mutex.Lock()
+	defer o.purges.mutex.Unlock()
+	job.EndTime = time.Now().UTC()
+	job.Status = purgeStatusFinished
+	if err != nil {
+		errorIf(err.Trace(job.Bucket), "Unable to purge bucket.", nil)
+		job.LastError = err.ToGoError().Error()
+	}
+	if err != nil || job.Failed > 0 {
+		job.Status = purgeStatusFailed
+	}
+	delete(o.purges.running, job.Bucket)
+	o.purges.ended = append(o.purges.ended, job.ID)
+	for len(o.purges.ended) > maxEndedPurgeJobs {
+		delete(o.purges.jobs, o.purges.ended[0])
+		o.purges.ended = o.purges.ended[1:]
+	}
+}
+
+// purgeObjects - deletes all objects of the bucket of job, batch by
+// batch. Retained objects are not deleted.
+func (o objectAPI) purgeObjects(job *purgeJob) *probe.Error {
+	marker := ""
+	for {
+		result, err := o.ListObjects(job.Bucket, "", marker, "", purgeListBatchSize)
+		if err != nil {
+			return err.Trace(job.Bucket, marker)
+		}
+		for _, object := range result.Objects {
+			// Force deleted buckets are not kept in trash.
+			err = o.deleteObject(job.Bucket, object.Name, false, false)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"bucket": job.Bucket,
+					"object": object.Name,
+				}).Errorf("Purge failed with %s", err.ToGoError())
+			}
+			o.updatePurgeJob(job, func(job *purgeJob) {
+				if err != nil {
+					job.Failed++
+					job.LastError = err.ToGoError().Error()
+					return
+				}
+				job.Deleted++
+			})
+		}
+		if !result.IsTruncated || len(result.Objects) == 0 {
+			return nil
+		}
+		marker = result.Objects[len(result.Objects)-1].Name
+	}
+}
+
+// purgeUploads - aborts all incomplete multipart uploads of the bucket
+// of job.
+func (o objectAPI) purgeUploads(job *purgeJob) *probe.Error {
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		result, err := o.ListMultipartUploads(job.Bucket, "", keyMarker, uploadIDMarker, "", purgeListBatchSize)
+		if err != nil {
+			return err.Trace(job.Bucket, keyMarker, uploadIDMarker)
+		}
+		for _, upload := range result.Uploads {
+			if err = o.AbortMultipartUpload(job.Bucket, upload.Object, upload.UploadID); err != nil {
+				log.WithFields(logrus.Fields{
+					"bucket":   job.Bucket,
+					"object":   upload.Object,
+					"uploadID": upload.UploadID,
+				}).Errorf("Purge failed with %s", err.ToGoError())
+				o.updatePurgeJob(job, func(job *purgeJob) {
+					job.Failed++
+					job.LastError = err.ToGoError().Error()
+				})
+			}
+		}
+		if !result.IsTruncated || len(result.Uploads) == 0 {
+			return nil
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// waitPurgeJob - waits for purge job with id to end.
+func waitPurgeJob(t *testing.T, objAPI objectAPI, id string) purgeJob {
+	for i := 0; i < 100; i++ {
+		job, ok := objAPI.GetPurgeJob(id)
+		if !ok {
+			t.Fatalf("Purge job %s not found", id)
+		}
+		if job.Status != purgeStatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Purge job %s did not end", id)
+	return purgeJob{}
+}
+
+// Tests force deleted buckets are hidden and purged in background.
+func TestPurgeBucket(t *testing.T) {
+	disk, e := ioutil.TempDir("", "minio-purge-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(disk)
+	fs, e := newFS(disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(fs)
+	for _, bucket := range []string{"bucket", "other"} {
+		if err := objAPI.MakeBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, object := range []string{"a", "b/c", "b/d"} {
+		if _, err := objAPI.PutObject("bucket", object, 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := objAPI.NewMultipartUpload("bucket", "upload", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := objAPI.PurgeBucket("missing"); err == nil || err.ToGoError() != (BucketNotFound{Bucket: "missing"}) {
+		t.Fatalf("Expected bucket not found, got %v", err)
+	}
+	id, err := objAPI.PurgeBucket("bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := waitPurgeJob(t, objAPI, id)
+	if job.Status != purgeStatusFinished || job.Deleted != 3 || job.Failed != 0 {
+		t.Fatalf("Unexpected purge job %+v", job)
+	}
+	if _, err = objAPI.GetBucketInfo("bucket"); err == nil {
+		t.Fatal("Expected purged bucket to be deleted")
+	}
+	if result, err := objAPI.ListMultipartUploads("other", "", "", "", "", 10); err != nil || len(result.Uploads) != 0 {
+		t.Fatalf("Unexpected uploads %v, %v", result.Uploads, err)
+	}
+
+	// Buckets being purged are hidden.
+	if err = objAPI.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	objAPI.purges.running["bucket"] = &purgeJob{Bucket: "bucket"}
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].Name != "other" {
+		t.Fatalf("Expected purging bucket to be hidden, got %v", buckets)
+	}
+
+	// Saved purge jobs are resumed.
+	if e = objAPI.writePurgeJob(purgeJob{ID: "resumed", Bucket: "bucket", Status: purgeStatusRunning}); e != nil {
+		t.Fatal(e)
+	}
+	objAPI = newObjectLayer(fs)
+	if err = objAPI.ResumeBucketPurges(); err != nil {
+		t.Fatal(err)
+	}
+	job = waitPurgeJob(t, objAPI, "resumed")
+	if job.Status != purgeStatusFinished {
+		t.Fatalf("Unexpected purge job %+v", job)
+	}
+	if _, err = objAPI.GetBucketInfo("bucket"); err == nil {
+		t.Fatal("Expected purged bucket to be deleted")
+	}
+	if len(objAPI.ListPurgeJobs()) != 1 {
+		t.Fatalf("Unexpected purge jobs %v", objAPI.ListPurgeJobs())
+	}
+}
+
+// Tests buckets with object lock enabled are not force deleted.
+func TestPurgeBucketObjectLock(t *testing.T) {
+	disk, e := ioutil.TempDir("", "minio-purge-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(disk)
+
+	// Bucket configuration is saved in the config path.
+	configPath, e := ioutil.TempDir("", "minio-purge-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	fs, e := newFS(disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(fs)
+	if err := objAPI.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	config := `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`
+	if err := writeBucketObjectLock("bucket", []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{
+		objectLockModeKey:        retentionGovernance,
+		objectLockRetainUntilKey: time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+	}
+	if _, err := objAPI.PutObject("bucket", "object", 5, bytes.NewReader([]byte("hello")), metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := objAPI.PurgeBucket("bucket"); err == nil || err.ToGoError() != (BucketObjectLocked{Bucket: "bucket"}) {
+		t.Fatalf("Expected bucket object locked, got %v", err)
+	}
+	if objAPI.IsBucketPurging("bucket") {
+		t.Fatal("Expected locked bucket not to be hidden")
+	}
+	if _, err := objAPI.GetObjectInfo("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+}
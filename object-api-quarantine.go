@@ -0,0 +1,350 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+const (
+	// Uploads failing verification are quarantined under this prefix
+	// in minioMetaVolume, as <id>.data with its <id>.json info.
+	quarantinePrefix = ".quarantine"
+
+	// Maximum size of a saved quarantine info.
+	maxQuarantineInfoSize = 64 * 1024
+
+	// Number of quarantined uploads listed at a time.
+	quarantineListBatchSize = 1000
+)
+
+// Reasons uploads are quarantined for.
+const (
+	quarantineReasonBadDigest      = "BadDigest"
+	quarantineReasonIncompleteBody = "IncompleteBody"
+)
+
+// quarantineConfig - upload quarantine configuration, while enabled
+// the received bytes of uploads failing verification are kept for
+// inspection and recovery instead of being discarded.
+type quarantineConfig struct {
+	Enable bool `json:"enable"`
+	// Uploads larger than MaxSize bytes are not quarantined, zero
+	// for no limit.
+	MaxSize int64 `json:"maxSize"`
+}
+
+// quarantineInfo - upload kept in quarantine.
+type quarantineInfo struct {
+	ID       string            `json:"id"`
+	Bucket   string            `json:"bucket"`
+	Object   string            `json:"object"`
+	UploadID string            `json:"uploadId,omitempty"`
+	PartID   int               `json:"partId,omitempty"`
+	Reason   string            `json:"reason"`
+	Expected string            `json:"expectedMD5,omitempty"`
+	Received string            `json:"receivedMD5,omitempty"`
+	Size     int64             `json:"size"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// byQuarantineTime - sorts quarantined uploads oldest first.
+type byQuarantineTime []quarantineInfo
+
+func (q byQuarantineTime) Len() int           { return len(q) }
+func (q byQuarantineTime) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q byQuarantineTime) Less(i, j int) bool { return q[i].Time.Before(q[j].Time) }
+
+// uploadQuarantine - quarantine configuration of the object layer.
+type uploadQuarantine struct {
+	rwMutex *sync.RWMutex
+	config  quarantineConfig
+}
+
+func newUploadQuarantine() *uploadQuarantine {
+	return &uploadQuarantine{rwMutex: &sync.RWMutex{}}
+}
+
+// SetQuarantine - sets quarantine configuration of uploads received
+// from now on.
+func (o objectAPI) SetQuarantine(config quarantineConfig) {
+	o.quarantine.rwMutex.Lock()
+	defer o.quarantine.rwMutex.Unlock()
+	o.quarantine.config = config
+}
+
+// quarantineDataPath - returns path of quarantined data in
+// minioMetaVolume.
+func quarantineDataPath(id string) string {
+	return path.Join(quarantinePrefix, id+".data")
+}
+
+// quarantineInfoPath - returns path of quarantine info in
+// minioMetaVolume.
+func quarantineInfoPath(id string) string {
+	return path.Join(quarantinePrefix, id+".json")
+}
+
+// quarantineWriter - copies received bytes of an upload to quarantine.
+// Failing to do so never fails the upload, the copy is given up
+// instead.
+type quarantineWriter struct {
+	o       objectAPI
+	id      string
+	writer  io.WriteCloser
+	md5     hash.Hash
+	size    int64
+	maxSize int64
+}
+
+// newQuarantineWriter - returns writer of received bytes of an upload,
+// nil if quarantine is disabled.
+func (o objectAPI) newQuarantineWriter() *quarantineWriter {
+	o.quarantine.rwMutex.RLock()
+	config := o.quarantine.config
+	o.quarantine.rwMutex.RUnlock()
+	if !config.Enable {
+		return nil
+	}
+	uid, e := uuid.New()
+	if e != nil {
+		errorIf(probe.NewError(e), "Unable to generate quarantine id.", nil)
+		return nil
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		errorIf(probe.NewError(e), "Unable to create minio meta volume.", nil)
+		return nil
+	}
+	id := uid.String()
+	writer, e := o.storage.CreateFile(context.Background(), minioMetaVolume, quarantineDataPath(id))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(id), "Unable to create quarantine data.", nil)
+		return nil
+	}
+	return &quarantineWriter{o: o, id: id, writer: writer, md5: md5.New(), maxSize: config.MaxSize}
+}
+
+// abort - gives up the copy, removing bytes copied so far.
+func (q *quarantineWriter) abort() {
+	if q == nil || q.writer == nil {
+		return
+	}
+	safeCloseAndRemove(q.writer)
+	q.writer = nil
+}
+
+func (q *quarantineWriter) Write(p []byte) (int, error) {
+	if q.writer == nil {
+		return len(p), nil
+	}
+	if q.maxSize > 0 && q.size+int64(len(p)) > q.maxSize {
+		q.abort()
+		return len(p), nil
+	}
+	n, e := q.writer.Write(p)
+	q.md5.Write(p[:n])
+	q.size += int64(n)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(q.id), "Unable to write quarantine data.", nil)
+		q.abort()
+	}
+	return len(p), nil
+}
+
+// commit - keeps bytes copied so far in quarantine along with info of
+// the failed upload.
+func (q *quarantineWriter) commit(info quarantineInfo) {
+	if q == nil || q.writer == nil {
+		return
+	}
+	info.ID = q.id
+	info.Size = q.size
+	info.Received = hex.EncodeToString(q.md5.Sum(nil))
+	info.Time = time.Now().UTC()
+	infoBytes, e := json.Marshal(info)
+	if e != nil {
+		q.abort()
+		return
+	}
+	if e = q.writer.Close(); e != nil {
+		errorIf(probe.NewError(e).Trace(q.id), "Unable to save quarantine data.", nil)
+		q.writer = nil
+		return
+	}
+	q.writer = nil
+	if e = q.o.writeMetaFile(quarantineInfoPath(q.id), infoBytes); e != nil {
+		errorIf(probe.NewError(e).Trace(q.id), "Unable to save quarantine info.", nil)
+		q.o.storage.DeleteFile(context.Background(), minioMetaVolume, quarantineDataPath(q.id))
+	}
+}
+
+// settle - keeps the copy in quarantine if the upload failed
+// verification with err, discards it otherwise.
+func (q *quarantineWriter) settle(err error, info quarantineInfo) {
+	switch e := err.(type) {
+	case BadDigest:
+		info.Reason = quarantineReasonBadDigest
+		info.Expected = e.ExpectedMD5
+	case IncompleteBody:
+		info.Reason = quarantineReasonIncompleteBody
+	default:
+		q.abort()
+		return
+	}
+	q.commit(info)
+}
+
+// ListQuarantine - returns quarantined uploads, oldest first.
+func (o objectAPI) ListQuarantine() ([]quarantineInfo, *probe.Error) {
+	infos := []quarantineInfo{}
+	marker := ""
+	for {
+		fileInfos, eof, e := o.storage.ListFiles(minioMetaVolume, quarantinePrefix+slashSeparator, marker, false, quarantineListBatchSize)
+		if e != nil {
+			if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+				break
+			}
+			return nil, probe.NewError(e)
+		}
+		for _, fileInfo := range fileInfos {
+			marker = fileInfo.Name
+			if !strings.HasSuffix(fileInfo.Name, ".json") {
+				continue
+			}
+			info, err := o.GetQuarantine(strings.TrimSuffix(path.Base(fileInfo.Name), ".json"))
+			if err != nil {
+				return nil, err.Trace(fileInfo.Name)
+			}
+			infos = append(infos, info)
+		}
+		if eof || len(fileInfos) == 0 {
+			break
+		}
+	}
+	sort.Sort(byQuarantineTime(infos))
+	return infos, nil
+}
+
+// isValidQuarantineID - ids are uuids, never paths.
+func isValidQuarantineID(id string) bool {
+	_, e := uuid.Parse(id)
+	return e == nil
+}
+
+// GetQuarantine - returns info of the quarantined upload with id.
+func (o objectAPI) GetQuarantine(id string) (quarantineInfo, *probe.Error) {
+	if !isValidQuarantineID(id) {
+		return quarantineInfo{}, probe.NewError(QuarantineNotFound{ID: id})
+	}
+	infoBytes, e := o.readMetaFile(quarantineInfoPath(id), maxQuarantineInfoSize)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return quarantineInfo{}, probe.NewError(QuarantineNotFound{ID: id})
+		}
+		return quarantineInfo{}, probe.NewError(e)
+	}
+	info := quarantineInfo{}
+	if e = json.Unmarshal(infoBytes, &info); e != nil {
+		return quarantineInfo{}, probe.NewError(e)
+	}
+	return info, nil
+}
+
+// GetQuarantineData - returns reader of the bytes received of the
+// quarantined upload with id.
+func (o objectAPI) GetQuarantineData(id string) (io.ReadCloser, *probe.Error) {
+	if _, err := o.GetQuarantine(id); err != nil {
+		return nil, err.Trace(id)
+	}
+	r, e := o.storage.ReadFile(o.context(), minioMetaVolume, quarantineDataPath(id), 0)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	return r, nil
+}
+
+// RecoverQuarantine - writes the bytes received of the quarantined
+// upload with id as object, or as its part if it was a part of a
+// multipart upload still in progress, and removes it from quarantine.
+// Bytes are not verified against the digest the client sent, only
+// against what was received. Empty bucket or object recover to those
+// of the original upload.
+func (o objectAPI) RecoverQuarantine(id, bucket, object string) (string, *probe.Error) {
+	info, err := o.GetQuarantine(id)
+	if err != nil {
+		return "", err.Trace(id)
+	}
+	isPart := info.UploadID != "" && bucket == "" && object == ""
+	if bucket == "" {
+		bucket = info.Bucket
+	}
+	if object == "" {
+		object = info.Object
+	}
+	r, e := o.storage.ReadFile(o.context(), minioMetaVolume, quarantineDataPath(id), 0)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer r.Close()
+	var md5Sum string
+	if isPart {
+		md5Sum, err = o.PutObjectPart(bucket, object, info.UploadID, info.PartID, info.Size, r, info.Received)
+	} else {
+		metadata := make(map[string]string)
+		for k, v := range info.Metadata {
+			metadata[k] = v
+		}
+		metadata["md5Sum"] = info.Received
+		md5Sum, err = o.PutObject(bucket, object, info.Size, r, metadata)
+	}
+	if err != nil {
+		return "", err.Trace(id, bucket, object)
+	}
+	if err = o.RemoveQuarantine(id); err != nil {
+		return "", err.Trace(id)
+	}
+	return md5Sum, nil
+}
+
+// RemoveQuarantine - discards the quarantined upload with id.
+func (o objectAPI) RemoveQuarantine(id string) *probe.Error {
+	if _, err := o.GetQuarantine(id); err != nil {
+		return err.Trace(id)
+	}
+	if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, quarantineInfoPath(id)); e != nil {
+		return probe.NewError(e)
+	}
+	if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, quarantineDataPath(id)); e != nil && errorCause(e) != errFileNotFound {
+		return probe.NewError(e)
+	}
+	return nil
+}
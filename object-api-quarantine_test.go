@@ -0,0 +1,162 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+// Tests uploads failing verification are quarantined and can be
+// recovered, while successful uploads leave nothing behind.
+func TestQuarantine(t *testing.T) {
+	disk, e := ioutil.TempDir("", "minio-quarantine-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(disk)
+	fs, e := newFS(disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	sum := md5.Sum(data)
+	md5Hex := hex.EncodeToString(sum[:])
+	badMD5Hex := hex.EncodeToString(make([]byte, md5.Size))
+
+	// Nothing is quarantined while disabled.
+	if _, err := obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), map[string]string{"md5Sum": badMD5Hex}); err == nil {
+		t.Fatal("Expected bad digest")
+	}
+	if infos, err := obj.ListQuarantine(); err != nil || len(infos) != 0 {
+		t.Fatalf("Expected empty quarantine, got %v %v", infos, err)
+	}
+
+	obj.SetQuarantine(quarantineConfig{Enable: true, MaxSize: 1024})
+	if _, err := obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), map[string]string{"md5Sum": md5Hex}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.PutObject("bucket", "large", 2048, bytes.NewReader(make([]byte, 2048)), map[string]string{"md5Sum": badMD5Hex}); err == nil {
+		t.Fatal("Expected bad digest")
+	}
+	fileInfos, _, e := fs.ListFiles(minioMetaVolume, quarantinePrefix+slashSeparator, "", false, 1000)
+	if e != nil && errorCause(e) != errFileNotFound {
+		t.Fatal(e)
+	}
+	if len(fileInfos) != 0 {
+		t.Fatalf("Expected successful and too large uploads not quarantined, got %v", fileInfos)
+	}
+
+	_, err := obj.PutObject("bucket", "bad", int64(len(data)), bytes.NewReader(data), map[string]string{"md5Sum": badMD5Hex, "content-type": "text/plain"})
+	if _, ok := err.ToGoError().(BadDigest); !ok {
+		t.Fatalf("Expected bad digest, got %v", err)
+	}
+	// Client disconnected before sending all of the content length.
+	truncated := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(io.ErrUnexpectedEOF))
+	_, err = obj.PutObject("bucket", "truncated", 100, truncated, nil)
+	if _, ok := err.ToGoError().(IncompleteBody); !ok {
+		t.Fatalf("Expected incomplete body, got %v", err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "multipart", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.PutObjectPart("bucket", "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), badMD5Hex); err == nil {
+		t.Fatal("Expected bad digest")
+	}
+
+	infos, err := obj.ListQuarantine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("Expected 3 quarantined uploads, got %+v", infos)
+	}
+	quarantined := make(map[string]quarantineInfo)
+	for _, info := range infos {
+		if info.Size != int64(len(data)) || info.Received != md5Hex {
+			t.Errorf("Unexpected quarantined upload %+v", info)
+		}
+		quarantined[info.Object] = info
+	}
+	if info := quarantined["bad"]; info.Reason != quarantineReasonBadDigest || info.Expected != badMD5Hex || info.Metadata["content-type"] != "text/plain" {
+		t.Errorf("Unexpected quarantined upload %+v", info)
+	}
+	if info := quarantined["truncated"]; info.Reason != quarantineReasonIncompleteBody {
+		t.Errorf("Unexpected quarantined upload %+v", info)
+	}
+	if info := quarantined["multipart"]; info.UploadID != uploadID || info.PartID != 1 {
+		t.Errorf("Unexpected quarantined upload %+v", info)
+	}
+
+	r, err := obj.GetQuarantineData(quarantined["truncated"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || !bytes.Equal(received, data) {
+		t.Fatalf("Expected %q, got %q %v", data, received, e)
+	}
+
+	// Recover to the original object, another object and the part.
+	if _, err = obj.RecoverQuarantine(quarantined["bad"].ID, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.RecoverQuarantine(quarantined["truncated"].ID, "bucket", "recovered"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.RecoverQuarantine(quarantined["multipart"].ID, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, object := range []string{"bad", "recovered"} {
+		objInfo, err := obj.GetObjectInfo("bucket", object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objInfo.Size != int64(len(data)) {
+			t.Errorf("Expected size %d, got %d", len(data), objInfo.Size)
+		}
+	}
+	parts, err := obj.ListObjectParts("bucket", "multipart", uploadID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts.Parts) != 1 || parts.Parts[0].ETag != md5Hex {
+		t.Fatalf("Expected recovered part, got %+v", parts.Parts)
+	}
+
+	if infos, err = obj.ListQuarantine(); err != nil || len(infos) != 0 {
+		t.Fatalf("Expected empty quarantine, got %v %v", infos, err)
+	}
+	if err = obj.RemoveQuarantine(quarantined["bad"].ID); err == nil {
+		t.Fatal("Expected recovered upload to be removed")
+	}
+	if _, err = obj.GetQuarantine("../bucket/bad"); err == nil {
+		t.Fatal("Expected invalid quarantine id")
+	}
+}
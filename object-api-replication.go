@@ -0,0 +1,292 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+	"github.com/minio/minio/pkg/s3"
+)
+
+const (
+	// Replication status is saved under this prefix in
+	// minioMetaVolume, bucket names cannot start with a '.'.
+	replicationStatusPrefix = ".replication"
+
+	// Maximum size of saved replication status.
+	maxReplicationStatusSize = 1024
+
+	// Replication status header of GET and HEAD Object.
+	replicationStatusKey = "X-Amz-Replication-Status"
+
+	// Replication statuses.
+	replicationPending   = "PENDING"
+	replicationCompleted = "COMPLETED"
+	replicationFailed    = "FAILED"
+
+	// Replication target id registered with the event notifier.
+	replicationTargetID = "replication"
+
+	// Number of attempts made to replicate an object.
+	maxReplicationAttempts = 3
+)
+
+// Delay between two replication attempts, multiplied by the attempt.
+var replicationRetryDelay = time.Second
+
+// replicationStatus - replication status of the object version last
+// modified at ModTime.
+type replicationStatus struct {
+	Status  string    `json:"status"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// replicationStatusPath - returns replication status path in
+// minioMetaVolume.
+func replicationStatusPath(bucket, object string) string {
+	return path.Join(replicationStatusPrefix, bucket, object)
+}
+
+// getReplicationStatus - returns replication status of object last
+// modified at modTime, empty if object is not replicated.
+func (o objectAPI) getReplicationStatus(bucket, object string, modTime time.Time) string {
+	statusBytes, e := o.readMetaFile(replicationStatusPath(bucket, object), maxReplicationStatusSize)
+	if e != nil {
+		if errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+			errorIf(probe.NewError(e).Trace(bucket, object), "Unable to read replication status.", nil)
+		}
+		return ""
+	}
+	var status replicationStatus
+	if e = json.Unmarshal(statusBytes, &status); e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to parse replication status.", nil)
+		return ""
+	}
+	// Status of a previous version of the object is stale.
+	if !status.ModTime.Equal(modTime) {
+		return ""
+	}
+	return status.Status
+}
+
+// writeReplicationStatus - saves replication status of object version
+// last modified at modTime.
+func (o objectAPI) writeReplicationStatus(bucket, object string, status replicationStatus) error {
+	statusBytes, e := json.Marshal(status)
+	if e != nil {
+		return e
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(replicationStatusPath(bucket, object), statusBytes)
+}
+
+// removeReplicationStatus - removes replication status of a deleted
+// object.
+func (o objectAPI) removeReplicationStatus(bucket, object string) {
+	e := o.storage.DeleteFile(context.Background(), minioMetaVolume, replicationStatusPath(bucket, object))
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to remove replication status.", nil)
+	}
+}
+
+// markReplicationPending - marks a newly written object as pending
+// replication if its bucket is replicated.
+func (o objectAPI) markReplicationPending(bucket, object string) {
+	config, ok, err := getBucketReplication(bucket)
+	if err != nil {
+		errorIf(err.Trace(bucket), "Unable to read bucket replication.", nil)
+		return
+	}
+	if !ok || !config.matches(object) {
+		return
+	}
+	fi, e := o.storage.StatFile(bucket, object)
+	if e == nil {
+		e = o.writeReplicationStatus(bucket, object, replicationStatus{
+			Status:  replicationPending,
+			ModTime: fi.ModTime,
+		})
+	}
+	errorIf(probe.NewError(e).Trace(bucket, object), "Unable to mark object pending replication.", nil)
+}
+
+// updateReplicationStatus - saves status of object version last
+// modified at modTime, unless the object was overwritten since.
+func (o objectAPI) updateReplicationStatus(bucket, object, status string, modTime time.Time) {
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil || !fi.ModTime.Equal(modTime) {
+		return
+	}
+	e = o.writeReplicationStatus(bucket, object, replicationStatus{
+		Status:  status,
+		ModTime: modTime,
+	})
+	errorIf(probe.NewError(e).Trace(bucket, object), "Unable to save replication status.", nil)
+}
+
+// hashReplica - returns hex encoded sha256 of object data.
+func (o objectAPI) hashReplica(bucket, object string) (string, *probe.Error) {
+	r, err := o.GetObject(bucket, object, 0)
+	if err != nil {
+		return "", err.Trace(bucket, object)
+	}
+	defer r.Close()
+	sha256Hash := sha256.New()
+	if _, e := io.Copy(sha256Hash, r); e != nil {
+		return "", probe.NewError(e)
+	}
+	return hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
+
+// ReplicateObject - copies object to the replication destination of
+// its bucket, objects of buckets which are not replicated and objects
+// deleted since are left alone. Replication status of the object is
+// updated with the outcome.
+func (o objectAPI) ReplicateObject(bucket, object string) *probe.Error {
+	config, ok, err := getBucketReplication(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if !ok || !config.matches(object) {
+		return nil
+	}
+	// Status refers to the local data, which differs from object info
+	// of transitioned objects.
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil {
+		if errorCause(e) == errFileNotFound {
+			return nil
+		}
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	objInfo, err := o.GetObjectInfo(bucket, object)
+	if err != nil {
+		return err.Trace(bucket, object)
+	}
+	if err = o.replicate(config.Destination, objInfo); err != nil {
+		o.updateReplicationStatus(bucket, object, replicationFailed, fi.ModTime)
+		return err.Trace(bucket, object)
+	}
+	o.updateReplicationStatus(bucket, object, replicationCompleted, fi.ModTime)
+	return nil
+}
+
+// replicate - uploads object to destination, signature requires the
+// payload checksum upfront so data is read twice.
+func (o objectAPI) replicate(destination replicationDestination, objInfo ObjectInfo) *probe.Error {
+	sha256Hex, err := o.hashReplica(objInfo.Bucket, objInfo.Name)
+	if err != nil {
+		return err.Trace()
+	}
+	client, e := destination.newClient()
+	if e != nil {
+		return probe.NewError(e)
+	}
+	r, err := o.GetObject(objInfo.Bucket, objInfo.Name, 0)
+	if err != nil {
+		return err.Trace()
+	}
+	defer r.Close()
+	if _, e = client.PutObject(destination.Bucket, objInfo.Name, r, objInfo.Size, sha256Hex); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// RemoveReplica - deletes replica of a deleted object from the
+// replication destination of its bucket.
+func (o objectAPI) RemoveReplica(bucket, object string) *probe.Error {
+	config, ok, err := getBucketReplication(bucket)
+	if err != nil {
+		return err.Trace(bucket)
+	}
+	if !ok || !config.matches(object) {
+		return nil
+	}
+	client, e := config.Destination.newClient()
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = client.DeleteObject(config.Destination.Bucket, object); e != nil && !s3.IsNotFound(e) {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// replicationTarget - notification target replicating created and
+// removed objects, the target queue is the replication queue.
+type replicationTarget struct {
+	objAPI objectAPI
+}
+
+// Send - replicates the object of entry, retrying failed attempts.
+func (t replicationTarget) Send(entry eventLogEntry) error {
+	// Entry key is "bucket/object", alarms have no object.
+	bucketObject := strings.SplitN(entry.Key, "/", 2)
+	if len(bucketObject) != 2 || bucketObject[1] == "" {
+		return nil
+	}
+	bucket, object := bucketObject[0], bucketObject[1]
+	var replicate func(bucket, object string) *probe.Error
+	switch {
+	case strings.HasPrefix(entry.EventType, "s3:ObjectCreated:"):
+		replicate = t.objAPI.ReplicateObject
+	case strings.HasPrefix(entry.EventType, "s3:ObjectRemoved:"):
+		replicate = t.objAPI.RemoveReplica
+	default:
+		return nil
+	}
+	var err *probe.Error
+	for attempt := 1; attempt <= maxReplicationAttempts; attempt++ {
+		if err = replicate(bucket, object); err == nil {
+			log.WithFields(logrus.Fields{
+				"bucket": bucket,
+				"object": object,
+				"event":  entry.EventType,
+			}).Debug("Replicated object.")
+			return nil
+		}
+		if attempt < maxReplicationAttempts {
+			time.Sleep(time.Duration(attempt) * replicationRetryDelay)
+		}
+	}
+	return err.ToGoError()
+}
+
+// Close - nothing to release.
+func (t replicationTarget) Close() error {
+	return nil
+}
+
+// initReplication - registers the replication target with the event
+// notifier, created and removed objects of replicated buckets are
+// queued for replication.
+func initReplication(objAPI objectAPI) {
+	globalEventNotifier.AddTarget(replicationTargetID, replicationTarget{objAPI: objAPI})
+}
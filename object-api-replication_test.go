@@ -0,0 +1,191 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeReplicationServer - S3 compatible destination keeping objects
+// in memory, all requests fail while failing is set.
+type fakeReplicationServer struct {
+	mutex   *sync.Mutex
+	objects map[string][]byte
+	failing bool
+}
+
+func (f *fakeReplicationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = data
+		w.Header().Set("ETag", "\"etag\"")
+	case "DELETE":
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Tests replication configuration validation.
+func TestParseBucketReplication(t *testing.T) {
+	testCases := []struct {
+		config string
+		valid  bool
+	}{
+		{`<ReplicationConfiguration><Destination><Endpoint>localhost:9000</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey><Bucket>replica</Bucket></Destination></ReplicationConfiguration>`, true},
+		{`<ReplicationConfiguration><Prefix>logs/</Prefix><Destination><Endpoint>s3.amazonaws.com</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey><Secure>true</Secure><Bucket>replica</Bucket></Destination></ReplicationConfiguration>`, true},
+		{`<ReplicationConfiguration><Destination><AccessKey>access</AccessKey><SecretKey>secret</SecretKey><Bucket>replica</Bucket></Destination></ReplicationConfiguration>`, false},
+		{`<ReplicationConfiguration><Destination><Endpoint>localhost:9000</Endpoint><AccessKey>access</AccessKey><Bucket>replica</Bucket></Destination></ReplicationConfiguration>`, false},
+		{`<ReplicationConfiguration><Destination><Endpoint>localhost:9000</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey><Bucket>a</Bucket></Destination></ReplicationConfiguration>`, false},
+	}
+	for i, testCase := range testCases {
+		_, e := parseBucketReplication([]byte(testCase.config))
+		if (e == nil) != testCase.valid {
+			t.Errorf("Test %d: Expected valid %v, got error %v", i+1, testCase.valid, e)
+		}
+	}
+}
+
+// Tests replication of created and removed objects along with their
+// replication status.
+func TestReplicateObject(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-replication-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	// Bucket configuration is saved in the config path.
+	configPath, e := ioutil.TempDir("", "minio-replication-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	savedRetryDelay := replicationRetryDelay
+	replicationRetryDelay = 0
+	defer func() { replicationRetryDelay = savedRetryDelay }()
+
+	destination := &fakeReplicationServer{mutex: &sync.Mutex{}, objects: make(map[string][]byte)}
+	server := httptest.NewServer(destination)
+	defer server.Close()
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	putObject := func(object, data string) {
+		if _, err := obj.PutObject("bucket", object, int64(len(data)), bytes.NewBufferString(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replicationStatus := func(object string) string {
+		objInfo, err := obj.GetObjectInfo("bucket", object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return objInfo.ReplicationStatus
+	}
+
+	// Objects of buckets which are not replicated have no status.
+	putObject("logs/1", "hello")
+	if status := replicationStatus("logs/1"); status != "" {
+		t.Fatalf("Expected no replication status, got %s", status)
+	}
+
+	config := `<ReplicationConfiguration><Prefix>logs/</Prefix><Destination><Endpoint>` +
+		strings.TrimPrefix(server.URL, "http://") +
+		`</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey><Bucket>replica</Bucket></Destination></ReplicationConfiguration>`
+	if err := writeBucketReplication("bucket", []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	putObject("logs/1", "hello")
+	putObject("data/1", "hello")
+	if status := replicationStatus("logs/1"); status != replicationPending {
+		t.Fatalf("Expected replication status %s, got %s", replicationPending, status)
+	}
+	if status := replicationStatus("data/1"); status != "" {
+		t.Fatalf("Expected no replication status outside prefix, got %s", status)
+	}
+
+	target := replicationTarget{objAPI: obj}
+	created := eventLogEntry{EventType: ObjectCreatedPut.String(), Key: "bucket/logs/1"}
+	if e = target.Send(created); e != nil {
+		t.Fatal(e)
+	}
+	if status := replicationStatus("logs/1"); status != replicationCompleted {
+		t.Fatalf("Expected replication status %s, got %s", replicationCompleted, status)
+	}
+	if data := string(destination.objects["/replica/logs/1"]); data != "hello" {
+		t.Fatalf("Expected replica with data hello, got %q", data)
+	}
+
+	// Failed replication is reported, the object is replicated again
+	// once written again.
+	putObject("logs/1", "hello world")
+	destination.failing = true
+	if e = target.Send(created); e == nil {
+		t.Fatal("Expected error replicating to a failing destination")
+	}
+	if status := replicationStatus("logs/1"); status != replicationFailed {
+		t.Fatalf("Expected replication status %s, got %s", replicationFailed, status)
+	}
+	destination.failing = false
+	if e = target.Send(created); e != nil {
+		t.Fatal(e)
+	}
+	if data := string(destination.objects["/replica/logs/1"]); data != "hello world" {
+		t.Fatalf("Expected replica with data hello world, got %q", data)
+	}
+
+	// Removed objects are removed from the destination.
+	if err := obj.DeleteObject("bucket", "logs/1", false); err != nil {
+		t.Fatal(err)
+	}
+	if e = target.Send(eventLogEntry{EventType: ObjectRemovedDelete.String(), Key: "bucket/logs/1"}); e != nil {
+		t.Fatal(e)
+	}
+	if _, ok := destination.objects["/replica/logs/1"]; ok {
+		t.Fatal("Expected replica to be removed")
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Maximum size of retention document.
+	maxRetentionSize = 4 * 1024
+
+	// Retention modes, governance retention can be bypassed while
+	// compliance retention cannot be shortened or removed by anyone.
+	retentionGovernance = "GOVERNANCE"
+	retentionCompliance = "COMPLIANCE"
+)
+
+// Object lock metadata keys of PutObject.
+const (
+	objectLockModeKey        = "X-Amz-Object-Lock-Mode"
+	objectLockRetainUntilKey = "X-Amz-Object-Lock-Retain-Until-Date"
+	objectLockLegalHoldKey   = "X-Amz-Object-Lock-Legal-Hold"
+	bypassGovernanceKey      = "X-Amz-Bypass-Governance-Retention"
+)
+
+var (
+	errInvalidRetentionMode = errors.New("Retention mode should be either GOVERNANCE or COMPLIANCE")
+	errInvalidRetainUntil   = errors.New("Retain until date should be in the future in ISO 8601 format")
+	errIncompleteRetention  = errors.New("Retention should have both a mode and a retain until date")
+)
+
+// objectRetention - retention of a single object.
+type objectRetention struct {
+	XMLName         xml.Name  `xml:"Retention" json:"-"`
+	Mode            string    `xml:"Mode" json:"mode"`
+	RetainUntilDate time.Time `xml:"RetainUntilDate" json:"retainUntilDate"`
+}
+
+// isValidRetentionMode - returns true for supported retention modes.
+func isValidRetentionMode(mode string) bool {
+	return mode == retentionGovernance || mode == retentionCompliance
+}
+
+// Validate - validates retention set at now.
+func (r objectRetention) Validate(now time.Time) error {
+	if !isValidRetentionMode(r.Mode) {
+		return errInvalidRetentionMode
+	}
+	if !r.RetainUntilDate.After(now) {
+		return errInvalidRetainUntil
+	}
+	return nil
+}
+
+// isActive - returns true if object is retained at now.
+func (r objectRetention) isActive(now time.Time) bool {
+	return now.Before(r.RetainUntilDate)
+}
+
+// allows - returns true if retention active at now may be replaced
+// by next, nil next removes the retention. Retention may always be
+// extended, governance retention may be bypassed.
+func (r objectRetention) allows(next *objectRetention, bypassGovernance bool, now time.Time) bool {
+	if !r.isActive(now) {
+		return true
+	}
+	if r.Mode == retentionGovernance && bypassGovernance {
+		return true
+	}
+	if next == nil || next.RetainUntilDate.Before(r.RetainUntilDate) {
+		return false
+	}
+	// Compliance retention cannot be relaxed to governance.
+	return r.Mode == retentionGovernance || next.Mode == retentionCompliance
+}
+
+// getObjectRetention - returns retention saved in metadata of object,
+// false if the object has no retention.
+func getObjectRetention(metadata map[string]string) (objectRetention, bool, error) {
+	value, ok := metadata[objectRetentionKey]
+	if !ok {
+		return objectRetention{}, false, nil
+	}
+	var retention objectRetention
+	if e := json.Unmarshal([]byte(value), &retention); e != nil {
+		return objectRetention{}, false, e
+	}
+	return retention, true, nil
+}
+
+// setObjectRetention - saves retention in metadata of object, nil
+// retention removes saved retention.
+func setObjectRetention(metadata map[string]string, retention *objectRetention) error {
+	if retention == nil {
+		delete(metadata, objectRetentionKey)
+		return nil
+	}
+	retentionBytes, e := json.Marshal(retention)
+	if e != nil {
+		return e
+	}
+	metadata[objectRetentionKey] = string(retentionBytes)
+	return nil
+}
+
+// objectLock - retention and legal hold of an object.
+type objectLock struct {
+	Retention *objectRetention
+	LegalHold bool
+}
+
+// getObjectLock - returns retention and legal hold saved in metadata
+// of object.
+func getObjectLock(metadata map[string]string) (objectLock, error) {
+	lock := objectLock{LegalHold: metadata[objectLegalHoldKey] == legalHoldOn}
+	retention, ok, e := getObjectRetention(metadata)
+	if e != nil {
+		return objectLock{}, e
+	}
+	if ok {
+		lock.Retention = &retention
+	}
+	return lock, nil
+}
+
+// setObjectLock - saves retention and legal hold in metadata of
+// object, both are removed if not set.
+func setObjectLock(metadata map[string]string, lock objectLock) error {
+	setObjectLegalHold(metadata, lock.LegalHold)
+	return setObjectRetention(metadata, lock.Retention)
+}
+
+// checkObjectLock - returns ObjectLocked if the retention of object
+// does not allow it to be replaced by an object with retention next,
+// nil next deletes or overwrites the object which legal hold forbids.
+func (o objectAPI) checkObjectLock(bucket, object string, next *objectRetention, bypassGovernance bool) *probe.Error {
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	lock, e := getObjectLock(metadata)
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	if next == nil && lock.LegalHold {
+		return probe.NewError(ObjectLocked{Bucket: bucket, Object: object})
+	}
+	if lock.Retention != nil && !lock.Retention.allows(next, bypassGovernance, time.Now().UTC()) {
+		return probe.NewError(ObjectLocked{Bucket: bucket, Object: object})
+	}
+	return nil
+}
+
+// newObjectLock - returns object lock of an object about to be
+// written with metadata, retention is either set by metadata or the
+// bucket default retention. Returns ObjectLocked if the current
+// object is retained or held.
+func (o objectAPI) newObjectLock(bucket, object string, metadata map[string]string) (objectLock, *probe.Error) {
+	now := time.Now().UTC()
+	config, enabled, err := getBucketObjectLock(bucket)
+	if err != nil {
+		return objectLock{}, err.Trace(bucket)
+	}
+	var legalHold bool
+	if status := metadata[objectLockLegalHoldKey]; status != "" {
+		if !enabled {
+			return objectLock{}, probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+		}
+		if !isValidLegalHoldStatus(status) {
+			return objectLock{}, probe.NewError(InvalidLegalHold{Status: status})
+		}
+		legalHold = status == legalHoldOn
+	}
+	mode, retainUntil := metadata[objectLockModeKey], metadata[objectLockRetainUntilKey]
+	var retention *objectRetention
+	switch {
+	case mode == "" && retainUntil == "":
+		if enabled {
+			retention = config.defaultRetention(now)
+		}
+	case mode == "" || retainUntil == "":
+		return objectLock{}, probe.NewError(InvalidRetention{Reason: errIncompleteRetention.Error()})
+	default:
+		if !enabled {
+			return objectLock{}, probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+		}
+		date, e := time.Parse(time.RFC3339, retainUntil)
+		if e != nil {
+			return objectLock{}, probe.NewError(InvalidRetention{Reason: errInvalidRetainUntil.Error()})
+		}
+		retention = &objectRetention{Mode: strings.ToUpper(mode), RetainUntilDate: date.UTC()}
+		if e = retention.Validate(now); e != nil {
+			return objectLock{}, probe.NewError(InvalidRetention{Reason: e.Error()})
+		}
+	}
+	// Overwriting removes the current retention.
+	bypassGovernance := strings.EqualFold(metadata[bypassGovernanceKey], "true")
+	if err = o.checkObjectLock(bucket, object, nil, bypassGovernance); err != nil {
+		return objectLock{}, err.Trace(bucket, object)
+	}
+	return objectLock{Retention: retention, LegalHold: legalHold}, nil
+}
+
+// GetObjectRetention - returns retention of an object.
+func (o objectAPI) GetObjectRetention(bucket, object string) (objectRetention, *probe.Error) {
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return objectRetention{}, err.Trace(bucket, object)
+	}
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		return objectRetention{}, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	retention, ok, e := getObjectRetention(metadata)
+	if e != nil {
+		return objectRetention{}, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	if !ok {
+		return objectRetention{}, probe.NewError(ObjectRetentionNotFound{Bucket: bucket, Object: object})
+	}
+	return retention, nil
+}
+
+// PutObjectRetention - replaces retention of an object in a bucket
+// with object lock enabled. Active retention may only be extended
+// unless governance retention is bypassed.
+func (o objectAPI) PutObjectRetention(bucket, object string, retention objectRetention, bypassGovernance bool) *probe.Error {
+	lock := o.sequences.lock(bucket, object)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return err.Trace(bucket, object)
+	}
+	if _, enabled, err := getBucketObjectLock(bucket); err != nil {
+		return err.Trace(bucket)
+	} else if !enabled {
+		return probe.NewError(BucketObjectLockNotFound{Bucket: bucket})
+	}
+	retention.RetainUntilDate = retention.RetainUntilDate.UTC()
+	if e := retention.Validate(time.Now().UTC()); e != nil {
+		return probe.NewError(InvalidRetention{Reason: e.Error()})
+	}
+	if err := o.checkObjectLock(bucket, object, &retention, bypassGovernance); err != nil {
+		return err.Trace(bucket, object)
+	}
+	e := o.updateObjectMetadata(bucket, object, func(metadata map[string]string) error {
+		return setObjectRetention(metadata, &retention)
+	})
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests object lock configuration validation.
+func TestObjectLockConfigurationValidate(t *testing.T) {
+	testCases := []struct {
+		config string
+		valid  bool
+	}{
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`, true},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, true},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Years>7</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, true},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Disabled</ObjectLockEnabled></ObjectLockConfiguration>`, false},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule></Rule></ObjectLockConfiguration>`, false},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>LEGAL</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, false},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, false},
+		{`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode></DefaultRetention></Rule></ObjectLockConfiguration>`, false},
+	}
+	for i, testCase := range testCases {
+		_, e := parseBucketObjectLock([]byte(testCase.config))
+		if (e == nil) != testCase.valid {
+			t.Errorf("Test %d: Expected valid %v, got error %v", i+1, testCase.valid, e)
+		}
+	}
+}
+
+// Tests which retention changes are allowed.
+func TestObjectRetentionAllows(t *testing.T) {
+	now := time.Date(2016, time.May, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+	evenLater := later.Add(24 * time.Hour)
+	governance := objectRetention{Mode: retentionGovernance, RetainUntilDate: later}
+	compliance := objectRetention{Mode: retentionCompliance, RetainUntilDate: later}
+	expired := objectRetention{Mode: retentionCompliance, RetainUntilDate: now.Add(-time.Second)}
+
+	testCases := []struct {
+		current objectRetention
+		next    *objectRetention
+		bypass  bool
+		allowed bool
+	}{
+		{expired, nil, false, true},
+		{governance, nil, false, false},
+		{governance, nil, true, true},
+		{governance, &objectRetention{Mode: retentionGovernance, RetainUntilDate: now.Add(time.Hour)}, false, false},
+		{governance, &objectRetention{Mode: retentionGovernance, RetainUntilDate: evenLater}, false, true},
+		{governance, &objectRetention{Mode: retentionCompliance, RetainUntilDate: later}, false, true},
+		{compliance, nil, true, false},
+		{compliance, &objectRetention{Mode: retentionCompliance, RetainUntilDate: evenLater}, false, true},
+		{compliance, &objectRetention{Mode: retentionGovernance, RetainUntilDate: evenLater}, true, false},
+		{compliance, &objectRetention{Mode: retentionCompliance, RetainUntilDate: now.Add(time.Hour)}, true, false},
+	}
+	for i, testCase := range testCases {
+		if allowed := testCase.current.allows(testCase.next, testCase.bypass, now); allowed != testCase.allowed {
+			t.Errorf("Test %d: Expected allowed %v, got %v", i+1, testCase.allowed, allowed)
+		}
+	}
+}
+
+// Tests retention enforcement on overwrite and delete.
+func TestObjectRetentionEnforced(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-retention-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	// Bucket configuration is saved in the config path.
+	configPath, e := ioutil.TempDir("", "minio-retention-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	putObject := func(object string, metadata map[string]string) error {
+		_, err := obj.PutObject("bucket", object, int64(len("hello")), bytes.NewBufferString("hello"), metadata)
+		if err != nil {
+			return err.ToGoError()
+		}
+		return nil
+	}
+	if e = putObject("object", nil); e != nil {
+		t.Fatal(e)
+	}
+
+	// Retention requires object lock on bucket.
+	retainUntil := time.Now().UTC().Add(time.Hour)
+	retention := objectRetention{Mode: retentionGovernance, RetainUntilDate: retainUntil}
+	if err := obj.PutObjectRetention("bucket", "object", retention, false); err == nil {
+		t.Fatal("Expected error without object lock")
+	} else if _, ok := err.ToGoError().(BucketObjectLockNotFound); !ok {
+		t.Fatalf("Expected BucketObjectLockNotFound, got %s", err)
+	}
+
+	config := `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`
+	if err := writeBucketObjectLock("bucket", []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Governance retention is bypassed only when asked to.
+	if err := obj.PutObjectRetention("bucket", "object", retention, false); err != nil {
+		t.Fatal(err)
+	}
+	if e = putObject("object", map[string]string{objectLockModeKey: retentionGovernance}); e == nil {
+		t.Fatal("Expected error for retention without retain until date")
+	} else if _, ok := e.(InvalidRetention); !ok {
+		t.Fatalf("Expected InvalidRetention, got %s", e)
+	}
+	if e = putObject("object", nil); e == nil {
+		t.Fatal("Expected error overwriting retained object")
+	} else if _, ok := e.(ObjectLocked); !ok {
+		t.Fatalf("Expected ObjectLocked, got %s", e)
+	}
+	if err := obj.DeleteObject("bucket", "object", false); err == nil {
+		t.Fatal("Expected error deleting retained object")
+	}
+	if err := obj.DeleteObject("bucket", "object", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// New objects get the bucket default compliance retention, which
+	// cannot be bypassed.
+	if e = putObject("object", nil); e != nil {
+		t.Fatal(e)
+	}
+	retention, err := obj.GetObjectRetention("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retention.Mode != retentionCompliance || !retention.RetainUntilDate.After(retainUntil) {
+		t.Fatalf("Unexpected default retention %+v", retention)
+	}
+	if err = obj.DeleteObject("bucket", "object", true); err == nil {
+		t.Fatal("Expected error deleting object under compliance retention")
+	}
+	if e = putObject("object", map[string]string{bypassGovernanceKey: "true"}); e == nil {
+		t.Fatal("Expected error overwriting object under compliance retention")
+	}
+
+	// Explicit retention overrides the bucket default.
+	metadata := map[string]string{
+		objectLockModeKey:        retentionGovernance,
+		objectLockRetainUntilKey: retainUntil.Format(time.RFC3339),
+	}
+	if e = putObject("explicit", metadata); e != nil {
+		t.Fatal(e)
+	}
+	if retention, err = obj.GetObjectRetention("bucket", "explicit"); err != nil {
+		t.Fatal(err)
+	}
+	if retention.Mode != retentionGovernance {
+		t.Fatalf("Expected governance retention, got %+v", retention)
+	}
+}
+
+// Tests legal hold enforcement regardless of retention.
+func TestObjectLegalHoldEnforced(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-legalhold-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	// Bucket configuration is saved in the config path.
+	configPath, e := ioutil.TempDir("", "minio-legalhold-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	putObject := func(object string, metadata map[string]string) error {
+		_, err := obj.PutObject("bucket", object, int64(len("hello")), bytes.NewBufferString("hello"), metadata)
+		if err != nil {
+			return err.ToGoError()
+		}
+		return nil
+	}
+	if e = putObject("object", nil); e != nil {
+		t.Fatal(e)
+	}
+
+	// Legal hold requires object lock on bucket.
+	if err := obj.PutObjectLegalHold("bucket", "object", legalHoldOn); err == nil {
+		t.Fatal("Expected error without object lock")
+	} else if _, ok := err.ToGoError().(BucketObjectLockNotFound); !ok {
+		t.Fatalf("Expected BucketObjectLockNotFound, got %s", err)
+	}
+
+	config := `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`
+	if err := writeBucketObjectLock("bucket", []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.PutObjectLegalHold("bucket", "object", "on"); err == nil {
+		t.Fatal("Expected error for invalid legal hold status")
+	} else if _, ok := err.ToGoError().(InvalidLegalHold); !ok {
+		t.Fatalf("Expected InvalidLegalHold, got %s", err)
+	}
+	if err := obj.PutObjectLegalHold("bucket", "object", legalHoldOn); err != nil {
+		t.Fatal(err)
+	}
+	status, err := obj.GetObjectLegalHold("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != legalHoldOn {
+		t.Fatalf("Expected legal hold %s, got %s", legalHoldOn, status)
+	}
+
+	// Held objects cannot be deleted or overwritten, not even when
+	// bypassing governance retention.
+	if err = obj.DeleteObject("bucket", "object", true); err == nil {
+		t.Fatal("Expected error deleting held object")
+	} else if _, ok := err.ToGoError().(ObjectLocked); !ok {
+		t.Fatalf("Expected ObjectLocked, got %s", err)
+	}
+	if e = putObject("object", map[string]string{bypassGovernanceKey: "true"}); e == nil {
+		t.Fatal("Expected error overwriting held object")
+	}
+
+	// Removing the hold releases the object.
+	if err = obj.PutObjectLegalHold("bucket", "object", legalHoldOff); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.DeleteObject("bucket", "object", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Legal hold set on creation is removed along with the object.
+	if e = putObject("object", map[string]string{objectLockLegalHoldKey: legalHoldOn}); e != nil {
+		t.Fatal(e)
+	}
+	if err = obj.DeleteObject("bucket", "object", false); err == nil {
+		t.Fatal("Expected error deleting held object")
+	}
+	if err = obj.PutObjectLegalHold("bucket", "object", legalHoldOff); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.DeleteObject("bucket", "object", false); err != nil {
+		t.Fatal(err)
+	}
+	if e = putObject("object", nil); e != nil {
+		t.Fatal(e)
+	}
+	if status, err = obj.GetObjectLegalHold("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+	if status != legalHoldOff {
+		t.Fatalf("Expected legal hold %s, got %s", legalHoldOff, status)
+	}
+}
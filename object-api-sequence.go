@@ -0,0 +1,159 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Sequence numbers of deleted keys are saved under this prefix in
+	// minioMetaVolume, existing objects save theirs in their metadata.
+	// A key created again continues its sequence.
+	objectSequencePrefix = ".sequence"
+
+	// Maximum size of a saved sequence number.
+	maxObjectSequenceSize = 32
+
+	// Mutations of keys hashing to the same lock are serialized.
+	objectSequenceLocks = 256
+)
+
+// errSequenceNotSaved - mutation was committed without its sequence
+// number.
+var errSequenceNotSaved = errors.New("Sequence number not saved")
+
+// objectSequencer - serializes mutations of keys with the update of
+// their sequence number, so that sequence numbers follow the order
+// mutations are committed in.
+type objectSequencer struct {
+	locks [objectSequenceLocks]sync.Mutex
+}
+
+func newObjectSequencer() *objectSequencer {
+	return &objectSequencer{}
+}
+
+// lock - returns lock of key object in bucket.
+func (s *objectSequencer) lock(bucket, object string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(bucket + slashSeparator + object))
+	return &s.locks[h.Sum32()%objectSequenceLocks]
+}
+
+// objectSequencePath - returns path of the sequence number of a
+// deleted key in minioMetaVolume.
+func objectSequencePath(bucket, object string) string {
+	return path.Join(objectSequencePrefix, bucket, object)
+}
+
+// formatSequencer - returns sequence as a fixed width hex string,
+// sequencers of a key compare in the same order as their sequence.
+func formatSequencer(sequence uint64) string {
+	return fmt.Sprintf("%016X", sequence)
+}
+
+// WithSequence - returns copy of the object layer which stores the
+// sequence number assigned to the key mutated by its next call in
+// sequence.
+func (o objectAPI) WithSequence(sequence *uint64) objectAPI {
+	o.sequence = sequence
+	return o
+}
+
+// parseSequence - returns saved sequence number, 0 if invalid.
+func parseSequence(bucket, object, value string) uint64 {
+	sequence, e := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Invalid object sequence.", nil)
+		return 0
+	}
+	return sequence
+}
+
+// objectSequence - returns sequence number saved in metadata of
+// object, 0 if none was.
+func objectSequence(bucket, object string, metadata map[string]string) uint64 {
+	value, ok := metadata[objectSequenceKey]
+	if !ok {
+		return 0
+	}
+	return parseSequence(bucket, object, value)
+}
+
+// setObjectSequence - saves sequence number in metadata of object.
+func setObjectSequence(metadata map[string]string, sequence uint64) {
+	metadata[objectSequenceKey] = strconv.FormatUint(sequence, 10)
+}
+
+// getObjectSequence - returns sequence number of the last mutation of
+// key object, 0 if it was never mutated.
+func (o objectAPI) getObjectSequence(bucket, object string) uint64 {
+	if metadata := o.statObjectMetadata(bucket, object); metadata != nil {
+		if _, ok := metadata[objectSequenceKey]; ok {
+			return objectSequence(bucket, object, metadata)
+		}
+	}
+	data, e := o.readMetaFile(objectSequencePath(bucket, object), maxObjectSequenceSize)
+	if e != nil {
+		if errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+			errorIf(probe.NewError(e).Trace(bucket, object), "Unable to read object sequence.", nil)
+		}
+		return 0
+	}
+	return parseSequence(bucket, object, string(data))
+}
+
+// saveDeletedSequence - saves sequence number of the mutation deleting
+// key object.
+func (o objectAPI) saveDeletedSequence(bucket, object string, sequence uint64) error {
+	// Create minio meta volume, if it doesn't exist yet.
+	if e := o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return o.writeMetaFile(objectSequencePath(bucket, object), []byte(strconv.FormatUint(sequence, 10)))
+}
+
+// sequenced - commits a mutation of key object with the next sequence
+// number of the key, commit saves it along with the mutation. Only a
+// failed commit fails the mutation, a committed mutation whose sequence
+// number cannot be saved is left without one, commit returns
+// errSequenceNotSaved then.
+func (o objectAPI) sequenced(bucket, object string, commit func(sequence uint64) error) error {
+	lock := o.sequences.lock(bucket, object)
+	lock.Lock()
+	defer lock.Unlock()
+	sequence := o.getObjectSequence(bucket, object) + 1
+	if e := commit(sequence); e != nil {
+		if e == errSequenceNotSaved {
+			return nil
+		}
+		return e
+	}
+	if o.sequence != nil {
+		*o.sequence = sequence
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Tests every mutation of a key gets the next sequence number of the
+// key, also across deletes, and that it is listed.
+func TestObjectSequence(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-sequence")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(object string) uint64 {
+		var sequence uint64
+		if _, err := obj.WithSequence(&sequence).PutObject("bucket", object, 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+			t.Fatal(err)
+		}
+		return sequence
+	}
+	if sequence := put("object"); sequence != 1 {
+		t.Fatalf("Expected sequence 1, got %d", sequence)
+	}
+	if sequence := put("object"); sequence != 2 {
+		t.Fatalf("Expected sequence 2 on overwrite, got %d", sequence)
+	}
+	var sequence uint64
+	if err := obj.WithSequence(&sequence).DeleteObject("bucket", "object", false); err != nil || sequence != 3 {
+		t.Fatalf("Expected sequence 3 on delete, got %d %v", sequence, err)
+	}
+	if sequence = put("object"); sequence != 4 {
+		t.Fatalf("Expected sequence 4 once created again, got %d", sequence)
+	}
+	// Other keys have their own sequence.
+	if sequence = put("other"); sequence != 1 {
+		t.Fatalf("Expected sequence 1 for another key, got %d", sequence)
+	}
+
+	objInfo, err := obj.GetObjectInfo("bucket", "object")
+	if err != nil || objInfo.Sequence != 4 {
+		t.Fatalf("Expected sequence 4, got %d %v", objInfo.Sequence, err)
+	}
+	result, err := obj.ListObjects("bucket", "", "", "", 10)
+	if err != nil || len(result.Objects) != 2 || result.Objects[0].Sequence != 4 || result.Objects[1].Sequence != 1 {
+		t.Fatalf("Unexpected listing %+v %v", result.Objects, err)
+	}
+	response := generateListObjectsResponse("bucket", "", "", "", "", 10, result)
+	if response.Contents[0].Sequencer != "0000000000000004" {
+		t.Fatalf("Unexpected sequencer %q", response.Contents[0].Sequencer)
+	}
+
+	// Concurrent mutations get distinct sequence numbers, the last
+	// one committed is the sequence of the object.
+	var wg sync.WaitGroup
+	sequences := make([]uint64, 8)
+	for i := range sequences {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sequences[i] = put("concurrent")
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[uint64]bool)
+	for _, sequence := range sequences {
+		if sequence < 1 || sequence > uint64(len(sequences)) || seen[sequence] {
+			t.Fatalf("Unexpected sequences %v", sequences)
+		}
+		seen[sequence] = true
+	}
+	if objInfo, err = obj.GetObjectInfo("bucket", "concurrent"); err != nil || objInfo.Sequence != uint64(len(sequences)) {
+		t.Fatalf("Expected sequence %d, got %d %v", len(sequences), objInfo.Sequence, err)
+	}
+}
+
+// Tests a committed write succeeds even if its metadata and sequence
+// number cannot be saved.
+func TestPutObjectMetadataFailure(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-sequence")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	faults := newStorageFaults()
+	obj := newObjectLayer(newFaultyDisk(fs, faults))
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.PutObject("bucket", "object", 5, bytes.NewReader([]byte("hello")), map[string]string{"X-Amz-Meta-Color": "red"}); err != nil {
+		t.Fatal(err)
+	}
+
+	faults.Set(storageOpCreateFile, storageFault{Err: errTestFault, Path: minioMetaVolume + "/*/bucket/object"})
+	var sequence uint64
+	if _, err := obj.WithSequence(&sequence).PutObject("bucket", "object", 5, bytes.NewReader([]byte("world")), nil); err != nil {
+		t.Fatalf("Expected committed write to succeed, got %v", err)
+	}
+	if sequence != 0 {
+		t.Fatalf("Expected no sequence assigned, got %d", sequence)
+	}
+	faults.Clear()
+	r, err := obj.GetObject("bucket", "object", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, e := ioutil.ReadAll(r); e != nil || string(data) != "world" {
+		t.Fatalf("Expected the object replaced, got %q %v", data, e)
+	}
+}
+
+// Tests listed objects get their state from their metadata alone.
+func TestListObjectsState(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-sequence")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	faults := newStorageFaults()
+	obj := newObjectLayer(newFaultyDisk(fs, faults))
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := obj.PutObject("bucket", "object", 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sequence numbers of existing objects are not read apart.
+	faults.Set(storageOpReadFile, storageFault{Err: errTestFault, Path: minioMetaVolume + "/" + objectSequencePrefix + "/*/*"})
+	result, err := obj.ListObjects("bucket", "", "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 {
+		t.Fatalf("Expected 1 object, got %d", len(result.Objects))
+	}
+	if objInfo := result.Objects[0]; objInfo.Sequence != 2 || objInfo.StorageClass != storageClassStandard {
+		t.Fatalf("Expected sequence 2 and class %s, got %d and %s", storageClassStandard, objInfo.Sequence, objInfo.StorageClass)
+	}
+}
@@ -0,0 +1,366 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Header selecting the read session of GET and HEAD Object requests.
+const readSessionHeader = "X-Minio-Read-Session"
+
+const (
+	// Data of objects overwritten or deleted while read sessions are
+	// open is preserved under this prefix in minioMetaVolume.
+	snapshotDataPrefix = ".snapshots"
+
+	// Default and maximum lifetime of a read session.
+	defaultReadSessionTTL = 15 * time.Minute
+	maxReadSessionTTL     = 12 * time.Hour
+
+	// Maximum number of open read sessions, every write copies the
+	// previous object data once for all sessions.
+	maxReadSessions = 100
+)
+
+// readSessionInfo - read session returned to clients.
+type readSessionInfo struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// snapshotEntry - object as of the cut of a read session.
+type snapshotEntry struct {
+	// Object did not exist at the cut.
+	absent bool
+	// Preserved data path in minioMetaVolume.
+	dataPath string
+	info     ObjectInfo
+}
+
+// readSession - open read session, objects written after the cut
+// are served from the preserved entries.
+type readSession struct {
+	readSessionInfo
+	preserved map[string]snapshotEntry
+}
+
+// snapshotManager - tracks open read sessions. Writers preserve the
+// previous object data for every session before committing, so that
+// a session reads all objects as they were when it was opened, even
+// while writers continue. Listing is not covered by sessions.
+type snapshotManager struct {
+	// Writers hold the read lock while preserving and committing,
+	// opening a session takes the write lock so that every write
+	// commits either entirely before or after the cut.
+	commitLock *sync.RWMutex
+
+	mutex    *sync.Mutex
+	sessions map[string]*readSession
+	// Number of sessions referring to preserved data paths.
+	refs map[string]int
+}
+
+// newSnapshotManager - returns a manager without open sessions.
+func newSnapshotManager() *snapshotManager {
+	return &snapshotManager{
+		commitLock: &sync.RWMutex{},
+		mutex:      &sync.Mutex{},
+		sessions:   make(map[string]*readSession),
+		refs:       make(map[string]int),
+	}
+}
+
+// snapshotKey - returns key of object in preserved entries.
+func snapshotKey(bucket, object string) string {
+	return bucket + slashSeparator + object
+}
+
+// expireSessions - closes sessions past their expiry, returns data
+// paths no longer referred to. Caller holds the mutex.
+func (sm *snapshotManager) expireSessions(now time.Time) (unused []string) {
+	for id, session := range sm.sessions {
+		if now.After(session.Expires) {
+			unused = append(unused, sm.closeSession(id)...)
+		}
+	}
+	return unused
+}
+
+// closeSession - removes session, returns data paths no longer
+// referred to. Caller holds the mutex.
+func (sm *snapshotManager) closeSession(id string) (unused []string) {
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil
+	}
+	delete(sm.sessions, id)
+	for _, entry := range session.preserved {
+		if entry.absent {
+			continue
+		}
+		sm.refs[entry.dataPath]--
+		if sm.refs[entry.dataPath] <= 0 {
+			delete(sm.refs, entry.dataPath)
+			unused = append(unused, entry.dataPath)
+		}
+	}
+	return unused
+}
+
+// lookup - returns the preserved entry of object in session, ok is
+// false if the object has not been written since the cut.
+func (sm *snapshotManager) lookup(id, bucket, object string) (entry snapshotEntry, ok bool, err *probe.Error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session, found := sm.sessions[id]
+	if !found || time.Now().UTC().After(session.Expires) {
+		return snapshotEntry{}, false, probe.NewError(ReadSessionNotFound{ID: id})
+	}
+	entry, ok = session.preserved[snapshotKey(bucket, object)]
+	return entry, ok, nil
+}
+
+// removeSnapshotData - removes preserved data no longer referred to.
+func (o objectAPI) removeSnapshotData(dataPaths []string) {
+	for _, dataPath := range dataPaths {
+		if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, dataPath); e != nil && errorCause(e) != errFileNotFound {
+			errorIf(probe.NewError(e).Trace(dataPath), "Unable to remove snapshot data.", nil)
+		}
+	}
+}
+
+// OpenReadSession - opens a read session cut at the current state of
+// all objects, ttl is capped to the maximum session lifetime.
+func (o objectAPI) OpenReadSession(ttl time.Duration) (readSessionInfo, *probe.Error) {
+	if ttl <= 0 {
+		ttl = defaultReadSessionTTL
+	}
+	if ttl > maxReadSessionTTL {
+		ttl = maxReadSessionTTL
+	}
+	uid, e := uuid.New()
+	if e != nil {
+		return readSessionInfo{}, probe.NewError(e)
+	}
+	sm := o.snapshots
+	// Wait for writes in progress to commit.
+	sm.commitLock.Lock()
+	defer sm.commitLock.Unlock()
+
+	sm.mutex.Lock()
+	now := time.Now().UTC()
+	unused := sm.expireSessions(now)
+	if len(sm.sessions) >= maxReadSessions {
+		sm.mutex.Unlock()
+		o.removeSnapshotData(unused)
+		return readSessionInfo{}, probe.NewError(TooManyReadSessions{})
+	}
+	session := &readSession{
+		readSessionInfo: readSessionInfo{
+			ID:      uid.String(),
+			Created: now,
+			Expires: now.Add(ttl),
+		},
+		preserved: make(map[string]snapshotEntry),
+	}
+	sm.sessions[session.ID] = session
+	sm.mutex.Unlock()
+	o.removeSnapshotData(unused)
+	return session.readSessionInfo, nil
+}
+
+// CloseReadSession - closes a read session and removes data
+// preserved only for it.
+func (o objectAPI) CloseReadSession(id string) *probe.Error {
+	sm := o.snapshots
+	sm.mutex.Lock()
+	if _, ok := sm.sessions[id]; !ok {
+		sm.mutex.Unlock()
+		return probe.NewError(ReadSessionNotFound{ID: id})
+	}
+	unused := sm.closeSession(id)
+	sm.mutex.Unlock()
+	o.removeSnapshotData(unused)
+	return nil
+}
+
+// beginCommit - preserves the current state of object for all open
+// sessions which have not preserved it yet. Writers call the returned
+// function once their write has committed or failed.
+func (o objectAPI) beginCommit(bucket, object string) (endCommit func()) {
+	sm := o.snapshots
+	sm.commitLock.RLock()
+
+	key := snapshotKey(bucket, object)
+	sm.mutex.Lock()
+	unused := sm.expireSessions(time.Now().UTC())
+	var pending []string
+	for id, session := range sm.sessions {
+		if _, ok := session.preserved[key]; !ok {
+			pending = append(pending, id)
+		}
+	}
+	sm.mutex.Unlock()
+	o.removeSnapshotData(unused)
+	if len(pending) == 0 {
+		return sm.commitLock.RUnlock
+	}
+
+	// One copy of the data is shared by all pending sessions.
+	entry, e := o.preserveObject(bucket, object)
+	if e != nil {
+		// Sessions see the object as written, log and continue.
+		errorIf(probe.NewError(e).Trace(bucket, object), "Unable to preserve object for read sessions.", nil)
+		return sm.commitLock.RUnlock
+	}
+	sm.mutex.Lock()
+	for _, id := range pending {
+		session, ok := sm.sessions[id]
+		if !ok {
+			continue
+		}
+		// Concurrent writer of the same object preserved it first.
+		if _, ok = session.preserved[key]; ok {
+			continue
+		}
+		session.preserved[key] = entry
+		if !entry.absent {
+			sm.refs[entry.dataPath]++
+		}
+	}
+	unreferenced := !entry.absent && sm.refs[entry.dataPath] == 0
+	sm.mutex.Unlock()
+	if unreferenced {
+		o.removeSnapshotData([]string{entry.dataPath})
+	}
+	return sm.commitLock.RUnlock
+}
+
+// preserveObject - copies current object data to minioMetaVolume.
+func (o objectAPI) preserveObject(bucket, object string) (snapshotEntry, error) {
+	objInfo, err := o.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.ToGoError().(ObjectNotFound); ok {
+			return snapshotEntry{absent: true}, nil
+		}
+		return snapshotEntry{}, err.ToGoError()
+	}
+	uid, e := uuid.New()
+	if e != nil {
+		return snapshotEntry{}, e
+	}
+	dataPath := path.Join(snapshotDataPrefix, uid.String())
+	r, err := o.GetObject(bucket, object, 0)
+	if err != nil {
+		return snapshotEntry{}, err.ToGoError()
+	}
+	defer r.Close()
+	w, e := o.storage.CreateFile(context.Background(), minioMetaVolume, dataPath)
+	if e != nil {
+		return snapshotEntry{}, e
+	}
+	if _, e = io.CopyN(w, r, objInfo.Size); e != nil {
+		safeCloseAndRemove(w)
+		return snapshotEntry{}, e
+	}
+	if e = w.Close(); e != nil {
+		return snapshotEntry{}, e
+	}
+	return snapshotEntry{dataPath: dataPath, info: objInfo}, nil
+}
+
+// GetObjectInfoAt - get object info as of the cut of read session,
+// latest object info without a session.
+func (o objectAPI) GetObjectInfoAt(sessionID, bucket, object string) (ObjectInfo, *probe.Error) {
+	if sessionID == "" {
+		return o.GetObjectInfo(bucket, object)
+	}
+	entry, ok, err := o.snapshots.lookup(sessionID, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, err.Trace(sessionID)
+	}
+	if !ok {
+		objInfo, err := o.GetObjectInfo(bucket, object)
+		if err != nil {
+			return ObjectInfo{}, err.Trace(bucket, object)
+		}
+		// Writer may have committed after the lookup, it preserves
+		// the object before committing.
+		if entry, ok, err = o.snapshots.lookup(sessionID, bucket, object); err != nil {
+			return ObjectInfo{}, err.Trace(sessionID)
+		}
+		if !ok {
+			return objInfo, nil
+		}
+	}
+	if entry.absent {
+		return ObjectInfo{}, probe.NewError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	return entry.info, nil
+}
+
+// GetObjectAt - get object as of the cut of read session, latest
+// object without a session.
+func (o objectAPI) GetObjectAt(sessionID, bucket, object string, startOffset int64) (io.ReadCloser, *probe.Error) {
+	return o.GetObjectRangeAt(sessionID, bucket, object, startOffset, -1)
+}
+
+// GetObjectRangeAt - get length bytes of object at startOffset as of
+// the cut of read session, all of the object after startOffset if
+// length is negative.
+func (o objectAPI) GetObjectRangeAt(sessionID, bucket, object string, startOffset, length int64) (io.ReadCloser, *probe.Error) {
+	if sessionID == "" {
+		return o.GetObjectRange(bucket, object, startOffset, length)
+	}
+	entry, ok, err := o.snapshots.lookup(sessionID, bucket, object)
+	if err != nil {
+		return nil, err.Trace(sessionID)
+	}
+	if !ok {
+		r, err := o.GetObjectRange(bucket, object, startOffset, length)
+		if err != nil {
+			return nil, err.Trace(bucket, object)
+		}
+		// No entry after opening means no writer has committed since
+		// the cut before the reader was opened.
+		if entry, ok, err = o.snapshots.lookup(sessionID, bucket, object); err != nil {
+			r.Close()
+			return nil, err.Trace(sessionID)
+		}
+		if !ok {
+			return r, nil
+		}
+		r.Close()
+	}
+	if entry.absent {
+		return nil, probe.NewError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	r, e := o.readFileRange(minioMetaVolume, entry.dataPath, startOffset, length)
+	if e != nil {
+		return nil, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return r, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// readObjectAt - returns object data as of the cut of read session.
+func readObjectAt(t *testing.T, obj objectAPI, sessionID, bucket, object string) string {
+	r, err := obj.GetObjectAt(sessionID, bucket, object, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return string(data)
+}
+
+// Tests reads pinned to the cut of a read session.
+func TestReadSession(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-snapshot-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	putObject := func(object, data string) {
+		if _, err := obj.PutObject("bucket", object, int64(len(data)), bytes.NewBufferString(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	putObject("overwritten", "old")
+	putObject("deleted", "old")
+	putObject("unchanged", "old")
+
+	session, err := obj.OpenReadSession(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putObject("overwritten", "new data")
+	putObject("overwritten", "newer data")
+	if err = obj.DeleteObject("bucket", "deleted", false); err != nil {
+		t.Fatal(err)
+	}
+	putObject("created", "new")
+
+	// Session reads the objects as they were when it was opened.
+	for _, object := range []string{"overwritten", "deleted", "unchanged"} {
+		if data := readObjectAt(t, obj, session.ID, "bucket", object); data != "old" {
+			t.Errorf("%s: expected \"old\", got %q", object, data)
+		}
+	}
+	objInfo, err := obj.GetObjectInfoAt(session.ID, "bucket", "overwritten")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objInfo.Size != int64(len("old")) {
+		t.Errorf("Expected size %d, got %d", len("old"), objInfo.Size)
+	}
+	if _, err = obj.GetObjectInfoAt(session.ID, "bucket", "created"); err == nil {
+		t.Error("Expected object created after the cut to be missing")
+	} else if _, ok := err.ToGoError().(ObjectNotFound); !ok {
+		t.Errorf("Expected ObjectNotFound, got %v", err)
+	}
+
+	// Reads without a session see the latest data.
+	if data := readObjectAt(t, obj, "", "bucket", "overwritten"); data != "newer data" {
+		t.Errorf("Expected \"newer data\", got %q", data)
+	}
+
+	if err = obj.CloseReadSession(session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.GetObjectInfoAt(session.ID, "bucket", "overwritten"); err == nil {
+		t.Fatal("Expected error for closed session")
+	} else if _, ok := err.ToGoError().(ReadSessionNotFound); !ok {
+		t.Fatalf("Expected ReadSessionNotFound, got %v", err)
+	}
+	// Preserved data is removed with the session.
+	fileInfos, _, e := fs.ListFiles(minioMetaVolume, snapshotDataPrefix, "", true, 1000)
+	if e != nil && e != errFileNotFound {
+		t.Fatal(e)
+	}
+	if len(fileInfos) != 0 {
+		t.Fatalf("Expected no preserved data, found %v", fileInfos)
+	}
+}
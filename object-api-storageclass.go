@@ -0,0 +1,86 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "io"
+
+const (
+	// Header objects are given their storage class with, saved along
+	// with user defined metadata.
+	amzStorageClassHeader = "X-Amz-Storage-Class"
+
+	// Storage classes known without being configured.
+	storageClassStandard          = "STANDARD"
+	storageClassReducedRedundancy = "REDUCED_REDUNDANCY"
+
+	// Parity blocks of reduced redundancy objects, unless configured.
+	defaultReducedRedundancyParity = 2
+)
+
+// storageClass - storage class objects are put with, mapped to the
+// number of parity blocks they are erasure coded with. Storage which
+// does not erasure code only records the class.
+type storageClass struct {
+	// Parity blocks, the default of the disks, half of them, if 0.
+	Parity int `json:"parity"`
+}
+
+// getStorageClass - returns storage class name, configured classes
+// override the STANDARD and REDUCED_REDUNDANCY classes.
+func getStorageClass(name string) (storageClass, bool) {
+	if class, ok := serverConfig.GetStorageClass(name); ok {
+		return class, true
+	}
+	switch name {
+	case storageClassStandard:
+		return storageClass{}, true
+	case storageClassReducedRedundancy:
+		return storageClass{Parity: defaultReducedRedundancyParity}, true
+	}
+	return storageClass{}, false
+}
+
+// createObjectFile - creates the file of object, erasure coded with the
+// parity of the storage class in metadata if storage supports it.
+func (o objectAPI) createObjectFile(bucket, object string, metadata map[string]string) (io.WriteCloser, error) {
+	name := metadata[amzStorageClassHeader]
+	if name == "" {
+		return o.storage.CreateFile(o.context(), bucket, object)
+	}
+	class, ok := getStorageClass(name)
+	if !ok {
+		return nil, InvalidStorageClass{StorageClass: name}
+	}
+	creator, ok := o.storage.(parityCreator)
+	if !ok || class.Parity == 0 {
+		return o.storage.CreateFile(o.context(), bucket, object)
+	}
+	w, e := creator.CreateFileParity(o.context(), bucket, object, class.Parity)
+	if errorCause(e) == errInvalidParity {
+		return nil, InvalidStorageClass{StorageClass: name}
+	}
+	return w, e
+}
+
+// objectStorageClass - returns storage class of object from its user
+// defined metadata, objects put without a class are STANDARD.
+func objectStorageClass(userDefined map[string]string) string {
+	if name, ok := userDefined[amzStorageClassHeader]; ok {
+		return name
+	}
+	return storageClassStandard
+}
@@ -0,0 +1,194 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Object tags are saved in the metadata of their object under
+	// keys of this prefix, it never collides with header keys.
+	objectTagKeyPrefix = "tag:"
+
+	// Maximum size of tagging document, request or saved.
+	maxTaggingSize = 64 * 1024
+
+	// Tag limits, same as S3.
+	maxObjectTags  = 10
+	maxTagKeyLen   = 128
+	maxTagValueLen = 256
+)
+
+var (
+	errTooManyTags     = errors.New("Object tags cannot be greater than 10")
+	errInvalidTagKey   = errors.New("Tag key should be between 1 and 128 characters")
+	errInvalidTagValue = errors.New("Tag value cannot be longer than 256 characters")
+	errDuplicateTagKey = errors.New("Cannot provide multiple tags with the same key")
+)
+
+// objectTag - single tag.
+type objectTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// objectTagging - tagging document of PUT and GET Object tagging.
+type objectTagging struct {
+	XMLName xml.Name    `xml:"Tagging"`
+	TagSet  []objectTag `xml:"TagSet>Tag"`
+}
+
+// Validate - validates tags against S3 limits.
+func (t objectTagging) Validate() error {
+	if len(t.TagSet) > maxObjectTags {
+		return errTooManyTags
+	}
+	keys := make(map[string]struct{})
+	for _, tag := range t.TagSet {
+		if tag.Key == "" || utf8.RuneCountInString(tag.Key) > maxTagKeyLen {
+			return errInvalidTagKey
+		}
+		if utf8.RuneCountInString(tag.Value) > maxTagValueLen {
+			return errInvalidTagValue
+		}
+		if _, ok := keys[tag.Key]; ok {
+			return errDuplicateTagKey
+		}
+		keys[tag.Key] = struct{}{}
+	}
+	return nil
+}
+
+// Map - returns tags keyed by tag key.
+func (t objectTagging) Map() map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range t.TagSet {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+// newObjectTagging - returns tagging document with tags sorted by key.
+func newObjectTagging(tags map[string]string) objectTagging {
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tagging := objectTagging{TagSet: []objectTag{}}
+	for _, key := range keys {
+		tagging.TagSet = append(tagging.TagSet, objectTag{Key: key, Value: tags[key]})
+	}
+	return tagging
+}
+
+// readObjectTags - reads tags saved in the metadata of object,
+// objects without saved metadata have no saved tags.
+func (o objectAPI) readObjectTags(bucket, object string) (map[string]string, error) {
+	metadata, e := o.readObjectMetadata(bucket, object)
+	if e != nil {
+		return nil, e
+	}
+	return objectTags(metadata), nil
+}
+
+// objectTags - returns tags saved in metadata of object.
+func objectTags(metadata map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for key, value := range metadata {
+		if strings.HasPrefix(key, objectTagKeyPrefix) {
+			tags[strings.TrimPrefix(key, objectTagKeyPrefix)] = value
+		}
+	}
+	return tags
+}
+
+// writeObjectTags - replaces tags saved in the metadata of object,
+// along with its other metadata. Objects are overwritten under the
+// same lock, their metadata is not lost.
+func (o objectAPI) writeObjectTags(bucket, object string, tags map[string]string) *probe.Error {
+	lock := o.sequences.lock(bucket, object)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return err.Trace(bucket, object)
+	}
+	e := o.updateObjectMetadata(bucket, object, func(metadata map[string]string) error {
+		for key := range metadata {
+			if strings.HasPrefix(key, objectTagKeyPrefix) {
+				delete(metadata, key)
+			}
+		}
+		for key, value := range tags {
+			metadata[objectTagKeyPrefix+key] = value
+		}
+		return nil
+	})
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return nil
+}
+
+// checkObjectExists - returns an error unless object exists.
+func (o objectAPI) checkObjectExists(bucket, object string) *probe.Error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
+	}
+	if _, e := o.storage.StatFile(bucket, object); e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return nil
+}
+
+// GetObjectTags - returns tags of an object, empty if the object has
+// no tags.
+func (o objectAPI) GetObjectTags(bucket, object string) (map[string]string, *probe.Error) {
+	if err := o.checkObjectExists(bucket, object); err != nil {
+		return nil, err.Trace(bucket, object)
+	}
+	tags, e := o.readObjectTags(bucket, object)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return make(map[string]string), nil
+		}
+		return nil, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return tags, nil
+}
+
+// PutObjectTags - replaces tags of an object.
+func (o objectAPI) PutObjectTags(bucket, object string, tags map[string]string) *probe.Error {
+	return o.writeObjectTags(bucket, object, tags)
+}
+
+// DeleteObjectTags - removes all tags of an object.
+func (o objectAPI) DeleteObjectTags(bucket, object string) *probe.Error {
+	return o.writeObjectTags(bucket, object, nil)
+}
@@ -0,0 +1,131 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Tests tag validation.
+func TestObjectTaggingValidate(t *testing.T) {
+	tooMany := objectTagging{}
+	for i := 0; i <= maxObjectTags; i++ {
+		tooMany.TagSet = append(tooMany.TagSet, objectTag{Key: strconv.Itoa(i)})
+	}
+	testCases := []struct {
+		tagging objectTagging
+		err     error
+	}{
+		{objectTagging{TagSet: []objectTag{{Key: "project", Value: "minio"}}}, nil},
+		{objectTagging{}, nil},
+		{tooMany, errTooManyTags},
+		{objectTagging{TagSet: []objectTag{{Key: "", Value: "minio"}}}, errInvalidTagKey},
+		{objectTagging{TagSet: []objectTag{{Key: strings.Repeat("k", 129)}}}, errInvalidTagKey},
+		{objectTagging{TagSet: []objectTag{{Key: "k", Value: strings.Repeat("v", 257)}}}, errInvalidTagValue},
+		{objectTagging{TagSet: []objectTag{{Key: "k"}, {Key: "k"}}}, errDuplicateTagKey},
+	}
+	for i, testCase := range testCases {
+		if e := testCase.tagging.Validate(); e != testCase.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, e)
+		}
+	}
+}
+
+// Tests saving, reading and removing object tags.
+func TestObjectTags(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-tagging-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{"X-Amz-Meta-Owner": "minio"}
+	md5Hex, err := obj.PutObject("bucket", "object", int64(len("hello")), bytes.NewBufferString("hello"), metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags of missing objects cannot be set.
+	if err := obj.PutObjectTags("bucket", "missing", map[string]string{"k": "v"}); err == nil {
+		t.Fatal("Expected error for missing object")
+	}
+
+	tags, err := obj.GetObjectTags("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Fatalf("Expected no tags, got %v", tags)
+	}
+	expected := map[string]string{"project": "minio", "tier": "cold"}
+	if err = obj.PutObjectTags("bucket", "object", expected); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err = obj.GetObjectTags("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("Expected tags %v, got %v", expected, tags)
+	}
+	// Tags are saved along with the metadata of the object, which is
+	// kept and returned without them.
+	objInfo, err := obj.GetObjectInfo("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(objInfo.UserDefined, metadata) || objInfo.MD5Sum != md5Hex {
+		t.Fatalf("Unexpected metadata %v %s", objInfo.UserDefined, objInfo.MD5Sum)
+	}
+
+	// Overwriting the object removes its tags.
+	if _, err = obj.PutObject("bucket", "object", int64(len("world")), bytes.NewBufferString("world"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err = obj.GetObjectTags("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Fatalf("Expected no tags after overwrite, got %v", tags)
+	}
+
+	if err = obj.PutObjectTags("bucket", "object", expected); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.DeleteObjectTags("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err = obj.GetObjectTags("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Fatalf("Expected no tags after delete, got %v", tags)
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+	"github.com/minio/minio/pkg/s3"
+)
+
+// Current tier stub version.
+const tierStubVersion = "1"
+
+// errObjectModified - object was modified while it was transitioned.
+var errObjectModified = errors.New("Object modified during transition")
+
+// remoteTier - S3 compatible remote tier objects are transitioned to
+// by bucket lifecycle rules, rules refer to a tier by its name as the
+// storage class.
+type remoteTier struct {
+	Endpoint  string `json:"endpoint"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	Region    string `json:"region"`
+	Secure    bool   `json:"secure"`
+	Bucket    string `json:"bucket"`
+	// Prefix prepended to the names of all transitioned objects.
+	Prefix string `json:"prefix"`
+}
+
+// newClient - returns a client for the tier endpoint.
+func (t remoteTier) newClient() (*s3.Client, error) {
+	return s3.New(s3.Config{
+		Endpoint:  t.Endpoint,
+		AccessKey: t.AccessKey,
+		SecretKey: t.SecretKey,
+		Region:    t.Region,
+		Secure:    t.Secure,
+	})
+}
+
+// tierStub - saved in the metadata of objects whose data has been
+// transitioned to a remote tier, the local object is truncated to zero
+// bytes.
+type tierStub struct {
+	Version string    `json:"version"`
+	Tier    string    `json:"tier"`
+	Object  string    `json:"object"` // Object name in tier bucket.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	MD5Sum  string    `json:"md5Sum"`
+}
+
+// parseTierStub - returns stub saved in metadata of object, false if
+// none was.
+func parseTierStub(bucket, object string, metadata map[string]string) (tierStub, bool) {
+	value, ok := metadata[objectTierStubKey]
+	if !ok {
+		return tierStub{}, false
+	}
+	var stub tierStub
+	if e := json.Unmarshal([]byte(value), &stub); e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Invalid tier stub.", nil)
+		return tierStub{}, false
+	}
+	return stub, true
+}
+
+// setTierStub - saves stub in metadata of object.
+func setTierStub(metadata map[string]string, stub tierStub) error {
+	stubBytes, e := json.Marshal(stub)
+	if e != nil {
+		return e
+	}
+	metadata[objectTierStubKey] = string(stubBytes)
+	return nil
+}
+
+// objectTierStub - returns stub saved in metadata if the object with
+// local size has been transitioned. Data written over a transitioned
+// object makes the stub stale, only empty objects have a stub.
+func objectTierStub(bucket, object string, metadata map[string]string, size int64) (tierStub, bool) {
+	if size != 0 {
+		return tierStub{}, false
+	}
+	return parseTierStub(bucket, object, metadata)
+}
+
+// getTierStub - returns stub if the object with local size has been
+// transitioned.
+func (o objectAPI) getTierStub(bucket, object string, size int64) (tierStub, bool) {
+	if size != 0 {
+		return tierStub{}, false
+	}
+	return objectTierStub(bucket, object, o.statObjectMetadata(bucket, object), size)
+}
+
+// removeTransitioned - removes the remote copy of an overwritten or
+// deleted object, as per the stub in its previous metadata.
+func (o objectAPI) removeTransitioned(bucket, object string, metadata map[string]string) {
+	if stub, ok := parseTierStub(bucket, object, metadata); ok {
+		o.removeTierCopy(bucket, object, stub)
+	}
+}
+
+// removeTierCopy - removes the remote copy of stub, of an object which
+// is no longer transitioned.
+func (o objectAPI) removeTierCopy(bucket, object string, stub tierStub) {
+	tier, ok := serverConfig.GetTier(stub.Tier)
+	if !ok {
+		return
+	}
+	client, e := tier.newClient()
+	if e == nil {
+		e = client.DeleteObject(tier.Bucket, stub.Object)
+	}
+	errorIf(probe.NewError(e).Trace(bucket, object, stub.Tier), "Unable to remove transitioned object from tier.", nil)
+}
+
+// readTransitioned - reads transitioned object data from its tier.
+func (o objectAPI) readTransitioned(stub tierStub, startOffset int64) (io.ReadCloser, error) {
+	tier, ok := serverConfig.GetTier(stub.Tier)
+	if !ok {
+		return nil, TierNotFound{Tier: stub.Tier}
+	}
+	client, e := tier.newClient()
+	if e != nil {
+		return nil, e
+	}
+	return client.GetObject(tier.Bucket, stub.Object, startOffset)
+}
+
+// hashObject - returns hex encoded md5 and sha256 of object data.
+func (o objectAPI) hashObject(bucket, object string) (md5Hex, sha256Hex string, e error) {
+	r, e := o.storage.ReadFile(context.Background(), bucket, object, 0)
+	if e != nil {
+		return "", "", e
+	}
+	defer r.Close()
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, e = io.Copy(io.MultiWriter(md5Hash, sha256Hash), r); e != nil {
+		return "", "", e
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
+
+// TransitionObject - moves data of object last modified at modTime to
+// the named tier, leaving a stub behind. Objects modified since, empty
+// or already transitioned objects are left alone.
+func (o objectAPI) TransitionObject(bucket, object string, modTime time.Time, tierName string) *probe.Error {
+	tier, ok := serverConfig.GetTier(tierName)
+	if !ok {
+		return probe.NewError(TierNotFound{Tier: tierName})
+	}
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	if fi.Size == 0 || !fi.ModTime.Equal(modTime) {
+		return nil
+	}
+
+	// Signature requires the payload checksum upfront, data is read
+	// twice.
+	md5Hex, sha256Hex, e := o.hashObject(bucket, object)
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	client, e := tier.newClient()
+	if e != nil {
+		return probe.NewError(e)
+	}
+	stub := tierStub{
+		Version: tierStubVersion,
+		Tier:    tierName,
+		Object:  tier.Prefix + path.Join(bucket, object),
+		Size:    fi.Size,
+		ModTime: fi.ModTime,
+		MD5Sum:  md5Hex,
+	}
+	r, e := o.storage.ReadFile(context.Background(), bucket, object, 0)
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	_, e = client.PutObject(tier.Bucket, stub.Object, r, fi.Size, sha256Hex)
+	r.Close()
+	if e != nil {
+		return probe.NewError(e)
+	}
+
+	// Data is truncated like any other write is committed, the object
+	// cannot be overwritten between the check and the truncate.
+	endCommit := o.beginCommit(bucket, object)
+	e = o.sequenced(bucket, object, func(sequence uint64) error {
+		// Object may have been overwritten while it was uploaded.
+		if fi, e := o.storage.StatFile(bucket, object); e != nil || fi.Size != stub.Size || !fi.ModTime.Equal(modTime) {
+			return errObjectModified
+		}
+		metadata, e := o.lookupObjectMetadata(bucket, object)
+		if e != nil {
+			return e
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		setObjectSequence(metadata, sequence)
+		if e = setTierStub(metadata, stub); e != nil {
+			return e
+		}
+		w, e := o.storage.CreateFile(context.Background(), bucket, object)
+		if e != nil {
+			return e
+		}
+		// Stub is committed along with the truncated data by storage
+		// which keeps metadata, saved before the data is truncated
+		// otherwise. A stub next to non-empty data is ignored.
+		if committer, ok := w.(metadataCommitter); ok {
+			committer.SetMetadata(metadata)
+		} else if e = o.writeObjectMetadata(bucket, object, metadata); e != nil {
+			safeCloseAndRemove(w)
+			return e
+		}
+		return w.Close()
+	})
+	endCommit()
+	if e == errObjectModified {
+		o.removeTierCopy(bucket, object, stub)
+		return nil
+	}
+	if e != nil {
+		return probe.NewError(toObjectErr(e, bucket, object))
+	}
+	o.notFound.Invalidate(bucket, object)
+	o.cache.Invalidate(bucket, object)
+	log.WithFields(logrus.Fields{
+		"bucket": bucket,
+		"object": object,
+		"tier":   tierName,
+	}).Debug("Transitioned object as per bucket lifecycle.")
+	return nil
+}
+
+// RestoreObject - copies data of a transitioned object back from its
+// tier, returns false if the object was not transitioned.
+func (o objectAPI) RestoreObject(bucket, object string) (bool, *probe.Error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return false, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return false, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
+	}
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil {
+		return false, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	metadata, e := o.lookupObjectMetadata(bucket, object)
+	if e != nil {
+		return false, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	stub, ok := objectTierStub(bucket, object, metadata, fi.Size)
+	if !ok {
+		return false, nil
+	}
+	lock, e := getObjectLock(metadata)
+	if e != nil {
+		return false, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	r, e := o.readTransitioned(stub, 0)
+	if e != nil {
+		return false, probe.NewError(e)
+	}
+	defer r.Close()
+	// PutObject removes the stub, the remote copy and the tags, user
+	// defined metadata is written again.
+	userMetadata, _ := userObjectMetadata(metadata)
+	if userMetadata == nil {
+		userMetadata = make(map[string]string)
+	}
+	userMetadata[objectMD5SumKey] = stub.MD5Sum
+	// Data is restored as is, retention of the object is kept.
+	if _, err := o.putObject(bucket, object, stub.Size, r, userMetadata, lock); err != nil {
+		return false, err.Trace(bucket, object)
+	}
+	if tags := objectTags(metadata); len(tags) > 0 {
+		if err := o.PutObjectTags(bucket, object, tags); err != nil {
+			return true, err.Trace(bucket, object)
+		}
+	}
+	return true, nil
+}
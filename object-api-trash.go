@@ -0,0 +1,341 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+const (
+	// Deleted objects are kept under this prefix in minioMetaVolume,
+	// as <id>.data with its <id>.json info.
+	trashPrefix = ".trash"
+
+	// Maximum size of a saved trash info.
+	maxTrashInfoSize = 64 * 1024
+
+	// Number of trashed objects listed at a time.
+	trashListBatchSize = 1000
+
+	// Default time deleted objects are kept for.
+	defaultTrashRetention = 7 * 24 * time.Hour
+
+	// Interval between two removals of expired trash.
+	trashPurgeInterval = time.Hour
+)
+
+// trashConfig - trash configuration, while enabled deleted objects are
+// kept for the retention period and can be restored until then.
+type trashConfig struct {
+	Enable bool `json:"enable"`
+	// Seconds deleted objects are kept for, zero for the default.
+	Retention int64 `json:"retention"`
+	// Objects larger than MaxSize bytes are deleted right away, zero
+	// for no limit.
+	MaxSize int64 `json:"maxSize"`
+}
+
+// retention - returns time deleted objects are kept for, defaults if
+// not configured.
+func (c trashConfig) retention() time.Duration {
+	if c.Retention <= 0 {
+		return defaultTrashRetention
+	}
+	return time.Duration(c.Retention) * time.Second
+}
+
+// trashInfo - deleted object kept in trash.
+type trashInfo struct {
+	ID       string            `json:"id"`
+	Bucket   string            `json:"bucket"`
+	Object   string            `json:"object"`
+	Size     int64             `json:"size"`
+	MD5Sum   string            `json:"md5Sum,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Deleted  time.Time         `json:"deleted"`
+	Expires  time.Time         `json:"expires"`
+}
+
+// byTrashTime - sorts trashed objects oldest first.
+type byTrashTime []trashInfo
+
+func (t byTrashTime) Len() int           { return len(t) }
+func (t byTrashTime) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byTrashTime) Less(i, j int) bool { return t[i].Deleted.Before(t[j].Deleted) }
+
+// objectTrash - trash configuration of the object layer.
+type objectTrash struct {
+	rwMutex *sync.RWMutex
+	config  trashConfig
+}
+
+func newObjectTrash() *objectTrash {
+	return &objectTrash{rwMutex: &sync.RWMutex{}}
+}
+
+// SetTrash - sets trash configuration of objects deleted from now on.
+func (o objectAPI) SetTrash(config trashConfig) {
+	o.trash.rwMutex.Lock()
+	defer o.trash.rwMutex.Unlock()
+	o.trash.config = config
+}
+
+// getTrashConfig - returns current trash configuration.
+func (o objectAPI) getTrashConfig() trashConfig {
+	o.trash.rwMutex.RLock()
+	defer o.trash.rwMutex.RUnlock()
+	return o.trash.config
+}
+
+// trashDataPath - returns path of trashed data in minioMetaVolume.
+func trashDataPath(id string) string {
+	return path.Join(trashPrefix, id+".data")
+}
+
+// trashInfoPath - returns path of trash info in minioMetaVolume.
+func trashInfoPath(id string) string {
+	return path.Join(trashPrefix, id+".json")
+}
+
+// moveToTrash - copies object about to be deleted to trash, if trash is
+// enabled and the object is not too large. Missing objects are left to
+// the delete to report.
+func (o objectAPI) moveToTrash(bucket, object string) error {
+	config := o.getTrashConfig()
+	if !config.Enable {
+		return nil
+	}
+	objInfo, err := o.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.ToGoError().(ObjectNotFound); ok {
+			return nil
+		}
+		return err.ToGoError()
+	}
+	if objInfo.IsDir || (config.MaxSize > 0 && objInfo.Size > config.MaxSize) {
+		return nil
+	}
+	uid, e := uuid.New()
+	if e != nil {
+		return e
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e = o.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	id := uid.String()
+	metadata, md5Hex := o.getObjectMetadata(bucket, object)
+	now := time.Now().UTC()
+	infoBytes, e := json.Marshal(trashInfo{
+		ID:       id,
+		Bucket:   bucket,
+		Object:   object,
+		Size:     objInfo.Size,
+		MD5Sum:   md5Hex,
+		Metadata: metadata,
+		Deleted:  now,
+		Expires:  now.Add(config.retention()),
+	})
+	if e != nil {
+		return e
+	}
+	r, err := o.GetObject(bucket, object, 0)
+	if err != nil {
+		return err.ToGoError()
+	}
+	defer r.Close()
+	w, e := o.storage.CreateFile(context.Background(), minioMetaVolume, trashDataPath(id))
+	if e != nil {
+		return e
+	}
+	if _, e = io.CopyN(w, r, objInfo.Size); e != nil {
+		safeCloseAndRemove(w)
+		return e
+	}
+	if e = w.Close(); e != nil {
+		return e
+	}
+	if e = o.writeMetaFile(trashInfoPath(id), infoBytes); e != nil {
+		o.storage.DeleteFile(context.Background(), minioMetaVolume, trashDataPath(id))
+		return e
+	}
+	return nil
+}
+
+// ListTrash - returns objects kept in trash, of bucket if not empty,
+// oldest first.
+func (o objectAPI) ListTrash(bucket string) ([]trashInfo, *probe.Error) {
+	infos := []trashInfo{}
+	marker := ""
+	for {
+		fileInfos, eof, e := o.storage.ListFiles(minioMetaVolume, trashPrefix+slashSeparator, marker, false, trashListBatchSize)
+		if e != nil {
+			if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+				break
+			}
+			return nil, probe.NewError(e)
+		}
+		for _, fileInfo := range fileInfos {
+			marker = fileInfo.Name
+			if !strings.HasSuffix(fileInfo.Name, ".json") {
+				continue
+			}
+			info, err := o.GetTrash(strings.TrimSuffix(path.Base(fileInfo.Name), ".json"))
+			if err != nil {
+				// Restored or purged meanwhile.
+				if _, ok := err.ToGoError().(TrashNotFound); ok {
+					continue
+				}
+				return nil, err.Trace(fileInfo.Name)
+			}
+			if bucket != "" && info.Bucket != bucket {
+				continue
+			}
+			infos = append(infos, info)
+		}
+		if eof || len(fileInfos) == 0 {
+			break
+		}
+	}
+	sort.Sort(byTrashTime(infos))
+	return infos, nil
+}
+
+// GetTrash - returns info of the trashed object with id.
+func (o objectAPI) GetTrash(id string) (trashInfo, *probe.Error) {
+	// Ids are uuids, never paths.
+	if _, e := uuid.Parse(id); e != nil {
+		return trashInfo{}, probe.NewError(TrashNotFound{ID: id})
+	}
+	infoBytes, e := o.readMetaFile(trashInfoPath(id), maxTrashInfoSize)
+	if e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return trashInfo{}, probe.NewError(TrashNotFound{ID: id})
+		}
+		return trashInfo{}, probe.NewError(e)
+	}
+	info := trashInfo{}
+	if e = json.Unmarshal(infoBytes, &info); e != nil {
+		return trashInfo{}, probe.NewError(e)
+	}
+	return info, nil
+}
+
+// RestoreTrash - writes the trashed object with id back, over any
+// object written since under the same name, and removes it from
+// trash. Empty bucket or object restore to those the object was
+// deleted from.
+func (o objectAPI) RestoreTrash(id, bucket, object string) (string, *probe.Error) {
+	info, err := o.GetTrash(id)
+	if err != nil {
+		return "", err.Trace(id)
+	}
+	if bucket == "" {
+		bucket = info.Bucket
+	}
+	if object == "" {
+		object = info.Object
+	}
+	r, e := o.storage.ReadFile(o.context(), minioMetaVolume, trashDataPath(id), 0)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer r.Close()
+	metadata := make(map[string]string)
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+	if info.MD5Sum != "" {
+		metadata[objectMD5SumKey] = info.MD5Sum
+	}
+	md5Sum, err := o.PutObject(bucket, object, info.Size, r, metadata)
+	if err != nil {
+		return "", err.Trace(id, bucket, object)
+	}
+	if err = o.RemoveTrash(id); err != nil {
+		return "", err.Trace(id)
+	}
+	return md5Sum, nil
+}
+
+// RemoveTrash - discards the trashed object with id.
+func (o objectAPI) RemoveTrash(id string) *probe.Error {
+	if _, err := o.GetTrash(id); err != nil {
+		return err.Trace(id)
+	}
+	if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, trashInfoPath(id)); e != nil {
+		return probe.NewError(e)
+	}
+	if e := o.storage.DeleteFile(context.Background(), minioMetaVolume, trashDataPath(id)); e != nil && errorCause(e) != errFileNotFound {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// PurgeTrash - discards objects kept in trash, of bucket if not empty,
+// which expired, or all of them. Returns the number of objects
+// discarded.
+func (o objectAPI) PurgeTrash(bucket string, all bool) (int64, *probe.Error) {
+	infos, err := o.ListTrash(bucket)
+	if err != nil {
+		return 0, err.Trace(bucket)
+	}
+	var removed int64
+	now := time.Now().UTC()
+	for _, info := range infos {
+		if !all && info.Expires.After(now) {
+			continue
+		}
+		if err = o.RemoveTrash(info.ID); err != nil {
+			if _, ok := err.ToGoError().(TrashNotFound); ok {
+				continue
+			}
+			return removed, err.Trace(info.ID)
+		}
+		log.WithFields(logrus.Fields{
+			"bucket":  info.Bucket,
+			"object":  info.Object,
+			"id":      info.ID,
+			"deleted": info.Deleted,
+		}).Debug("Purged trashed object.")
+		removed++
+	}
+	return removed, nil
+}
+
+// initTrashPurger - starts discarding expired trash of objAPI in
+// background.
+func initTrashPurger(objAPI objectAPI) {
+	go func() {
+		for {
+			_, err := objAPI.PurgeTrash("", false)
+			errorIf(err.Trace(), "Unable to purge expired trash.", nil)
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+}
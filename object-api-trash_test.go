@@ -0,0 +1,146 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests deleted objects are kept in trash while enabled, and can be
+// restored, removed or purged.
+func TestTrash(t *testing.T) {
+	disk, e := ioutil.TempDir("", "minio-trash-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(disk)
+	fs, e := newFS(disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	putObject := func(object string, data []byte) string {
+		md5Hex, err := obj.PutObject("bucket", object, int64(len(data)), bytes.NewReader(data), map[string]string{"content-type": "text/plain", "X-Amz-Meta-Color": "blue"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return md5Hex
+	}
+	listTrash := func(bucket string) []trashInfo {
+		infos, err := obj.ListTrash(bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return infos
+	}
+
+	// Nothing is kept while disabled.
+	putObject("object", data)
+	if err := obj.DeleteObject("bucket", "object", false); err != nil {
+		t.Fatal(err)
+	}
+	if infos := listTrash(""); len(infos) != 0 {
+		t.Fatalf("Expected empty trash, got %v", infos)
+	}
+
+	obj.SetTrash(trashConfig{Enable: true, MaxSize: 1024})
+	md5Hex := putObject("object", data)
+	putObject("large", make([]byte, 2048))
+	for _, object := range []string{"object", "large"} {
+		if err := obj.DeleteObject("bucket", object, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := obj.DeleteObject("bucket", "missing", false); err == nil {
+		t.Fatal("Expected missing object not deleted")
+	}
+	infos := listTrash("")
+	if len(infos) != 1 || infos[0].Object != "object" || infos[0].Size != int64(len(data)) || infos[0].MD5Sum != md5Hex {
+		t.Fatalf("Expected only the deleted object kept, got %+v", infos)
+	}
+	if retention := infos[0].Expires.Sub(infos[0].Deleted); retention != defaultTrashRetention {
+		t.Fatalf("Expected default retention, got %v", retention)
+	}
+	if infos := listTrash("other"); len(infos) != 0 {
+		t.Fatalf("Expected no trash of other bucket, got %v", infos)
+	}
+
+	// Restored objects are written back along with their metadata.
+	if _, err := obj.RestoreTrash(infos[0].ID, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	objInfo, err := obj.GetObjectInfo("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objInfo.MD5Sum != md5Hex || objInfo.ContentType != "text/plain" || objInfo.UserDefined["X-Amz-Meta-Color"] != "blue" {
+		t.Fatalf("Unexpected restored object %+v", objInfo)
+	}
+	if infos := listTrash(""); len(infos) != 0 {
+		t.Fatalf("Expected restored object removed from trash, got %v", infos)
+	}
+	if _, err = obj.RestoreTrash(infos[0].ID, "", ""); err == nil {
+		t.Fatal("Expected restored object not found")
+	} else if _, ok := err.ToGoError().(TrashNotFound); !ok {
+		t.Fatalf("Expected trash not found, got %v", err)
+	}
+	if err = obj.RemoveTrash("../object"); err == nil {
+		t.Fatal("Expected invalid id not found")
+	}
+
+	// Objects are purged once expired, or all of them on request.
+	for _, object := range []string{"object", "other"} {
+		putObject(object, data)
+		if err = obj.DeleteObject("bucket", object, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	infos = listTrash("")
+	if len(infos) != 2 {
+		t.Fatalf("Expected two objects kept, got %v", infos)
+	}
+	expired := infos[0]
+	expired.Expires = time.Now().UTC().Add(-time.Second)
+	infoBytes, e := json.Marshal(expired)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = obj.writeMetaFile(trashInfoPath(expired.ID), infoBytes); e != nil {
+		t.Fatal(e)
+	}
+	if removed, err := obj.PurgeTrash("", false); err != nil || removed != 1 {
+		t.Fatalf("Expected the expired object purged, got %d, %v", removed, err)
+	}
+	if infos = listTrash(""); len(infos) != 1 || infos[0].ID == expired.ID {
+		t.Fatalf("Expected the expired object purged, got %v", infos)
+	}
+	if removed, err := obj.PurgeTrash("", true); err != nil || removed != 1 {
+		t.Fatalf("Expected all objects purged, got %d, %v", removed, err)
+	}
+	if infos = listTrash(""); len(infos) != 0 {
+		t.Fatalf("Expected empty trash, got %v", infos)
+	}
+}
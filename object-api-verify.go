@@ -0,0 +1,42 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "github.com/minio/minio/pkg/probe"
+
+// VerifyObject - reads the parts of object on all disks and verifies
+// them against their checksums, parts which are not healthy are
+// rebuilt if repair is set.
+func (o objectAPI) VerifyObject(bucket, object string, repair bool) (fileVerification, *probe.Error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return fileVerification{}, probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return fileVerification{}, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
+	}
+	verifier, ok := o.storage.(fileVerifier)
+	if !ok {
+		return fileVerification{}, probe.NewError(VerifyNotSupported{})
+	}
+	verification, e := verifier.verifyFile(bucket, object, repair)
+	if e != nil {
+		return fileVerification{}, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	return verification, nil
+}
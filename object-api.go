@@ -17,10 +17,12 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"io"
+	"io/ioutil"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -32,17 +34,106 @@ import (
 
 type objectAPI struct {
 	storage StorageAPI
+	// Recent object not found results.
+	notFound *notFoundCache
+	// Open read sessions.
+	snapshots *snapshotManager
+	// Purges of force deleted buckets.
+	purges *bucketPurges
+	// In-progress multipart uploads.
+	multiparts *multipartSessions
+	// Signs attestations of written objects.
+	attestor *objectAttestor
+	// Keeps uploads failing verification.
+	quarantine *uploadQuarantine
+	// Keeps deleted objects.
+	trash *objectTrash
+	// Orders mutations of keys.
+	sequences *objectSequencer
+	// Recently read objects cached on local disks.
+	cache *objectCache
+	// Receives the sequence number of the next mutation, if set.
+	sequence *uint64
+	// Cache-Control header value of the request served.
+	cacheControl string
+	// Context of the request served, object data reads and writes
+	// are aborted once it is done.
+	ctx context.Context
+	// Condition the object replaced by the next put must meet, if set.
+	writeCondition func(objInfo ObjectInfo, exists bool) bool
 }
 
 func newObjectLayer(storage StorageAPI) objectAPI {
-	return objectAPI{storage}
+	return objectAPI{
+		storage:    storage,
+		notFound:   newNotFoundCache(),
+		snapshots:  newSnapshotManager(),
+		purges:     newBucketPurges(),
+		multiparts: newMultipartSessions(),
+		attestor:   newObjectAttestor(),
+		quarantine: newUploadQuarantine(),
+		trash:      newObjectTrash(),
+		sequences:  newObjectSequencer(),
+		cache:      newObjectCache(),
+	}
+}
+
+// WithContext - returns copy of the object layer serving a request
+// with ctx, so that client disconnects and request timeouts abort
+// reads and writes of object data.
+func (o objectAPI) WithContext(ctx context.Context) objectAPI {
+	o.ctx = ctx
+	return o
+}
+
+// WithWriteCondition - returns copy of the object layer putting objects
+// only if condition holds for the object they replace, exists is false
+// if there is none. The condition is evaluated as the object commits,
+// puts failing it return PreconditionFailed.
+func (o objectAPI) WithWriteCondition(condition func(objInfo ObjectInfo, exists bool) bool) objectAPI {
+	o.writeCondition = condition
+	return o
+}
+
+// checkWriteCondition - evaluates the write condition against object,
+// called by commits holding the sequence lock of object. Object info is
+// read from storage, the not found cache may lag behind commits.
+func (o objectAPI) checkWriteCondition(bucket, object string) error {
+	if o.writeCondition == nil {
+		return nil
+	}
+	exists := true
+	fi, e := o.storage.StatFile(bucket, object)
+	if e != nil {
+		if errorCause(e) != errFileNotFound {
+			return e
+		}
+		exists = false
+	}
+	objInfo := ObjectInfo{Bucket: bucket, Name: object, ModTime: fi.ModTime, Size: fi.Size}
+	if exists {
+		_, objInfo.MD5Sum = userObjectMetadata(o.fileObjectMetadata(bucket, object, fi))
+	}
+	if !o.writeCondition(objInfo, exists) {
+		return PreconditionFailed{Bucket: bucket, Object: object}
+	}
+	return nil
+}
+
+// context - returns context of the request served, background context
+// if none was set.
+func (o objectAPI) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
 }
 
 // checks whether bucket exists.
 func (o objectAPI) isBucketExist(bucketName string) (bool, error) {
 	// Check whether bucket exists.
 	if _, e := o.storage.StatVol(bucketName); e != nil {
-		if e == errVolumeNotFound {
+		if errorCause(e) == errVolumeNotFound {
 			return false, nil
 		}
 		return false, e
@@ -66,7 +157,30 @@ func (o objectAPI) MakeBucket(bucket string) *probe.Error {
 	// other calls.
 	// Create minio meta volume, if it doesn't exist yet.
 	if e := o.storage.MakeVol(minioMetaVolume); e != nil {
-		if e != errVolumeExists {
+		if errorCause(e) != errVolumeExists {
+			return probe.NewError(toObjectErr(e, minioMetaVolume))
+		}
+	}
+	return nil
+}
+
+// MakeBucketOnDisks - make a bucket pinned to a subset of disks, given
+// as indexes of the disks reported by the admin API.
+func (o objectAPI) MakeBucketOnDisks(bucket string, disks []int) *probe.Error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return probe.NewError(BucketNameInvalid{Bucket: bucket})
+	}
+	placer, ok := o.storage.(volumePlacer)
+	if !ok {
+		return probe.NewError(PlacementNotSupported{})
+	}
+	if e := placer.MakeVolOnDisks(bucket, disks); e != nil {
+		return probe.NewError(toObjectErr(e, bucket))
+	}
+	// Create minio meta volume, if it doesn't exist yet.
+	if e := o.storage.MakeVol(minioMetaVolume); e != nil {
+		if errorCause(e) != errVolumeExists {
 			return probe.NewError(toObjectErr(e, minioMetaVolume))
 		}
 	}
@@ -111,6 +225,10 @@ func (o objectAPI) ListBuckets() ([]BucketInfo, *probe.Error) {
 		if !IsValidBucketName(vol.Name) {
 			continue
 		}
+		// Force deleted buckets are hidden while being purged.
+		if o.IsBucketPurging(vol.Name) {
+			continue
+		}
 		bucketInfos = append(bucketInfos, BucketInfo{
 			Name:    vol.Name,
 			Created: vol.Created,
@@ -131,6 +249,9 @@ func (o objectAPI) DeleteBucket(bucket string) *probe.Error {
 	if e := o.storage.DeleteVol(bucket); e != nil {
 		return probe.NewError(toObjectErr(e))
 	}
+	// Objects in a deleted bucket are reported as bucket not found.
+	o.notFound.InvalidateBucket(bucket)
+	o.cache.InvalidateBucket(bucket)
 	return nil
 }
 
@@ -138,6 +259,12 @@ func (o objectAPI) DeleteBucket(bucket string) *probe.Error {
 
 // GetObject - get an object.
 func (o objectAPI) GetObject(bucket, object string, startOffset int64) (io.ReadCloser, *probe.Error) {
+	return o.GetObjectRange(bucket, object, startOffset, -1)
+}
+
+// GetObjectRange - get length bytes of an object at startOffset, all
+// of the object after startOffset if length is negative.
+func (o objectAPI) GetObjectRange(bucket, object string, startOffset, length int64) (io.ReadCloser, *probe.Error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
 		return nil, probe.NewError(BucketNameInvalid{Bucket: bucket})
@@ -146,11 +273,64 @@ func (o objectAPI) GetObject(bucket, object string, startOffset int64) (io.ReadC
 	if !IsValidObjectName(object) {
 		return nil, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
-	r, e := o.storage.ReadFile(bucket, object, startOffset)
+	if o.notFound.IsNotFound(bucket, object) {
+		return nil, probe.NewError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	cc := parseCacheControl(o.cacheControl)
+	// Cached objects not expired yet are served without checking the
+	// backend.
+	if r := o.cache.get(bucket, object, nil, startOffset, cc); r != nil {
+		return limitReadCloser(r, length), nil
+	}
+	generation := o.notFound.Generation()
+	fi, e := o.storage.StatFile(bucket, object)
 	if e != nil {
+		if errorCause(e) == errFileNotFound {
+			o.notFound.Add(bucket, object, generation)
+		}
 		return nil, probe.NewError(toObjectErr(e, bucket, object))
 	}
-	return r, nil
+	// Data of transitioned objects is read through from the tier.
+	if stub, ok := o.getTierStub(bucket, object, fi.Size); ok {
+		fi.Size, fi.ModTime = stub.Size, stub.ModTime
+		if r := o.cache.get(bucket, object, &fi, startOffset, cc); r != nil {
+			return limitReadCloser(r, length), nil
+		}
+		r, e := o.readTransitioned(stub, startOffset)
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		if length >= 0 {
+			return limitReadCloser(r, length), nil
+		}
+		return o.readThrough(bucket, object, fi, startOffset, r), nil
+	}
+	if r := o.cache.get(bucket, object, &fi, startOffset, cc); r != nil {
+		return limitReadCloser(r, length), nil
+	}
+	r, e := o.readFileRange(bucket, object, startOffset, length)
+	if e != nil {
+		return nil, probe.NewError(toObjectErr(e, bucket, object))
+	}
+	// Partial reads do not fill the cache.
+	if length >= 0 {
+		return r, nil
+	}
+	return o.readThrough(bucket, object, fi, startOffset, r), nil
+}
+
+// readFileRange - reads length bytes of file at offset, all of the
+// file after offset if length is negative. Storage reading ranges
+// stops reading at their end.
+func (o objectAPI) readFileRange(volume, path string, offset, length int64) (io.ReadCloser, error) {
+	if reader, ok := o.storage.(rangeReader); ok && length >= 0 {
+		return reader.ReadFileRange(o.context(), volume, path, offset, length)
+	}
+	r, e := o.storage.ReadFile(o.context(), volume, path, offset)
+	if e != nil {
+		return nil, e
+	}
+	return limitReadCloser(r, length), nil
 }
 
 // GetObjectInfo - get object info.
@@ -163,8 +343,15 @@ func (o objectAPI) GetObjectInfo(bucket, object string) (ObjectInfo, *probe.Erro
 	if !IsValidObjectName(object) {
 		return ObjectInfo{}, probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
+	if o.notFound.IsNotFound(bucket, object) {
+		return ObjectInfo{}, probe.NewError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	generation := o.notFound.Generation()
 	fi, e := o.storage.StatFile(bucket, object)
 	if e != nil {
+		if errorCause(e) == errFileNotFound {
+			o.notFound.Add(bucket, object, generation)
+		}
 		return ObjectInfo{}, probe.NewError(toObjectErr(e, bucket, object))
 	}
 	contentType := "application/octet-stream"
@@ -174,15 +361,33 @@ func (o objectAPI) GetObjectInfo(bucket, object string) (ObjectInfo, *probe.Erro
 			contentType = content.ContentType
 		}
 	}
-	return ObjectInfo{
+	objInfo := ObjectInfo{
 		Bucket:      bucket,
 		Name:        object,
 		ModTime:     fi.ModTime,
 		Size:        fi.Size,
 		IsDir:       fi.Mode.IsDir(),
 		ContentType: contentType,
-		MD5Sum:      "", // Read from metadata.
-	}, nil
+	}
+	// Metadata of the object is read once along with its state.
+	metadata := o.fileObjectMetadata(bucket, object, fi)
+	objInfo.UserDefined, objInfo.MD5Sum = userObjectMetadata(metadata)
+	// Content type saved along with the object wins over the type
+	// guessed from its extension.
+	if value, ok := objInfo.UserDefined["Content-Type"]; ok {
+		objInfo.ContentType = value
+		delete(objInfo.UserDefined, "Content-Type")
+	}
+	objInfo.StorageClass = objectStorageClass(objInfo.UserDefined)
+	delete(objInfo.UserDefined, amzStorageClassHeader)
+	if stub, ok := objectTierStub(bucket, object, metadata, fi.Size); ok {
+		objInfo.ModTime = stub.ModTime
+		objInfo.Size = stub.Size
+		objInfo.MD5Sum = stub.MD5Sum
+	}
+	objInfo.ReplicationStatus = o.getReplicationStatus(bucket, object, fi.ModTime)
+	objInfo.Sequence = objectSequence(bucket, object, metadata)
+	return objInfo, nil
 }
 
 // safeCloseAndRemove - safely closes and removes underlying temporary
@@ -193,10 +398,100 @@ func safeCloseAndRemove(writer io.WriteCloser) error {
 	if ok {
 		return safeWriter.CloseAndRemove()
 	}
+	// If writer is a safe file written directly, or synced, remove
+	// it too.
+	directWriter, ok := writer.(*directFile)
+	if ok {
+		return directWriter.CloseAndRemove()
+	}
+	syncedWriter, ok := writer.(*syncedFile)
+	if ok {
+		return syncedWriter.CloseAndRemove()
+	}
 	pipeWriter, ok := writer.(*io.PipeWriter)
 	if ok {
 		return pipeWriter.CloseWithError(errors.New("Close and error out."))
 	}
+	// If writer is an erasure coded file, abort and wait for cleanup.
+	waitWriter, ok := writer.(*waitCloser)
+	if ok {
+		return waitWriter.CloseWithError(errors.New("Close and error out."))
+	}
+	// If writer uploads components, delete the ones uploaded.
+	gcsWriter, ok := writer.(*gcsObjectWriter)
+	if ok {
+		return gcsWriter.CloseAndRemove()
+	}
+	// If writer is scheduled, remove the file it writes to.
+	scheduledWriter, ok := writer.(*scheduledWriter)
+	if ok {
+		return safeCloseAndRemove(scheduledWriter.WriteCloser)
+	}
+	// If writer can be faulted, remove the file it writes to.
+	faultyWriter, ok := writer.(*faultyWriter)
+	if ok {
+		return safeCloseAndRemove(faultyWriter.WriteCloser)
+	}
+	return nil
+}
+
+// readMetaFile - reads at most maxSize bytes of a file in
+// minioMetaVolume.
+func (o objectAPI) readMetaFile(metaPath string, maxSize int64) ([]byte, error) {
+	r, e := o.storage.ReadFile(context.Background(), minioMetaVolume, metaPath, 0)
+	if e != nil {
+		return nil, e
+	}
+	defer r.Close()
+	return ioutil.ReadAll(io.LimitReader(r, maxSize))
+}
+
+// writeMetaFile - saves data to a file in minioMetaVolume.
+func (o objectAPI) writeMetaFile(metaPath string, data []byte) error {
+	w, e := o.storage.CreateFile(context.Background(), minioMetaVolume, metaPath)
+	if e != nil {
+		return e
+	}
+	if _, e = w.Write(data); e != nil {
+		safeCloseAndRemove(w)
+		return e
+	}
+	return w.Close()
+}
+
+// objectSizeLimitReader - reads data of an object of unknown size,
+// fails once more than left bytes are read.
+type objectSizeLimitReader struct {
+	io.Reader
+	bucket, object string
+	left           int64
+}
+
+func (r *objectSizeLimitReader) Read(p []byte) (int, error) {
+	n, e := r.Reader.Read(p)
+	r.left -= int64(n)
+	if r.left < 0 {
+		return n, ObjectTooLarge{Bucket: r.bucket, Object: r.object}
+	}
+	return n, e
+}
+
+// checkFreeSpace - returns errDiskFull if size bytes do not fit in the
+// space left on the disks of volume, before any of them is written.
+// Writes of unknown size, and to disks which cannot report their
+// space, are not checked.
+func (o objectAPI) checkFreeSpace(volume string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	vfsInfo, e := o.storage.StatVFS(volume)
+	if e != nil {
+		// Writes fail on their own if disks cannot be reached.
+		return nil
+	}
+	if vfsInfo.Free >= 0 && size > vfsInfo.Free {
+		return errDiskFull
+	}
 	return nil
 }
 
@@ -212,15 +507,67 @@ func (o objectAPI) PutObject(bucket string, object string, size int64, data io.R
 		})
 	}
 	// Check whether the bucket exists.
-	isExist, err := o.isBucketExist(bucket)
-	if err != nil {
-		return "", probe.NewError(err)
+	isExist, e := o.isBucketExist(bucket)
+	if e != nil {
+		return "", probe.NewError(e)
 	}
 	if !isExist {
 		return "", probe.NewError(BucketNotFound{Bucket: bucket})
 	}
 
-	fileWriter, e := o.storage.CreateFile(bucket, object)
+	// Objects are put with a known storage class, STANDARD objects
+	// record none.
+	if name, ok := metadata[amzStorageClassHeader]; ok {
+		if _, ok = getStorageClass(name); !ok {
+			return "", probe.NewError(InvalidStorageClass{StorageClass: name})
+		}
+		if name == storageClassStandard {
+			delete(metadata, amzStorageClassHeader)
+		}
+	}
+
+	// Objects which do not fit in the quota of their bucket are
+	// refused.
+	if err := globalBucketQuotas.Check(bucket, size); err != nil {
+		return "", err.Trace(bucket, object)
+	}
+
+	// Retained or held objects cannot be overwritten.
+	lock, err := o.newObjectLock(bucket, object, metadata)
+	if err != nil {
+		return "", err.Trace(bucket, object)
+	}
+	// Objects of unknown size are refused once they are larger than
+	// the maximum object size.
+	if size < 0 {
+		data = &objectSizeLimitReader{Reader: data, bucket: bucket, object: object, left: globalObjectLimits.Get().maxObjectSize()}
+	} else if isMaxObjectSize(size) {
+		return "", probe.NewError(ObjectTooLarge{Bucket: bucket, Object: object})
+	}
+	if e = o.checkFreeSpace(bucket, size); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
+	hasher := o.newAttestationHasher()
+	if hasher != nil {
+		data = io.TeeReader(data, hasher)
+	}
+	counter := &quotaCountingReader{Reader: data}
+	md5Sum, err := o.putObject(bucket, object, size, counter, metadata, lock)
+	if err != nil {
+		return "", err.Trace(bucket, object)
+	}
+	globalDataUsage.AddWritten(bucket, counter.n)
+	if e = o.attestObject(bucket, object, md5Sum, hasher); e != nil {
+		return "", probe.NewError(toObjectErr(e, bucket, object))
+	}
+	o.markReplicationPending(bucket, object)
+	return md5Sum, nil
+}
+
+// putObject - writes object data and metadata, with retention and
+// legal hold of lock.
+func (o objectAPI) putObject(bucket string, object string, size int64, data io.Reader, metadata map[string]string, lock objectLock) (string, *probe.Error) {
+	fileWriter, e := o.createObjectFile(bucket, object, metadata)
 	if e != nil {
 		return "", probe.NewError(toObjectErr(e, bucket, object))
 	}
@@ -231,9 +578,24 @@ func (o objectAPI) PutObject(bucket string, object string, size int64, data io.R
 	// Instantiate a new multi writer.
 	multiWriter := io.MultiWriter(md5Writer, fileWriter)
 
+	// Keep received bytes in quarantine until they are verified.
+	quarantine := o.newQuarantineWriter()
+	if quarantine != nil {
+		data = io.TeeReader(data, quarantine)
+	}
+	quarantined := quarantineInfo{Bucket: bucket, Object: object, Metadata: metadata}
+
+	// Cache written data if configured to.
+	fill := o.newWriteFill(bucket, object, size)
+	if fill != nil {
+		data = io.TeeReader(data, fill)
+		defer fill.abort()
+	}
+
 	// Instantiate checksum hashers and create a multiwriter.
 	if size > 0 {
 		if _, e = io.CopyN(multiWriter, data, size); e != nil {
+			quarantine.settle(toObjectErr(e), quarantined)
 			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
 				return "", probe.NewError(clErr)
 			}
@@ -241,6 +603,7 @@ func (o objectAPI) PutObject(bucket string, object string, size int64, data io.R
 		}
 	} else {
 		if _, e = io.Copy(multiWriter, data); e != nil {
+			quarantine.abort()
 			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
 				return "", probe.NewError(clErr)
 			}
@@ -256,22 +619,58 @@ func (o objectAPI) PutObject(bucket string, object string, size int64, data io.R
 	}
 	if md5Hex != "" {
 		if newMD5Hex != md5Hex {
+			quarantine.settle(BadDigest{md5Hex, newMD5Hex}, quarantined)
 			if e = safeCloseAndRemove(fileWriter); e != nil {
 				return "", probe.NewError(e)
 			}
 			return "", probe.NewError(BadDigest{md5Hex, newMD5Hex})
 		}
 	}
-	e = fileWriter.Close()
+	quarantine.abort()
+	endCommit := o.beginCommit(bucket, object)
+	e = o.sequenced(bucket, object, func(sequence uint64) error {
+		if e := o.checkWriteCondition(bucket, object); e != nil {
+			safeCloseAndRemove(fileWriter)
+			return e
+		}
+		objectMetadata, e := newObjectMetadata(newMD5Hex, filterUserMetadata(metadata), lock, sequence)
+		if e != nil {
+			safeCloseAndRemove(fileWriter)
+			return e
+		}
+		previous := o.statObjectMetadata(bucket, object)
+		// Metadata is committed along with the data, write conditions
+		// see the etag of the data they replace.
+		e = o.commitObject(bucket, object, fileWriter, objectMetadata)
+		if e != nil && e != errSequenceNotSaved {
+			return e
+		}
+		o.notFound.Invalidate(bucket, object)
+		o.cache.Invalidate(bucket, object)
+		o.removeTransitioned(bucket, object, previous)
+		return e
+	})
+	endCommit()
 	if e != nil {
-		return "", probe.NewError(e)
+		return "", probe.NewError(toObjectErr(e, bucket, object))
 	}
+	o.commitWriteFill(fill, bucket, object)
 
 	// Return md5sum, successfully wrote object.
 	return newMD5Hex, nil
 }
 
-func (o objectAPI) DeleteObject(bucket, object string) *probe.Error {
+// DeleteObject - deletes object, held objects cannot be deleted and
+// retained objects only if their governance retention is bypassed.
+// Deleted objects are kept in trash while it is enabled.
+func (o objectAPI) DeleteObject(bucket, object string, bypassGovernance bool) *probe.Error {
+	return o.deleteObject(bucket, object, bypassGovernance, true)
+}
+
+// deleteObject - deletes object, keeping it in trash if trash is set
+// and enabled. Objects which cannot be kept are not deleted, unless
+// the disks are full.
+func (o objectAPI) deleteObject(bucket, object string, bypassGovernance, trash bool) *probe.Error {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
 		return probe.NewError(BucketNameInvalid{Bucket: bucket})
@@ -279,9 +678,45 @@ func (o objectAPI) DeleteObject(bucket, object string) *probe.Error {
 	if !IsValidObjectName(object) {
 		return probe.NewError(ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
-	if e := o.storage.DeleteFile(bucket, object); e != nil {
+	if err := o.checkObjectLock(bucket, object, nil, bypassGovernance); err != nil {
+		return err.Trace(bucket, object)
+	}
+	if trash {
+		// Deleting must still free space of full disks, objects are
+		// deleted without being kept then.
+		if e := o.moveToTrash(bucket, object); e != nil {
+			if errorCause(e) != errDiskFull {
+				return probe.NewError(toObjectErr(e, bucket, object))
+			}
+			errorIf(probe.NewError(e).Trace(bucket, object), "Unable to keep deleted object in trash.", nil)
+		}
+	}
+	endCommit := o.beginCommit(bucket, object)
+	var previous map[string]string
+	e := o.sequenced(bucket, object, func(sequence uint64) error {
+		previous = o.statObjectMetadata(bucket, object)
+		if e := o.storage.DeleteFile(context.Background(), bucket, object); e != nil {
+			return e
+		}
+		// The sequence number of the key outlives its metadata.
+		e := o.saveDeletedSequence(bucket, object, sequence)
+		if e == nil {
+			e = o.removeObjectMetadata(bucket, object)
+		}
+		if e != nil {
+			errorIf(probe.NewError(e).Trace(bucket, object), "Unable to remove object metadata.", nil)
+			return errSequenceNotSaved
+		}
+		return nil
+	})
+	endCommit()
+	if e != nil {
 		return probe.NewError(toObjectErr(e, bucket, object))
 	}
+	o.cache.Invalidate(bucket, object)
+	o.removeTransitioned(bucket, object, previous)
+	o.removeObjectAttestation(bucket, object)
+	o.removeReplicationStatus(bucket, object)
 	return nil
 }
 
@@ -332,12 +767,23 @@ func (o objectAPI) ListObjects(bucket, prefix, marker, delimiter string, maxKeys
 				continue
 			}
 		}
-		result.Objects = append(result.Objects, ObjectInfo{
+		objInfo := ObjectInfo{
 			Name:    fileInfo.Name,
 			ModTime: fileInfo.ModTime,
 			Size:    fileInfo.Size,
 			IsDir:   false,
-		})
+		}
+		// State of each object is read once, from its metadata,
+		// listed along with the file by storage which keeps it.
+		metadata := o.fileObjectMetadata(bucket, fileInfo.Name, fileInfo)
+		if stub, ok := objectTierStub(bucket, fileInfo.Name, metadata, fileInfo.Size); ok {
+			objInfo.ModTime = stub.ModTime
+			objInfo.Size = stub.Size
+		}
+		objInfo.Sequence = objectSequence(bucket, fileInfo.Name, metadata)
+		userMetadata, _ := userObjectMetadata(metadata)
+		objInfo.StorageClass = objectStorageClass(userMetadata)
+		result.Objects = append(result.Objects, objInfo)
 	}
 	return result, nil
 }
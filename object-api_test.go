@@ -19,6 +19,10 @@ package main
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
 	. "gopkg.in/check.v1"
 )
@@ -62,8 +66,95 @@ func (s *MySuite) TestXLAPISuite(c *C) {
 	defer removeRoots(c, storageList)
 }
 
+// Tests the backend selected on the command line is used regardless
+// of the export paths style.
+func (s *MySuite) TestStorageBackend(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "minio-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+	var disks []string
+	for i := 0; i < 4; i++ {
+		// A ':' selects network storage unless the backend is fs.
+		disk := filepath.Join(root, "disk:"+string(rune('a'+i)))
+		c.Assert(os.Mkdir(disk, 0700), IsNil)
+		disks = append(disks, disk)
+	}
+
+	storage, err := newStorageBackend(storageBackendFS, disks[0])
+	c.Assert(err, IsNil)
+	_, ok := storage.(fsStorage)
+	c.Assert(ok, Equals, true)
+	storage, err = newStorageBackend(storageBackendXL, disks...)
+	c.Assert(err, IsNil)
+	_, ok = storage.(*XL)
+	c.Assert(ok, Equals, true)
+	storage, err = newStorageBackend("", root)
+	c.Assert(err, IsNil)
+	_, ok = storage.(fsStorage)
+	c.Assert(ok, Equals, true)
+
+	_, err = newStorageBackend(storageBackendFS, disks...)
+	c.Assert(err, NotNil)
+	_, err = newStorageBackend("nfs", disks[0])
+	c.Assert(err, NotNil)
+}
+
 func removeRoots(c *C, roots []string) {
 	for _, root := range roots {
 		os.RemoveAll(root)
 	}
 }
+
+// Tests write conditions are evaluated atomically with the commit, of
+// concurrent puts creating an object only if it does not exist exactly
+// one succeeds.
+func (s *MySuite) TestWriteCondition(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "minio-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+	storage, err := newStorageAPI(root)
+	c.Assert(err, IsNil)
+	obj := newObjectLayer(storage)
+	c.Assert(obj.MakeBucket("bucket"), IsNil)
+
+	ifNoneExists := obj.WithWriteCondition(func(objInfo ObjectInfo, exists bool) bool {
+		return !exists
+	})
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var created []string
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			_, perr := ifNoneExists.PutObject("bucket", "object", int64(len(data)), strings.NewReader(data), nil)
+			if perr == nil {
+				mutex.Lock()
+				created = append(created, data)
+				mutex.Unlock()
+				return
+			}
+			_, ok := perr.ToGoError().(PreconditionFailed)
+			c.Check(ok, Equals, true)
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	c.Assert(len(created), Equals, 1)
+
+	// The object kept is the one created, conditions see its etag.
+	r, perr := obj.GetObject("bucket", "object", 0)
+	c.Assert(perr, IsNil)
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, created[0])
+	objInfo, perr := obj.GetObjectInfo("bucket", "object")
+	c.Assert(perr, IsNil)
+	ifMatch := obj.WithWriteCondition(func(current ObjectInfo, exists bool) bool {
+		return exists && current.MD5Sum == objInfo.MD5Sum
+	})
+	_, perr = ifMatch.PutObject("bucket", "object", 3, strings.NewReader("new"), nil)
+	c.Assert(perr, IsNil)
+	_, perr = ifMatch.PutObject("bucket", "object", 3, strings.NewReader("old"), nil)
+	c.Assert(perr, NotNil)
+}
@@ -0,0 +1,65 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetObjectAttestationHandler - GET Object attestation
+// -----------------
+// This implementation of the GET operation uses the attestation
+// subresource to return the server signed attestation of an object,
+// recorded when the object was written.
+func (api objectAPIHandlers) GetObjectAttestationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// Attestations are readable by anyone allowed to read the object.
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r.URL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	attestation, err := api.ObjectAPI.GetObjectAttestation(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectAttestation failed.", nil)
+		switch err.ToGoError().(type) {
+		case AttestationNotFound:
+			writeErrorResponse(w, r, ErrNoSuchAttestation, r.URL.Path)
+		default:
+			writeObjectTaggingError(w, r, err)
+		}
+		return
+	}
+	writeAdminResponse(w, r, attestation)
+}
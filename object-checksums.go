@@ -0,0 +1,158 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Additional checksums of objects, sent by clients as headers or as
+// trailers of chunked bodies declared by amzTrailerHeader. Verified
+// checksums are saved with the object and returned on GET and HEAD.
+const (
+	checksumCRC32Key  = "X-Amz-Checksum-Crc32"
+	checksumCRC32CKey = "X-Amz-Checksum-Crc32c"
+	checksumSHA1Key   = "X-Amz-Checksum-Sha1"
+	checksumSHA256Key = "X-Amz-Checksum-Sha256"
+
+	amzTrailerHeader = "X-Amz-Trailer"
+)
+
+// objectChecksumHashes - hashes of additional checksums by their key.
+var objectChecksumHashes = map[string]func() hash.Hash{
+	checksumCRC32Key:  func() hash.Hash { return crc32.NewIEEE() },
+	checksumCRC32CKey: func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	checksumSHA1Key:   sha1.New,
+	checksumSHA256Key: sha256.New,
+}
+
+// isChecksumMetadataKey - returns true if canonical key is of an
+// additional checksum.
+func isChecksumMetadataKey(key string) bool {
+	_, ok := objectChecksumHashes[key]
+	return ok
+}
+
+// validChecksum - returns true if value is a base64 encoded checksum
+// of the size of hasher.
+func validChecksum(value string, hasher hash.Hash) bool {
+	decoded, e := base64.StdEncoding.DecodeString(value)
+	return e == nil && len(decoded) == hasher.Size()
+}
+
+// checksumReader - reads an object body computing its additional
+// checksum, verified once the body is read whole.
+type checksumReader struct {
+	io.Reader
+	request  *http.Request
+	key      string
+	expected string // Empty until the trailer is read if trailing.
+	hasher   hash.Hash
+	size     int64
+	read     int64
+	verified bool
+	metadata map[string]string
+}
+
+// newChecksumReader - returns reader of the body of r with size
+// verifying the additional checksum sent, nil if none. The verified
+// checksum is added to metadata before the end of the body is read.
+func newChecksumReader(r *http.Request, size int64, metadata map[string]string) (*checksumReader, APIErrorCode) {
+	var keys []string
+	for key := range objectChecksumHashes {
+		if r.Header.Get(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+	trailing := false
+	if trailer := r.Header.Get(amzTrailerHeader); trailer != "" {
+		keys = append(keys, http.CanonicalHeaderKey(strings.TrimSpace(trailer)))
+		trailing = true
+	}
+	if len(keys) == 0 {
+		return nil, ErrNone
+	}
+	if len(keys) > 1 || !isChecksumMetadataKey(keys[0]) {
+		return nil, ErrInvalidChecksum
+	}
+	// Only chunked bodies have trailers.
+	if trailing && size != -1 {
+		return nil, ErrInvalidChecksum
+	}
+	reader := &checksumReader{
+		Reader:   r.Body,
+		request:  r,
+		key:      keys[0],
+		hasher:   objectChecksumHashes[keys[0]](),
+		size:     size,
+		metadata: metadata,
+	}
+	if !trailing {
+		reader.expected = r.Header.Get(reader.key)
+		if !validChecksum(reader.expected, reader.hasher) {
+			return nil, ErrInvalidChecksum
+		}
+	}
+	// Empty bodies are never read.
+	if size == 0 {
+		if e := reader.verify(); e != nil {
+			return nil, ErrBadChecksum
+		}
+	}
+	return reader, ErrNone
+}
+
+// Read - reads the body, failing with BadChecksum instead of its end if
+// the checksum does not match. The last bytes read are withheld then,
+// readers of exactly size bytes never see the body as complete.
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, e := c.Reader.Read(p)
+	c.hasher.Write(p[:n])
+	c.read += int64(n)
+	if !c.verified && (e == io.EOF || (c.size >= 0 && c.read == c.size)) {
+		if vErr := c.verify(); vErr != nil {
+			return 0, vErr
+		}
+	}
+	return n, e
+}
+
+// verify - compares the checksum computed with the one sent, saving
+// it to metadata if they match.
+func (c *checksumReader) verify() error {
+	expected := c.expected
+	if expected == "" {
+		// Trailers are read along with the end of the body.
+		expected = c.request.Trailer.Get(c.key)
+		if !validChecksum(expected, c.hasher) {
+			return BadChecksum{Key: c.key}
+		}
+	}
+	if base64.StdEncoding.EncodeToString(c.hasher.Sum(nil)) != expected {
+		return BadChecksum{Key: c.key}
+	}
+	c.metadata[c.key] = expected
+	c.verified = true
+	return nil
+}
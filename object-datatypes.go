@@ -35,6 +35,15 @@ type ObjectInfo struct {
 	MD5Sum      string
 	Size        int64
 	IsDir       bool
+	// User defined metadata, "X-Amz-Meta-" prefixed keys, and
+	// standard headers saved along with the object.
+	UserDefined map[string]string
+	// Replication status, empty if bucket is not replicated.
+	ReplicationStatus string
+	// Sequence number of the last mutation of the key.
+	Sequence uint64
+	// Storage class the object was put with.
+	StorageClass string
 }
 
 // ListPartsInfo - various types of object resources.
@@ -43,6 +52,7 @@ type ListPartsInfo struct {
 	Object               string
 	UploadID             string
 	StorageClass         string
+	Initiator            string
 	PartNumberMarker     int
 	NextPartNumberMarker int
 	MaxParts             int
@@ -88,7 +98,9 @@ type uploadMetadata struct {
 	Object       string
 	UploadID     string
 	StorageClass string
-	Initiated    time.Time
+	// Access key which initiated the upload, empty if unknown.
+	Initiator string
+	Initiated time.Time
 }
 
 // completePart - completed part container.
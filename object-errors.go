@@ -25,7 +25,15 @@ import (
 // handle all cases where we have known types of errors returned by
 // underlying storage layer.
 func toObjectErr(err error, params ...string) error {
-	switch err {
+	// Read quorum errors carry diagnostics of the failed disks.
+	if qErr, ok := err.(readQuorumError); ok {
+		return StorageInsufficientReadResources{
+			ReadQuorum: qErr.ReadQuorum,
+			Available:  qErr.Available,
+			Disks:      qErr.Disks,
+		}
+	}
+	switch errorCause(err) {
 	case errVolumeNotFound:
 		if len(params) >= 1 {
 			return BucketNotFound{Bucket: params[0]}
@@ -34,12 +42,20 @@ func toObjectErr(err error, params ...string) error {
 		if len(params) >= 1 {
 			return BucketExists{Bucket: params[0]}
 		}
+	case errInvalidVolumeName:
+		if len(params) >= 1 {
+			return BucketNameInvalid{Bucket: params[0]}
+		}
 	case errDiskFull:
 		return StorageFull{}
 	case errReadQuorum:
 		return StorageInsufficientReadResources{}
 	case errWriteQuorum:
 		return StorageInsufficientWriteResources{}
+	case errInvalidPlacement:
+		return InvalidPlacement{}
+	case errLockTimeout:
+		return OperationTimedOut{}
 	case errIsNotRegular:
 		if len(params) >= 2 {
 			return ObjectExistsAsPrefix{
@@ -68,7 +84,13 @@ func (e StorageFull) Error() string {
 }
 
 // StorageInsufficientReadResources storage cannot satisfy quorum for read operation.
-type StorageInsufficientReadResources struct{}
+// Diagnostics are set when known, ReadQuorum disks were required and
+// only Available disks were readable.
+type StorageInsufficientReadResources struct {
+	ReadQuorum int         `json:"readQuorum,omitempty"`
+	Available  int         `json:"available"`
+	Disks      []diskError `json:"failedDisks,omitempty"`
+}
 
 func (e StorageInsufficientReadResources) Error() string {
 	return "Storage resources are insufficient for the read operation."
@@ -81,6 +103,13 @@ func (e StorageInsufficientWriteResources) Error() string {
 	return "Stroage resources are insufficient for the write operation."
 }
 
+// OperationTimedOut a lock of the object was not acquired in time.
+type OperationTimedOut struct{}
+
+func (e OperationTimedOut) Error() string {
+	return "Operation timed out waiting for a lock of the object."
+}
+
 // GenericError - generic object layer error.
 type GenericError struct {
 	Bucket string
@@ -132,6 +161,26 @@ func (e BadDigest) Error() string {
 	return "Bad digest: Expected " + e.ExpectedMD5 + " is not valid with what we calculated " + e.CalculatedMD5
 }
 
+// BadChecksum - additional checksum you specified did not match what
+// we received.
+type BadChecksum struct {
+	Key string
+}
+
+func (e BadChecksum) Error() string {
+	return "Bad checksum: " + e.Key + " is not valid with what we calculated"
+}
+
+// InvalidStorageClass - storage class is not known, or its parity is
+// not supported by the disks.
+type InvalidStorageClass struct {
+	StorageClass string
+}
+
+func (e InvalidStorageClass) Error() string {
+	return "Invalid storage class: " + e.StorageClass
+}
+
 // UnsupportedDelimiter - unsupported delimiter.
 type UnsupportedDelimiter struct {
 	Delimiter string
@@ -166,6 +215,134 @@ func (e BucketPolicyNotFound) Error() string {
 	return "No bucket policy found for bucket: " + e.Bucket
 }
 
+// BucketLifecycleNotFound - no bucket lifecycle configuration found.
+type BucketLifecycleNotFound GenericError
+
+func (e BucketLifecycleNotFound) Error() string {
+	return "No bucket lifecycle configuration found for bucket: " + e.Bucket
+}
+
+// BucketCorsNotFound - no bucket CORS configuration found.
+type BucketCorsNotFound GenericError
+
+func (e BucketCorsNotFound) Error() string {
+	return "No bucket CORS configuration found for bucket: " + e.Bucket
+}
+
+// BucketReplicationNotFound - no bucket replication configuration found.
+type BucketReplicationNotFound GenericError
+
+func (e BucketReplicationNotFound) Error() string {
+	return "No bucket replication configuration found for bucket: " + e.Bucket
+}
+
+// TierNotFound - remote tier is not configured.
+type TierNotFound struct {
+	Tier string
+}
+
+func (e TierNotFound) Error() string {
+	return "Remote tier not configured: " + e.Tier
+}
+
+// ReadSessionNotFound - read session does not exist or has expired.
+type ReadSessionNotFound struct {
+	ID string
+}
+
+func (e ReadSessionNotFound) Error() string {
+	return "Read session not found: " + e.ID
+}
+
+// TooManyReadSessions - maximum number of read sessions are open.
+type TooManyReadSessions struct{}
+
+func (e TooManyReadSessions) Error() string {
+	return "Too many open read sessions"
+}
+
+// ObjectLocked - object is retained and cannot be overwritten or
+// deleted.
+type ObjectLocked GenericError
+
+func (e ObjectLocked) Error() string {
+	return "Object is WORM protected: " + e.Bucket + "#" + e.Object
+}
+
+// PreconditionFailed - object replaced by a write does not meet the
+// conditions of the write.
+type PreconditionFailed GenericError
+
+func (e PreconditionFailed) Error() string {
+	return "Precondition failed for object: " + e.Bucket + "#" + e.Object
+}
+
+// ObjectRetentionNotFound - object has no retention.
+type ObjectRetentionNotFound GenericError
+
+func (e ObjectRetentionNotFound) Error() string {
+	return "No retention found for object: " + e.Bucket + "#" + e.Object
+}
+
+// BucketObjectLockNotFound - object lock is not enabled on bucket.
+type BucketObjectLockNotFound GenericError
+
+func (e BucketObjectLockNotFound) Error() string {
+	return "No object lock configuration found for bucket: " + e.Bucket
+}
+
+// BucketObjectLocked - object lock is enabled on bucket, it cannot be
+// force deleted.
+type BucketObjectLocked GenericError
+
+func (e BucketObjectLocked) Error() string {
+	return "Object lock is enabled on bucket: " + e.Bucket
+}
+
+// InvalidRetention - retention is not valid.
+type InvalidRetention struct {
+	Reason string
+}
+
+func (e InvalidRetention) Error() string {
+	return "Invalid retention: " + e.Reason
+}
+
+// PresignRevocationNotFound - signer is not revoked.
+type PresignRevocationNotFound struct {
+	Signer string
+}
+
+func (e PresignRevocationNotFound) Error() string {
+	return "Presigned requests of signer " + e.Signer + " are not revoked"
+}
+
+// InvalidLegalHold - legal hold status is neither ON nor OFF.
+type InvalidLegalHold struct {
+	Status string
+}
+
+func (e InvalidLegalHold) Error() string {
+	return "Invalid legal hold status: " + e.Status
+}
+
+// InvalidArchive - uploaded archive cannot be extracted.
+type InvalidArchive struct {
+	Reason string
+}
+
+func (e InvalidArchive) Error() string {
+	return "Invalid archive: " + e.Reason
+}
+
+// ArchiveEntryDenied - requester may not create the object of an
+// archive entry.
+type ArchiveEntryDenied GenericError
+
+func (e ArchiveEntryDenied) Error() string {
+	return "Access denied to archive entry: " + e.Bucket + "#" + e.Object
+}
+
 /// Bucket related errors.
 
 // BucketNameInvalid - bucketname provided is invalid.
@@ -203,6 +380,13 @@ func (e IncompleteBody) Error() string {
 	return e.Bucket + "#" + e.Object + "has incomplete body"
 }
 
+// ObjectTooLarge - object is larger than maximum object size.
+type ObjectTooLarge GenericError
+
+func (e ObjectTooLarge) Error() string {
+	return e.Bucket + "#" + e.Object + " is larger than maximum object size"
+}
+
 /// Multipart related errors.
 
 // MalformedUploadID malformed upload id.
@@ -238,3 +422,197 @@ type InvalidPartOrder struct {
 func (e InvalidPartOrder) Error() string {
 	return "Invalid part order sent for " + e.UploadID
 }
+
+// HealNotSupported - storage cannot heal objects.
+type HealNotSupported struct{}
+
+func (e HealNotSupported) Error() string {
+	return "Heal is not supported by the storage backend"
+}
+
+// HealAlreadyRunning - a heal operation is already running.
+type HealAlreadyRunning struct{}
+
+func (e HealAlreadyRunning) Error() string {
+	return "Heal is already running"
+}
+
+// HealNotRunning - no heal operation is running.
+type HealNotRunning struct{}
+
+func (e HealNotRunning) Error() string {
+	return "Heal is not running"
+}
+
+// VerifyNotSupported - storage cannot verify objects.
+type VerifyNotSupported struct{}
+
+func (e VerifyNotSupported) Error() string {
+	return "Object verification is not supported by the storage backend"
+}
+
+// MigrationNotSupported - storage has no metadata formats to migrate.
+type MigrationNotSupported struct{}
+
+func (e MigrationNotSupported) Error() string {
+	return "Format migration is not supported by the storage backend"
+}
+
+// MigrationAlreadyRunning - a format migration is already running.
+type MigrationAlreadyRunning struct{}
+
+func (e MigrationAlreadyRunning) Error() string {
+	return "Format migration is already running"
+}
+
+// PlacementNotSupported - storage cannot pin buckets to disks.
+type PlacementNotSupported struct{}
+
+func (e PlacementNotSupported) Error() string {
+	return "Bucket placement is not supported by the storage backend"
+}
+
+// InvalidPlacement - placement is not a valid subset of disks.
+type InvalidPlacement struct{}
+
+func (e InvalidPlacement) Error() string {
+	return "Placement should be an even number of distinct disks"
+}
+
+// InvalidPartMapParts - requested number of parts is out of range.
+type InvalidPartMapParts struct {
+	Parts int
+}
+
+func (e InvalidPartMapParts) Error() string {
+	return fmt.Sprintf("Invalid number of parts: %d", e.Parts)
+}
+
+// TooManyMultipartUploads - bucket or access key reached its limit of
+// in-progress multipart uploads.
+type TooManyMultipartUploads struct {
+	Bucket    string
+	AccessKey string
+	Limit     int
+}
+
+func (e TooManyMultipartUploads) Error() string {
+	if e.AccessKey != "" {
+		return fmt.Sprintf("Access key %s reached its limit of %d in-progress multipart uploads", e.AccessKey, e.Limit)
+	}
+	return fmt.Sprintf("Bucket %s reached its limit of %d in-progress multipart uploads", e.Bucket, e.Limit)
+}
+
+// AttestationNotFound - object was written while attestations were
+// disabled.
+type AttestationNotFound GenericError
+
+func (e AttestationNotFound) Error() string {
+	return "No attestation found for object: " + e.Bucket + "#" + e.Object
+}
+
+// BandwidthLimitNotFound - no bandwidth limit is set for the target.
+type BandwidthLimitNotFound struct {
+	Target string
+	Name   string
+}
+
+func (e BandwidthLimitNotFound) Error() string {
+	return "No bandwidth limit set for " + e.Target + " " + e.Name
+}
+
+// UserNotFound - no user with the access key exists.
+type UserNotFound struct {
+	AccessKey string
+}
+
+func (e UserNotFound) Error() string {
+	return "User not found: " + e.AccessKey
+}
+
+// PolicyNotFound - no canned or custom policy with the name exists.
+type PolicyNotFound struct {
+	Policy string
+}
+
+func (e PolicyNotFound) Error() string {
+	return "Policy not found: " + e.Policy
+}
+
+// PolicyInUse - policy is attached to a user.
+type PolicyInUse struct {
+	Policy    string
+	AccessKey string
+}
+
+func (e PolicyInUse) Error() string {
+	return "Policy " + e.Policy + " is attached to user " + e.AccessKey
+}
+
+// QuarantineNotFound - no upload with the id is in quarantine.
+type QuarantineNotFound struct {
+	ID string
+}
+
+func (e QuarantineNotFound) Error() string {
+	return "Quarantined upload not found: " + e.ID
+}
+
+// TrashNotFound - no deleted object with the id is in trash.
+type TrashNotFound struct {
+	ID string
+}
+
+func (e TrashNotFound) Error() string {
+	return "Trashed object not found: " + e.ID
+}
+
+// EventTargetNotFound - no notification target with the id is active,
+// or has undelivered events.
+type EventTargetNotFound struct {
+	Target string
+}
+
+func (e EventTargetNotFound) Error() string {
+	return "Notification target not found: " + e.Target
+}
+
+// ServiceAccountNotFound - no service account with the access key
+// exists.
+type ServiceAccountNotFound struct {
+	AccessKey string
+}
+
+func (e ServiceAccountNotFound) Error() string {
+	return "Service account not found: " + e.AccessKey
+}
+
+// TooManyServiceAccounts - parent has the maximum number of service
+// accounts.
+type TooManyServiceAccounts struct {
+	Parent string
+}
+
+func (e TooManyServiceAccounts) Error() string {
+	return "Too many service accounts of " + e.Parent
+}
+
+// QuotaExceeded - writing the object would exceed the quota of its
+// bucket.
+type QuotaExceeded struct {
+	Bucket string
+	Quota  int64
+}
+
+func (e QuotaExceeded) Error() string {
+	return fmt.Sprintf("Quota of %d bytes exceeded for bucket %s", e.Quota, e.Bucket)
+}
+
+// BucketQuotaNotFound - no quota is set for the bucket.
+type BucketQuotaNotFound struct {
+	Bucket string
+}
+
+func (e BucketQuotaNotFound) Error() string {
+	return "No quota set for bucket " + e.Bucket
+}
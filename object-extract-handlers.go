@@ -0,0 +1,205 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+
+	mux "github.com/gorilla/mux"
+	fastSha256 "github.com/minio/minio/pkg/crypto/sha256"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// archivePrefix - returns prefix archive entries are extracted under,
+// the directory of the archive object name.
+func archivePrefix(object string) string {
+	prefix := path.Dir(object)
+	if prefix == "." || prefix == slashSeparator {
+		return ""
+	}
+	return retainSlash(prefix)
+}
+
+// archiveBodyReader - reads size bytes of the request body of r. Signed
+// requests are verified against the sha256 of the body once it is read.
+// Failures are kept, archive readers may report them otherwise.
+type archiveBodyReader struct {
+	r    *http.Request
+	left int64
+	// Hash of the body read so far, nil for anonymous requests.
+	sha hash.Hash
+	err error
+}
+
+func (b *archiveBodyReader) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.left == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, e := b.r.Body.Read(p)
+	b.left -= int64(n)
+	if b.sha != nil {
+		b.sha.Write(p[:n])
+	}
+	switch {
+	case b.left == 0:
+		e = io.EOF
+		if b.sha != nil && !b.signatureMatches() {
+			b.err = errSignatureMismatch
+			e = b.err
+		}
+	case e == io.EOF:
+		b.err = io.ErrUnexpectedEOF
+		e = b.err
+	case e != nil:
+		b.err = e
+	}
+	return n, e
+}
+
+// signatureMatches - verifies the signature of the request against the
+// body read.
+func (b *archiveBodyReader) signatureMatches() bool {
+	shaPayload := hex.EncodeToString(b.sha.Sum(nil))
+	validateRegion := true // Validate region.
+	if isRequestSignatureV4(b.r) {
+		return doesSignatureMatch(shaPayload, b.r, validateRegion) == ErrNone
+	}
+	return doesPresignedSignatureMatch(shaPayload, b.r, validateRegion) == ErrNone
+}
+
+// ExtractArchiveHandler - PUT Object with X-Minio-Extract
+// -----------------
+// This implementation of the PUT operation extracts an uploaded tar,
+// gzip compressed tar or zip archive into one object per regular file,
+// under the directory of the archive object name. The archive itself
+// is not saved. Entry modification times are saved as the
+// X-Amz-Meta-Mtime metadata of each object. Users need s3:PutObject on
+// the object of every entry.
+func (api objectAPIHandlers) ExtractArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	/// if Content-Length is unknown/missing, deny the request
+	size := r.ContentLength
+	if size == -1 {
+		writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+		return
+	}
+	/// maximum Upload size for objects in a single operation
+	if isMaxObjectSize(size) {
+		writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+		return
+	}
+
+	authType := getRequestAuthType(r)
+	switch authType {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		// The archive is extracted as it is read, the payload is
+		// verified once it is read.
+		if s3Error := isReqHeaderAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	body := &archiveBodyReader{r: r, left: size}
+	if authType != authTypeAnonymous {
+		body.sha = fastSha256.New()
+	}
+	accessKey := getAuditRequester(r)
+	allowed := func(object string) bool {
+		return globalIAMUsers.IsAllowed(accessKey, "s3:PutObject", getIAMResource(bucket, object))
+	}
+	prefix := archivePrefix(object)
+	objects, err := api.ObjectAPI.ExtractArchive(bucket, prefix, body, allowed)
+	if err == nil {
+		// Read the rest of the body, verifying its signature.
+		io.Copy(ioutil.Discard, body)
+	}
+	// Notify object created events, also for objects extracted before
+	// a failure.
+	for _, extracted := range objects {
+		notifyObjectCreated(api.ObjectAPI, r, ObjectCreatedPut, bucket, extracted.Name, extracted.MD5Sum, extracted.Sequence)
+	}
+	switch body.err {
+	case nil:
+	case errSignatureMismatch:
+		writeErrorResponse(w, r, ErrSignatureDoesNotMatch, r.URL.Path)
+		return
+	case io.ErrUnexpectedEOF:
+		writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
+		return
+	default:
+		errorIf(probe.NewError(body.err), "Unable to read HTTP body.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "ExtractArchive failed.", nil)
+		switch err.ToGoError().(type) {
+		case ArchiveEntryDenied:
+			writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		case InvalidArchive, IncompleteBody, ObjectNameInvalid:
+			// Malformed archives and entry names escaping the prefix.
+			writeErrorResponse(w, r, ErrInvalidArchive, r.URL.Path)
+		case ObjectLocked:
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+		case ObjectTooLarge:
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+		case StorageFull:
+			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case QuotaExceeded:
+			writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case ObjectExistsAsPrefix:
+			writeErrorResponse(w, r, ErrObjectExistsAsPrefix, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	encodedSuccessResponse := encodeResponse(generateExtractArchiveResponse(bucket, prefix, objects))
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
@@ -0,0 +1,291 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+var unixEpochTime = time.Unix(0, 0)
+
+// Conditional request headers, of the object read or written and of the
+// source of copies.
+const (
+	ifMatchHeader                     = "If-Match"
+	ifNoneMatchHeader                 = "If-None-Match"
+	ifModifiedSinceHeader             = "If-Modified-Since"
+	ifUnmodifiedSinceHeader           = "If-Unmodified-Since"
+	copySourceIfMatchHeader           = "X-Amz-Copy-Source-If-Match"
+	copySourceIfNoneMatchHeader       = "X-Amz-Copy-Source-If-None-Match"
+	copySourceIfModifiedSinceHeader   = "X-Amz-Copy-Source-If-Modified-Since"
+	copySourceIfUnmodifiedSinceHeader = "X-Amz-Copy-Source-If-Unmodified-Since"
+)
+
+// conditionResult - outcome of evaluating conditional headers.
+type conditionResult int
+
+const (
+	conditionsMet conditionResult = iota
+	conditionNotModified
+	conditionPreconditionFailed
+)
+
+// evalConditions - evaluates conditional headers of an object as of
+// RFC 7232 section 6: If-Match, else If-Unmodified-Since, fail the
+// request, If-None-Match, else If-Modified-Since, report the object as
+// not modified. Headers with invalid dates are ignored.
+func evalConditions(header http.Header, ifMatch, ifNoneMatch, ifModifiedSince, ifUnmodifiedSince string, objInfo ObjectInfo) conditionResult {
+	if etags := header.Get(ifMatch); etags != "" {
+		if !etagMatches(etags, objInfo.MD5Sum) {
+			return conditionPreconditionFailed
+		}
+	} else if t, ok := parseConditionTime(header.Get(ifUnmodifiedSince)); ok && isModifiedSince(objInfo.ModTime, t) {
+		return conditionPreconditionFailed
+	}
+	if etags := header.Get(ifNoneMatch); etags != "" {
+		if etagMatches(etags, objInfo.MD5Sum) {
+			return conditionNotModified
+		}
+	} else if t, ok := parseConditionTime(header.Get(ifModifiedSince)); ok && !isModifiedSince(objInfo.ModTime, t) {
+		return conditionNotModified
+	}
+	return conditionsMet
+}
+
+// checkPreconditions - implements If-Match, If-None-Match,
+// If-Modified-Since and If-Unmodified-Since of GET and HEAD requests.
+// The return value is whether this request is now complete.
+func checkPreconditions(w http.ResponseWriter, r *http.Request, objInfo ObjectInfo) bool {
+	switch evalConditions(r.Header, ifMatchHeader, ifNoneMatchHeader, ifModifiedSinceHeader, ifUnmodifiedSinceHeader, objInfo) {
+	case conditionNotModified:
+		writeNotModified(w, objInfo)
+		return true
+	case conditionPreconditionFailed:
+		writeErrorResponse(w, r, ErrPreconditionFailed, r.URL.Path)
+		return true
+	}
+	return false
+}
+
+// checkCopySourcePreconditions - implements the x-amz-copy-source-if-*
+// headers of copies against their source object, copies which would
+// be reported as not modified fail as well. The return value is
+// whether this request is now complete.
+func checkCopySourcePreconditions(w http.ResponseWriter, r *http.Request, objInfo ObjectInfo) bool {
+	if evalConditions(r.Header, copySourceIfMatchHeader, copySourceIfNoneMatchHeader, copySourceIfModifiedSinceHeader, copySourceIfUnmodifiedSinceHeader, objInfo) != conditionsMet {
+		writeErrorResponse(w, r, ErrPreconditionFailed, r.URL.Path)
+		return true
+	}
+	return false
+}
+
+// hasWritePreconditions - returns true if r writes an object only if
+// the object it replaces meets conditions.
+func hasWritePreconditions(r *http.Request) bool {
+	return r.Header.Get(ifMatchHeader) != "" || r.Header.Get(ifNoneMatchHeader) != "" || r.Header.Get(ifUnmodifiedSinceHeader) != ""
+}
+
+// writePreconditionsMet - implements If-Match, If-None-Match and
+// If-Unmodified-Since of requests writing an object, against the object
+// they replace, if exists. If-None-Match "*" writes objects only if
+// they do not exist yet.
+func writePreconditionsMet(h http.Header, objInfo ObjectInfo, exists bool) bool {
+	if !exists {
+		// Only objects which do not exist match none of the etags.
+		return h.Get(ifMatchHeader) == ""
+	}
+	// Objects which are not modified since are written as well.
+	return evalConditions(h, ifMatchHeader, ifNoneMatchHeader, "", ifUnmodifiedSinceHeader, objInfo) == conditionsMet
+}
+
+// checkWritePreconditions - writes an error response if the object
+// replaced does not meet the conditional headers of r. The return
+// value is whether this request is now complete.
+func checkWritePreconditions(w http.ResponseWriter, r *http.Request, objInfo ObjectInfo, exists bool) bool {
+	if !writePreconditionsMet(r.Header, objInfo, exists) {
+		writeErrorResponse(w, r, ErrPreconditionFailed, r.URL.Path)
+		return true
+	}
+	return false
+}
+
+// getWriteCondition - returns the conditional headers of r as write
+// condition of the object layer, nil if r has none.
+func getWriteCondition(r *http.Request) func(objInfo ObjectInfo, exists bool) bool {
+	if !hasWritePreconditions(r) {
+		return nil
+	}
+	return func(objInfo ObjectInfo, exists bool) bool {
+		return writePreconditionsMet(r.Header, objInfo, exists)
+	}
+}
+
+// checkObjectWritePreconditions - implements conditional headers of r
+// writing object of bucket against the object it replaces, if any, so
+// that failing requests are rejected before their data is read. The
+// object layer evaluates them again as the object commits. The return
+// value is whether this request is now complete.
+func (api objectAPIHandlers) checkObjectWritePreconditions(w http.ResponseWriter, r *http.Request, bucket, object string) bool {
+	if !hasWritePreconditions(r) {
+		return false
+	}
+	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		switch err.ToGoError().(type) {
+		case ObjectNotFound:
+			return checkWritePreconditions(w, r, ObjectInfo{}, false)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case ObjectNameInvalid:
+			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		default:
+			errorIf(err.Trace(bucket, object), "GetObjectInfo failed.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return true
+	}
+	return checkWritePreconditions(w, r, objInfo, true)
+}
+
+// writeNotModified - writes a 304 response, along with the validators
+// of objInfo.
+func writeNotModified(w http.ResponseWriter, objInfo ObjectInfo) {
+	h := w.Header()
+	// Remove following headers if already set.
+	delete(h, "Content-Type")
+	delete(h, "Content-Length")
+	delete(h, "Content-Range")
+	setCommonHeaders(w)
+	if objInfo.MD5Sum != "" {
+		h.Set("ETag", "\""+objInfo.MD5Sum+"\"")
+	}
+	if !objInfo.ModTime.IsZero() {
+		h.Set("Last-Modified", objInfo.ModTime.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagMatches - returns true if the comma separated list of etags, or
+// "*", matches etag. Etags are compared without quotes and weakness
+// indicators.
+func etagMatches(etags, etag string) bool {
+	for _, candidate := range strings.Split(etags, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		candidate = strings.Trim(strings.TrimPrefix(candidate, "W/"), "\"")
+		if candidate != "" && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConditionTime - parses a date of a conditional header, false if
+// it is missing or invalid.
+func parseConditionTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, e := http.ParseTime(value)
+	if e != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// isModifiedSince - returns true if modtime is after t. Dates of
+// headers have no sub-second precision, so neither has modtime. Objects
+// without a modtime are never modified.
+func isModifiedSince(modtime, t time.Time) bool {
+	if modtime.IsZero() || modtime.Equal(unixEpochTime) {
+		return false
+	}
+	return modtime.Truncate(time.Second).After(t)
+}
+
+// Maximum size of user defined metadata of an object, keys and values
+// of "X-Amz-Meta-" headers.
+const maxUserMetadataSize = 2 * 1024
+
+// Copies keep the metadata of their source unless it is replaced by
+// the metadata of the request.
+const (
+	metadataDirectiveHeader  = "X-Amz-Metadata-Directive"
+	metadataDirectiveCopy    = "COPY"
+	metadataDirectiveReplace = "REPLACE"
+)
+
+// extractUserMetadata - returns user defined metadata and standard
+// headers of header saved along with objects. Multiple values of a
+// header are joined.
+func extractUserMetadata(header http.Header) (map[string]string, APIErrorCode) {
+	metadata := make(map[string]string)
+	userMetadataSize := 0
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		// Checksums are saved once verified against the body.
+		if !isUserMetadataKey(key) || isChecksumMetadataKey(key) {
+			continue
+		}
+		value := strings.Join(values, ",")
+		if strings.HasPrefix(key, userMetadataKeyPrefix) {
+			userMetadataSize += len(key) - len(userMetadataKeyPrefix) + len(value)
+		}
+		metadata[key] = value
+	}
+	if userMetadataSize > maxUserMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	if class, ok := metadata[amzStorageClassHeader]; ok {
+		if _, ok = getStorageClass(class); !ok {
+			return nil, ErrInvalidStorageClass
+		}
+	}
+	return metadata, ErrNone
+}
+
+// getCopyMetadata - returns metadata saved along with the copy of
+// object of objInfo, as of the metadata directive of r.
+func getCopyMetadata(r *http.Request, objInfo ObjectInfo) (map[string]string, APIErrorCode) {
+	switch strings.ToUpper(r.Header.Get(metadataDirectiveHeader)) {
+	case "", metadataDirectiveCopy:
+		metadata := make(map[string]string)
+		for key, value := range objInfo.UserDefined {
+			metadata[key] = value
+		}
+		if objInfo.ContentType != "" {
+			metadata["Content-Type"] = objInfo.ContentType
+		}
+		// Copies are STANDARD unless given a class of their own.
+		if class := r.Header.Get(amzStorageClassHeader); class != "" {
+			if _, ok := getStorageClass(class); !ok {
+				return nil, ErrInvalidStorageClass
+			}
+			metadata[amzStorageClassHeader] = class
+		}
+		return metadata, ErrNone
+	case metadataDirectiveReplace:
+		return extractUserMetadata(r.Header)
+	}
+	return nil, ErrInvalidMetadataDirective
+}
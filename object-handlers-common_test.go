@@ -0,0 +1,65 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Tests conditional headers are evaluated in the order of RFC 7232.
+func TestEvalConditions(t *testing.T) {
+	modTime := time.Date(2016, 4, 1, 10, 0, 0, 500, time.UTC)
+	objInfo := ObjectInfo{MD5Sum: "abcd", ModTime: modTime}
+	before := modTime.Add(-time.Hour).Format(http.TimeFormat)
+	same := modTime.Format(http.TimeFormat)
+
+	testCases := []struct {
+		headers  map[string]string
+		expected conditionResult
+	}{
+		{map[string]string{}, conditionsMet},
+		{map[string]string{"If-Match": `"abcd"`}, conditionsMet},
+		{map[string]string{"If-Match": `"0123", W/"abcd"`}, conditionsMet},
+		{map[string]string{"If-Match": "*"}, conditionsMet},
+		{map[string]string{"If-Match": `"0123"`}, conditionPreconditionFailed},
+		{map[string]string{"If-Unmodified-Since": before}, conditionPreconditionFailed},
+		{map[string]string{"If-Unmodified-Since": same}, conditionsMet},
+		// If-Match takes precedence over If-Unmodified-Since.
+		{map[string]string{"If-Match": `"abcd"`, "If-Unmodified-Since": before}, conditionsMet},
+		{map[string]string{"If-None-Match": `"abcd"`}, conditionNotModified},
+		{map[string]string{"If-None-Match": `"0123"`}, conditionsMet},
+		{map[string]string{"If-Modified-Since": same}, conditionNotModified},
+		{map[string]string{"If-Modified-Since": before}, conditionsMet},
+		{map[string]string{"If-Modified-Since": "yesterday"}, conditionsMet},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{map[string]string{"If-None-Match": `"0123"`, "If-Modified-Since": same}, conditionsMet},
+		// Failed preconditions take precedence over not modified.
+		{map[string]string{"If-Match": `"0123"`, "If-None-Match": `"abcd"`}, conditionPreconditionFailed},
+	}
+	for i, testCase := range testCases {
+		header := make(http.Header)
+		for key, value := range testCase.headers {
+			header.Set(key, value)
+		}
+		result := evalConditions(header, ifMatchHeader, ifNoneMatchHeader, ifModifiedSinceHeader, ifUnmodifiedSinceHeader, objInfo)
+		if result != testCase.expected {
+			t.Errorf("Test %d: expected %d, got %d", i+1, testCase.expected, result)
+		}
+	}
+}
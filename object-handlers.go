@@ -18,10 +18,12 @@ package main
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"sort"
@@ -31,10 +33,16 @@ import (
 
 	fastSha256 "github.com/minio/minio/pkg/crypto/sha256"
 
+	"github.com/Sirupsen/logrus"
 	mux "github.com/gorilla/mux"
 	"github.com/minio/minio/pkg/probe"
 )
 
+// readQuorumDiagnosticsHeader - extension header of failed reads
+// carrying the required and available disks and the errors of failed
+// disks as JSON.
+const readQuorumDiagnosticsHeader = "X-Minio-Read-Quorum-Diagnostics"
+
 // supportedGetReqParams - supported request parameters for GET presigned request.
 var supportedGetReqParams = map[string]string{
 	"response-expires":             "Expires",
@@ -70,6 +78,30 @@ func errAllowableObjectNotFound(bucket string, r *http.Request) APIErrorCode {
 	return ErrNoSuchKey
 }
 
+// writeReadQuorumErrorResponse - logs diagnostics of a read which did
+// not meet read quorum and writes the error response. Authenticated
+// requests get the diagnostics in readQuorumDiagnosticsHeader, so that
+// a quorum loss can be told apart from a missing object.
+func writeReadQuorumErrorResponse(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	diagnostics, ok := err.ToGoError().(StorageInsufficientReadResources)
+	if !ok {
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	errorIf(err.Trace(), "Read quorum not met.", logrus.Fields{
+		"readQuorum":  diagnostics.ReadQuorum,
+		"available":   diagnostics.Available,
+		"failedDisks": diagnostics.Disks,
+	})
+	switch getRequestAuthType(r) {
+	case authTypePresigned, authTypeSigned:
+		if diagnosticsBytes, e := json.Marshal(diagnostics); e == nil {
+			w.Header().Set(readQuorumDiagnosticsHeader, string(diagnosticsBytes))
+		}
+	}
+	writeErrorResponse(w, r, ErrInsufficientReadResources, r.URL.Path)
+}
+
 // GetObjectHandler - GET Object
 // ----------
 // This implementation of the GET operation retrieves object. To use GET,
@@ -97,10 +129,13 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 	}
-	// Fetch object stat info.
-	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	// Fetch object stat info, as of the cut of read session if any.
+	sessionID := r.Header.Get(readSessionHeader)
+	objInfo, err := api.ObjectAPI.GetObjectInfoAt(sessionID, bucket, object)
 	if err != nil {
 		switch err.ToGoError().(type) {
+		case ReadSessionNotFound:
+			writeErrorResponse(w, r, ErrNoSuchReadSession, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
@@ -109,6 +144,10 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 			writeErrorResponse(w, r, errAllowableObjectNotFound(bucket, r), r.URL.Path)
 		case ObjectNameInvalid:
 			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		case StorageInsufficientReadResources:
+			writeReadQuorumErrorResponse(w, r, err.Trace(bucket, object))
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
 		default:
 			errorIf(err.Trace(), "GetObjectInfo failed.", nil)
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
@@ -116,36 +155,33 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Verify 'If-Modified-Since' and 'If-Unmodified-Since'.
-	lastModified := objInfo.ModTime
-	if checkLastModified(w, r, lastModified) {
-		return
-	}
-	// Verify 'If-Match' and 'If-None-Match'.
-	if checkETag(w, r) {
+	// Verify 'If-Match', 'If-None-Match', 'If-Modified-Since' and
+	// 'If-Unmodified-Since'.
+	if checkPreconditions(w, r, objInfo) {
 		return
 	}
 
-	var hrange *httpRange
-	hrange, err = getRequestedRange(r.Header.Get("Range"), objInfo.Size)
+	ranges, err := getRequestedRanges(r.Header.Get("Range"), objInfo.Size)
 	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", objInfo.Size))
 		writeErrorResponse(w, r, ErrInvalidRange, r.URL.Path)
 		return
 	}
+	if len(ranges) > 1 {
+		api.writeObjectRanges(w, r, sessionID, bucket, object, objInfo, ranges)
+		return
+	}
 
-	// Get the object.
-	startOffset := hrange.start
-	readCloser, err := api.ObjectAPI.GetObject(bucket, object, startOffset)
+	// Get the object, or the requested range of it.
+	var hrange *httpRange
+	startOffset, length := int64(0), int64(-1)
+	if len(ranges) == 1 {
+		hrange = ranges[0]
+		startOffset, length = hrange.start, hrange.length
+	}
+	readCloser, err := api.ObjectAPI.WithContext(r.Context()).WithCacheControl(r.Header.Get("Cache-Control")).GetObjectRangeAt(sessionID, bucket, object, startOffset, length)
 	if err != nil {
-		switch err.ToGoError().(type) {
-		case BucketNotFound:
-			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
-		case ObjectNotFound:
-			writeErrorResponse(w, r, errAllowableObjectNotFound(bucket, r), r.URL.Path)
-		default:
-			errorIf(err.Trace(), "GetObject failed.", nil)
-			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
-		}
+		writeGetObjectError(w, r, bucket, object, err)
 		return
 	}
 	defer readCloser.Close() // Close after this handler returns.
@@ -156,7 +192,7 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 	// Set any additional requested response headers.
 	setGetRespHeaders(w, r.URL.Query())
 
-	if hrange.length > 0 {
+	if hrange != nil {
 		if _, e := io.CopyN(w, readCloser, hrange.length); e != nil {
 			errorIf(probe.NewError(e), "Writing to client failed", nil)
 			// Do not send error response here, since client could have died.
@@ -171,98 +207,64 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
-var unixEpochTime = time.Unix(0, 0)
-
-// checkLastModified implements If-Modified-Since and
-// If-Unmodified-Since checks.
-//
-// modtime is the modification time of the resource to be served, or
-// IsZero(). return value is whether this request is now complete.
-func checkLastModified(w http.ResponseWriter, r *http.Request, modtime time.Time) bool {
-	if modtime.IsZero() || modtime.Equal(unixEpochTime) {
-		// If the object doesn't have a modtime (IsZero), or the modtime
-		// is obviously garbage (Unix time == 0), then ignore modtimes
-		// and don't process the If-Modified-Since header.
-		return false
-	}
-
-	// The Date-Modified header truncates sub-second precision, so
-	// use mtime < t+1s instead of mtime <= t to check for unmodified.
-	if _, ok := r.Header["If-Modified-Since"]; ok {
-		// Return the object only if it has been modified since the
-		// specified time, otherwise return a 304 (not modified).
-		t, err := time.Parse(http.TimeFormat, r.Header.Get("If-Modified-Since"))
-		if err == nil && modtime.Before(t.Add(1*time.Second)) {
-			h := w.Header()
-			// Remove following headers if already set.
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusNotModified)
-			return true
-		}
-	} else if _, ok := r.Header["If-Unmodified-Since"]; ok {
-		// Return the object only if it has not been modified since
-		// the specified time, otherwise return a 412 (precondition failed).
-		t, err := time.Parse(http.TimeFormat, r.Header.Get("If-Unmodified-Since"))
-		if err == nil && modtime.After(t.Add(1*time.Second)) {
-			h := w.Header()
-			// Remove following headers if already set.
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return true
-		}
+// writeObjectRanges - writes ranges of object as a multipart/byteranges
+// response, as of RFC 7233 section 4.1. Ranges are read one after the
+// other, failures after the first range is read end the response.
+func (api objectAPIHandlers) writeObjectRanges(w http.ResponseWriter, r *http.Request, sessionID, bucket, object string, objInfo ObjectInfo, ranges []*httpRange) {
+	objectAPI := api.ObjectAPI.WithContext(r.Context()).WithCacheControl(r.Header.Get("Cache-Control"))
+	readCloser, err := objectAPI.GetObjectRangeAt(sessionID, bucket, object, ranges[0].start, ranges[0].length)
+	if err != nil {
+		writeGetObjectError(w, r, bucket, object, err)
+		return
 	}
-	w.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
-	return false
-}
 
-// checkETag implements If-None-Match and If-Match checks.
-//
-// The ETag must have been previously set in the ResponseWriter's
-// headers. The return value is whether this request is now considered
-// done.
-func checkETag(w http.ResponseWriter, r *http.Request) bool {
-	etag := w.Header().Get("ETag")
-	// Must know ETag.
-	if etag == "" {
-		return false
-	}
-	if inm := r.Header.Get("If-None-Match"); inm != "" {
-		// Return the object only if its entity tag (ETag) is
-		// different from the one specified; otherwise, return a 304
-		// (not modified).
-		if r.Method != "GET" && r.Method != "HEAD" {
-			return false
-		}
-		if inm == etag || inm == "*" {
-			h := w.Header()
-			// Remove following headers if already set.
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusNotModified)
-			return true
+	mimeWriter := multipart.NewWriter(w)
+	setObjectHeaders(w, objInfo, nil)
+	setGetRespHeaders(w, r.URL.Query())
+	contentType := w.Header().Get("Content-Type")
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mimeWriter.Boundary())
+	w.Header().Set("Content-Length", strconv.FormatInt(rangesMIMESize(ranges, contentType, mimeWriter.Boundary()), 10))
+	w.WriteHeader(http.StatusPartialContent)
+
+	for index, hrange := range ranges {
+		if index > 0 {
+			readCloser, err = objectAPI.GetObjectRangeAt(sessionID, bucket, object, hrange.start, hrange.length)
+			if err != nil {
+				errorIf(err.Trace(bucket, object), "GetObject failed.", nil)
+				return
+			}
 		}
-	} else if im := r.Header.Get("If-Match"); im != "" {
-		// Return the object only if its entity tag (ETag) is the same
-		// as the one specified; otherwise, return a 412 (precondition failed).
-		if r.Method != "GET" && r.Method != "HEAD" {
-			return false
+		part, e := mimeWriter.CreatePart(rangePartHeader(hrange, contentType))
+		if e == nil {
+			_, e = io.CopyN(part, readCloser, hrange.length)
 		}
-		if im != etag {
-			h := w.Header()
-			// Remove following headers if already set.
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return true
+		readCloser.Close()
+		if e != nil {
+			errorIf(probe.NewError(e), "Writing to client failed", nil)
+			// Do not send error response here, since client could have died.
+			return
 		}
 	}
-	return false
+	mimeWriter.Close()
+}
+
+// writeGetObjectError - writes response of a failed read of object.
+func writeGetObjectError(w http.ResponseWriter, r *http.Request, bucket, object string, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case ReadSessionNotFound:
+		writeErrorResponse(w, r, ErrNoSuchReadSession, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case ObjectNotFound:
+		writeErrorResponse(w, r, errAllowableObjectNotFound(bucket, r), r.URL.Path)
+	case StorageInsufficientReadResources:
+		writeReadQuorumErrorResponse(w, r, err.Trace(bucket, object))
+	case OperationTimedOut:
+		writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+	default:
+		errorIf(err.Trace(), "GetObject failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
 }
 
 // HeadObjectHandler - HEAD Object
@@ -292,10 +294,12 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	objInfo, err := api.ObjectAPI.GetObjectInfoAt(r.Header.Get(readSessionHeader), bucket, object)
 	if err != nil {
 		errorIf(err.Trace(bucket, object), "GetObjectInfo failed.", nil)
 		switch err.ToGoError().(type) {
+		case ReadSessionNotFound:
+			writeErrorResponse(w, r, ErrNoSuchReadSession, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
@@ -304,20 +308,19 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 			writeErrorResponse(w, r, errAllowableObjectNotFound(bucket, r), r.URL.Path)
 		case ObjectNameInvalid:
 			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		case StorageInsufficientReadResources:
+			writeReadQuorumErrorResponse(w, r, err.Trace(bucket, object))
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
 		return
 	}
 
-	// Verify 'If-Modified-Since' and 'If-Unmodified-Since'.
-	lastModified := objInfo.ModTime
-	if checkLastModified(w, r, lastModified) {
-		return
-	}
-
-	// Verify 'If-Match' and 'If-None-Match'.
-	if checkETag(w, r) {
+	// Verify 'If-Match', 'If-None-Match', 'If-Modified-Since' and
+	// 'If-Unmodified-Since'.
+	if checkPreconditions(w, r, objInfo) {
 		return
 	}
 
@@ -358,21 +361,7 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	// TODO: Reject requests where body/payload is present, for now we
 	// don't even read it.
 
-	// objectSource
-	objectSource := r.Header.Get("X-Amz-Copy-Source")
-
-	// Skip the first element if it is '/', split the rest.
-	if strings.HasPrefix(objectSource, "/") {
-		objectSource = objectSource[1:]
-	}
-	splits := strings.SplitN(objectSource, "/", 2)
-
-	// Save sourceBucket and sourceObject extracted from url Path.
-	var sourceBucket, sourceObject string
-	if len(splits) == 2 {
-		sourceBucket = splits[0]
-		sourceObject = splits[1]
-	}
+	objectSource, sourceBucket, sourceObject := getCopySource(r)
 	// If source object is empty, reply back error.
 	if sourceObject == "" {
 		writeErrorResponse(w, r, ErrInvalidCopySource, r.URL.Path)
@@ -404,16 +393,10 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 	// Verify before writing.
 
-	// Verify x-amz-copy-source-if-modified-since and
+	// Verify x-amz-copy-source-if-match, x-amz-copy-source-if-none-match,
+	// x-amz-copy-source-if-modified-since and
 	// x-amz-copy-source-if-unmodified-since.
-	lastModified := objInfo.ModTime
-	if checkCopySourceLastModified(w, r, lastModified) {
-		return
-	}
-
-	// Verify x-amz-copy-source-if-match and
-	// x-amz-copy-source-if-none-match.
-	if checkCopySourceETag(w, r) {
+	if checkCopySourcePreconditions(w, r, objInfo) {
 		return
 	}
 
@@ -423,6 +406,12 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Verify 'If-Match', 'If-None-Match' and 'If-Unmodified-Since'
+	// against the object replaced.
+	if api.checkObjectWritePreconditions(w, r, bucket, object) {
+		return
+	}
+
 	var md5Bytes []byte
 	if objInfo.MD5Sum != "" {
 		var e error
@@ -434,9 +423,16 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		}
 	}
 
+	// Save metadata of the source, or of the request.
+	metadata, s3Error := getCopyMetadata(r, objInfo)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
 	startOffset := int64(0) // Read the whole file.
 	// Get the object.
-	readCloser, getErr := api.ObjectAPI.GetObject(sourceBucket, sourceObject, startOffset)
+	readCloser, getErr := api.ObjectAPI.WithContext(r.Context()).GetObject(sourceBucket, sourceObject, startOffset)
 	if getErr != nil {
 		errorIf(getErr.Trace(sourceBucket, sourceObject), "Reading "+objectSource+" failed.", nil)
 		switch err.ToGoError().(type) {
@@ -452,24 +448,46 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	// Size of object.
 	size := objInfo.Size
 
-	// Save metadata.
-	metadata := make(map[string]string)
 	metadata["md5Sum"] = hex.EncodeToString(md5Bytes)
+	if s3Error := checkBypassGovernance(r, bucket, object); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	setObjectLockMetadata(r, metadata)
 
 	// Create the object.
-	md5Sum, err := api.ObjectAPI.PutObject(bucket, object, size, readCloser, metadata)
+	var sequence uint64
+	md5Sum, err := api.ObjectAPI.WithContext(r.Context()).WithSequence(&sequence).WithWriteCondition(getWriteCondition(r)).PutObject(bucket, object, size, readCloser, metadata)
 	if err != nil {
 		switch err.ToGoError().(type) {
+		case ObjectLocked:
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+		case PreconditionFailed:
+			writeErrorResponse(w, r, ErrPreconditionFailed, r.URL.Path)
+		case InvalidRetention:
+			writeErrorResponse(w, r, ErrInvalidRetention, r.URL.Path)
+		case InvalidLegalHold:
+			writeErrorResponse(w, r, ErrInvalidLegalHold, r.URL.Path)
+		case BucketObjectLockNotFound:
+			writeErrorResponse(w, r, ErrObjectLockConfigurationNotFound, r.URL.Path)
 		case StorageFull:
 			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+		case QuotaExceeded:
+			writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
 		case BucketNotFound:
 			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case InvalidStorageClass:
+			writeErrorResponse(w, r, ErrInvalidStorageClass, r.URL.Path)
 		case BadDigest:
 			writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
 		case IncompleteBody:
 			writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
+		case ObjectTooLarge:
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
 		case ObjectExistsAsPrefix:
 			writeErrorResponse(w, r, ErrObjectExistsAsPrefix, r.URL.Path)
 		default:
@@ -494,98 +512,34 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	writeSuccessResponse(w, encodedSuccessResponse)
 	// Explicitly close the reader, to avoid fd leaks.
 	readCloser.Close()
-}
 
-// checkCopySource implements x-amz-copy-source-if-modified-since and
-// x-amz-copy-source-if-unmodified-since checks.
-//
-// modtime is the modification time of the resource to be served, or
-// IsZero(). return value is whether this request is now complete.
-func checkCopySourceLastModified(w http.ResponseWriter, r *http.Request, modtime time.Time) bool {
-	if modtime.IsZero() || modtime.Equal(unixEpochTime) {
-		// If the object doesn't have a modtime (IsZero), or the modtime
-		// is obviously garbage (Unix time == 0), then ignore modtimes
-		// and don't process the If-Modified-Since header.
-		return false
-	}
-	// The Date-Modified header truncates sub-second precision, so
-	// use mtime < t+1s instead of mtime <= t to check for unmodified.
-	if _, ok := r.Header["x-amz-copy-source-if-modified-since"]; ok {
-		// Return the object only if it has been modified since the
-		// specified time, otherwise return a 304 error (not modified).
-		t, err := time.Parse(http.TimeFormat, r.Header.Get("x-amz-copy-source-if-modified-since"))
-		if err == nil && modtime.Before(t.Add(1*time.Second)) {
-			h := w.Header()
-			// Remove Content headers if set
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusNotModified)
-			return true
-		}
-	} else if _, ok := r.Header["x-amz-copy-source-if-unmodified-since"]; ok {
-		// Return the object only if it has not been modified since the
-		// specified time, otherwise return a 412 error (precondition failed).
-		t, err := time.Parse(http.TimeFormat, r.Header.Get("x-amz-copy-source-if-unmodified-since"))
-		if err == nil && modtime.After(t.Add(1*time.Second)) {
-			h := w.Header()
-			// Remove Content headers if set
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return true
-		}
-	}
-	w.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
-	return false
+	// Notify object created event.
+	objInfo.MD5Sum = md5Sum
+	objInfo.Sequence = sequence
+	eventNotify(eventData{
+		Type:      ObjectCreatedCopy,
+		Bucket:    bucket,
+		ObjInfo:   objInfo,
+		ReqParams: eventReqParams(r),
+	})
 }
 
-// checkCopySourceETag implements x-amz-copy-source-if-match and
-// x-amz-copy-source-if-none-match checks.
-//
-// The ETag must have been previously set in the ResponseWriter's
-// headers. The return value is whether this request is now considered
-// complete.
-func checkCopySourceETag(w http.ResponseWriter, r *http.Request) bool {
-	etag := w.Header().Get("ETag")
-	// Tag must be provided...
-	if etag == "" {
-		return false
-	}
-	if inm := r.Header.Get("x-amz-copy-source-if-none-match"); inm != "" {
-		// Return the object only if its entity tag (ETag) is different
-		// from the one specified; otherwise, return a 304 (not modified).
-		if r.Method != "PUT" {
-			return false
-		}
-		if inm == etag || inm == "*" {
-			h := w.Header()
-			// Remove Content headers if set
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusNotModified)
-			return true
-		}
-	} else if inm := r.Header.Get("x-amz-copy-source-if-match"); inm != "" {
-		// Return the object only if its entity tag (ETag) is the same
-		// as the one specified; otherwise, return a 412 (precondition failed).
-		if r.Method != "PUT" {
-			return false
-		}
-		if inm != etag {
-			h := w.Header()
-			// Remove Content headers if set
-			delete(h, "Content-Type")
-			delete(h, "Content-Length")
-			delete(h, "Content-Range")
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return true
-		}
+// getCopySource - returns the X-Amz-Copy-Source header of r without
+// its leading '/', along with the source bucket and object, which are
+// empty if the header is malformed. The header is URL encoded by
+// clients, it is used as is if it does not decode.
+func getCopySource(r *http.Request) (objectSource, sourceBucket, sourceObject string) {
+	objectSource = r.Header.Get("X-Amz-Copy-Source")
+	if source, e := url.PathUnescape(objectSource); e == nil {
+		objectSource = source
 	}
-	return false
-
+	objectSource = strings.TrimPrefix(objectSource, "/")
+	splits := strings.SplitN(objectSource, "/", 2)
+	if len(splits) == 2 {
+		sourceBucket = splits[0]
+		sourceObject = splits[1]
+	}
+	return objectSource, sourceBucket, sourceObject
 }
 
 // PutObjectHandler - PUT Object
@@ -609,7 +563,8 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		writeErrorResponse(w, r, ErrInvalidDigest, r.URL.Path)
 		return
 	}
-	/// if Content-Length is unknown/missing, deny the request
+	/// if Content-Length is unknown/missing, deny the request unless
+	/// the body is chunked, the object is then as large as the body.
 	size := r.ContentLength
 	if size == -1 && !contains(r.TransferEncoding, "chunked") {
 		writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
@@ -621,7 +576,30 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Save user defined metadata and standard headers.
+	metadata, s3Error := extractUserMetadata(r.Header)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	if s3Error = checkBypassGovernance(r, bucket, object); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	// Additional checksums sent are verified as the body is read.
+	var body io.Reader = r.Body
+	checksumReader, s3Error := newChecksumReader(r, size, metadata)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	if checksumReader != nil {
+		body = checksumReader
+	}
+
 	var md5Sum string
+	var sequence uint64
 	switch getRequestAuthType(r) {
 	default:
 		// For all unknown auth types return error.
@@ -633,9 +611,24 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
+		if api.checkObjectWritePreconditions(w, r, bucket, object) {
+			return
+		}
 		// Create anonymous object.
-		md5Sum, err = api.ObjectAPI.PutObject(bucket, object, size, r.Body, nil)
+		setObjectLockMetadata(r, metadata)
+		md5Sum, err = api.ObjectAPI.WithContext(r.Context()).WithSequence(&sequence).WithWriteCondition(getWriteCondition(r)).PutObject(bucket, object, size, body, metadata)
 	case authTypePresigned, authTypeSigned:
+		// Conditions are only checked for authenticated requests, the
+		// payload is verified as it is written.
+		if hasWritePreconditions(r) {
+			if s3Error := isReqHeaderAuthenticated(r); s3Error != ErrNone {
+				writeErrorResponse(w, r, s3Error, r.URL.Path)
+				return
+			}
+			if api.checkObjectWritePreconditions(w, r, bucket, object) {
+				return
+			}
+		}
 		// Initialize a pipe for data pipe line.
 		reader, writer := io.Pipe()
 
@@ -643,7 +636,14 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		go func() {
 			shaWriter := fastSha256.New()
 			multiWriter := io.MultiWriter(shaWriter, writer)
-			if _, e := io.CopyN(multiWriter, r.Body, size); e != nil {
+			var e error
+			if size == -1 {
+				// Chunked bodies are read until their end.
+				_, e = io.Copy(multiWriter, body)
+			} else {
+				_, e = io.CopyN(multiWriter, body, size)
+			}
+			if e != nil {
 				errorIf(probe.NewError(e), "Unable to read HTTP body.", nil)
 				writer.CloseWithError(e)
 				return
@@ -668,12 +668,11 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			writer.Close()
 		}()
 
-		// Save metadata.
-		metadata := make(map[string]string)
 		// Make sure we hex encode here.
 		metadata["md5"] = hex.EncodeToString(md5Bytes)
+		setObjectLockMetadata(r, metadata)
 		// Create object.
-		md5Sum, err = api.ObjectAPI.PutObject(bucket, object, size, reader, metadata)
+		md5Sum, err = api.ObjectAPI.WithContext(r.Context()).WithSequence(&sequence).WithWriteCondition(getWriteCondition(r)).PutObject(bucket, object, size, reader, metadata)
 	}
 	if err != nil {
 		errorIf(err.Trace(), "PutObject failed.", nil)
@@ -684,16 +683,36 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 		switch e.(type) {
+		case ObjectLocked:
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+		case PreconditionFailed:
+			writeErrorResponse(w, r, ErrPreconditionFailed, r.URL.Path)
+		case InvalidRetention:
+			writeErrorResponse(w, r, ErrInvalidRetention, r.URL.Path)
+		case InvalidLegalHold:
+			writeErrorResponse(w, r, ErrInvalidLegalHold, r.URL.Path)
+		case BucketObjectLockNotFound:
+			writeErrorResponse(w, r, ErrObjectLockConfigurationNotFound, r.URL.Path)
 		case StorageFull:
 			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+		case QuotaExceeded:
+			writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
 		case BucketNotFound:
 			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BadChecksum:
+			writeErrorResponse(w, r, ErrBadChecksum, r.URL.Path)
+		case InvalidStorageClass:
+			writeErrorResponse(w, r, ErrInvalidStorageClass, r.URL.Path)
 		case BadDigest:
 			writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
 		case IncompleteBody:
 			writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
+		case ObjectTooLarge:
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
 		case ObjectExistsAsPrefix:
 			writeErrorResponse(w, r, ErrObjectExistsAsPrefix, r.URL.Path)
 		default:
@@ -705,6 +724,9 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		w.Header().Set("ETag", "\""+md5Sum+"\"")
 	}
 	writeSuccessResponse(w, nil)
+
+	// Notify object created event.
+	notifyObjectCreated(api.ObjectAPI, r, ObjectCreatedPut, bucket, object, md5Sum, sequence)
 }
 
 /// Multipart objectAPIHandlers
@@ -734,12 +756,16 @@ func (api objectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 		}
 	}
 
-	uploadID, err := api.ObjectAPI.NewMultipartUpload(bucket, object)
+	uploadID, err := api.ObjectAPI.NewMultipartUpload(bucket, object, getAuditRequester(r))
 	if err != nil {
 		errorIf(err.Trace(), "NewMultipartUpload failed.", nil)
 		switch err.ToGoError().(type) {
 		case StorageFull:
 			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+		case TooManyMultipartUploads:
+			writeErrorResponse(w, r, ErrTooManyMultipartUploads, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
@@ -784,7 +810,7 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 	}
 
 	/// maximum Upload size for multipart objects in a single operation
-	if isMaxObjectSize(size) {
+	if isMaxPartSize(size) {
 		writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
 		return
 	}
@@ -797,6 +823,10 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		writeErrorResponse(w, r, ErrInvalidPart, r.URL.Path)
 		return
 	}
+	if !isValidPartID(partID) {
+		writeErrorResponse(w, r, ErrInvalidPartNumber, r.URL.Path)
+		return
+	}
 
 	var partMD5 string
 	switch getRequestAuthType(r) {
@@ -812,7 +842,7 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		}
 		// No need to verify signature, anonymous request access is
 		// already allowed.
-		partMD5, err = api.ObjectAPI.PutObjectPart(bucket, object, uploadID, partID, size, r.Body, hex.EncodeToString(md5Bytes))
+		partMD5, err = api.ObjectAPI.WithContext(r.Context()).PutObjectPart(bucket, object, uploadID, partID, size, r.Body, hex.EncodeToString(md5Bytes))
 	case authTypePresigned, authTypeSigned:
 		validateRegion := true // Validate region.
 		// Initialize a pipe for data pipe line.
@@ -845,7 +875,7 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 			// Close the writer.
 			writer.Close()
 		}()
-		partMD5, err = api.ObjectAPI.PutObjectPart(bucket, object, uploadID, partID, size, reader, hex.EncodeToString(md5Bytes))
+		partMD5, err = api.ObjectAPI.WithContext(r.Context()).PutObjectPart(bucket, object, uploadID, partID, size, reader, hex.EncodeToString(md5Bytes))
 	}
 	if err != nil {
 		errorIf(err.Trace(), "PutObjectPart failed.", nil)
@@ -858,12 +888,18 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		switch e.(type) {
 		case StorageFull:
 			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+		case QuotaExceeded:
+			writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
 		case InvalidUploadID:
 			writeErrorResponse(w, r, ErrNoSuchUpload, r.URL.Path)
 		case BadDigest:
 			writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
 		case IncompleteBody:
 			writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
+		case ObjectTooLarge:
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
@@ -875,6 +911,161 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 	writeSuccessResponse(w, nil)
 }
 
+// CopyObjectPartHandler - Upload part copy
+// ----------
+// This implementation of the PUT operation uploads a part by copying
+// data from an existing object, all of it or the byte range of the
+// x-amz-copy-source-range header.
+func (api objectAPIHandlers) CopyObjectPartHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	objectSource, sourceBucket, sourceObject := getCopySource(r)
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+		// Anonymous requests need to be allowed to read the source.
+		sourceURL := &url.URL{Path: "/" + objectSource}
+		if s3Error := enforceBucketPolicy("s3:GetObject", sourceBucket, sourceURL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, objectSource)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If source object is empty, reply back error.
+	if sourceObject == "" {
+		writeErrorResponse(w, r, ErrInvalidCopySource, r.URL.Path)
+		return
+	}
+
+	uploadID := r.URL.Query().Get("uploadId")
+	partID, e := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if e != nil {
+		writeErrorResponse(w, r, ErrInvalidPart, r.URL.Path)
+		return
+	}
+	if !isValidPartID(partID) {
+		writeErrorResponse(w, r, ErrInvalidPartNumber, r.URL.Path)
+		return
+	}
+
+	objInfo, err := api.ObjectAPI.GetObjectInfo(sourceBucket, sourceObject)
+	if err != nil {
+		errorIf(err.Trace(), "GetObjectInfo failed.", nil)
+		switch err.ToGoError().(type) {
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, objectSource)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, objectSource)
+		case ObjectNotFound:
+			writeErrorResponse(w, r, ErrNoSuchKey, objectSource)
+		case ObjectNameInvalid:
+			writeErrorResponse(w, r, ErrNoSuchKey, objectSource)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, objectSource)
+		}
+		return
+	}
+
+	// Verify x-amz-copy-source-if-match, x-amz-copy-source-if-none-match,
+	// x-amz-copy-source-if-modified-since and
+	// x-amz-copy-source-if-unmodified-since.
+	if checkCopySourcePreconditions(w, r, objInfo) {
+		return
+	}
+
+	startOffset, length := int64(0), objInfo.Size
+	if copyRange := r.Header.Get("X-Amz-Copy-Source-Range"); copyRange != "" {
+		var ok bool
+		if startOffset, length, ok = parseCopyPartRange(copyRange, objInfo.Size); !ok {
+			writeErrorResponse(w, r, ErrInvalidCopyPartRange, r.URL.Path)
+			return
+		}
+	}
+
+	/// maximum Upload size for multipart objects in a single operation
+	if isMaxPartSize(length) {
+		writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+		return
+	}
+
+	partMD5, err := api.ObjectAPI.WithContext(r.Context()).CopyObjectPart(sourceBucket, sourceObject, startOffset, length, bucket, object, uploadID, partID)
+	if err != nil {
+		errorIf(err.Trace(), "CopyObjectPart failed.", nil)
+		switch err.ToGoError().(type) {
+		case StorageFull:
+			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
+		case QuotaExceeded:
+			writeErrorResponse(w, r, ErrQuotaExceeded, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case ObjectNotFound:
+			writeErrorResponse(w, r, ErrNoSuchKey, objectSource)
+		case InvalidUploadID:
+			writeErrorResponse(w, r, ErrNoSuchUpload, r.URL.Path)
+		case InvalidRange:
+			writeErrorResponse(w, r, ErrInvalidCopyPartRange, r.URL.Path)
+		case BadDigest:
+			// Source changed while it was copied.
+			writeErrorResponse(w, r, ErrBadDigest, r.URL.Path)
+		case ObjectTooLarge:
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+
+	response := generateCopyObjectPartResponse(partMD5, time.Now().UTC())
+	encodedSuccessResponse := encodeResponse(response)
+	// write headers
+	setCommonHeaders(w)
+	// write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// parseCopyPartRange - parses a copy source range of the form
+// "bytes=first-last", which needs to lie within an object of size.
+func parseCopyPartRange(copyRange string, size int64) (startOffset, length int64, ok bool) {
+	if !strings.HasPrefix(copyRange, b) {
+		return 0, 0, false
+	}
+	splits := strings.SplitN(strings.TrimPrefix(copyRange, b), "-", 2)
+	if len(splits) != 2 {
+		return 0, 0, false
+	}
+	first, e := strconv.ParseInt(splits[0], 10, 64)
+	if e != nil {
+		return 0, 0, false
+	}
+	last, e := strconv.ParseInt(splits[1], 10, 64)
+	if e != nil {
+		return 0, 0, false
+	}
+	if first < 0 || first > last || last >= size {
+		return 0, 0, false
+	}
+	return first, last - first + 1, true
+}
+
 // AbortMultipartUploadHandler - Abort multipart upload
 func (api objectAPIHandlers) AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -955,7 +1146,8 @@ func (api objectAPIHandlers) ListObjectPartsHandler(w http.ResponseWriter, r *ht
 		writeErrorResponse(w, r, ErrInvalidMaxParts, r.URL.Path)
 		return
 	}
-	if maxParts == 0 {
+	// At most maxPartsList parts are returned.
+	if maxParts == 0 || maxParts > maxPartsList {
 		maxParts = maxPartsList
 	}
 
@@ -1040,10 +1232,13 @@ func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 		completeParts = append(completeParts, part)
 	}
 	// Complete multipart upload.
-	md5Sum, err = api.ObjectAPI.CompleteMultipartUpload(bucket, object, uploadID, completeParts)
+	var sequence uint64
+	md5Sum, err = api.ObjectAPI.WithContext(r.Context()).WithSequence(&sequence).CompleteMultipartUpload(bucket, object, uploadID, completeParts)
 	if err != nil {
 		errorIf(err.Trace(), "CompleteMultipartUpload failed.", nil)
 		switch err.ToGoError().(type) {
+		case ObjectLocked:
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
@@ -1058,6 +1253,8 @@ func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 			writeErrorResponse(w, r, ErrInvalidPart, r.URL.Path)
 		case IncompleteBody:
 			writeErrorResponse(w, r, ErrIncompleteBody, r.URL.Path)
+		case StorageFull:
+			writeErrorResponse(w, r, ErrStorageFull, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
@@ -1072,6 +1269,9 @@ func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 	setCommonHeaders(w)
 	// write success response.
 	writeSuccessResponse(w, encodedSuccessResponse)
+
+	// Notify object created event.
+	notifyObjectCreated(api.ObjectAPI, r, ObjectCreatedCompleteMultipartUpload, bucket, object, md5Sum, sequence)
 }
 
 /// Delete objectAPIHandlers
@@ -1099,10 +1299,17 @@ func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 			return
 		}
 	}
-	err := api.ObjectAPI.DeleteObject(bucket, object)
+	if s3Error := checkBypassGovernance(r, bucket, object); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	var sequence uint64
+	err := api.ObjectAPI.WithSequence(&sequence).DeleteObject(bucket, object, isBypassGovernance(r))
 	if err != nil {
 		errorIf(err.Trace(), "DeleteObject failed.", nil)
 		switch err.ToGoError().(type) {
+		case ObjectLocked:
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
 		case BucketNameInvalid:
 			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
 		case BucketNotFound:
@@ -1111,10 +1318,15 @@ func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
 		case ObjectNameInvalid:
 			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		case OperationTimedOut:
+			writeErrorResponse(w, r, ErrOperationTimedOut, r.URL.Path)
 		default:
 			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
 		}
 		return
 	}
 	writeSuccessNoContent(w)
+
+	// Notify object removed event.
+	notifyObjectRemoved(api.ObjectAPI, r, bucket, object, sequence)
 }
@@ -0,0 +1,105 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync"
+
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/UploadingObjects.html
+const (
+	// maximum object size per PUT request is 5GiB
+	maxObjectSize = 1024 * 1024 * 1024 * 5
+	// maximum size of a part of a multipart upload is 5GiB
+	maxPartSize = 1024 * 1024 * 1024 * 5
+	// maximum part number of a multipart upload
+	maxPartID = 10000
+)
+
+// objectLimitsConfig - limits of objects and parts uploaded, checked
+// before their data is read. Zero values default to the limits of S3,
+// which are the highest allowed.
+type objectLimitsConfig struct {
+	// Largest object written by a single request, PUT, copy or
+	// extraction, in bytes.
+	MaxObjectSize int64 `json:"maxObjectSize"`
+	// Largest part of a multipart upload, in bytes.
+	MaxPartSize int64 `json:"maxPartSize"`
+	// Highest part number of a multipart upload, which bounds the
+	// number of parts of an upload.
+	MaxParts int `json:"maxParts"`
+}
+
+// objectLimits - current limits of objects and parts.
+type objectLimits struct {
+	rwMutex *sync.RWMutex
+	config  objectLimitsConfig
+}
+
+// Global object limits, configured at server start.
+var globalObjectLimits = &objectLimits{rwMutex: &sync.RWMutex{}}
+
+// Get - returns current configuration.
+func (l *objectLimits) Get() objectLimitsConfig {
+	l.rwMutex.RLock()
+	defer l.rwMutex.RUnlock()
+	return l.config
+}
+
+// Set - sets configuration, applies to requests received afterwards.
+func (l *objectLimits) Set(config objectLimitsConfig) {
+	l.rwMutex.Lock()
+	defer l.rwMutex.Unlock()
+	l.config = config
+}
+
+// maxObjectSize - returns the largest object size allowed.
+func (c objectLimitsConfig) maxObjectSize() int64 {
+	if c.MaxObjectSize <= 0 || c.MaxObjectSize > maxObjectSize {
+		return maxObjectSize
+	}
+	return c.MaxObjectSize
+}
+
+// maxPartSize - returns the largest part size allowed.
+func (c objectLimitsConfig) maxPartSize() int64 {
+	if c.MaxPartSize <= 0 || c.MaxPartSize > maxPartSize {
+		return maxPartSize
+	}
+	return c.MaxPartSize
+}
+
+// maxParts - returns the highest part number allowed.
+func (c objectLimitsConfig) maxParts() int {
+	if c.MaxParts <= 0 || c.MaxParts > maxPartID {
+		return maxPartID
+	}
+	return c.MaxParts
+}
+
+// isMaxObjectSize - verify if max object size
+func isMaxObjectSize(size int64) bool {
+	return size > globalObjectLimits.Get().maxObjectSize()
+}
+
+// isMaxPartSize - returns true if size is larger than parts may be.
+func isMaxPartSize(size int64) bool {
+	return size > globalObjectLimits.Get().maxPartSize()
+}
+
+// isValidPartID - returns true if partID is a part number allowed.
+func isValidPartID(partID int) bool {
+	return partID >= 1 && partID <= globalObjectLimits.Get().maxParts()
+}
@@ -0,0 +1,100 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests limits default to, and cannot exceed, the limits of S3.
+func TestObjectLimitsConfig(t *testing.T) {
+	testCases := []struct {
+		config        objectLimitsConfig
+		maxObjectSize int64
+		maxPartSize   int64
+		maxParts      int
+	}{
+		{objectLimitsConfig{}, maxObjectSize, maxPartSize, maxPartID},
+		{objectLimitsConfig{MaxObjectSize: 1024, MaxPartSize: 512, MaxParts: 10}, 1024, 512, 10},
+		{objectLimitsConfig{MaxObjectSize: 2 * maxObjectSize, MaxPartSize: 2 * maxPartSize, MaxParts: 2 * maxPartID}, maxObjectSize, maxPartSize, maxPartID},
+	}
+	for i, testCase := range testCases {
+		if size := testCase.config.maxObjectSize(); size != testCase.maxObjectSize {
+			t.Errorf("Test %d: expected max object size %d, got %d", i+1, testCase.maxObjectSize, size)
+		}
+		if size := testCase.config.maxPartSize(); size != testCase.maxPartSize {
+			t.Errorf("Test %d: expected max part size %d, got %d", i+1, testCase.maxPartSize, size)
+		}
+		if parts := testCase.config.maxParts(); parts != testCase.maxParts {
+			t.Errorf("Test %d: expected max parts %d, got %d", i+1, testCase.maxParts, parts)
+		}
+	}
+}
+
+// Tests objects and parts over the limits are refused before they are
+// written.
+func TestObjectLimits(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-object-limits-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	globalObjectLimits.Set(objectLimitsConfig{MaxObjectSize: 10, MaxPartSize: 5, MaxParts: 2})
+	defer globalObjectLimits.Set(objectLimitsConfig{})
+
+	data := []byte("hello world")
+	if _, err := obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err == nil {
+		t.Fatal("Expected object over the limit to be refused")
+	} else if _, ok := err.ToGoError().(ObjectTooLarge); !ok {
+		t.Fatalf("Expected ObjectTooLarge, got %s", err.ToGoError())
+	}
+	if _, err := obj.PutObject("bucket", "object", -1, bytes.NewReader(data), nil); err == nil {
+		t.Fatal("Expected object of unknown size over the limit to be refused")
+	}
+	if _, err := obj.GetObjectInfo("bucket", "object"); err == nil {
+		t.Fatal("Expected object refused not to be written")
+	}
+
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.PutObjectPart("bucket", "object", uploadID, 1, int64(len(data)), bytes.NewReader(data), ""); err == nil {
+		t.Fatal("Expected part over the limit to be refused")
+	} else if _, ok := err.ToGoError().(ObjectTooLarge); !ok {
+		t.Fatalf("Expected ObjectTooLarge, got %s", err.ToGoError())
+	}
+	if _, err = obj.PutObjectPart("bucket", "object", uploadID, 3, 5, bytes.NewReader(data[:5]), ""); err == nil {
+		t.Fatal("Expected part number over the limit to be refused")
+	} else if _, ok := err.ToGoError().(InvalidPart); !ok {
+		t.Fatalf("Expected InvalidPart, got %s", err.ToGoError())
+	}
+	if _, err = obj.PutObjectPart("bucket", "object", uploadID, 2, 5, bytes.NewReader(data[:5]), ""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Duration for which a not found result is remembered.
+	notFoundCacheTTL = 5 * time.Second
+
+	// Maximum number of not found results remembered at a time.
+	maxNotFoundCacheEntries = 10000
+)
+
+// notFoundCache - remembers recent object not found results, so
+// that clients polling for objects which do not exist yet do not
+// cost a metadata lookup on all disks for every request. Entries are
+// invalidated on every write to the same object.
+type notFoundCache struct {
+	mutex   *sync.Mutex
+	entries map[string]time.Time // "bucket/object" to expiry.
+	// Incremented on every invalidation, lookups which started before
+	// an invalidation are not cached.
+	generation uint64
+}
+
+// newNotFoundCache - initialize a new not found cache.
+func newNotFoundCache() *notFoundCache {
+	return &notFoundCache{
+		mutex:   &sync.Mutex{},
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Generation - returns the current generation, to be passed to Add
+// once the lookup finishes.
+func (c *notFoundCache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.generation
+}
+
+// IsNotFound - returns true if object was recently not found.
+func (c *notFoundCache) IsNotFound(bucket, object string) bool {
+	if c == nil {
+		return false
+	}
+	key := bucket + slashSeparator + object
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expiry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// Add - remembers object as not found, unless the object was written
+// after generation was obtained.
+func (c *notFoundCache) Add(bucket, object string, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if generation != c.generation {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= maxNotFoundCacheEntries {
+		// Purge expired entries, start afresh if all of them are live.
+		for key, expiry := range c.entries {
+			if now.After(expiry) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxNotFoundCacheEntries {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+	c.entries[bucket+slashSeparator+object] = now.Add(notFoundCacheTTL)
+}
+
+// Invalidate - forgets not found result for object.
+func (c *notFoundCache) Invalidate(bucket, object string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	delete(c.entries, bucket+slashSeparator+object)
+}
+
+// InvalidateBucket - forgets not found results for all objects in
+// bucket.
+func (c *notFoundCache) InvalidateBucket(bucket string) {
+	if c == nil {
+		return
+	}
+	prefix := bucket + slashSeparator
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
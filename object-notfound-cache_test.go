@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests that not found results are cached and invalidated on writes.
+func TestNotFoundCache(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-notfound-cache-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := obj.GetObjectInfo("bucket", "object")
+	if _, ok := err.ToGoError().(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %s", err)
+	}
+	if !obj.notFound.IsNotFound("bucket", "object") {
+		t.Fatal("Expected not found result to be cached")
+	}
+
+	// Write invalidates the cached result.
+	if _, err = obj.PutObject("bucket", "object", int64(len("hello")), bytes.NewBufferString("hello"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if obj.notFound.IsNotFound("bucket", "object") {
+		t.Fatal("Expected not found result to be invalidated")
+	}
+	if _, err = obj.GetObjectInfo("bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Results of lookups which raced with a write are not cached.
+	generation := obj.notFound.Generation()
+	obj.notFound.Invalidate("bucket", "other")
+	obj.notFound.Add("bucket", "other", generation)
+	if obj.notFound.IsNotFound("bucket", "other") {
+		t.Fatal("Expected stale not found result to be ignored")
+	}
+
+	// Deleting a bucket forgets all its objects.
+	obj.notFound.Add("bucket", "other", obj.notFound.Generation())
+	obj.notFound.InvalidateBucket("bucket")
+	if obj.notFound.IsNotFound("bucket", "other") {
+		t.Fatal("Expected bucket results to be invalidated")
+	}
+}
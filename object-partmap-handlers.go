@@ -0,0 +1,89 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetObjectPartMapHandler - GET Object part map
+// -----------------
+// This operation uses the partmap subresource to return a split plan
+// of the object for parallel ranged GETs, parts are aligned to the
+// erasure stripes of the storage. The optional parts parameter sets
+// the maximum number of parts, the object is never split below a
+// stripe.
+func (api objectAPIHandlers) GetObjectPartMapHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// Part map is readable by anyone allowed to read the object.
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r.URL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	parts := defaultPartMapParts
+	if partsStr := r.URL.Query().Get("parts"); partsStr != "" {
+		var e error
+		if parts, e = strconv.Atoi(partsStr); e != nil {
+			writeErrorResponse(w, r, ErrInvalidPartMapParts, r.URL.Path)
+			return
+		}
+	}
+
+	partMap, err := api.ObjectAPI.GetObjectPartMap(bucket, object, parts)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectPartMap failed.", nil)
+		switch err.ToGoError().(type) {
+		case InvalidPartMapParts:
+			writeErrorResponse(w, r, ErrInvalidPartMapParts, r.URL.Path)
+		case BucketNameInvalid:
+			writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+		case BucketNotFound:
+			writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+		case ObjectNotFound:
+			writeErrorResponse(w, r, errAllowableObjectNotFound(bucket, r), r.URL.Path)
+		case ObjectNameInvalid:
+			writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+		default:
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		}
+		return
+	}
+	encodedSuccessResponse := encodeResponse(partMap)
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
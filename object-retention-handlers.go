@@ -0,0 +1,274 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// isBypassGovernance - returns true if an authenticated request asks
+// to bypass governance retention, anonymous requests cannot bypass.
+func isBypassGovernance(r *http.Request) bool {
+	switch getRequestAuthType(r) {
+	case authTypePresigned, authTypeSigned:
+		return strings.EqualFold(r.Header.Get(bypassGovernanceKey), "true")
+	}
+	return false
+}
+
+// checkBypassGovernance - returns ErrAccessDenied if request asks to
+// bypass governance retention of object in bucket, but its signer is
+// not allowed to.
+func checkBypassGovernance(r *http.Request, bucket, object string) APIErrorCode {
+	if !isBypassGovernance(r) {
+		return ErrNone
+	}
+	if !globalIAMUsers.IsAllowed(getAuditRequester(r), "s3:BypassGovernanceRetention", getIAMResource(bucket, object)) {
+		return ErrAccessDenied
+	}
+	return ErrNone
+}
+
+// setObjectLockMetadata - copies object lock headers of request to
+// PutObject metadata.
+func setObjectLockMetadata(r *http.Request, metadata map[string]string) {
+	for _, key := range []string{objectLockModeKey, objectLockRetainUntilKey, objectLockLegalHoldKey} {
+		if value := r.Header.Get(key); value != "" {
+			metadata[key] = value
+		}
+	}
+	if isBypassGovernance(r) {
+		metadata[bypassGovernanceKey] = "true"
+	}
+}
+
+// writeObjectRetentionError - writes error response for retention and
+// legal hold errors.
+func writeObjectRetentionError(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case BucketNameInvalid:
+		writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case ObjectNotFound, ObjectNameInvalid:
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	case BucketObjectLockNotFound:
+		writeErrorResponse(w, r, ErrObjectLockConfigurationNotFound, r.URL.Path)
+	case ObjectRetentionNotFound:
+		writeErrorResponse(w, r, ErrNoSuchObjectLockConfiguration, r.URL.Path)
+	case InvalidRetention:
+		writeErrorResponse(w, r, ErrInvalidRetention, r.URL.Path)
+	case InvalidLegalHold:
+		writeErrorResponse(w, r, ErrInvalidLegalHold, r.URL.Path)
+	case ObjectLocked:
+		writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// PutObjectRetentionHandler - PUT Object retention
+// -----------------
+// This implementation of the PUT operation uses the retention
+// subresource to set the retention of an existing object in a bucket
+// with object lock enabled. Active retention can only be extended,
+// unless governance retention is bypassed.
+func (api objectAPIHandlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxRetentionSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	retentionBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxRetentionSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Reading retention failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	var retention objectRetention
+	if e = xml.Unmarshal(retentionBytes, &retention); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse retention.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+
+	if s3Error := checkBypassGovernance(r, bucket, object); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	if err := api.ObjectAPI.PutObjectRetention(bucket, object, retention, isBypassGovernance(r)); err != nil {
+		errorIf(err.Trace(bucket, object), "PutObjectRetention failed.", nil)
+		writeObjectRetentionError(w, r, err)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetObjectRetentionHandler - GET Object retention
+// -----------------
+// This operation uses the retention subresource to return the
+// retention of an object.
+func (api objectAPIHandlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	retention, err := api.ObjectAPI.GetObjectRetention(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectRetention failed.", nil)
+		writeObjectRetentionError(w, r, err)
+		return
+	}
+	encodedSuccessResponse := encodeResponse(retention)
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// PutObjectLegalHoldHandler - PUT Object legal hold
+// -----------------
+// This implementation of the PUT operation uses the legal-hold
+// subresource to place or remove the legal hold of an existing object
+// in a bucket with object lock enabled.
+func (api objectAPIHandlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxLegalHoldSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	legalHoldBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxLegalHoldSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Reading legal hold failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	var legalHold objectLegalHold
+	if e = xml.Unmarshal(legalHoldBytes, &legalHold); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse legal hold.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+
+	if err := api.ObjectAPI.PutObjectLegalHold(bucket, object, legalHold.Status); err != nil {
+		errorIf(err.Trace(bucket, object), "PutObjectLegalHold failed.", nil)
+		writeObjectRetentionError(w, r, err)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetObjectLegalHoldHandler - GET Object legal hold
+// -----------------
+// This operation uses the legal-hold subresource to return the legal
+// hold status of an object.
+func (api objectAPIHandlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	status, err := api.ObjectAPI.GetObjectLegalHold(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectLegalHold failed.", nil)
+		writeObjectRetentionError(w, r, err)
+		return
+	}
+	encodedSuccessResponse := encodeResponse(objectLegalHold{Status: status})
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
@@ -0,0 +1,86 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// SelectObjectContentHandler - POST Object select
+// -----------------
+// This operation filters the content of a CSV or JSON object with an
+// SQL expression, only the records and columns selected are returned.
+// Records are streamed as they are selected, framed in the event
+// stream encoding, followed by statistics of the bytes scanned.
+func (api objectAPIHandlers) SelectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r.URL); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is greater than maximum allowed size.
+	if r.ContentLength > maxSelectRequestSize {
+		writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+		return
+	}
+	var selectReq selectObjectContentRequest
+	if e := xml.NewDecoder(io.LimitReader(r.Body, maxSelectRequestSize)).Decode(&selectReq); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse select request.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	query, s3Error := newSelectQuery(selectReq)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	readCloser, err := api.ObjectAPI.WithContext(r.Context()).GetObject(bucket, object, 0)
+	if err != nil {
+		writeGetObjectError(w, r, bucket, object, err)
+		return
+	}
+	defer readCloser.Close()
+
+	setCommonHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	if e := query.run(w, readCloser); e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "SelectObjectContent failed.", nil)
+	}
+}
@@ -0,0 +1,1158 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SQL expressions of select requests, a subset of the S3 Select SQL:
+//
+//   SELECT * | expr [[AS] alias], ... FROM S3Object [[AS] alias]
+//     [WHERE expr] [LIMIT n]
+//
+// Expressions are column references, literals, arithmetic,
+// comparisons, LIKE, BETWEEN, IN, IS [NOT] NULL, AND, OR, NOT, the
+// functions CAST, LOWER, UPPER, TRIM, CHAR_LENGTH and COALESCE, and
+// the aggregates COUNT, SUM, AVG, MIN and MAX.
+
+var (
+	errSQLUnexpectedEnd     = errors.New("Unexpected end of SQL expression")
+	errSQLUnterminated      = errors.New("Unterminated string or quoted identifier")
+	errSQLInvalidTable      = errors.New("Only S3Object can be selected from")
+	errSQLMixedAggregate    = errors.New("Aggregates cannot be selected along with columns")
+	errSQLNestedAggregate   = errors.New("Aggregates cannot be used in WHERE or in other aggregates")
+	errSQLInvalidLimit      = errors.New("LIMIT should be a non negative integer")
+	errSQLInvalidCastType   = errors.New("CAST type should be one of INT, INTEGER, FLOAT, DECIMAL, STRING or BOOL")
+	errSQLNotNumber         = errors.New("Value is not a number")
+	errSQLDivisionByZero    = errors.New("Division by zero")
+	errSQLInvalidComparison = errors.New("Values cannot be compared")
+)
+
+// Keywords which end expressions, they cannot be aliases without AS.
+var sqlReservedKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true,
+	"AND": true, "OR": true, "NOT": true, "AS": true, "IS": true,
+	"LIKE": true, "BETWEEN": true, "IN": true,
+}
+
+const (
+	sqlTokenEOF = iota
+	sqlTokenIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenOp
+)
+
+// sqlToken - token of an SQL expression, quoted identifiers are case
+// sensitive.
+type sqlToken struct {
+	kind   int
+	text   string
+	quoted bool
+}
+
+// sqlOperators - operators, longest first.
+var sqlOperators = []string{"<=", ">=", "<>", "!=", "||", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ",", "."}
+
+// tokenizeSQL - splits expression into tokens.
+func tokenizeSQL(expression string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			// Quotes are escaped by doubling them.
+			var text []rune
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == r {
+					if j+1 < len(runes) && runes[j+1] == r {
+						text = append(text, r)
+						j++
+						continue
+					}
+					break
+				}
+				text = append(text, runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errSQLUnterminated
+			}
+			kind := sqlTokenString
+			if r == '"' {
+				kind = sqlTokenIdent
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: string(text), quoted: r == '"'})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' || runes[j] == 'e' || runes[j] == 'E' ||
+				((runes[j] == '+' || runes[j] == '-') && (runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenNumber, text: string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			matched := false
+			for _, op := range sqlOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, sqlToken{kind: sqlTokenOp, text: op})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("Unexpected character %q in SQL expression", r)
+			}
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlTokenEOF}), nil
+}
+
+// sqlRecord - record expressions are evaluated against.
+type sqlRecord interface {
+	// value - returns value of the column at path, nil if missing.
+	value(path []sqlToken) interface{}
+}
+
+// sqlExpr - expression evaluated against records, values are nil,
+// bool, float64, string, or JSON arrays and objects.
+type sqlExpr interface {
+	eval(record sqlRecord) (interface{}, error)
+}
+
+// sqlSelectItem - expression selected, named alias in the output.
+type sqlSelectItem struct {
+	expr  sqlExpr
+	alias string
+}
+
+// sqlSelect - parsed SELECT statement.
+type sqlSelect struct {
+	// Whole records are selected if there are no items.
+	items      []sqlSelectItem
+	where      sqlExpr
+	limit      int64
+	aggregates []*sqlAggregate
+}
+
+// isAggregate - returns true if statement returns a single record
+// of aggregates.
+func (stmt *sqlSelect) isAggregate() bool {
+	return len(stmt.aggregates) > 0
+}
+
+// itemName - returns name of the i-th selected item in the output,
+// columns are named after themselves, other expressions after their
+// position.
+func (stmt *sqlSelect) itemName(i int) string {
+	item := stmt.items[i]
+	if item.alias != "" {
+		return item.alias
+	}
+	if column, ok := item.expr.(*sqlColumn); ok {
+		return column.path[len(column.path)-1].text
+	}
+	return "_" + strconv.Itoa(i+1)
+}
+
+// sqlParser - recursive descent parser of SELECT statements.
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+	// Alias of S3Object, column references may be prefixed with it.
+	tableAlias string
+	// Aggregates found so far, nil where they are not allowed.
+	aggregates *[]*sqlAggregate
+	// True once a column is referenced outside of aggregates.
+	hasColumns bool
+}
+
+// parseSQLSelect - parses a SELECT statement.
+func parseSQLSelect(expression string) (*sqlSelect, error) {
+	tokens, e := tokenizeSQL(expression)
+	if e != nil {
+		return nil, e
+	}
+	p := &sqlParser{tokens: tokens}
+	// Alias of the table is needed to parse the items before it.
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind == sqlTokenIdent && !tokens[i].quoted && strings.EqualFold(tokens[i].text, "FROM") {
+			j := i + 2
+			if j < len(tokens) && tokens[j].kind == sqlTokenIdent && !tokens[j].quoted && strings.EqualFold(tokens[j].text, "AS") {
+				j++
+			}
+			if j < len(tokens) && tokens[j].kind == sqlTokenIdent && (tokens[j].quoted || !sqlReservedKeywords[strings.ToUpper(tokens[j].text)]) {
+				p.tableAlias = tokens[j].text
+			}
+			break
+		}
+	}
+	return p.parseSelect()
+}
+
+// peek - returns next token, EOF past the end.
+func (p *sqlParser) peek() sqlToken {
+	if p.pos >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos]
+}
+
+// next - returns next token and moves past it, EOF past the end.
+func (p *sqlParser) next() sqlToken {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// isKeyword - returns true if token is the unquoted keyword.
+func (token sqlToken) isKeyword(keyword string) bool {
+	return token.kind == sqlTokenIdent && !token.quoted && strings.EqualFold(token.text, keyword)
+}
+
+func (p *sqlParser) acceptKeyword(keyword string) bool {
+	if p.peek().isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *sqlParser) acceptOp(op string) bool {
+	if token := p.peek(); token.kind == sqlTokenOp && token.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.unexpected(op)
+	}
+	return nil
+}
+
+// unexpected - returns error of an unexpected token where expected
+// was expected.
+func (p *sqlParser) unexpected(expected string) error {
+	token := p.peek()
+	if token.kind == sqlTokenEOF {
+		return errSQLUnexpectedEnd
+	}
+	return fmt.Errorf("Expected %s, found %q", expected, token.text)
+}
+
+// acceptAlias - returns alias following an expression or a table, if
+// any.
+func (p *sqlParser) acceptAlias() (string, error) {
+	if p.acceptKeyword("AS") {
+		token := p.next()
+		if token.kind != sqlTokenIdent {
+			p.pos--
+			return "", p.unexpected("alias")
+		}
+		return token.text, nil
+	}
+	if token := p.peek(); token.kind == sqlTokenIdent && (token.quoted || !sqlReservedKeywords[strings.ToUpper(token.text)]) {
+		p.pos++
+		return token.text, nil
+	}
+	return "", nil
+}
+
+func (p *sqlParser) parseSelect() (*sqlSelect, error) {
+	if e := p.expectKeyword("SELECT"); e != nil {
+		return nil, e
+	}
+	var aggregates []*sqlAggregate
+	p.aggregates = &aggregates
+	stmt := &sqlSelect{limit: -1}
+	if !p.acceptOp("*") {
+		for {
+			expr, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			alias, e := p.acceptAlias()
+			if e != nil {
+				return nil, e
+			}
+			stmt.items = append(stmt.items, sqlSelectItem{expr: expr, alias: alias})
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+	stmt.aggregates = aggregates
+	if stmt.isAggregate() && (p.hasColumns || len(stmt.items) == 0) {
+		return nil, errSQLMixedAggregate
+	}
+	p.aggregates = nil
+
+	if e := p.expectKeyword("FROM"); e != nil {
+		return nil, e
+	}
+	if !p.acceptKeyword("S3Object") {
+		return nil, errSQLInvalidTable
+	}
+	if _, e := p.acceptAlias(); e != nil {
+		return nil, e
+	}
+	if p.acceptKeyword("WHERE") {
+		where, e := p.parseExpr()
+		if e != nil {
+			return nil, e
+		}
+		stmt.where = where
+	}
+	if p.acceptKeyword("LIMIT") {
+		token := p.next()
+		limit, e := strconv.ParseInt(token.text, 10, 64)
+		if token.kind != sqlTokenNumber || e != nil || limit < 0 {
+			return nil, errSQLInvalidLimit
+		}
+		stmt.limit = limit
+	}
+	if token := p.peek(); token.kind != sqlTokenEOF {
+		return nil, fmt.Errorf("Unexpected %q at end of SQL expression", token.text)
+	}
+	return stmt, nil
+}
+
+func (p *sqlParser) parseExpr() (sqlExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sqlParser) parseOr() (sqlExpr, error) {
+	left, e := p.parseAnd()
+	if e != nil {
+		return nil, e
+	}
+	for p.acceptKeyword("OR") {
+		right, e := p.parseAnd()
+		if e != nil {
+			return nil, e
+		}
+		left = &sqlLogical{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	left, e := p.parseNot()
+	if e != nil {
+		return nil, e
+	}
+	for p.acceptKeyword("AND") {
+		right, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		left = &sqlLogical{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.acceptKeyword("NOT") {
+		expr, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		return &sqlNot{expr: expr}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (sqlExpr, error) {
+	left, e := p.parseAdditive()
+	if e != nil {
+		return nil, e
+	}
+	if token := p.peek(); token.kind == sqlTokenOp {
+		switch token.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.pos++
+			right, e := p.parseAdditive()
+			if e != nil {
+				return nil, e
+			}
+			return &sqlComparison{op: token.text, left: left, right: right}, nil
+		}
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if e = p.expectKeyword("NULL"); e != nil {
+			return nil, e
+		}
+		return &sqlIsNull{expr: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		pattern, e := p.parseAdditive()
+		if e != nil {
+			return nil, e
+		}
+		return &sqlLike{expr: left, pattern: pattern, not: not}, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, e := p.parseAdditive()
+		if e != nil {
+			return nil, e
+		}
+		if e = p.expectKeyword("AND"); e != nil {
+			return nil, e
+		}
+		high, e := p.parseAdditive()
+		if e != nil {
+			return nil, e
+		}
+		return &sqlBetween{expr: left, low: low, high: high, not: not}, nil
+	case p.acceptKeyword("IN"):
+		if e = p.expectOp("("); e != nil {
+			return nil, e
+		}
+		in := &sqlIn{expr: left, not: not}
+		for {
+			value, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			in.list = append(in.list, value)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		if e = p.expectOp(")"); e != nil {
+			return nil, e
+		}
+		return in, nil
+	}
+	if not {
+		return nil, p.unexpected("LIKE, BETWEEN or IN")
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAdditive() (sqlExpr, error) {
+	left, e := p.parseMultiplicative()
+	if e != nil {
+		return nil, e
+	}
+	for {
+		token := p.peek()
+		if token.kind != sqlTokenOp || (token.text != "+" && token.text != "-" && token.text != "||") {
+			return left, nil
+		}
+		p.pos++
+		right, e := p.parseMultiplicative()
+		if e != nil {
+			return nil, e
+		}
+		left = &sqlArithmetic{op: token.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) parseMultiplicative() (sqlExpr, error) {
+	left, e := p.parseUnary()
+	if e != nil {
+		return nil, e
+	}
+	for {
+		token := p.peek()
+		if token.kind != sqlTokenOp || (token.text != "*" && token.text != "/" && token.text != "%") {
+			return left, nil
+		}
+		p.pos++
+		right, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		left = &sqlArithmetic{op: token.text, left: left, right: right}
+	}
+}
+
+func (p *sqlParser) parseUnary() (sqlExpr, error) {
+	if p.acceptOp("-") {
+		expr, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		return &sqlArithmetic{op: "-", left: &sqlLiteral{float64(0)}, right: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (sqlExpr, error) {
+	token := p.next()
+	switch token.kind {
+	case sqlTokenNumber:
+		f, e := strconv.ParseFloat(token.text, 64)
+		if e != nil {
+			return nil, fmt.Errorf("Invalid number %q", token.text)
+		}
+		return &sqlLiteral{f}, nil
+	case sqlTokenString:
+		return &sqlLiteral{token.text}, nil
+	case sqlTokenOp:
+		if token.text == "(" {
+			expr, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			if e = p.expectOp(")"); e != nil {
+				return nil, e
+			}
+			return expr, nil
+		}
+	case sqlTokenIdent:
+		switch {
+		case token.isKeyword("NULL"):
+			return &sqlLiteral{nil}, nil
+		case token.isKeyword("TRUE"):
+			return &sqlLiteral{true}, nil
+		case token.isKeyword("FALSE"):
+			return &sqlLiteral{false}, nil
+		case !token.quoted && p.peek().kind == sqlTokenOp && p.peek().text == "(":
+			p.pos++
+			return p.parseFunction(strings.ToUpper(token.text))
+		case !token.quoted && sqlReservedKeywords[strings.ToUpper(token.text)]:
+			p.pos--
+			return nil, p.unexpected("expression")
+		}
+		return p.parseColumn(token)
+	}
+	p.pos--
+	return nil, p.unexpected("expression")
+}
+
+// parseColumn - parses column reference starting with token, without
+// the alias of S3Object.
+func (p *sqlParser) parseColumn(token sqlToken) (sqlExpr, error) {
+	path := []sqlToken{token}
+	for p.acceptOp(".") {
+		next := p.next()
+		if next.kind != sqlTokenIdent {
+			p.pos--
+			return nil, p.unexpected("column name")
+		}
+		path = append(path, next)
+	}
+	if len(path) > 1 && p.tableAlias != "" && (path[0].text == p.tableAlias || (!path[0].quoted && strings.EqualFold(path[0].text, p.tableAlias))) {
+		path = path[1:]
+	}
+	if p.aggregates != nil {
+		p.hasColumns = true
+	}
+	return &sqlColumn{path: path}, nil
+}
+
+// parseFunction - parses arguments of function name, after its
+// opening parenthesis.
+func (p *sqlParser) parseFunction(name string) (sqlExpr, error) {
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.aggregates == nil {
+			return nil, errSQLNestedAggregate
+		}
+		aggregates := p.aggregates
+		p.aggregates = nil
+		aggregate := &sqlAggregate{name: name}
+		if name != "COUNT" || !p.acceptOp("*") {
+			arg, e := p.parseExpr()
+			if e != nil {
+				return nil, e
+			}
+			aggregate.arg = arg
+		}
+		p.aggregates = aggregates
+		if e := p.expectOp(")"); e != nil {
+			return nil, e
+		}
+		*p.aggregates = append(*p.aggregates, aggregate)
+		return aggregate, nil
+	case "CAST":
+		expr, e := p.parseExpr()
+		if e != nil {
+			return nil, e
+		}
+		if e = p.expectKeyword("AS"); e != nil {
+			return nil, e
+		}
+		typ := strings.ToUpper(p.next().text)
+		switch typ {
+		case "INT", "INTEGER", "FLOAT", "DECIMAL", "STRING", "BOOL":
+		default:
+			return nil, errSQLInvalidCastType
+		}
+		if e = p.expectOp(")"); e != nil {
+			return nil, e
+		}
+		return &sqlCast{expr: expr, typ: typ}, nil
+	case "LOWER", "UPPER", "TRIM", "CHAR_LENGTH", "CHARACTER_LENGTH", "COALESCE":
+		function := &sqlFunction{name: name}
+		if !p.acceptOp(")") {
+			for {
+				arg, e := p.parseExpr()
+				if e != nil {
+					return nil, e
+				}
+				function.args = append(function.args, arg)
+				if !p.acceptOp(",") {
+					break
+				}
+			}
+			if e := p.expectOp(")"); e != nil {
+				return nil, e
+			}
+		}
+		if name != "COALESCE" && len(function.args) != 1 {
+			return nil, fmt.Errorf("%s takes exactly one argument", name)
+		}
+		return function, nil
+	}
+	return nil, fmt.Errorf("Unsupported function %s", name)
+}
+
+/// Values.
+
+// sqlToNumber - returns v as a number, strings are parsed.
+func sqlToNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, e := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, e == nil
+	}
+	return 0, false
+}
+
+// sqlToString - returns v as a string, JSON arrays and objects are
+// encoded.
+func sqlToString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// sqlToBool - returns v as a truth value, nil if unknown.
+func sqlToBool(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		if b, e := strconv.ParseBool(v); e == nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// sqlCompare - compares a and b, numerically if either is a number
+// and the other one can be read as a number.
+func sqlCompare(a, b interface{}) (int, error) {
+	_, aNumber := a.(float64)
+	_, bNumber := b.(float64)
+	if aNumber || bNumber {
+		x, ok1 := sqlToNumber(a)
+		y, ok2 := sqlToNumber(b)
+		if !ok1 || !ok2 {
+			return 0, errSQLInvalidComparison
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	aBool, aIsBool := a.(bool)
+	bBool, bIsBool := b.(bool)
+	if aIsBool || bIsBool {
+		if !aIsBool {
+			aBool, aIsBool = sqlToBool(a).(bool)
+		}
+		if !bIsBool {
+			bBool, bIsBool = sqlToBool(b).(bool)
+		}
+		if !aIsBool || !bIsBool {
+			return 0, errSQLInvalidComparison
+		}
+		switch {
+		case aBool == bBool:
+			return 0, nil
+		case bBool:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return strings.Compare(sqlToString(a), sqlToString(b)), nil
+}
+
+/// Expressions.
+
+// sqlLiteral - constant value.
+type sqlLiteral struct {
+	value interface{}
+}
+
+func (l *sqlLiteral) eval(record sqlRecord) (interface{}, error) {
+	return l.value, nil
+}
+
+// sqlColumn - reference to a column of records, or a nested value of
+// JSON records.
+type sqlColumn struct {
+	path []sqlToken
+}
+
+func (c *sqlColumn) eval(record sqlRecord) (interface{}, error) {
+	if record == nil {
+		return nil, nil
+	}
+	return record.value(c.path), nil
+}
+
+// sqlLogical - AND or OR, with unknown values as SQL defines.
+type sqlLogical struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (l *sqlLogical) eval(record sqlRecord) (interface{}, error) {
+	left, e := l.left.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	a := sqlToBool(left)
+	// Short circuit evaluation.
+	if a == (l.op == "OR") {
+		return a, nil
+	}
+	right, e := l.right.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	b := sqlToBool(right)
+	if b == (l.op == "OR") {
+		return b, nil
+	}
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	return l.op == "AND", nil
+}
+
+// sqlNot - negation, unknown stays unknown.
+type sqlNot struct {
+	expr sqlExpr
+}
+
+func (n *sqlNot) eval(record sqlRecord) (interface{}, error) {
+	v, e := n.expr.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if b, ok := sqlToBool(v).(bool); ok {
+		return !b, nil
+	}
+	return nil, nil
+}
+
+// sqlComparison - comparison of two values, unknown if either is
+// null.
+type sqlComparison struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (c *sqlComparison) eval(record sqlRecord) (interface{}, error) {
+	left, e := c.left.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	right, e := c.right.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	cmp, e := sqlCompare(left, right)
+	if e != nil {
+		return nil, e
+	}
+	switch c.op {
+	case "=":
+		return cmp == 0, nil
+	case "!=", "<>":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// sqlIsNull - IS [NOT] NULL, missing columns are null.
+type sqlIsNull struct {
+	expr sqlExpr
+	not  bool
+}
+
+func (n *sqlIsNull) eval(record sqlRecord) (interface{}, error) {
+	v, e := n.expr.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	return (v == nil) != n.not, nil
+}
+
+// sqlLike - [NOT] LIKE, % matches any characters and _ any single
+// character.
+type sqlLike struct {
+	expr, pattern sqlExpr
+	not           bool
+	// Last pattern compiled, patterns are usually literals.
+	lastPattern string
+	re          *regexp.Regexp
+}
+
+func (l *sqlLike) eval(record sqlRecord) (interface{}, error) {
+	v, e := l.expr.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	pattern, e := l.pattern.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if v == nil || pattern == nil {
+		return nil, nil
+	}
+	if l.re == nil || l.lastPattern != sqlToString(pattern) {
+		l.lastPattern = sqlToString(pattern)
+		var expr bytes.Buffer
+		expr.WriteString("(?s)^")
+		for _, r := range l.lastPattern {
+			switch r {
+			case '%':
+				expr.WriteString(".*")
+			case '_':
+				expr.WriteString(".")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		expr.WriteString("$")
+		if l.re, e = regexp.Compile(expr.String()); e != nil {
+			return nil, e
+		}
+	}
+	return l.re.MatchString(sqlToString(v)) != l.not, nil
+}
+
+// sqlBetween - [NOT] BETWEEN, bounds included.
+type sqlBetween struct {
+	expr, low, high sqlExpr
+	not             bool
+}
+
+func (b *sqlBetween) eval(record sqlRecord) (interface{}, error) {
+	v, e := b.expr.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	low, e := b.low.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	high, e := b.high.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if v == nil || low == nil || high == nil {
+		return nil, nil
+	}
+	cmpLow, e := sqlCompare(v, low)
+	if e != nil {
+		return nil, e
+	}
+	cmpHigh, e := sqlCompare(v, high)
+	if e != nil {
+		return nil, e
+	}
+	return (cmpLow >= 0 && cmpHigh <= 0) != b.not, nil
+}
+
+// sqlIn - [NOT] IN a list of values.
+type sqlIn struct {
+	expr sqlExpr
+	list []sqlExpr
+	not  bool
+}
+
+func (in *sqlIn) eval(record sqlRecord) (interface{}, error) {
+	v, e := in.expr.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if v == nil {
+		return nil, nil
+	}
+	for _, expr := range in.list {
+		candidate, e := expr.eval(record)
+		if e != nil {
+			return nil, e
+		}
+		if candidate == nil {
+			continue
+		}
+		if cmp, e := sqlCompare(v, candidate); e == nil && cmp == 0 {
+			return !in.not, nil
+		}
+	}
+	return in.not, nil
+}
+
+// sqlArithmetic - arithmetic of numbers, or || concatenation of
+// strings.
+type sqlArithmetic struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (a *sqlArithmetic) eval(record sqlRecord) (interface{}, error) {
+	left, e := a.left.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	right, e := a.right.eval(record)
+	if e != nil {
+		return nil, e
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if a.op == "||" {
+		return sqlToString(left) + sqlToString(right), nil
+	}
+	x, ok1 := sqlToNumber(left)
+	y, ok2 := sqlToNumber(right)
+	if !ok1 || !ok2 {
+		return nil, errSQLNotNumber
+	}
+	switch a.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	}
+	if y == 0 {
+		return nil, errSQLDivisionByZero
+	}
+	if a.op == "/" {
+		return x / y, nil
+	}
+	return math.Mod(x, y), nil
+}
+
+// sqlCast - CAST(expr AS type).
+type sqlCast struct {
+	expr sqlExpr
+	typ  string
+}
+
+func (c *sqlCast) eval(record sqlRecord) (interface{}, error) {
+	v, e := c.expr.eval(record)
+	if e != nil || v == nil {
+		return nil, e
+	}
+	switch c.typ {
+	case "STRING":
+		return sqlToString(v), nil
+	case "BOOL":
+		if b, ok := sqlToBool(v).(bool); ok {
+			return b, nil
+		}
+		if f, ok := sqlToNumber(v); ok {
+			return f != 0, nil
+		}
+		return nil, fmt.Errorf("Cannot cast %q to BOOL", sqlToString(v))
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	}
+	f, ok := sqlToNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("Cannot cast %q to %s", sqlToString(v), c.typ)
+	}
+	if c.typ == "INT" || c.typ == "INTEGER" {
+		return math.Trunc(f), nil
+	}
+	return f, nil
+}
+
+// sqlFunction - scalar function.
+type sqlFunction struct {
+	name string
+	args []sqlExpr
+}
+
+func (f *sqlFunction) eval(record sqlRecord) (interface{}, error) {
+	var args []interface{}
+	for _, arg := range f.args {
+		v, e := arg.eval(record)
+		if e != nil {
+			return nil, e
+		}
+		args = append(args, v)
+	}
+	if f.name == "COALESCE" {
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	s := sqlToString(args[0])
+	switch f.name {
+	case "LOWER":
+		return strings.ToLower(s), nil
+	case "UPPER":
+		return strings.ToUpper(s), nil
+	case "TRIM":
+		return strings.TrimSpace(s), nil
+	}
+	return float64(len([]rune(s))), nil
+}
+
+// sqlAggregate - aggregate of the values of all records matching the
+// WHERE clause, records are added before it is evaluated.
+type sqlAggregate struct {
+	name string
+	// Records are counted if nil.
+	arg   sqlExpr
+	count int64
+	sum   float64
+	value interface{}
+}
+
+// add - adds value of the argument of record to the aggregate, nulls
+// are ignored.
+func (a *sqlAggregate) add(record sqlRecord) error {
+	if a.arg == nil {
+		a.count++
+		return nil
+	}
+	v, e := a.arg.eval(record)
+	if e != nil || v == nil {
+		return e
+	}
+	a.count++
+	switch a.name {
+	case "SUM", "AVG":
+		f, ok := sqlToNumber(v)
+		if !ok {
+			return errSQLNotNumber
+		}
+		a.sum += f
+	case "MIN", "MAX":
+		if a.value == nil {
+			a.value = v
+			return nil
+		}
+		cmp, e := sqlCompare(v, a.value)
+		if e != nil {
+			return e
+		}
+		if (a.name == "MIN" && cmp < 0) || (a.name == "MAX" && cmp > 0) {
+			a.value = v
+		}
+	}
+	return nil
+}
+
+func (a *sqlAggregate) eval(record sqlRecord) (interface{}, error) {
+	switch a.name {
+	case "COUNT":
+		return float64(a.count), nil
+	case "SUM":
+		if a.count == 0 {
+			return nil, nil
+		}
+		return a.sum, nil
+	case "AVG":
+		if a.count == 0 {
+			return nil, nil
+		}
+		return a.sum / float64(a.count), nil
+	}
+	return a.value, nil
+}
@@ -0,0 +1,644 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Maximum size of select requests.
+	maxSelectRequestSize = 256 * 1024
+
+	// Records are sent once they grow beyond this size.
+	maxSelectRecordsPayload = 128 * 1024
+)
+
+var (
+	errSelectCSVHeader  = errors.New("CSV header is missing")
+	errSelectJSONRecord = errors.New("JSON records should be objects")
+	errSelectJSONEnd    = errors.New("JSON object ends within a record")
+)
+
+// selectCSVInput - format of CSV objects.
+type selectCSVInput struct {
+	FileHeaderInfo       string `xml:"FileHeaderInfo"`
+	Comments             string `xml:"Comments"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter"`
+	RecordDelimiter      string `xml:"RecordDelimiter"`
+	FieldDelimiter       string `xml:"FieldDelimiter"`
+	QuoteCharacter       string `xml:"QuoteCharacter"`
+}
+
+// selectJSONInput - format of JSON objects, a DOCUMENT or LINES of
+// objects.
+type selectJSONInput struct {
+	Type string `xml:"Type"`
+}
+
+// selectInputSerialization - format of objects selected from.
+type selectInputSerialization struct {
+	CompressionType string           `xml:"CompressionType"`
+	CSV             *selectCSVInput  `xml:"CSV"`
+	JSON            *selectJSONInput `xml:"JSON"`
+}
+
+// selectCSVOutput - format of CSV records returned.
+type selectCSVOutput struct {
+	QuoteFields          string `xml:"QuoteFields"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter"`
+	RecordDelimiter      string `xml:"RecordDelimiter"`
+	FieldDelimiter       string `xml:"FieldDelimiter"`
+	QuoteCharacter       string `xml:"QuoteCharacter"`
+}
+
+// selectJSONOutput - format of JSON records returned.
+type selectJSONOutput struct {
+	RecordDelimiter string `xml:"RecordDelimiter"`
+}
+
+// selectOutputSerialization - format of records returned.
+type selectOutputSerialization struct {
+	CSV  *selectCSVOutput  `xml:"CSV"`
+	JSON *selectJSONOutput `xml:"JSON"`
+}
+
+// selectObjectContentRequest - body of select requests.
+type selectObjectContentRequest struct {
+	XMLName             xml.Name                  `xml:"SelectObjectContentRequest"`
+	Expression          string                    `xml:"Expression"`
+	ExpressionType      string                    `xml:"ExpressionType"`
+	InputSerialization  selectInputSerialization  `xml:"InputSerialization"`
+	OutputSerialization selectOutputSerialization `xml:"OutputSerialization"`
+	RequestProgress     struct {
+		Enabled bool `xml:"Enabled"`
+	} `xml:"RequestProgress"`
+}
+
+// selectStats - bytes read from the object, after decompression, and
+// returned, sent as Stats and Progress events.
+type selectStats struct {
+	XMLName        xml.Name
+	BytesScanned   int64 `xml:"BytesScanned"`
+	BytesProcessed int64 `xml:"BytesProcessed"`
+	BytesReturned  int64 `xml:"BytesReturned"`
+}
+
+// selectQuery - validated select request.
+type selectQuery struct {
+	stmt     *sqlSelect
+	input    selectInputSerialization
+	output   selectOutputSerialization
+	progress bool
+}
+
+// isSingleChar - returns true if s is a single character.
+func isSingleChar(s string) bool {
+	return utf8.RuneCountInString(s) == 1
+}
+
+// newSelectQuery - validates request, and parses its expression.
+func newSelectQuery(req selectObjectContentRequest) (*selectQuery, APIErrorCode) {
+	if !strings.EqualFold(req.ExpressionType, "SQL") {
+		return nil, ErrInvalidExpressionType
+	}
+	switch strings.ToUpper(req.InputSerialization.CompressionType) {
+	case "", "NONE", "GZIP", "BZIP2":
+	default:
+		return nil, ErrInvalidCompressionFormat
+	}
+	input, output := req.InputSerialization, req.OutputSerialization
+	if (input.CSV == nil) == (input.JSON == nil) || (output.CSV == nil) == (output.JSON == nil) {
+		return nil, ErrInvalidDataSource
+	}
+	if csvInput := input.CSV; csvInput != nil {
+		switch strings.ToUpper(csvInput.FileHeaderInfo) {
+		case "", "NONE", "USE", "IGNORE":
+		default:
+			return nil, ErrInvalidFileHeaderInfo
+		}
+		// Records are read by encoding/csv, which only knows of
+		// newlines and double quotes.
+		switch {
+		case csvInput.RecordDelimiter != "" && csvInput.RecordDelimiter != "\n" && csvInput.RecordDelimiter != "\r\n":
+			return nil, ErrInvalidRequestParameter
+		case csvInput.FieldDelimiter != "" && (!isSingleChar(csvInput.FieldDelimiter) || csvInput.FieldDelimiter == "\"" || csvInput.FieldDelimiter == "\n"):
+			return nil, ErrInvalidRequestParameter
+		case csvInput.QuoteCharacter != "" && csvInput.QuoteCharacter != "\"":
+			return nil, ErrInvalidRequestParameter
+		case csvInput.QuoteEscapeCharacter != "" && csvInput.QuoteEscapeCharacter != "\"":
+			return nil, ErrInvalidRequestParameter
+		case csvInput.Comments != "" && !isSingleChar(csvInput.Comments):
+			return nil, ErrInvalidRequestParameter
+		}
+	}
+	if jsonInput := input.JSON; jsonInput != nil {
+		switch strings.ToUpper(jsonInput.Type) {
+		case "DOCUMENT", "LINES":
+		default:
+			return nil, ErrInvalidJSONType
+		}
+	}
+	if csvOutput := output.CSV; csvOutput != nil {
+		switch {
+		case csvOutput.FieldDelimiter != "" && !isSingleChar(csvOutput.FieldDelimiter):
+			return nil, ErrInvalidRequestParameter
+		case csvOutput.QuoteCharacter != "" && !isSingleChar(csvOutput.QuoteCharacter):
+			return nil, ErrInvalidRequestParameter
+		case csvOutput.QuoteEscapeCharacter != "" && !isSingleChar(csvOutput.QuoteEscapeCharacter):
+			return nil, ErrInvalidRequestParameter
+		}
+		switch strings.ToUpper(csvOutput.QuoteFields) {
+		case "", "ASNEEDED", "ALWAYS":
+		default:
+			return nil, ErrInvalidQuoteFields
+		}
+	}
+	stmt, e := parseSQLSelect(req.Expression)
+	if e != nil {
+		return nil, ErrInvalidSelectExpression
+	}
+	return &selectQuery{stmt: stmt, input: input, output: output, progress: req.RequestProgress.Enabled}, ErrNone
+}
+
+/// Records read.
+
+// selectCSVRecord - record of a CSV object, columns are named after
+// the header if there is one, and after their position.
+type selectCSVRecord struct {
+	header []string
+	fields []string
+}
+
+func (r selectCSVRecord) value(path []sqlToken) interface{} {
+	if len(path) != 1 {
+		return nil
+	}
+	name := path[0]
+	for i, column := range r.header {
+		if (name.quoted && column == name.text) || (!name.quoted && strings.EqualFold(column, name.text)) {
+			if i < len(r.fields) {
+				return r.fields[i]
+			}
+			return nil
+		}
+	}
+	if strings.HasPrefix(name.text, "_") {
+		if i, e := strconv.Atoi(name.text[1:]); e == nil && i >= 1 && i <= len(r.fields) {
+			return r.fields[i-1]
+		}
+	}
+	return nil
+}
+
+// all - returns names and values of all columns.
+func (r selectCSVRecord) all() ([]string, []interface{}) {
+	names := make([]string, len(r.fields))
+	values := make([]interface{}, len(r.fields))
+	for i, field := range r.fields {
+		names[i] = "_" + strconv.Itoa(i+1)
+		if i < len(r.header) {
+			names[i] = r.header[i]
+		}
+		values[i] = field
+	}
+	return names, values
+}
+
+// selectJSONRecord - record of a JSON object, nested values are
+// referenced by their path.
+type selectJSONRecord map[string]interface{}
+
+func (r selectJSONRecord) value(path []sqlToken) interface{} {
+	var v interface{} = map[string]interface{}(r)
+	for _, name := range path {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if v, ok = object[name.text]; ok || name.quoted {
+			continue
+		}
+		for key, value := range object {
+			if strings.EqualFold(key, name.text) {
+				v, ok = value, true
+				break
+			}
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// all - returns names and values of all fields, sorted by name.
+func (r selectJSONRecord) all() ([]string, []interface{}) {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = r[name]
+	}
+	return names, values
+}
+
+// selectRecord - record read from the object.
+type selectRecord interface {
+	sqlRecord
+	all() ([]string, []interface{})
+}
+
+// selectRecordReader - reads records of the object, io.EOF at its end.
+type selectRecordReader interface {
+	Read() (selectRecord, error)
+}
+
+type selectCSVReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (r *selectCSVReader) Read() (selectRecord, error) {
+	fields, e := r.reader.Read()
+	if e != nil {
+		return nil, e
+	}
+	return selectCSVRecord{header: r.header, fields: fields}, nil
+}
+
+type selectJSONReader struct {
+	decoder *json.Decoder
+}
+
+func (r *selectJSONReader) Read() (selectRecord, error) {
+	var v interface{}
+	if e := r.decoder.Decode(&v); e != nil {
+		if e == io.ErrUnexpectedEOF {
+			return nil, errSelectJSONEnd
+		}
+		return nil, e
+	}
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errSelectJSONRecord
+	}
+	return selectJSONRecord(object), nil
+}
+
+// newRecordReader - returns reader of records of data in the input
+// format of q.
+func (q *selectQuery) newRecordReader(data io.Reader) (selectRecordReader, error) {
+	if q.input.JSON != nil {
+		return &selectJSONReader{decoder: json.NewDecoder(data)}, nil
+	}
+	csvInput := q.input.CSV
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	if csvInput.FieldDelimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(csvInput.FieldDelimiter)
+	}
+	if csvInput.Comments != "" {
+		reader.Comment, _ = utf8.DecodeRuneInString(csvInput.Comments)
+	}
+	r := &selectCSVReader{reader: reader}
+	switch strings.ToUpper(csvInput.FileHeaderInfo) {
+	case "USE", "IGNORE":
+		header, e := reader.Read()
+		if e == io.EOF {
+			return nil, errSelectCSVHeader
+		}
+		if e != nil {
+			return nil, e
+		}
+		if strings.EqualFold(csvInput.FileHeaderInfo, "USE") {
+			r.header = header
+		}
+	}
+	return r, nil
+}
+
+/// Records returned.
+
+// writeCSVRow - appends names and values as a CSV record to buf.
+func (q *selectQuery) writeCSVRow(buf *bytes.Buffer, values []interface{}) {
+	csvOutput := q.output.CSV
+	fieldDelimiter, recordDelimiter := ",", "\n"
+	quote, escape := "\"", "\""
+	if csvOutput.FieldDelimiter != "" {
+		fieldDelimiter = csvOutput.FieldDelimiter
+	}
+	if csvOutput.RecordDelimiter != "" {
+		recordDelimiter = csvOutput.RecordDelimiter
+	}
+	if csvOutput.QuoteCharacter != "" {
+		quote = csvOutput.QuoteCharacter
+	}
+	if csvOutput.QuoteEscapeCharacter != "" {
+		escape = csvOutput.QuoteEscapeCharacter
+	}
+	always := strings.EqualFold(csvOutput.QuoteFields, "ALWAYS")
+	for i, value := range values {
+		if i > 0 {
+			buf.WriteString(fieldDelimiter)
+		}
+		field := sqlToString(value)
+		if always || strings.Contains(field, fieldDelimiter) || strings.Contains(field, quote) ||
+			strings.Contains(field, recordDelimiter) || strings.ContainsAny(field, "\r\n") {
+			field = quote + strings.Replace(field, quote, escape+quote, -1) + quote
+		}
+		buf.WriteString(field)
+	}
+	buf.WriteString(recordDelimiter)
+}
+
+// writeJSONRow - appends names and values as a JSON object to buf,
+// fields are in the order they are selected.
+func (q *selectQuery) writeJSONRow(buf *bytes.Buffer, names []string, values []interface{}) {
+	recordDelimiter := "\n"
+	if q.output.JSON.RecordDelimiter != "" {
+		recordDelimiter = q.output.JSON.RecordDelimiter
+	}
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		nameBytes, _ := json.Marshal(name)
+		valueBytes, e := json.Marshal(values[i])
+		if e != nil {
+			valueBytes = []byte("null")
+		}
+		buf.Write(nameBytes)
+		buf.WriteByte(':')
+		buf.Write(valueBytes)
+	}
+	buf.WriteByte('}')
+	buf.WriteString(recordDelimiter)
+}
+
+// writeRow - appends names and values in the output format of q.
+func (q *selectQuery) writeRow(buf *bytes.Buffer, names []string, values []interface{}) {
+	if q.output.CSV != nil {
+		q.writeCSVRow(buf, values)
+		return
+	}
+	q.writeJSONRow(buf, names, values)
+}
+
+// project - returns names and values of the items selected of record.
+func (q *selectQuery) project(record selectRecord) ([]string, []interface{}, error) {
+	if len(q.stmt.items) == 0 {
+		names, values := record.all()
+		return names, values, nil
+	}
+	names := make([]string, len(q.stmt.items))
+	values := make([]interface{}, len(q.stmt.items))
+	for i, item := range q.stmt.items {
+		v, e := item.expr.eval(record)
+		if e != nil {
+			return nil, nil, e
+		}
+		names[i], values[i] = q.stmt.itemName(i), v
+	}
+	return names, values, nil
+}
+
+/// Event stream.
+
+// eventStreamHeader - header of an event stream message, values are
+// strings.
+type eventStreamHeader struct {
+	name, value string
+}
+
+// writeEventStreamMessage - writes a message of the event stream
+// encoding select responses are framed in: total and headers length,
+// CRC of both, headers, payload, and CRC of the whole message.
+func writeEventStreamMessage(w io.Writer, headers []eventStreamHeader, payload []byte) error {
+	var headerBytes bytes.Buffer
+	for _, header := range headers {
+		headerBytes.WriteByte(byte(len(header.name)))
+		headerBytes.WriteString(header.name)
+		// String value type.
+		headerBytes.WriteByte(7)
+		binary.Write(&headerBytes, binary.BigEndian, uint16(len(header.value)))
+		headerBytes.WriteString(header.value)
+	}
+	var message bytes.Buffer
+	totalLength := 4 + 4 + 4 + headerBytes.Len() + len(payload) + 4
+	binary.Write(&message, binary.BigEndian, uint32(totalLength))
+	binary.Write(&message, binary.BigEndian, uint32(headerBytes.Len()))
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	message.Write(headerBytes.Bytes())
+	message.Write(payload)
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	_, e := w.Write(message.Bytes())
+	return e
+}
+
+// selectEventWriter - writes events of a select response, flushed as
+// they are written.
+type selectEventWriter struct {
+	w     io.Writer
+	stats selectStats
+}
+
+func (sw *selectEventWriter) writeEvent(eventType, contentType string, payload []byte) error {
+	headers := []eventStreamHeader{{":event-type", eventType}}
+	if contentType != "" {
+		headers = append(headers, eventStreamHeader{":content-type", contentType})
+	}
+	headers = append(headers, eventStreamHeader{":message-type", "event"})
+	if e := writeEventStreamMessage(sw.w, headers, payload); e != nil {
+		return e
+	}
+	if flusher, ok := sw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// writeRecords - writes records buffered in buf as a Records event.
+func (sw *selectEventWriter) writeRecords(buf *bytes.Buffer) error {
+	if buf.Len() == 0 {
+		return nil
+	}
+	sw.stats.BytesReturned += int64(buf.Len())
+	e := sw.writeEvent("Records", "application/octet-stream", buf.Bytes())
+	buf.Reset()
+	return e
+}
+
+// writeStats - writes a Stats or Progress event.
+func (sw *selectEventWriter) writeStats(eventType string) error {
+	stats := sw.stats
+	stats.XMLName = xml.Name{Local: eventType}
+	payload, e := xml.Marshal(stats)
+	if e != nil {
+		return e
+	}
+	return sw.writeEvent(eventType, "text/xml", payload)
+}
+
+// writeError - writes an error event, which ends the response.
+func (sw *selectEventWriter) writeError(code, message string) error {
+	return writeEventStreamMessage(sw.w, []eventStreamHeader{
+		{":error-code", code},
+		{":error-message", message},
+		{":message-type", "error"},
+	}, nil)
+}
+
+// selectCountingReader - counts bytes read.
+type selectCountingReader struct {
+	io.Reader
+	count *int64
+}
+
+func (r selectCountingReader) Read(p []byte) (int, error) {
+	n, e := r.Reader.Read(p)
+	*r.count += int64(n)
+	return n, e
+}
+
+// run - streams records of object selected by q to w as events.
+// Failures once events are written are sent as an error event.
+func (q *selectQuery) run(w io.Writer, object io.Reader) error {
+	sw := &selectEventWriter{w: w}
+	e := q.runRecords(sw, object)
+	if e != nil {
+		code := "InternalError"
+		switch e.(type) {
+		case *csv.ParseError:
+			code = "CSVParsingError"
+		case *json.SyntaxError, *json.UnmarshalTypeError:
+			code = "JSONParsingError"
+		}
+		switch e {
+		case errSelectJSONRecord, errSelectJSONEnd:
+			code = "JSONParsingError"
+		case errSelectCSVHeader:
+			code = "CSVParsingError"
+		case gzip.ErrHeader, gzip.ErrChecksum:
+			code = "InvalidCompressionFormat"
+		case io.ErrUnexpectedEOF:
+			code = "IncompleteBody"
+		case errSQLNotNumber, errSQLDivisionByZero, errSQLInvalidComparison:
+			code = "EvaluatorInvalidArguments"
+		}
+		sw.writeError(code, e.Error())
+		return e
+	}
+	if e = sw.writeStats("Stats"); e != nil {
+		return e
+	}
+	return sw.writeEvent("End", "", nil)
+}
+
+// runRecords - writes Records and Progress events of records of
+// object selected by q.
+func (q *selectQuery) runRecords(sw *selectEventWriter, object io.Reader) error {
+	data := io.Reader(selectCountingReader{Reader: object, count: &sw.stats.BytesScanned})
+	switch strings.ToUpper(q.input.CompressionType) {
+	case "GZIP":
+		gzipReader, e := gzip.NewReader(data)
+		if e != nil {
+			return e
+		}
+		defer gzipReader.Close()
+		data = gzipReader
+	case "BZIP2":
+		data = bzip2.NewReader(data)
+	}
+	data = selectCountingReader{Reader: data, count: &sw.stats.BytesProcessed}
+	reader, e := q.newRecordReader(data)
+	if e != nil {
+		return e
+	}
+
+	stmt := q.stmt
+	var buf bytes.Buffer
+	var returned int64
+	for stmt.limit < 0 || returned < stmt.limit || stmt.isAggregate() {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return e
+		}
+		if stmt.where != nil {
+			v, e := stmt.where.eval(record)
+			if e != nil {
+				return e
+			}
+			if b, ok := sqlToBool(v).(bool); !ok || !b {
+				continue
+			}
+		}
+		if stmt.isAggregate() {
+			for _, aggregate := range stmt.aggregates {
+				if e = aggregate.add(record); e != nil {
+					return e
+				}
+			}
+			continue
+		}
+		names, values, e := q.project(record)
+		if e != nil {
+			return e
+		}
+		q.writeRow(&buf, names, values)
+		returned++
+		if buf.Len() >= maxSelectRecordsPayload {
+			if e = sw.writeRecords(&buf); e != nil {
+				return e
+			}
+			if q.progress {
+				if e = sw.writeStats("Progress"); e != nil {
+					return e
+				}
+			}
+		}
+	}
+	if stmt.isAggregate() && stmt.limit != 0 {
+		names, values, e := q.project(nil)
+		if e != nil {
+			return e
+		}
+		q.writeRow(&buf, names, values)
+	}
+	return sw.writeRecords(&buf)
+}
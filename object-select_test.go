@@ -0,0 +1,224 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+// eventStreamMessage - decoded message of an event stream.
+type eventStreamMessage struct {
+	headers map[string]string
+	payload []byte
+}
+
+// decodeEventStream - decodes all messages of data, verifying their
+// lengths and CRCs.
+func decodeEventStream(data []byte) ([]eventStreamMessage, error) {
+	var messages []eventStreamMessage
+	for len(data) > 0 {
+		if len(data) < 16 {
+			return nil, errors.New("short message")
+		}
+		totalLength := binary.BigEndian.Uint32(data[0:4])
+		headersLength := binary.BigEndian.Uint32(data[4:8])
+		if int(totalLength) > len(data) || crc32.ChecksumIEEE(data[0:8]) != binary.BigEndian.Uint32(data[8:12]) {
+			return nil, errors.New("invalid prelude")
+		}
+		if crc32.ChecksumIEEE(data[:totalLength-4]) != binary.BigEndian.Uint32(data[totalLength-4:totalLength]) {
+			return nil, errors.New("invalid message CRC")
+		}
+		message := eventStreamMessage{headers: make(map[string]string)}
+		headers := data[12 : 12+headersLength]
+		for len(headers) > 0 {
+			nameLength := int(headers[0])
+			name := string(headers[1 : 1+nameLength])
+			valueLength := int(binary.BigEndian.Uint16(headers[2+nameLength : 4+nameLength]))
+			message.headers[name] = string(headers[4+nameLength : 4+nameLength+valueLength])
+			headers = headers[4+nameLength+valueLength:]
+		}
+		message.payload = data[12+headersLength : totalLength-4]
+		messages = append(messages, message)
+		data = data[totalLength:]
+	}
+	return messages, nil
+}
+
+// selectRecords - returns records of a select response, and the type
+// of its last event, or the error code it ends with.
+func selectRecords(t *testing.T, data []byte) (string, string) {
+	messages, e := decodeEventStream(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var records bytes.Buffer
+	last := ""
+	for _, message := range messages {
+		if message.headers[":message-type"] == "error" {
+			return records.String(), message.headers[":error-code"]
+		}
+		if message.headers[":event-type"] == "Records" {
+			records.Write(message.payload)
+		}
+		last = message.headers[":event-type"]
+	}
+	return records.String(), last
+}
+
+// Tests parsing of SQL expressions.
+func TestParseSQLSelect(t *testing.T) {
+	testCases := []struct {
+		expression string
+		valid      bool
+	}{
+		{"SELECT * FROM S3Object", true},
+		{"select s.name, s._2 AS age FROM S3Object s WHERE s.age > 30 AND name LIKE 'A%' LIMIT 10", true},
+		{"SELECT COUNT(*), SUM(CAST(age AS INT)), MAX(name) FROM S3Object WHERE city IN ('Paris', 'Rome')", true},
+		{"SELECT \"first name\" FROM S3Object WHERE age BETWEEN 1 AND 2 OR age IS NOT NULL", true},
+		{"SELECT UPPER(name) || '!' FROM S3Object WHERE NOT (age * 2 >= 60)", true},
+		{"SELECT * FROM table", false},
+		{"SELECT name, COUNT(*) FROM S3Object", false},
+		{"SELECT * FROM S3Object WHERE COUNT(*) > 1", false},
+		{"SELECT * FROM S3Object LIMIT -1", false},
+		{"SELECT * FROM S3Object WHERE name = 'unterminated", false},
+		{"SELECT name FROM S3Object WHERE", false},
+		{"SELECT FROM S3Object", false},
+		{"SELECT UNKNOWN(name) FROM S3Object", false},
+		{"SELECT CAST(name AS DATE) FROM S3Object", false},
+		{"DELETE FROM S3Object", false},
+	}
+	for i, testCase := range testCases {
+		if _, e := parseSQLSelect(testCase.expression); (e == nil) != testCase.valid {
+			t.Errorf("Test %d: Expected valid %v, got error %v", i+1, testCase.valid, e)
+		}
+	}
+}
+
+// Tests selecting records of CSV and JSON objects.
+func TestSelectQueryRun(t *testing.T) {
+	csvData := "name,age,city\nAlice,35,Paris\nBob,28,\"Rome, Italy\"\nCarol,41,Paris\n"
+	jsonData := `{"name":"Alice","age":35,"address":{"city":"Paris"}}
+{"name":"Bob","age":28,"address":{"city":"Rome"}}
+{"name":"Carol","age":41,"address":{"city":"Paris"}}`
+	var gzipData bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipData)
+	gzipWriter.Write([]byte(csvData))
+	gzipWriter.Close()
+
+	csvInput := selectInputSerialization{CSV: &selectCSVInput{FileHeaderInfo: "USE"}}
+	jsonInput := selectInputSerialization{JSON: &selectJSONInput{Type: "LINES"}}
+	csvOutput := selectOutputSerialization{CSV: &selectCSVOutput{}}
+	jsonOutput := selectOutputSerialization{JSON: &selectJSONOutput{}}
+	testCases := []struct {
+		expression string
+		input      selectInputSerialization
+		output     selectOutputSerialization
+		data       []byte
+		records    string
+		end        string
+	}{
+		{"SELECT * FROM S3Object", csvInput, csvOutput, []byte(csvData), "Alice,35,Paris\nBob,28,\"Rome, Italy\"\nCarol,41,Paris\n", "End"},
+		{"SELECT s.name FROM S3Object s WHERE s.city = 'Paris' AND s.age > 40", csvInput, csvOutput, []byte(csvData), "Carol\n", "End"},
+		{"SELECT name, age FROM S3Object WHERE city LIKE 'Rome%'", csvInput, jsonOutput, []byte(csvData), "{\"name\":\"Bob\",\"age\":\"28\"}\n", "End"},
+		{"SELECT _1 FROM S3Object LIMIT 2", csvInput, csvOutput, []byte(csvData), "Alice\nBob\n", "End"},
+		{"SELECT COUNT(*), AVG(age), MIN(name) FROM S3Object WHERE city = 'Paris'", csvInput, csvOutput, []byte(csvData), "2,38,Alice\n", "End"},
+		{"SELECT name FROM S3Object WHERE age >= 35", selectInputSerialization{CompressionType: "GZIP", CSV: &selectCSVInput{FileHeaderInfo: "USE"}}, csvOutput, gzipData.Bytes(), "Alice\nCarol\n", "End"},
+		{"SELECT s.name, s.address.city AS city FROM S3Object s WHERE s.age < 30", jsonInput, jsonOutput, []byte(jsonData), "{\"name\":\"Bob\",\"city\":\"Rome\"}\n", "End"},
+		{"SELECT name, age * 2 FROM S3Object WHERE address.city IN ('Rome', 'Oslo')", jsonInput, csvOutput, []byte(jsonData), "Bob,56\n", "End"},
+		{"SELECT SUM(age) AS total FROM S3Object", jsonInput, jsonOutput, []byte(jsonData), "{\"total\":104}\n", "End"},
+		{"SELECT name FROM S3Object WHERE name / 2 > 1", csvInput, csvOutput, []byte(csvData), "", "EvaluatorInvalidArguments"},
+		{"SELECT name FROM S3Object", jsonInput, csvOutput, []byte(`{"name":"Alice"} {"name":`), "", "JSONParsingError"},
+		{"SELECT name FROM S3Object", csvInput, csvOutput, []byte("name\n\"Alice\n"), "", "CSVParsingError"},
+	}
+	for i, testCase := range testCases {
+		query, s3Error := newSelectQuery(selectObjectContentRequest{
+			Expression:          testCase.expression,
+			ExpressionType:      "SQL",
+			InputSerialization:  testCase.input,
+			OutputSerialization: testCase.output,
+		})
+		if s3Error != ErrNone {
+			t.Fatalf("Test %d: Unexpected error %v", i+1, s3Error)
+		}
+		var response bytes.Buffer
+		query.run(&response, bytes.NewReader(testCase.data))
+		records, end := selectRecords(t, response.Bytes())
+		if records != testCase.records || end != testCase.end {
+			t.Errorf("Test %d: Expected %q ending with %s, got %q ending with %s", i+1, testCase.records, testCase.end, records, end)
+		}
+	}
+}
+
+// Tests statistics of select responses.
+func TestSelectQueryStats(t *testing.T) {
+	query, s3Error := newSelectQuery(selectObjectContentRequest{
+		Expression:          "SELECT _1 FROM S3Object WHERE _2 = 'x'",
+		ExpressionType:      "sql",
+		InputSerialization:  selectInputSerialization{CSV: &selectCSVInput{}},
+		OutputSerialization: selectOutputSerialization{CSV: &selectCSVOutput{}},
+	})
+	if s3Error != ErrNone {
+		t.Fatal(s3Error)
+	}
+	var response bytes.Buffer
+	data := "a,x\nb,y\n"
+	if e := query.run(&response, io.LimitReader(bytes.NewReader([]byte(data)), int64(len(data)))); e != nil {
+		t.Fatal(e)
+	}
+	messages, e := decodeEventStream(response.Bytes())
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected Records, Stats and End events, got %d events", len(messages))
+	}
+	expected := "<Stats><BytesScanned>8</BytesScanned><BytesProcessed>8</BytesProcessed><BytesReturned>2</BytesReturned></Stats>"
+	if stats := messages[1]; stats.headers[":event-type"] != "Stats" || string(stats.payload) != expected {
+		t.Errorf("Expected %s, got %s", expected, stats.payload)
+	}
+}
+
+// Tests validation of select requests.
+func TestNewSelectQuery(t *testing.T) {
+	csvInput := selectInputSerialization{CSV: &selectCSVInput{}}
+	csvOutput := selectOutputSerialization{CSV: &selectCSVOutput{}}
+	testCases := []struct {
+		req   selectObjectContentRequest
+		s3Err APIErrorCode
+	}{
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: csvInput, OutputSerialization: csvOutput}, ErrNone},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "XPATH", InputSerialization: csvInput, OutputSerialization: csvOutput}, ErrInvalidExpressionType},
+		{selectObjectContentRequest{Expression: "SELECT *", ExpressionType: "SQL", InputSerialization: csvInput, OutputSerialization: csvOutput}, ErrInvalidSelectExpression},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", OutputSerialization: csvOutput}, ErrInvalidDataSource},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: selectInputSerialization{CompressionType: "ZIP", CSV: &selectCSVInput{}}, OutputSerialization: csvOutput}, ErrInvalidCompressionFormat},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: selectInputSerialization{CSV: &selectCSVInput{FileHeaderInfo: "FIRST"}}, OutputSerialization: csvOutput}, ErrInvalidFileHeaderInfo},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: selectInputSerialization{CSV: &selectCSVInput{FieldDelimiter: "::"}}, OutputSerialization: csvOutput}, ErrInvalidRequestParameter},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: selectInputSerialization{JSON: &selectJSONInput{Type: "ARRAY"}}, OutputSerialization: csvOutput}, ErrInvalidJSONType},
+		{selectObjectContentRequest{Expression: "SELECT * FROM S3Object", ExpressionType: "SQL", InputSerialization: csvInput, OutputSerialization: selectOutputSerialization{CSV: &selectCSVOutput{QuoteFields: "NEVER"}}}, ErrInvalidQuoteFields},
+	}
+	for i, testCase := range testCases {
+		if _, s3Err := newSelectQuery(testCase.req); s3Err != testCase.s3Err {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.s3Err, s3Err)
+		}
+	}
+}
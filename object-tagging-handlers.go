@@ -0,0 +1,166 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// writeObjectTaggingError - writes error response for tagging errors.
+func writeObjectTaggingError(w http.ResponseWriter, r *http.Request, err *probe.Error) {
+	switch err.ToGoError().(type) {
+	case BucketNameInvalid:
+		writeErrorResponse(w, r, ErrInvalidBucketName, r.URL.Path)
+	case BucketNotFound:
+		writeErrorResponse(w, r, ErrNoSuchBucket, r.URL.Path)
+	case ObjectNotFound, ObjectNameInvalid:
+		writeErrorResponse(w, r, ErrNoSuchKey, r.URL.Path)
+	default:
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+	}
+}
+
+// PutObjectTaggingHandler - PUT Object tagging
+// -----------------
+// This implementation of the PUT operation uses the tagging
+// subresource to replace the tags of an existing object.
+func (api objectAPIHandlers) PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// If Content-Length is unknown or zero, deny the request.
+	if !contains(r.TransferEncoding, "chunked") {
+		if r.ContentLength == -1 || r.ContentLength == 0 {
+			writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
+			return
+		}
+		// If Content-Length is greater than maximum allowed size.
+		if r.ContentLength > maxTaggingSize {
+			writeErrorResponse(w, r, ErrEntityTooLarge, r.URL.Path)
+			return
+		}
+	}
+
+	taggingBytes, e := ioutil.ReadAll(io.LimitReader(r.Body, maxTaggingSize))
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(bucket, object), "Reading tagging failed.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	// Parse and validate tags.
+	var tagging objectTagging
+	if e = xml.Unmarshal(taggingBytes, &tagging); e != nil {
+		errorIf(probe.NewError(e), "Unable to parse tagging.", nil)
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	if e = tagging.Validate(); e != nil {
+		errorIf(probe.NewError(e), "Invalid tagging.", nil)
+		writeErrorResponse(w, r, ErrInvalidTag, r.URL.Path)
+		return
+	}
+
+	if err := api.ObjectAPI.PutObjectTags(bucket, object, tagging.Map()); err != nil {
+		errorIf(err.Trace(bucket, object), "PutObjectTags failed.", nil)
+		writeObjectTaggingError(w, r, err)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetObjectTaggingHandler - GET Object tagging
+// -----------------
+// This operation uses the tagging subresource to return the tags of
+// an object, sorted by key.
+func (api objectAPIHandlers) GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	tags, err := api.ObjectAPI.GetObjectTags(bucket, object)
+	if err != nil {
+		errorIf(err.Trace(bucket, object), "GetObjectTags failed.", nil)
+		writeObjectTaggingError(w, r, err)
+		return
+	}
+	encodedSuccessResponse := encodeResponse(newObjectTagging(tags))
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// DeleteObjectTaggingHandler - DELETE Object tagging
+// -----------------
+// This implementation of the DELETE operation uses the tagging
+// subresource to remove all tags of an object.
+func (api objectAPIHandlers) DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if err := api.ObjectAPI.DeleteObjectTags(bucket, object); err != nil {
+		errorIf(err.Trace(bucket, object), "DeleteObjectTags failed.", nil)
+		writeObjectTaggingError(w, r, err)
+		return
+	}
+	writeSuccessNoContent(w)
+}
@@ -42,28 +42,20 @@ func IsValidBucketName(bucket string) bool {
 
 // IsValidObjectName verifies an object name in accordance with Amazon's
 // requirements. It cannot exceed 1024 characters and must be a valid UTF8
-// string.
+// string, any character is allowed in it, including spaces and the
+// characters Amazon advises to avoid.
 //
 // See:
 // http://docs.aws.amazon.com/AmazonS3/latest/dev/UsingMetadata.html
 //
-// You should avoid the following characters in a key name because of
-// significant special handling for consistency across all
-// applications.
+// Each element of the name separated by '/' is stored as a file or a
+// directory on disks, names with elements which cannot be are
+// rejected.
 //
-// Rejects strings with following characters.
-//
-// - Backslash ("\")
-// - Left curly brace ("{")
-// - Caret ("^")
-// - Right curly brace ("}")
-// - Grave accent / back tick ("`")
-// - Right square bracket ("]")
-// - Left square bracket ("[")
-// - Tilde ("~")
-// - 'Greater Than' symbol (">")
-// - 'Less Than' symbol ("<")
-// - Vertical bar / pipe ("|")
+// - Empty elements, leading or repeated slashes ("/a", "a//b")
+// - Elements "." and ".."
+// - Elements longer than 255 bytes
+// - NUL characters
 func IsValidObjectName(object string) bool {
 	if len(object) > 1024 || len(object) == 0 {
 		return false
@@ -71,10 +63,24 @@ func IsValidObjectName(object string) bool {
 	if !utf8.ValidString(object) {
 		return false
 	}
-	// Reject unsupported characters in object name.
-	return !strings.ContainsAny(object, "`^*{}[]|\\\"'")
+	if strings.ContainsRune(object, 0) {
+		return false
+	}
+	// A trailing slash names a directory object, its empty last
+	// element is not stored.
+	elements := strings.Split(strings.TrimSuffix(object, slashSeparator), slashSeparator)
+	for _, element := range elements {
+		if element == "" || element == "." || element == ".." || len(element) > objectElementMax {
+			return false
+		}
+	}
+	return true
 }
 
+// Longest element of an object name, longest file name of most
+// filesystems.
+const objectElementMax = 255
+
 // IsValidObjectPrefix verifies whether the prefix is a valid object name.
 // Its valid to have a empty prefix.
 func IsValidObjectPrefix(object string) bool {
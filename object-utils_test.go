@@ -21,6 +21,7 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -91,10 +92,22 @@ func TestIsValidObjectName(t *testing.T) {
 		{"117Gn8rfHL2ACARPAhaFd0AGzic9pUbIA/5OCn5A", true},
 		{"SHØRT", true},
 		{"There are far too many object names, and far too few bucket names!", true},
+		{"a/b/c/", true},
+		{"curly{braces}[and]`quotes`'\"|^*~", true},
+		{"日本語/キー", true},
+		{"..hidden/.dot", true},
+		{strings.Repeat("a/", 512), true},
 		// cases for which test should fail.
 		// passing invalid object names.
 		{"", false},
 		{string([]byte{0xff, 0xfe, 0xfd}), false},
+		{"/leading", false},
+		{"a//b", false},
+		{"a/./b", false},
+		{"../b", false},
+		{"nul\x00", false},
+		{strings.Repeat("a", 256), false},
+		{strings.Repeat("a/", 512) + "a", false},
 	}
 
 	for i, testCase := range testCases {
@@ -62,7 +62,7 @@ func testMultipartObjectCreation(c *check.C, create func() objectAPI) {
 	obj := create()
 	err := obj.MakeBucket("bucket")
 	c.Assert(err, check.IsNil)
-	uploadID, err := obj.NewMultipartUpload("bucket", "key")
+	uploadID, err := obj.NewMultipartUpload("bucket", "key", "")
 	c.Assert(err, check.IsNil)
 
 	completedParts := completeMultipartUpload{}
@@ -87,7 +87,7 @@ func testMultipartObjectAbort(c *check.C, create func() objectAPI) {
 	obj := create()
 	err := obj.MakeBucket("bucket")
 	c.Assert(err, check.IsNil)
-	uploadID, err := obj.NewMultipartUpload("bucket", "key")
+	uploadID, err := obj.NewMultipartUpload("bucket", "key", "")
 	c.Assert(err, check.IsNil)
 
 	parts := make(map[int]string)
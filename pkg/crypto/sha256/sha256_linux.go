@@ -74,6 +74,21 @@ func (d *digest) Reset() {
 	d.len = 0
 }
 
+// Implementation - returns the instruction set the block transform
+// uses on this CPU.
+func Implementation() string {
+	switch true {
+	case cpuid.CPU.AVX2():
+		return "avx2"
+	case cpuid.CPU.AVX():
+		return "avx"
+	case cpuid.CPU.SSSE3():
+		return "ssse3"
+	default:
+		return "generic"
+	}
+}
+
 func block(dig *digest, p []byte) {
 	switch true {
 	case cpuid.CPU.AVX2():
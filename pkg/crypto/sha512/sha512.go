@@ -1,4 +1,4 @@
-// +build freebsd darwin windows 386 arm !cgo
+// +build !linux !amd64 !cgo
 
 /*
  * Minio Cloud Storage, (C) 2014-2016 Minio, Inc.
@@ -30,6 +30,13 @@ const Size = 64
 // BlockSize - The blocksize of SHA512 in bytes.
 const BlockSize = 128
 
+// Implementation - returns "stdlib", the block transform of the Go
+// standard library is used, which selects assembly for the CPU of
+// most architectures at runtime.
+func Implementation() string {
+	return "stdlib"
+}
+
 // New returns a new hash.Hash computing SHA512.
 func New() hash.Hash {
 	return sha512.New()
@@ -45,6 +45,21 @@ type digest struct {
 	len uint64
 }
 
+// Implementation - returns the instruction set the block transform
+// uses on this CPU.
+func Implementation() string {
+	switch true {
+	case cpuid.CPU.AVX2():
+		return "avx2"
+	case cpuid.CPU.AVX():
+		return "avx"
+	case cpuid.CPU.SSSE3():
+		return "ssse3"
+	default:
+		return "generic"
+	}
+}
+
 func block(dig *digest, p []byte) {
 	switch true {
 	case cpuid.CPU.AVX2():
@@ -64,7 +64,7 @@ func (a *app) listen() *probe.Error {
 // serve start serving all listeners
 func (a *app) serve() {
 	h := &httpdown.HTTP{
-		StopTimeout: 10 * time.Second,
+		StopTimeout: StopTimeout,
 		KillTimeout: 1 * time.Second,
 	}
 	for i, s := range a.servers {
@@ -91,12 +91,12 @@ func (a *app) wait() {
 // trapSignal wait on listed signals for pre-defined behaviors
 func (a *app) trapSignal(wg *sync.WaitGroup) {
 	ch := make(chan os.Signal, 10)
-	signal.Notify(ch, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR2)
 	for {
 		sig := <-ch
 		switch sig {
-		case syscall.SIGTERM:
-			// this ensures a subsequent TERM will trigger standard go behaviour of terminating
+		case syscall.SIGTERM, syscall.SIGINT:
+			// this ensures a subsequent TERM or INT will trigger standard go behaviour of terminating
 			signal.Stop(ch)
 			// roll through all initialized http servers and stop them
 			for _, s := range a.sds {
@@ -108,7 +108,7 @@ func (a *app) trapSignal(wg *sync.WaitGroup) {
 				}(s)
 			}
 			return
-		case syscall.SIGHUP:
+		case syscall.SIGUSR2:
 			// we only return here if there's an error, otherwise the new process
 			// will send us a TERM when it's ready to trigger the actual shutdown.
 			if _, err := a.net.StartProcess(); err != nil {
@@ -119,7 +119,8 @@ func (a *app) trapSignal(wg *sync.WaitGroup) {
 }
 
 // ListenAndServe will serve the given http.Servers and will monitor for signals
-// allowing for graceful termination (SIGTERM) or restart (SIGUSR2/SIGHUP).
+// allowing for graceful termination (SIGTERM) or restart (SIGUSR2). SIGHUP
+// is left to the servers, to reload their configuration.
 func ListenAndServe(servers ...*http.Server) *probe.Error {
 	// get parent process id
 	ppid := os.Getppid()
@@ -64,7 +64,7 @@ func (a *app) listen() *probe.Error {
 // serve start serving all listeners
 func (a *app) serve() {
 	h := &httpdown.HTTP{
-		StopTimeout: 10 * time.Second,
+		StopTimeout: StopTimeout,
 		KillTimeout: 1 * time.Second,
 	}
 	for i, s := range a.servers {
@@ -91,12 +91,12 @@ func (a *app) wait() {
 // trapSignal wait on listed signals for pre-defined behaviors
 func (a *app) trapSignal(wg *sync.WaitGroup) {
 	ch := make(chan os.Signal, 10)
-	signal.Notify(ch, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 	for {
 		sig := <-ch
 		switch sig {
-		case syscall.SIGTERM:
-			// this ensures a subsequent TERM will trigger standard go behaviour of terminating
+		case syscall.SIGTERM, syscall.SIGINT:
+			// this ensures a subsequent TERM or INT will trigger standard go behaviour of terminating
 			signal.Stop(ch)
 			// roll through all initialized http servers and stop them
 			for _, s := range a.sds {
@@ -17,11 +17,17 @@
 package minhttp
 
 import (
+	"io"
 	"net"
 	"os"
 	"sync"
+	"time"
 )
 
+// StopTimeout - duration in-flight requests are given to complete on
+// graceful shutdown, their connections are closed afterwards.
+var StopTimeout = 10 * time.Second
+
 // rateLimitedListener returns a Listener that accepts at most n simultaneous
 // connections from the provided Listener.
 func rateLimitedListener(l net.Listener, nconn int) net.Listener {
@@ -62,6 +68,15 @@ type rateLimitListenerConn struct {
 	release     func()
 }
 
+// ReadFrom - writes data read from r to the connection, files are sent
+// with sendfile by TCP connections.
+func (l *rateLimitListenerConn) ReadFrom(r io.Reader) (int64, error) {
+	if readerFrom, ok := l.Conn.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(r)
+	}
+	return io.Copy(l.Conn, r)
+}
+
 func (l *rateLimitListenerConn) Close() error {
 	err := l.Conn.Close()
 	l.releaseOnce.Do(l.release)
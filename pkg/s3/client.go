@@ -0,0 +1,223 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package s3 implements a minimal client for S3 compatible servers,
+// sufficient to copy single objects to and from a remote bucket.
+// Requests use path style addressing and signature version '4'.
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRegion = "us-east-1"
+
+var errNoEndpoint = errors.New("s3: no endpoint configured")
+
+// Config - client configuration.
+type Config struct {
+	// Endpoint in "host:port" form.
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	// Region used for signing requests, defaults to "us-east-1".
+	Region string
+	// Use HTTPS instead of HTTP.
+	Secure bool
+	// Transport used for all requests, defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Client - issues requests to a single S3 compatible endpoint. Client
+// is safe for concurrent use.
+type Client struct {
+	config Config
+	scheme string
+	client *http.Client
+}
+
+// ObjectInfo - remote object metadata.
+type ObjectInfo struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// ErrorResponse - error returned by the remote server.
+type ErrorResponse struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	StatusCode int      `xml:"-"`
+}
+
+func (e ErrorResponse) Error() string {
+	return fmt.Sprintf("s3: %s: %s (%d)", e.Code, e.Message, e.StatusCode)
+}
+
+// IsNotFound - returns true if err reports a missing object or bucket.
+func IsNotFound(err error) bool {
+	e, ok := err.(ErrorResponse)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// New - returns a new client for the configured endpoint.
+func New(config Config) (*Client, error) {
+	if config.Endpoint == "" {
+		return nil, errNoEndpoint
+	}
+	if config.Region == "" {
+		config.Region = defaultRegion
+	}
+	scheme := "http"
+	if config.Secure {
+		scheme = "https"
+	}
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Client{
+		config: config,
+		scheme: scheme,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// newRequest - returns a signed request for object in bucket.
+func (c *Client) newRequest(method, bucket, object string, body io.Reader, size int64, sha256Hex string) (*http.Request, error) {
+	u := &url.URL{
+		Scheme: c.scheme,
+		Host:   c.config.Endpoint,
+		Path:   "/" + bucket + "/" + object,
+	}
+	// Opaque keeps the request path exactly as signed.
+	u.Opaque = "//" + u.Host + encodePath(u.Path)
+	req, e := http.NewRequest(method, u.String(), body)
+	if e != nil {
+		return nil, e
+	}
+	req.URL = u
+	if body != nil {
+		req.ContentLength = size
+	}
+	if sha256Hex == "" {
+		sha256Hex = emptySHA256
+	}
+	signV4(req, c.config.AccessKey, c.config.SecretKey, c.config.Region, sha256Hex, time.Now().UTC())
+	return req, nil
+}
+
+// do - sends the request, non 2xx responses are returned as
+// ErrorResponse.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, e := c.client.Do(req)
+	if e != nil {
+		return nil, e
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	errResp := ErrorResponse{StatusCode: resp.StatusCode}
+	// HEAD responses carry no body, status is all there is.
+	if body, e := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024)); e == nil && len(body) > 0 {
+		xml.Unmarshal(body, &errResp)
+	}
+	if errResp.Code == "" {
+		errResp.Code = strings.Replace(http.StatusText(resp.StatusCode), " ", "", -1)
+	}
+	return nil, errResp
+}
+
+// PutObject - uploads size bytes from data, sha256Hex is the hex
+// encoded sha256 of the data and is verified by the server.
+func (c *Client) PutObject(bucket, object string, data io.Reader, size int64, sha256Hex string) (etag string, e error) {
+	req, e := c.newRequest("PUT", bucket, object, ioutil.NopCloser(data), size, sha256Hex)
+	if e != nil {
+		return "", e
+	}
+	resp, e := c.do(req)
+	if e != nil {
+		return "", e
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	return strings.Trim(resp.Header.Get("ETag"), "\""), nil
+}
+
+// GetObject - returns object data starting at offset.
+func (c *Client) GetObject(bucket, object string, offset int64) (io.ReadCloser, error) {
+	req, e := c.newRequest("GET", bucket, object, nil, 0, "")
+	if e != nil {
+		return nil, e
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, e := c.do(req)
+	if e != nil {
+		return nil, e
+	}
+	return resp.Body, nil
+}
+
+// StatObject - returns object metadata.
+func (c *Client) StatObject(bucket, object string) (ObjectInfo, error) {
+	req, e := c.newRequest("HEAD", bucket, object, nil, 0, "")
+	if e != nil {
+		return ObjectInfo{}, e
+	}
+	resp, e := c.do(req)
+	if e != nil {
+		return ObjectInfo{}, e
+	}
+	resp.Body.Close()
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return ObjectInfo{
+		Size:    resp.ContentLength,
+		ModTime: modTime.UTC(),
+		ETag:    strings.Trim(resp.Header.Get("ETag"), "\""),
+	}, nil
+}
+
+// DeleteObject - removes object, deleting a missing object is not an
+// error.
+func (c *Client) DeleteObject(bucket, object string) error {
+	req, e := c.newRequest("DELETE", bucket, object, nil, 0, "")
+	if e != nil {
+		return e
+	}
+	resp, e := c.do(req)
+	if e != nil {
+		if IsNotFound(e) {
+			return nil
+		}
+		return e
+	}
+	resp.Body.Close()
+	return nil
+}
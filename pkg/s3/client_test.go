@@ -0,0 +1,160 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer - in memory object store, verifies payload checksums.
+type fakeServer struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), signV4Algorithm+" Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		data, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<Error><Code>XAmzContentSHA256Mismatch</Code><Message>mismatch</Message></Error>"))
+			return
+		}
+		f.objects[r.URL.Path] = data
+		w.Header().Set("ETag", "\"etag\"")
+	case "GET", "HEAD":
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == "GET" {
+				w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>"))
+			}
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			data = data[offset:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	case "DELETE":
+		if _, ok := f.objects[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeServer{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, e := New(Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	data := []byte("hello, world")
+	sum := sha256.Sum256(data)
+	if _, e = client.PutObject("bucket", "dir/object one", bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:])); e != nil {
+		t.Fatal(e)
+	}
+	if _, ok := fake.objects["/bucket/dir/object one"]; !ok {
+		t.Fatalf("Object not saved under expected path, have %v", fake.objects)
+	}
+
+	// Checksum mismatch is returned as ErrorResponse.
+	_, e = client.PutObject("bucket", "object", bytes.NewReader(data), int64(len(data)), emptySHA256)
+	if errResp, ok := e.(ErrorResponse); !ok || errResp.Code != "XAmzContentSHA256Mismatch" {
+		t.Fatalf("Expected checksum mismatch, got %v", e)
+	}
+
+	info, e := client.StatObject("bucket", "dir/object one")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if info.Size != int64(len(data)) {
+		t.Fatalf("Expected size %d, got %d", len(data), info.Size)
+	}
+
+	reader, e := client.GetObject("bucket", "dir/object one", 7)
+	if e != nil {
+		t.Fatal(e)
+	}
+	readData, e := ioutil.ReadAll(reader)
+	reader.Close()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(readData) != "world" {
+		t.Fatalf("Expected \"world\", got %q", readData)
+	}
+
+	if e = client.DeleteObject("bucket", "dir/object one"); e != nil {
+		t.Fatal(e)
+	}
+	// Deleting a missing object succeeds.
+	if e = client.DeleteObject("bucket", "dir/object one"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = client.StatObject("bucket", "dir/object one"); !IsNotFound(e) {
+		t.Fatalf("Expected not found, got %v", e)
+	}
+	if _, e = client.GetObject("bucket", "dir/object one", 0); !IsNotFound(e) {
+		t.Fatalf("Expected not found, got %v", e)
+	}
+}
+
+func TestEncodePath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/bucket/object", "/bucket/object"},
+		{"/bucket/a b+c", "/bucket/a%20b%2Bc"},
+		{"/bucket/世界", "/bucket/%E4%B8%96%E7%95%8C"},
+	}
+	for i, testCase := range testCases {
+		if encoded := encodePath(testCase.path); encoded != testCase.expected {
+			t.Errorf("Test %d: expected %s, got %s", i+1, testCase.expected, encoded)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version '4' constants.
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	iso8601Format   = "20060102T150405Z"
+	yyyymmdd        = "20060102"
+)
+
+// Hex encoded sha256 of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func sumHMAC(key []byte, data []byte) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+// encodePath - encodes object path as required by the canonical
+// request, every byte except unreserved characters and '/' is
+// percent encoded.
+func encodePath(path string) string {
+	var buf strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+			buf.WriteByte(c)
+		case c == '-', c == '_', c == '.', c == '~', c == '/':
+			buf.WriteByte(c)
+		default:
+			buf.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return buf.String()
+}
+
+// signV4 - signs the request with AWS signature version '4', payload
+// is the hex encoded sha256 of the request body.
+func signV4(req *http.Request, accessKey, secretKey, region, payload string, t time.Time) {
+	req.Header.Set("X-Amz-Date", t.Format(iso8601Format))
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	// Sign host and all amz headers.
+	var headers []string
+	vals := make(map[string]string)
+	for k, vv := range req.Header {
+		k = strings.ToLower(k)
+		if !strings.HasPrefix(k, "x-amz-") && k != "content-md5" {
+			continue
+		}
+		headers = append(headers, k)
+		vals[k] = strings.Join(vv, ",")
+	}
+	headers = append(headers, "host")
+	vals["host"] = req.URL.Host
+	sort.Strings(headers)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range headers {
+		canonicalHeaders.WriteString(k + ":" + vals[k] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodePath(req.URL.Path),
+		strings.Replace(req.URL.Query().Encode(), "+", "%20", -1),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	scope := strings.Join([]string{t.Format(yyyymmdd), region, "s3", "aws4_request"}, "/")
+	canonicalRequestSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := signV4Algorithm + "\n" + t.Format(iso8601Format) + "\n" + scope + "\n" +
+		hex.EncodeToString(canonicalRequestSum[:])
+
+	signingKey := sumHMAC([]byte("AWS4"+secretKey), []byte(t.Format(yyyymmdd)))
+	signingKey = sumHMAC(signingKey, []byte(region))
+	signingKey = sumHMAC(signingKey, []byte("s3"))
+	signingKey = sumHMAC(signingKey, []byte("aws4_request"))
+	signature := hex.EncodeToString(sumHMAC(signingKey, []byte(stringToSign)))
+
+	req.Header.Set("Authorization", signV4Algorithm+" Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
@@ -0,0 +1,385 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Revoked signers are saved in this file in the config path.
+	presignRevocationsFile = "presign-revocations.json"
+
+	// Anomalies are counted within this window.
+	presignAnomalyWindow = time.Minute
+
+	// Maximum number of recent anomalies remembered.
+	maxPresignAnomalies = 100
+)
+
+// Kinds of presigned requests.
+const (
+	presignKindURL  = "url"
+	presignKindPost = "post"
+)
+
+// Types of presigned request anomalies.
+const (
+	anomalyExpiredFlood     = "expiredSignatureFlood"
+	anomalyOversizedUploads = "oversizedUploadsRejected"
+	anomalyRevokedUse       = "revokedSignerUsed"
+)
+
+// Number of occurrences within presignAnomalyWindow at which an
+// anomaly is flagged.
+var presignAnomalyThresholds = map[string]int64{
+	anomalyExpiredFlood:     10,
+	anomalyOversizedUploads: 1,
+	anomalyRevokedUse:       1,
+}
+
+// presignUsage - usage of presigned URLs and POST policies by a
+// single signer on a single bucket.
+type presignUsage struct {
+	Signer        string    `json:"signer"`
+	Bucket        string    `json:"bucket"`
+	URLRequests   int64     `json:"urlRequests"`
+	PostRequests  int64     `json:"postRequests"`
+	Rejected      int64     `json:"rejected"`
+	Expired       int64     `json:"expired"`
+	Oversized     int64     `json:"oversized"`
+	Revoked       int64     `json:"revoked"`
+	BytesUploaded int64     `json:"bytesUploaded"`
+	MaxValidity   int64     `json:"maxValidity"` // In seconds.
+	LastSeen      time.Time `json:"lastSeen"`
+}
+
+// presignAnomaly - a flagged anomaly.
+type presignAnomaly struct {
+	Type   string    `json:"type"`
+	Signer string    `json:"signer"`
+	Bucket string    `json:"bucket"`
+	Count  int64     `json:"count"`
+	Since  time.Time `json:"since"`
+}
+
+// presignRevocation - presigned requests of signer signed at or
+// before RevokedAt are rejected.
+type presignRevocation struct {
+	Signer    string    `json:"signer"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// presignInfo - response of the admin presign API.
+type presignInfo struct {
+	Usage       []presignUsage      `json:"usage"`
+	Anomalies   []presignAnomaly    `json:"anomalies"`
+	Revocations []presignRevocation `json:"revocations"`
+}
+
+// presignRequest - outcome of a single presigned request.
+type presignRequest struct {
+	Kind     string
+	Signer   string
+	Bucket   string
+	Validity time.Duration
+	Size     int64
+	Error    APIErrorCode
+}
+
+// anomalyWindow - occurrences of an anomaly in the current window.
+type anomalyWindow struct {
+	start time.Time
+	count int64
+}
+
+// presignMonitor - tracks usage of presigned requests and revoked
+// signers.
+type presignMonitor struct {
+	mutex       *sync.Mutex
+	usage       map[string]*presignUsage
+	windows     map[string]*anomalyWindow
+	anomalies   []presignAnomaly
+	revocations map[string]time.Time
+}
+
+// newPresignMonitor - returns a new presign monitor.
+func newPresignMonitor() *presignMonitor {
+	return &presignMonitor{
+		mutex:       &sync.Mutex{},
+		usage:       make(map[string]*presignUsage),
+		windows:     make(map[string]*anomalyWindow),
+		revocations: make(map[string]time.Time),
+	}
+}
+
+// Global presign monitor, revocations are loaded at server start.
+var globalPresignMonitor = newPresignMonitor()
+
+// record - accounts a presigned request completed at now, flagging
+// anomalies whose threshold is reached.
+func (m *presignMonitor) record(req presignRequest, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := req.Signer + "/" + req.Bucket
+	usage, ok := m.usage[key]
+	if !ok {
+		usage = &presignUsage{Signer: req.Signer, Bucket: req.Bucket}
+		m.usage[key] = usage
+	}
+	usage.LastSeen = now
+	if req.Kind == presignKindPost {
+		usage.PostRequests++
+	} else {
+		usage.URLRequests++
+	}
+	if validity := int64(req.Validity / time.Second); validity > usage.MaxValidity {
+		usage.MaxValidity = validity
+	}
+	switch req.Error {
+	case ErrNone:
+		if req.Size > 0 {
+			usage.BytesUploaded += req.Size
+		}
+		return
+	case ErrExpiredPresignRequest, ErrPolicyAlreadyExpired:
+		usage.Expired++
+		m.flag(anomalyExpiredFlood, req, now)
+	case ErrEntityTooLarge:
+		usage.Oversized++
+		m.flag(anomalyOversizedUploads, req, now)
+	case ErrPresignRevoked:
+		usage.Revoked++
+		m.flag(anomalyRevokedUse, req, now)
+	}
+	usage.Rejected++
+}
+
+// flag - counts an occurrence of anomaly, flags it once its threshold
+// is reached within the window.
+func (m *presignMonitor) flag(anomalyType string, req presignRequest, now time.Time) {
+	key := anomalyType + "/" + req.Signer + "/" + req.Bucket
+	window, ok := m.windows[key]
+	if !ok || now.Sub(window.start) > presignAnomalyWindow {
+		window = &anomalyWindow{start: now}
+		m.windows[key] = window
+	}
+	window.count++
+	if window.count != presignAnomalyThresholds[anomalyType] {
+		return
+	}
+	anomaly := presignAnomaly{
+		Type:   anomalyType,
+		Signer: req.Signer,
+		Bucket: req.Bucket,
+		Count:  window.count,
+		Since:  window.start,
+	}
+	m.anomalies = append(m.anomalies, anomaly)
+	if len(m.anomalies) > maxPresignAnomalies {
+		m.anomalies = m.anomalies[len(m.anomalies)-maxPresignAnomalies:]
+	}
+	log.WithFields(logrus.Fields{
+		"anomaly": anomaly.Type,
+		"signer":  anomaly.Signer,
+		"bucket":  anomaly.Bucket,
+		"count":   anomaly.Count,
+	}).Warn("Presigned request anomaly detected.")
+}
+
+// isRevoked - returns true if requests of signer signed at signedAt
+// are revoked.
+func (m *presignMonitor) isRevoked(signer string, signedAt time.Time) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	revokedAt, ok := m.revocations[signer]
+	return ok && !signedAt.After(revokedAt)
+}
+
+// Info - returns usage sorted by signer and bucket, recent anomalies
+// and all revocations.
+func (m *presignMonitor) Info() presignInfo {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	info := presignInfo{
+		Usage:       []presignUsage{},
+		Anomalies:   append([]presignAnomaly{}, m.anomalies...),
+		Revocations: m.listRevocations(),
+	}
+	var keys []string
+	for key := range m.usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		info.Usage = append(info.Usage, *m.usage[key])
+	}
+	return info
+}
+
+// listRevocations - returns revocations sorted by signer, callers
+// hold the mutex.
+func (m *presignMonitor) listRevocations() []presignRevocation {
+	var signers []string
+	for signer := range m.revocations {
+		signers = append(signers, signer)
+	}
+	sort.Strings(signers)
+	revocations := []presignRevocation{}
+	for _, signer := range signers {
+		revocations = append(revocations, presignRevocation{Signer: signer, RevokedAt: m.revocations[signer]})
+	}
+	return revocations
+}
+
+// Revoke - revokes all presigned requests of signer signed at or
+// before revokedAt, revocations are saved across restarts.
+func (m *presignMonitor) Revoke(signer string, revokedAt time.Time) *probe.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.revocations[signer] = revokedAt.UTC()
+	return m.saveRevocations().Trace(signer)
+}
+
+// Unrevoke - removes revocation of signer.
+func (m *presignMonitor) Unrevoke(signer string) *probe.Error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.revocations[signer]; !ok {
+		return probe.NewError(PresignRevocationNotFound{Signer: signer})
+	}
+	delete(m.revocations, signer)
+	return m.saveRevocations().Trace(signer)
+}
+
+// getPresignRevocationsFile - get revocations file path.
+func getPresignRevocationsFile() (string, *probe.Error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(configPath, presignRevocationsFile), nil
+}
+
+// saveRevocations - saves revocations, callers hold the mutex.
+func (m *presignMonitor) saveRevocations() *probe.Error {
+	revocationsFile, err := getPresignRevocationsFile()
+	if err != nil {
+		return err.Trace()
+	}
+	revocationsBytes, e := json.Marshal(m.listRevocations())
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.MkdirAll(filepath.Dir(revocationsFile), 0700); e != nil {
+		return probe.NewError(e)
+	}
+	if e = ioutil.WriteFile(revocationsFile, revocationsBytes, 0600); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// loadRevocations - loads saved revocations, replacing current ones.
+func (m *presignMonitor) loadRevocations() *probe.Error {
+	revocationsFile, err := getPresignRevocationsFile()
+	if err != nil {
+		return err.Trace()
+	}
+	revocationsBytes, e := ioutil.ReadFile(revocationsFile)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil
+		}
+		return probe.NewError(e)
+	}
+	var revocations []presignRevocation
+	if e = json.Unmarshal(revocationsBytes, &revocations); e != nil {
+		return probe.NewError(e)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.revocations = make(map[string]time.Time)
+	for _, revocation := range revocations {
+		m.revocations[revocation.Signer] = revocation.RevokedAt
+	}
+	return nil
+}
+
+// initPresignMonitor - loads revoked signers at server start.
+func initPresignMonitor() {
+	err := globalPresignMonitor.loadRevocations()
+	fatalIf(err.Trace(), "Unable to load presign revocations.", nil)
+}
+
+// getRequestBucket - returns bucket of a path style request.
+func getRequestBucket(urlPath string) string {
+	return strings.SplitN(strings.TrimPrefix(urlPath, "/"), "/", 2)[0]
+}
+
+// recordPresignedRequest - accounts a presigned URL request which was
+// authenticated with s3Error, requests without a parsable signature
+// cannot be attributed to a signer and are not accounted.
+func recordPresignedRequest(r *http.Request, s3Error APIErrorCode) {
+	preSignValues, err := parsePreSignV4(r.URL.Query())
+	if err != ErrNone {
+		return
+	}
+	globalPresignMonitor.record(presignRequest{
+		Kind:     presignKindURL,
+		Signer:   preSignValues.Credential.accessKey,
+		Bucket:   getRequestBucket(r.URL.Path),
+		Validity: time.Duration(preSignValues.Expires),
+		Size:     r.ContentLength,
+		Error:    s3Error,
+	}, time.Now().UTC())
+}
+
+// recordPostPolicyRequest - accounts a POST policy request of size
+// bytes which completed with s3Error.
+func recordPostPolicyRequest(formValues map[string]string, size int64, s3Error APIErrorCode) {
+	credHeader, err := parseCredentialHeader("Credential=" + formValues["X-Amz-Credential"])
+	if err != ErrNone {
+		return
+	}
+	req := presignRequest{
+		Kind:   presignKindPost,
+		Signer: credHeader.accessKey,
+		Bucket: formValues["Bucket"],
+		Size:   size,
+		Error:  s3Error,
+	}
+	signedAt, e := time.Parse(iso8601Format, formValues["X-Amz-Date"])
+	if e == nil {
+		if expiration, err := getPostPolicyExpiration(formValues); err == nil {
+			req.Validity = expiration.Sub(signedAt)
+		}
+	}
+	globalPresignMonitor.record(req, time.Now().UTC())
+}
@@ -0,0 +1,140 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests usage accounting and anomaly detection.
+func TestPresignMonitorRecord(t *testing.T) {
+	m := newPresignMonitor()
+	now := time.Date(2016, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	m.record(presignRequest{Kind: presignKindURL, Signer: "signer", Bucket: "bucket", Validity: time.Hour, Size: 5}, now)
+	m.record(presignRequest{Kind: presignKindPost, Signer: "signer", Bucket: "bucket", Size: 10}, now)
+	m.record(presignRequest{Kind: presignKindPost, Signer: "signer", Bucket: "bucket", Size: 100, Error: ErrEntityTooLarge}, now)
+
+	// Expired signature flood is flagged once its threshold is reached.
+	threshold := presignAnomalyThresholds[anomalyExpiredFlood]
+	for i := int64(0); i < threshold-1; i++ {
+		m.record(presignRequest{Kind: presignKindURL, Signer: "signer", Bucket: "bucket", Error: ErrExpiredPresignRequest}, now)
+	}
+	info := m.Info()
+	if len(info.Anomalies) != 1 || info.Anomalies[0].Type != anomalyOversizedUploads {
+		t.Fatalf("Expected only oversized upload anomaly, got %v", info.Anomalies)
+	}
+	m.record(presignRequest{Kind: presignKindURL, Signer: "signer", Bucket: "bucket", Error: ErrExpiredPresignRequest}, now)
+	m.record(presignRequest{Kind: presignKindURL, Signer: "signer", Bucket: "bucket", Error: ErrExpiredPresignRequest}, now)
+	info = m.Info()
+	if len(info.Anomalies) != 2 || info.Anomalies[1].Type != anomalyExpiredFlood || info.Anomalies[1].Count != threshold {
+		t.Fatalf("Expected expired signature flood anomaly, got %v", info.Anomalies)
+	}
+
+	// Windows restart after presignAnomalyWindow.
+	m.record(presignRequest{Kind: presignKindPost, Signer: "signer", Bucket: "bucket", Error: ErrEntityTooLarge}, now.Add(presignAnomalyWindow/2))
+	m.record(presignRequest{Kind: presignKindPost, Signer: "signer", Bucket: "bucket", Error: ErrEntityTooLarge}, now.Add(2*presignAnomalyWindow))
+	if info = m.Info(); len(info.Anomalies) != 3 {
+		t.Fatalf("Expected 3 anomalies, got %v", info.Anomalies)
+	}
+
+	if len(info.Usage) != 1 {
+		t.Fatalf("Expected usage of a single signer and bucket, got %v", info.Usage)
+	}
+	usage := info.Usage[0]
+	if usage.URLRequests != 1+threshold+1 || usage.PostRequests != 4 {
+		t.Fatalf("Unexpected request counts %+v", usage)
+	}
+	if usage.Expired != threshold+1 || usage.Oversized != 3 || usage.Rejected != threshold+4 {
+		t.Fatalf("Unexpected rejection counts %+v", usage)
+	}
+	if usage.BytesUploaded != 15 || usage.MaxValidity != int64(time.Hour/time.Second) {
+		t.Fatalf("Unexpected upload analytics %+v", usage)
+	}
+}
+
+// Tests revocations are enforced and saved across restarts.
+func TestPresignMonitorRevocations(t *testing.T) {
+	configPath, e := ioutil.TempDir("", "minio-presign-config")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(configPath)
+	savedConfigPath := customConfigPath
+	setGlobalConfigPath(configPath)
+	defer setGlobalConfigPath(savedConfigPath)
+
+	revokedAt := time.Date(2016, time.May, 1, 0, 0, 0, 0, time.UTC)
+	m := newPresignMonitor()
+	if err := m.Unrevoke("signer"); err == nil {
+		t.Fatal("Expected error unrevoking signer which is not revoked")
+	}
+	if err := m.Revoke("signer", revokedAt); err != nil {
+		t.Fatal(err)
+	}
+	if !m.isRevoked("signer", revokedAt.Add(-time.Hour)) || !m.isRevoked("signer", revokedAt) {
+		t.Fatal("Expected requests signed before revocation to be revoked")
+	}
+	if m.isRevoked("signer", revokedAt.Add(time.Second)) || m.isRevoked("other", revokedAt) {
+		t.Fatal("Expected requests signed after revocation or by others to be accepted")
+	}
+
+	restarted := newPresignMonitor()
+	if err := restarted.loadRevocations(); err != nil {
+		t.Fatal(err)
+	}
+	if !restarted.isRevoked("signer", revokedAt) {
+		t.Fatal("Expected revocation to be loaded")
+	}
+	if err := restarted.Unrevoke("signer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadRevocations(); err != nil {
+		t.Fatal(err)
+	}
+	if m.isRevoked("signer", revokedAt) {
+		t.Fatal("Expected revocation to be removed")
+	}
+}
+
+// Tests content length range of POST policies.
+func TestCheckPostPolicyContentLength(t *testing.T) {
+	expiration := time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano)
+	policy := `{"expiration": "` + expiration + `", "conditions": [["content-length-range", 10, 100]]}`
+	formValues := map[string]string{
+		"X-Amz-Algorithm": signV4Algorithm,
+		"Policy":          base64.StdEncoding.EncodeToString([]byte(policy)),
+	}
+	testCases := []struct {
+		size  int64
+		s3Err APIErrorCode
+	}{
+		{5, ErrEntityTooSmall},
+		{10, ErrNone},
+		{100, ErrNone},
+		{101, ErrEntityTooLarge},
+	}
+	for i, testCase := range testCases {
+		if s3Err := checkPostPolicy(formValues, testCase.size); s3Err != testCase.s3Err {
+			t.Errorf("Test %d: Expected %d, got %d", i+1, testCase.s3Err, s3Err)
+		}
+	}
+}
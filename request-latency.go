@@ -0,0 +1,113 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of most recent request latencies kept.
+const maxLatencySamples = 1024
+
+// latencySample - time to first byte of a request served at time.
+type latencySample struct {
+	time    time.Time
+	latency time.Duration
+}
+
+// latencyTracker - keeps latencies of the most recent foreground
+// requests.
+type latencyTracker struct {
+	mutex   *sync.Mutex
+	samples []latencySample
+	// Index the next sample is saved at, once samples is full.
+	next int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{mutex: &sync.Mutex{}}
+}
+
+// Global latency of S3 requests, heal concurrency adapts to it.
+var globalRequestLatency = newLatencyTracker()
+
+// Record - records latency of a request served now.
+func (l *latencyTracker) Record(latency time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	sample := latencySample{time: time.Now().UTC(), latency: latency}
+	if len(l.samples) < maxLatencySamples {
+		l.samples = append(l.samples, sample)
+		return
+	}
+	l.samples[l.next] = sample
+	l.next = (l.next + 1) % maxLatencySamples
+}
+
+// Percentile - returns the p-th percentile, 0 < p <= 1, of latencies of
+// requests served since, false if none were.
+func (l *latencyTracker) Percentile(p float64, since time.Time) (time.Duration, bool) {
+	l.mutex.Lock()
+	var latencies []time.Duration
+	for _, sample := range l.samples {
+		if !sample.time.Before(since) {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	l.mutex.Unlock()
+	if len(latencies) == 0 {
+		return 0, false
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	i := int(float64(len(latencies))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i], true
+}
+
+// requestLatencyHandler - records time to first byte of S3 requests,
+// so that transfers of large objects to slow clients do not count.
+// Internode and admin requests are not recorded.
+type requestLatencyHandler struct {
+	handler http.Handler
+}
+
+func setRequestLatencyHandler(h http.Handler) http.Handler {
+	return requestLatencyHandler{handler: h}
+}
+
+func (h requestLatencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	sw := newStatusResponseWriter(w)
+	h.handler.ServeHTTP(sw, r)
+	if sw.started.IsZero() {
+		sw.started = time.Now()
+	}
+	globalRequestLatency.Record(sw.started.Sub(start))
+}
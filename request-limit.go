@@ -0,0 +1,211 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default duration requests beyond the concurrent request limit are
+// queued for before they are rejected.
+const defaultMaxRequestsWait = 10 * time.Second
+
+// Largest chunk of data throttled at once, so that large reads and
+// writes are paced evenly.
+const maxThrottleChunkSize = 32 * 1024
+
+// bandwidthThrottle - paces reads and writes of a connection to rate
+// bytes per second.
+type bandwidthThrottle struct {
+	mutex *sync.Mutex
+	rate  int64
+	// Time the bytes throttled so far are due.
+	next time.Time
+}
+
+func newBandwidthThrottle(rate int64) *bandwidthThrottle {
+	return &bandwidthThrottle{mutex: &sync.Mutex{}, rate: rate}
+}
+
+// wait - waits until n more bytes may be transferred, returns early
+// with an error once ctx is done.
+func (t *bandwidthThrottle) wait(ctx context.Context, n int) error {
+	t.mutex.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.rate))
+	t.mutex.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitThrottles - waits until n more bytes may be transferred through
+// all throttles.
+func waitThrottles(ctx context.Context, throttles []*bandwidthThrottle, n int) error {
+	for _, throttle := range throttles {
+		if e := throttle.wait(ctx, n); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+type connThrottleKey struct{}
+
+// setConnThrottle - returns function attaching a throttle of rate bytes
+// per second to the context of each connection, shared by all its
+// requests. Zero rate disables throttling.
+func setConnThrottle(rate int64) func(context.Context, net.Conn) context.Context {
+	return func(ctx context.Context, c net.Conn) context.Context {
+		if rate <= 0 {
+			return ctx
+		}
+		return context.WithValue(ctx, connThrottleKey{}, newBandwidthThrottle(rate))
+	}
+}
+
+// throttledReader - request body paced by throttles.
+type throttledReader struct {
+	io.ReadCloser
+	ctx       context.Context
+	throttles []*bandwidthThrottle
+}
+
+func (r throttledReader) Read(p []byte) (n int, err error) {
+	if len(p) > maxThrottleChunkSize {
+		p = p[:maxThrottleChunkSize]
+	}
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		if e := waitThrottles(r.ctx, r.throttles, n); e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+// throttledResponseWriter - response paced by throttles.
+type throttledResponseWriter struct {
+	*statusResponseWriter
+	ctx       context.Context
+	throttles []*bandwidthThrottle
+}
+
+func newThrottledResponseWriter(ctx context.Context, w http.ResponseWriter, throttles []*bandwidthThrottle) throttledResponseWriter {
+	return throttledResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(w),
+		ctx:                  ctx,
+		throttles:            throttles,
+	}
+}
+
+func (w throttledResponseWriter) Write(p []byte) (written int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxThrottleChunkSize {
+			chunk = chunk[:maxThrottleChunkSize]
+		}
+		if err = waitThrottles(w.ctx, w.throttles, len(chunk)); err != nil {
+			return written, err
+		}
+		var n int
+		n, err = w.statusResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// ReadFrom - writes data read from r with Write, so that it is paced
+// by the throttles as well.
+func (w throttledResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, r)
+}
+
+// requestLimitHandler - caps the number of S3 requests served at once,
+// requests beyond the cap are queued for at most wait and then
+// rejected with SlowDown. Data of requests is paced by the bandwidth
+// throttle of their connection. Internode and admin requests are not
+// limited.
+type requestLimitHandler struct {
+	handler http.Handler
+	// Free request slots, nil if concurrent requests are unlimited.
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// setRequestLimitHandler - returns handler function limiting concurrent
+// requests to maxRequests, zero disables the limit.
+func setRequestLimitHandler(maxRequests int, wait time.Duration) HandlerFunc {
+	return func(h http.Handler) http.Handler {
+		handler := requestLimitHandler{handler: h, wait: wait}
+		if maxRequests > 0 {
+			handler.slots = make(chan struct{}, maxRequests)
+		}
+		return handler
+	}
+}
+
+func (h requestLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	if h.slots != nil {
+		timer := time.NewTimer(h.wait)
+		select {
+		case h.slots <- struct{}{}:
+			timer.Stop()
+			defer func() { <-h.slots }()
+		case <-timer.C:
+			writeErrorResponse(w, r, ErrSlowDown, r.URL.Path)
+			return
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	if throttle, ok := r.Context().Value(connThrottleKey{}).(*bandwidthThrottle); ok {
+		throttles := []*bandwidthThrottle{throttle}
+		if r.Body != nil {
+			r.Body = throttledReader{ReadCloser: r.Body, ctx: r.Context(), throttles: throttles}
+		}
+		w = newThrottledResponseWriter(r.Context(), w, throttles)
+	}
+	h.handler.ServeHTTP(w, r)
+}
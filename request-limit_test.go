@@ -0,0 +1,105 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests requests beyond the cap are queued and rejected with SlowDown
+// once they waited too long.
+func TestRequestLimitHandler(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := setRequestLimitHandler(1, 10*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		r, _ := http.NewRequest("GET", "http://localhost/bucket/object", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		close(done)
+	}()
+	<-started
+
+	r, e := http.NewRequest("GET", "http://localhost/bucket/object", nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "<Code>SlowDown</Code>") {
+		t.Fatalf("Expected SlowDown, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Queued requests are served once a slot frees up.
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		close(release)
+	}()
+	<-done
+	go func() { <-started }()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected request to be served, got %d", w.Code)
+	}
+}
+
+// Tests data of a connection is paced to its bandwidth limit.
+func TestBandwidthThrottle(t *testing.T) {
+	ctx := setConnThrottle(100*1024)(context.Background(), nil)
+	handler := setRequestLimitHandler(0, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, e := ioutil.ReadAll(r.Body)
+		if e != nil {
+			t.Fatal(e)
+		}
+		w.Write(data)
+	}))
+	r, e := http.NewRequest("PUT", "http://localhost/bucket/object", bytes.NewReader(make([]byte, 10*1024)))
+	if e != nil {
+		t.Fatal(e)
+	}
+	w := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(w, r.WithContext(ctx))
+	// 20KiB read and written at 100KiB/s, the first chunk is not paced.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("Expected transfer to be paced, took %s", elapsed)
+	}
+	if w.Body.Len() != 10*1024 {
+		t.Fatalf("Expected 10KiB response, got %d", w.Body.Len())
+	}
+
+	// Waits are aborted once the request is done.
+	throttle := newBandwidthThrottle(1)
+	throttle.wait(context.Background(), 1)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if e = throttle.wait(cancelled, 1); e != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, e)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -40,14 +41,161 @@ func newStorageAPI(exportPaths ...string) (StorageAPI, error) {
 	return newXL(exportPaths...)
 }
 
+// Storage backends selectable on the command line.
+const (
+	// Selected by the style and number of export paths.
+	storageBackendAuto = "auto"
+	// Single directory, for example a NAS mount, without erasure
+	// coding. Metadata is saved in sidecar files and objects are
+	// committed by renaming them into place.
+	storageBackendFS = "fs"
+	// Erasure coded across disks.
+	storageBackendXL = "xl"
+	// Containers of an Azure Blob Storage account, the export path
+	// is the account endpoint.
+	storageBackendAzure = "azure"
+	// Buckets of a Google Cloud Storage project, the export path is
+	// the project, that of the service account if it is missing.
+	storageBackendGCS = "gcs"
+)
+
+// newStorageBackend - initialize storage API of backend.
+func newStorageBackend(backend string, exportPaths ...string) (StorageAPI, error) {
+	switch backend {
+	case "", storageBackendAuto:
+		return newStorageAPI(exportPaths...)
+	case storageBackendFS:
+		if len(exportPaths) != 1 {
+			return nil, fmt.Errorf("Backend %s takes a single directory, got %d", backend, len(exportPaths))
+		}
+		// Paths with a ':' are local directories too.
+		return newFS(exportPaths[0])
+	case storageBackendXL:
+		return newXL(exportPaths...)
+	case storageBackendAzure:
+		if len(exportPaths) != 1 {
+			return nil, fmt.Errorf("Backend %s takes a single account endpoint, got %d", backend, len(exportPaths))
+		}
+		return newAzureStorage(exportPaths[0])
+	case storageBackendGCS:
+		if len(exportPaths) > 1 {
+			return nil, fmt.Errorf("Backend %s takes a single project, got %d", backend, len(exportPaths))
+		}
+		projectID := ""
+		if len(exportPaths) == 1 {
+			projectID = exportPaths[0]
+		}
+		return newGCSStorage(projectID)
+	}
+	return nil, fmt.Errorf("Unknown backend %q, expected %q, %q, %q, %q or %q", backend, storageBackendAuto, storageBackendFS, storageBackendXL, storageBackendAzure, storageBackendGCS)
+}
+
 // configureServer handler returns final handler for the http server.
 func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
-	storageAPI, e := newStorageAPI(srvCmdConfig.exportPaths...)
+	storageAPI, e := newStorageBackend(srvCmdConfig.backend, srvCmdConfig.exportPaths...)
 	fatalIf(probe.NewError(e), "Initializing storage API failed.", nil)
 
 	// Initialize object layer.
 	objAPI := newObjectLayer(storageAPI)
 
+	// Initialize config shared by all servers.
+	initConfigStore(objAPI)
+
+	// Initialize event notifier.
+	initEventNotifier()
+
+	// Resume purging force deleted buckets.
+	err := objAPI.ResumeBucketPurges()
+	fatalIf(err.Trace(), "Resuming bucket purges failed.", nil)
+
+	// Initialize multipart upload limits.
+	objAPI.SetMultipartLimits(serverConfig.GetMultipart())
+	err = objAPI.LoadMultipartSessions()
+	fatalIf(err.Trace(), "Loading multipart uploads failed.", nil)
+
+	// Initialize per bucket disk shares.
+	globalDiskShares.Set(serverConfig.GetDiskShares())
+
+	// Initialize disk I/O of files created from now on.
+	globalDiskIO.Set(serverConfig.GetDiskIO())
+
+	// Initialize namespace lock timeouts.
+	globalNameSpaceLocks.Set(serverConfig.GetLocks())
+
+	// Initialize object and part size limits.
+	globalObjectLimits.Set(serverConfig.GetLimits())
+
+	// Initialize heal concurrency.
+	globalHealControl.SetConfig(serverConfig.GetHeal())
+
+	// Initialize upload quarantine.
+	objAPI.SetQuarantine(serverConfig.GetQuarantine())
+
+	// Initialize trash of deleted objects.
+	objAPI.SetTrash(serverConfig.GetTrash())
+
+	// Initialize in memory block cache.
+	if cacher, ok := objAPI.storage.(blockCacher); ok {
+		cacher.SetBlockCache(serverConfig.GetBlockCache())
+	}
+
+	// Initialize local disk cache.
+	e = objAPI.SetCache(serverConfig.GetCache())
+	fatalIf(probe.NewError(e), "Initializing local disk cache failed.", nil)
+
+	// Initialize object attestations.
+	e = initAttestations(objAPI)
+	fatalIf(probe.NewError(e), "Initializing object attestations failed.", nil)
+
+	// Initialize audit log.
+	e = initAuditLog()
+	fatalIf(probe.NewError(e), "Initializing audit log failed.", nil)
+
+	// Initialize bucket replication.
+	initReplication(objAPI)
+
+	// Initialize lifecycle expiration.
+	initLifecycleWorker(objAPI)
+
+	// Initialize capacity alarms.
+	initCapacityAlarms(objAPI)
+
+	// Initialize data usage crawler.
+	initDataUsageCrawler(objAPI)
+
+	// Initialize removal of stale multipart uploads.
+	initMultipartCleaner(objAPI)
+
+	// Initialize removal of expired trash.
+	initTrashPurger(objAPI)
+
+	// Initialize disk health checks.
+	initDiskHealth(storageAPI)
+
+	// Initialize checks of namespace locks held too long.
+	initNameSpaceLockChecks(storageAPI)
+
+	// Initialize bucket access logging.
+	initBucketLogging(objAPI)
+
+	// Initialize presigned request monitor.
+	initPresignMonitor()
+
+	// Initialize bandwidth limits.
+	initBandwidthLimiter()
+
+	// Initialize bucket quotas.
+	initBucketQuotas()
+
+	// Initialize users and their policies.
+	initIAMUsers(objAPI)
+
+	// Initialize automatic certificates.
+	initACME(objAPI)
+
+	// Initialize federation of deployments.
+	initFederation(objAPI)
+
 	// Initialize storage rpc.
 	storageRPC := newStorageRPC(storageAPI)
 
@@ -56,6 +204,11 @@ func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
 		ObjectAPI: objAPI,
 	}
 
+	// Initialize Admin API.
+	adminHandlers := adminAPIHandlers{
+		ObjectAPI: objAPI,
+	}
+
 	// Initialize Web.
 	webHandlers := &webAPIHandlers{
 		ObjectAPI: objAPI,
@@ -66,6 +219,10 @@ func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
 
 	// Register all routers.
 	registerStorageRPCRouter(mux, storageRPC)
+	registerFederationRouter(mux)
+	registerAdminRouter(mux, adminHandlers)
+	registerSTSRouter(mux, stsAPIHandlers{})
+	registerHealthRouter(mux, healthAPIHandlers{ObjectAPI: objAPI})
 	registerWebRouter(mux, webHandlers)
 	registerAPIRouter(mux, apiHandlers)
 	// Add new routers here.
@@ -80,10 +237,24 @@ func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
 		setPrivateBucketHandler,
 		// Adds cache control for all browser requests.
 		setBrowserCacheControlHandler,
+		// Adds configured security headers for all requests.
+		setSecurityHeadersHandler,
 		// Validates all incoming requests to have a valid date header.
 		setTimeValidityHandler,
 		// CORS setting for all browser API requests.
 		setCorsHandler,
+		// Proxies or redirects requests for buckets of other
+		// federated deployments to them.
+		setFederationHandler,
+		// Rejects requests for force deleted buckets, which are still
+		// being purged.
+		setBucketPurgeHandler(objAPI),
+		// Rejects requests needing optional features the storage
+		// backend does not support.
+		setCapabilityHandler(objAPI),
+		// Rejects object names with empty, '.' or '..' elements, which
+		// the router would redirect to other objects.
+		setObjectPathHandler,
 		// Validates all incoming URL resources, for invalid/unsupported
 		// resources client receives a HTTP error.
 		setIgnoreResourcesHandler,
@@ -91,6 +262,31 @@ func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
 		// routes them accordingly. Client receives a HTTP error for
 		// invalid/unsupported signatures.
 		setAuthHandler,
+		// Enforces policies attached to users on their requests.
+		setIAMPolicyHandler(mux),
+		// Caps the number of requests served at once and paces the
+		// bandwidth of each connection.
+		setRequestLimitHandler(srvCmdConfig.maxRequests, srvCmdConfig.maxRequestsWait),
+		// Paces uploads and downloads to the bandwidth limits of
+		// their bucket and access key.
+		setBandwidthLimitHandler,
+		// Records latency of requests, heal concurrency adapts to it.
+		setRequestLatencyHandler,
+		// Audit log records all S3 requests, including the ones
+		// rejected by the handlers above.
+		setAuditLogHandler(mux),
+		// Bucket access logs record S3 requests on buckets with
+		// logging enabled, written to their target buckets.
+		setBucketLogHandler(mux),
+		// Sends requests and their responses to operators tracing
+		// them through the admin API.
+		setHTTPTraceHandler,
+		// Aborts requests taking longer than the configured timeout.
+		setRequestDeadlineHandler(srvCmdConfig.requestTimeout),
+		// Serves virtual host style requests, which name their bucket
+		// in the host, as path style requests. Applied first, so that
+		// all handlers find the bucket in the path.
+		setVirtualHostHandler,
 		// Add new handlers here.
 	}
 
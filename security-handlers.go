@@ -0,0 +1,136 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Default Content-Security-Policy for the web console, the console
+// is a single page app loading all its assets from the server.
+const defaultContentSecurityPolicy = "default-src 'self' 'unsafe-eval' 'unsafe-inline'"
+
+// httpsRedirect - configuration of the plaintext listener which
+// redirects all requests to HTTPS, only used if certs are available.
+type httpsRedirect struct {
+	Enable  bool   `json:"enable"`
+	Address string `json:"address"`
+}
+
+// hsts - Strict-Transport-Security header configuration, the header
+// is only sent on HTTPS responses.
+type hsts struct {
+	Enable            bool  `json:"enable"`
+	MaxAge            int64 `json:"maxAge"` // In seconds.
+	IncludeSubdomains bool  `json:"includeSubdomains"`
+}
+
+// security - security related response headers and redirects.
+type security struct {
+	HTTPSRedirect httpsRedirect `json:"httpsRedirect"`
+	HSTS          hsts          `json:"hsts"`
+	// Sends "X-Content-Type-Options: nosniff" on all responses.
+	NoSniff bool `json:"noSniff"`
+	// Content-Security-Policy for the web console, empty disables it.
+	ContentSecurityPolicy string `json:"contentSecurityPolicy"`
+}
+
+// newSecurityConfig - security configuration for fresh and migrated
+// configs, redirect and HSTS are left for the operator to enable.
+func newSecurityConfig() security {
+	return security{
+		HTTPSRedirect: httpsRedirect{Address: ":80"},
+		HSTS: hsts{
+			MaxAge: 31536000, // One year.
+		},
+		NoSniff:               true,
+		ContentSecurityPolicy: defaultContentSecurityPolicy,
+	}
+}
+
+// String - returns value for the Strict-Transport-Security header.
+func (h hsts) String() string {
+	value := "max-age=" + strconv.FormatInt(h.MaxAge, 10)
+	if h.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return value
+}
+
+// Adds security headers to all responses.
+type securityHeadersHandler struct {
+	handler http.Handler
+}
+
+// setSecurityHeadersHandler sets security headers as configured.
+func setSecurityHeadersHandler(h http.Handler) http.Handler {
+	return securityHeadersHandler{h}
+}
+
+func (h securityHeadersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sec := serverConfig.GetSecurity()
+	if sec.HSTS.Enable && r.TLS != nil {
+		w.Header().Set("Strict-Transport-Security", sec.HSTS.String())
+	}
+	if sec.NoSniff {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	// Policy applies only to the web console.
+	if sec.ContentSecurityPolicy != "" && strings.HasPrefix(r.URL.Path, reservedBucket+"/") {
+		w.Header().Set("Content-Security-Policy", sec.ContentSecurityPolicy)
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// Redirects all requests to the HTTPS listener.
+type httpsRedirectHandler struct {
+	// HTTPS listener port.
+	port string
+}
+
+func (h httpsRedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, e := net.SplitHostPort(r.Host)
+	if e != nil {
+		// Host header without a port.
+		host = r.Host
+	}
+	if h.port != "" && h.port != "443" {
+		host = net.JoinHostPort(host, h.port)
+	}
+	location := "https://" + host + r.URL.RequestURI()
+	// Clients are allowed to turn a redirected PUT or POST into a GET
+	// for 301, use 307 which preserves the method and body.
+	status := http.StatusTemporaryRedirect
+	if r.Method == "GET" || r.Method == "HEAD" {
+		status = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, location, status)
+}
+
+// configureRedirectServer - returns the plaintext server redirecting
+// to the HTTPS server at serverAddr.
+func configureRedirectServer(redirectAddr, serverAddr string) *http.Server {
+	_, port, _ := net.SplitHostPort(serverAddr)
+	return &http.Server{
+		Addr:           redirectAddr,
+		Handler:        httpsRedirectHandler{port: port},
+		MaxHeaderBytes: 1 << 20,
+	}
+}
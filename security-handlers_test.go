@@ -0,0 +1,64 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Tests redirects from the plaintext listener.
+func TestHTTPSRedirectHandler(t *testing.T) {
+	testCases := []struct {
+		serverAddr string
+		method     string
+		host       string
+		uri        string
+		location   string
+		status     int
+	}{
+		{":443", "GET", "example.com", "/bucket/object?uploads", "https://example.com/bucket/object?uploads", http.StatusMovedPermanently},
+		{":9000", "GET", "example.com:80", "/bucket", "https://example.com:9000/bucket", http.StatusMovedPermanently},
+		{"10.0.0.1:9000", "PUT", "example.com", "/bucket/object", "https://example.com:9000/bucket/object", http.StatusTemporaryRedirect},
+	}
+	for i, testCase := range testCases {
+		server := configureRedirectServer(":80", testCase.serverAddr)
+		r, e := http.NewRequest(testCase.method, "http://"+testCase.host+testCase.uri, nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		if w.Code != testCase.status {
+			t.Errorf("Test %d: Expected status %d, got %d", i+1, testCase.status, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != testCase.location {
+			t.Errorf("Test %d: Expected location %s, got %s", i+1, testCase.location, location)
+		}
+	}
+}
+
+// Tests Strict-Transport-Security header values.
+func TestHSTSString(t *testing.T) {
+	if value := (hsts{MaxAge: 600}).String(); value != "max-age=600" {
+		t.Errorf("Unexpected header value %s", value)
+	}
+	if value := (hsts{MaxAge: 600, IncludeSubdomains: true}).String(); value != "max-age=600; includeSubDomains" {
+		t.Errorf("Unexpected header value %s", value)
+	}
+}
@@ -0,0 +1,394 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+const (
+	// Let's Encrypt production directory, on fresh and migrated configs.
+	defaultACMEDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+	// Days before expiry certificates are renewed, on fresh and
+	// migrated configs.
+	defaultACMERenewBefore = 30
+
+	// ALPN protocol of tls-alpn-01 challenge handshakes.
+	acmeTLSALPNProtocol = "acme-tls/1"
+
+	// Account and certificates are saved under this directory in
+	// minioMetaVolume, which is written to all disks.
+	acmeMetaDir = ".acme"
+
+	// Maximum size of the saved account and certificates.
+	maxACMEMetaSize = 1024 * 1024
+
+	// Interval between checks of the certificate for renewal.
+	acmeCheckInterval = 12 * time.Hour
+
+	// First and maximum interval between retries of failed renewals.
+	acmeMinRetryInterval = time.Minute
+	acmeMaxRetryInterval = 4 * time.Hour
+)
+
+// Certificate extension of tls-alpn-01 challenge certificates, holding
+// the digest of the key authorization.
+var acmeIdentifierOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// errACMECertificatePending - no certificate is obtained yet.
+var errACMECertificatePending = errors.New("ACME certificate not obtained yet")
+
+// acmeConfig - automatic certificates for a public domain, obtained
+// from an ACME server such as Let's Encrypt. The server must be
+// reachable on port 443 of domain, challenges are solved over TLS.
+type acmeConfig struct {
+	Enable bool   `json:"enable"`
+	Domain string `json:"domain"`
+	// Contact of the account, notified of expiring certificates.
+	Email        string `json:"email"`
+	DirectoryURL string `json:"directoryURL"`
+	// Days before expiry certificates are renewed.
+	RenewBefore int `json:"renewBefore"`
+}
+
+// newACMEConfig - ACME configuration for fresh and migrated configs.
+func newACMEConfig() acmeConfig {
+	return acmeConfig{
+		DirectoryURL: defaultACMEDirectoryURL,
+		RenewBefore:  defaultACMERenewBefore,
+	}
+}
+
+// acmeAccount - saved account, registered with the server at
+// DirectoryURL.
+type acmeAccount struct {
+	DirectoryURL string `json:"directoryURL"`
+	URL          string `json:"url"`
+	Key          string `json:"key"`
+}
+
+// acmeCertificate - saved certificate chain and its key.
+type acmeCertificate struct {
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+}
+
+// acmeManager - serves the certificate of the configured domain,
+// obtained and renewed from the ACME server.
+type acmeManager struct {
+	config acmeConfig
+	objAPI objectAPI
+	mutex  *sync.RWMutex
+	cert   *tls.Certificate
+	// Challenge certificates presented, by domain.
+	challenges map[string]*tls.Certificate
+}
+
+// Global ACME manager, nil unless ACME is enabled.
+var globalACMEManager *acmeManager
+
+// initACME - loads the saved certificate if ACME is enabled.
+func initACME(o objectAPI) {
+	config := serverConfig.GetACME()
+	if !config.Enable {
+		return
+	}
+	manager, err := newACMEManager(config, o)
+	fatalIf(err.Trace(config.Domain), "Unable to initialize ACME.", nil)
+	globalACMEManager = manager
+}
+
+// newACMEManager - returns manager of config, serving the certificate
+// saved by o if any.
+func newACMEManager(config acmeConfig, o objectAPI) (*acmeManager, *probe.Error) {
+	if config.Domain == "" {
+		return nil, probe.NewError(errors.New("ACME domain cannot be empty"))
+	}
+	config.Domain = strings.ToLower(config.Domain)
+	m := &acmeManager{
+		config:     config,
+		objAPI:     o,
+		mutex:      &sync.RWMutex{},
+		challenges: make(map[string]*tls.Certificate),
+	}
+	saved := acmeCertificate{}
+	if e := m.readJSON(m.certificatePath(), &saved); e != nil {
+		if errorCause(e) == errFileNotFound || errorCause(e) == errVolumeNotFound {
+			return m, nil
+		}
+		return nil, probe.NewError(e)
+	}
+	cert, e := parseACMECertificate([]byte(saved.Certificate), []byte(saved.Key))
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	m.cert = cert
+	return m, nil
+}
+
+// parseACMECertificate - returns certificate of the PEM encoded chain
+// and key, with its leaf parsed.
+func parseACMECertificate(chain, key []byte) (*tls.Certificate, error) {
+	cert, e := tls.X509KeyPair(chain, key)
+	if e != nil {
+		return nil, e
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, e = x509.ParseCertificate(cert.Certificate[0]); e != nil {
+			return nil, e
+		}
+	}
+	return &cert, nil
+}
+
+func (m *acmeManager) accountPath() string {
+	return path.Join(acmeMetaDir, "account.json")
+}
+
+func (m *acmeManager) certificatePath() string {
+	return path.Join(acmeMetaDir, "certs", m.config.Domain+".json")
+}
+
+func (m *acmeManager) readJSON(metaPath string, v interface{}) error {
+	data, e := m.objAPI.readMetaFile(metaPath, maxACMEMetaSize)
+	if e != nil {
+		return e
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (m *acmeManager) writeJSON(metaPath string, v interface{}) error {
+	data, e := json.Marshal(v)
+	if e != nil {
+		return e
+	}
+	if e = m.objAPI.storage.MakeVol(minioMetaVolume); e != nil && errorCause(e) != errVolumeExists {
+		return e
+	}
+	return m.objAPI.writeMetaFile(metaPath, data)
+}
+
+// GetCertificate - returns the challenge certificate to tls-alpn-01
+// handshakes, the obtained certificate otherwise. Implements
+// tls.Config.GetCertificate.
+func (m *acmeManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, proto := range hello.SupportedProtos {
+		if proto != acmeTLSALPNProtocol {
+			continue
+		}
+		cert, ok := m.challenges[strings.ToLower(hello.ServerName)]
+		if !ok {
+			return nil, errors.New("No ACME challenge for " + hello.ServerName)
+		}
+		return cert, nil
+	}
+	if m.cert == nil {
+		return nil, errACMECertificatePending
+	}
+	return m.cert, nil
+}
+
+// present - serves the challenge certificate of keyAuth to tls-alpn-01
+// handshakes for domain, implements acmeSolver.
+func (m *acmeManager) present(domain, keyAuth string) error {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		return e
+	}
+	digest := sha256.Sum256([]byte(keyAuth))
+	extension, e := asn1.Marshal(digest[:])
+	if e != nil {
+		return e
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: acmeIdentifierOID, Critical: true, Value: extension},
+		},
+	}
+	der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if e != nil {
+		return e
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.challenges[strings.ToLower(domain)] = &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+	return nil
+}
+
+// cleanup - stops serving the challenge certificate of domain,
+// implements acmeSolver.
+func (m *acmeManager) cleanup(domain string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.challenges, strings.ToLower(domain))
+}
+
+// needsRenewal - returns true if no certificate is obtained yet, or
+// it expires within the configured days.
+func (m *acmeManager) needsRenewal() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.cert == nil {
+		return true
+	}
+	renewBefore := time.Duration(m.config.RenewBefore) * 24 * time.Hour
+	return time.Now().Add(renewBefore).After(m.cert.Leaf.NotAfter)
+}
+
+// client - returns client of the saved account, registering a new
+// account if none is saved for the configured server.
+func (m *acmeManager) client() (*acmeClient, error) {
+	account := acmeAccount{}
+	e := m.readJSON(m.accountPath(), &account)
+	if e != nil && errorCause(e) != errFileNotFound && errorCause(e) != errVolumeNotFound {
+		return nil, e
+	}
+	if e == nil && account.DirectoryURL == m.config.DirectoryURL {
+		block, _ := pem.Decode([]byte(account.Key))
+		if block == nil {
+			return nil, errors.New("Invalid ACME account key")
+		}
+		key, e := x509.ParseECPrivateKey(block.Bytes)
+		if e != nil {
+			return nil, e
+		}
+		return newACMEClient(m.config.DirectoryURL, key, account.URL), nil
+	}
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		return nil, e
+	}
+	keyPEM, e := encodeECKey(key)
+	if e != nil {
+		return nil, e
+	}
+	client := newACMEClient(m.config.DirectoryURL, key, "")
+	if e = client.register(m.config.Email); e != nil {
+		return nil, e
+	}
+	account = acmeAccount{
+		DirectoryURL: m.config.DirectoryURL,
+		URL:          client.kid,
+		Key:          string(keyPEM),
+	}
+	if e = m.writeJSON(m.accountPath(), account); e != nil {
+		return nil, e
+	}
+	return client, nil
+}
+
+// encodeECKey - returns PEM encoding of key.
+func encodeECKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, e := x509.MarshalECPrivateKey(key)
+	if e != nil {
+		return nil, e
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// renew - obtains a new certificate, saves and serves it.
+func (m *acmeManager) renew() error {
+	client, e := m.client()
+	if e != nil {
+		return e
+	}
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		return e
+	}
+	csr, e := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.config.Domain},
+		DNSNames: []string{m.config.Domain},
+	}, key)
+	if e != nil {
+		return e
+	}
+	chain, e := client.obtain(m.config.Domain, csr, m)
+	if e != nil {
+		return e
+	}
+	keyPEM, e := encodeECKey(key)
+	if e != nil {
+		return e
+	}
+	cert, e := parseACMECertificate(chain, keyPEM)
+	if e != nil {
+		return e
+	}
+	saved := acmeCertificate{Certificate: string(chain), Key: string(keyPEM)}
+	if e = m.writeJSON(m.certificatePath(), saved); e != nil {
+		return e
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.cert = cert
+	return nil
+}
+
+// run - renews the certificate whenever needed until done is closed.
+// Failed renewals are retried with exponential backoff, the current
+// certificate is served meanwhile.
+func (m *acmeManager) run(done <-chan struct{}) {
+	retryInterval := acmeMinRetryInterval
+	for {
+		wait := acmeCheckInterval
+		if m.needsRenewal() {
+			if e := m.renew(); e != nil {
+				errorIf(probe.NewError(e).Trace(m.config.Domain), "Unable to obtain ACME certificate.", nil)
+				wait = retryInterval
+				if retryInterval *= 2; retryInterval > acmeMaxRetryInterval {
+					retryInterval = acmeMaxRetryInterval
+				}
+			} else {
+				retryInterval = acmeMinRetryInterval
+			}
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeACMEServer - ACME server issuing certificates of validity once
+// the tls-alpn-01 challenge certificate served by solver verifies.
+type fakeACMEServer struct {
+	*httptest.Server
+	solver   *acmeManager
+	validity time.Duration
+	mutex    sync.Mutex
+	accounts int
+	orders   int
+	jwk      json.RawMessage
+	domain   string
+	status   string
+	certPEM  []byte
+	caKey    *ecdsa.PrivateKey
+}
+
+func newFakeACMEServer(t *testing.T, validity time.Duration) *fakeACMEServer {
+	caKey, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+	s := &fakeACMEServer{validity: validity, caKey: caKey}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+		if r.URL.Path == "/directory" {
+			json.NewEncoder(w).Encode(acmeDirectory{
+				NewNonce:   s.URL + "/nonce",
+				NewAccount: s.URL + "/account",
+				NewOrder:   s.URL + "/order",
+			})
+			return
+		}
+		if r.Method != "POST" {
+			return
+		}
+		payload, e := s.parseJWS(r)
+		if e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if e = s.serve(w, r.URL.Path, payload); e != nil {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(acmeProblem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: e.Error()})
+		}
+	}))
+	return s
+}
+
+// parseJWS - returns payload of the JWS request, recording the account
+// key of new accounts.
+func (s *fakeACMEServer) parseJWS(r *http.Request) ([]byte, error) {
+	jws := struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}{}
+	if e := json.NewDecoder(r.Body).Decode(&jws); e != nil {
+		return nil, e
+	}
+	protectedBytes, e := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if e != nil {
+		return nil, e
+	}
+	protected := struct {
+		URL string          `json:"url"`
+		JWK json.RawMessage `json:"jwk"`
+	}{}
+	if e = json.Unmarshal(protectedBytes, &protected); e != nil {
+		return nil, e
+	}
+	if protected.URL != s.URL+r.URL.Path {
+		return nil, fmt.Errorf("Unexpected url %s", protected.URL)
+	}
+	if protected.JWK != nil {
+		s.jwk = protected.JWK
+	}
+	return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+func (s *fakeACMEServer) serve(w http.ResponseWriter, urlPath string, payload []byte) error {
+	order := func() acmeOrder {
+		return acmeOrder{
+			Status:         s.status,
+			Authorizations: []string{s.URL + "/authz"},
+			Finalize:       s.URL + "/finalize",
+			Certificate:    s.URL + "/cert",
+		}
+	}
+	switch urlPath {
+	case "/account":
+		s.accounts++
+		w.Header().Set("Location", s.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+	case "/order":
+		request := struct {
+			Identifiers []acmeIdentifier `json:"identifiers"`
+		}{}
+		if e := json.Unmarshal(payload, &request); e != nil {
+			return e
+		}
+		s.orders++
+		s.domain = request.Identifiers[0].Value
+		s.status = "pending"
+		w.Header().Set("Location", s.URL+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order())
+	case "/order/1":
+		json.NewEncoder(w).Encode(order())
+	case "/authz":
+		json.NewEncoder(w).Encode(acmeAuthorization{
+			Status:     s.status,
+			Identifier: acmeIdentifier{Type: "dns", Value: s.domain},
+			Challenges: []acmeChallenge{
+				{Type: "http-01", URL: s.URL + "/http", Token: "token"},
+				{Type: acmeChallengeTLSALPN01, URL: s.URL + "/challenge", Token: "token"},
+			},
+		})
+	case "/challenge":
+		if e := s.validate(); e != nil {
+			s.status = acmeStatusInvalid
+			return e
+		}
+		s.status = acmeStatusValid
+		json.NewEncoder(w).Encode(acmeChallenge{Type: acmeChallengeTLSALPN01, Status: s.status})
+	case "/finalize":
+		request := struct {
+			CSR string `json:"csr"`
+		}{}
+		if e := json.Unmarshal(payload, &request); e != nil {
+			return e
+		}
+		if e := s.issue(request.CSR); e != nil {
+			return e
+		}
+		json.NewEncoder(w).Encode(order())
+	case "/cert":
+		w.Write(s.certPEM)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+	return nil
+}
+
+// validate - verifies the challenge certificate the solver serves to
+// tls-alpn-01 handshakes.
+func (s *fakeACMEServer) validate() error {
+	cert, e := s.solver.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      s.domain,
+		SupportedProtos: []string{acmeTLSALPNProtocol},
+	})
+	if e != nil {
+		return e
+	}
+	leaf, e := x509.ParseCertificate(cert.Certificate[0])
+	if e != nil {
+		return e
+	}
+	thumbprint := sha256.Sum256(s.jwk)
+	digest := sha256.Sum256([]byte("token." + base64.RawURLEncoding.EncodeToString(thumbprint[:])))
+	expected, _ := asn1.Marshal(digest[:])
+	for _, extension := range leaf.Extensions {
+		if extension.Id.Equal(acmeIdentifierOID) && extension.Critical && bytes.Equal(extension.Value, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid challenge certificate for %s", s.domain)
+}
+
+// issue - issues the certificate of the base64url encoded csr.
+func (s *fakeACMEServer) issue(encodedCSR string) error {
+	der, e := base64.RawURLEncoding.DecodeString(encodedCSR)
+	if e != nil {
+		return e
+	}
+	csr, e := x509.ParseCertificateRequest(der)
+	if e != nil {
+		return e
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(s.validity),
+	}
+	certDER, e := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, s.caKey)
+	if e != nil {
+		return e
+	}
+	s.certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	s.status = acmeStatusValid
+	return nil
+}
+
+// Tests certificates are obtained by solving tls-alpn-01 challenges,
+// saved along with the account, and renewed before expiry.
+func TestACMEManager(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-acme-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	obj := newObjectLayer(fs)
+
+	server := newFakeACMEServer(t, 90*24*time.Hour)
+	defer server.Close()
+	config := acmeConfig{
+		Enable:       true,
+		Domain:       "Minio.Example.com",
+		Email:        "admin@example.com",
+		DirectoryURL: server.URL + "/directory",
+		RenewBefore:  defaultACMERenewBefore,
+	}
+	manager, err := newACMEManager(config, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.solver = manager
+	if _, e = manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "minio.example.com"}); e != errACMECertificatePending {
+		t.Fatalf("Expected %s, got %v", errACMECertificatePending, e)
+	}
+	if !manager.needsRenewal() {
+		t.Fatal("Expected missing certificate to need renewal")
+	}
+	if e = manager.renew(); e != nil {
+		t.Fatal(e)
+	}
+	if manager.needsRenewal() {
+		t.Fatal("Expected fresh certificate not to need renewal")
+	}
+	servedDomain := func(m *acmeManager) string {
+		cert, e := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "minio.example.com", SupportedProtos: []string{"http/1.1"}})
+		if e != nil {
+			t.Fatal(e)
+		}
+		return cert.Leaf.DNSNames[0]
+	}
+	if domain := servedDomain(manager); domain != "minio.example.com" {
+		t.Fatalf("Unexpected certificate of %s", domain)
+	}
+	// Challenge certificates are removed once solved.
+	if _, e = manager.GetCertificate(&tls.ClientHelloInfo{ServerName: "minio.example.com", SupportedProtos: []string{acmeTLSALPNProtocol}}); e == nil {
+		t.Fatal("Expected no challenge certificate")
+	}
+
+	// Saved certificates are served after restarts.
+	restarted, err := newACMEManager(config, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain := servedDomain(restarted); domain != "minio.example.com" || restarted.needsRenewal() {
+		t.Fatalf("Unexpected saved certificate of %s", domain)
+	}
+
+	// Certificates expiring within renewBefore days are renewed with
+	// the saved account.
+	server.validity = 10 * 24 * time.Hour
+	if e = manager.renew(); e != nil {
+		t.Fatal(e)
+	}
+	if !manager.needsRenewal() {
+		t.Fatal("Expected expiring certificate to need renewal")
+	}
+	if server.accounts != 1 || server.orders != 2 {
+		t.Fatalf("Expected 1 account and 2 orders, got %d and %d", server.accounts, server.orders)
+	}
+}
@@ -17,7 +17,7 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -27,7 +27,9 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/console"
 	"github.com/minio/minio/pkg/minhttp"
@@ -42,6 +44,33 @@ var serverCmd = cli.Command{
 			Name:  "address",
 			Value: ":9000",
 		},
+		cli.StringFlag{
+			Name:  "backend",
+			Value: storageBackendAuto,
+			Usage: "Storage backend: \"fs\" for a single directory without erasure coding, \"xl\" for erasure coding across disks, \"azure\" for containers of the Azure Blob Storage account at PATH, \"auto\" to select it by the number of PATHs.",
+		},
+		cli.DurationFlag{
+			Name:  "request-timeout",
+			Usage: "Abort requests taking longer than this duration, e.g. 30m. Disabled by default.",
+		},
+		cli.IntFlag{
+			Name:  "max-requests",
+			Usage: "Maximum number of S3 requests served at once, others are queued. Unlimited by default.",
+		},
+		cli.DurationFlag{
+			Name:  "max-requests-wait",
+			Value: defaultMaxRequestsWait,
+			Usage: "Time requests beyond --max-requests are queued for before they are rejected with SlowDown.",
+		},
+		cli.StringFlag{
+			Name:  "max-connection-bandwidth",
+			Usage: "Maximum bandwidth per client connection in bytes per second, e.g. 10MiB. Unlimited by default.",
+		},
+		cli.DurationFlag{
+			Name:  "shutdown-timeout",
+			Value: defaultShutdownTimeout,
+			Usage: "Time in-flight requests, and afterwards in-flight commits, are given to complete on shutdown.",
+		},
 	},
 	Action: serverMain,
 	CustomHelpTemplate: `NAME:
@@ -67,15 +96,44 @@ EXAMPLES:
   3. Start minio server on Windows.
       $ minio {{.Name}} C:\MyShare
 
-  4. Start minio server 8 disks to enable erasure coded layer with 4 data and 4 parity.
+  4. Start minio server aborting requests which take longer than 30 minutes.
+      $ minio {{.Name}} --request-timeout 30m /home/shared
+
+  5. Start minio server serving at most 64 requests at once, each connection limited to 10MiB/s.
+      $ minio {{.Name}} --max-requests 64 --max-connection-bandwidth 10MiB /home/shared
+
+  6. Gracefully stop minio server, or restart it with a new binary inheriting its listening sockets.
+      $ kill -TERM <pid>
+      $ kill -HUP <pid>
+
+  7. Start minio server 8 disks to enable erasure coded layer with 4 data and 4 parity.
       $ minio {{.Name}} /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend /mnt/export4/backend \
           /mnt/export5/backend /mnt/export6/backend /mnt/export7/backend /mnt/export8/backend
+
+  8. Start minio server on a NAS mount, without erasure coding.
+      $ minio {{.Name}} --backend fs /mnt/nas/minio
+
+  9. Start minio server as a gateway to an Azure Blob Storage account.
+      $ export MINIO_AZURE_ACCOUNT_NAME=myaccount
+      $ export MINIO_AZURE_ACCOUNT_KEY=<base64 encoded account key>
+      $ minio {{.Name}} --backend azure https://myaccount.blob.core.windows.net
 `,
 }
 
 type serverCmdConfig struct {
 	serverAddr  string
 	exportPaths []string
+	// Storage backend of export paths, selected by their number if
+	// empty.
+	backend string
+	// Requests are aborted after this duration, zero disables it.
+	requestTimeout time.Duration
+	// Maximum concurrent requests and the time requests beyond are
+	// queued for, zero disables the limit.
+	maxRequests     int
+	maxRequestsWait time.Duration
+	// Bytes per second of each connection, zero disables the limit.
+	maxConnBandwidth int64
 }
 
 // configureServer configure a new server instance
@@ -85,15 +143,33 @@ func configureServer(srvCmdConfig serverCmdConfig) *http.Server {
 		Addr:           srvCmdConfig.serverAddr,
 		Handler:        configureServerHandler(srvCmdConfig),
 		MaxHeaderBytes: 1 << 20,
+		// Requests are aborted once the server shuts down.
+		BaseContext: func(net.Listener) context.Context {
+			return globalServerCtx
+		},
+		// Requests of a connection share its bandwidth limit.
+		ConnContext: setConnThrottle(srvCmdConfig.maxConnBandwidth),
 	}
 
-	// Configure TLS if certs are available.
-	if isSSL() {
+	// Configure TLS with certificates obtained by ACME if enabled,
+	// they are renewed in the background and challenges are solved
+	// over TLS.
+	if globalACMEManager != nil {
 		var e error
-		apiServer.TLSConfig = &tls.Config{}
-		apiServer.TLSConfig.Certificates = make([]tls.Certificate, 1)
-		apiServer.TLSConfig.Certificates[0], e = tls.LoadX509KeyPair(mustGetCertFile(), mustGetKeyFile())
+		apiServer.TLSConfig, e = newServerTLSConfig(serverConfig.GetTLS(), globalACMEManager.GetCertificate)
+		fatalIf(probe.NewError(e), "Invalid TLS configuration.", nil)
+		apiServer.TLSConfig.NextProtos = []string{"http/1.1", acmeTLSALPNProtocol}
+		go globalACMEManager.run(globalServerCtx.Done())
+	} else if isSSL() {
+		// Configure TLS if certs are available, certs are reloaded on
+		// SIGHUP and once they change without restarting.
+		reloader, e := newCertReloader(mustGetCertFile(), mustGetKeyFile())
 		fatalIf(probe.NewError(e), "Unable to load certificates.", nil)
+		config := serverConfig.GetTLS()
+		apiServer.TLSConfig, e = newServerTLSConfig(config, reloader.GetCertificate)
+		fatalIf(probe.NewError(e), "Invalid TLS configuration.", nil)
+		reloadInterval := time.Duration(config.ReloadInterval) * time.Second
+		go reloader.watch(reloadInterval, globalServerCtx.Done())
 	}
 
 	// Returns configured HTTP server.
@@ -154,6 +230,10 @@ func initServerConfig(c *cli.Context) {
 		})
 	}
 
+	// Override settings with environment variables, never saved.
+	e := initEnvOverrides()
+	fatalIf(probe.NewError(e), "Invalid config environment variable.", nil)
+
 	// Set maxOpenFiles, This is necessary since default operating
 	// system limits of 1024, 2048 are not enough for Minio server.
 	setMaxOpenFiles()
@@ -250,6 +330,13 @@ func serverMain(c *cli.Context) {
 	// check 'server' cli arguments.
 	checkServerSyntax(c)
 
+	// Save all command line args as export paths.
+	startServer(c, c.String("backend"), c.Args())
+}
+
+// startServer - starts serving exportPaths of backend, until the
+// server is stopped.
+func startServer(c *cli.Context, backend string, exportPaths []string) {
 	// Initialize server config.
 	initServerConfig(c)
 
@@ -269,13 +356,23 @@ func serverMain(c *cli.Context) {
 	// Check if requested port is available.
 	checkPortAvailability(getPort(net.JoinHostPort(host, port)))
 
-	// Save all command line args as export paths.
-	exportPaths := c.Args()
+	// Bandwidth limit of each connection.
+	var maxConnBandwidth uint64
+	if bandwidth := c.String("max-connection-bandwidth"); bandwidth != "" {
+		var e error
+		maxConnBandwidth, e = humanize.ParseBytes(bandwidth)
+		fatalIf(probe.NewError(e).Trace(bandwidth), "Invalid connection bandwidth.", nil)
+	}
 
 	// Configure server.
 	apiServer := configureServer(serverCmdConfig{
-		serverAddr:  serverAddress,
-		exportPaths: exportPaths,
+		serverAddr:       serverAddress,
+		exportPaths:      exportPaths,
+		backend:          backend,
+		requestTimeout:   c.Duration("request-timeout"),
+		maxRequests:      c.Int("max-requests"),
+		maxRequestsWait:  c.Duration("max-requests-wait"),
+		maxConnBandwidth: int64(maxConnBandwidth),
 	})
 
 	// Credential.
@@ -306,7 +403,25 @@ func serverMain(c *cli.Context) {
 		console.Println("    $ ./mc config host add myminio http://localhost:9000 " + cred.AccessKeyID + " " + cred.SecretAccessKey)
 	}
 
-	// Start server.
-	err := minhttp.ListenAndServe(apiServer)
+	servers := []*http.Server{apiServer}
+	// Redirect plaintext requests to HTTPS, if configured.
+	if redirect := serverConfig.GetSecurity().HTTPSRedirect; isSSL() && redirect.Enable {
+		redirectServer := configureRedirectServer(redirect.Address, net.JoinHostPort(host, port))
+		checkPortAvailability(getPort(redirectServer.Addr))
+		console.Println("\nRedirecting to HTTPS:")
+		printListenIPs(redirectServer)
+		servers = append(servers, redirectServer)
+	}
+
+	// Start server, stops gracefully on SIGTERM and SIGINT.
+	shutdownTimeout := c.Duration("shutdown-timeout")
+	minhttp.StopTimeout = shutdownTimeout
+	err := minhttp.ListenAndServe(servers...)
 	errorIf(err.Trace(), "Failed to start the minio server.", nil)
+
+	// Wait for commits of requests which were not complete in time.
+	shutdownServer(shutdownTimeout)
+
+	// Write bucket access logs not written yet.
+	globalBucketLogger.Flush()
 }
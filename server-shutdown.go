@@ -0,0 +1,97 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Default duration in-flight requests and commits are each given to
+// complete on shutdown.
+const defaultShutdownTimeout = 10 * time.Second
+
+// errShutdownTimeout - in-flight commits did not complete in time.
+var errShutdownTimeout = errors.New("In-flight commits did not complete before shutdown timeout")
+
+// Parent context of all requests, done once the server shuts down so
+// that writes not yet committing are rolled back.
+var globalServerCtx, cancelGlobalServerCtx = context.WithCancel(context.Background())
+
+// inFlightCommits - counts writes and deletes spanning disks which are
+// in flight, shutdown waits for them to commit or roll back.
+type inFlightCommits struct {
+	wg    *sync.WaitGroup
+	count int64
+}
+
+// Global in-flight commits, waited for on shutdown.
+var globalInFlightCommits = &inFlightCommits{wg: &sync.WaitGroup{}}
+
+// begin - registers a commit, the returned function must be called
+// once it committed or rolled back.
+func (c *inFlightCommits) begin() (end func()) {
+	c.wg.Add(1)
+	atomic.AddInt64(&c.count, 1)
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&c.count, -1)
+			c.wg.Done()
+		})
+	}
+}
+
+// Count - returns number of commits in flight.
+func (c *inFlightCommits) Count() int64 {
+	return atomic.LoadInt64(&c.count)
+}
+
+// wait - waits for all commits in flight, returns false if they did
+// not complete within timeout.
+func (c *inFlightCommits) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// shutdownServer - rolls back writes of requests which were still
+// served once the server stopped serving, and waits for at most timeout
+// for commits in flight. Name space locks are released as commits
+// complete.
+func shutdownServer(timeout time.Duration) {
+	cancelGlobalServerCtx()
+	if !globalInFlightCommits.wait(timeout) {
+		errorIf(probe.NewError(errShutdownTimeout), "Unable to complete in-flight commits.", logrus.Fields{
+			"inFlight": globalInFlightCommits.Count(),
+		})
+	}
+}
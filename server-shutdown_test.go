@@ -0,0 +1,81 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests shutdown waits for in-flight commits, bounded by its timeout.
+func TestInFlightCommits(t *testing.T) {
+	commits := &inFlightCommits{wg: &sync.WaitGroup{}}
+	if !commits.wait(time.Millisecond) {
+		t.Fatal("Expected no commits to wait for")
+	}
+	end := commits.begin()
+	if commits.Count() != 1 {
+		t.Fatalf("Expected 1 commit in flight, got %d", commits.Count())
+	}
+	if commits.wait(10 * time.Millisecond) {
+		t.Fatal("Expected wait to time out")
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		end()
+		end()
+	}()
+	if !commits.wait(time.Second) {
+		t.Fatal("Expected commit to complete")
+	}
+	if commits.Count() != 0 {
+		t.Fatalf("Expected no commits in flight, got %d", commits.Count())
+	}
+}
+
+// Tests name space locks are handed over between writers and released
+// once unused.
+func TestXLNameSpaceLock(t *testing.T) {
+	xl := XL{
+		nameSpaceLockMap:      make(map[nameSpaceParam]*nameSpaceLock),
+		nameSpaceLockMapMutex: &sync.Mutex{},
+	}
+	holder, _ := xl.lockNS("bucket", "object", false)
+	locked := make(chan struct{})
+	go func() {
+		// Waits for the first writer without blocking its unlock.
+		holder, _ := xl.lockNS("bucket", "object", false)
+		close(locked)
+		xl.unlockNS(holder)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	xl.unlockNS(holder)
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Lock was not handed over")
+	}
+	holder, _ = xl.lockNS("bucket", "object", true)
+	xl.unlockNS(holder)
+
+	xl.nameSpaceLockMapMutex.Lock()
+	defer xl.nameSpaceLockMapMutex.Unlock()
+	if len(xl.nameSpaceLockMap) != 0 {
+		t.Fatalf("Expected all locks released, got %d", len(xl.nameSpaceLockMap))
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// Seconds between checks of the certificate and key for changes, on
+// fresh and migrated configs.
+const defaultTLSReloadInterval = 60
+
+// tlsConfig - HTTPS configuration, HTTPS is served once the
+// certificate and key exist.
+type tlsConfig struct {
+	// Certificate and key paths, empty for public.crt and private.key
+	// in the certs directory.
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// Minimum TLS version, "1.0", "1.1", "1.2" or "1.3", empty for
+	// the default.
+	MinVersion string `json:"minVersion"`
+	// Names of cipher suites of TLS 1.2 and older, empty for the
+	// default suites. TLS 1.3 suites are not configurable.
+	CipherSuites []string `json:"cipherSuites"`
+	// Seconds between checks of the certificate and key for changes,
+	// zero reloads them on SIGHUP only.
+	ReloadInterval int `json:"reloadInterval"`
+}
+
+// newTLSConfig - TLS configuration for fresh and migrated configs.
+func newTLSConfig() tlsConfig {
+	return tlsConfig{
+		CipherSuites:   []string{},
+		ReloadInterval: defaultTLSReloadInterval,
+	}
+}
+
+// TLS versions by their configured name.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func validTLSVersion(value string) error {
+	if _, ok := tlsVersions[value]; value != "" && !ok {
+		return fmt.Errorf("Unknown TLS version %s", value)
+	}
+	return nil
+}
+
+// parseCipherSuites - returns ids of cipher suites with names.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[suite.Name] = suite.ID
+	}
+	var ids []uint16
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown cipher suite %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newServerTLSConfig - returns server TLS configuration of config,
+// serving certificates returned by getCertificate.
+func newServerTLSConfig(config tlsConfig, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tls.Config, error) {
+	if err := validTLSVersion(config.MinVersion); err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(config.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetCertificate: getCertificate,
+		MinVersion:     tlsVersions[config.MinVersion],
+		CipherSuites:   cipherSuites,
+	}, nil
+}
+
+// certReloader - serves the certificate loaded from its certificate
+// and key files, loaded again on SIGHUP and once the files change.
+// Failing to load keeps the previous certificate, so that a bad
+// rotation never stops HTTPS.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mutex    *sync.RWMutex
+	cert     *tls.Certificate
+	// Latest modification time of the files when last loaded.
+	modTime time.Time
+}
+
+// newCertReloader - returns reloader of the certificate of certFile
+// and keyFile, which must load.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		mutex:    &sync.RWMutex{},
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate - returns the certificate loaded last, implements
+// tls.Config.GetCertificate.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.cert, nil
+}
+
+// filesModTime - returns latest modification time of the files.
+func (c *certReloader) filesModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		st, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if st.ModTime().After(modTime) {
+			modTime = st.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// Reload - loads the certificate from its files.
+func (c *certReloader) Reload() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// Files written once are not loaded again until they change,
+	// whether they loaded or not.
+	c.modTime = modTime
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	return nil
+}
+
+// reloadIfChanged - loads the certificate if its files changed since
+// last loaded.
+func (c *certReloader) reloadIfChanged() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+	c.mutex.RLock()
+	changed := !modTime.Equal(c.modTime)
+	c.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+	return c.Reload()
+}
+
+// watch - reloads the certificate on SIGHUP, and once its files
+// change if interval is not zero, until done is closed.
+func (c *certReloader) watch(interval time.Duration, done <-chan struct{}) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-sighup:
+			err = c.Reload()
+		case <-tick:
+			err = c.reloadIfChanged()
+		}
+		errorIf(probe.NewError(err).Trace(c.certFile, c.keyFile), "Unable to reload certificate, serving the previous one.", nil)
+	}
+}
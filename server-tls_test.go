@@ -0,0 +1,156 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert - writes a self signed certificate of commonName and
+// its key to certFile and keyFile, modified at modTime.
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	keyDER, e := x509.MarshalECPrivateKey(key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); e != nil {
+		t.Fatal(e)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if e = os.Chtimes(file, modTime, modTime); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+// Tests certificates are served until their files change, and kept if
+// the changed files do not load.
+func TestCertReloader(t *testing.T) {
+	dir, e := ioutil.TempDir("", "minio-tls-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "public.crt")
+	keyFile := filepath.Join(dir, "private.key")
+	modTime := time.Now().Add(-time.Minute)
+
+	if _, e = newCertReloader(certFile, keyFile); e == nil {
+		t.Fatal("Expected missing certificate to fail")
+	}
+	writeTestCert(t, certFile, keyFile, "old.example.com", modTime)
+	reloader, e := newCertReloader(certFile, keyFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	servedName := func() string {
+		cert, e := reloader.GetCertificate(nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		leaf, e := x509.ParseCertificate(cert.Certificate[0])
+		if e != nil {
+			t.Fatal(e)
+		}
+		return leaf.Subject.CommonName
+	}
+
+	// Unchanged files are not loaded again.
+	writeTestCert(t, certFile, keyFile, "same.example.com", modTime)
+	if e = reloader.reloadIfChanged(); e != nil || servedName() != "old.example.com" {
+		t.Fatalf("Expected unchanged certificate, got %s %v", servedName(), e)
+	}
+
+	writeTestCert(t, certFile, keyFile, "new.example.com", modTime.Add(time.Second))
+	if e = reloader.reloadIfChanged(); e != nil || servedName() != "new.example.com" {
+		t.Fatalf("Expected reloaded certificate, got %s %v", servedName(), e)
+	}
+
+	// Broken files keep the certificate loaded last.
+	if e = ioutil.WriteFile(keyFile, []byte("broken"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	if e = os.Chtimes(keyFile, modTime.Add(2*time.Second), modTime.Add(2*time.Second)); e != nil {
+		t.Fatal(e)
+	}
+	if e = reloader.reloadIfChanged(); e == nil || servedName() != "new.example.com" {
+		t.Fatalf("Expected previous certificate kept, got %s %v", servedName(), e)
+	}
+	if e = reloader.reloadIfChanged(); e != nil {
+		t.Fatalf("Expected broken files not loaded again, got %v", e)
+	}
+}
+
+// Tests TLS version and cipher suites configuration.
+func TestServerTLSConfig(t *testing.T) {
+	reloader := &certReloader{}
+	testCases := []struct {
+		config       tlsConfig
+		minVersion   uint16
+		cipherSuites int
+		err          bool
+	}{
+		{tlsConfig{}, 0, 0, false},
+		{tlsConfig{MinVersion: "1.2"}, tls.VersionTLS12, 0, false},
+		{tlsConfig{MinVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}}, tls.VersionTLS13, 2, false},
+		{tlsConfig{MinVersion: "2.0"}, 0, 0, true},
+		{tlsConfig{CipherSuites: []string{"TLS_UNKNOWN"}}, 0, 0, true},
+	}
+	for i, testCase := range testCases {
+		config, e := newServerTLSConfig(testCase.config, reloader.GetCertificate)
+		if (e != nil) != testCase.err {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, e)
+			continue
+		}
+		if e != nil {
+			continue
+		}
+		if config.MinVersion != testCase.minVersion || len(config.CipherSuites) != testCase.cipherSuites {
+			t.Errorf("Test %d: Unexpected TLS config %+v", i+1, config)
+		}
+	}
+}
@@ -17,10 +17,16 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net"
 	"os"
 	"sort"
@@ -31,10 +37,12 @@ import (
 
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/gorilla/rpc/v2/json2"
 	. "gopkg.in/check.v1"
 )
 
@@ -46,6 +54,7 @@ const (
 // API suite container.
 type MyAPISuite struct {
 	root       string
+	fsroot     string
 	req        *http.Request
 	body       io.ReadSeeker
 	credential credential
@@ -77,6 +86,7 @@ func (s *MyAPISuite) SetUpSuite(c *C) {
 
 	fsroot, e := ioutil.TempDir(os.TempDir(), "api-")
 	c.Assert(e, IsNil)
+	s.fsroot = fsroot
 
 	// Initialize server config.
 	initConfig()
@@ -144,6 +154,12 @@ var ignoredHeaders = map[string]bool{
 }
 
 func (s *MyAPISuite) newRequest(method, urlStr string, contentLength int64, body io.ReadSeeker) (*http.Request, error) {
+	return newSignedRequest(method, urlStr, contentLength, body, s.credential, serviceS3, "")
+}
+
+// newSignedRequest - returns request signed with cred for service,
+// carrying sessionToken if set.
+func newSignedRequest(method, urlStr string, contentLength int64, body io.ReadSeeker, cred credential, serviceName, sessionToken string) (*http.Request, error) {
 	if method == "" {
 		method = "POST"
 	}
@@ -155,6 +171,9 @@ func (s *MyAPISuite) newRequest(method, urlStr string, contentLength int64, body
 	}
 
 	req.Header.Set("x-amz-date", t.Format(iso8601Format))
+	if sessionToken != "" {
+		req.Header.Set("x-amz-security-token", sessionToken)
+	}
 
 	// Add Content-Length
 	req.ContentLength = contentLength
@@ -241,7 +260,7 @@ func (s *MyAPISuite) newRequest(method, urlStr string, contentLength int64, body
 	scope := strings.Join([]string{
 		t.Format(yyyymmdd),
 		"us-east-1",
-		"s3",
+		serviceName,
 		"aws4_request",
 	}, "/")
 
@@ -249,16 +268,16 @@ func (s *MyAPISuite) newRequest(method, urlStr string, contentLength int64, body
 	stringToSign = stringToSign + scope + "\n"
 	stringToSign = stringToSign + hex.EncodeToString(sum256([]byte(canonicalRequest)))
 
-	date := sumHMAC([]byte("AWS4"+s.credential.SecretAccessKey), []byte(t.Format(yyyymmdd)))
+	date := sumHMAC([]byte("AWS4"+cred.SecretAccessKey), []byte(t.Format(yyyymmdd)))
 	region := sumHMAC(date, []byte("us-east-1"))
-	service := sumHMAC(region, []byte("s3"))
+	service := sumHMAC(region, []byte(serviceName))
 	signingKey := sumHMAC(service, []byte("aws4_request"))
 
 	signature := hex.EncodeToString(sumHMAC(signingKey, []byte(stringToSign)))
 
 	// final Authorization header
 	parts := []string{
-		"AWS4-HMAC-SHA256" + " Credential=" + s.credential.AccessKeyID + "/" + scope,
+		"AWS4-HMAC-SHA256" + " Credential=" + cred.AccessKeyID + "/" + scope,
 		"SignedHeaders=" + signedHeaders,
 		"Signature=" + signature,
 	}
@@ -347,28 +366,112 @@ func (s *MyAPISuite) TestBucketPolicy(c *C) {
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
-}
 
-func (s *MyAPISuite) TestDeleteBucket(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucket", 0, nil)
+	// Deleted policy is gone.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/policybucket?policy", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucketPolicy", "The specified bucket does not have a bucket policy.", http.StatusNotFound)
+}
 
+func (s *MyAPISuite) TestWebBrowser(c *C) {
 	client := http.Client{}
+	// Calls method of the web JSON-RPC service, authenticated with token.
+	webRPC := func(token, method string, args interface{}, reply interface{}) *json2.Error {
+		reqBytes, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "Web." + method,
+			"params":  args,
+			"id":      1,
+		})
+		c.Assert(err, IsNil)
+		request, err := http.NewRequest("POST", testAPIFSCacheServer.URL+"/minio/webrpc", bytes.NewReader(reqBytes))
+		c.Assert(err, IsNil)
+		request.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+			request.Header.Set("X-Amz-Date", time.Now().UTC().Format(iso8601Format))
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		defer response.Body.Close()
+		rpcReply := struct {
+			Result *json.RawMessage `json:"result"`
+			Error  *json2.Error     `json:"error"`
+		}{}
+		c.Assert(json.NewDecoder(response.Body).Decode(&rpcReply), IsNil)
+		if rpcReply.Error != nil {
+			return rpcReply.Error
+		}
+		c.Assert(json.Unmarshal(*rpcReply.Result, reply), IsNil)
+		return nil
+	}
+
+	login := LoginRep{}
+	rpcErr := webRPC("", "Login", LoginArgs{Username: s.credential.AccessKeyID, Password: "invalid"}, &login)
+	c.Assert(rpcErr, Not(IsNil))
+	rpcErr = webRPC("", "Login", LoginArgs{Username: s.credential.AccessKeyID, Password: s.credential.SecretAccessKey}, &login)
+	c.Assert(rpcErr, IsNil)
+	token := login.Token
+
+	// Requests without a token are refused.
+	generic := WebGenericRep{}
+	rpcErr = webRPC("", "MakeBucket", MakeBucketArgs{BucketName: "webbucket"}, &generic)
+	c.Assert(rpcErr, Not(IsNil))
+	c.Assert(rpcErr.Message, Equals, "Unauthorized request")
+	c.Assert(webRPC(token, "MakeBucket", MakeBucketArgs{BucketName: "webbucket"}, &generic), IsNil)
+
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/webbucket/shared object", int64(len("hello world")), bytes.NewReader([]byte("hello world")))
+	c.Assert(err, IsNil)
 	response, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/deletebucket", 0, nil)
+	// Share links download objects anonymously.
+	presigned := PresignedGetRep{}
+	rpcErr = webRPC(token, "PresignedGet", PresignedGetArgs{BucketName: "webbucket", ObjectName: "shared object", Expiry: 60}, &presigned)
+	c.Assert(rpcErr, IsNil)
+	response, err = client.Get(presigned.URL)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
 
-	client = http.Client{}
-	response, err = client.Do(request)
+	rpcErr = webRPC(token, "PresignedGet", PresignedGetArgs{BucketName: "webbucket", ObjectName: "shared object", Expiry: 8 * 24 * 3600}, &presigned)
+	c.Assert(rpcErr, Not(IsNil))
+	rpcErr = webRPC(token, "PresignedGet", PresignedGetArgs{BucketName: "webbucket", ObjectName: "missing"}, &presigned)
+	c.Assert(rpcErr, Not(IsNil))
+
+	// Policies are validated before they are set.
+	policy := GetBucketPolicyRep{}
+	c.Assert(webRPC(token, "GetBucketPolicy", GetBucketPolicyArgs{BucketName: "webbucket"}, &policy), IsNil)
+	c.Assert(policy.Policy, Equals, "")
+	rpcErr = webRPC(token, "SetBucketPolicy", SetBucketPolicyArgs{BucketName: "webbucket", Policy: "{}"}, &generic)
+	c.Assert(rpcErr, Not(IsNil))
+	bucketPolicy := `{"Version":"2012-10-17","Statement":[{"Action":["s3:GetObject"],"Effect":"Allow","Principal":{"AWS":["*"]},"Resource":["arn:aws:s3:::webbucket/*"]}]}`
+	c.Assert(webRPC(token, "SetBucketPolicy", SetBucketPolicyArgs{BucketName: "webbucket", Policy: bucketPolicy}, &generic), IsNil)
+	c.Assert(webRPC(token, "GetBucketPolicy", GetBucketPolicyArgs{BucketName: "webbucket"}, &policy), IsNil)
+	c.Assert(policy.Policy, Equals, bucketPolicy)
+
+	response, err = client.Get(testAPIFSCacheServer.URL + "/webbucket/shared%20object")
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Empty policies remove the bucket policy.
+	c.Assert(webRPC(token, "SetBucketPolicy", SetBucketPolicyArgs{BucketName: "webbucket"}, &generic), IsNil)
+	c.Assert(webRPC(token, "GetBucketPolicy", GetBucketPolicyArgs{BucketName: "webbucket"}, &policy), IsNil)
+	c.Assert(policy.Policy, Equals, "")
+	response, err = client.Get(testAPIFSCacheServer.URL + "/webbucket/shared%20object")
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
 }
 
-func (s *MyAPISuite) TestDeleteObject(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucketobject", 0, nil)
+func (s *MyAPISuite) TestBucketLifecycle(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/lifecycle-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
@@ -376,34 +479,145 @@ func (s *MyAPISuite) TestDeleteObject(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucketobject/myobject", 0, nil)
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/lifecycle-bucket?lifecycle", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist.", http.StatusNotFound)
 
-	client = http.Client{}
+	// Rule without expiration is rejected.
+	invalidLifecycle := bytes.NewReader([]byte(`<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/lifecycle-bucket?lifecycle", int64(invalidLifecycle.Len()), invalidLifecycle)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The lifecycle configuration contains an invalid rule.", http.StatusBadRequest)
+
+	lifecycle := []byte(`<LifecycleConfiguration><Rule><ID>logs</ID><Prefix>logs/</Prefix><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/lifecycle-bucket?lifecycle", int64(len(lifecycle)), bytes.NewReader(lifecycle))
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/deletebucketobject/myobject", 0, nil)
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/lifecycle-bucket?lifecycle", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, lifecycle)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/lifecycle-bucket?lifecycle", 0, nil)
 	c.Assert(err, IsNil)
-	client = http.Client{}
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
 }
 
-func (s *MyAPISuite) TestNonExistantBucket(c *C) {
-	request, err := s.newRequest("HEAD", testAPIFSCacheServer.URL+"/nonexistantbucket", 0, nil)
-	c.Assert(err, IsNil)
+func (s *MyAPISuite) TestLifecycleTransition(c *C) {
+	// Server is its own remote tier.
+	serverConfig.SetTier("cold", remoteTier{
+		Endpoint:  strings.TrimPrefix(testAPIFSCacheServer.URL, "http://"),
+		AccessKey: s.credential.AccessKeyID,
+		SecretKey: s.credential.SecretAccessKey,
+		Region:    "us-east-1",
+		Bucket:    "tier-bucket",
+		Prefix:    "cold/",
+	})
 
 	client := http.Client{}
+	for _, bucket := range []string{"transition-bucket", "tier-bucket"} {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/"+bucket, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// Transition to an unknown tier is rejected.
+	lifecycle := []byte(`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>unknown</StorageClass></Transition></Rule></LifecycleConfiguration>`)
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/transition-bucket?lifecycle", int64(len(lifecycle)), bytes.NewReader(lifecycle))
+	c.Assert(err, IsNil)
 	response, err := client.Do(request)
 	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidStorageClass", "The storage class you specified is not valid.", http.StatusBadRequest)
+
+	lifecycle = []byte(`<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>30</Days><StorageClass>cold</StorageClass></Transition></Rule></LifecycleConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/transition-bucket?lifecycle", int64(len(lifecycle)), bytes.NewReader(lifecycle))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/transition-bucket/logs/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Transition through an object layer sharing the server export.
+	fs, e := newFS(s.fsroot)
+	c.Assert(e, IsNil)
+	obj := newObjectLayer(fs)
+	objInfo, perr := obj.GetObjectInfo("transition-bucket", "logs/object")
+	c.Assert(perr, IsNil)
+	sequence := obj.getObjectSequence("transition-bucket", "logs/object")
+	c.Assert(obj.TransitionObject("transition-bucket", "logs/object", objInfo.ModTime, "cold"), IsNil)
+	// Truncating the data is committed like writes are.
+	c.Assert(obj.getObjectSequence("transition-bucket", "logs/object"), Equals, sequence+1)
+
+	// Data is in the tier, local object keeps its size and mtime.
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/tier-bucket/cold/transition-bucket/logs/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	fi, e := fs.StatFile("transition-bucket", "logs/object")
+	c.Assert(e, IsNil)
+	c.Assert(fi.Size, Equals, int64(0))
+	result, perr := obj.ListObjects("transition-bucket", "logs/", "", "", 1000)
+	c.Assert(perr, IsNil)
+	c.Assert(len(result.Objects), Equals, 1)
+	c.Assert(result.Objects[0].Size, Equals, int64(len("hello world")))
+	c.Assert(result.Objects[0].ModTime.Equal(objInfo.ModTime), Equals, true)
+
+	// GET reads through from the tier.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/transition-bucket/logs/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	// Restore copies data back and removes the remote copy.
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/transition-bucket/logs/object?restore", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusAccepted)
+	fi, e = fs.StatFile("transition-bucket", "logs/object")
+	c.Assert(e, IsNil)
+	c.Assert(fi.Size, Equals, int64(len("hello world")))
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/tier-bucket/cold/transition-bucket/logs/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/transition-bucket/logs/object?restore", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
 
-func (s *MyAPISuite) TestEmptyObject(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/emptyobject", 0, nil)
+func (s *MyAPISuite) TestObjectTagging(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/tagging-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
@@ -411,30 +625,71 @@ func (s *MyAPISuite) TestEmptyObject(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/emptyobject/object", 0, nil)
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/tagging-bucket/object", int64(buffer.Len()), buffer)
 	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	client = http.Client{}
+	// Duplicate keys are rejected.
+	tagging := []byte(`<Tagging><TagSet><Tag><Key>k</Key><Value>1</Value></Tag><Tag><Key>k</Key><Value>2</Value></Tag></TagSet></Tagging>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/tagging-bucket/object?tagging", int64(len(tagging)), bytes.NewReader(tagging))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidTag", "The tag provided was not a valid tag.", http.StatusBadRequest)
+
+	tagging = []byte(`<Tagging><TagSet><Tag><Key>team</Key><Value>ops</Value></Tag><Tag><Key>class</Key><Value>archive</Value></Tag></TagSet></Tagging>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/tagging-bucket/missing?tagging", int64(len(tagging)), bytes.NewReader(tagging))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/tagging-bucket/object?tagging", int64(len(tagging)), bytes.NewReader(tagging))
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/emptyobject/object", 0, nil)
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var result objectTagging
+	c.Assert(xml.NewDecoder(response.Body).Decode(&result), IsNil)
+	c.Assert(result.TagSet, DeepEquals, []objectTag{{Key: "class", Value: "archive"}, {Key: "team", Value: "ops"}})
 
-	client = http.Client{}
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/tagging-bucket/object?tagging", 0, nil)
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	result = objectTagging{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&result), IsNil)
+	c.Assert(len(result.TagSet), Equals, 0)
 
-	var buffer bytes.Buffer
+	// Object data is unaffected by tagging.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/tagging-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 	responseBody, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
-	c.Assert(true, Equals, bytes.Equal(responseBody, buffer.Bytes()))
+	c.Assert(string(responseBody), Equals, "hello world")
 }
 
-func (s *MyAPISuite) TestBucket(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/bucket", 0, nil)
+func (s *MyAPISuite) TestExtractArchive(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
@@ -442,226 +697,2012 @@ func (s *MyAPISuite) TestBucket(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/bucket", 0, nil)
+	modTime := time.Date(2015, time.March, 10, 12, 30, 0, 0, time.UTC)
+	buffer := new(bytes.Buffer)
+	tarWriter := tar.NewWriter(buffer)
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		c.Assert(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name)), ModTime: modTime}), IsNil)
+		_, err = tarWriter.Write([]byte(name))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tarWriter.Close(), IsNil)
+	archive := buffer.Bytes()
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket/photos/upload.tar", int64(len(archive)), bytes.NewReader(archive))
 	c.Assert(err, IsNil)
+	request.Header.Set("X-Minio-Extract", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var result ExtractArchiveResponse
+	c.Assert(xml.NewDecoder(response.Body).Decode(&result), IsNil)
+	c.Assert(result.Prefix, Equals, "photos/")
+	c.Assert(result.Objects, Equals, 2)
+	c.Assert(result.Size, Equals, int64(len("a.txt")+len("dir/b.txt")))
 
-	client = http.Client{}
+	// Archive itself is not saved.
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/extract-bucket/photos/upload.tar", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/extract-bucket/photos/dir/b.txt", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Amz-Meta-Mtime"), Equals, modTime.Format(time.RFC3339Nano))
+	data, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "dir/b.txt")
+
+	// Not an archive.
+	archive = []byte("hello world")
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket/upload.tar", int64(len(archive)), bytes.NewReader(archive))
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Minio-Extract", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArchive", "The archive you provided is not a valid tar, gzip compressed tar or zip archive.", http.StatusBadRequest)
+
+	// The payload is verified once read.
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket/tampered/upload.tar", int64(len(buffer.Bytes())), bytes.NewReader(buffer.Bytes()))
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Minio-Extract", "true")
+	tampered := append([]byte(nil), buffer.Bytes()...)
+	tampered[len(tampered)-1] = 1
+	request.Body = ioutil.NopCloser(bytes.NewReader(tampered))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+
+	// Users need s3:PutObject on every entry.
+	scoped := s.newTestUser(c, "extractuser", `[{"effect": "Allow", "actions": ["s3:ExtractArchive"], "resources": ["arn:aws:s3:::extract-bucket/*"]},
+		{"effect": "Allow", "actions": ["s3:PutObject"], "resources": ["arn:aws:s3:::extract-bucket/photos/dir/*"]}]`)
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket/photos/dir/upload.tar", int64(len(buffer.Bytes())), bytes.NewReader(buffer.Bytes()), scoped, serviceS3, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Minio-Extract", "true")
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/extract-bucket/photos/upload.tar", int64(len(buffer.Bytes())), bytes.NewReader(buffer.Bytes()), scoped, serviceS3, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Minio-Extract", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
 }
 
-func (s *MyAPISuite) TestObject(c *C) {
+func (s *MyAPISuite) TestObjectLock(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/lock-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/lock-bucket?object-lock", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket.", http.StatusNotFound)
+
+	config := []byte(`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/lock-bucket?object-lock", int64(len(config)), bytes.NewReader(config))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Object created with governance retention.
 	buffer := bytes.NewReader([]byte("hello world"))
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/testobject", 0, nil)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/lock-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	retainUntil := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	request.Header.Set("X-Amz-Object-Lock-Mode", "GOVERNANCE")
+	request.Header.Set("X-Amz-Object-Lock-Retain-Until-Date", retainUntil.Format(time.RFC3339))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/lock-bucket/object?retention", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var retention objectRetention
+	c.Assert(xml.NewDecoder(response.Body).Decode(&retention), IsNil)
+	c.Assert(retention.Mode, Equals, "GOVERNANCE")
+	c.Assert(retention.RetainUntilDate.Equal(retainUntil), Equals, true)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/lock-bucket/object", 0, nil)
 	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Object is WORM protected and cannot be overwritten or deleted.", http.StatusForbidden)
+
+	// Retention cannot be shortened without bypassing governance.
+	shorter := []byte(`<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>` + retainUntil.Add(-time.Minute).Format(time.RFC3339) + `</RetainUntilDate></Retention>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/lock-bucket/object?retention", int64(len(shorter)), bytes.NewReader(shorter))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Object is WORM protected and cannot be overwritten or deleted.", http.StatusForbidden)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/lock-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+}
 
+// Tests users need s3:BypassGovernanceRetention to bypass governance
+// retention, write access alone is not enough.
+func (s *MyAPISuite) TestUserBypassGovernance(c *C) {
 	client := http.Client{}
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/bypass-bucket", 0, nil)
+	c.Assert(err, IsNil)
 	response, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/testobject/object", int64(buffer.Len()), buffer)
+	config := []byte(`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/bypass-bucket?object-lock", int64(len(config)), bytes.NewReader(config))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	client = http.Client{}
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/bypass-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	retainUntil := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	request.Header.Set("X-Amz-Object-Lock-Mode", "GOVERNANCE")
+	request.Header.Set("X-Amz-Object-Lock-Retain-Until-Date", retainUntil.Format(time.RFC3339))
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/testobject/object", 0, nil)
+	// Create a read-write user denied bypassing governance retention.
+	writer := s.newTestUser(c, "bypassuser", `[{"effect": "Allow", "actions": ["s3:*"], "resources": ["arn:aws:s3:::*"]},
+		{"effect": "Deny", "actions": ["s3:BypassGovernanceRetention"], "resources": ["arn:aws:s3:::*"]}]`)
+
+	request, err = newSignedRequest("DELETE", testAPIFSCacheServer.URL+"/bypass-bucket/object", 0, nil, writer, serviceS3, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
 
-	client = http.Client{}
+	buffer = bytes.NewReader([]byte("overwritten"))
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/bypass-bucket/object", int64(buffer.Len()), buffer, writer, serviceS3, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	shorter := []byte(`<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>` + retainUntil.Add(-time.Minute).Format(time.RFC3339) + `</RetainUntilDate></Retention>`)
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/bypass-bucket/object?retention", int64(len(shorter)), bytes.NewReader(shorter), writer, serviceS3, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	deleteBody := []byte(`<Delete><Object><Key>object</Key></Object></Delete>`)
+	request, err = newSignedRequest("POST", testAPIFSCacheServer.URL+"/bypass-bucket?delete", int64(len(deleteBody)), bytes.NewReader(deleteBody), writer, serviceS3, "")
+	c.Assert(err, IsNil)
+	sum := md5.Sum(deleteBody)
+	request.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	deleteResponse := &DeleteObjectsResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(deleteResponse), IsNil)
+	c.Assert(len(deleteResponse.DeletedObjects), Equals, 0)
+	c.Assert(len(deleteResponse.Errors), Equals, 1)
+	c.Assert(deleteResponse.Errors[0].Code, Equals, "AccessDenied")
 
-	responseBody, err := ioutil.ReadAll(response.Body)
+	// The object and its retention are unchanged.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/bypass-bucket/object?retention", 0, nil)
 	c.Assert(err, IsNil)
-	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var retention objectRetention
+	c.Assert(xml.NewDecoder(response.Body).Decode(&retention), IsNil)
+	c.Assert(retention.RetainUntilDate.Equal(retainUntil), Equals, true)
 
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/bypass-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
 }
 
-func (s *MyAPISuite) TestMultipleObjects(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects", 0, nil)
+func (s *MyAPISuite) TestObjectLegalHold(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/legal-hold-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
 	response, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
-
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object", 0, nil)
+
+	config := []byte(`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/legal-hold-bucket?object-lock", int64(len(config)), bytes.NewReader(config))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Object created under legal hold.
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/legal-hold-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Object-Lock-Legal-Hold", "ON")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/legal-hold-bucket/object?legal-hold", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var legalHold objectLegalHold
+	c.Assert(xml.NewDecoder(response.Body).Decode(&legalHold), IsNil)
+	c.Assert(legalHold.Status, Equals, "ON")
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/legal-hold-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Bypass-Governance-Retention", "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Object is WORM protected and cannot be overwritten or deleted.", http.StatusForbidden)
+
+	invalid := []byte(`<LegalHold><Status>MAYBE</Status></LegalHold>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/legal-hold-bucket/object?legal-hold", int64(len(invalid)), bytes.NewReader(invalid))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Legal hold status should be either ON or OFF.", http.StatusBadRequest)
+
+	off := []byte(`<LegalHold><Status>OFF</Status></LegalHold>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/legal-hold-bucket/object?legal-hold", int64(len(off)), bytes.NewReader(off))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/legal-hold-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+}
+
+func (s *MyAPISuite) TestBucketReplication(c *C) {
+	client := http.Client{}
+	for _, bucket := range []string{"replication-source", "replication-replica"} {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/"+bucket, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/replication-source?replication", 0, nil)
+	c.Assert(err, IsNil)
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "ReplicationConfigurationNotFoundError", "The replication configuration was not found.", http.StatusNotFound)
+
+	// Replicate to another bucket of the same server.
+	config := []byte(`<ReplicationConfiguration><Destination><Endpoint>` + strings.TrimPrefix(testAPIFSCacheServer.URL, "http://") +
+		`</Endpoint><AccessKey>` + s.credential.AccessKeyID + `</AccessKey><SecretKey>` + s.credential.SecretAccessKey +
+		`</SecretKey><Bucket>replication-replica</Bucket></Destination></ReplicationConfiguration>`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/replication-source?replication", int64(len(config)), bytes.NewReader(config))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Secret key is never returned.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/replication-source?replication", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var replication replicationConfiguration
+	c.Assert(xml.NewDecoder(response.Body).Decode(&replication), IsNil)
+	c.Assert(replication.Destination.Bucket, Equals, "replication-replica")
+	c.Assert(replication.Destination.SecretKey, Equals, "")
+
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/replication-source/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Replication is asynchronous.
+	var status string
+	for i := 0; i < 50 && status != "COMPLETED"; i++ {
+		time.Sleep(100 * time.Millisecond)
+		request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/replication-source/object", 0, nil)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		status = response.Header.Get("X-Amz-Replication-Status")
+	}
+	c.Assert(status, Equals, "COMPLETED")
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/replication-replica/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "hello world")
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/replication-source?replication", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+}
+
+func (s *MyAPISuite) TestObjectPartMap(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/partmap-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	data := bytes.Repeat([]byte("a"), 3*defaultPartMapAlignment+10)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/partmap-bucket/object", int64(len(data)), bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/partmap-bucket/object?partmap&parts=2", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var partMap PartMap
+	c.Assert(xml.NewDecoder(response.Body).Decode(&partMap), IsNil)
+	c.Assert(partMap.Size, Equals, int64(len(data)))
+	c.Assert(partMap.StripeSize, Equals, int64(defaultPartMapAlignment))
+	c.Assert(len(partMap.Parts), Equals, 2)
+
+	// Ranged GETs of all parts return the whole object.
+	var readData []byte
+	for _, part := range partMap.Parts {
+		request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/partmap-bucket/object", 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("Range", part.Range)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+		partData, err := ioutil.ReadAll(response.Body)
+		c.Assert(err, IsNil)
+		c.Assert(int64(len(partData)), Equals, part.Length)
+		readData = append(readData, partData...)
+	}
+	c.Assert(bytes.Equal(readData, data), Equals, true)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/partmap-bucket/object?partmap&parts=0", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Number of parts should be between 1 and 10000.", http.StatusBadRequest)
+}
+
+func (s *MyAPISuite) TestReadSession(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/read-session-bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer := bytes.NewReader([]byte("old"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/read-session-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/read-sessions?ttl=60", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var session readSessionInfo
+	c.Assert(json.NewDecoder(response.Body).Decode(&session), IsNil)
+	c.Assert(session.ID, Not(Equals), "")
+
+	buffer = bytes.NewReader([]byte("new"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/read-session-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/read-session-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set(readSessionHeader, session.ID)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, "old")
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/minio/admin/read-sessions/"+session.ID, 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/read-session-bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set(readSessionHeader, session.ID)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchReadSession", "The specified read session does not exist or has expired.", http.StatusNotFound)
+}
+
+func (s *MyAPISuite) TestAdminHealth(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/health", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var health healthInfo
+	c.Assert(json.NewDecoder(response.Body).Decode(&health), IsNil)
+	c.Assert(health.Status, Equals, healthStatusOK)
+
+	// Anonymous requests are rejected.
+	response, err = client.Get(testAPIFSCacheServer.URL + "/minio/admin/health")
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+}
+
+func (s *MyAPISuite) TestAdminConfigDocument(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/config?document", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	doc, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Contains(doc, []byte(s.credential.SecretAccessKey)), Equals, false)
+
+	// Documents as returned are accepted back.
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/minio/admin/config", int64(len(doc)), bytes.NewReader(doc))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	invalidDoc := []byte(`{"version": "1"}`)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/minio/admin/config", int64(len(invalidDoc)), bytes.NewReader(invalidDoc))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidConfigDocument", "The config document is malformed, of another config version or has invalid values.", http.StatusBadRequest)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/config/reload", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *MyAPISuite) TestAdminIncompleteUploads(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/multipart/uploads?olderThan=0", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var info incompleteUploadsInfo
+	c.Assert(json.NewDecoder(response.Body).Decode(&info), IsNil)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/multipart/uploads?olderThan=-1", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/multipart/uploads?bucket=incomplete-uploads-missing", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/multipart/cleanup", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *MyAPISuite) TestHealthProbes(c *C) {
+	// Probes are served to anonymous requests.
+	client := http.Client{}
+	for _, probe := range []string{"live", "ready"} {
+		response, err := client.Get(testAPIFSCacheServer.URL + "/minio/health/" + probe)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+		response, err = client.Head(testAPIFSCacheServer.URL + "/minio/health/" + probe)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+}
+
+func (s *MyAPISuite) TestAdminServerInfo(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/info", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var info serverInfo
+	c.Assert(json.NewDecoder(response.Body).Decode(&info), IsNil)
+	c.Assert(info.Version, Equals, minioVersion)
+	c.Assert(len(info.Disks), Equals, 1)
+	c.Assert(info.Disks[0].Online, Equals, true)
+	c.Assert(info.Disks[0].Total > 0, Equals, true)
+	c.Assert(info.Heal.Status, Equals, healStatusIdle)
+
+	// FS backend cannot heal.
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/heal", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NotImplemented", "Heal is not supported by the storage backend.", http.StatusNotImplemented)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/verify?bucket=bucket&object=object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NotImplemented", "Object verification is not supported by the storage backend.", http.StatusNotImplemented)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/migrate", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NotImplemented", "Format migration is not supported by the storage backend.", http.StatusNotImplemented)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/usage", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var usage dataUsageInfo
+	c.Assert(json.NewDecoder(response.Body).Decode(&usage), IsNil)
+	c.Assert(usage.Buckets, NotNil)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/minio/admin/quotas/bucket", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucketQuota", "No quota is set for the specified bucket.", http.StatusNotFound)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/minio/admin/heal", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "HealNotRunning", "No heal operation is running.", http.StatusConflict)
+}
+
+func (s *MyAPISuite) TestUsersAndAssumeRole(c *C) {
+	client := http.Client{}
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/iam-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/iam-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Create a read only user.
+	reader := credential{AccessKeyID: "reader", SecretAccessKey: "reader-secret"}
+	userBody := bytes.NewReader([]byte(`{"secretKey": "reader-secret", "policy": "readonly"}`))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/minio/admin/users/reader", int64(userBody.Len()), userBody)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, reader, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/iam-bucket/object", int64(buffer.Len()), buffer, reader, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// Users are not admins.
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/users", 0, nil, reader, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// Assume role as the read only user.
+	stsBody := bytes.NewReader([]byte("Action=AssumeRole&Version=2011-06-15&DurationSeconds=900"))
+	request, err = newSignedRequest("POST", testAPIFSCacheServer.URL+"/", int64(stsBody.Len()), stsBody, reader, serviceSTS, "")
+	c.Assert(err, IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var assumed assumeRoleResponse
+	c.Assert(xml.NewDecoder(response.Body).Decode(&assumed), IsNil)
+	temp := assumed.Result.Credentials
+	c.Assert(temp.SessionToken, Not(Equals), "")
+	tempCred := credential{AccessKeyID: temp.AccessKeyID, SecretAccessKey: temp.SecretAccessKey}
+
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, tempCred, serviceS3, temp.SessionToken)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Temporary credentials are restricted like the user.
+	buffer = bytes.NewReader([]byte("hello world"))
+	request, err = newSignedRequest("PUT", testAPIFSCacheServer.URL+"/iam-bucket/object", int64(buffer.Len()), buffer, tempCred, serviceS3, temp.SessionToken)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// Session token is required.
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, tempCred, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidToken", "The provided token is malformed or otherwise invalid.", http.StatusBadRequest)
+
+	// Users create service accounts, which cannot create others.
+	request, err = newSignedRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/service-accounts", 0, nil, reader, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	var account serviceAccount
+	c.Assert(json.NewDecoder(response.Body).Decode(&account), IsNil)
+	c.Assert(account.Parent, Equals, "reader")
+	accountCred := credential{AccessKeyID: account.AccessKey, SecretAccessKey: account.SecretKey}
+
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, accountCred, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = newSignedRequest("POST", testAPIFSCacheServer.URL+"/minio/admin/service-accounts", 0, nil, accountCred, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// Temporary credentials and service accounts are revoked with
+	// their user.
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/minio/admin/users/reader", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, tempCred, serviceS3, temp.SessionToken)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidAccessKeyID", "The access key ID you provided does not exist in our records.", http.StatusForbidden)
+
+	request, err = newSignedRequest("GET", testAPIFSCacheServer.URL+"/iam-bucket/object", 0, nil, accountCred, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidAccessKeyID", "The access key ID you provided does not exist in our records.", http.StatusForbidden)
+}
+
+// newTestUser - creates a user named name with a policy of statements,
+// returns its credential.
+func (s *MyAPISuite) newTestUser(c *C, name, statements string) credential {
+	client := http.Client{}
+	policyBody := bytes.NewReader([]byte(`{"statements": ` + statements + `}`))
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/minio/admin/policies/"+name, int64(policyBody.Len()), policyBody)
+	c.Assert(err, IsNil)
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	userBody := bytes.NewReader([]byte(`{"secretKey": "` + name + `-secret", "policy": "` + name + `"}`))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/minio/admin/users/"+name, int64(userBody.Len()), userBody)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	return credential{AccessKeyID: name, SecretAccessKey: name + "-secret"}
+}
+
+// Tests copies by users need read access to the source, for copies of
+// whole objects and of parts alike.
+func (s *MyAPISuite) TestUserCopySource(c *C) {
+	client := http.Client{}
+	for _, bucket := range []string{"copy-private", "copy-scoped"} {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/"+bucket, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/"+bucket+"/source", int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// Create a user allowed everything in a single bucket.
+	scoped := s.newTestUser(c, "copyuser", `[{"effect": "Allow", "actions": ["s3:*"], "resources": ["arn:aws:s3:::copy-scoped", "arn:aws:s3:::copy-scoped/*"]}]`)
+
+	copyObject := func(target, source string) *http.Response {
+		request, err := newSignedRequest("PUT", testAPIFSCacheServer.URL+target, 0, nil, scoped, serviceS3, "")
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", source)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response := copyObject("/copy-scoped/copy", "/copy-scoped/source")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = copyObject("/copy-scoped/copy", "/copy-private/source")
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	request, err := newSignedRequest("POST", testAPIFSCacheServer.URL+"/copy-scoped/parts?uploads", 0, nil, scoped, serviceS3, "")
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	newResponse := &InitiateMultipartUploadResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(newResponse), IsNil)
+	partURL := "/copy-scoped/parts?uploadId=" + newResponse.UploadID + "&partNumber=1"
+
+	response = copyObject(partURL, "/copy-scoped/source")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = copyObject(partURL, "/copy-private/source")
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+}
+
+// Tests multi-object deletes by users apply their policy to each key.
+func (s *MyAPISuite) TestUserDeleteMultipleObjects(c *C) {
+	client := http.Client{}
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/delete-scoped", 0, nil)
+	c.Assert(err, IsNil)
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	for _, object := range []string{"public/object", "private/object"} {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/delete-scoped/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// Create a user allowed to delete only under "public/".
+	scoped := s.newTestUser(c, "deleteuser", `[{"effect": "Allow", "actions": ["s3:DeleteMultipleObjects"], "resources": ["arn:aws:s3:::delete-scoped"]},
+		{"effect": "Allow", "actions": ["s3:DeleteObject"], "resources": ["arn:aws:s3:::delete-scoped/public/*"]}]`)
+
+	deleteBody := []byte(`<Delete><Object><Key>public/object</Key></Object><Object><Key>private/object</Key></Object></Delete>`)
+	request, err = newSignedRequest("POST", testAPIFSCacheServer.URL+"/delete-scoped?delete", int64(len(deleteBody)), bytes.NewReader(deleteBody), scoped, serviceS3, "")
+	c.Assert(err, IsNil)
+	sum := md5.Sum(deleteBody)
+	request.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	deleteResponse := &DeleteObjectsResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(deleteResponse), IsNil)
+	c.Assert(deleteResponse.DeletedObjects, DeepEquals, []ObjectIdentifier{{ObjectName: "public/object"}})
+	c.Assert(len(deleteResponse.Errors), Equals, 1)
+	c.Assert(deleteResponse.Errors[0].Key, Equals, "private/object")
+	c.Assert(deleteResponse.Errors[0].Code, Equals, "AccessDenied")
+
+	// The denied object is still there.
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/delete-scoped/private/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *MyAPISuite) TestDeleteBucket(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/deletebucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+}
+
+func (s *MyAPISuite) TestForceDeleteBucket(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/forcedeletebucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	for _, object := range []string{"a", "b/c", "d"} {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/forcedeletebucket/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/forcedeletebucket", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set(forceDeleteHeader, "true")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusAccepted)
+	jobID := response.Header.Get(purgeJobIDHeader)
+	c.Assert(jobID, Not(Equals), "")
+
+	// Bucket is gone right away.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/forcedeletebucket/a", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+
+	var job purgeJob
+	for i := 0; i < 100; i++ {
+		request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/purge/"+jobID, 0, nil)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		c.Assert(json.NewDecoder(response.Body).Decode(&job), IsNil)
+		response.Body.Close()
+		if job.Status != purgeStatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(job.Status, Equals, purgeStatusFinished)
+	c.Assert(job.Deleted, Equals, int64(3))
+
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/forcedeletebucket", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/purge/unknown", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchPurgeJob", "The specified purge job does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPISuite) TestDeleteObject(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucketobject", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/deletebucketobject/myobject", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/deletebucketobject/myobject", 0, nil)
+	c.Assert(err, IsNil)
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+}
+
+func (s *MyAPISuite) TestNonExistantBucket(c *C) {
+	request, err := s.newRequest("HEAD", testAPIFSCacheServer.URL+"/nonexistantbucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *MyAPISuite) TestEmptyObject(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/emptyobject", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/emptyobject/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/emptyobject/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var buffer bytes.Buffer
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(true, Equals, bytes.Equal(responseBody, buffer.Bytes()))
+}
+
+func (s *MyAPISuite) TestBucket(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/bucket", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *MyAPISuite) TestObject(c *C) {
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/testobject", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/testobject/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/testobject/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, []byte("hello world"))
+
+}
+
+func (s *MyAPISuite) TestMultipleObjects(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+
+	//// test object 1
+
+	// get object
+	buffer1 := bytes.NewReader([]byte("hello one"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object1", int64(buffer1.Len()), buffer1)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object1", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// verify response data
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello one")))
+
+	buffer2 := bytes.NewReader([]byte("hello two"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object2", int64(buffer2.Len()), buffer2)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object2", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// verify response data
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello two")))
+
+	buffer3 := bytes.NewReader([]byte("hello three"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object3", int64(buffer3.Len()), buffer3)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object3", 0, nil)
+	c.Assert(err, IsNil)
+
+	client = http.Client{}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// verify object
+	responseBody, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello three")))
+}
+
+func (s *MyAPISuite) TestNotImplemented(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/bucket/object?policy", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotImplemented)
+}
+
+func (s *MyAPISuite) TestHeader(c *C) {
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/bucket/object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+}
+
+func (s *MyAPISuite) TestSecurityHeaders(c *C) {
+	serverConfig.SetSecurity(newSecurityConfig())
+
+	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Content-Type-Options"), Equals, "nosniff")
+	// HSTS is never sent over plaintext.
+	c.Assert(response.Header.Get("Strict-Transport-Security"), Equals, "")
+	// Content security policy is only sent for the web console.
+	c.Assert(response.Header.Get("Content-Security-Policy"), Equals, "")
+}
+
+func (s *MyAPISuite) TestPutBucket(c *C) {
+	// Block 1: Testing for racey access
+	// The assertion is removed from this block since the purpose of this block is to find races
+	// The purpose this block is not to check for correctness of functionality
+	// Run the test with -race flag to utilize this
+	var wg sync.WaitGroup
+	for i := 0; i < ConcurrencyLevel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-bucket", 0, nil)
+			c.Assert(err, IsNil)
+
+			client := http.Client{}
+			response, err := client.Do(request)
+			c.Assert(err, IsNil)
+			defer response.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	//Block 2: testing for correctness of the functionality
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-bucket-slash/", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response.Body.Close()
+
+}
+
+func (s *MyAPISuite) TestCopyObject(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer1 := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy/object", int64(buffer1.Len()), buffer1)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy/object1", 0, nil)
+	request.Header.Set("X-Amz-Copy-Source", "/put-object-copy/object")
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/put-object-copy/object1", 0, nil)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+
+	c.Assert(string(object), Equals, "hello world")
+}
+
+func (s *MyAPISuite) TestPutObject(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	buffer1 := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object/object", int64(buffer1.Len()), buffer1)
+	c.Assert(err, IsNil)
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+}
+
+func (s *MyAPISuite) TestObjectNameEncoding(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-name-encoding", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Object names are sent URL encoded and stored decoded.
+	objectName := "dir/hello world+%é.txt"
+	escapedName := "dir/hello%20world%2B%25%C3%A9.txt"
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-name-encoding/"+escapedName, int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/object-name-encoding/"+escapedName, 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello world")
+
+	for _, encodingType := range []string{"", "url"} {
+		request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/object-name-encoding?encoding-type="+encodingType, 0, nil)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		result := ListObjectsResponse{}
+		c.Assert(xml.NewDecoder(response.Body).Decode(&result), IsNil)
+		c.Assert(len(result.Contents), Equals, 1)
+		c.Assert(result.EncodingType, Equals, encodingType)
+		if encodingType == "" {
+			c.Assert(result.Contents[0].Key, Equals, objectName)
+		} else {
+			c.Assert(result.Contents[0].Key, Equals, "dir/hello+world%2B%25%C3%A9.txt")
+		}
+	}
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/object-name-encoding?encoding-type=base64", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Invalid Encoding Method specified in Request", http.StatusBadRequest)
+
+	// Names with empty or dot elements are rejected rather than
+	// redirected to another object.
+	for _, name := range []string{"dir//object", "dir/./object", "dir/../object"} {
+		buffer = bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-name-encoding/"+name, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		verifyError(c, response, "XMinioInvalidObjectName", "Object name contains empty, '.' or '..' elements, which cannot be stored.", http.StatusBadRequest)
+	}
+}
+
+func (s *MyAPISuite) TestPutObjectChunked(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-chunked", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Bodies without a Content-Length are sent chunked.
+	data := bytes.Repeat([]byte("hello world"), 10000)
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-chunked/object", -1, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("ETag"), Equals, "\""+hex.EncodeToString(sumMD5(data))+"\"")
+
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/put-object-chunked/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.ContentLength, Equals, int64(len(data)))
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/put-object-chunked/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	readData, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(readData, data), Equals, true)
+
+	// Chunked bodies are verified against their signature as well.
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-chunked/other", -1, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	request.Body = ioutil.NopCloser(bytes.NewReader(data[1:]))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+}
+
+func (s *MyAPISuite) TestObjectChecksums(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-checksums", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	data := bytes.Repeat([]byte("hello world"), 10000)
+	sha256Sum := sha256.Sum256(data)
+	checksumSHA256 := base64.StdEncoding.EncodeToString(sha256Sum[:])
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(data))
+	checksumCRC32 := base64.StdEncoding.EncodeToString(crc)
+
+	put := func(object string, size int64, headers, trailers map[string]string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-checksums/"+object, size, bytes.NewReader(data))
+		c.Assert(err, IsNil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		if trailers != nil {
+			request.Trailer = http.Header{}
+			for key, value := range trailers {
+				request.Trailer.Set(key, value)
+			}
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	get := func(method, object, byteRange string) *http.Response {
+		request, err := s.newRequest(method, testAPIFSCacheServer.URL+"/object-checksums/"+object, 0, nil)
+		c.Assert(err, IsNil)
+		if byteRange != "" {
+			request.Header.Set("Range", byteRange)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+
+	// Checksums sent as headers are verified, and returned with the
+	// whole object.
+	response = put("object", int64(len(data)), map[string]string{"X-Amz-Checksum-Sha256": checksumSHA256}, nil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get("HEAD", "object", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Amz-Checksum-Sha256"), Equals, checksumSHA256)
+	response = get("GET", "object", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Amz-Checksum-Sha256"), Equals, checksumSHA256)
+	response = get("GET", "object", "bytes=0-9")
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("X-Amz-Checksum-Sha256"), Equals, "")
+
+	response = put("mismatch", int64(len(data)), map[string]string{"X-Amz-Checksum-Crc32": "AAAAAA=="}, nil)
+	verifyError(c, response, "BadDigest", "The checksum you specified did not match what we received.", http.StatusBadRequest)
+	response = get("HEAD", "mismatch", "")
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+
+	response = put("invalid", int64(len(data)), map[string]string{"X-Amz-Checksum-Crc32": checksumSHA256}, nil)
+	verifyError(c, response, "InvalidRequest", "Expecting a single valid x-amz-checksum header, sent as a header or a trailer of a chunked body.", http.StatusBadRequest)
+
+	// Checksums of chunked bodies can be sent as trailers.
+	response = put("trailing", -1, map[string]string{"X-Amz-Trailer": "x-amz-checksum-crc32"}, map[string]string{"X-Amz-Checksum-Crc32": checksumCRC32})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get("HEAD", "trailing", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Amz-Checksum-Crc32"), Equals, checksumCRC32)
+
+	response = put("trailing-mismatch", -1, map[string]string{"X-Amz-Trailer": "x-amz-checksum-crc32"}, map[string]string{"X-Amz-Checksum-Crc32": "AAAAAA=="})
+	verifyError(c, response, "BadDigest", "The checksum you specified did not match what we received.", http.StatusBadRequest)
+
+	// Checksums are never taken from headers of copies.
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/object-checksums/copy", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/object-checksums/trailing")
+	request.Header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	request.Header.Set("X-Amz-Checksum-Crc32", "AAAAAA==")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get("HEAD", "copy", "")
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("X-Amz-Checksum-Crc32"), Equals, "")
+}
+
+func (s *MyAPISuite) TestObjectStorageClass(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/storage-class", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	serverConfig.SetStorageClass("COLD", storageClass{Parity: 1})
+	data := []byte("hello world")
+	put := func(object, class string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/storage-class/"+object, int64(len(data)), bytes.NewReader(data))
+		c.Assert(err, IsNil)
+		if class != "" {
+			request.Header.Set("X-Amz-Storage-Class", class)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	head := func(object string) *http.Response {
+		request, err := s.newRequest("HEAD", testAPIFSCacheServer.URL+"/storage-class/"+object, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		return response
+	}
+
+	// Classes are returned with objects, STANDARD is never returned.
+	for object, class := range map[string]string{"default": "", "standard": "STANDARD", "reduced": "REDUCED_REDUNDANCY", "cold": "COLD"} {
+		response = put(object, class)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		if class == "STANDARD" {
+			class = ""
+		}
+		c.Assert(head(object).Header.Get("X-Amz-Storage-Class"), Equals, class)
+	}
+
+	response = put("unknown", "GLACIER")
+	verifyError(c, response, "InvalidStorageClass", "The storage class you specified is not valid.", http.StatusBadRequest)
+
+	// Listings return the class of each object.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/storage-class", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse := ListObjectsResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&listResponse), IsNil)
+	classes := make(map[string]string)
+	for _, content := range listResponse.Contents {
+		classes[content.Key] = content.StorageClass
+	}
+	c.Assert(classes, DeepEquals, map[string]string{"cold": "COLD", "default": "STANDARD", "reduced": "REDUCED_REDUNDANCY", "standard": "STANDARD"})
+
+	// Copies are STANDARD unless given a class.
+	for object, class := range map[string]string{"copy": "", "copy-reduced": "REDUCED_REDUNDANCY"} {
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/storage-class/"+object, 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", "/storage-class/cold")
+		if class != "" {
+			request.Header.Set("X-Amz-Storage-Class", class)
+		}
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		c.Assert(head(object).Header.Get("X-Amz-Storage-Class"), Equals, class)
+	}
+}
+
+func (s *MyAPISuite) TestObjectUserMetadata(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/user-metadata", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	headers := map[string]string{
+		"X-Amz-Meta-Color":    "blue",
+		"Content-Type":        "text/csv",
+		"Cache-Control":       "max-age=60",
+		"Content-Disposition": "attachment; filename=\"report.csv\"",
+		"Content-Encoding":    "gzip",
+	}
+	data := []byte("a,b,c")
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/user-metadata/object", int64(len(data)), bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	head := func(object string) *http.Response {
+		request, err := s.newRequest("HEAD", testAPIFSCacheServer.URL+"/user-metadata/"+object, 0, nil)
+		c.Assert(err, IsNil)
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		return response
+	}
+	response = head("object")
+	for key, value := range headers {
+		c.Assert(response.Header.Get(key), Equals, value)
+	}
+
+	// Copies keep the metadata of their source by default.
+	copyObject := func(object, directive string, headers map[string]string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/user-metadata/"+object, 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", "/user-metadata/object")
+		if directive != "" {
+			request.Header.Set("X-Amz-Metadata-Directive", directive)
+		}
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response = copyObject("copy", "", map[string]string{"X-Amz-Meta-Color": "red"})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = head("copy")
+	for key, value := range headers {
+		c.Assert(response.Header.Get(key), Equals, value)
+	}
+	response = copyObject("replaced", "REPLACE", map[string]string{"X-Amz-Meta-Color": "red"})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = head("replaced")
+	c.Assert(response.Header.Get("X-Amz-Meta-Color"), Equals, "red")
+	c.Assert(response.Header.Get("Content-Encoding"), Equals, "")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+
+	response = copyObject("invalid", "MOVE", nil)
+	verifyError(c, response, "InvalidArgument", "Unknown metadata directive.", http.StatusBadRequest)
+
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/user-metadata/large", int64(len(data)), bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Meta-Large", strings.Repeat("a", 2*1024))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest)
+}
+
+// newPostPolicyRequest - returns a browser form upload of data to
+// bucket, signed with policy conditions and carrying extra fields.
+func (s *MyAPISuite) newPostPolicyRequest(bucket string, conditions []interface{}, fields map[string]string, data []byte) (*http.Request, error) {
+	t := time.Now().UTC()
+	policy, e := json.Marshal(map[string]interface{}{
+		"expiration": t.Add(10 * time.Minute).Format(time.RFC3339Nano),
+		"conditions": conditions,
+	})
+	if e != nil {
+		return nil, e
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+	region := serverConfig.GetRegion()
+	signingKey := getSigningKey(s.credential.SecretAccessKey, t, region, serviceS3)
+	formValues := map[string]string{
+		"policy":           encodedPolicy,
+		"x-amz-algorithm":  signV4Algorithm,
+		"x-amz-credential": s.credential.AccessKeyID + "/" + getScope(t, region, serviceS3),
+		"x-amz-date":       t.Format(iso8601Format),
+		"x-amz-signature":  getSignature(signingKey, encodedPolicy),
+	}
+	for key, value := range fields {
+		formValues[key] = value
+	}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for key, value := range formValues {
+		if e = writer.WriteField(key, value); e != nil {
+			return nil, e
+		}
+	}
+	file, e := writer.CreateFormFile("file", "upload.txt")
+	if e != nil {
+		return nil, e
+	}
+	if _, e = file.Write(data); e != nil {
+		return nil, e
+	}
+	if e = writer.Close(); e != nil {
+		return nil, e
+	}
+	request, e := http.NewRequest("POST", testAPIFSCacheServer.URL+"/"+bucket, body)
+	if e != nil {
+		return nil, e
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request, nil
+}
+
+func (s *MyAPISuite) TestPostPolicyUpload(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/post-policy", 0, nil)
+	c.Assert(err, IsNil)
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	conditions := []interface{}{
+		map[string]string{"bucket": "post-policy"},
+		[]string{"starts-with", "$key", "uploads/"},
+		[]string{"starts-with", "$Content-Type", "text/"},
+		[]string{"starts-with", "$x-amz-meta-owner", ""},
+		[]string{"eq", "$success_action_status", "201"},
+		[]interface{}{"content-length-range", 1, 16},
+	}
+	fields := map[string]string{
+		"key":                   "uploads/${filename}",
+		"Content-Type":          "text/plain",
+		"x-amz-meta-owner":      "minio",
+		"success_action_status": "201",
+	}
+	data := []byte("hello world")
+
+	// Upload named after the file, described by the response.
+	request, err = s.newPostPolicyRequest("post-policy", conditions, fields, data)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusCreated)
+	postResponse := PostResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&postResponse), IsNil)
+	c.Assert(postResponse.Bucket, Equals, "post-policy")
+	c.Assert(postResponse.Key, Equals, "uploads/upload.txt")
+	c.Assert(postResponse.ETag, Equals, "\""+hex.EncodeToString(sumMD5(data))+"\"")
+
+	// Form metadata is stored with the object.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/post-policy/uploads/upload.txt", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Content-Type"), Equals, "text/plain")
+	c.Assert(response.Header.Get("X-Amz-Meta-Owner"), Equals, "minio")
+	responseBody, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, data)
+
+	// Fields need to meet the conditions.
+	fields["key"] = "other/${filename}"
+	request, err = s.newPostPolicyRequest("post-policy", conditions, fields, data)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Invalid according to Policy: Policy Condition failed", http.StatusForbidden)
+	fields["key"] = "uploads/${filename}"
+
+	// Fields need a condition.
+	fields["x-amz-meta-extra"] = "extra"
+	request, err = s.newPostPolicyRequest("post-policy", conditions, fields, data)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Invalid according to Policy: Extra input fields", http.StatusForbidden)
+	delete(fields, "x-amz-meta-extra")
+
+	// Files out of the content length range are not stored.
+	fields["key"] = "uploads/large"
+	request, err = s.newPostPolicyRequest("post-policy", conditions, fields, bytes.Repeat([]byte("a"), 17))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+	request, err = s.newRequest("HEAD", testAPIFSCacheServer.URL+"/post-policy/uploads/large", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *MyAPISuite) TestBucketCors(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/cors-bucket", 0, nil)
+	c.Assert(err, IsNil)
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Preflight requests fail until CORS is configured.
+	request, err = http.NewRequest("OPTIONS", testAPIFSCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "https://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessForbidden", "CORSResponse: CORS is not enabled for this bucket.", http.StatusForbidden)
+
+	corsConfig := `<CORSConfiguration><CORSRule><AllowedOrigin>https://*.example.com</AllowedOrigin><AllowedMethod>PUT</AllowedMethod><AllowedMethod>GET</AllowedMethod><AllowedHeader>content-*</AllowedHeader><ExposeHeader>ETag</ExposeHeader><MaxAgeSeconds>600</MaxAgeSeconds></CORSRule></CORSConfiguration>`
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/cors-bucket?cors", int64(len(corsConfig)), bytes.NewReader([]byte(corsConfig)))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
+	c.Assert(string(responseBody), Equals, corsConfig)
 
-	client = http.Client{}
+	// Allowed preflight requests.
+	request, err = http.NewRequest("OPTIONS", testAPIFSCacheServer.URL+"/cors-bucket/object", nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Origin", "https://www.example.com")
+	request.Header.Set("Access-Control-Request-Method", "PUT")
+	request.Header.Set("Access-Control-Request-Headers", "Content-Type")
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
-
-	//// test object 1
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "https://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Allow-Methods"), Equals, "PUT, GET")
+	c.Assert(response.Header.Get("Access-Control-Allow-Headers"), Equals, "Content-Type")
+	c.Assert(response.Header.Get("Access-Control-Max-Age"), Equals, "600")
+
+	// Preflight requests of other origins, methods or headers.
+	for _, preflight := range []struct{ origin, method, headers string }{
+		{"https://www.example.org", "PUT", ""},
+		{"https://www.example.com", "DELETE", ""},
+		{"https://www.example.com", "PUT", "Authorization"},
+	} {
+		request, err = http.NewRequest("OPTIONS", testAPIFSCacheServer.URL+"/cors-bucket/object", nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("Origin", preflight.origin)
+		request.Header.Set("Access-Control-Request-Method", preflight.method)
+		if preflight.headers != "" {
+			request.Header.Set("Access-Control-Request-Headers", preflight.headers)
+		}
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusForbidden)
+		c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "")
+	}
 
-	// get object
-	buffer1 := bytes.NewReader([]byte("hello one"))
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object1", int64(buffer1.Len()), buffer1)
+	// Cross origin requests carry CORS headers if allowed.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/cors-bucket", 0, nil)
 	c.Assert(err, IsNil)
-
-	client = http.Client{}
+	request.Header.Set("Origin", "https://www.example.com")
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "https://www.example.com")
+	c.Assert(response.Header.Get("Access-Control-Expose-Headers"), Equals, "ETag")
 
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object1", 0, nil)
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/cors-bucket", 0, nil)
 	c.Assert(err, IsNil)
-
-	client = http.Client{}
+	request.Header.Set("Origin", "https://www.example.org")
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Access-Control-Allow-Origin"), Equals, "")
 
-	// verify response data
-	responseBody, err := ioutil.ReadAll(response.Body)
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/cors-bucket?cors", 0, nil)
 	c.Assert(err, IsNil)
-	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello one")))
-
-	buffer2 := bytes.NewReader([]byte("hello two"))
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object2", int64(buffer2.Len()), buffer2)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
 
-	client = http.Client{}
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/cors-bucket?cors", 0, nil)
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	verifyError(c, response, "NoSuchCORSConfiguration", "The CORS configuration does not exist.", http.StatusNotFound)
+}
 
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object2", 0, nil)
+func (s *MyAPISuite) TestVirtualHostStyle(c *C) {
+	serverConfig.SetDomain("s3.test")
+	defer serverConfig.SetDomain("")
+
+	// Hosts of the domain are all served by the test server.
+	serverAddr := testAPIFSCacheServer.Listener.Addr().String()
+	_, port, err := net.SplitHostPort(serverAddr)
 	c.Assert(err, IsNil)
+	client := http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial(network, serverAddr)
+		},
+	}}
+	bucketURL := "http://vhost-bucket.s3.test:" + port
 
-	client = http.Client{}
-	response, err = client.Do(request)
+	request, err := s.newRequest("PUT", bucketURL+"/", 0, nil)
+	c.Assert(err, IsNil)
+	response, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	// verify response data
-	responseBody, err = ioutil.ReadAll(response.Body)
+	data := []byte("hello world")
+	request, err = s.newRequest("PUT", bucketURL+"/dir/object", int64(len(data)), bytes.NewReader(data))
 	c.Assert(err, IsNil)
-	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello two")))
-
-	buffer3 := bytes.NewReader([]byte("hello three"))
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multipleobjects/object3", int64(buffer3.Len()), buffer3)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	client = http.Client{}
+	// Objects are the same path style.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/vhost-bucket/dir/object", 0, nil)
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
-
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multipleobjects/object3", 0, nil)
+	responseBody, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, data)
 
-	client = http.Client{}
+	request, err = s.newRequest("GET", bucketURL+"/", 0, nil)
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse := ListObjectsResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(&listResponse), IsNil)
+	c.Assert(listResponse.Name, Equals, "vhost-bucket")
+	c.Assert(len(listResponse.Contents), Equals, 1)
+	c.Assert(listResponse.Contents[0].Key, Equals, "dir/object")
 
-	// verify object
-	responseBody, err = ioutil.ReadAll(response.Body)
+	// Presigned requests are signed over the path without bucket.
+	query := presignV4("GET", "vhost-bucket.s3.test:"+port, "/dir/object", s.credential.AccessKeyID, s.credential.SecretAccessKey, serverConfig.GetRegion(), time.Now().UTC(), time.Minute)
+	response, err = client.Get(bucketURL + "/dir/object?" + query)
 	c.Assert(err, IsNil)
-	c.Assert(true, Equals, bytes.Equal(responseBody, []byte("hello three")))
-}
-
-func (s *MyAPISuite) TestNotImplemented(c *C) {
-	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/bucket/object?policy", 0, nil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	responseBody, err = ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
+	c.Assert(responseBody, DeepEquals, data)
 
-	client := http.Client{}
-	response, err := client.Do(request)
+	// Signatures over the path style path do not match.
+	query = presignV4("GET", "vhost-bucket.s3.test:"+port, "/vhost-bucket/dir/object", s.credential.AccessKeyID, s.credential.SecretAccessKey, serverConfig.GetRegion(), time.Now().UTC(), time.Minute)
+	response, err = client.Get(bucketURL + "/dir/object?" + query)
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusNotImplemented)
+	c.Assert(response.StatusCode, Equals, http.StatusForbidden)
 }
 
-func (s *MyAPISuite) TestHeader(c *C) {
-	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/bucket/object", 0, nil)
+func (s *MyAPISuite) TestSelectObjectContent(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/select-object", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
 	response, err := client.Do(request)
 	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
-}
-
-func (s *MyAPISuite) TestPutBucket(c *C) {
-	// Block 1: Testing for racey access
-	// The assertion is removed from this block since the purpose of this block is to find races
-	// The purpose this block is not to check for correctness of functionality
-	// Run the test with -race flag to utilize this
-	var wg sync.WaitGroup
-	for i := 0; i < ConcurrencyLevel; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-bucket", 0, nil)
-			c.Assert(err, IsNil)
-
-			client := http.Client{}
-			response, err := client.Do(request)
-			defer response.Body.Close()
-		}()
-	}
-	wg.Wait()
-
-	//Block 2: testing for correctness of the functionality
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-bucket-slash/", 0, nil)
+	buffer := bytes.NewReader([]byte("name,age\nAlice,35\nBob,28\n"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/select-object/people.csv", int64(buffer.Len()), buffer)
 	c.Assert(err, IsNil)
 
-	client := http.Client{}
-	response, err := client.Do(request)
+	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
-	response.Body.Close()
 
-}
+	selectRequest := func(expression, expressionType string) *http.Request {
+		body := []byte(`<SelectObjectContentRequest><Expression>` + expression + `</Expression>` +
+			`<ExpressionType>` + expressionType + `</ExpressionType>` +
+			`<InputSerialization><CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV></InputSerialization>` +
+			`<OutputSerialization><CSV/></OutputSerialization></SelectObjectContentRequest>`)
+		request, err := s.newRequest("POST", testAPIFSCacheServer.URL+"/select-object/people.csv?select&select-type=2", int64(len(body)), bytes.NewReader(body))
+		c.Assert(err, IsNil)
+		return request
+	}
 
-func (s *MyAPISuite) TestCopyObject(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy", 0, nil)
+	response, err = client.Do(selectRequest("SELECT name FROM S3Object WHERE CAST(age AS INT) &gt; 30", "SQL"))
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
+	messages, err := decodeEventStream(data)
+	c.Assert(err, IsNil)
+	c.Assert(len(messages), Equals, 3)
+	c.Assert(messages[0].headers[":event-type"], Equals, "Records")
+	c.Assert(string(messages[0].payload), Equals, "Alice\n")
+	c.Assert(messages[1].headers[":event-type"], Equals, "Stats")
+	c.Assert(messages[2].headers[":event-type"], Equals, "End")
 
-	client := http.Client{}
-	response, err := client.Do(request)
+	response, err = client.Do(selectRequest("SELECT name FROM S3Object", "XPATH"))
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	verifyError(c, response, "InvalidExpressionType", "The ExpressionType is invalid. Only SQL expressions are supported.", http.StatusBadRequest)
 
-	buffer1 := bytes.NewReader([]byte("hello world"))
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy/object", int64(buffer1.Len()), buffer1)
+	body := []byte(`<SelectObjectContentRequest><Expression>SELECT * FROM S3Object</Expression><ExpressionType>SQL</ExpressionType>` +
+		`<InputSerialization><CSV/></InputSerialization><OutputSerialization><CSV/></OutputSerialization></SelectObjectContentRequest>`)
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/select-object/missing.csv?select&select-type=2", int64(len(body)), bytes.NewReader(body))
 	c.Assert(err, IsNil)
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	verifyError(c, response, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+}
 
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object-copy/object1", 0, nil)
-	request.Header.Set("X-Amz-Copy-Source", "/put-object-copy/object")
+func (s *MyAPISuite) TestListenBucketNotification(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/listen-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
-	response, err = client.Do(request)
+	client := http.Client{}
+	response, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/put-object-copy/object1", 0, nil)
+	// Unsupported event names are rejected.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/listen-bucket?events=s3:ObjectAccessed:*", 0, nil)
 	c.Assert(err, IsNil)
-
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.StatusCode, Equals, http.StatusOK)
-	object, err := ioutil.ReadAll(response.Body)
+	verifyError(c, response, "InvalidArgument", "A specified event is not supported for notifications.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/listen-bucket?events=s3:ObjectCreated:*&prefix=photos/&suffix=.jpg", 0, nil)
+	c.Assert(err, IsNil)
+	listenResponse, err := client.Do(request)
 	c.Assert(err, IsNil)
+	defer listenResponse.Body.Close()
+	c.Assert(listenResponse.StatusCode, Equals, http.StatusOK)
 
-	c.Assert(string(object), Equals, "hello world")
+	// Only the last object matches the filters.
+	for _, object := range []string{"photos/a.txt", "videos/b.jpg", "photos/c.jpg"} {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/listen-bucket/"+object, int64(buffer.Len()), buffer)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	var entry eventLogEntry
+	c.Assert(json.NewDecoder(listenResponse.Body).Decode(&entry), IsNil)
+	c.Assert(entry.EventType, Equals, "s3:ObjectCreated:Put")
+	c.Assert(entry.Key, Equals, "listen-bucket/photos/c.jpg")
+	c.Assert(len(entry.Records), Equals, 1)
+	c.Assert(entry.Records[0].S3.Object.Size, Equals, int64(len("hello world")))
 }
 
-func (s *MyAPISuite) TestPutObject(c *C) {
-	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object", 0, nil)
+func (s *MyAPISuite) TestAdminTrace(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/trace-bucket", 0, nil)
 	c.Assert(err, IsNil)
 
 	client := http.Client{}
@@ -669,13 +2710,33 @@ func (s *MyAPISuite) TestPutObject(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 
-	buffer1 := bytes.NewReader([]byte("hello world"))
-	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/put-object/object", int64(buffer1.Len()), buffer1)
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/trace?body=100000", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Trace body size should be between 0 and 65536.", http.StatusBadRequest)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/minio/admin/trace?bucket=trace-bucket&body=5", 0, nil)
+	c.Assert(err, IsNil)
+	traceResponse, err := client.Do(request)
 	c.Assert(err, IsNil)
+	defer traceResponse.Body.Close()
+	c.Assert(traceResponse.StatusCode, Equals, http.StatusOK)
 
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/trace-bucket/object", int64(buffer.Len()), buffer)
+	c.Assert(err, IsNil)
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var trace traceInfo
+	c.Assert(json.NewDecoder(traceResponse.Body).Decode(&trace), IsNil)
+	c.Assert(trace.Method, Equals, "PUT")
+	c.Assert(trace.Path, Equals, "/trace-bucket/object")
+	c.Assert(trace.StatusCode, Equals, http.StatusOK)
+	c.Assert(trace.RequestBody, Equals, "hello")
+	c.Assert(trace.ResponseHeader.Get("ETag"), Not(Equals), "")
 }
 
 func (s *MyAPISuite) TestListBuckets(c *C) {
@@ -747,6 +2808,92 @@ func (s *MyAPISuite) TestHeadOnObject(c *C) {
 	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
 }
 
+func (s *MyAPISuite) TestConditionalRequests(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/conditionalrequests", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	put := func(object, data string, headers map[string]string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/conditionalrequests/"+object, int64(len(data)), bytes.NewReader([]byte(data)))
+		c.Assert(err, IsNil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+
+	// Objects are only created if they do not exist yet.
+	response = put("object", "hello world", map[string]string{"If-None-Match": "*"})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	etag := response.Header.Get("ETag")
+	response = put("object", "hello again", map[string]string{"If-None-Match": "*"})
+	verifyError(c, response, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold", http.StatusPreconditionFailed)
+	response = put("missing", "hello world", map[string]string{"If-Match": etag})
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+
+	// Objects are only replaced if they did not change meanwhile.
+	response = put("object", "hello again", map[string]string{"If-Match": `"0123", ` + etag})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = put("object", "hello world", map[string]string{"If-Match": etag})
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+	etag = put("object", "hello world", nil).Header.Get("ETag")
+
+	get := func(headers map[string]string) *http.Response {
+		request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/conditionalrequests/object", 0, nil)
+		c.Assert(err, IsNil)
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response = get(map[string]string{"If-None-Match": etag})
+	c.Assert(response.StatusCode, Equals, http.StatusNotModified)
+	c.Assert(response.Header.Get("ETag"), Equals, etag)
+	response = get(map[string]string{"If-None-Match": `W/"0123"`})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get(map[string]string{"If-Match": `"0123"`})
+	verifyError(c, response, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold", http.StatusPreconditionFailed)
+
+	// Dates are only compared if no etags are given.
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	response = get(map[string]string{"If-Match": etag, "If-Unmodified-Since": past})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get(map[string]string{"If-None-Match": `"0123"`, "If-Modified-Since": future})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = get(map[string]string{"If-Modified-Since": future})
+	c.Assert(response.StatusCode, Equals, http.StatusNotModified)
+
+	// Copies are conditional on their source.
+	copyObject := func(headers map[string]string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/conditionalrequests/copy", 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", "/conditionalrequests/object")
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+	response = copyObject(map[string]string{"X-Amz-Copy-Source-If-Match": `"0123"`})
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+	response = copyObject(map[string]string{"X-Amz-Copy-Source-If-None-Match": etag})
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+	response = copyObject(map[string]string{"X-Amz-Copy-Source-If-Match": etag, "If-None-Match": "*"})
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	response = copyObject(map[string]string{"If-None-Match": "*"})
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+}
+
 func (s *MyAPISuite) TestHeadOnBucket(c *C) {
 	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/headonbucket", 0, nil)
 	c.Assert(err, IsNil)
@@ -853,14 +3000,14 @@ func (s *MyAPISuite) TestContentTypePersists(c *C) {
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 
 	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/contenttype-persists/two", 0, nil)
 	c.Assert(err, IsNil)
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 }
 
 func (s *MyAPISuite) TestPartialContent(c *C) {
@@ -896,6 +3043,66 @@ func (s *MyAPISuite) TestPartialContent(c *C) {
 	c.Assert(string(partialObject), Equals, "Wo")
 }
 
+func (s *MyAPISuite) TestMultiRangeGet(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/multi-range", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	data := "Hello World"
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/multi-range/bar.txt", int64(len(data)), bytes.NewReader([]byte(data)))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multi-range/bar.txt", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes=0-4, 6-, -3")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	mediaType, params, e := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	c.Assert(e, IsNil)
+	c.Assert(mediaType, Equals, "multipart/byteranges")
+
+	body, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(int64(len(body)), Equals, response.ContentLength)
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for _, expected := range []struct {
+		contentRange, data string
+	}{
+		{"bytes 0-4/11", "Hello"},
+		{"bytes 6-10/11", "World"},
+		{"bytes 8-10/11", "rld"},
+	} {
+		part, e := reader.NextPart()
+		c.Assert(e, IsNil)
+		c.Assert(part.Header.Get("Content-Range"), Equals, expected.contentRange)
+		c.Assert(part.Header.Get("Content-Type"), Equals, "text/plain")
+		partData, e := ioutil.ReadAll(part)
+		c.Assert(e, IsNil)
+		c.Assert(string(partData), Equals, expected.data)
+	}
+	_, e = reader.NextPart()
+	c.Assert(e, Equals, io.EOF)
+
+	// Ranges starting past the end of the object cannot be satisfied.
+	for _, hrange := range []string{"bytes=11-", "bytes=0-1,20-30", "bytes=-0"} {
+		request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/multi-range/bar.txt", 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("Range", hrange)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.Header.Get("Content-Range"), Equals, "bytes */11")
+		verifyError(c, response, "InvalidRange", "The requested range cannot be satisfied.", http.StatusRequestedRangeNotSatisfiable)
+	}
+}
+
 func (s *MyAPISuite) TestListObjectsHandlerErrors(c *C) {
 	request, err := s.newRequest("GET", testAPIFSCacheServer.URL+"/objecthandlererrors-.", 0, nil)
 	c.Assert(err, IsNil)
@@ -1062,12 +3269,37 @@ func (s *MyAPISuite) TestObjectMultipartAbort(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(response2.StatusCode, Equals, http.StatusOK)
 
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/objectmultipartabort?events=s3:ObjectRemoved:*", 0, nil)
+	c.Assert(err, IsNil)
+	listenResponse, err := client.Do(request)
+	c.Assert(err, IsNil)
+	defer listenResponse.Body.Close()
+	c.Assert(listenResponse.StatusCode, Equals, http.StatusOK)
+
 	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/objectmultipartabort/object?uploadId="+uploadID, 0, nil)
 	c.Assert(err, IsNil)
 
 	response3, err := client.Do(request)
 	c.Assert(err, IsNil)
 	c.Assert(response3.StatusCode, Equals, http.StatusNoContent)
+
+	// Aborting removes no object, the first event is of a delete.
+	buffer3 := bytes.NewReader([]byte("hello world"))
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/objectmultipartabort/deleted", int64(buffer3.Len()), buffer3)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	request, err = s.newRequest("DELETE", testAPIFSCacheServer.URL+"/objectmultipartabort/deleted", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	var entry eventLogEntry
+	c.Assert(json.NewDecoder(listenResponse.Body).Decode(&entry), IsNil)
+	c.Assert(entry.EventType, Equals, "s3:ObjectRemoved:Delete")
+	c.Assert(entry.Key, Equals, "objectmultipartabort/deleted")
 }
 
 func (s *MyAPISuite) TestBucketMultipartList(c *C) {
@@ -1146,7 +3378,7 @@ func (s *MyAPISuite) TestBucketMultipartList(c *C) {
 			Owner        Owner
 			StorageClass string
 			Initiated    time.Time // Keep this native to be able to parse properly.
-		}
+		} `xml:"Upload"`
 		Prefix         string
 		Delimiter      string
 		CommonPrefixes []CommonPrefix
@@ -1157,6 +3389,27 @@ func (s *MyAPISuite) TestBucketMultipartList(c *C) {
 	err = decoder.Decode(newResponse3)
 	c.Assert(err, IsNil)
 	c.Assert(newResponse3.Bucket, Equals, "bucketmultipartlist")
+	c.Assert(len(newResponse3.Uploads), Equals, 1)
+	c.Assert(newResponse3.Uploads[0].UploadID, Equals, uploadID)
+	c.Assert(newResponse3.Uploads[0].Initiator.ID, Equals, s.credential.AccessKeyID)
+	c.Assert(newResponse3.Uploads[0].StorageClass, Equals, "STANDARD")
+
+	// Uploads of the key marker itself are only listed after an upload id marker.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/bucketmultipartlist?uploads&key-marker=object", 0, nil)
+	c.Assert(err, IsNil)
+	response4, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response4.StatusCode, Equals, http.StatusOK)
+	newResponse4 := &listMultipartUploadsResponse{}
+	c.Assert(xml.NewDecoder(response4.Body).Decode(newResponse4), IsNil)
+	c.Assert(newResponse4.KeyMarker, Equals, "object")
+	c.Assert(len(newResponse4.Uploads), Equals, 0)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/bucketmultipartlist?uploads&key-marker=object&upload-id-marker=invalid", 0, nil)
+	c.Assert(err, IsNil)
+	response5, err := client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response5, "InvalidArgument", "Invalid uploadId marker, it must be a valid upload id along with a key marker of an object.", http.StatusBadRequest)
 }
 
 func (s *MyAPISuite) TestValidateObjectMultipartUploadID(c *C) {
@@ -1311,6 +3564,76 @@ func (s *MyAPISuite) TestObjectMultipart(c *C) {
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
 
+func (s *MyAPISuite) TestObjectMultipartCopyPart(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/objectmultipartcopy", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	source := []byte("hello world, copied in parts")
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/objectmultipartcopy/source", int64(len(source)), bytes.NewReader(source))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/objectmultipartcopy/object?uploads", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	newResponse := &InitiateMultipartUploadResponse{}
+	c.Assert(xml.NewDecoder(response.Body).Decode(newResponse), IsNil)
+	uploadID := newResponse.UploadID
+
+	copyPart := func(partNumber int, copyRange string) *http.Response {
+		request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/objectmultipartcopy/object?uploadId="+uploadID+"&partNumber="+strconv.Itoa(partNumber), 0, nil)
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", "/objectmultipartcopy/source")
+		if copyRange != "" {
+			request.Header.Set("X-Amz-Copy-Source-Range", copyRange)
+		}
+		response, err := client.Do(request)
+		c.Assert(err, IsNil)
+		return response
+	}
+
+	// Parts copy a range of the source, or all of it.
+	var parts []completePart
+	for partNumber, copyRange := range []string{"bytes=13-27", ""} {
+		response = copyPart(partNumber+1, copyRange)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		result := &CopyObjectPartResponse{}
+		c.Assert(xml.NewDecoder(response.Body).Decode(result), IsNil)
+		parts = append(parts, completePart{PartNumber: partNumber + 1, ETag: result.ETag})
+	}
+
+	for _, copyRange := range []string{"bytes=0-28", "bytes=5-", "bytes=3-2", "items=0-1"} {
+		response = copyPart(3, copyRange)
+		verifyError(c, response, "InvalidArgument", "The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy.", http.StatusBadRequest)
+	}
+
+	completeBytes, err := xml.Marshal(&completeMultipartUpload{Parts: parts})
+	c.Assert(err, IsNil)
+	request, err = s.newRequest("POST", testAPIFSCacheServer.URL+"/objectmultipartcopy/object?uploadId="+uploadID, int64(len(completeBytes)), bytes.NewReader(completeBytes))
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/objectmultipartcopy/object", 0, nil)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "copied in partshello world, copied in parts")
+}
+
 func verifyError(c *C, response *http.Response, code, description string, statusCode int) {
 	data, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
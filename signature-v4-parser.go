@@ -63,7 +63,7 @@ func parseCredentialHeader(credElement string) (credentialHeader, APIErrorCode)
 		return credentialHeader{}, ErrInvalidRegion
 	}
 	cred.scope.region = credElements[2]
-	if credElements[3] != "s3" {
+	if credElements[3] != serviceS3 && credElements[3] != serviceSTS {
 		return credentialHeader{}, ErrInvalidService
 	}
 	cred.scope.service = credElements[3]
@@ -20,6 +20,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
 	"time"
@@ -41,6 +42,9 @@ func toInteger(val interface{}) int {
 	switch v := val.(type) {
 	case int:
 		return v
+	case float64:
+		// JSON numbers are decoded as float64.
+		return int(v)
 	}
 	return 0
 }
@@ -158,52 +162,107 @@ func parsePostPolicyFormV4(policy string) (PostPolicyForm, *probe.Error) {
 	return parsedPolicy, nil
 }
 
-// checkPostPolicy - apply policy conditions and validate input values.
-func checkPostPolicy(formValues map[string]string) APIErrorCode {
-	if formValues["X-Amz-Algorithm"] != signV4Algorithm {
-		return ErrSignatureVersionNotSupported
-	}
-	/// Decoding policy
+// parsePostPolicyForm - decode and parse policy of form values.
+func parsePostPolicyForm(formValues map[string]string) (PostPolicyForm, *probe.Error) {
 	policyBytes, e := base64.StdEncoding.DecodeString(formValues["Policy"])
 	if e != nil {
-		return ErrMalformedPOSTRequest
+		return PostPolicyForm{}, probe.NewError(e)
 	}
 	postPolicyForm, err := parsePostPolicyFormV4(string(policyBytes))
+	if err != nil {
+		return PostPolicyForm{}, err.Trace()
+	}
+	return postPolicyForm, nil
+}
+
+// getPostPolicyExpiration - returns expiration of policy of form values.
+func getPostPolicyExpiration(formValues map[string]string) (time.Time, *probe.Error) {
+	postPolicyForm, err := parsePostPolicyForm(formValues)
+	if err != nil {
+		return time.Time{}, err.Trace()
+	}
+	return postPolicyForm.Expiration, nil
+}
+
+// Form fields which need no condition of the policy, along with
+// fields prefixed with "X-Ignore-". Signature fields are verified by
+// the signature.
+var postPolicyUncheckedFields = []string{
+	"Policy",
+	"File",
+	"Bucket",
+	"X-Amz-Signature",
+	"X-Amz-Algorithm",
+	"X-Amz-Credential",
+	"X-Amz-Date",
+	"X-Amz-Security-Token",
+}
+
+// getPostPolicyContentLengthRange - returns the range of sizes of
+// files allowed by policy of form values, max is 0 if unlimited.
+func getPostPolicyContentLengthRange(formValues map[string]string) (min, max int64, err *probe.Error) {
+	postPolicyForm, err := parsePostPolicyForm(formValues)
+	if err != nil {
+		return 0, 0, err.Trace()
+	}
+	contentLengthRange := postPolicyForm.Conditions.ContentLengthRange
+	return int64(contentLengthRange.Min), int64(contentLengthRange.Max), nil
+}
+
+// checkPostPolicy - apply policy conditions and validate input values,
+// size is the size of the uploaded file, negative if not known yet.
+// Every form field needs to meet the conditions on it, and all fields
+// need a condition.
+func checkPostPolicy(formValues map[string]string, size int64) APIErrorCode {
+	if formValues["X-Amz-Algorithm"] != signV4Algorithm {
+		return ErrSignatureVersionNotSupported
+	}
+	postPolicyForm, err := parsePostPolicyForm(formValues)
 	if err != nil {
 		return ErrMalformedPOSTRequest
 	}
 	if !postPolicyForm.Expiration.After(time.Now().UTC()) {
 		return ErrPolicyAlreadyExpired
 	}
-	if postPolicyForm.Conditions.Policies["$bucket"].Operator == "eq" {
-		if formValues["Bucket"] != postPolicyForm.Conditions.Policies["$bucket"].Value {
-			return ErrMissingFields
-		}
-	}
-	if postPolicyForm.Conditions.Policies["$x-amz-date"].Operator == "eq" {
-		if formValues["X-Amz-Date"] != postPolicyForm.Conditions.Policies["$x-amz-date"].Value {
-			return ErrMissingFields
+	if size >= 0 {
+		if s3Error := checkPostPolicySize(postPolicyForm, size); s3Error != ErrNone {
+			return s3Error
 		}
 	}
-	if postPolicyForm.Conditions.Policies["$Content-Type"].Operator == "starts-with" {
-		if !strings.HasPrefix(formValues["Content-Type"], postPolicyForm.Conditions.Policies["$Content-Type"].Value) {
-			return ErrMissingFields
+	checked := make(map[string]bool)
+	for name, condition := range postPolicyForm.Conditions.Policies {
+		field := http.CanonicalHeaderKey(strings.TrimPrefix(name, "$"))
+		checked[field] = true
+		value := formValues[field]
+		switch condition.Operator {
+		case "eq":
+			if value != condition.Value {
+				return ErrPostPolicyConditionFailed
+			}
+		case "starts-with":
+			if !strings.HasPrefix(value, condition.Value) {
+				return ErrPostPolicyConditionFailed
+			}
 		}
 	}
-	if postPolicyForm.Conditions.Policies["$Content-Type"].Operator == "eq" {
-		if formValues["Content-Type"] != postPolicyForm.Conditions.Policies["$Content-Type"].Value {
-			return ErrMissingFields
+	for field := range formValues {
+		if checked[field] || contains(postPolicyUncheckedFields, field) || strings.HasPrefix(field, "X-Ignore-") {
+			continue
 		}
+		return ErrPostPolicyExtraInputFields
 	}
-	if postPolicyForm.Conditions.Policies["$key"].Operator == "starts-with" {
-		if !strings.HasPrefix(formValues["Key"], postPolicyForm.Conditions.Policies["$key"].Value) {
-			return ErrMissingFields
-		}
+	return ErrNone
+}
+
+// checkPostPolicySize - returns an error unless size is within the
+// content length range of postPolicyForm.
+func checkPostPolicySize(postPolicyForm PostPolicyForm, size int64) APIErrorCode {
+	contentLengthRange := postPolicyForm.Conditions.ContentLengthRange
+	if contentLengthRange.Max > 0 && size > int64(contentLengthRange.Max) {
+		return ErrEntityTooLarge
 	}
-	if postPolicyForm.Conditions.Policies["$key"].Operator == "eq" {
-		if formValues["Key"] != postPolicyForm.Conditions.Policies["$key"].Value {
-			return ErrMissingFields
-		}
+	if size < int64(contentLengthRange.Min) {
+		return ErrEntityTooSmall
 	}
 	return ErrNone
 }
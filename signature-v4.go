@@ -44,6 +44,12 @@ const (
 	yyyymmdd        = "20060102"
 )
 
+// Services requests are signed for.
+const (
+	serviceS3  = "s3"
+	serviceSTS = "sts"
+)
+
 // getCanonicalHeaders generate a list of request headers with their values
 func getCanonicalHeaders(signedHeaders http.Header, host string) string {
 	var headers []string
@@ -114,31 +120,31 @@ func getCanonicalRequest(extractedSignedHeaders http.Header, payload, queryStr,
 }
 
 // getScope generate a string of a specific date, an AWS region, and a service.
-func getScope(t time.Time, region string) string {
+func getScope(t time.Time, region, service string) string {
 	scope := strings.Join([]string{
 		t.Format(yyyymmdd),
 		region,
-		"s3",
+		service,
 		"aws4_request",
 	}, "/")
 	return scope
 }
 
 // getStringToSign a string based on selected query values.
-func getStringToSign(canonicalRequest string, t time.Time, region string) string {
+func getStringToSign(canonicalRequest string, t time.Time, region, service string) string {
 	stringToSign := signV4Algorithm + "\n" + t.Format(iso8601Format) + "\n"
-	stringToSign = stringToSign + getScope(t, region) + "\n"
+	stringToSign = stringToSign + getScope(t, region, service) + "\n"
 	canonicalRequestBytes := sha256.Sum256([]byte(canonicalRequest))
 	stringToSign = stringToSign + hex.EncodeToString(canonicalRequestBytes[:])
 	return stringToSign
 }
 
 // getSigningKey hmac seed to calculate final signature.
-func getSigningKey(secretKey string, t time.Time, region string) []byte {
+func getSigningKey(secretKey string, t time.Time, region, service string) []byte {
 	date := sumHMAC([]byte("AWS4"+secretKey), []byte(t.Format(yyyymmdd)))
 	regionBytes := sumHMAC(date, []byte(region))
-	service := sumHMAC(regionBytes, []byte("s3"))
-	signingKey := sumHMAC(service, []byte("aws4_request"))
+	serviceBytes := sumHMAC(regionBytes, []byte(service))
+	signingKey := sumHMAC(serviceBytes, []byte("aws4_request"))
 	return signingKey
 }
 
@@ -147,13 +153,28 @@ func getSignature(signingKey []byte, stringToSign string) string {
 	return hex.EncodeToString(sumHMAC(signingKey, []byte(stringToSign)))
 }
 
+// presignV4 - returns query of a request presigned with accessKey and
+// secretKey at t, valid for expires. Only the host header is signed,
+// the payload is not.
+func presignV4(method, host, urlPath, accessKey, secretKey, region string, t time.Time, expires time.Duration) string {
+	query := make(url.Values)
+	query.Set("X-Amz-Algorithm", signV4Algorithm)
+	query.Set("X-Amz-Date", t.Format(iso8601Format))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", getSignedHeaders(make(http.Header)))
+	query.Set("X-Amz-Credential", accessKey+"/"+getScope(t, region, serviceS3))
+	encodedQuery := query.Encode()
+
+	canonicalReq := getCanonicalRequest(make(http.Header), "UNSIGNED-PAYLOAD", encodedQuery, urlPath, method, host)
+	stringToSign := getStringToSign(canonicalReq, t, region, serviceS3)
+	signingKey := getSigningKey(secretKey, t, region, serviceS3)
+	return encodedQuery + "&X-Amz-Signature=" + getSignature(signingKey, stringToSign)
+}
+
 // doesPolicySignatureMatch - Verify query headers with post policy
 //     - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
 // returns true if matches, false otherwise. if error is not nil then it is always false
 func doesPolicySignatureMatch(formValues map[string]string) APIErrorCode {
-	// Access credentials.
-	cred := serverConfig.GetCredential()
-
 	// Server region.
 	region := serverConfig.GetRegion()
 
@@ -163,11 +184,22 @@ func doesPolicySignatureMatch(formValues map[string]string) APIErrorCode {
 		return ErrMissingFields
 	}
 
-	// Verify if the access key id matches.
-	if credHeader.accessKey != cred.AccessKeyID {
+	// Verify if the access key id is known.
+	secretKey, ok := globalIAMUsers.GetSecretKey(credHeader.accessKey)
+	if !ok {
 		return ErrInvalidAccessKeyID
 	}
 
+	// Post policies are only signed for s3.
+	if credHeader.scope.service != serviceS3 {
+		return ErrInvalidService
+	}
+
+	// Verify the session token of temporary credentials.
+	if s3Error := globalIAMUsers.CheckSessionToken(credHeader.accessKey, formValues["X-Amz-Security-Token"]); s3Error != ErrNone {
+		return s3Error
+	}
+
 	// Verify if the region is valid.
 	sRegion := credHeader.scope.region
 	if !isValidRegion(sRegion, region) {
@@ -181,7 +213,7 @@ func doesPolicySignatureMatch(formValues map[string]string) APIErrorCode {
 	}
 
 	// Get signing key.
-	signingKey := getSigningKey(cred.SecretAccessKey, t, region)
+	signingKey := getSigningKey(secretKey, t, region, serviceS3)
 
 	// Get signature.
 	newSignature := getSignature(signingKey, formValues["Policy"])
@@ -190,6 +222,10 @@ func doesPolicySignatureMatch(formValues map[string]string) APIErrorCode {
 	if newSignature != formValues["X-Amz-Signature"] {
 		return ErrSignatureDoesNotMatch
 	}
+	// Verify if the signer revoked its policies.
+	if globalPresignMonitor.isRevoked(credHeader.accessKey, t) {
+		return ErrPresignRevoked
+	}
 	return ErrNone
 }
 
@@ -197,9 +233,6 @@ func doesPolicySignatureMatch(formValues map[string]string) APIErrorCode {
 //     - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
 // returns true if matches, false otherwise. if error is not nil then it is always false
 func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validateRegion bool) APIErrorCode {
-	// Access credentials.
-	cred := serverConfig.GetCredential()
-
 	// Server region.
 	region := serverConfig.GetRegion()
 
@@ -212,11 +245,23 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validate
 		return err
 	}
 
-	// Verify if the access key id matches.
-	if preSignValues.Credential.accessKey != cred.AccessKeyID {
+	// Verify if the access key id is known.
+	secretKey, ok := globalIAMUsers.GetSecretKey(preSignValues.Credential.accessKey)
+	if !ok {
 		return ErrInvalidAccessKeyID
 	}
 
+	// Presigned requests are only signed for s3.
+	if preSignValues.Credential.scope.service != serviceS3 {
+		return ErrInvalidService
+	}
+
+	// Verify the session token of temporary credentials.
+	sessionToken := req.URL.Query().Get("X-Amz-Security-Token")
+	if s3Error := globalIAMUsers.CheckSessionToken(preSignValues.Credential.accessKey, sessionToken); s3Error != ErrNone {
+		return s3Error
+	}
+
 	// Verify if region is valid.
 	sRegion := preSignValues.Credential.scope.region
 	// Should validate region, only if region is set. Some operations
@@ -253,7 +298,10 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validate
 	query.Set("X-Amz-Date", t.Format(iso8601Format))
 	query.Set("X-Amz-Expires", strconv.Itoa(expireSeconds))
 	query.Set("X-Amz-SignedHeaders", getSignedHeaders(extractedSignedHeaders))
-	query.Set("X-Amz-Credential", cred.AccessKeyID+"/"+getScope(t, sRegion))
+	query.Set("X-Amz-Credential", preSignValues.Credential.accessKey+"/"+getScope(t, sRegion, serviceS3))
+	if sessionToken != "" {
+		query.Set("X-Amz-Security-Token", sessionToken)
+	}
 
 	// Save other headers available in the request parameters.
 	for k, v := range req.URL.Query() {
@@ -292,13 +340,13 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validate
 	/// Verify finally if signature is same.
 
 	// Get canonical request.
-	presignedCanonicalReq := getCanonicalRequest(extractedSignedHeaders, hashedPayload, encodedQuery, req.URL.Path, req.Method, req.Host)
+	presignedCanonicalReq := getCanonicalRequest(extractedSignedHeaders, hashedPayload, encodedQuery, getSignedURLPath(&req), req.Method, req.Host)
 
 	// Get string to sign from canonical request.
-	presignedStringToSign := getStringToSign(presignedCanonicalReq, t, region)
+	presignedStringToSign := getStringToSign(presignedCanonicalReq, t, region, serviceS3)
 
 	// Get hmac presigned signing key.
-	presignedSigningKey := getSigningKey(cred.SecretAccessKey, t, region)
+	presignedSigningKey := getSigningKey(secretKey, t, region, serviceS3)
 
 	// Get new signature.
 	newSignature := getSignature(presignedSigningKey, presignedStringToSign)
@@ -307,6 +355,10 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validate
 	if req.URL.Query().Get("X-Amz-Signature") != newSignature {
 		return ErrSignatureDoesNotMatch
 	}
+	// Verify if the signer revoked its presigned requests.
+	if globalPresignMonitor.isRevoked(preSignValues.Credential.accessKey, t) {
+		return ErrPresignRevoked
+	}
 	return ErrNone
 }
 
@@ -314,9 +366,12 @@ func doesPresignedSignatureMatch(hashedPayload string, r *http.Request, validate
 //     - http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
 // returns true if matches, false otherwise. if error is not nil then it is always false
 func doesSignatureMatch(hashedPayload string, r *http.Request, validateRegion bool) APIErrorCode {
-	// Access credentials.
-	cred := serverConfig.GetCredential()
+	return doesServiceSignatureMatch(serviceS3, hashedPayload, r, validateRegion)
+}
 
+// doesServiceSignatureMatch - Verify authorization header of a request
+// signed for service, either s3 or sts.
+func doesServiceSignatureMatch(service, hashedPayload string, r *http.Request, validateRegion bool) APIErrorCode {
 	// Server region.
 	region := serverConfig.GetRegion()
 
@@ -335,11 +390,23 @@ func doesSignatureMatch(hashedPayload string, r *http.Request, validateRegion bo
 	// Extract all the signed headers along with its values.
 	extractedSignedHeaders := extractSignedHeaders(signV4Values.SignedHeaders, req.Header)
 
-	// Verify if the access key id matches.
-	if signV4Values.Credential.accessKey != cred.AccessKeyID {
+	// Verify if the access key id is known.
+	secretKey, ok := globalIAMUsers.GetSecretKey(signV4Values.Credential.accessKey)
+	if !ok {
 		return ErrInvalidAccessKeyID
 	}
 
+	// Verify if the request is signed for the service.
+	if signV4Values.Credential.scope.service != service {
+		return ErrInvalidService
+	}
+
+	// Verify the session token of temporary credentials.
+	sessionToken := req.Header.Get("X-Amz-Security-Token")
+	if s3Error := globalIAMUsers.CheckSessionToken(signV4Values.Credential.accessKey, sessionToken); s3Error != ErrNone {
+		return s3Error
+	}
+
 	// Verify if region is valid.
 	sRegion := signV4Values.Credential.scope.region
 	// Should validate region, only if region is set. Some operations
@@ -369,13 +436,13 @@ func doesSignatureMatch(hashedPayload string, r *http.Request, validateRegion bo
 	queryStr := req.URL.Query().Encode()
 
 	// Get canonical request.
-	canonicalRequest := getCanonicalRequest(extractedSignedHeaders, hashedPayload, queryStr, req.URL.Path, req.Method, req.Host)
+	canonicalRequest := getCanonicalRequest(extractedSignedHeaders, hashedPayload, queryStr, getSignedURLPath(&req), req.Method, req.Host)
 
 	// Get string to sign from canonical request.
-	stringToSign := getStringToSign(canonicalRequest, t, region)
+	stringToSign := getStringToSign(canonicalRequest, t, region, service)
 
 	// Get hmac signing key.
-	signingKey := getSigningKey(cred.SecretAccessKey, t, region)
+	signingKey := getSigningKey(secretKey, t, region, service)
 
 	// Calculate signature.
 	newSignature := getSignature(signingKey, stringToSign)
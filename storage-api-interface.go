@@ -16,7 +16,11 @@
 
 package main
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // StorageAPI interface.
 type StorageAPI interface {
@@ -25,11 +29,136 @@ type StorageAPI interface {
 	ListVols() (vols []VolInfo, err error)
 	StatVol(volume string) (vol VolInfo, err error)
 	DeleteVol(volume string) (err error)
+	StatVFS(volume string) (vfsInfo VFSInfo, err error)
 
-	// File operations.
+	// File operations, reads and writes are aborted once ctx is done.
 	ListFiles(volume, prefix, marker string, recursive bool, count int) (files []FileInfo, eof bool, err error)
-	ReadFile(volume string, path string, offset int64) (readCloser io.ReadCloser, err error)
-	CreateFile(volume string, path string) (writeCloser io.WriteCloser, err error)
+	ReadFile(ctx context.Context, volume string, path string, offset int64) (readCloser io.ReadCloser, err error)
+	CreateFile(ctx context.Context, volume string, path string) (writeCloser io.WriteCloser, err error)
 	StatFile(volume string, path string) (file FileInfo, err error)
-	DeleteFile(volume string, path string) (err error)
+	DeleteFile(ctx context.Context, volume string, path string) (err error)
+}
+
+// diskStatusReporter - implemented by storage which reports status of
+// its local disks.
+type diskStatusReporter interface {
+	DiskStatus() []DiskStatus
+}
+
+// quorumReporter - implemented by storage which needs a quorum of its
+// disks online to write.
+type quorumReporter interface {
+	WriteQuorum() int
+}
+
+// readStatsReporter - implemented by storage which reports how its
+// file reads were served.
+type readStatsReporter interface {
+	ReadStats() xlReadStats
+}
+
+// blockCacher - implemented by storage which can cache decoded blocks
+// of hot files in memory.
+type blockCacher interface {
+	SetBlockCache(config blockCacheConfig)
+}
+
+// lockReporter - implemented by storage which locks files, reports
+// the locks currently held.
+type lockReporter interface {
+	Locks() []nameSpaceLockInfo
+}
+
+// lockChecker - implemented by storage which locks files, logs and
+// optionally releases locks held longer than threshold.
+type lockChecker interface {
+	checkLocks(threshold time.Duration, forceRelease bool) int
+}
+
+// fileHealer - implemented by storage which can heal files, missing or
+// outdated parts of the file are rebuilt.
+type fileHealer interface {
+	healFile(volume, path string) error
+}
+
+// fileVerifier - implemented by storage which can verify the parts of
+// a file against their checksums, and repair them.
+type fileVerifier interface {
+	verifyFile(volume, path string, repair bool) (fileVerification, error)
+}
+
+// fileMigrator - implemented by storage which records the format of
+// file metadata, and can rewrite metadata of older formats.
+type fileMigrator interface {
+	migrateFile(volume, path string, dryRun bool) (migrated bool, err error)
+}
+
+// usageReporter - implemented by storage which takes more space than
+// the size of its files, such as for parity.
+type usageReporter interface {
+	usedSize(volume string, size int64) (int64, error)
+}
+
+// volumePlacer - implemented by storage which can pin volumes to a
+// subset of its disks.
+type volumePlacer interface {
+	MakeVolOnDisks(volume string, disks []int) error
+}
+
+// featureLimiter - implemented by storage which can not back some of
+// the optional features of the object layer.
+type featureLimiter interface {
+	UnsupportedFeatures() []string
+}
+
+// stripeSizer - implemented by storage which reads data in stripes,
+// reads aligned to stripes are served without decoding extra data.
+type stripeSizer interface {
+	StripeSize() int64
+}
+
+// parityCreator - implemented by storage which erasure codes files,
+// creates files with a number of parity blocks of their own.
+type parityCreator interface {
+	CreateFileParity(ctx context.Context, volume, path string, parityBlocks int) (io.WriteCloser, error)
+}
+
+// rangeReader - implemented by storage which can read a range of a
+// file without reading data after it.
+type rangeReader interface {
+	ReadFileRange(ctx context.Context, volume, path string, offset, length int64) (io.ReadCloser, error)
+}
+
+// partStager - implemented by storage which can upload parts of a file
+// next to it, and commit them as the file without copying them.
+type partStager interface {
+	// StagePart - returns writer of a part of the file of path, the
+	// part is identified by the token of the writer once closed.
+	StagePart(ctx context.Context, volume, path string) (partWriter, error)
+	// CommitParts - commits the parts of tokens, in order, as the
+	// file of path.
+	CommitParts(ctx context.Context, volume, path string, tokens []string) error
+	// RemoveParts - removes the parts of tokens.
+	RemoveParts(ctx context.Context, volume, path string, tokens []string) error
+}
+
+// partWriter - writer of a part staged by storage.
+type partWriter interface {
+	io.WriteCloser
+	Token() string
+}
+
+// metadataKeeper - implemented by storage which keeps metadata of the
+// object layer with files, committed, healed and removed along with
+// them. Files report it in their FileInfo.
+type metadataKeeper interface {
+	// SetFileMetadata - replaces metadata kept with the file of path.
+	SetFileMetadata(volume, path string, metadata map[string]string) error
+}
+
+// metadataCommitter - implemented by writers of storage which keeps
+// metadata, the file is committed along with metadata set before it
+// is closed.
+type metadataCommitter interface {
+	SetMetadata(metadata map[string]string)
 }
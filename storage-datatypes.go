@@ -30,6 +30,14 @@ type VolInfo struct {
 	FSType  string
 }
 
+// VFSInfo - space of the filesystems holding a volume, in bytes. Free
+// is the size of the largest file which can still be written to them,
+// both are negative if the backend has no fixed capacity.
+type VFSInfo struct {
+	Total int64
+	Free  int64
+}
+
 // FileInfo - file stat information.
 type FileInfo struct {
 	Volume  string
@@ -38,4 +46,18 @@ type FileInfo struct {
 	ModTime time.Time
 	Size    int64
 	Mode    os.FileMode
+	// Metadata of the object layer, kept with the file by storage
+	// which keeps metadata, nil otherwise.
+	Metadata map[string]string
+}
+
+// DiskStatus - online state and space usage of a disk.
+type DiskStatus struct {
+	Path   string `json:"path"`
+	Online bool   `json:"online"`
+	Total  int64  `json:"total"`
+	Used   int64  `json:"used"`
+	Free   int64  `json:"free"`
+	FSType string `json:"fsType,omitempty"`
+	Error  string `json:"error,omitempty"`
 }
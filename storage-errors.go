@@ -16,7 +16,11 @@
 
 package main
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // errDiskFull - cannot create volume or files when disk is full.
 var errDiskFull = errors.New("disk path full")
@@ -43,6 +47,9 @@ var errVolumeAccessDenied = errors.New("volume access denied")
 // errVolumeAccessDenied - cannot access file, insufficient permissions.
 var errFileAccessDenied = errors.New("file access denied")
 
+// errInvalidVolumeName - volume name is not valid for the backend.
+var errInvalidVolumeName = errors.New("invalid volume name")
+
 // errReadQuorum - did not meet read quorum.
 var errReadQuorum = errors.New("I/O error.  did not meet read quorum.")
 
@@ -51,3 +58,86 @@ var errWriteQuorum = errors.New("I/O error.  did not meet write quorum.")
 
 // errDataCorrupt - err data corrupt.
 var errDataCorrupt = errors.New("data likely corrupted, all blocks are zero in length")
+
+// storageErr - error of a storage operation, carries the operation, the
+// disk and the file it failed for. Callers decide on the storage error
+// it wraps, returned by errorCause.
+type storageErr struct {
+	Op string
+	// Index of the disk the operation failed on, -1 if unknown.
+	Disk   int
+	Volume string
+	Path   string
+	Err    error
+}
+
+func (e storageErr) Error() string {
+	target := e.Volume
+	if e.Path != "" {
+		target = e.Volume + "/" + e.Path
+	}
+	if target != "" {
+		target = " " + target
+	}
+	if e.Disk >= 0 {
+		return fmt.Sprintf("%s%s on disk %d: %s", e.Op, target, e.Disk, e.Err)
+	}
+	return fmt.Sprintf("%s%s: %s", e.Op, target, e.Err)
+}
+
+// Unwrap - returns the wrapped error.
+func (e storageErr) Unwrap() error {
+	return e.Err
+}
+
+// newDiskErr - wraps err of operation op on volume and path with index
+// of the disk it failed on, nil errors stay nil.
+func newDiskErr(op string, disk int, volume, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if sErr, ok := err.(storageErr); ok {
+		// Keep the innermost operation, only the disk is not known
+		// by the layers below.
+		sErr.Disk = disk
+		return sErr
+	}
+	return storageErr{Op: op, Disk: disk, Volume: volume, Path: path, Err: err}
+}
+
+// errorCause - returns the error wrapped by storage errors of all
+// layers, to be compared with the storage errors above.
+func errorCause(err error) error {
+	for {
+		sErr, ok := err.(storageErr)
+		if !ok {
+			return err
+		}
+		err = sErr.Err
+	}
+}
+
+// Storage errors recognized across the RPC boundary.
+var rpcStorageErrors = []error{
+	errDiskFull,
+	errFileNotFound,
+	errVolumeExists,
+	errIsNotRegular,
+	errVolumeNotFound,
+	errVolumeNotEmpty,
+	errVolumeAccessDenied,
+	errFileAccessDenied,
+	errRPCAuthFailed,
+}
+
+// rpcErrorCause - returns the storage error an error message received
+// over RPC ends with, nil if none. Storage errors of the remote end may
+// be wrapped.
+func rpcErrorCause(message string) error {
+	for _, err := range rpcStorageErrors {
+		if message == err.Error() || strings.HasSuffix(message, ": "+err.Error()) {
+			return err
+		}
+	}
+	return nil
+}
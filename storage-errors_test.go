@@ -0,0 +1,60 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"net/rpc"
+	"testing"
+)
+
+// Tests storage errors keep their cause across layers and the RPC
+// boundary.
+func TestStorageErrCause(t *testing.T) {
+	// Remote errors are recovered from their text, also if wrapped by
+	// the remote end.
+	remoteErr := newDiskErr("ReadFile", 2, "bucket", "object/part.2", errFileNotFound)
+	if remoteErr.Error() != "ReadFile bucket/object/part.2 on disk 2: file not found" {
+		t.Fatalf("Unexpected error text %s", remoteErr)
+	}
+	err := toStorageErr("ReadFile", "bucket", "object", rpc.ServerError(remoteErr.Error()))
+	if errorCause(err) != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, err)
+	}
+	unknownErr := errors.New("connection reset")
+	if err = toStorageErr("StatVol", "bucket", "", unknownErr); errorCause(err) != unknownErr {
+		t.Fatalf("Expected %s, got %v", unknownErr, err)
+	}
+
+	// Disk index is set on errors wrapped by lower layers.
+	err = newDiskErr("ReadFile", 3, "bucket", "object", toStorageErr("ReadFile", "bucket", "object", rpc.ServerError(errDiskFull.Error())))
+	sErr, ok := err.(storageErr)
+	if !ok || sErr.Disk != 3 || sErr.Op != "ReadFile" || errorCause(err) != errDiskFull {
+		t.Fatalf("Unexpected error %#v", err)
+	}
+	if newDiskErr("ReadFile", 0, "bucket", "object", nil) != nil {
+		t.Fatal("Expected nil error to stay nil")
+	}
+
+	// Wrapped errors map to object layer errors.
+	if _, ok = toObjectErr(err, "bucket", "object").(StorageFull); !ok {
+		t.Fatalf("Expected StorageFull, got %v", toObjectErr(err, "bucket", "object"))
+	}
+	if _, ok = toObjectErr(remoteErr, "bucket", "object").(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", toObjectErr(remoteErr, "bucket", "object"))
+	}
+}
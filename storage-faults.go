@@ -0,0 +1,264 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	slashpath "path"
+	"sync"
+	"time"
+)
+
+// storageOp - operation of a disk faults are injected into.
+type storageOp string
+
+// Operations of a disk, file data reads and writes and committing a
+// created file are operations of their own.
+const (
+	storageOpAny        storageOp = "*"
+	storageOpMakeVol    storageOp = "MakeVol"
+	storageOpListVols   storageOp = "ListVols"
+	storageOpStatVol    storageOp = "StatVol"
+	storageOpDeleteVol  storageOp = "DeleteVol"
+	storageOpListFiles  storageOp = "ListFiles"
+	storageOpReadFile   storageOp = "ReadFile"
+	storageOpCreateFile storageOp = "CreateFile"
+	storageOpStatFile   storageOp = "StatFile"
+	storageOpDeleteFile storageOp = "DeleteFile"
+	storageOpRead       storageOp = "Read"
+	storageOpWrite      storageOp = "Write"
+	storageOpClose      storageOp = "Close"
+)
+
+// storageFault - fault injected into operations of a disk, a fault
+// firing delays the operation by Latency and fails it with Err if set.
+type storageFault struct {
+	Latency time.Duration
+	Err     error
+	// Writes failing write half their data first.
+	PartialWrite bool
+	// Probability of the fault firing, always if 0.
+	Probability float64
+	// Pattern of paths the fault fires for as in path.Match, any if
+	// empty.
+	Path string
+	// Number of times the fault fires, unlimited if 0.
+	Count int
+}
+
+// storageFaults - faults of disks per operation, safe for concurrent
+// use so they can be changed while disks are in use.
+type storageFaults struct {
+	mutex  sync.Mutex
+	faults map[storageOp]*storageFault
+}
+
+func newStorageFaults() *storageFaults {
+	return &storageFaults{faults: make(map[storageOp]*storageFault)}
+}
+
+// Set - injects fault into op, replacing its previous fault.
+func (f *storageFaults) Set(op storageOp, fault storageFault) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults[op] = &fault
+}
+
+// Clear - removes all faults.
+func (f *storageFaults) Clear() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = make(map[storageOp]*storageFault)
+}
+
+// inject - fires the fault of op on path if any, returns whether a
+// write should be partial and the error of the fault.
+func (f *storageFaults) inject(op storageOp, path string) (partial bool, err error) {
+	f.mutex.Lock()
+	fault, ok := f.faults[op]
+	if !ok {
+		op = storageOpAny
+		fault, ok = f.faults[op]
+	}
+	if !ok || !fault.matches(path) {
+		f.mutex.Unlock()
+		return false, nil
+	}
+	if fault.Count > 0 {
+		if fault.Count--; fault.Count == 0 {
+			delete(f.faults, op)
+		}
+	}
+	latency, partial, err := fault.Latency, fault.PartialWrite, fault.Err
+	f.mutex.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return partial, err
+}
+
+// matches - returns true if the fault fires for path.
+func (fault *storageFault) matches(path string) bool {
+	if fault.Path != "" {
+		if matched, e := slashpath.Match(fault.Path, path); e != nil || !matched {
+			return false
+		}
+	}
+	return fault.Probability == 0 || rand.Float64() < fault.Probability
+}
+
+// faultyDisk - disk whose operations, including each read and write of
+// file data, fail as configured by its faults.
+type faultyDisk struct {
+	StorageAPI
+	faults *storageFaults
+}
+
+func newFaultyDisk(disk StorageAPI, faults *storageFaults) faultyDisk {
+	return faultyDisk{StorageAPI: disk, faults: faults}
+}
+
+// MakeVol - makes a volume unless faulted.
+func (d faultyDisk) MakeVol(volume string) error {
+	if _, e := d.faults.inject(storageOpMakeVol, volume); e != nil {
+		return e
+	}
+	return d.StorageAPI.MakeVol(volume)
+}
+
+// ListVols - lists volumes unless faulted.
+func (d faultyDisk) ListVols() ([]VolInfo, error) {
+	if _, e := d.faults.inject(storageOpListVols, ""); e != nil {
+		return nil, e
+	}
+	return d.StorageAPI.ListVols()
+}
+
+// StatVol - stats a volume unless faulted.
+func (d faultyDisk) StatVol(volume string) (VolInfo, error) {
+	if _, e := d.faults.inject(storageOpStatVol, volume); e != nil {
+		return VolInfo{}, e
+	}
+	return d.StorageAPI.StatVol(volume)
+}
+
+// DeleteVol - deletes a volume unless faulted.
+func (d faultyDisk) DeleteVol(volume string) error {
+	if _, e := d.faults.inject(storageOpDeleteVol, volume); e != nil {
+		return e
+	}
+	return d.StorageAPI.DeleteVol(volume)
+}
+
+// ListFiles - lists files unless faulted.
+func (d faultyDisk) ListFiles(volume, prefix, marker string, recursive bool, count int) ([]FileInfo, bool, error) {
+	if _, e := d.faults.inject(storageOpListFiles, slashpath.Join(volume, prefix)); e != nil {
+		return nil, false, e
+	}
+	return d.StorageAPI.ListFiles(volume, prefix, marker, recursive, count)
+}
+
+// StatFile - stats a file unless faulted.
+func (d faultyDisk) StatFile(volume, path string) (FileInfo, error) {
+	if _, e := d.faults.inject(storageOpStatFile, slashpath.Join(volume, path)); e != nil {
+		return FileInfo{}, e
+	}
+	return d.StorageAPI.StatFile(volume, path)
+}
+
+// DeleteFile - deletes a file unless faulted.
+func (d faultyDisk) DeleteFile(ctx context.Context, volume, path string) error {
+	if _, e := d.faults.inject(storageOpDeleteFile, slashpath.Join(volume, path)); e != nil {
+		return e
+	}
+	return d.StorageAPI.DeleteFile(ctx, volume, path)
+}
+
+// ReadFile - opens a file unless faulted, its reads can be faulted.
+func (d faultyDisk) ReadFile(ctx context.Context, volume, path string, offset int64) (io.ReadCloser, error) {
+	filePath := slashpath.Join(volume, path)
+	if _, e := d.faults.inject(storageOpReadFile, filePath); e != nil {
+		return nil, e
+	}
+	r, e := d.StorageAPI.ReadFile(ctx, volume, path, offset)
+	if e != nil {
+		return nil, e
+	}
+	return faultyReader{ReadCloser: r, faults: d.faults, path: filePath}, nil
+}
+
+// CreateFile - creates a file unless faulted, its writes and commit
+// can be faulted.
+func (d faultyDisk) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	filePath := slashpath.Join(volume, path)
+	if _, e := d.faults.inject(storageOpCreateFile, filePath); e != nil {
+		return nil, e
+	}
+	w, e := d.StorageAPI.CreateFile(ctx, volume, path)
+	if e != nil {
+		return nil, e
+	}
+	return &faultyWriter{WriteCloser: w, faults: d.faults, path: filePath}, nil
+}
+
+// faultyReader - file data reads which can be faulted.
+type faultyReader struct {
+	io.ReadCloser
+	faults *storageFaults
+	path   string
+}
+
+func (r faultyReader) Read(p []byte) (int, error) {
+	if _, e := r.faults.inject(storageOpRead, r.path); e != nil {
+		return 0, e
+	}
+	return r.ReadCloser.Read(p)
+}
+
+// faultyWriter - file data writes which can be faulted, a faulted
+// commit removes the file.
+type faultyWriter struct {
+	io.WriteCloser
+	faults *storageFaults
+	path   string
+}
+
+func (w *faultyWriter) Write(p []byte) (int, error) {
+	partial, e := w.faults.inject(storageOpWrite, w.path)
+	if e == nil {
+		return w.WriteCloser.Write(p)
+	}
+	if !partial {
+		return 0, e
+	}
+	n, pErr := w.WriteCloser.Write(p[:len(p)/2])
+	if pErr != nil {
+		return n, pErr
+	}
+	return n, e
+}
+
+func (w *faultyWriter) Close() error {
+	if _, e := w.faults.inject(storageOpClose, w.path); e != nil {
+		safeCloseAndRemove(w.WriteCloser)
+		return e
+	}
+	return w.WriteCloser.Close()
+}
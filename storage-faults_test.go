@@ -0,0 +1,257 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var errTestFault = errors.New("injected test fault")
+
+// newFaultyXL - returns XL on totalDisks temporary disks with a
+// volume "bucket", and the faults of each disk. Call cleanup to remove
+// the disks.
+func newFaultyXL(t *testing.T, totalDisks int) (xl *XL, faults []*storageFaults, cleanup func()) {
+	var disks []string
+	cleanup = func() {
+		for _, disk := range disks {
+			os.RemoveAll(disk)
+		}
+	}
+	for i := 0; i < totalDisks; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			cleanup()
+			t.Fatal(e)
+		}
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		cleanup()
+		t.Fatal(e)
+	}
+	xl = storage.(*XL)
+	for index, disk := range xl.storageDisks {
+		faults = append(faults, newStorageFaults())
+		xl.storageDisks[index] = newFaultyDisk(disk, faults[index])
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		cleanup()
+		t.Fatal(e)
+	}
+	return xl, faults, cleanup
+}
+
+// writeFaultyXL - writes data to path in "bucket" of xl.
+func writeFaultyXL(xl *XL, path string, data []byte) error {
+	w, e := xl.CreateFile(context.Background(), "bucket", path)
+	if e != nil {
+		return e
+	}
+	if _, e = w.Write(data); e != nil {
+		safeCloseAndRemove(w)
+		return e
+	}
+	return w.Close()
+}
+
+// readFaultyXL - reads path in "bucket" of xl.
+func readFaultyXL(xl *XL, path string) ([]byte, error) {
+	r, e := xl.ReadFile(context.Background(), "bucket", path, 0)
+	if e != nil {
+		return nil, e
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// testFaultyData - returns data spanning more than one erasure block.
+func testFaultyData() []byte {
+	data := make([]byte, erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// Tests faults fire for their operations and paths, as many times as
+// configured.
+func TestFaultyDisk(t *testing.T) {
+	path, e := ioutil.TempDir("", "minio-faults-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(path)
+	fsDisk, e := newFS(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	faults := newStorageFaults()
+	disk := newFaultyDisk(fsDisk, faults)
+	if e = disk.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+
+	// Faults fire Count times for paths matching Path.
+	faults.Set(storageOpStatFile, storageFault{Err: errTestFault, Path: "bucket/a*", Count: 2})
+	testCases := []struct {
+		file     string
+		expected error
+	}{
+		{"b", errFileNotFound},
+		{"a", errTestFault},
+		{"a", errTestFault},
+		{"a", errFileNotFound},
+	}
+	for i, testCase := range testCases {
+		if _, e = disk.StatFile("bucket", testCase.file); e != testCase.expected {
+			t.Fatalf("Test %d: expected %v, got %v", i+1, testCase.expected, e)
+		}
+	}
+
+	// Faults of any operation apply unless the operation has its own.
+	faults.Set(storageOpAny, storageFault{Err: errTestFault})
+	faults.Set(storageOpCreateFile, storageFault{Latency: 10 * time.Millisecond})
+	if _, e = disk.ListVols(); e != errTestFault {
+		t.Fatalf("Expected %v, got %v", errTestFault, e)
+	}
+	start := time.Now()
+	w, e := disk.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Fatal("Expected CreateFile to be delayed")
+	}
+
+	// Partial writes write half their data, failed commits remove
+	// the file.
+	faults.Set(storageOpWrite, storageFault{Err: errTestFault, PartialWrite: true})
+	if n, e := w.Write([]byte("hello world!")); e != errTestFault || n != 6 {
+		t.Fatalf("Expected 6 bytes written and %v, got %d and %v", errTestFault, n, e)
+	}
+	if e = w.Close(); e != errTestFault {
+		t.Fatalf("Expected %v, got %v", errTestFault, e)
+	}
+	faults.Clear()
+	if _, e = disk.StatFile("bucket", "object"); e != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+
+}
+
+// Tests CreateFile tolerates faulted disks up to its write quorum.
+func TestXLCreateFileFaults(t *testing.T) {
+	xl, faults, cleanup := newFaultyXL(t, 8)
+	defer cleanup()
+	data := testFaultyData()
+
+	faults[3].Set(storageOpCreateFile, storageFault{Err: errTestFault})
+	if e := writeFaultyXL(xl, "one", data); e != nil {
+		t.Fatal(e)
+	}
+	if readData, e := readFaultyXL(xl, "one"); e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	faults[5].Set(storageOpCreateFile, storageFault{Err: errTestFault})
+	if e := writeFaultyXL(xl, "two", data); e != errWriteQuorum {
+		t.Fatalf("Expected %v, got %v", errWriteQuorum, e)
+	}
+
+	// Writes failing on any disk fail the file, nothing is committed.
+	faults[3].Clear()
+	faults[5].Clear()
+	faults[5].Set(storageOpWrite, storageFault{Err: errTestFault, PartialWrite: true})
+	if e := writeFaultyXL(xl, "partial", data); e != errTestFault {
+		t.Fatalf("Expected %v, got %v", errTestFault, e)
+	}
+	faults[5].Clear()
+	if _, e := xl.StatFile("bucket", "partial"); e != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+}
+
+// Tests ReadFile tolerates faulted disks up to its read quorum.
+func TestXLReadFileFaults(t *testing.T) {
+	xl, faults, cleanup := newFaultyXL(t, 4)
+	defer cleanup()
+	data := testFaultyData()
+	if e := writeFaultyXL(xl, "object", data); e != nil {
+		t.Fatal(e)
+	}
+
+	// Parts failing to be read are reconstructed from the others, as
+	// long as their metadata is read.
+	detachMetadata(t, xl, 0, "object")
+	detachMetadata(t, xl, 1, "object")
+	faults[0].Set(storageOpRead, storageFault{Err: errTestFault, Path: "bucket/object/part.*"})
+	faults[1].Set(storageOpReadFile, storageFault{Err: errTestFault, Path: "bucket/object/part.1"})
+	if readData, e := readFaultyXL(xl, "object"); e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	// Fewer than a read quorum of readable metadata fail the read.
+	faults[0].Clear()
+	faults[1].Clear()
+	faults[2].Set(storageOpReadFile, storageFault{Err: errTestFault, Path: "bucket/object/part.2"})
+	faults[3].Set(storageOpReadFile, storageFault{Err: errTestFault, Path: "bucket/object/part.3"})
+	if _, e := readFaultyXL(xl, "object"); e == nil {
+		t.Fatal("Expected read to fail without a read quorum")
+	} else if quorumErr, ok := e.(readQuorumError); !ok || quorumErr.Available != 2 || len(quorumErr.Disks) != 2 {
+		t.Fatalf("Unexpected error %#v", e)
+	}
+}
+
+// Tests heal rebuilds parts missing on a disk once its faults are
+// cleared.
+func TestXLHealFaults(t *testing.T) {
+	xl, faults, cleanup := newFaultyXL(t, 4)
+	defer cleanup()
+	data := testFaultyData()
+	if e := writeFaultyXL(xl, "object", data); e != nil {
+		t.Fatal(e)
+	}
+	faults[2].Set(storageOpDeleteFile, storageFault{Err: errTestFault, Count: 1})
+	if e := xl.storageDisks[2].DeleteFile(context.Background(), "bucket", "object/part.2"); e != errTestFault {
+		t.Fatalf("Expected %v, got %v", errTestFault, e)
+	}
+	if e := xl.storageDisks[2].DeleteFile(context.Background(), "bucket", "object/part.2"); e != nil {
+		t.Fatal(e)
+	}
+
+	// Healed parts fail to be written to a faulted disk.
+	faults[2].Set(storageOpCreateFile, storageFault{Err: errTestFault})
+	if e := xl.healFile("bucket", "object"); e == nil {
+		t.Fatal("Expected heal to fail")
+	}
+	faults[2].Clear()
+	if e := xl.healFile("bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+	verification, e := xl.verifyFile("bucket", "object", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
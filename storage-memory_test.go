@@ -0,0 +1,298 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStorage - StorageAPI keeping files in memory, measures the
+// erasure layer without disk I/O.
+type memStorage struct {
+	mutex sync.RWMutex
+	vols  map[string]*memVolume
+}
+
+type memVolume struct {
+	created time.Time
+	files   map[string]memFile
+}
+
+type memFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{vols: make(map[string]*memVolume)}
+}
+
+func (m *memStorage) MakeVol(volume string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.vols[volume]; ok {
+		return errVolumeExists
+	}
+	m.vols[volume] = &memVolume{created: time.Now().UTC(), files: make(map[string]memFile)}
+	return nil
+}
+
+func (m *memStorage) ListVols() ([]VolInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	var vols []VolInfo
+	for name, vol := range m.vols {
+		vols = append(vols, VolInfo{Name: name, Created: vol.created})
+	}
+	sort.Slice(vols, func(i, j int) bool { return vols[i].Name < vols[j].Name })
+	return vols, nil
+}
+
+func (m *memStorage) StatVol(volume string) (VolInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return VolInfo{}, errVolumeNotFound
+	}
+	return VolInfo{Name: volume, Created: vol.created}, nil
+}
+
+func (m *memStorage) StatVFS(volume string) (VFSInfo, error) {
+	return VFSInfo{Total: -1, Free: -1}, nil
+}
+
+func (m *memStorage) DeleteVol(volume string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return errVolumeNotFound
+	}
+	if len(vol.files) > 0 {
+		return errVolumeNotEmpty
+	}
+	delete(m.vols, volume)
+	return nil
+}
+
+// ListFiles - lists files after marker starting with prefix, files
+// below prefix are listed as their directories unless recursive.
+func (m *memStorage) ListFiles(volume, prefix, marker string, recursive bool, count int) ([]FileInfo, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return nil, true, errVolumeNotFound
+	}
+	var names []string
+	for name := range vol.files {
+		if strings.HasPrefix(name, prefix) && name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var files []FileInfo
+	for _, name := range names {
+		file := FileInfo{Volume: volume, Name: name}
+		if i := strings.Index(name[len(prefix):], "/"); i >= 0 && !recursive {
+			file.Name, file.Mode = name[:len(prefix)+i+1], os.ModeDir
+		} else {
+			file.Size, file.ModTime = int64(len(vol.files[name].data)), vol.files[name].modTime
+		}
+		if len(files) > 0 && files[len(files)-1].Name == file.Name {
+			continue
+		}
+		if count > 0 && len(files) == count {
+			return files, false, nil
+		}
+		files = append(files, file)
+	}
+	return files, true, nil
+}
+
+func (m *memStorage) ReadFile(ctx context.Context, volume, path string, offset int64) (io.ReadCloser, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return nil, errVolumeNotFound
+	}
+	file, ok := vol.files[path]
+	if !ok {
+		return nil, errFileNotFound
+	}
+	if offset > int64(len(file.data)) {
+		return nil, errInvalidArgument
+	}
+	return ioutil.NopCloser(bytes.NewReader(file.data[offset:])), nil
+}
+
+// CreateFile - returns a writer of a file saved once closed.
+func (m *memStorage) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	if _, err := m.StatVol(volume); err != nil {
+		return nil, err
+	}
+	return &memWriter{storage: m, volume: volume, path: path}, nil
+}
+
+func (m *memStorage) StatFile(volume, path string) (FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return FileInfo{}, errVolumeNotFound
+	}
+	file, ok := vol.files[path]
+	if !ok {
+		return FileInfo{}, errFileNotFound
+	}
+	return FileInfo{Volume: volume, Name: path, Size: int64(len(file.data)), ModTime: file.modTime}, nil
+}
+
+func (m *memStorage) DeleteFile(ctx context.Context, volume, path string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	vol, ok := m.vols[volume]
+	if !ok {
+		return errVolumeNotFound
+	}
+	if _, ok = vol.files[path]; !ok {
+		return errFileNotFound
+	}
+	delete(vol.files, path)
+	return nil
+}
+
+// memWriter - buffers data of a file until closed.
+type memWriter struct {
+	storage *memStorage
+	volume  string
+	path    string
+	buffer  bytes.Buffer
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	return w.buffer.Write(p)
+}
+
+func (w *memWriter) Close() error {
+	w.storage.mutex.Lock()
+	defer w.storage.mutex.Unlock()
+	vol, ok := w.storage.vols[w.volume]
+	if !ok {
+		return errVolumeNotFound
+	}
+	vol.files[w.path] = memFile{data: w.buffer.Bytes(), modTime: time.Now().UTC()}
+	return nil
+}
+
+// newMemXL - returns XL on totalDisks in memory disks with a volume
+// "bucket".
+func newMemXL(tb testing.TB, totalDisks int) *XL {
+	diskPaths := make([]string, totalDisks)
+	storageDisks := make([]StorageAPI, totalDisks)
+	for index := range storageDisks {
+		diskPaths[index] = fmt.Sprintf("mem-%d", index)
+		storageDisks[index] = newMemStorage()
+	}
+	xl, e := newXLDisks(diskPaths, storageDisks)
+	if e != nil {
+		tb.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		tb.Fatal(e)
+	}
+	return xl
+}
+
+// Tests files round trip through XL on in memory disks.
+func TestMemXL(t *testing.T) {
+	xl := newMemXL(t, 4)
+	for i, size := range []int{0, 100, erasureBlockSize + 1000} {
+		data := bytes.Repeat([]byte("a"), size)
+		path := fmt.Sprintf("dir/object-%d", i)
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		r, e := xl.ReadFile(context.Background(), "bucket", path, 0)
+		if e != nil {
+			t.Fatal(e)
+		}
+		readData, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil || !bytes.Equal(readData, data) {
+			t.Fatalf("Test %d: Unexpected data read, %v", i+1, e)
+		}
+	}
+	files, eof, e := xl.ListFiles("bucket", "dir/", "", true, 10)
+	if e != nil || !eof || len(files) != 3 || files[0].Name != "dir/object-0" {
+		t.Fatalf("Unexpected files %+v, %v, %v", files, eof, e)
+	}
+}
+
+// Benchmarks writing and reading back files through XL on in memory
+// disks, inlined files and erasure coded ones of one or more blocks.
+func BenchmarkXLMemoryRoundTrip(b *testing.B) {
+	for _, totalDisks := range []int{4, 8, 16} {
+		xl := newMemXL(b, totalDisks)
+		for _, size := range []int{4 * 1024, 1024 * 1024, 4 * erasureBlockSize} {
+			data := bytes.Repeat([]byte("a"), size)
+			b.Run(fmt.Sprintf("disks-%d/size-%dKiB", totalDisks, size/1024), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					w, e := xl.CreateFile(context.Background(), "bucket", "object")
+					if e != nil {
+						b.Fatal(e)
+					}
+					if _, e = w.Write(data); e != nil {
+						b.Fatal(e)
+					}
+					if e = w.Close(); e != nil {
+						b.Fatal(e)
+					}
+					r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+					if e != nil {
+						b.Fatal(e)
+					}
+					if _, e = io.Copy(ioutil.Discard, r); e != nil {
+						b.Fatal(e)
+					}
+					r.Close()
+				}
+			})
+		}
+	}
+}
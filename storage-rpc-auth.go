@@ -0,0 +1,216 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum clock skew between two nodes, requests signed outside
+	// of it are rejected. Nonces are remembered for as long.
+	rpcAuthMaxSkew = 5 * time.Minute
+
+	// Id of the secret derived from server credentials, used when no
+	// secrets are configured.
+	rpcCredentialSecretID = "credential"
+
+	// Headers carrying authentication of storage upload and download
+	// requests.
+	rpcSecretIDHeader  = "X-Minio-Rpc-Secret-Id"
+	rpcTimestampHeader = "X-Minio-Rpc-Timestamp"
+	rpcNonceHeader     = "X-Minio-Rpc-Nonce"
+	rpcSignatureHeader = "X-Minio-Rpc-Signature"
+)
+
+// errRPCAuthFailed - returned to peers whose requests fail
+// authentication, the reason is only logged by the server.
+var errRPCAuthFailed = errors.New("RPC authentication failed")
+
+// Reasons of failed authentication.
+var (
+	errRPCAuthUnknownSecret = errors.New("RPC request is signed with an unknown secret")
+	errRPCAuthSkewed        = errors.New("RPC request timestamp is too skewed")
+	errRPCAuthReplayed      = errors.New("RPC request nonce was already seen")
+	errRPCAuthSignature     = errors.New("RPC request signature does not match")
+)
+
+// rpcSecret - shared secret of the cluster, identified by its id.
+type rpcSecret struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// rpcAuth - storage RPC authentication configuration. Requests are
+// signed with the first secret, all secrets are accepted. To rotate,
+// append the new secret on all nodes, move it first on all nodes and
+// finally remove the old one.
+type rpcAuth struct {
+	Secrets []rpcSecret `json:"secrets"`
+}
+
+// newRPCAuthConfig - RPC authentication configuration for fresh and
+// migrated configs, secret is derived from credentials until secrets
+// are configured.
+func newRPCAuthConfig() rpcAuth {
+	return rpcAuth{Secrets: []rpcSecret{}}
+}
+
+// credentialRPCSecret - returns secret derived from server credentials,
+// shared by nodes with the same credentials.
+func credentialRPCSecret(cred credential) rpcSecret {
+	mac := hmac.New(sha256.New, []byte(cred.SecretAccessKey))
+	mac.Write([]byte("minio-storage-rpc:" + cred.AccessKeyID))
+	return rpcSecret{
+		ID:     rpcCredentialSecretID,
+		Secret: hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+// RPCAuthArgs - authentication of a storage rpc request.
+type RPCAuthArgs struct {
+	SecretID  string
+	Timestamp time.Time
+	Nonce     string
+	Signature string
+}
+
+// rpcStringToSign - returns string signed for method and its args.
+func rpcStringToSign(method string, timestamp time.Time, nonce string, args []string) string {
+	return strings.Join(append([]string{
+		method,
+		timestamp.UTC().Format(time.RFC3339Nano),
+		nonce,
+	}, args...), "\n")
+}
+
+// rpcSignature - returns hex encoded signature of string to sign.
+func rpcSignature(secret rpcSecret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret.Secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRPC - returns authentication of a request for method with args,
+// signed with secret.
+func signRPC(secret rpcSecret, method string, args ...string) RPCAuthArgs {
+	nonceBytes := make([]byte, 16)
+	rand.Read(nonceBytes)
+	auth := RPCAuthArgs{
+		SecretID:  secret.ID,
+		Timestamp: time.Now().UTC(),
+		Nonce:     hex.EncodeToString(nonceBytes),
+	}
+	auth.Signature = rpcSignature(secret, rpcStringToSign(method, auth.Timestamp, auth.Nonce, args))
+	return auth
+}
+
+// setHeaders - sets authentication on headers of a HTTP request.
+func (auth RPCAuthArgs) setHeaders(header http.Header) {
+	header.Set(rpcSecretIDHeader, auth.SecretID)
+	header.Set(rpcTimestampHeader, auth.Timestamp.Format(time.RFC3339Nano))
+	header.Set(rpcNonceHeader, auth.Nonce)
+	header.Set(rpcSignatureHeader, auth.Signature)
+}
+
+// rpcAuthFromHeaders - returns authentication set on headers of a HTTP
+// request, zero timestamp if it is missing or malformed.
+func rpcAuthFromHeaders(header http.Header) RPCAuthArgs {
+	timestamp, _ := time.Parse(time.RFC3339Nano, header.Get(rpcTimestampHeader))
+	return RPCAuthArgs{
+		SecretID:  header.Get(rpcSecretIDHeader),
+		Timestamp: timestamp,
+		Nonce:     header.Get(rpcNonceHeader),
+		Signature: header.Get(rpcSignatureHeader),
+	}
+}
+
+// rpcAuthenticator - verifies storage rpc requests, remembering the
+// nonces of recent requests to reject replays.
+type rpcAuthenticator struct {
+	mutex *sync.Mutex
+	// Nonces seen, mapped to the time they can be forgotten.
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+// newRPCAuthenticator - returns an authenticator which has seen no
+// nonces yet.
+func newRPCAuthenticator() *rpcAuthenticator {
+	return &rpcAuthenticator{
+		mutex:     &sync.Mutex{},
+		nonces:    make(map[string]time.Time),
+		lastPrune: time.Now().UTC(),
+	}
+}
+
+// Verify - verifies auth of a request for method with args is signed
+// by any of secrets, recently and only once.
+func (a *rpcAuthenticator) Verify(secrets []rpcSecret, auth RPCAuthArgs, method string, args ...string) error {
+	// Secrets derived from the current and the previous server
+	// credential share their id.
+	var matching []rpcSecret
+	for _, secret := range secrets {
+		if secret.ID == auth.SecretID {
+			matching = append(matching, secret)
+		}
+	}
+	if len(matching) == 0 {
+		return errRPCAuthUnknownSecret
+	}
+	now := time.Now().UTC()
+	if auth.Timestamp.Before(now.Add(-rpcAuthMaxSkew)) || auth.Timestamp.After(now.Add(rpcAuthMaxSkew)) {
+		return errRPCAuthSkewed
+	}
+	stringToSign := rpcStringToSign(method, auth.Timestamp, auth.Nonce, args)
+	matched := false
+	for _, secret := range matching {
+		expected := rpcSignature(secret, stringToSign)
+		if hmac.Equal([]byte(expected), []byte(auth.Signature)) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return errRPCAuthSignature
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	// Forget nonces of requests which would be rejected as skewed.
+	if now.Sub(a.lastPrune) > rpcAuthMaxSkew {
+		for nonce, expiry := range a.nonces {
+			if expiry.Before(now) {
+				delete(a.nonces, nonce)
+			}
+		}
+		a.lastPrune = now
+	}
+	if _, ok := a.nonces[auth.Nonce]; ok {
+		return errRPCAuthReplayed
+	}
+	a.nonces[auth.Nonce] = auth.Timestamp.Add(rpcAuthMaxSkew)
+	return nil
+}
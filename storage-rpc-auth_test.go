@@ -0,0 +1,156 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	router "github.com/gorilla/mux"
+)
+
+// Tests verification of signed storage rpc requests.
+func TestRPCAuthenticator(t *testing.T) {
+	oldSecret := rpcSecret{ID: "old", Secret: "old-secret"}
+	newSecret := rpcSecret{ID: "new", Secret: "new-secret"}
+	skewed := signRPC(newSecret, "Storage.DeleteFileHandler", "bucket", "object")
+	skewed.Timestamp = skewed.Timestamp.Add(-2 * rpcAuthMaxSkew)
+	skewed.Signature = rpcSignature(newSecret, rpcStringToSign("Storage.DeleteFileHandler",
+		skewed.Timestamp, skewed.Nonce, []string{"bucket", "object"}))
+
+	auth := newRPCAuthenticator()
+	replayed := signRPC(newSecret, "Storage.DeleteFileHandler", "bucket", "object")
+	if err := auth.Verify([]rpcSecret{newSecret}, replayed, "Storage.DeleteFileHandler", "bucket", "object"); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		secrets []rpcSecret
+		auth    RPCAuthArgs
+		args    []string
+		err     error
+	}{
+		// Old secret is accepted while rotating.
+		{[]rpcSecret{newSecret, oldSecret}, signRPC(oldSecret, "Storage.DeleteFileHandler", "bucket", "object"), []string{"bucket", "object"}, nil},
+		// Removed secret is rejected.
+		{[]rpcSecret{newSecret}, signRPC(oldSecret, "Storage.DeleteFileHandler", "bucket", "object"), []string{"bucket", "object"}, errRPCAuthUnknownSecret},
+		// Signature covers args.
+		{[]rpcSecret{newSecret}, signRPC(newSecret, "Storage.DeleteFileHandler", "bucket", "object"), []string{"bucket", "other"}, errRPCAuthSignature},
+		{[]rpcSecret{newSecret}, RPCAuthArgs{SecretID: "new", Timestamp: time.Now().UTC()}, []string{"bucket", "object"}, errRPCAuthSignature},
+		{[]rpcSecret{newSecret}, skewed, []string{"bucket", "object"}, errRPCAuthSkewed},
+		{[]rpcSecret{newSecret}, replayed, []string{"bucket", "object"}, errRPCAuthReplayed},
+	}
+	for i, testCase := range testCases {
+		err := auth.Verify(testCase.secrets, testCase.auth, "Storage.DeleteFileHandler", testCase.args...)
+		if err != testCase.err {
+			t.Fatalf("Test %d: Expected %v, got %v", i+1, testCase.err, err)
+		}
+	}
+}
+
+// Tests peers only serve storage requests signed with a shared secret.
+func TestStorageRPCAuth(t *testing.T) {
+	savedConfig := serverConfig
+	defer func() {
+		serverConfig = savedConfig
+	}()
+	serverConfig = &serverConfigV5{
+		Credential: mustGenAccessKeys(),
+		RPC:        newRPCAuthConfig(),
+		rwMutex:    &sync.RWMutex{},
+	}
+
+	disk, e := ioutil.TempDir("", "minio-rpc-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(disk)
+	fs, e := newFS(disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	mux := router.NewRouter()
+	registerStorageRPCRouter(mux, newStorageRPC(fs))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	netAddr := strings.TrimPrefix(server.URL, "http://")
+
+	peer, e := newNetworkFS(netAddr + ":" + disk)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = peer.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	w, e := peer.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write([]byte("hello")); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	r, e := peer.ReadFile(context.Background(), "bucket", "object", 1)
+	if e != nil {
+		t.Fatal(e)
+	}
+	data, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || string(data) != "ello" {
+		t.Fatalf("Unexpected data %q, %v", data, e)
+	}
+
+	// Requests without a valid signature are rejected.
+	client, e := rpc.DialHTTPPath("tcp", netAddr, storageRPCPath)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer client.Close()
+	e = client.Call("Storage.DeleteFileHandler", DeleteFileArgs{
+		Vol:  "bucket",
+		Path: "object",
+	}, &GenericReply{})
+	if e == nil || errorCause(toStorageErr("DeleteFile", "bucket", "object", e)) != errRPCAuthFailed {
+		t.Fatalf("Expected %s, got %v", errRPCAuthFailed, e)
+	}
+	e = client.Call("Storage.DeleteFileHandler", DeleteFileArgs{
+		Auth: signRPC(rpcSecret{ID: rpcCredentialSecretID, Secret: "guessed"}, "Storage.DeleteFileHandler", "bucket", "object"),
+		Vol:  "bucket",
+		Path: "object",
+	}, &GenericReply{})
+	if e == nil || errorCause(toStorageErr("DeleteFile", "bucket", "object", e)) != errRPCAuthFailed {
+		t.Fatalf("Expected %s, got %v", errRPCAuthFailed, e)
+	}
+	if _, e = fs.StatFile("bucket", "object"); e != nil {
+		t.Fatalf("Expected object to survive unsigned delete, got %v", e)
+	}
+
+	// Peers keep working with rotated secrets.
+	serverConfig.SetRPC(rpcAuth{Secrets: []rpcSecret{{ID: "rotated", Secret: "rotated-secret"}}})
+	if e = peer.DeleteFile(context.Background(), "bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+}
@@ -20,7 +20,15 @@ package main
 type GenericReply struct{}
 
 // GenericArgs generic rpc args.
-type GenericArgs struct{}
+type GenericArgs struct {
+	Auth RPCAuthArgs
+}
+
+// VolArgs volume rpc args.
+type VolArgs struct {
+	Auth RPCAuthArgs
+	Vol  string
+}
 
 // ListVolsReply list vols rpc reply.
 type ListVolsReply struct {
@@ -29,6 +37,7 @@ type ListVolsReply struct {
 
 // ListFilesArgs list file args.
 type ListFilesArgs struct {
+	Auth      RPCAuthArgs
 	Vol       string
 	Prefix    string
 	Marker    string
@@ -44,12 +53,14 @@ type ListFilesReply struct {
 
 // StatFileArgs stat file args.
 type StatFileArgs struct {
+	Auth RPCAuthArgs
 	Vol  string
 	Path string
 }
 
 // DeleteFileArgs delete file args.
 type DeleteFileArgs struct {
+	Auth RPCAuthArgs
 	Vol  string
 	Path string
 }
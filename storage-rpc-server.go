@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/rpc"
@@ -14,16 +15,33 @@ import (
 // disk over a network.
 type storageServer struct {
 	storage StorageAPI
+	auth    *rpcAuthenticator
+}
+
+// authenticate - verifies request for method with args is signed by a
+// peer of the cluster.
+func (s *storageServer) authenticate(auth RPCAuthArgs, method string, args ...string) error {
+	if err := s.auth.Verify(serverConfig.GetRPCSecrets(), auth, method, args...); err != nil {
+		log.WithFields(logrus.Fields{
+			"method":   method,
+			"secretID": auth.SecretID,
+		}).Errorf("Storage RPC authentication failed with %s", err)
+		return errRPCAuthFailed
+	}
+	return nil
 }
 
 /// Volume operations handlers
 
 // MakeVolHandler - make vol handler is rpc wrapper for MakeVol operation.
-func (s *storageServer) MakeVolHandler(arg *string, reply *GenericReply) error {
-	err := s.storage.MakeVol(*arg)
+func (s *storageServer) MakeVolHandler(arg *VolArgs, reply *GenericReply) error {
+	if err := s.authenticate(arg.Auth, "Storage.MakeVolHandler", arg.Vol); err != nil {
+		return err
+	}
+	err := s.storage.MakeVol(arg.Vol)
 	if err != nil {
 		log.WithFields(logrus.Fields{
-			"volume": *arg,
+			"volume": arg.Vol,
 		}).Debugf("MakeVol failed with error %s", err)
 		return err
 	}
@@ -31,7 +49,10 @@ func (s *storageServer) MakeVolHandler(arg *string, reply *GenericReply) error {
 }
 
 // ListVolsHandler - list vols handler is rpc wrapper for ListVols operation.
-func (s *storageServer) ListVolsHandler(arg *string, reply *ListVolsReply) error {
+func (s *storageServer) ListVolsHandler(arg *GenericArgs, reply *ListVolsReply) error {
+	if err := s.authenticate(arg.Auth, "Storage.ListVolsHandler"); err != nil {
+		return err
+	}
 	vols, err := s.storage.ListVols()
 	if err != nil {
 		log.Debugf("Listsvols failed with error %s", err)
@@ -42,11 +63,14 @@ func (s *storageServer) ListVolsHandler(arg *string, reply *ListVolsReply) error
 }
 
 // StatVolHandler - stat vol handler is a rpc wrapper for StatVol operation.
-func (s *storageServer) StatVolHandler(arg *string, reply *VolInfo) error {
-	volInfo, err := s.storage.StatVol(*arg)
+func (s *storageServer) StatVolHandler(arg *VolArgs, reply *VolInfo) error {
+	if err := s.authenticate(arg.Auth, "Storage.StatVolHandler", arg.Vol); err != nil {
+		return err
+	}
+	volInfo, err := s.storage.StatVol(arg.Vol)
 	if err != nil {
 		log.WithFields(logrus.Fields{
-			"volume": *arg,
+			"volume": arg.Vol,
 		}).Debugf("StatVol failed with error %s", err)
 		return err
 	}
@@ -54,13 +78,33 @@ func (s *storageServer) StatVolHandler(arg *string, reply *VolInfo) error {
 	return nil
 }
 
+// StatVFSHandler - stat vfs handler is a rpc wrapper for StatVFS
+// operation.
+func (s *storageServer) StatVFSHandler(arg *VolArgs, reply *VFSInfo) error {
+	if err := s.authenticate(arg.Auth, "Storage.StatVFSHandler", arg.Vol); err != nil {
+		return err
+	}
+	vfsInfo, err := s.storage.StatVFS(arg.Vol)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": arg.Vol,
+		}).Debugf("StatVFS failed with error %s", err)
+		return err
+	}
+	*reply = vfsInfo
+	return nil
+}
+
 // DeleteVolHandler - delete vol handler is a rpc wrapper for
 // DeleteVol operation.
-func (s *storageServer) DeleteVolHandler(arg *string, reply *GenericReply) error {
-	err := s.storage.DeleteVol(*arg)
+func (s *storageServer) DeleteVolHandler(arg *VolArgs, reply *GenericReply) error {
+	if err := s.authenticate(arg.Auth, "Storage.DeleteVolHandler", arg.Vol); err != nil {
+		return err
+	}
+	err := s.storage.DeleteVol(arg.Vol)
 	if err != nil {
 		log.WithFields(logrus.Fields{
-			"volume": *arg,
+			"volume": arg.Vol,
 		}).Debugf("DeleteVol failed with error %s", err)
 		return err
 	}
@@ -71,6 +115,10 @@ func (s *storageServer) DeleteVolHandler(arg *string, reply *GenericReply) error
 
 // ListFilesHandler - list files handler.
 func (s *storageServer) ListFilesHandler(arg *ListFilesArgs, reply *ListFilesReply) error {
+	if err := s.authenticate(arg.Auth, "Storage.ListFilesHandler", arg.Vol, arg.Prefix, arg.Marker,
+		strconv.FormatBool(arg.Recursive), strconv.Itoa(arg.Count)); err != nil {
+		return err
+	}
 	files, eof, err := s.storage.ListFiles(arg.Vol, arg.Prefix, arg.Marker, arg.Recursive, arg.Count)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -93,6 +141,9 @@ func (s *storageServer) ListFilesHandler(arg *ListFilesArgs, reply *ListFilesRep
 
 // StatFileHandler - stat file handler is rpc wrapper to stat file.
 func (s *storageServer) StatFileHandler(arg *StatFileArgs, reply *FileInfo) error {
+	if err := s.authenticate(arg.Auth, "Storage.StatFileHandler", arg.Vol, arg.Path); err != nil {
+		return err
+	}
 	fileInfo, err := s.storage.StatFile(arg.Vol, arg.Path)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -107,7 +158,10 @@ func (s *storageServer) StatFileHandler(arg *StatFileArgs, reply *FileInfo) erro
 
 // DeleteFileHandler - delete file handler is rpc wrapper to delete file.
 func (s *storageServer) DeleteFileHandler(arg *DeleteFileArgs, reply *GenericReply) error {
-	err := s.storage.DeleteFile(arg.Vol, arg.Path)
+	if err := s.authenticate(arg.Auth, "Storage.DeleteFileHandler", arg.Vol, arg.Path); err != nil {
+		return err
+	}
+	err := s.storage.DeleteFile(context.Background(), arg.Vol, arg.Path)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": arg.Vol,
@@ -122,6 +176,7 @@ func (s *storageServer) DeleteFileHandler(arg *DeleteFileArgs, reply *GenericRep
 func newStorageRPC(storageAPI StorageAPI) *storageServer {
 	return &storageServer{
 		storage: storageAPI,
+		auth:    newRPCAuthenticator(),
 	}
 }
 
@@ -137,17 +192,29 @@ func registerStorageRPCRouter(mux *router.Router, stServer *storageServer) {
 		vars := router.Vars(r)
 		volume := vars["volume"]
 		path := vars["path"]
-		writeCloser, err := stServer.storage.CreateFile(volume, path)
+		ctx := r.Context()
+		authArgs := []string{volume, path}
+		if r.URL.Query().Get("reserved") == "true" {
+			ctx = withReservedSpace(ctx)
+			authArgs = append(authArgs, "reserved")
+		}
+		if err := stServer.authenticate(rpcAuthFromHeaders(r.Header), "Storage.Upload", authArgs...); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		writeCloser, err := stServer.storage.CreateFile(ctx, volume, path)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
 			}).Debugf("CreateFile failed with error %s", err)
 			httpErr := http.StatusInternalServerError
-			if err == errVolumeNotFound {
+			if errorCause(err) == errVolumeNotFound {
 				httpErr = http.StatusNotFound
-			} else if err == errIsNotRegular {
+			} else if errorCause(err) == errIsNotRegular {
 				httpErr = http.StatusConflict
+			} else if errorCause(err) == errDiskFull {
+				httpErr = http.StatusInsufficientStorage
 			}
 			http.Error(w, err.Error(), httpErr)
 			return
@@ -170,7 +237,12 @@ func registerStorageRPCRouter(mux *router.Router, stServer *storageServer) {
 		vars := router.Vars(r)
 		volume := vars["volume"]
 		path := vars["path"]
-		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		offsetStr := r.URL.Query().Get("offset")
+		if err := stServer.authenticate(rpcAuthFromHeaders(r.Header), "Storage.Download", volume, path, offsetStr); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		offset, err := strconv.ParseInt(offsetStr, 10, 64)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
@@ -179,16 +251,16 @@ func registerStorageRPCRouter(mux *router.Router, stServer *storageServer) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		readCloser, err := stServer.storage.ReadFile(volume, path, offset)
+		readCloser, err := stServer.storage.ReadFile(r.Context(), volume, path, offset)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
 			}).Debugf("ReadFile failed with error %s", err)
 			httpErr := http.StatusBadRequest
-			if err == errVolumeNotFound {
+			if errorCause(err) == errVolumeNotFound {
 				httpErr = http.StatusNotFound
-			} else if err == errFileNotFound {
+			} else if errorCause(err) == errFileNotFound {
 				httpErr = http.StatusNotFound
 			}
 			http.Error(w, err.Error(), httpErr)
@@ -0,0 +1,140 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// spaceDisk - disk reporting free bytes of space, or an error.
+type spaceDisk struct {
+	StorageAPI
+	free int64
+	err  error
+}
+
+func (d spaceDisk) StatVFS(volume string) (VFSInfo, error) {
+	if d.err != nil {
+		return VFSInfo{}, d.err
+	}
+	return VFSInfo{Total: 1000, Free: d.free}, nil
+}
+
+// Tests files fit in XL as long as their blocks fit on a write quorum
+// of disks.
+func TestXLStatVFS(t *testing.T) {
+	testCases := []struct {
+		free     []int64
+		errs     []error
+		expected int64
+		err      error
+	}{
+		// 4 disks, 2 data blocks, write quorum of 4.
+		{[]int64{100, 100, 100, 100}, nil, 200, nil},
+		{[]int64{100, 50, 100, 10}, nil, 20, nil},
+		// 8 disks, 4 data blocks, write quorum of 7.
+		{[]int64{100, 100, 100, 100, 100, 100, 100, 10}, nil, 400, nil},
+		{[]int64{10, 100, 100, 100, 100, 100, 100, 10}, nil, 40, nil},
+		{[]int64{100, 100, 100, 100, 100, 100, 100, 10}, []error{errChaos, nil, nil, nil, nil, nil, nil, nil}, 40, nil},
+		{[]int64{100, 100, 100, 100, 100, 100, 100, 100}, []error{errChaos, errChaos, nil, nil, nil, nil, nil, nil}, 0, errWriteQuorum},
+	}
+	for i, testCase := range testCases {
+		diskPaths := make([]string, len(testCase.free))
+		storageDisks := make([]StorageAPI, len(testCase.free))
+		for index := range storageDisks {
+			diskPaths[index] = fmt.Sprintf("mem-%d", index)
+			disk := spaceDisk{StorageAPI: newMemStorage(), free: testCase.free[index]}
+			if testCase.errs != nil {
+				disk.err = testCase.errs[index]
+			}
+			storageDisks[index] = disk
+		}
+		xl, e := newXLDisks(diskPaths, storageDisks)
+		if e != nil {
+			t.Fatal(e)
+		}
+		vfsInfo, e := xl.StatVFS("bucket")
+		if e != testCase.err {
+			t.Fatalf("Test %d: expected %v, got %v", i+1, testCase.err, e)
+		}
+		if vfsInfo.Free != testCase.expected {
+			t.Errorf("Test %d: expected free %d, got %d", i+1, testCase.expected, vfsInfo.Free)
+		}
+	}
+}
+
+// Tests objects and parts which do not fit on disks are refused before
+// they are written.
+func TestObjectFreeSpace(t *testing.T) {
+	directory, e := ioutil.TempDir("", "minio-free-space-test")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(directory)
+	fs, e := newFS(directory)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if vfsInfo, e := fs.StatVFS("bucket"); e != nil || vfsInfo.Free <= 0 || vfsInfo.Free > vfsInfo.Total {
+		t.Fatalf("Unexpected disk space %+v, %v", vfsInfo, e)
+	}
+	obj := newObjectLayer(spaceDisk{StorageAPI: fs, free: 10})
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("hello world")
+	if _, err := obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err == nil {
+		t.Fatal("Expected object larger than free space to be refused")
+	} else if _, ok := err.ToGoError().(StorageFull); !ok {
+		t.Fatalf("Expected StorageFull, got %s", err.ToGoError())
+	}
+	if _, err := obj.GetObjectInfo("bucket", "object"); err == nil {
+		t.Fatal("Expected object refused not to be written")
+	}
+	if _, err := obj.PutObject("bucket", "object", 5, bytes.NewReader(data[:5]), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadID, err := obj.NewMultipartUpload("bucket", "object", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.PutObjectPart("bucket", "object", uploadID, 1, int64(len(data)), bytes.NewReader(data), ""); err == nil {
+		t.Fatal("Expected part larger than free space to be refused")
+	} else if _, ok := err.ToGoError().(StorageFull); !ok {
+		t.Fatalf("Expected StorageFull, got %s", err.ToGoError())
+	}
+	var parts []completePart
+	for partID := 1; partID <= 3; partID++ {
+		md5Hex, err := obj.PutObjectPart("bucket", "object", uploadID, partID, 5, bytes.NewReader(data[:5]), hex.EncodeToString(sumMD5(data[:5])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: partID, ETag: md5Hex})
+	}
+	if _, err = obj.CompleteMultipartUpload("bucket", "object", uploadID, parts); err == nil {
+		t.Fatal("Expected object of parts larger than free space to be refused")
+	} else if _, ok := err.ToGoError().(StorageFull); !ok {
+		t.Fatalf("Expected StorageFull, got %s", err.ToGoError())
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio/pkg/probe"
+)
+
+// STS API version served.
+const stsAPIVersion = "2011-06-15"
+
+// Maximum size of STS request bodies.
+const maxSTSRequestSize = 64 * 1024
+
+// Durations of temporary credentials in seconds.
+const (
+	minSTSDuration        = 900
+	defaultSTSDuration    = 3600
+	defaultSTSMaxDuration = 12 * 3600
+)
+
+// stsConfig - durations of temporary credentials in seconds. Requests
+// without DurationSeconds are issued credentials valid for
+// DefaultDuration, requests may ask for up to MaxDuration.
+type stsConfig struct {
+	DefaultDuration int64 `json:"defaultDuration"`
+	MaxDuration     int64 `json:"maxDuration"`
+}
+
+// newSTSConfig - STS configuration for fresh and migrated configs.
+func newSTSConfig() stsConfig {
+	return stsConfig{
+		DefaultDuration: defaultSTSDuration,
+		MaxDuration:     defaultSTSMaxDuration,
+	}
+}
+
+// durations - returns default and maximum durations, defaults if not
+// configured.
+func (c stsConfig) durations() (defaultDuration, maxDuration int64) {
+	defaultDuration, maxDuration = c.DefaultDuration, c.MaxDuration
+	if maxDuration < minSTSDuration {
+		maxDuration = defaultSTSMaxDuration
+	}
+	if defaultDuration < minSTSDuration || defaultDuration > maxDuration {
+		defaultDuration = defaultSTSDuration
+		if defaultDuration > maxDuration {
+			defaultDuration = maxDuration
+		}
+	}
+	return defaultDuration, maxDuration
+}
+
+// assumeRoleCredentials - temporary credential returned to clients.
+type assumeRoleCredentials struct {
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string
+}
+
+// newAssumeRoleCredentials - returns temp as returned to clients.
+func newAssumeRoleCredentials(temp tempCredential) assumeRoleCredentials {
+	return assumeRoleCredentials{
+		AccessKeyID:     temp.AccessKey,
+		SecretAccessKey: temp.SecretKey,
+		SessionToken:    temp.SessionToken,
+		Expiration:      temp.Expiration.Format(timeFormatAMZ),
+	}
+}
+
+// stsResponseMetadata - metadata of all STS responses.
+type stsResponseMetadata struct {
+	RequestID string `xml:"RequestId"`
+}
+
+// assumeRoleResponse - response of AssumeRole.
+type assumeRoleResponse struct {
+	XMLName xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleResponse" json:"-"`
+	Result  struct {
+		Credentials assumeRoleCredentials
+	} `xml:"AssumeRoleResult"`
+	ResponseMetadata stsResponseMetadata
+}
+
+// assumeRoleWithLDAPIdentityResponse - response of
+// AssumeRoleWithLDAPIdentity.
+type assumeRoleWithLDAPIdentityResponse struct {
+	XMLName xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleWithLDAPIdentityResponse" json:"-"`
+	Result  struct {
+		Credentials assumeRoleCredentials
+	} `xml:"AssumeRoleWithLDAPIdentityResult"`
+	ResponseMetadata stsResponseMetadata
+}
+
+// getSTSDuration - returns duration of temporary credentials asked
+// for by values, false if it is out of the configured bounds.
+func getSTSDuration(values url.Values) (time.Duration, bool) {
+	defaultDuration, maxDuration := serverConfig.GetSTS().durations()
+	duration := defaultDuration
+	if durationSeconds := values.Get("DurationSeconds"); durationSeconds != "" {
+		var e error
+		duration, e = strconv.ParseInt(durationSeconds, 10, 64)
+		if e != nil || duration < minSTSDuration || duration > maxDuration {
+			return 0, false
+		}
+	}
+	return time.Duration(duration) * time.Second, true
+}
+
+// STSHandler - POST /
+// ----------
+// Serves form encoded STS requests, dispatched by their Action.
+func (api stsAPIHandlers) STSHandler(w http.ResponseWriter, r *http.Request) {
+	payload, e := ioutil.ReadAll(io.LimitReader(r.Body, maxSTSRequestSize))
+	if e != nil {
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	values, e := url.ParseQuery(string(payload))
+	if e != nil || values.Get("Version") != stsAPIVersion {
+		writeErrorResponse(w, r, ErrInvalidSTSRequest, r.URL.Path)
+		return
+	}
+	switch values.Get("Action") {
+	case "AssumeRole":
+		api.assumeRole(w, r, payload, values)
+	case "AssumeRoleWithLDAPIdentity":
+		api.assumeRoleWithLDAPIdentity(w, r, values)
+	default:
+		writeErrorResponse(w, r, ErrInvalidSTSRequest, r.URL.Path)
+	}
+}
+
+// assumeRole - Action=AssumeRole
+// ----------
+// Issues a temporary access key, secret key and session token to the
+// user or server credential signing the request. Requests signed with
+// the temporary credential must carry its session token and are
+// allowed what the signer of AssumeRole is allowed, until the
+// credential expires.
+func (api stsAPIHandlers) assumeRole(w http.ResponseWriter, r *http.Request, payload []byte, values url.Values) {
+	if getRequestAuthType(r) != authTypeSigned {
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	}
+	validateRegion := true // Validate region.
+	if s3Error := doesServiceSignatureMatch(serviceSTS, hex.EncodeToString(sum256(payload)), r, validateRegion); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	duration, ok := getSTSDuration(values)
+	if !ok {
+		writeErrorResponse(w, r, ErrInvalidSTSRequest, r.URL.Path)
+		return
+	}
+
+	// Temporary credentials cannot assume roles themselves.
+	parent := getAuditRequester(r)
+	if globalIAMUsers.IsTempCredential(parent) {
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	}
+	// Credentials of the server credential outlive its rotations.
+	if isRootAccessKey(parent) {
+		parent = serverConfig.GetCredential().AccessKeyID
+	}
+	temp, err := globalIAMUsers.AssumeRole(parent, nil, duration)
+	if err != nil {
+		errorIf(err.Trace(parent), "Unable to issue temporary credentials.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	response := assumeRoleResponse{}
+	response.Result.Credentials = newAssumeRoleCredentials(temp)
+	setCommonHeaders(w)
+	response.ResponseMetadata.RequestID = w.Header().Get("X-Amz-Request-Id")
+	w.Write(encodeResponse(response))
+}
+
+// assumeRoleWithLDAPIdentity - Action=AssumeRoleWithLDAPIdentity
+// ----------
+// Issues temporary credentials to the LDAP user authenticated by the
+// LDAPUsername and LDAPPassword of the request, which needs not be
+// signed. The credentials are allowed what the policies mapped to the
+// groups of the user allow.
+func (api stsAPIHandlers) assumeRoleWithLDAPIdentity(w http.ResponseWriter, r *http.Request, values url.Values) {
+	config := serverConfig.GetLDAP()
+	if !config.Enable {
+		writeErrorResponse(w, r, ErrLDAPDisabled, r.URL.Path)
+		return
+	}
+	duration, ok := getSTSDuration(values)
+	if !ok {
+		writeErrorResponse(w, r, ErrInvalidSTSRequest, r.URL.Path)
+		return
+	}
+	username := values.Get("LDAPUsername")
+	userDN, policies, e := ldapAuthenticate(config, username, values.Get("LDAPPassword"))
+	switch e {
+	case nil:
+	case errLDAPInvalidCredentials, errLDAPNoPolicy:
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	default:
+		errorIf(probe.NewError(e).Trace(username), "Unable to authenticate LDAP user.", nil)
+		writeErrorResponse(w, r, ErrLDAPUnavailable, r.URL.Path)
+		return
+	}
+	temp, err := globalIAMUsers.AssumeRole(userDN, policies, duration)
+	if err != nil {
+		errorIf(err.Trace(userDN), "Unable to issue temporary credentials.", nil)
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	response := assumeRoleWithLDAPIdentityResponse{}
+	response.Result.Credentials = newAssumeRoleCredentials(temp)
+	setCommonHeaders(w)
+	response.ResponseMetadata.RequestID = w.Header().Get("X-Amz-Request-Id")
+	w.Write(encodeResponse(response))
+}
@@ -0,0 +1,33 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import router "github.com/gorilla/mux"
+
+// stsAPIHandlers implements and provides http handlers for the STS
+// API.
+type stsAPIHandlers struct{}
+
+// registerSTSRouter - registers STS compatible APIs, served as form
+// encoded POST requests to the root path like AWS STS.
+func registerSTSRouter(mux *router.Router, api stsAPIHandlers) {
+	// STS router
+	stsRouter := mux.NewRoute().Methods("POST").Path("/").HeadersRegexp("Content-Type", "application/x-www-form-urlencoded*").Subrouter()
+
+	// AssumeRole, AssumeRoleWithLDAPIdentity
+	stsRouter.Methods("POST").HandlerFunc(api.STSHandler)
+}
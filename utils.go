@@ -39,17 +39,6 @@ func checkValidMD5(md5 string) ([]byte, *probe.Error) {
 	return md5Bytes, nil
 }
 
-/// http://docs.aws.amazon.com/AmazonS3/latest/dev/UploadingObjects.html
-const (
-	// maximum object size per PUT request is 5GiB
-	maxObjectSize = 1024 * 1024 * 1024 * 5
-)
-
-// isMaxObjectSize - verify if max object size
-func isMaxObjectSize(size int64) bool {
-	return size > maxObjectSize
-}
-
 func contains(stringList []string, element string) bool {
 	for _, e := range stringList {
 		if e == element {
@@ -59,3 +48,15 @@ func contains(stringList []string, element string) bool {
 
 	return false
 }
+
+// limitReadCloser - returns reader of the first length bytes of r, all
+// of r if length is negative.
+func limitReadCloser(r io.ReadCloser, length int64) io.ReadCloser {
+	if length < 0 {
+		return r
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(r, length), r}
+}
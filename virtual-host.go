@@ -0,0 +1,103 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Context key of the path of virtual host style requests as sent by
+// clients, before their bucket is moved into it.
+type virtualHostPathKey struct{}
+
+// virtualHostPath - path and raw path of a virtual host style request.
+type virtualHostPath struct {
+	path    string
+	rawPath string
+}
+
+// getVirtualHostBucket - returns bucket named in host of a virtual
+// host style request to domain, empty for path style requests.
+func getVirtualHostBucket(host, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	if h, _, e := net.SplitHostPort(host); e == nil {
+		host = h
+	}
+	host, domain = strings.ToLower(host), strings.ToLower(domain)
+	if !strings.HasSuffix(host, "."+domain) {
+		return ""
+	}
+	bucket := strings.TrimSuffix(host, "."+domain)
+	// Browser and admin APIs are only served path style.
+	if "/"+bucket == reservedBucket {
+		return ""
+	}
+	return bucket
+}
+
+// virtualHostHandler - serves virtual host style requests as path
+// style requests, by moving the bucket named in their host into their
+// path.
+type virtualHostHandler struct {
+	handler http.Handler
+}
+
+// setVirtualHostHandler handler for virtual host style requests.
+func setVirtualHostHandler(h http.Handler) http.Handler {
+	return virtualHostHandler{h}
+}
+
+func (h virtualHostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket := getVirtualHostBucket(r.Host, serverConfig.GetDomain())
+	if bucket == "" {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	// Signatures are computed over the path sent by clients.
+	r = r.WithContext(context.WithValue(r.Context(), virtualHostPathKey{}, virtualHostPath{r.URL.Path, r.URL.RawPath}))
+	if r.URL.Path == "/" {
+		r.URL.Path = "/" + bucket
+	} else {
+		r.URL.Path = "/" + bucket + r.URL.Path
+	}
+	if r.URL.RawPath != "" {
+		r.URL.RawPath = "/" + bucket + r.URL.RawPath
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// getSignedURLPath - returns path of r as signed by clients.
+func getSignedURLPath(r *http.Request) string {
+	if urlPath, ok := r.Context().Value(virtualHostPathKey{}).(virtualHostPath); ok {
+		return urlPath.path
+	}
+	return r.URL.Path
+}
+
+// restoreVirtualHostPath - sets the path of virtual host style request r
+// back to the path sent by clients, for servers moving the bucket into
+// the path themselves.
+func restoreVirtualHostPath(r *http.Request) {
+	if urlPath, ok := r.Context().Value(virtualHostPathKey{}).(virtualHostPath); ok {
+		r.URL.Path, r.URL.RawPath = urlPath.path, urlPath.rawPath
+	}
+}
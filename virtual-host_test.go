@@ -0,0 +1,44 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "testing"
+
+// Tests buckets named in hosts of virtual host style requests.
+func TestGetVirtualHostBucket(t *testing.T) {
+	testCases := []struct {
+		host   string
+		domain string
+		bucket string
+	}{
+		{"photos.s3.example.com", "s3.example.com", "photos"},
+		{"photos.s3.example.com:9000", "s3.example.com", "photos"},
+		{"Photos.S3.Example.com", "s3.example.com", "photos"},
+		{"my.photos.s3.example.com", "s3.example.com", "my.photos"},
+		{"s3.example.com", "s3.example.com", ""},
+		{"s3.example.com:9000", "s3.example.com", ""},
+		{"photos.s3.example.org", "s3.example.com", ""},
+		{"photoss3.example.com", "s3.example.com", ""},
+		{"minio.s3.example.com", "s3.example.com", ""},
+		{"photos.s3.example.com", "", ""},
+	}
+	for i, testCase := range testCases {
+		if bucket := getVirtualHostBucket(testCase.host, testCase.domain); bucket != testCase.bucket {
+			t.Errorf("Test %d: Expected bucket %q, got %q", i+1, testCase.bucket, bucket)
+		}
+	}
+}
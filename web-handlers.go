@@ -227,7 +227,7 @@ func (web *webAPIHandlers) RemoveObject(r *http.Request, args *RemoveObjectArgs,
 		return &json2.Error{Message: "Unauthorized request"}
 	}
 	reply.UIVersion = miniobrowser.UIVersion
-	e := web.ObjectAPI.DeleteObject(args.BucketName, args.ObjectName)
+	e := web.ObjectAPI.DeleteObject(args.BucketName, args.ObjectName, false)
 	if e != nil {
 		return &json2.Error{Message: e.Cause.Error()}
 	}
@@ -340,6 +340,133 @@ func (web *webAPIHandlers) GetAuth(r *http.Request, args *WebGenericArgs, reply
 	return nil
 }
 
+// Share links expire after a week at most, the longest expiry of
+// presigned requests.
+const maxPresignedExpiry = 7 * 24 * time.Hour
+
+// PresignedGetArgs - presigned-get API args.
+type PresignedGetArgs struct {
+	// Host header required for signed headers.
+	HostName   string `json:"host"`
+	BucketName string `json:"bucket"`
+	ObjectName string `json:"object"`
+	// Expiry in seconds, defaults to the longest expiry allowed.
+	Expiry int64 `json:"expiry"`
+}
+
+// PresignedGetRep - presigned-get URL reply.
+type PresignedGetRep struct {
+	URL       string `json:"url"`
+	UIVersion string `json:"uiVersion"`
+}
+
+// PresignedGet - returns a share link downloading an object without
+// credentials, signed with the server credentials.
+func (web *webAPIHandlers) PresignedGet(r *http.Request, args *PresignedGetArgs, reply *PresignedGetRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if args.BucketName == "" || args.ObjectName == "" {
+		return &json2.Error{Message: "Bucket and object name are required"}
+	}
+	expiry := time.Duration(args.Expiry) * time.Second
+	if args.Expiry == 0 {
+		expiry = maxPresignedExpiry
+	}
+	if expiry < time.Second || expiry > maxPresignedExpiry {
+		return &json2.Error{Message: "Expiry must be between 1 second and 7 days"}
+	}
+	if _, e := web.ObjectAPI.GetObjectInfo(args.BucketName, args.ObjectName); e != nil {
+		return &json2.Error{Message: e.Cause.Error()}
+	}
+	host := args.HostName
+	if host == "" {
+		host = r.Host
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	cred := serverConfig.GetCredential()
+	urlPath := "/" + args.BucketName + "/" + args.ObjectName
+	query := presignV4("GET", host, urlPath, cred.AccessKeyID, cred.SecretAccessKey, serverConfig.GetRegion(), time.Now().UTC(), expiry)
+	reply.URL = scheme + "://" + host + getURLEncodedName(urlPath) + "?" + query
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// GetBucketPolicyArgs - get bucket policy args.
+type GetBucketPolicyArgs struct {
+	BucketName string `json:"bucketName"`
+}
+
+// GetBucketPolicyRep - get bucket policy reply.
+type GetBucketPolicyRep struct {
+	// Policy document, empty if the bucket has no policy.
+	Policy    string `json:"policy"`
+	UIVersion string `json:"uiVersion"`
+}
+
+// GetBucketPolicy - returns access policy of a bucket.
+func (web *webAPIHandlers) GetBucketPolicy(r *http.Request, args *GetBucketPolicyArgs, reply *GetBucketPolicyRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, e := web.ObjectAPI.GetBucketInfo(args.BucketName); e != nil {
+		return &json2.Error{Message: e.Cause.Error()}
+	}
+	policyBytes, err := readBucketPolicy(args.BucketName)
+	if err != nil {
+		if _, ok := err.ToGoError().(BucketPolicyNotFound); !ok {
+			return &json2.Error{Message: err.Cause.Error()}
+		}
+	}
+	reply.Policy = string(policyBytes)
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// SetBucketPolicyArgs - set bucket policy args.
+type SetBucketPolicyArgs struct {
+	BucketName string `json:"bucketName"`
+	// Policy document, an empty policy removes the bucket policy.
+	Policy string `json:"policy"`
+}
+
+// SetBucketPolicy - validates and sets access policy of a bucket.
+func (web *webAPIHandlers) SetBucketPolicy(r *http.Request, args *SetBucketPolicyArgs, reply *WebGenericRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, e := web.ObjectAPI.GetBucketInfo(args.BucketName); e != nil {
+		return &json2.Error{Message: e.Cause.Error()}
+	}
+	reply.UIVersion = miniobrowser.UIVersion
+	if args.Policy == "" {
+		err := removeBucketPolicy(args.BucketName)
+		if err != nil {
+			if _, ok := err.ToGoError().(BucketPolicyNotFound); !ok {
+				return &json2.Error{Message: err.Cause.Error()}
+			}
+		}
+		return nil
+	}
+	if len(args.Policy) > maxAccessPolicySize {
+		return &json2.Error{Message: getAPIError(ErrEntityTooLarge).Description}
+	}
+	bucketPolicy, e := parseBucketPolicy([]byte(args.Policy))
+	if e != nil {
+		return &json2.Error{Message: getAPIError(ErrInvalidPolicyDocument).Description, Data: e.Error()}
+	}
+	if s3Error := checkBucketPolicyResources(args.BucketName, bucketPolicy); s3Error != ErrNone {
+		return &json2.Error{Message: getAPIError(s3Error).Description}
+	}
+	if err := writeBucketPolicy(args.BucketName, []byte(args.Policy)); err != nil {
+		return &json2.Error{Message: err.Cause.Error()}
+	}
+	return nil
+}
+
 // Upload - file upload handler.
 func (web *webAPIHandlers) Upload(w http.ResponseWriter, r *http.Request) {
 	if !isJWTReqAuthenticated(r) {
@@ -375,7 +502,7 @@ func (web *webAPIHandlers) Download(w http.ResponseWriter, r *http.Request) {
 	// Add content disposition.
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(object)))
 
-	objReader, err := web.ObjectAPI.GetObject(bucket, object, 0)
+	objReader, err := web.ObjectAPI.WithContext(r.Context()).GetObject(bucket, object, 0)
 	if err != nil {
 		writeWebErrorResponse(w, err.ToGoError())
 		return
@@ -399,6 +526,8 @@ func writeWebErrorResponse(w http.ResponseWriter, err error) {
 	switch err.(type) {
 	case StorageFull:
 		apiErrCode = ErrStorageFull
+	case QuotaExceeded:
+		apiErrCode = ErrQuotaExceeded
 	case BucketNotFound:
 		apiErrCode = ErrNoSuchBucket
 	case BucketNameInvalid:
@@ -0,0 +1,269 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// Least recently read blocks are evicted first.
+	blockCacheEvictionLRU = "lru"
+	// Least recently cached blocks are evicted first.
+	blockCacheEvictionFIFO = "fifo"
+)
+
+// blockCacheConfig - in memory cache of decoded blocks of erasure coded
+// and inlined files, so that reads of hot files avoid the disks.
+type blockCacheConfig struct {
+	// Maximum bytes of memory used, zero disables the cache.
+	MaxSize int64 `json:"maxSize"`
+	// Files larger than MaxFileSize bytes are not cached, zero for no
+	// limit.
+	MaxFileSize int64 `json:"maxFileSize"`
+	// Eviction policy, "lru" or "fifo", "lru" if empty.
+	Eviction string `json:"eviction"`
+}
+
+// xlFileKey - file of a volume.
+type xlFileKey struct {
+	volume string
+	path   string
+}
+
+// xlBlockKey - block of a file, blocks are erasureBlockSize bytes of
+// decoded file data.
+type xlBlockKey struct {
+	xlFileKey
+	block int64
+}
+
+// xlCachedBlock - decoded data of a block.
+type xlCachedBlock struct {
+	key  xlBlockKey
+	data []byte
+}
+
+// xlCachedFile - file with cached blocks.
+type xlCachedFile struct {
+	size   int64
+	blocks int
+}
+
+// xlBlockCache - decoded blocks of files cached in memory, shared by
+// the XL of pinned volumes. Blocks are removed when their file is
+// written or deleted.
+type xlBlockCache struct {
+	mutex  *sync.Mutex
+	config blockCacheConfig
+	blocks map[xlBlockKey]*list.Element
+	files  map[xlFileKey]*xlCachedFile
+	// Blocks by eviction order, next evicted in back.
+	order *list.List
+	usage int64
+	// Incremented on each invalidation, blocks decoded before are not
+	// cached.
+	generation uint64
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func newXLBlockCache() *xlBlockCache {
+	return &xlBlockCache{
+		mutex:  &sync.Mutex{},
+		blocks: make(map[xlBlockKey]*list.Element),
+		files:  make(map[xlFileKey]*xlCachedFile),
+		order:  list.New(),
+	}
+}
+
+// SetBlockCache - sets block cache configuration, cached blocks are
+// dropped.
+func (xl XL) SetBlockCache(config blockCacheConfig) {
+	c := xl.blockCache
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config = config
+	c.blocks = make(map[xlBlockKey]*list.Element)
+	c.files = make(map[xlFileKey]*xlCachedFile)
+	c.order.Init()
+	c.usage = 0
+	c.generation++
+}
+
+// Generation - returns current generation, to be passed to add.
+func (c *xlBlockCache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.generation
+}
+
+// cacheable - returns true if blocks of files of size are cached.
+func (c *xlBlockCache) cacheable(size int64) bool {
+	if c == nil {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.config.MaxSize <= 0 || size > c.config.MaxSize {
+		return false
+	}
+	return c.config.MaxFileSize <= 0 || size <= c.config.MaxFileSize
+}
+
+// get - returns reader of file data from offset, ok is false unless
+// all blocks from offset are cached.
+func (c *xlBlockCache) get(volume, path string, offset int64) (r io.ReadCloser, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.config.MaxSize <= 0 {
+		return nil, false
+	}
+	fileKey := xlFileKey{volume, path}
+	file, found := c.files[fileKey]
+	if !found || offset > file.size {
+		c.misses++
+		return nil, false
+	}
+	var elements []*list.Element
+	for block := offset / erasureBlockSize; block*erasureBlockSize < file.size; block++ {
+		element, cached := c.blocks[xlBlockKey{fileKey, block}]
+		if !cached {
+			c.misses++
+			return nil, false
+		}
+		elements = append(elements, element)
+	}
+	var readers []io.Reader
+	for index, element := range elements {
+		data := element.Value.(*xlCachedBlock).data
+		if index == 0 {
+			data = data[offset%erasureBlockSize:]
+		}
+		readers = append(readers, bytes.NewReader(data))
+		if c.config.Eviction != blockCacheEvictionFIFO {
+			c.order.MoveToFront(element)
+		}
+	}
+	c.hits++
+	return ioutil.NopCloser(io.MultiReader(readers...)), true
+}
+
+// add - caches decoded data of block of file of size, unless the file
+// was invalidated since generation.
+func (c *xlBlockCache) add(volume, path string, size, block int64, data []byte, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.config.MaxSize <= 0 || generation != c.generation || int64(len(data)) > c.config.MaxSize {
+		return
+	}
+	fileKey := xlFileKey{volume, path}
+	key := xlBlockKey{fileKey, block}
+	if _, cached := c.blocks[key]; cached {
+		return
+	}
+	file, found := c.files[fileKey]
+	if !found {
+		file = &xlCachedFile{size: size}
+		c.files[fileKey] = file
+	}
+	file.blocks++
+	c.blocks[key] = c.order.PushFront(&xlCachedBlock{key: key, data: data})
+	c.usage += int64(len(data))
+	for c.usage > c.config.MaxSize {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// remove - removes cached block of element. Callers hold mutex.
+func (c *xlBlockCache) remove(element *list.Element) {
+	cached := c.order.Remove(element).(*xlCachedBlock)
+	delete(c.blocks, cached.key)
+	c.usage -= int64(len(cached.data))
+	if file := c.files[cached.key.xlFileKey]; file != nil {
+		if file.blocks--; file.blocks == 0 {
+			delete(c.files, cached.key.xlFileKey)
+		}
+	}
+}
+
+// invalidate - removes cached blocks of file at path.
+func (c *xlBlockCache) invalidate(volume, path string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	fileKey := xlFileKey{volume, path}
+	file, found := c.files[fileKey]
+	if !found {
+		return
+	}
+	for block := int64(0); block*erasureBlockSize < file.size; block++ {
+		if element, cached := c.blocks[xlBlockKey{fileKey, block}]; cached {
+			c.remove(element)
+		}
+	}
+}
+
+// invalidateVolume - removes cached blocks of files of volume.
+func (c *xlBlockCache) invalidateVolume(volume string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	for key, element := range c.blocks {
+		if key.volume == volume {
+			c.remove(element)
+		}
+	}
+}
+
+// stats - adds block cache usage and hits to stats.
+func (c *xlBlockCache) stats(stats *xlReadStats) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats.BlockCacheHits = c.hits
+	stats.BlockCacheMisses = c.misses
+	stats.BlockCacheEvictions = c.evictions
+	stats.BlockCacheUsage = c.usage
+}
@@ -0,0 +1,146 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests decoded blocks of files are cached, invalidated on writes and
+// evicted once the cache is full.
+func TestXLBlockCache(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	xl.SetBlockCache(blockCacheConfig{MaxSize: 2*erasureBlockSize + 150, MaxFileSize: 2 * erasureBlockSize})
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	createFile := func(path string, data []byte) {
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+	readFile := func(path string, offset int64) []byte {
+		r, e := xl.ReadFile(context.Background(), "bucket", path, offset)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer r.Close()
+		data, e := ioutil.ReadAll(r)
+		if e != nil {
+			t.Fatal(e)
+		}
+		return data
+	}
+
+	data := bytes.Repeat([]byte("a"), erasureBlockSize+100)
+	createFile("object", data)
+	if !bytes.Equal(readFile("object", 0), data) {
+		t.Fatal("Unexpected data read")
+	}
+	if stats := xl.ReadStats(); stats.BlockCacheHits != 0 || stats.BlockCacheMisses != 1 || stats.BlockCacheUsage != int64(len(data)) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Cached files are read without their parts.
+	for _, disk := range disks {
+		matches, _ := filepath.Glob(filepath.Join(disk, "bucket", "object", "part.[0-9]*"))
+		for _, match := range matches {
+			os.Rename(match, match+".moved")
+			defer os.Rename(match+".moved", match)
+		}
+	}
+	if !bytes.Equal(readFile("object", 0), data) {
+		t.Fatal("Unexpected data read")
+	}
+	if !bytes.Equal(readFile("object", erasureBlockSize+50), data[erasureBlockSize+50:]) {
+		t.Fatal("Unexpected data read")
+	}
+	if stats := xl.ReadStats(); stats.BlockCacheHits != 2 || stats.ErasureReads != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Written files are read from the disks again.
+	small := []byte("hello, world")
+	createFile("object", small)
+	if !bytes.Equal(readFile("object", 0), small) {
+		t.Fatal("Unexpected data read")
+	}
+	if !bytes.Equal(readFile("object", 0), small) {
+		t.Fatal("Unexpected data read")
+	}
+	if stats := xl.ReadStats(); stats.BlockCacheHits != 3 || stats.BlockCacheUsage != int64(len(small)) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Files larger than the limit are not cached.
+	large := bytes.Repeat([]byte("b"), 2*erasureBlockSize+100)
+	createFile("large", large)
+	readFile("large", 0)
+	if stats := xl.ReadStats(); stats.BlockCacheUsage != int64(len(small)) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Least recently read blocks are evicted once the cache is full.
+	createFile("first", data)
+	createFile("second", data)
+	readFile("first", 0)
+	readFile("object", 0)
+	readFile("second", 0)
+	stats := xl.ReadStats()
+	if stats.BlockCacheEvictions != 1 || stats.BlockCacheUsage != int64(len(small)+len(data)+100) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if _, ok := xl.blockCache.get("bucket", "object", 0); !ok {
+		t.Fatal("Expected recently read file to be cached")
+	}
+	if _, ok := xl.blockCache.get("bucket", "first", 0); ok {
+		t.Fatal("Expected partially evicted file not to be served")
+	}
+
+	// Deleted files are not served from the cache.
+	if e = xl.DeleteFile(context.Background(), "bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = xl.ReadFile(context.Background(), "bucket", "object", 0); e == nil {
+		t.Fatal("Expected deleted file not to be found")
+	}
+}
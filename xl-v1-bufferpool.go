@@ -0,0 +1,68 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync"
+
+// erasureBuffers - pool of buffers holding a block of data along with
+// its parity, shared by all writes of an XL so that concurrent uploads
+// do not allocate a block per file.
+type erasureBuffers struct {
+	pool *sync.Pool
+}
+
+// newErasureBuffers - returns pool of buffers of blocks erasure coded
+// into dataBlocks and parityBlocks.
+func newErasureBuffers(dataBlocks, parityBlocks int) *erasureBuffers {
+	size := getEncodedBlockLen(erasureBlockSize, dataBlocks) * (dataBlocks + parityBlocks)
+	return &erasureBuffers{
+		pool: &sync.Pool{
+			New: func() interface{} {
+				buffer := make([]byte, size)
+				return &buffer
+			},
+		},
+	}
+}
+
+// get - returns a buffer, data is read into its first erasureBlockSize
+// bytes.
+func (e *erasureBuffers) get() *[]byte {
+	return e.pool.Get().(*[]byte)
+}
+
+// put - returns buffer to the pool, it must no longer be used.
+func (e *erasureBuffers) put(buffer *[]byte) {
+	e.pool.Put(buffer)
+}
+
+// splitBlock - splits the first n bytes of buffer into shards of data
+// and room for parity, as ReedSolomon.Split does but without
+// allocating. Shards are slices of buffer.
+func (xl XL) splitBlock(buffer []byte, n int, shards [][]byte) [][]byte {
+	perShard := getEncodedBlockLen(n, xl.DataBlocks)
+	// Pad the last data shard with zeros, parity shards are
+	// overwritten by encoding.
+	padding := buffer[n : perShard*xl.DataBlocks]
+	for i := range padding {
+		padding[i] = 0
+	}
+	for i := range shards {
+		shards[i] = buffer[i*perShard : (i+1)*perShard]
+	}
+	return shards
+}
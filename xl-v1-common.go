@@ -17,7 +17,7 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	slashpath "path"
 	"path/filepath"
@@ -78,11 +78,12 @@ func (xl XL) listOnlineDisks(volume, path string) (onlineDisks []StorageAPI, mda
 	// to return an error indicating that the disk is not available and should be
 	// different from ErrNotExist.
 	for _, err := range errs {
-		if err == errFileNotFound {
+		if errorCause(err) == errFileNotFound {
 			notFoundCount++
 			// If we have errors with file not found greater than allowed read
-			// quorum we return err as errFileNotFound.
-			if notFoundCount > xl.readQuorum {
+			// quorum, or on all disks when all are needed to read, we return
+			// err as errFileNotFound.
+			if notFoundCount > xl.readQuorum || notFoundCount == len(xl.storageDisks) {
 				return nil, fileMetadata{}, false, errFileNotFound
 			}
 		}
@@ -111,6 +112,9 @@ func (xl XL) listOnlineDisks(volume, path string) (onlineDisks []StorageAPI, mda
 			onlineDiskCount++
 		} else {
 			onlineDisks[index] = nil
+			if errs[index] == nil {
+				errs[index] = errStaleDisk
+			}
 		}
 	}
 
@@ -129,42 +133,104 @@ func (xl XL) listOnlineDisks(volume, path string) (onlineDisks []StorageAPI, mda
 				"path":            path,
 				"onlineDiskCount": onlineDiskCount,
 				"readQuorumCount": xl.readQuorum,
+				"failedDisks":     xl.failedDisks(errs),
 			}).Errorf("%s", errReadQuorum)
-			return nil, fileMetadata{}, false, errReadQuorum
+			return nil, fileMetadata{}, false, xl.newReadQuorumError(xl.readQuorum, errs)
 		}
 	}
 	return onlineDisks, mdata, heal, nil
 }
 
-// Get parts.json metadata as a map slice.
+// failedDisks - returns errors of disks which failed, errs is indexed
+// in accordance with storage disks.
+func (xl XL) failedDisks(errs []error) []diskError {
+	var disks []diskError
+	for index, err := range errs {
+		if err == nil {
+			continue
+		}
+		// Disk is identified already, only the cause is kept.
+		disks = append(disks, diskError{
+			Disk:  xl.diskPaths[index],
+			Error: errorCause(err).Error(),
+		})
+	}
+	return disks
+}
+
+// newReadQuorumError - returns read quorum error of a read which
+// required readQuorum disks, errs carries the failure of each disk.
+func (xl XL) newReadQuorumError(readQuorum int, errs []error) readQuorumError {
+	disks := xl.failedDisks(errs)
+	return readQuorumError{
+		ReadQuorum: readQuorum,
+		Available:  len(errs) - len(disks),
+		Disks:      disks,
+	}
+}
+
+// Get xl.json metadata as a map slice, metadata of older formats
+// is upgraded to the current one.
 // Returns error slice indicating the failed metadata reads.
 // Read lockNS() should be done by caller.
 func (xl XL) getPartsMetadata(volume, path string) ([]fileMetadata, []error) {
-	errs := make([]error, len(xl.storageDisks))
-	metadataArray := make([]fileMetadata, len(xl.storageDisks))
+	metadataArray, _, errs := xl.readMetadataFiles(volume, path)
+	xl.upgradePartsMetadata(volume, path, metadataArray, errs)
+	return metadataArray, errs
+}
+
+// upgradePartsMetadata - upgrades metadata of older formats read from
+// each disk in place, metadata which cannot be upgraded is set as
+// failed in errs.
+func (xl XL) upgradePartsMetadata(volume, path string, metadataArray []fileMetadata, errs []error) {
 	metadataFilePath := slashpath.Join(path, metadataFile)
-	for index, disk := range xl.storageDisks {
-		offset := int64(0)
-		metadataReader, err := disk.ReadFile(volume, metadataFilePath, offset)
+	for index, metadata := range metadataArray {
+		if errs[index] != nil {
+			continue
+		}
+		if _, err := metadata.upgradeFormat(); err != nil {
+			errs[index] = newDiskErr("ReadFile", index, volume, metadataFilePath, err)
+			metadataArray[index] = nil
+		}
+	}
+}
+
+// readPartsMetadata - reads xl.json metadata as written on each
+// disk. Read lockNS() should be done by caller.
+func (xl XL) readPartsMetadata(volume, path string) ([]fileMetadata, []error) {
+	metadataArray, _, errs := xl.readMetadataFiles(volume, path)
+	return metadataArray, errs
+}
+
+// readMetadataFiles - reads metadata as written on each disk, legacy
+// marks disks it was read from part.json on. Read lockNS() should be
+// done by caller.
+func (xl XL) readMetadataFiles(volume, path string) (metadataArray []fileMetadata, legacy []bool, errs []error) {
+	errs = make([]error, len(xl.storageDisks))
+	legacy = make([]bool, len(xl.storageDisks))
+	metadataArray = make([]fileMetadata, len(xl.storageDisks))
+	for index := range xl.storageDisks {
+		metadataReader, metadataFilePath, err := xl.readMetadataFile(index, volume, path)
 		if err != nil {
-			errs[index] = err
+			errs[index] = newDiskErr("ReadFile", index, volume, metadataFilePath, err)
 			continue
 		}
 		defer metadataReader.Close()
 
 		metadata, err := fileMetadataDecode(metadataReader)
 		if err != nil {
-			// Unable to parse parts.json, set error.
-			errs[index] = err
+			// Unable to parse metadata, set error.
+			errs[index] = newDiskErr("ReadFile", index, volume, metadataFilePath, err)
 			continue
 		}
 		metadataArray[index] = metadata
+		legacy[index] = slashpath.Base(metadataFilePath) == legacyMetadataFile
 	}
-	return metadataArray, errs
+	return metadataArray, legacy, errs
 }
 
-// Writes/Updates `parts.json` for given file. updateParts carries
-// index of disks where `parts.json` needs to be updated.
+// Writes/Updates `xl.json` for given file. updateParts carries
+// index of disks where `xl.json` needs to be updated.
 //
 // Returns collection of errors, indexed in accordance with input
 // updateParts order.
@@ -177,7 +243,7 @@ func (xl XL) setPartsMetadata(volume, path string, metadata fileMetadata, update
 		errs[index] = errors.New("Metadata not updated")
 	}
 
-	metadataBytes, err := json.Marshal(metadata)
+	metadataBytes, err := encodeFileMetadata(metadata)
 	if err != nil {
 		for index := range updateParts {
 			errs[index] = err
@@ -189,14 +255,14 @@ func (xl XL) setPartsMetadata(volume, path string, metadata fileMetadata, update
 		if !shouldUpdate {
 			continue
 		}
-		writer, err := xl.storageDisks[index].CreateFile(volume, metadataFilePath)
-		errs[index] = err
+		writer, err := xl.storageDisks[index].CreateFile(withReservedSpace(context.Background()), volume, metadataFilePath)
+		errs[index] = newDiskErr("CreateFile", index, volume, metadataFilePath, err)
 		if err != nil {
 			continue
 		}
 		_, err = writer.Write(metadataBytes)
 		if err != nil {
-			errs[index] = err
+			errs[index] = newDiskErr("CreateFile", index, volume, metadataFilePath, err)
 			safeCloseAndRemove(writer)
 			continue
 		}
@@ -0,0 +1,106 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Environment variables enabling consistency checks of the erasure
+// layer, meant for validating it in CI.
+const (
+	// Set to "on" to read back and verify every committed file.
+	xlVerifyWritesEnv = "MINIO_XL_VERIFY_WRITES"
+	// Probability in range (0, 1] of any disk operation failing.
+	xlChaosEnv = "MINIO_XL_CHAOS"
+)
+
+// errWriteVerify - returned when a committed file read back does not
+// match what was written.
+var errWriteVerify = errors.New("File read back after commit does not match what was written")
+
+// errChaos - returned by disk operations failed in chaos mode.
+var errChaos = errors.New("Disk error injected by chaos mode")
+
+// xlVerifyWrites - returns true if committed files are read back and
+// verified, as set in the environment.
+func xlVerifyWrites(lookup func(string) (string, bool)) bool {
+	value, _ := lookup(xlVerifyWritesEnv)
+	switch strings.ToLower(value) {
+	case "on", "true", "1":
+		return true
+	}
+	return false
+}
+
+// xlChaosProbability - returns probability of disk operations failing
+// as set in the environment, 0 if chaos mode is not enabled.
+func xlChaosProbability(lookup func(string) (string, bool)) (float64, error) {
+	value, ok := lookup(xlChaosEnv)
+	if !ok || value == "" {
+		return 0, nil
+	}
+	probability, e := strconv.ParseFloat(value, 64)
+	if e != nil || probability < 0 || probability > 1 {
+		return 0, fmt.Errorf("%s should be a probability between 0 and 1, got %q", xlChaosEnv, value)
+	}
+	return probability, nil
+}
+
+// verifyWrite - reads back the file at path just committed with size,
+// a read quorum of its parts, enough to decode it, should match their
+// checksums and none be corrupt. Write lockNS() should be done by caller.
+func (xl XL) verifyWrite(volume, path string, size int64) error {
+	verification, err := xl.verifyParts(volume, path)
+	if err != nil {
+		return err
+	}
+	// Files with less parity need more parts to be decoded.
+	quorum := xl.readQuorum
+	if xl.DataBlocks > quorum {
+		quorum = xl.DataBlocks
+	}
+	healthy := 0
+	for index, part := range verification.Parts {
+		switch part.Status {
+		case partStatusHealthy:
+			healthy++
+		case partStatusCorrupt:
+			log.WithFields(logrus.Fields{
+				"volume":    volume,
+				"path":      path,
+				"diskIndex": index,
+			}).Errorf("Verifying write found corrupt part, %s", part.Error)
+			return errWriteVerify
+		}
+	}
+	if healthy < quorum || verification.Size != size {
+		log.WithFields(logrus.Fields{
+			"volume":  volume,
+			"path":    path,
+			"healthy": healthy,
+			"size":    verification.Size,
+		}).Errorf("Verifying write expected %d healthy parts of size %d", quorum, size)
+		return errWriteVerify
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// corruptingDisk - disk flipping the first byte of erasure coded
+// parts, leaving their metadata trailer intact.
+type corruptingDisk struct {
+	StorageAPI
+}
+
+func (d corruptingDisk) CreateFile(ctx context.Context, volume, path string) (io.WriteCloser, error) {
+	w, e := d.StorageAPI.CreateFile(ctx, volume, path)
+	if e != nil || !strings.HasPrefix(filepath.Base(path), "part.") || filepath.Base(path) == metadataFile {
+		return w, e
+	}
+	return &corruptingWriter{WriteCloser: w}, nil
+}
+
+type corruptingWriter struct {
+	io.WriteCloser
+	corrupted bool
+}
+
+func (w *corruptingWriter) Write(p []byte) (int, error) {
+	if w.corrupted || len(p) == 0 {
+		return w.WriteCloser.Write(p)
+	}
+	w.corrupted = true
+	corrupted := append([]byte{}, p...)
+	corrupted[0] ^= 0xff
+	return w.WriteCloser.Write(corrupted)
+}
+
+// Tests consistency check modes are parsed from the environment.
+func TestXLConsistencyEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) (string, bool) {
+		return func(key string) (string, bool) {
+			value, ok := vars[key]
+			return value, ok
+		}
+	}
+	for value, expected := range map[string]bool{"on": true, "ON": true, "1": true, "off": false, "": false} {
+		if verify := xlVerifyWrites(env(map[string]string{xlVerifyWritesEnv: value})); verify != expected {
+			t.Errorf("Expected %v for %q, got %v", expected, value, verify)
+		}
+	}
+	if probability, e := xlChaosProbability(env(nil)); e != nil || probability != 0 {
+		t.Fatalf("Unexpected probability %v, %v", probability, e)
+	}
+	if probability, e := xlChaosProbability(env(map[string]string{xlChaosEnv: "0.25"})); e != nil || probability != 0.25 {
+		t.Fatalf("Unexpected probability %v, %v", probability, e)
+	}
+	for _, value := range []string{"often", "-0.1", "1.5"} {
+		if _, e := xlChaosProbability(env(map[string]string{xlChaosEnv: value})); e == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+// Tests committed files are read back and verified.
+func TestXLVerifyWrites(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	xl.verifyWrites = true
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	write := func(path string) error {
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			return e
+		}
+		if _, e = w.Write(data); e != nil {
+			return e
+		}
+		return w.Close()
+	}
+	if e = write("object"); e != nil {
+		t.Fatal(e)
+	}
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	if readData, e := ioutil.ReadAll(r); e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	// A part not matching its checksum fails the write.
+	xl.storageDisks[1] = corruptingDisk{xl.storageDisks[1]}
+	if e = write("corrupt"); e != errWriteVerify {
+		t.Fatalf("Expected %v, got %v", errWriteVerify, e)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"hash"
@@ -37,7 +38,8 @@ const erasureBlockSize = 4 * 1024 * 1024 // 4MiB.
 func (xl XL) cleanupCreateFileOps(volume, path string, writers ...io.WriteCloser) {
 	closeAndRemoveWriters(writers...)
 	for _, disk := range xl.storageDisks {
-		if err := disk.DeleteFile(volume, path); err != nil {
+		// Cleanup runs even if the write was aborted by its context.
+		if err := disk.DeleteFile(context.Background(), volume, path); err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
@@ -56,24 +58,33 @@ func closeAndRemoveWriters(writers ...io.WriteCloser) {
 }
 
 // WriteErasure reads predefined blocks, encodes them and writes to
-// configured storage disks.
-func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *waitCloser) {
-	// Release the block writer upon function return.
-	defer wcloser.release()
+// configured storage disks. Nothing is committed once ctx is done.
+func (xl XL) writeErasure(ctx context.Context, volume, path string, reader *io.PipeReader, wcloser *waitCloser) {
+	var err error
+	// Release the block writer with the write error upon function return.
+	defer func() {
+		wcloser.release(err)
+	}()
 
 	// Lock right before reading from disk.
 	readLock := true
-	xl.lockNS(volume, path, readLock)
-	partsMetadata, errs := xl.getPartsMetadata(volume, path)
-	xl.unlockNS(volume, path, readLock)
+	var holder *nameSpaceHolder
+	if holder, err = xl.lockNS(volume, path, readLock); err != nil {
+		reader.CloseWithError(err)
+		return
+	}
+	partsMetadata, legacy, errs := xl.readMetadataFiles(volume, path)
+	xl.upgradePartsMetadata(volume, path, partsMetadata, errs)
+	xl.unlockNS(holder)
 
 	// Count errors other than fileNotFound, bigger than the allowed
 	// readQuorum, if yes throw an error.
 	metadataReadErrCount := 0
-	for _, err := range errs {
-		if err != nil && err != errFileNotFound {
+	for _, metadataErr := range errs {
+		if metadataErr != nil && errorCause(metadataErr) != errFileNotFound {
 			metadataReadErrCount++
 			if metadataReadErrCount > xl.readQuorum {
+				err = metadataErr
 				log.WithFields(logrus.Fields{
 					"volume": volume,
 					"path":   path,
@@ -85,7 +96,7 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 	}
 
 	// List all the file versions on existing files.
-	versions, err := listFileVersions(partsMetadata, errs)
+	versions, _ := listFileVersions(partsMetadata, errs)
 	// Get highest file version.
 	higherVersion := highestInt(versions)
 	// Increment to have next higher version.
@@ -104,7 +115,7 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 	for index, disk := range xl.storageDisks {
 		erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
 		var writer io.WriteCloser
-		writer, err = disk.CreateFile(volume, erasurePart)
+		writer, err = disk.CreateFile(ctx, volume, erasurePart)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
@@ -120,40 +131,23 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 
 			// Remove previous temp writers for any failure.
 			xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
-			reader.CloseWithError(errWriteQuorum)
-			return
-		}
-
-		// create meta data file
-		var metadataWriter io.WriteCloser
-		metadataWriter, err = disk.CreateFile(volume, metadataFilePath)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"volume": volume,
-				"path":   path,
-			}).Errorf("CreateFile failed with %s", err)
-			createFileError++
-
-			// We can safely allow CreateFile errors up to
-			// len(xl.storageDisks) - xl.writeQuorum otherwise return failure.
-			if createFileError <= len(xl.storageDisks)-xl.writeQuorum {
-				continue
-			}
-
-			// Remove previous temp writers for any failure.
-			xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
-			reader.CloseWithError(errWriteQuorum)
+			err = errWriteQuorum
+			reader.CloseWithError(err)
 			return
 		}
 
 		writers[index] = writer
-		metadataWriters[index] = metadataWriter
 		sha512Writers[index] = fastSha512.New()
 	}
 
-	// Allocate 4MiB block size buffer for reading.
-	dataBuffer := make([]byte, erasureBlockSize)
-	var totalSize int64 // Saves total incoming stream size.
+	// Read 4MiB blocks into a pooled buffer with room for parity,
+	// encoding does not allocate.
+	buffer := xl.buffers.get()
+	defer xl.buffers.put(buffer)
+	dataBuffer := (*buffer)[:erasureBlockSize]
+	dataBlocks := make([][]byte, len(xl.storageDisks))
+	var totalSize int64   // Saves total incoming stream size.
+	var inlineData []byte // Data of small files, nil otherwise.
 	for {
 		// Read up to allocated block size.
 		var n int
@@ -161,6 +155,10 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 		if err != nil {
 			// Any unexpected errors, close the pipe reader with error.
 			if err != io.ErrUnexpectedEOF && err != io.EOF {
+				// Pipe aborted once ctx is done, report why.
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
 				log.WithFields(logrus.Fields{
 					"volume": volume,
 					"path":   path,
@@ -171,24 +169,20 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 				return
 			}
 		}
+		// Small files are read whole by the first read, they are
+		// inlined in metadata instead of being erasure coded.
+		if totalSize == 0 && err != nil && n <= xlInlineMaxSize {
+			inlineData = append([]byte{}, dataBuffer[:n]...)
+			totalSize = int64(n)
+			break
+		}
 		// At EOF break out.
 		if err == io.EOF {
 			break
 		}
 		if n > 0 {
 			// Split the input buffer into data and parity blocks.
-			var dataBlocks [][]byte
-			dataBlocks, err = xl.ReedSolomon.Split(dataBuffer[0:n])
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("Splitting data buffer into erasure data blocks failed with %s", err)
-				// Remove all temp writers.
-				xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
-				reader.CloseWithError(err)
-				return
-			}
+			dataBlocks = xl.splitBlock(*buffer, n, dataBlocks)
 
 			// Encode parity blocks using data blocks.
 			err = xl.ReedSolomon.Encode(dataBlocks)
@@ -231,73 +225,142 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 		}
 	}
 
+	// Inlined files have no parts, remove temporary parts, their
+	// metadata is written to xl.json on the disks instead.
+	if inlineData != nil {
+		for index, writer := range writers {
+			if writer == nil {
+				continue
+			}
+			closeAndRemoveWriters(writer)
+			writers[index] = nil
+			sha512Writers[index] = nil
+			metadataWriters[index], err = xl.storageDisks[index].CreateFile(ctx, volume, metadataFilePath)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"volume": volume,
+					"path":   path,
+				}).Errorf("CreateFile failed with %s", err)
+				createFileError++
+
+				// We can safely allow CreateFile errors up to
+				// len(xl.storageDisks) - xl.writeQuorum otherwise return failure.
+				if createFileError <= len(xl.storageDisks)-xl.writeQuorum {
+					continue
+				}
+
+				// Remove previous temp writers for any failure.
+				xl.cleanupCreateFileOps(volume, path, metadataWriters...)
+				err = errWriteQuorum
+				reader.CloseWithError(err)
+				return
+			}
+		}
+	}
+
 	// Initialize metadata map, save all erasure related metadata.
 	metadata := make(fileMetadata)
 	metadata.Set("version", minioVersion)
-	metadata.Set("format.major", "1")
-	metadata.Set("format.minor", "0")
-	metadata.Set("format.patch", "0")
-	metadata.Set("file.size", strconv.FormatInt(totalSize, 10))
-	if len(xl.storageDisks) > len(writers) {
-		// Save file.version only if we wrote to less disks than all
-		// storage disks.
-		metadata.Set("file.version", strconv.FormatInt(higherVersion, 10))
+	metadata.SetFormat(xlFormatCurrent)
+	if inlineData != nil {
+		metadata.SetInlineData(inlineData)
 	}
+	metadata.Set("file.size", strconv.FormatInt(totalSize, 10))
+	metadata.Set("file.version", strconv.FormatInt(higherVersion, 10))
 	metadata.Set("file.modTime", modTime.Format(timeFormatAMZ))
 	metadata.Set("file.xl.blockSize", strconv.Itoa(erasureBlockSize))
 	metadata.Set("file.xl.dataBlocks", strconv.Itoa(xl.DataBlocks))
 	metadata.Set("file.xl.parityBlocks", strconv.Itoa(xl.ParityBlocks))
+	// Metadata of the object layer was set before the writer was
+	// closed, before the end of data was read.
+	metadata.SetObjectMetadata(wcloser.metadata)
+
+	if inlineData == nil {
+		// Save sha512 checksum of the encoded blocks of each part.
+		sums := make([]string, len(sha512Writers))
+		for index, sha512Writer := range sha512Writers {
+			if sha512Writer != nil {
+				sums[index] = hex.EncodeToString(sha512Writer.Sum(nil))
+			}
+		}
+		metadata.SetPartSums(sums)
+	}
 
-	// Write all the metadata.
+	// Write all the metadata, the same on all disks.
 	// below case is not handled here
 	// Case: when storageDisks is 16 and write quorumDisks is 13,
 	//       meta data write failure up to 2 can be considered.
 	//       currently we fail for any meta data writes
+	metadataBytes, err := encodeFileMetadata(metadata)
+	if err == nil && inlineData == nil {
+		// Parts are committed along with their metadata, in a
+		// trailer, a single file per disk.
+		trailer := encodePartTrailer(metadataBytes)
+		for index, writer := range writers {
+			if writer == nil {
+				continue
+			}
+			if _, err = writer.Write(trailer); err != nil {
+				log.WithFields(logrus.Fields{
+					"volume":    volume,
+					"path":      path,
+					"diskIndex": index,
+				}).Errorf("Writing metadata failed with %s", err)
+				break
+			}
+		}
+	}
 	for index, metadataWriter := range metadataWriters {
-		if metadataWriter == nil {
+		if metadataWriter == nil || err != nil {
 			continue
 		}
-		if sha512Writers[index] != nil {
-			// Save sha512 checksum of each encoded blocks.
-			metadata.Set("file.xl.block512Sum", hex.EncodeToString(sha512Writers[index].Sum(nil)))
-		}
-
-		// Write metadata.
-		err = metadata.Write(metadataWriter)
-		if err != nil {
+		if _, err = metadataWriter.Write(metadataBytes); err != nil {
 			log.WithFields(logrus.Fields{
 				"volume":    volume,
 				"path":      path,
 				"diskIndex": index,
 			}).Errorf("Writing metadata failed with %s", err)
-			// Remove temporary files.
-			xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
-			reader.CloseWithError(err)
-			return
 		}
 	}
+	if err != nil {
+		// Remove temporary files.
+		xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
+		reader.CloseWithError(err)
+		return
+	}
+
+	// Client went away or the request timed out, do not commit.
+	if err = ctx.Err(); err != nil {
+		xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
+		reader.CloseWithError(err)
+		return
+	}
 
 	// Lock right before commit to disk.
 	readLock = false // false means writeLock.
-	xl.lockNS(volume, path, readLock)
-	defer xl.unlockNS(volume, path, readLock)
+	if holder, err = xl.lockNS(volume, path, readLock); err != nil {
+		xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
+		reader.CloseWithError(err)
+		return
+	}
+	defer xl.unlockNS(holder)
+	xl.blockCache.invalidate(volume, path)
 
 	// Close all writers and metadata writers in routines.
 	for index, writer := range writers {
-		if writer == nil {
-			continue
-		}
 		// Safely wrote, now rename to its actual location.
-		if err = writer.Close(); err != nil {
-			log.WithFields(logrus.Fields{
-				"volume":    volume,
-				"path":      path,
-				"diskIndex": index,
-			}).Errorf("Safely committing part failed with %s", err)
-			// Remove all temp writers upon error.
-			xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
-			reader.CloseWithError(err)
-			return
+		if writer != nil {
+			if err = writer.Close(); err != nil {
+				log.WithFields(logrus.Fields{
+					"volume":    volume,
+					"path":      path,
+					"diskIndex": index,
+				}).Errorf("Safely committing part failed with %s", err)
+				// Remove all temp writers upon error.
+				xl.cleanupCreateFileOps(volume, path, append(writers, metadataWriters...)...)
+				reader.CloseWithError(err)
+				return
+			}
 		}
 
 		if metadataWriters[index] == nil {
@@ -318,13 +381,43 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 
 	}
 
+	// Parts of an earlier erasure coded version are no longer read,
+	// nor is xl.json of an earlier inlined version.
+	if inlineData != nil {
+		xl.removeErasureParts(volume, path)
+	} else {
+		written := make([]bool, len(writers))
+		for index, writer := range writers {
+			written[index] = writer != nil
+		}
+		xl.removeMetadataFile(volume, path, written)
+	}
+	// Nor is metadata of an earlier version written before xl.json.
+	xl.removeLegacyMetadata(volume, path, legacy)
+
+	// Read back the committed file, when verifying writes.
+	if xl.verifyWrites {
+		if err = xl.verifyWrite(volume, path, totalSize); err != nil {
+			reader.CloseWithError(err)
+			return
+		}
+	}
+
 	// Close the pipe reader and return.
+	err = nil
 	reader.Close()
 	return
 }
 
-// CreateFile - create a file.
-func (xl XL) CreateFile(volume, path string) (writeCloser io.WriteCloser, err error) {
+// CreateFile - create a file, the write is aborted and cleaned up once
+// ctx is done.
+func (xl XL) CreateFile(ctx context.Context, volume, path string) (writeCloser io.WriteCloser, err error) {
+	return xl.createFile(ctx, volume, path, 0)
+}
+
+// createFile - creates a file erasure coded with parityBlocks parity
+// blocks, the default of the disks if 0.
+func (xl XL) createFile(ctx context.Context, volume, path string, parityBlocks int) (writeCloser io.WriteCloser, err error) {
 	if !isValidVolname(volume) {
 		return nil, errInvalidArgument
 	}
@@ -332,14 +425,40 @@ func (xl XL) CreateFile(volume, path string) (writeCloser io.WriteCloser, err er
 		return nil, errInvalidArgument
 	}
 
+	if xl, err = xl.volumeDisks(volume); err != nil {
+		return nil, err
+	}
+	if xl, err = xl.withParity(parityBlocks); err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Initialize pipe for data pipe line.
 	pipeReader, pipeWriter := io.Pipe()
 
 	// Initialize a new wait closer, implements both Write and Close.
 	wcloser := newWaitCloser(pipeWriter)
 
-	// Start erasure encoding in routine, reading data block by block from pipeReader.
-	go xl.writeErasure(volume, path, pipeReader, wcloser)
+	// Start erasure encoding in routine, reading data block by block
+	// from pipeReader. Shutdown waits for it to commit or roll back.
+	endCommit := globalInFlightCommits.begin()
+	done := make(chan struct{})
+	go func() {
+		defer endCommit()
+		defer close(done)
+		xl.writeErasure(ctx, volume, path, pipeReader, wcloser)
+	}()
+
+	// Abort the pipe once ctx is done, unblocking both ends.
+	go func() {
+		select {
+		case <-ctx.Done():
+			pipeReader.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
 
 	// Return the writer, caller should start writing to this.
 	return wcloser, nil
@@ -0,0 +1,201 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+// Tests cancelled writes and reads are aborted and cleaned up.
+func TestXLContextCancel(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+
+	// Write cancelled midway is not committed.
+	ctx, cancel := context.WithCancel(context.Background())
+	w, e := xl.CreateFile(ctx, "bucket", "cancelled")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(bytes.Repeat([]byte("a"), erasureBlockSize+1)); e != nil {
+		t.Fatal(e)
+	}
+	cancel()
+	if e = w.Close(); e != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, e)
+	}
+	if _, e = xl.StatFile("bucket", "cancelled"); e != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, e)
+	}
+
+	// Aborted write is cleaned up.
+	w, e = xl.CreateFile(context.Background(), "bucket", "aborted")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write([]byte("data")); e != nil {
+		t.Fatal(e)
+	}
+	if e = safeCloseAndRemove(w); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = xl.StatFile("bucket", "aborted"); e != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, e)
+	}
+
+	// Read is aborted once cancelled.
+	w, e = xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(bytes.Repeat([]byte("a"), 2*erasureBlockSize)); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	r, e := xl.ReadFile(ctx, "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	if _, e = r.Read(make([]byte, 1024)); e != nil {
+		t.Fatal(e)
+	}
+	cancel()
+	if _, e = ioutil.ReadAll(r); e != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, e)
+	}
+
+	// Nothing is done for cancelled contexts.
+	if _, e = xl.CreateFile(ctx, "bucket", "object"); e != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, e)
+	}
+	if e = xl.DeleteFile(ctx, "bucket", "object"); e != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, e)
+	}
+}
+
+// Tests files ending with a block shorter than the number of data
+// blocks are written, buffers reused by later writes are padded.
+func TestXLCreateFileShortBlock(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	for i, data := range [][]byte{
+		bytes.Repeat([]byte("a"), 2*erasureBlockSize),
+		append(bytes.Repeat([]byte("b"), erasureBlockSize), 'c'),
+	} {
+		path := fmt.Sprintf("object-%d", i)
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		r, e := xl.ReadFile(context.Background(), "bucket", path, 0)
+		if e != nil {
+			t.Fatal(e)
+		}
+		read, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(read, data) {
+			t.Fatalf("Test %d: Unexpected data read", i+1)
+		}
+	}
+}
+
+// Benchmarks concurrent erasure coded writes of 8MiB files.
+func BenchmarkXLCreateFile(b *testing.B) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			b.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		b.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		b.Fatal(e)
+	}
+	data := bytes.Repeat([]byte("a"), 2*erasureBlockSize)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	var writer int64
+	b.RunParallel(func(pb *testing.PB) {
+		path := fmt.Sprintf("object-%d", atomic.AddInt64(&writer, 1))
+		for pb.Next() {
+			w, e := xl.CreateFile(context.Background(), "bucket", path)
+			if e != nil {
+				b.Fatal(e)
+			}
+			if _, e = w.Write(data); e != nil {
+				b.Fatal(e)
+			}
+			if e = w.Close(); e != nil {
+				b.Fatal(e)
+			}
+		}
+	})
+}
@@ -0,0 +1,221 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+const (
+	// Identity of a disk, saved in minioMetaVolume of each disk.
+	xlDiskFormatFile = "format.json"
+	// Version of the disk format.
+	xlDiskFormatVersion = "1"
+)
+
+// xlDiskFormat - identity of a disk of an XL deployment, saved at first
+// start. Parts of a file are read by the index of their disk, disks
+// are put back in their order of first start if they are passed in a
+// different order.
+type xlDiskFormat struct {
+	Version string `json:"version"`
+	// UUID of the deployment, shared by all its disks.
+	Deployment string `json:"deployment"`
+	// UUID of this disk.
+	Disk string `json:"disk"`
+	// UUIDs of all disks of the deployment, in erasure order.
+	Disks []string `json:"disks"`
+}
+
+// readDiskFormat - reads format of disk, errFileNotFound if the disk
+// was not formatted yet.
+func readDiskFormat(disk StorageAPI) (xlDiskFormat, error) {
+	reader, err := disk.ReadFile(context.Background(), minioMetaVolume, xlDiskFormatFile, 0)
+	if err != nil {
+		if cause := errorCause(err); cause == errVolumeNotFound || cause == errFileNotFound {
+			err = errFileNotFound
+		}
+		return xlDiskFormat{}, err
+	}
+	defer reader.Close()
+	var format xlDiskFormat
+	if err = json.NewDecoder(reader).Decode(&format); err != nil {
+		return xlDiskFormat{}, err
+	}
+	if format.Version != xlDiskFormatVersion {
+		return xlDiskFormat{}, fmt.Errorf("Unsupported disk format version %q", format.Version)
+	}
+	return format, nil
+}
+
+// writeDiskFormat - saves format of disk.
+func writeDiskFormat(disk StorageAPI, format xlDiskFormat) error {
+	if err := disk.MakeVol(minioMetaVolume); err != nil && errorCause(err) != errVolumeExists {
+		return err
+	}
+	formatBytes, err := json.Marshal(format)
+	if err != nil {
+		return err
+	}
+	writer, err := disk.CreateFile(context.Background(), minioMetaVolume, xlDiskFormatFile)
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(formatBytes); err != nil {
+		safeCloseAndRemove(writer)
+		return err
+	}
+	return writer.Close()
+}
+
+// newUUID - returns a new random UUID.
+func newUUID() (string, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// loadDiskFormats - formats disks at first start, and puts disks back
+// in the order they were formatted in. Disks replaced by unformatted
+// ones are formatted with the identity of the disk they replace. Disks
+// of other deployments, or passed more than once, are refused.
+func (xl *XL) loadDiskFormats() error {
+	formats := make([]xlDiskFormat, len(xl.storageDisks))
+	var reference *xlDiskFormat
+	referenceIndex := 0
+	for index, disk := range xl.storageDisks {
+		format, err := readDiskFormat(disk)
+		if err == errFileNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to read format of disk %s, %s", xl.diskPaths[index], err)
+		}
+		if reference == nil {
+			reference, referenceIndex = &format, index
+		}
+		if format.Deployment != reference.Deployment {
+			return fmt.Errorf("Disk %s belongs to deployment %s, disk %s to deployment %s", xl.diskPaths[index], format.Deployment, xl.diskPaths[referenceIndex], reference.Deployment)
+		}
+		if len(format.Disks) != len(xl.storageDisks) {
+			return fmt.Errorf("Disk %s was formatted with %d disks, %d disks passed", xl.diskPaths[index], len(format.Disks), len(xl.storageDisks))
+		}
+		formats[index] = format
+	}
+
+	// First start, format all disks in their order.
+	if reference == nil {
+		deployment, err := newUUID()
+		if err != nil {
+			return err
+		}
+		disks := make([]string, len(xl.storageDisks))
+		for index := range disks {
+			if disks[index], err = newUUID(); err != nil {
+				return err
+			}
+		}
+		for index, disk := range xl.storageDisks {
+			format := xlDiskFormat{
+				Version:    xlDiskFormatVersion,
+				Deployment: deployment,
+				Disk:       disks[index],
+				Disks:      disks,
+			}
+			if err = writeDiskFormat(disk, format); err != nil {
+				return fmt.Errorf("Unable to format disk %s, %s", xl.diskPaths[index], err)
+			}
+		}
+		return nil
+	}
+
+	// Place formatted disks at their index.
+	order := make([]int, len(xl.storageDisks))
+	placed := make([]bool, len(xl.storageDisks))
+	for index := range order {
+		order[index] = -1
+	}
+	slots := make(map[string]int)
+	for slot, diskID := range reference.Disks {
+		slots[diskID] = slot
+	}
+	for index, format := range formats {
+		if format.Disk == "" {
+			continue
+		}
+		slot, ok := slots[format.Disk]
+		if !ok {
+			return fmt.Errorf("Disk %s is not a disk of deployment %s", xl.diskPaths[index], reference.Deployment)
+		}
+		if order[slot] != -1 {
+			return fmt.Errorf("Disks %s and %s are the same disk %s", xl.diskPaths[order[slot]], xl.diskPaths[index], format.Disk)
+		}
+		order[slot] = index
+		placed[index] = true
+	}
+	// Unformatted disks replace the disks missing, in their order.
+	for index := range xl.storageDisks {
+		if placed[index] {
+			continue
+		}
+		for slot := range order {
+			if order[slot] == -1 {
+				order[slot] = index
+				break
+			}
+		}
+	}
+
+	storageDisks := make([]StorageAPI, len(xl.storageDisks))
+	diskPaths := make([]string, len(xl.diskPaths))
+	for slot, index := range order {
+		storageDisks[slot] = xl.storageDisks[index]
+		diskPaths[slot] = xl.diskPaths[index]
+		if slot != index {
+			log.WithFields(logrus.Fields{
+				"disk":  xl.diskPaths[index],
+				"index": slot,
+			}).Warnf("Disk was passed at index %d, it is used at its index of first start", index)
+		}
+		if placed[index] {
+			continue
+		}
+		format := xlDiskFormat{
+			Version:    xlDiskFormatVersion,
+			Deployment: reference.Deployment,
+			Disk:       reference.Disks[slot],
+			Disks:      reference.Disks,
+		}
+		if err := writeDiskFormat(xl.storageDisks[index], format); err != nil {
+			return fmt.Errorf("Unable to format disk %s, %s", xl.diskPaths[index], err)
+		}
+		log.WithFields(logrus.Fields{
+			"disk":  xl.diskPaths[index],
+			"index": slot,
+		}).Warnf("Unformatted disk replaces disk %s", format.Disk)
+	}
+	xl.storageDisks = storageDisks
+	xl.diskPaths = diskPaths
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests disks are identified by their format, and put back in their
+// order of first start.
+func TestXLDiskFormat(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	formats := make(map[string]xlDiskFormat)
+	for index, disk := range xl.storageDisks {
+		format, e := readDiskFormat(disk)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if format.Disk != format.Disks[index] {
+			t.Fatalf("Unexpected format %+v of disk %d", format, index)
+		}
+		if index > 0 && format.Deployment != formats[disks[0]].Deployment {
+			t.Fatalf("Expected disks of the same deployment, got %s and %s", formats[disks[0]].Deployment, format.Deployment)
+		}
+		formats[disks[index]] = format
+	}
+
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	// Disks passed in another order are used in their order of first
+	// start.
+	reversed := []string{disks[3], disks[2], disks[1], disks[0]}
+	if storage, e = newXL(reversed...); e != nil {
+		t.Fatal(e)
+	}
+	xl = storage.(*XL)
+	for index, disk := range xl.diskPaths {
+		if disk != disks[index] {
+			t.Fatalf("Expected disk %s at index %d, got %s", disks[index], index, disk)
+		}
+	}
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	readData, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	// An unformatted disk replaces the disk missing.
+	replacement, e := ioutil.TempDir("", "minio-xl-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(replacement)
+	if storage, e = newXL(disks[0], replacement, disks[2], disks[3]); e != nil {
+		t.Fatal(e)
+	}
+	format, e := readDiskFormat(storage.(*XL).storageDisks[1])
+	if e != nil {
+		t.Fatal(e)
+	}
+	if format.Disk != formats[disks[1]].Disk || format.Deployment != formats[disks[1]].Deployment {
+		t.Fatalf("Expected replacement disk to be formatted as %+v, got %+v", formats[disks[1]], format)
+	}
+
+	// Disks of another deployment are refused.
+	var others []string
+	for i := 0; i < 4; i++ {
+		other, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(other)
+		others = append(others, other)
+	}
+	if _, e = newXL(others...); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = newXL(disks[0], others[1], disks[2], disks[3]); e == nil {
+		t.Fatal("Expected disk of another deployment to be refused")
+	}
+
+	// A disk passed twice is refused.
+	if _, e = newXL(disks[0], disks[0], disks[2], disks[3]); e == nil {
+		t.Fatal("Expected disk passed twice to be refused")
+	}
+}
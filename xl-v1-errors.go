@@ -27,8 +27,41 @@ var errMaxDisks = errors.New("Total number of disks specified is higher than sup
 // errNumDisks - returned for odd numebr of disks.
 var errNumDisks = errors.New("Invalid number of disks provided, should be always multiples of '2'")
 
+// errInvalidParity - returned for parity other than between one and
+// half of the disks.
+var errInvalidParity = errors.New("Invalid number of parity blocks, should be between '1' and half of the disks")
+
 // errModTime - returned for missing file modtime.
 var errModTime = errors.New("Missing 'file.modTime' in metadata")
 
 // errUnexpected - returned for any unexpected error.
 var errUnexpected = errors.New("Unexpected error - please report at https://github.com/minio/minio/issues")
+
+// errLockTimeout - returned when a namespace lock is not acquired
+// within the configured acquire timeout.
+var errLockTimeout = errors.New("Timed out waiting for a namespace lock")
+
+// errStaleDisk - returned for disks with an older version of the file.
+var errStaleDisk = errors.New("Disk has an older version of the file")
+
+// errDiskNotOnline - returned for disks without readable metadata of
+// the file.
+var errDiskNotOnline = errors.New("Disk has no readable metadata of the file")
+
+// diskError - error of a single disk, disks are identified by their path.
+type diskError struct {
+	Disk  string `json:"disk"`
+	Error string `json:"error"`
+}
+
+// readQuorumError - returned when fewer disks than ReadQuorum are
+// readable, carries the errors of the failed disks.
+type readQuorumError struct {
+	ReadQuorum int
+	Available  int
+	Disks      []diskError
+}
+
+func (e readQuorumError) Error() string {
+	return errReadQuorum.Error()
+}
@@ -0,0 +1,213 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	slashpath "path"
+	"strconv"
+)
+
+// xlFormat - version of the layout of file metadata. Minor versions
+// only add to the layout, metadata of a newer minor version is read
+// by servers knowing an older one. Metadata of a newer major version
+// is not read.
+type xlFormat struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (f xlFormat) String() string {
+	return fmt.Sprintf("%d.%d.%d", f.Major, f.Minor, f.Patch)
+}
+
+var (
+	// Metadata written before its format was recorded.
+	xlFormatLegacy = xlFormat{0, 0, 0}
+	// Erasure coded files.
+	xlFormatV1 = xlFormat{1, 0, 0}
+	// Files inlined in their metadata.
+	xlFormatV1Inline = xlFormat{1, 1, 0}
+	// file.version is always recorded, zero for files written to all
+	// disks.
+	xlFormatV1FileVersion = xlFormat{1, 2, 0}
+	// Metadata holds the checksums of all parts, in xl.json.
+	xlFormatV1Parts = xlFormat{1, 3, 0}
+
+	// Format of metadata written.
+	xlFormatCurrent = xlFormatV1Parts
+)
+
+var errUnsupportedFormat = errors.New("Metadata format is newer than supported")
+
+// xlFormatUpgrade - upgrades metadata of format from to format to.
+type xlFormatUpgrade struct {
+	from    xlFormat
+	to      xlFormat
+	upgrade func(metadata fileMetadata)
+}
+
+// xlFormatUpgrades - upgrades of each older format, applied in order
+// until metadata is of the current format.
+var xlFormatUpgrades = []xlFormatUpgrade{
+	{
+		from: xlFormatLegacy,
+		to:   xlFormatV1,
+		upgrade: func(metadata fileMetadata) {
+			// Files were always erasure coded in blocks of the
+			// same size.
+			if metadata.Get("file.xl.blockSize") == nil {
+				metadata.Set("file.xl.blockSize", strconv.Itoa(erasureBlockSize))
+			}
+		},
+	},
+	{
+		// Erasure coded files are unchanged.
+		from:    xlFormatV1,
+		to:      xlFormatV1Inline,
+		upgrade: func(metadata fileMetadata) {},
+	},
+	{
+		from: xlFormatV1Inline,
+		to:   xlFormatV1FileVersion,
+		upgrade: func(metadata fileMetadata) {
+			if metadata.Get("file.version") == nil {
+				metadata.SetFileVersion(0)
+			}
+		},
+	},
+	{
+		// Checksums of legacy metadata are of the part of its own
+		// disk, all are gathered once the file is migrated.
+		from:    xlFormatV1FileVersion,
+		to:      xlFormatV1Parts,
+		upgrade: func(metadata fileMetadata) {},
+	},
+}
+
+// GetFormat - returns format of metadata, xlFormatLegacy if it is not
+// recorded.
+func (f fileMetadata) GetFormat() (xlFormat, error) {
+	var format xlFormat
+	for _, field := range []struct {
+		key   string
+		value *int
+	}{
+		{"format.major", &format.Major},
+		{"format.minor", &format.Minor},
+		{"format.patch", &format.Patch},
+	} {
+		values := f.Get(field.key)
+		if values == nil {
+			return xlFormatLegacy, nil
+		}
+		value, err := strconv.Atoi(values[0])
+		if err != nil {
+			return xlFormat{}, err
+		}
+		*field.value = value
+	}
+	return format, nil
+}
+
+// SetFormat - sets format of metadata.
+func (f fileMetadata) SetFormat(format xlFormat) {
+	f.Set("format.major", strconv.Itoa(format.Major))
+	f.Set("format.minor", strconv.Itoa(format.Minor))
+	f.Set("format.patch", strconv.Itoa(format.Patch))
+}
+
+// upgradeFormat - upgrades metadata of an older format to the current
+// one in place, returns true if it was upgraded. Metadata of a newer
+// major format returns errUnsupportedFormat.
+func (f fileMetadata) upgradeFormat() (bool, error) {
+	format, err := f.GetFormat()
+	if err != nil {
+		return false, err
+	}
+	if format.Major > xlFormatCurrent.Major {
+		return false, errUnsupportedFormat
+	}
+	upgraded := false
+	for _, upgrade := range xlFormatUpgrades {
+		if upgrade.from != format {
+			continue
+		}
+		upgrade.upgrade(f)
+		f.SetFormat(upgrade.to)
+		format = upgrade.to
+		upgraded = true
+	}
+	return upgraded, nil
+}
+
+// migrateFile - rewrites metadata of older formats of the file at path
+// in the current format, returns true if any was. Metadata is only
+// checked if dryRun is set.
+func (xl XL) migrateFile(volume, path string, dryRun bool) (bool, error) {
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return false, err
+	}
+
+	// Acquire a write lock, so that metadata written meanwhile is not
+	// overwritten.
+	readLock := false
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return false, err
+	}
+	defer xl.unlockNS(holder)
+
+	migrated := false
+	partsMetadata, legacy, errs := xl.readMetadataFiles(volume, path)
+	needsMigration := make([]bool, len(xl.storageDisks))
+	for index, metadata := range partsMetadata {
+		// Missing and unreadable metadata is healed instead.
+		if errs[index] != nil {
+			continue
+		}
+		upgraded, err := metadata.upgradeFormat()
+		if err != nil {
+			return migrated, newDiskErr("ReadFile", index, volume, slashpath.Join(path, metadataFile), err)
+		}
+		needsMigration[index] = upgraded || legacy[index]
+		migrated = migrated || needsMigration[index]
+	}
+	if !migrated || dryRun {
+		return migrated, nil
+	}
+	for index, metadata := range partsMetadata {
+		if !needsMigration[index] {
+			continue
+		}
+		// Legacy metadata of each disk holds the checksum of its own
+		// part, xl.json holds the checksums of all parts written along.
+		if !metadata.IsInline() {
+			metadata.SetPartSums(consolidatePartSums(metadata, partsMetadata))
+		}
+		updateParts := make([]bool, len(xl.storageDisks))
+		updateParts[index] = true
+		if err = xl.setPartsMetadata(volume, path, metadata, updateParts)[index]; err != nil {
+			return migrated, err
+		}
+	}
+	xl.removeLegacyMetadata(volume, path, legacy)
+	return migrated, nil
+}
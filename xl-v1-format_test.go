@@ -0,0 +1,190 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Tests metadata of older formats is upgraded to the current one.
+func TestXLFormatUpgrade(t *testing.T) {
+	legacy := fileMetadata{
+		"file.size":    {"10"},
+		"file.modTime": {"2016-01-01T00:00:00.000Z"},
+	}
+	upgraded, e := legacy.upgradeFormat()
+	if e != nil || !upgraded {
+		t.Fatalf("Expected legacy metadata to be upgraded, %v", e)
+	}
+	if format, _ := legacy.GetFormat(); format != xlFormatCurrent {
+		t.Fatalf("Expected format %s, got %s", xlFormatCurrent, format)
+	}
+	if version, e := legacy.GetFileVersion(); e != nil || version != 0 {
+		t.Fatalf("Unexpected file version %d, %v", version, e)
+	}
+	if blockSize := legacy.Get("file.xl.blockSize"); blockSize == nil || blockSize[0] != "4194304" {
+		t.Fatalf("Unexpected block size %v", blockSize)
+	}
+
+	// File versions recorded are kept.
+	v1 := fileMetadata{"file.version": {"3"}}
+	v1.SetFormat(xlFormatV1)
+	if upgraded, e = v1.upgradeFormat(); e != nil || !upgraded {
+		t.Fatalf("Expected metadata to be upgraded, %v", e)
+	}
+	if version, _ := v1.GetFileVersion(); version != 3 {
+		t.Fatalf("Unexpected file version %d", version)
+	}
+
+	// Newer minor formats are read as is, newer major ones are not.
+	newer := fileMetadata{}
+	newer.SetFormat(xlFormat{xlFormatCurrent.Major, xlFormatCurrent.Minor + 1, 0})
+	if upgraded, e = newer.upgradeFormat(); e != nil || upgraded {
+		t.Fatalf("Expected metadata not to be upgraded, %v", e)
+	}
+	newer.SetFormat(xlFormat{xlFormatCurrent.Major + 1, 0, 0})
+	if _, e = newer.upgradeFormat(); e != errUnsupportedFormat {
+		t.Fatalf("Expected %v, got %v", errUnsupportedFormat, e)
+	}
+}
+
+// Tests files with metadata of older formats are read, and migrated.
+func TestXLMigrateFormat(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	for _, path := range []string{"legacy", "dir/current"} {
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	// Rewrite metadata as written before formats were recorded, in
+	// part.json of each disk holding the checksum of its own part.
+	for index, disk := range disks {
+		metadataBytes := readMetadataBytes(t, xl, index, "legacy")
+		metadata := make(fileMetadata)
+		if e = json.Unmarshal(metadataBytes, &metadata); e != nil {
+			t.Fatal(e)
+		}
+		for _, key := range []string{"format.major", "format.minor", "format.patch", "file.version"} {
+			delete(metadata, key)
+		}
+		metadata.Set("file.xl.block512Sum", metadata.GetPartSum(index))
+		delete(metadata, "file.xl.parts512Sum")
+		if metadataBytes, e = json.Marshal(metadata); e != nil {
+			t.Fatal(e)
+		}
+		// Parts were written without metadata trailer.
+		partPath := filepath.Join(disk, "bucket", "legacy", fmt.Sprintf("part.%d", index))
+		if e = os.Truncate(partPath, xl.partSize(int64(len(data)))); e != nil {
+			t.Fatal(e)
+		}
+		legacyPath := filepath.Join(disk, "bucket", "legacy", legacyMetadataFile)
+		if e = ioutil.WriteFile(legacyPath, metadataBytes, 0600); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if verification, e := xl.verifyFile("bucket", "legacy", false); e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+	r, e := xl.ReadFile(context.Background(), "bucket", "legacy", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	readData, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	migrate := func(dryRun bool) (scanned, migrated int) {
+		e := migrateFormat(xl, xl, dryRun, func(volume, path string, fileMigrated bool, e error) {
+			if e != nil {
+				t.Fatal(e)
+			}
+			scanned++
+			if fileMigrated {
+				migrated++
+			}
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		return scanned, migrated
+	}
+	if scanned, migrated := migrate(true); scanned != 2 || migrated != 1 {
+		t.Fatalf("Unexpected dry run, %d of %d files migrated", migrated, scanned)
+	}
+	if scanned, migrated := migrate(false); scanned != 2 || migrated != 1 {
+		t.Fatalf("Unexpected migration, %d of %d files migrated", migrated, scanned)
+	}
+	if _, migrated := migrate(false); migrated != 0 {
+		t.Fatalf("Expected migrated files not to be migrated again, %d were", migrated)
+	}
+	partsMetadata, errs := xl.readPartsMetadata("bucket", "legacy")
+	for index, metadata := range partsMetadata {
+		if errs[index] != nil {
+			t.Fatal(errs[index])
+		}
+		if format, _ := metadata.GetFormat(); format != xlFormatCurrent {
+			t.Fatalf("Expected format %s, got %s", xlFormatCurrent, format)
+		}
+		if !reflect.DeepEqual(metadata, partsMetadata[0]) {
+			t.Fatal("Expected migrated metadata to be the same on all disks")
+		}
+		if _, e = os.Stat(filepath.Join(disks[index], "bucket", "legacy", legacyMetadataFile)); !os.IsNotExist(e) {
+			t.Fatalf("Expected legacy metadata removed, %v", e)
+		}
+	}
+
+	// Checksums of all parts are gathered from each disk.
+	verification, e := xl.verifyFile("bucket", "legacy", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
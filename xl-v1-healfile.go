@@ -17,25 +17,45 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	slashpath "path"
+	"sync"
 
 	"github.com/Sirupsen/logrus"
+	fastSha512 "github.com/minio/minio/pkg/crypto/sha512"
 )
 
 // healHeal - heals the file at path.
 func (xl XL) healFile(volume string, path string) error {
+	return xl.repairFile(volume, path, nil)
+}
+
+// repairFile - heals the file at path, parts of disks marked in corrupt
+// are rebuilt along with missing and outdated parts.
+func (xl XL) repairFile(volume, path string, corrupt []bool) error {
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return err
+	}
+
 	totalBlocks := xl.DataBlocks + xl.ParityBlocks
 	needsHeal := make([]bool, totalBlocks)
-	var readers = make([]io.Reader, totalBlocks)
 	var writers = make([]io.WriteCloser, totalBlocks)
+	// Checksums of the healed parts.
+	var hashes = make([]hash.Hash, totalBlocks)
 
 	// Acquire a read lock.
 	readLock := true
-	xl.lockNS(volume, path, readLock)
-	defer xl.unlockNS(volume, path, readLock)
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return err
+	}
+	defer xl.unlockNS(holder)
 
 	// Fetch all online disks.
 	onlineDisks, metadata, heal, err := xl.listOnlineDisks(volume, path)
@@ -46,6 +66,10 @@ func (xl XL) healFile(volume string, path string) error {
 		}).Errorf("List online disks failed with %s", err)
 		return err
 	}
+	for index, disk := range onlineDisks {
+		needsHeal[index] = disk == nil || (corrupt != nil && corrupt[index])
+		heal = heal || needsHeal[index]
+	}
 	if !heal {
 		return nil
 	}
@@ -59,33 +83,36 @@ func (xl XL) healFile(volume string, path string) error {
 		return err
 	}
 
-	for index, disk := range onlineDisks {
-		if disk == nil {
-			needsHeal[index] = true
-			continue
+	// Inlined files are healed by writing their metadata.
+	if metadata.IsInline() {
+		// Metadata with corrupted data is not copied.
+		if _, _, err = metadata.GetInlineData(); err != nil {
+			partsMetadata, _ := xl.getPartsMetadata(volume, path)
+			for index, partMetadata := range partsMetadata {
+				if needsHeal[index] || partMetadata == nil {
+					continue
+				}
+				if _, _, err = partMetadata.GetInlineData(); err == nil {
+					metadata = partMetadata
+					break
+				}
+			}
+			if err != nil {
+				return err
+			}
 		}
-		erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
-		// If disk.ReadFile returns error and we don't have read quorum it will be taken care as
-		// ReedSolomon.Reconstruct() will fail later.
-		var reader io.ReadCloser
-		offset := int64(0)
-		if reader, err = xl.storageDisks[index].ReadFile(volume, erasurePart, offset); err == nil {
-			readers[index] = reader
-			defer reader.Close()
+		errs := xl.setPartsMetadata(volume, path, metadata, needsHeal)
+		for index, healNeeded := range needsHeal {
+			if healNeeded && errs[index] != nil {
+				return errs[index]
+			}
 		}
+		return nil
 	}
 
-	// Check if there is atleast one part that needs to be healed.
-	atleastOneHeal := false
-	for _, healNeeded := range needsHeal {
-		if healNeeded {
-			atleastOneHeal = true
-			break
-		}
-	}
-	if !atleastOneHeal {
-		// Return if healing not needed anywhere.
-		return nil
+	// Parts are rebuilt with the parity the file was written with.
+	if xl, err = xl.forFile(metadata); err != nil {
+		return err
 	}
 
 	// create writers for parts where healing is needed.
@@ -94,8 +121,9 @@ func (xl XL) healFile(volume string, path string) error {
 			continue
 		}
 		erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
-		writers[index], err = xl.storageDisks[index].CreateFile(volume, erasurePart)
+		writers[index], err = xl.storageDisks[index].CreateFile(withReservedSpace(context.Background()), volume, erasurePart)
 		if err != nil {
+			err = newDiskErr("CreateFile", index, volume, erasurePart, err)
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
@@ -104,99 +132,37 @@ func (xl XL) healFile(volume string, path string) error {
 			closeAndRemoveWriters(writers...)
 			return err
 		}
+		hashes[index] = fastSha512.New()
 	}
-	var totalLeft = size
-	for totalLeft > 0 {
-		// Figure out the right blockSize.
-		var curBlockSize int
-		if erasureBlockSize < totalLeft {
-			curBlockSize = erasureBlockSize
-		} else {
-			curBlockSize = int(totalLeft)
-		}
-		// Calculate the current block size.
-		curBlockSize = getEncodedBlockLen(curBlockSize, xl.DataBlocks)
-		enBlocks := make([][]byte, totalBlocks)
-		// Loop through all readers and read.
-		for index, reader := range readers {
-			// Initialize block slice and fill the data from each parts.
-			// ReedSolomon.Verify() expects that slice is not nil even if the particular
-			// part needs healing.
-			enBlocks[index] = make([]byte, curBlockSize)
-			if needsHeal[index] {
-				// Skip reading if the part needs healing.
-				continue
-			}
-			_, err = io.ReadFull(reader, enBlocks[index])
-			if err != nil && err != io.ErrUnexpectedEOF {
-				enBlocks[index] = nil
-			}
-		}
-
-		// Check blocks if they are all zero in length.
-		if checkBlockSize(enBlocks) == 0 {
-			log.WithFields(logrus.Fields{
-				"volume": volume,
-				"path":   path,
-			}).Errorf("%s", errDataCorrupt)
-			return errDataCorrupt
-		}
 
-		// Verify the blocks.
-		ok, err := xl.ReedSolomon.Verify(enBlocks)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"volume": volume,
-				"path":   path,
-			}).Errorf("ReedSolomon verify failed with %s", err)
-			closeAndRemoveWriters(writers...)
-			return err
+	// Ranges of blocks are rebuilt in parallel, blocks are written in
+	// order as their ranges rebuild them.
+	stop := make(chan struct{})
+	var ranges []chan xlHealedBlock
+	blockCount := (size + erasureBlockSize - 1) / erasureBlockSize
+	rangeBlocks := (blockCount + xlHealRanges - 1) / xlHealRanges
+	for first := int64(0); first < blockCount; first += rangeBlocks {
+		end := first + rangeBlocks
+		if end > blockCount {
+			end = blockCount
 		}
-
-		// Verification failed, blocks require reconstruction.
-		if !ok {
-			for index, healNeeded := range needsHeal {
-				if healNeeded {
-					// Reconstructs() reconstructs the parts if the array is nil.
-					enBlocks[index] = nil
-				}
-			}
-			err = xl.ReedSolomon.Reconstruct(enBlocks)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("ReedSolomon reconstruct failed with %s", err)
-				closeAndRemoveWriters(writers...)
-				return err
-			}
-			// Verify reconstructed blocks again.
-			ok, err = xl.ReedSolomon.Verify(enBlocks)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("ReedSolomon verify failed with %s", err)
-				closeAndRemoveWriters(writers...)
-				return err
-			}
-			if !ok {
-				// Blocks cannot be reconstructed, corrupted data.
-				err = errors.New("Verification failed after reconstruction, data likely corrupted.")
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("%s", err)
-				closeAndRemoveWriters(writers...)
-				return err
+		ranges = append(ranges, xl.healRange(volume, path, needsHeal, first, end, size, stop))
+	}
+	defer func() {
+		// Wait for blocks being rebuilt before returning.
+		close(stop)
+		for _, blocks := range ranges {
+			for range blocks {
 			}
 		}
-		for index, healNeeded := range needsHeal {
-			if !healNeeded {
-				continue
+	}()
+	for _, blocks := range ranges {
+		for healed := range blocks {
+			if healed.err != nil {
+				closeAndRemoveWriters(writers...)
+				return healed.err
 			}
-			_, err := writers[index].Write(enBlocks[index])
-			if err != nil {
+			if err = writeHealedParts(writers, hashes, healed.enBlocks, needsHeal); err != nil {
 				log.WithFields(logrus.Fields{
 					"volume": volume,
 					"path":   path,
@@ -205,7 +171,6 @@ func (xl XL) healFile(volume string, path string) error {
 				return err
 			}
 		}
-		totalLeft = totalLeft - erasureBlockSize
 	}
 
 	// After successful healing Close() the writer so that the temp
@@ -217,11 +182,197 @@ func (xl XL) healFile(volume string, path string) error {
 		writer.Close()
 	}
 
-	// Update the quorum metadata after selfheal.
-	errs := xl.setPartsMetadata(volume, path, metadata, needsHeal)
+	// Update the quorum metadata after selfheal, along with the
+	// checksum of each healed part.
+	sums := xl.getPartSums(volume, path, metadata)
 	for index, healNeeded := range needsHeal {
-		if healNeeded && errs[index] != nil {
-			return errs[index]
+		if healNeeded {
+			sums[index] = hex.EncodeToString(hashes[index].Sum(nil))
+		}
+	}
+	healedMetadata := make(fileMetadata)
+	for key, values := range metadata {
+		healedMetadata[key] = values
+	}
+	healedMetadata.SetPartSums(sums)
+	for index, err := range xl.setPartsMetadata(volume, path, healedMetadata, needsHeal) {
+		if needsHeal[index] && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Blocks of a file are healed in up to this many ranges rebuilt in
+// parallel, each range rebuilding at most xlHealRangeAhead blocks ahead
+// of the block being written.
+const (
+	xlHealRanges     = 4
+	xlHealRangeAhead = 2
+)
+
+// xlHealedBlock - encoded parts of a block, parts needing heal rebuilt.
+type xlHealedBlock struct {
+	enBlocks [][]byte
+	err      error
+}
+
+// healRange - reads blocks from first up to end of the file of size
+// from its own readers of the healthy parts, and rebuilds the parts
+// needing heal. Blocks are sent in order, blocks is closed after the
+// last block, a failure or once stop is closed.
+func (xl XL) healRange(volume, path string, needsHeal []bool, first, end, size int64, stop chan struct{}) (blocks chan xlHealedBlock) {
+	blocks = make(chan xlHealedBlock, xlHealRangeAhead)
+	go func() {
+		defer close(blocks)
+		readers := make([]io.ReadCloser, len(needsHeal))
+		defer func() {
+			for _, reader := range readers {
+				if reader != nil {
+					reader.Close()
+				}
+			}
+		}()
+		partOffset := first * int64(getEncodedBlockLen(erasureBlockSize, xl.DataBlocks))
+		// Parts which cannot be read are reconstructed along with the
+		// parts needing heal, as long as enough are readable.
+		rebuild := make([]bool, len(needsHeal))
+		for index, healNeeded := range needsHeal {
+			rebuild[index] = true
+			if healNeeded {
+				continue
+			}
+			erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
+			if reader, err := xl.storageDisks[index].ReadFile(context.Background(), volume, erasurePart, partOffset); err == nil {
+				readers[index] = reader
+				rebuild[index] = false
+			}
+		}
+		for block := first; block < end; block++ {
+			blockSize := erasureBlockSize
+			if left := size - block*erasureBlockSize; left < erasureBlockSize {
+				blockSize = int(left)
+			}
+			enBlocks := readHealBlock(readers, getEncodedBlockLen(blockSize, xl.DataBlocks))
+			err := xl.rebuildBlock(volume, path, enBlocks, rebuild)
+			select {
+			case blocks <- xlHealedBlock{enBlocks: enBlocks, err: err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return blocks
+}
+
+// readHealBlock - reads encoded parts of size of a block from readers
+// in parallel. Parts without reader are empty, parts failing to read
+// are nil.
+func readHealBlock(readers []io.ReadCloser, size int) [][]byte {
+	enBlocks := make([][]byte, len(readers))
+	var wg sync.WaitGroup
+	for index, reader := range readers {
+		// ReedSolomon.Verify() expects that slice is not nil even if
+		// the particular part needs healing.
+		enBlocks[index] = make([]byte, size)
+		if reader == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(index int, reader io.Reader) {
+			defer wg.Done()
+			if _, err := io.ReadFull(reader, enBlocks[index]); err != nil && err != io.ErrUnexpectedEOF {
+				enBlocks[index] = nil
+			}
+		}(index, reader)
+	}
+	wg.Wait()
+	return enBlocks
+}
+
+// rebuildBlock - verifies encoded parts of a block, and reconstructs the
+// parts marked in rebuild and those failing to read if they do not
+// match.
+func (xl XL) rebuildBlock(volume, path string, enBlocks [][]byte, rebuild []bool) error {
+	// Check blocks if they are all zero in length.
+	if checkBlockSize(enBlocks) == 0 {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("%s", errDataCorrupt)
+		return errDataCorrupt
+	}
+
+	// Verify the blocks.
+	ok, err := xl.ReedSolomon.Verify(enBlocks)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("ReedSolomon verify failed with %s", err)
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	// Verification failed, blocks require reconstruction.
+	for index, rebuildNeeded := range rebuild {
+		if rebuildNeeded {
+			// Reconstructs() reconstructs the parts if the array is nil.
+			enBlocks[index] = nil
+		}
+	}
+	if err = xl.ReedSolomon.Reconstruct(enBlocks); err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("ReedSolomon reconstruct failed with %s", err)
+		return err
+	}
+	// Verify reconstructed blocks again.
+	if ok, err = xl.ReedSolomon.Verify(enBlocks); err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("ReedSolomon verify failed with %s", err)
+		return err
+	}
+	if !ok {
+		// Blocks cannot be reconstructed, corrupted data.
+		err = errors.New("Verification failed after reconstruction, data likely corrupted.")
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("%s", err)
+		return err
+	}
+	return nil
+}
+
+// writeHealedParts - writes rebuilt parts of a block to the parts
+// needing heal in parallel, along with their checksums.
+func writeHealedParts(writers []io.WriteCloser, hashes []hash.Hash, enBlocks [][]byte, needsHeal []bool) error {
+	errs := make([]error, len(writers))
+	var wg sync.WaitGroup
+	for index, healNeeded := range needsHeal {
+		if !healNeeded {
+			continue
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			hashes[index].Write(enBlocks[index])
+			_, errs[index] = writers[index].Write(enBlocks[index])
+		}(index)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,101 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests files of several block ranges are rebuilt in order, also with
+// parts of healthy disks failing to read.
+func TestXLRepairFileRanges(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, 5*erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	// Part of the first disk cannot be read, part of the second is
+	// rebuilt from the others. Their metadata is still read.
+	for index := range disks {
+		detachMetadata(t, xl, index, "object")
+	}
+	part := filepath.Join(disks[0], "bucket", "object", "part.0")
+	partData, e := ioutil.ReadFile(part)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = os.Remove(part); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(disks[1], "bucket", "object", "part.1"), nil, 0600); e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.repairFile("bucket", "object", []bool{false, true, false, false}); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(part, partData, 0600); e != nil {
+		t.Fatal(e)
+	}
+	verification, e := xl.verifyFile("bucket", "object", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+
+	// Data is read back from the rebuilt part.
+	os.Remove(filepath.Join(disks[2], "bucket", "object", "part.2"))
+	os.Remove(filepath.Join(disks[3], "bucket", "object", "part.3"))
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	if readData, e := ioutil.ReadAll(r); e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+}
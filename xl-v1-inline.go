@@ -0,0 +1,168 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	slashpath "path"
+	"sync/atomic"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Files up to this size are inlined in their metadata, replicated on
+// all disks instead of being erasure coded, so that reading them takes
+// a single metadata read and no part file access.
+const xlInlineMaxSize = 128 * 1024 // 128KiB.
+
+// xlReadStats - number of file reads served from inlined data and by
+// decoding erasure coded parts, and by the block cache.
+type xlReadStats struct {
+	InlineReads  int64 `json:"inlineReads"`
+	ErasureReads int64 `json:"erasureReads"`
+
+	BlockCacheHits      int64 `json:"blockCacheHits"`
+	BlockCacheMisses    int64 `json:"blockCacheMisses"`
+	BlockCacheEvictions int64 `json:"blockCacheEvictions"`
+	// Bytes of memory used by cached blocks.
+	BlockCacheUsage int64 `json:"blockCacheUsage"`
+}
+
+// xlReadCounters - counts file reads of an XL, shared by the XL of its
+// pinned volumes.
+type xlReadCounters struct {
+	inline  int64
+	erasure int64
+}
+
+func (c *xlReadCounters) addInline() {
+	if c != nil {
+		atomic.AddInt64(&c.inline, 1)
+	}
+}
+
+func (c *xlReadCounters) addErasure() {
+	if c != nil {
+		atomic.AddInt64(&c.erasure, 1)
+	}
+}
+
+// ReadStats - returns number of file reads served from inlined data,
+// by decoding erasure coded parts and by the block cache since the
+// server started.
+func (xl XL) ReadStats() xlReadStats {
+	stats := xlReadStats{}
+	if xl.readCounters != nil {
+		stats.InlineReads = atomic.LoadInt64(&xl.readCounters.inline)
+		stats.ErasureReads = atomic.LoadInt64(&xl.readCounters.erasure)
+	}
+	xl.blockCache.stats(&stats)
+	return stats
+}
+
+// Set inlined file data, along with its checksum.
+func (f fileMetadata) SetInlineData(data []byte) {
+	sum := sha512.Sum512(data)
+	f.Set("file.xl.inline", base64.StdEncoding.EncodeToString(data))
+	f.Set("file.xl.inline512Sum", hex.EncodeToString(sum[:]))
+}
+
+// IsInline - returns true if file data is inlined.
+func (f fileMetadata) IsInline() bool {
+	return f.Get("file.xl.inline") != nil
+}
+
+// Get inlined file data, ok is false if the file is erasure coded.
+// Data not matching its checksum is reported as errDataCorrupt.
+func (f fileMetadata) GetInlineData() (data []byte, ok bool, err error) {
+	encoded := f.Get("file.xl.inline")
+	if encoded == nil {
+		return nil, false, nil
+	}
+	data, err = base64.StdEncoding.DecodeString(encoded[0])
+	if err != nil {
+		return nil, true, errDataCorrupt
+	}
+	sum := sha512.Sum512(data)
+	sums := f.Get("file.xl.inline512Sum")
+	if sums == nil || sums[0] != hex.EncodeToString(sum[:]) {
+		return nil, true, errDataCorrupt
+	}
+	return data, true, nil
+}
+
+// readInline - returns reader of inlined data of the file at path
+// from offset. Data of metadata which is corrupted is read from the
+// metadata of other disks with the same file version.
+func (xl XL) readInline(volume, path string, metadata fileMetadata, offset int64) (io.ReadCloser, error) {
+	data, _, err := metadata.GetInlineData()
+	if err == errDataCorrupt {
+		version, _ := metadata.GetFileVersion()
+		readLock := true
+		holder, lockErr := xl.lockNS(volume, path, readLock)
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		partsMetadata, errs := xl.getPartsMetadata(volume, path)
+		xl.unlockNS(holder)
+		for index, partMetadata := range partsMetadata {
+			if errs[index] != nil {
+				continue
+			}
+			if partVersion, _ := partMetadata.GetFileVersion(); partVersion != version {
+				continue
+			}
+			if data, _, err = partMetadata.GetInlineData(); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("%s", err)
+		return nil, err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return ioutil.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+// removeErasureParts - removes erasure coded parts left by an earlier
+// version of the file at path, now inlined in its metadata. Write
+// lockNS() should be done by caller.
+func (xl XL) removeErasureParts(volume, path string) {
+	for index, disk := range xl.storageDisks {
+		erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
+		if err := disk.DeleteFile(context.Background(), volume, erasurePart); err != nil && errorCause(err) != errFileNotFound {
+			log.WithFields(logrus.Fields{
+				"volume":    volume,
+				"path":      path,
+				"diskIndex": index,
+			}).Errorf("DeleteFile failed with %s", err)
+		}
+	}
+}
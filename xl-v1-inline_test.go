@@ -0,0 +1,166 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests small files are inlined in their metadata and read, healed,
+// listed and deleted without part files.
+func TestXLInline(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	createFile := func(path string, data []byte) {
+		w, e := xl.CreateFile(context.Background(), "bucket", path)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+	readFile := func(path string, offset int64) []byte {
+		r, e := xl.ReadFile(context.Background(), "bucket", path, offset)
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer r.Close()
+		data, e := ioutil.ReadAll(r)
+		if e != nil {
+			t.Fatal(e)
+		}
+		return data
+	}
+	hasParts := func(path string) bool {
+		matches, _ := filepath.Glob(filepath.Join(disks[0], "bucket", path, "part.[0-9]*"))
+		return len(matches) > 0
+	}
+
+	small := []byte("hello, world")
+	large := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	createFile("small", small)
+	createFile("empty", nil)
+	createFile("dir/large", large)
+	if hasParts("small") || hasParts("empty") || !hasParts("dir/large") {
+		t.Fatal("Expected only small files inlined")
+	}
+
+	testCases := []struct {
+		path   string
+		offset int64
+		data   []byte
+	}{
+		{"small", 0, small},
+		{"small", 7, small[7:]},
+		{"small", 100, []byte{}},
+		{"empty", 0, []byte{}},
+		{"dir/large", 0, large},
+	}
+	for i, testCase := range testCases {
+		if data := readFile(testCase.path, testCase.offset); !bytes.Equal(data, testCase.data) {
+			t.Errorf("Test %d: Expected %d bytes, got %d", i+1, len(testCase.data), len(data))
+		}
+	}
+	if stats := xl.ReadStats(); stats.InlineReads != 4 || stats.ErasureReads != 1 {
+		t.Errorf("Unexpected read stats %+v", stats)
+	}
+	if fileInfo, e := xl.StatFile("bucket", "small"); e != nil || fileInfo.Size != int64(len(small)) {
+		t.Fatalf("Unexpected file info %+v %v", fileInfo, e)
+	}
+
+	// Inlined files are listed along with erasure coded files.
+	filesInfo, _, e := xl.ListFiles("bucket", "", "", true, 10)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var names []string
+	for _, fileInfo := range filesInfo {
+		names = append(names, fileInfo.Name)
+	}
+	if len(names) != 3 || names[0] != "dir/large" || names[1] != "empty" || names[2] != "small" {
+		t.Fatalf("Unexpected listing %v", names)
+	}
+
+	// Corrupted inlined data is read from other disks.
+	metadataPath := filepath.Join(disks[3], "bucket", "small", metadataFile)
+	metadata, e := xl.extractMetadata("bucket", "small")
+	if e != nil {
+		t.Fatal(e)
+	}
+	metadata.Set("file.xl.inline", "Y29ycnVwdGVk")
+	corrupted := &bytes.Buffer{}
+	if e = metadata.Write(corrupted); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(metadataPath, corrupted.Bytes(), 0644); e != nil {
+		t.Fatal(e)
+	}
+	if data := readFile("small", 0); !bytes.Equal(data, small) {
+		t.Fatalf("Expected %q, got %q", small, data)
+	}
+
+	// Inlined files are healed by writing their metadata.
+	if e = os.RemoveAll(filepath.Join(disks[3], "bucket", "small")); e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.healFile("bucket", "small"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = os.Stat(filepath.Join(disks[3], "bucket", "small", metadataFile)); e != nil {
+		t.Fatalf("Expected metadata to be healed, %s", e)
+	}
+
+	// Erasure coded parts are removed once a file is inlined.
+	createFile("dir/large", small)
+	if hasParts("dir/large") {
+		t.Fatal("Expected parts of the earlier version removed")
+	}
+	if data := readFile("dir/large", 0); !bytes.Equal(data, small) {
+		t.Fatalf("Expected %q, got %q", small, data)
+	}
+
+	if e = xl.DeleteFile(context.Background(), "bucket", "small"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = xl.StatFile("bucket", "small"); e != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, e)
+	}
+}
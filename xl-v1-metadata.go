@@ -17,10 +17,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
+	"io/ioutil"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -62,9 +65,10 @@ func (f fileMetadata) Get(key string) []string {
 	return v
 }
 
-// Write writes a metadata in wire format.
+// Write writes a metadata in wire format, metadata not matching its
+// schema is not written.
 func (f fileMetadata) Write(writer io.Writer) error {
-	metadataBytes, err := json.Marshal(f)
+	metadataBytes, err := encodeFileMetadata(f)
 	if err != nil {
 		return err
 	}
@@ -115,13 +119,125 @@ func (f fileMetadata) SetFileVersion(fileVersion int64) {
 	f.Set("file.version", strconv.FormatInt(fileVersion, 10))
 }
 
+// Metadata of the object layer is kept under keys of this prefix.
+const objectMetadataKeyPrefix = "object."
+
+// GetObjectMetadata - returns metadata of the object layer kept with
+// the file, nil if none.
+func (f fileMetadata) GetObjectMetadata() map[string]string {
+	var metadata map[string]string
+	for key, values := range f {
+		if !strings.HasPrefix(key, objectMetadataKeyPrefix) || len(values) == 0 {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[strings.TrimPrefix(key, objectMetadataKeyPrefix)] = values[0]
+	}
+	return metadata
+}
+
+// SetObjectMetadata - replaces metadata of the object layer kept with
+// the file.
+func (f fileMetadata) SetObjectMetadata(metadata map[string]string) {
+	for key := range f {
+		if strings.HasPrefix(key, objectMetadataKeyPrefix) {
+			delete(f, key)
+		}
+	}
+	for key, value := range metadata {
+		f.Set(objectMetadataKeyPrefix+key, value)
+	}
+}
+
 // fileMetadataDecode - file metadata decode.
 func fileMetadataDecode(reader io.Reader) (fileMetadata, error) {
+	metadataBytes, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFileMetadata(metadataBytes)
+}
+
+// fileMetadataCodec - wire format of metadata. Codecs are told apart
+// by the first bytes of metadata they encode, metadata is read in any
+// known codec and written in the configured one.
+type fileMetadataCodec interface {
+	// Detect - returns true if data is encoded by the codec.
+	Detect(data []byte) bool
+	Encode(f fileMetadata) ([]byte, error)
+	Decode(data []byte) (fileMetadata, error)
+}
+
+// fileMetadataJSON - metadata as a JSON object of string arrays.
+type fileMetadataJSON struct{}
+
+func (fileMetadataJSON) Detect(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("{"))
+}
+
+func (fileMetadataJSON) Encode(f fileMetadata) ([]byte, error) {
+	return json.Marshal(f)
+}
+
+func (fileMetadataJSON) Decode(data []byte) (fileMetadata, error) {
 	metadata := make(fileMetadata)
-	decoder := json.NewDecoder(reader)
-	// Unmarshalling failed, file possibly corrupted.
-	if err := decoder.Decode(&metadata); err != nil {
+	if err := json.Unmarshal(data, &metadata); err != nil {
 		return nil, err
 	}
 	return metadata, nil
 }
+
+// Encodings of metadata, as configured.
+const (
+	fileMetadataEncodingJSON    = "json"
+	fileMetadataEncodingMsgpack = "msgpack"
+)
+
+// Codecs metadata is read in by their encoding.
+var fileMetadataCodecs = map[string]fileMetadataCodec{
+	fileMetadataEncodingJSON:    fileMetadataJSON{},
+	fileMetadataEncodingMsgpack: fileMetadataMsgpack{},
+}
+
+// fileMetadataWriteCodec - returns codec of metadata written, as
+// configured, JSON by default.
+func fileMetadataWriteCodec() fileMetadataCodec {
+	if codec, ok := fileMetadataCodecs[globalDiskIO.Get().MetadataEncoding]; ok {
+		return codec
+	}
+	return fileMetadataJSON{}
+}
+
+// errUnknownMetadataCodec - returned for metadata of no known codec.
+var errUnknownMetadataCodec = errors.New("Metadata is not encoded by any known codec")
+
+// encodeFileMetadata - returns metadata in wire format, fails if it
+// does not match its schema.
+func encodeFileMetadata(f fileMetadata) ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return fileMetadataWriteCodec().Encode(f)
+}
+
+// decodeFileMetadata - returns metadata of data in any known codec,
+// fails if it does not match its schema.
+func decodeFileMetadata(data []byte) (fileMetadata, error) {
+	for _, codec := range fileMetadataCodecs {
+		if !codec.Detect(data) {
+			continue
+		}
+		// Unmarshalling failed, file possibly corrupted.
+		metadata, err := codec.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		if err = metadata.Validate(); err != nil {
+			return nil, err
+		}
+		return metadata, nil
+	}
+	return nil, errUnknownMetadataCodec
+}
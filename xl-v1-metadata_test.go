@@ -0,0 +1,103 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests metadata of objects is kept in the metadata of their file,
+// healed and removed along with it.
+func TestXLObjectMetadata(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-metadata-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	obj := newObjectLayer(storage)
+	if err := obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Large enough to be erasure coded, and small enough to be inlined.
+	for _, size := range []int{xlInlineMaxSize + 1, 1} {
+		data := bytes.Repeat([]byte("a"), size)
+		if _, err := obj.PutObject("bucket", "object", int64(size), bytes.NewReader(data), map[string]string{"X-Amz-Meta-Color": "red"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := obj.PutObjectTags("bucket", "object", map[string]string{"team": "storage"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, e = xl.StatFile(minioMetaVolume, objectMetadataPath("bucket", "object")); errorCause(e) != errFileNotFound {
+			t.Fatalf("Expected no metadata apart from the object, got %v", e)
+		}
+		fi, e := xl.StatFile("bucket", "object")
+		if e != nil {
+			t.Fatal(e)
+		}
+		if fi.Metadata["X-Amz-Meta-Color"] != "red" || objectTags(fi.Metadata)["team"] != "storage" {
+			t.Fatalf("Size %d: unexpected metadata %v", size, fi.Metadata)
+		}
+
+		// Metadata is healed along with the file.
+		if e = os.RemoveAll(filepath.Join(disks[0], "bucket", "object")); e != nil {
+			t.Fatal(e)
+		}
+		if e = xl.healFile("bucket", "object"); e != nil {
+			t.Fatal(e)
+		}
+		reader, _, e := xl.readMetadataFile(0, "bucket", "object")
+		if e != nil {
+			t.Fatal(e)
+		}
+		metadata, e := fileMetadataDecode(reader)
+		reader.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if tags := objectTags(metadata.GetObjectMetadata()); tags["team"] != "storage" {
+			t.Fatalf("Size %d: expected healed tags, got %v", size, tags)
+		}
+		objInfo, err := obj.GetObjectInfo("bucket", "object")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objInfo.UserDefined["X-Amz-Meta-Color"] != "red" {
+			t.Fatalf("Size %d: unexpected user defined metadata %v", size, objInfo.UserDefined)
+		}
+	}
+
+	if err := obj.DeleteObject("bucket", "object", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.GetObjectTags("bucket", "object"); err == nil {
+		t.Fatal("Expected tags of deleted object to be gone")
+	}
+}
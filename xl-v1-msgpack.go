@@ -0,0 +1,192 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// Metadata encoded as msgpack is prefixed by this magic and the
+// version of its layout.
+var fileMetadataMsgpackMagic = []byte("XLMP")
+
+const fileMetadataMsgpackVersion = 1
+
+// msgpack type markers of the subset used by metadata, maps of
+// strings to arrays of strings.
+const (
+	msgpFixMap   = 0x80
+	msgpMap16    = 0xde
+	msgpMap32    = 0xdf
+	msgpFixArray = 0x90
+	msgpArray16  = 0xdc
+	msgpArray32  = 0xdd
+	msgpFixStr   = 0xa0
+	msgpStr8     = 0xd9
+	msgpStr16    = 0xda
+	msgpStr32    = 0xdb
+)
+
+var errMsgpackMetadata = errors.New("Malformed msgpack metadata")
+
+// fileMetadataMsgpack - metadata as a msgpack map of string arrays,
+// smaller and faster to parse than JSON.
+type fileMetadataMsgpack struct{}
+
+func (fileMetadataMsgpack) Detect(data []byte) bool {
+	return bytes.HasPrefix(data, fileMetadataMsgpackMagic)
+}
+
+func (fileMetadataMsgpack) Encode(f fileMetadata) ([]byte, error) {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	// Keys are sorted, the same metadata is always encoded the same.
+	sort.Strings(keys)
+	buf := append([]byte{}, fileMetadataMsgpackMagic...)
+	buf = append(buf, fileMetadataMsgpackVersion)
+	buf = msgpAppendHeader(buf, len(keys), msgpFixMap, 16, msgpMap16, msgpMap32)
+	for _, key := range keys {
+		buf = msgpAppendString(buf, key)
+		buf = msgpAppendHeader(buf, len(f[key]), msgpFixArray, 16, msgpArray16, msgpArray32)
+		for _, value := range f[key] {
+			buf = msgpAppendString(buf, value)
+		}
+	}
+	return buf, nil
+}
+
+func (fileMetadataMsgpack) Decode(data []byte) (fileMetadata, error) {
+	data = data[len(fileMetadataMsgpackMagic):]
+	if len(data) == 0 {
+		return nil, errMsgpackMetadata
+	}
+	if data[0] != fileMetadataMsgpackVersion {
+		return nil, errUnsupportedFormat
+	}
+	r := &msgpReader{data: data[1:]}
+	count := r.readHeader(msgpFixMap, 16, msgpMap16, msgpMap32)
+	metadata := make(fileMetadata)
+	for i := 0; i < count && r.err == nil; i++ {
+		key := r.readString()
+		n := r.readHeader(msgpFixArray, 16, msgpArray16, msgpArray32)
+		// Each value takes at least a byte, counts larger than the
+		// data left are corrupt.
+		if n > len(r.data) {
+			r.err = errMsgpackMetadata
+		}
+		values := make([]string, 0, n)
+		for j := 0; j < n && r.err == nil; j++ {
+			values = append(values, r.readString())
+		}
+		metadata[key] = values
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.err = errMsgpackMetadata
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return metadata, nil
+}
+
+// msgpAppendHeader - appends header of a map or an array of n
+// entries, fixed types hold fewer than fixMax entries.
+func msgpAppendHeader(buf []byte, n int, fixType byte, fixMax int, type16, type32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(buf, fixType|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, type16, 0, 0)
+		binary.BigEndian.PutUint16(buf[len(buf)-2:], uint16(n))
+		return buf
+	}
+	buf = append(buf, type32, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(n))
+	return buf
+}
+
+// msgpAppendString - appends s as a msgpack str.
+func msgpAppendString(buf []byte, s string) []byte {
+	if n := len(s); n <= 0xff && n >= 32 {
+		buf = append(buf, msgpStr8, byte(n))
+	} else {
+		buf = msgpAppendHeader(buf, n, msgpFixStr, 32, msgpStr16, msgpStr32)
+	}
+	return append(buf, s...)
+}
+
+// msgpReader - reads msgpack values from data, the first error is
+// kept and reads after it return zero values.
+type msgpReader struct {
+	data []byte
+	err  error
+}
+
+// next - returns the next n bytes of data.
+func (r *msgpReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errMsgpackMetadata
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// readHeader - reads header of a map or an array, returns its count
+// of entries.
+func (r *msgpReader) readHeader(fixType byte, fixMax int, type16, type32 byte) int {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	switch {
+	case b[0]&^byte(fixMax-1) == fixType:
+		return int(b[0] & byte(fixMax-1))
+	case b[0] == type16:
+		if b = r.next(2); b != nil {
+			return int(binary.BigEndian.Uint16(b))
+		}
+	case b[0] == type32:
+		if b = r.next(4); b != nil {
+			return int(binary.BigEndian.Uint32(b))
+		}
+	default:
+		r.err = errMsgpackMetadata
+	}
+	return 0
+}
+
+// readString - reads a msgpack str.
+func (r *msgpReader) readString() string {
+	if r.err == nil && len(r.data) > 0 && r.data[0] == msgpStr8 {
+		r.next(1)
+		if b := r.next(1); b != nil {
+			return string(r.next(int(b[0])))
+		}
+		return ""
+	}
+	return string(r.next(r.readHeader(msgpFixStr, 32, msgpStr16, msgpStr32)))
+}
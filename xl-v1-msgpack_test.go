@@ -0,0 +1,138 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests metadata round trips through msgpack, with strs, arrays and
+// maps of each size.
+func TestFileMetadataMsgpack(t *testing.T) {
+	metadata := testFileMetadata()
+	metadata.SetInlineData(bytes.Repeat([]byte("a"), 70000))
+	metadata["file.xl.many"] = strings.Split(strings.Repeat("v,", 20), ",")
+	for i := 0; i < 20; i++ {
+		metadata.Set(fmt.Sprintf("file.xl.key%d", i), strings.Repeat("b", i*20))
+	}
+	codec := fileMetadataMsgpack{}
+	metadataBytes, e := codec.Encode(metadata)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !codec.Detect(metadataBytes) || (fileMetadataJSON{}).Detect(metadataBytes) {
+		t.Fatal("Expected msgpack metadata to be detected as msgpack only")
+	}
+	decoded, e := decodeFileMetadata(metadataBytes)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(decoded, metadata) {
+		t.Fatal("Decoded metadata does not match encoded metadata")
+	}
+	jsonBytes, _ := (fileMetadataJSON{}).Encode(metadata)
+	if len(metadataBytes) >= len(jsonBytes) {
+		t.Errorf("Expected msgpack metadata smaller than JSON, %d >= %d", len(metadataBytes), len(jsonBytes))
+	}
+
+	// Truncated and trailing data, and unknown versions are not read.
+	for i, data := range [][]byte{
+		metadataBytes[:len(metadataBytes)-1],
+		metadataBytes[:len(fileMetadataMsgpackMagic)],
+		append(append([]byte{}, metadataBytes...), 0),
+	} {
+		if _, e = codec.Decode(data); e != errMsgpackMetadata {
+			t.Errorf("Test %d: expected %v, got %v", i+1, errMsgpackMetadata, e)
+		}
+	}
+	newer := append(append([]byte{}, fileMetadataMsgpackMagic...), fileMetadataMsgpackVersion+1)
+	if _, e = codec.Decode(newer); e != errUnsupportedFormat {
+		t.Errorf("Expected %v, got %v", errUnsupportedFormat, e)
+	}
+}
+
+// Tests files are written with the configured metadata encoding, and
+// read whichever encoding their metadata has.
+func TestXLMetadataEncoding(t *testing.T) {
+	defer globalDiskIO.Set(globalDiskIO.Get())
+	xl := newMemXL(t, 4)
+	data := testFaultyData()
+	for _, encoding := range []string{fileMetadataEncodingMsgpack, fileMetadataEncodingJSON} {
+		globalDiskIO.Set(diskIOConfig{MetadataEncoding: encoding})
+		w, e := xl.CreateFile(context.Background(), "bucket", encoding)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e = w.Write(data); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+		r, _, e := xl.readMetadataFile(0, "bucket", encoding)
+		if e != nil {
+			t.Fatal(e)
+		}
+		metadataBytes, _ := ioutil.ReadAll(r)
+		r.Close()
+		if !fileMetadataCodecs[encoding].Detect(metadataBytes) {
+			t.Fatalf("Expected metadata encoded as %s", encoding)
+		}
+	}
+	for _, encoding := range []string{fileMetadataEncodingMsgpack, fileMetadataEncodingJSON} {
+		r, e := xl.ReadFile(context.Background(), "bucket", encoding, 0)
+		if e != nil {
+			t.Fatal(e)
+		}
+		readData, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil || !bytes.Equal(readData, data) {
+			t.Fatalf("%s: unexpected data read, %v", encoding, e)
+		}
+	}
+}
+
+// Benchmarks decoding metadata of a small erasure coded file in each
+// encoding.
+func BenchmarkFileMetadataDecode(b *testing.B) {
+	metadata := testFileMetadata()
+	metadata.Set("version", minioVersion)
+	metadata.Set("file.xl.dataBlocks", "8")
+	metadata.Set("file.xl.parityBlocks", "8")
+	metadata.Set("file.xl.block512Sum", strings.Repeat("ab", 64))
+	for encoding, codec := range fileMetadataCodecs {
+		metadataBytes, e := codec.Encode(metadata)
+		if e != nil {
+			b.Fatal(e)
+		}
+		b.Run(encoding, func(b *testing.B) {
+			b.SetBytes(int64(len(metadataBytes)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, e := decodeFileMetadata(metadataBytes); e != nil {
+					b.Fatal(e)
+				}
+			}
+		})
+	}
+}
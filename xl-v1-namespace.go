@@ -16,7 +16,13 @@
 
 package main
 
-import "sync"
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
 // nameSpaceParam - carries name space resource.
 type nameSpaceParam struct {
@@ -24,48 +30,236 @@ type nameSpaceParam struct {
 	path   string
 }
 
-// nameSpaceLock - provides primitives for locking critical namespace regions.
-type nameSpaceLock struct {
+// nameSpaceLockConfig - timeouts of namespace locks, so that an
+// operation which never releases its lock cannot block a file forever.
+type nameSpaceLockConfig struct {
+	// Operations fail after waiting this many seconds for a lock,
+	// zero waits until the lock is released.
+	AcquireTimeout int64 `json:"acquireTimeout"`
+	// Locks held longer than this many seconds are logged, defaults
+	// if zero.
+	HeldThreshold int64 `json:"heldThreshold"`
+	// Locks held longer than the threshold are released on behalf of
+	// their holders, which are logged.
+	ForceRelease bool `json:"forceRelease"`
+}
+
+const (
+	// Default time locks are held for before they are logged.
+	defaultNameSpaceLockHeldThreshold = 10 * time.Minute
+
+	// Interval locks held are checked at.
+	nameSpaceLockCheckInterval = 10 * time.Second
+)
+
+// acquireTimeout - returns time operations wait for a lock, zero is
+// unlimited.
+func (c nameSpaceLockConfig) acquireTimeout() time.Duration {
+	if c.AcquireTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(c.AcquireTimeout) * time.Second
+}
+
+// heldThreshold - returns time locks are held for before they are
+// logged, defaults if not configured.
+func (c nameSpaceLockConfig) heldThreshold() time.Duration {
+	if c.HeldThreshold <= 0 {
+		return defaultNameSpaceLockHeldThreshold
+	}
+	return time.Duration(c.HeldThreshold) * time.Second
+}
+
+// nameSpaceLocks - current namespace lock configuration, shared by all
+// disks.
+type nameSpaceLocks struct {
 	rwMutex *sync.RWMutex
-	count   uint
+	config  nameSpaceLockConfig
 }
 
-func (nsLock *nameSpaceLock) InUse() bool {
-	return nsLock.count != 0
+// Global namespace lock configuration, configured at server start.
+var globalNameSpaceLocks = &nameSpaceLocks{rwMutex: &sync.RWMutex{}}
+
+// Get - returns current configuration.
+func (n *nameSpaceLocks) Get() nameSpaceLockConfig {
+	n.rwMutex.RLock()
+	defer n.rwMutex.RUnlock()
+	return n.config
+}
+
+// Set - sets configuration, applies to locks taken afterwards and to
+// the next check of locks held.
+func (n *nameSpaceLocks) Set(config nameSpaceLockConfig) {
+	n.rwMutex.Lock()
+	defer n.rwMutex.Unlock()
+	n.config = config
 }
 
-// Lock acquires write lock and increments the namespace counter.
-func (nsLock *nameSpaceLock) Lock() {
-	nsLock.rwMutex.Lock()
-	nsLock.count++
+// nameSpaceHolder - holder of a namespace lock, returned by lockNS and
+// passed to unlockNS. Fields are guarded by the lock map mutex.
+type nameSpaceHolder struct {
+	param    nameSpaceParam
+	readLock bool
+	// Operation which took the lock.
+	owner string
+	since time.Time
+	// Held past the threshold and logged.
+	reported bool
+	// Released on behalf of the holder, unlockNS has nothing to do.
+	released bool
 }
 
-// Unlock releases write lock and decrements the namespace counter.
-func (nsLock *nameSpaceLock) Unlock() {
-	nsLock.rwMutex.Unlock()
-	if nsLock.count != 0 {
-		nsLock.count--
+// nameSpaceLock - provides primitives for locking critical namespace
+// regions. Fields are guarded by the lock map mutex, waiters are woken
+// whenever the lock is released.
+type nameSpaceLock struct {
+	// Number of holders and waiters of the lock.
+	count uint
+	// Number of writers waiting, readers wait behind them so that
+	// writers are not starved.
+	writersWaiting int
+	readers        int
+	writer         bool
+	// Current holders of the lock.
+	holders []*nameSpaceHolder
+	// Closed and replaced whenever the lock is released.
+	released chan struct{}
+}
+
+func (nsLock *nameSpaceLock) InUse() bool {
+	return nsLock.count != 0
+}
+
+// available - returns true if a lock of the kind can be taken now.
+func (nsLock *nameSpaceLock) available(readLock bool) bool {
+	if readLock {
+		return !nsLock.writer && nsLock.writersWaiting == 0
 	}
+	return !nsLock.writer && nsLock.readers == 0
 }
 
-// RLock acquires read lock and increments the namespace counter.
-func (nsLock *nameSpaceLock) RLock() {
-	nsLock.rwMutex.RLock()
-	nsLock.count++
+// take - records holder taking the lock.
+func (nsLock *nameSpaceLock) take(holder *nameSpaceHolder) {
+	if holder.readLock {
+		nsLock.readers++
+	} else {
+		nsLock.writer = true
+	}
+	nsLock.holders = append(nsLock.holders, holder)
 }
 
-// RUnlock release read lock and decrements the namespace counter.
-func (nsLock *nameSpaceLock) RUnlock() {
-	nsLock.rwMutex.RUnlock()
-	if nsLock.count != 0 {
-		nsLock.count--
+// release - releases the lock of holder, and wakes up waiters.
+func (nsLock *nameSpaceLock) release(holder *nameSpaceHolder) {
+	for i, h := range nsLock.holders {
+		if h == holder {
+			nsLock.holders = append(nsLock.holders[:i], nsLock.holders[i+1:]...)
+			break
+		}
+	}
+	if holder.readLock {
+		nsLock.readers--
+	} else {
+		nsLock.writer = false
 	}
+	holder.released = true
+	nsLock.count--
+	close(nsLock.released)
+	nsLock.released = make(chan struct{})
 }
 
 // newNSLock - provides a new instance of namespace locking primitives.
 func newNSLock() *nameSpaceLock {
 	return &nameSpaceLock{
-		rwMutex: &sync.RWMutex{},
-		count:   0,
+		released: make(chan struct{}),
+	}
+}
+
+// nameSpaceLockOwner - returns name of the operation calling the caller
+// of the lock, such as "XL.ReadFile".
+func nameSpaceLockOwner() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimPrefix(name, "main.")
+}
+
+// nameSpaceLockInfo - lock held on a file, one per holder.
+type nameSpaceLockInfo struct {
+	Volume string `json:"volume"`
+	Path   string `json:"path"`
+	// Type of lock, "read" or "write".
+	Type  string    `json:"type"`
+	Owner string    `json:"owner"`
+	Since time.Time `json:"since"`
+	// Time the lock is held for, in nanoseconds.
+	Held time.Duration `json:"held"`
+	// Number of operations waiting for the lock of the file.
+	Waiters int `json:"waiters"`
+}
+
+// Types of namespace locks.
+const (
+	nameSpaceLockRead  = "read"
+	nameSpaceLockWrite = "write"
+)
+
+// Locks - returns locks currently held, longest held first.
+func (xl XL) Locks() []nameSpaceLockInfo {
+	now := time.Now().UTC()
+	locks := []nameSpaceLockInfo{}
+	xl.nameSpaceLockMapMutex.Lock()
+	for param, nsLock := range xl.nameSpaceLockMap {
+		waiters := int(nsLock.count) - len(nsLock.holders)
+		for _, holder := range nsLock.holders {
+			lockType := nameSpaceLockWrite
+			if holder.readLock {
+				lockType = nameSpaceLockRead
+			}
+			locks = append(locks, nameSpaceLockInfo{
+				Volume:  param.volume,
+				Path:    param.path,
+				Type:    lockType,
+				Owner:   holder.owner,
+				Since:   holder.since,
+				Held:    now.Sub(holder.since),
+				Waiters: waiters,
+			})
+		}
+	}
+	xl.nameSpaceLockMapMutex.Unlock()
+	sort.Slice(locks, func(i, j int) bool {
+		if !locks[i].Since.Equal(locks[j].Since) {
+			return locks[i].Since.Before(locks[j].Since)
+		}
+		if locks[i].Volume != locks[j].Volume {
+			return locks[i].Volume < locks[j].Volume
+		}
+		return locks[i].Path < locks[j].Path
+	})
+	return locks
+}
+
+// initNameSpaceLockChecks - checks locks of storage held too long
+// periodically.
+func initNameSpaceLockChecks(storage StorageAPI) {
+	checker, ok := storage.(lockChecker)
+	if !ok {
+		return
 	}
+	go func() {
+		for {
+			time.Sleep(nameSpaceLockCheckInterval)
+			config := globalNameSpaceLocks.Get()
+			checker.checkLocks(config.heldThreshold(), config.ForceRelease)
+		}
+	}()
 }
@@ -0,0 +1,151 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests locks held are reported with their holders and waiters,
+// longest held first.
+func TestXLLocks(t *testing.T) {
+	xl := XL{
+		nameSpaceLockMap:      make(map[nameSpaceParam]*nameSpaceLock),
+		nameSpaceLockMapMutex: &sync.Mutex{},
+	}
+	if locks := xl.Locks(); len(locks) != 0 {
+		t.Fatalf("Expected no locks, got %+v", locks)
+	}
+	writer, _ := xl.lockNS("bucket", "object", false)
+	time.Sleep(10 * time.Millisecond)
+	reader, _ := xl.lockNS("bucket", "other", true)
+	locked := make(chan struct{})
+	go func() {
+		holder, _ := xl.lockNS("bucket", "object", true)
+		close(locked)
+		xl.unlockNS(holder)
+	}()
+	waitForLockCount(&xl, "object", 2)
+
+	locks := xl.Locks()
+	if len(locks) != 2 {
+		t.Fatalf("Expected 2 locks, got %+v", locks)
+	}
+	if lock := locks[0]; lock.Path != "object" || lock.Type != nameSpaceLockWrite || lock.Waiters != 1 || lock.Held < 10*time.Millisecond {
+		t.Fatalf("Unexpected lock %+v", lock)
+	}
+	if lock := locks[1]; lock.Path != "other" || lock.Type != nameSpaceLockRead || lock.Waiters != 0 {
+		t.Fatalf("Unexpected lock %+v", lock)
+	}
+	// Locks are owned by the operation which took them.
+	if !strings.HasSuffix(locks[0].Owner, "TestXLLocks") {
+		t.Fatalf("Unexpected owner %s", locks[0].Owner)
+	}
+
+	xl.unlockNS(writer)
+	<-locked
+	xl.unlockNS(reader)
+	if locks = xl.Locks(); len(locks) != 0 {
+		t.Fatalf("Expected no locks, got %+v", locks)
+	}
+}
+
+// waitForLockCount - waits until count operations hold or wait for
+// the lock of path.
+func waitForLockCount(xl *XL, path string, count uint) {
+	for i := 0; i < 1000; i++ {
+		xl.nameSpaceLockMapMutex.Lock()
+		nsLock := xl.nameSpaceLockMap[nameSpaceParam{"bucket", path}]
+		done := nsLock != nil && nsLock.count == count
+		xl.nameSpaceLockMapMutex.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Tests waiting for locks times out, writers are not starved by
+// readers, and locks held too long are released on behalf of their
+// holders.
+func TestXLLockTimeouts(t *testing.T) {
+	defer globalNameSpaceLocks.Set(globalNameSpaceLocks.Get())
+	xl := XL{
+		nameSpaceLockMap:      make(map[nameSpaceParam]*nameSpaceLock),
+		nameSpaceLockMapMutex: &sync.Mutex{},
+	}
+	globalNameSpaceLocks.Set(nameSpaceLockConfig{AcquireTimeout: 1})
+	reader, e := xl.lockNS("bucket", "object", true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// Writers wait for readers, and readers for waiting writers.
+	writerDone := make(chan error)
+	go func() {
+		holder, e := xl.lockNS("bucket", "object", false)
+		xl.unlockNS(holder)
+		writerDone <- e
+	}()
+	waitForLockCount(&xl, "object", 2)
+	// The reader starts waiting well after the writer, so that it
+	// does not time out along with it.
+	time.Sleep(100 * time.Millisecond)
+	readerDone := make(chan error)
+	go func() {
+		holder, e := xl.lockNS("bucket", "object", true)
+		xl.unlockNS(holder)
+		readerDone <- e
+	}()
+	if e = <-writerDone; e != errLockTimeout {
+		t.Fatalf("Expected %v, got %v", errLockTimeout, e)
+	}
+	// Readers go ahead once the writer gave up.
+	if e = <-readerDone; e != nil {
+		t.Fatalf("Expected reader to lock, got %v", e)
+	}
+
+	// Locks held past the threshold are only logged, unless they are
+	// to be released.
+	if released := xl.checkLocks(time.Hour, true); released != 0 {
+		t.Fatalf("Expected no locks released, got %d", released)
+	}
+	if released := xl.checkLocks(0, false); released != 0 || len(xl.Locks()) != 1 {
+		t.Fatalf("Expected no locks released, got %d", released)
+	}
+	if released := xl.checkLocks(0, true); released != 1 || len(xl.Locks()) != 0 {
+		t.Fatalf("Expected lock released, got %d", released)
+	}
+	// Writers lock once the lock was released, unlocking the released
+	// lock has no effect.
+	writer, e := xl.lockNS("bucket", "object", false)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl.unlockNS(reader)
+	if locks := xl.Locks(); len(locks) != 1 || locks[0].Type != nameSpaceLockWrite {
+		t.Fatalf("Unexpected locks %+v", locks)
+	}
+	xl.unlockNS(writer)
+	xl.nameSpaceLockMapMutex.Lock()
+	defer xl.nameSpaceLockMapMutex.Unlock()
+	if len(xl.nameSpaceLockMap) != 0 {
+		t.Fatalf("Expected all locks released, got %d", len(xl.nameSpaceLockMap))
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// xlErasureCoding - encoder and block buffers of files erasure coded
+// into a number of data and parity blocks.
+type xlErasureCoding struct {
+	rs      reedsolomon.Encoder
+	buffers *erasureBuffers
+}
+
+// xlErasureCodings - erasure codings of files with parity other than
+// the default of their disks, created once per number of data and
+// parity blocks so that buffers are pooled across files.
+type xlErasureCodings struct {
+	mutex   sync.Mutex
+	codings map[[2]int]xlErasureCoding
+}
+
+func newXLErasureCodings() *xlErasureCodings {
+	return &xlErasureCodings{codings: make(map[[2]int]xlErasureCoding)}
+}
+
+// get - returns erasure coding of dataBlocks and parityBlocks.
+func (e *xlErasureCodings) get(dataBlocks, parityBlocks int) (xlErasureCoding, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	key := [2]int{dataBlocks, parityBlocks}
+	if coding, ok := e.codings[key]; ok {
+		return coding, nil
+	}
+	rs, err := reedsolomon.New(dataBlocks, parityBlocks)
+	if err != nil {
+		return xlErasureCoding{}, err
+	}
+	coding := xlErasureCoding{rs: rs, buffers: newErasureBuffers(dataBlocks, parityBlocks)}
+	e.codings[key] = coding
+	return coding, nil
+}
+
+// withParity - returns XL erasure coding files into parityBlocks
+// parity blocks, xl itself if parityBlocks is 0 or its default. Write
+// quorum is raised so that committed files keep a part to spare.
+func (xl XL) withParity(parityBlocks int) (XL, error) {
+	if parityBlocks == 0 || parityBlocks == xl.ParityBlocks {
+		return xl, nil
+	}
+	totalDisks := len(xl.storageDisks)
+	if parityBlocks < 1 || parityBlocks > totalDisks/2 {
+		return XL{}, errInvalidParity
+	}
+	dataBlocks := totalDisks - parityBlocks
+	coding, err := xl.codings.get(dataBlocks, parityBlocks)
+	if err != nil {
+		return XL{}, err
+	}
+	xl.DataBlocks = dataBlocks
+	xl.ParityBlocks = parityBlocks
+	xl.ReedSolomon = coding.rs
+	xl.buffers = coding.buffers
+	if xl.writeQuorum <= dataBlocks {
+		xl.writeQuorum = dataBlocks + 1
+	}
+	return xl, nil
+}
+
+// forFile - returns XL decoding the file of metadata, as erasure coded
+// with the parity blocks saved in metadata.
+func (xl XL) forFile(metadata fileMetadata) (XL, error) {
+	parity := metadata.Get("file.xl.parityBlocks")
+	if parity == nil {
+		return xl, nil
+	}
+	parityBlocks, err := strconv.Atoi(parity[0])
+	if err != nil {
+		return XL{}, err
+	}
+	return xl.withParity(parityBlocks)
+}
+
+// CreateFileParity - creates a file erasure coded with parityBlocks
+// parity blocks instead of the default of the disks, half of them.
+func (xl XL) CreateFileParity(ctx context.Context, volume, path string, parityBlocks int) (io.WriteCloser, error) {
+	return xl.createFile(ctx, volume, path, parityBlocks)
+}
@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// Tests files are written with a parity of their own, and are read
+// and healed with it.
+func TestXLCreateFileParity(t *testing.T) {
+	xl := newMemXL(t, 8)
+	data := testFaultyData()
+	for _, parity := range []int{-1, 5, 9} {
+		if _, e := xl.CreateFileParity(context.Background(), "bucket", "object", parity); e != errInvalidParity {
+			t.Fatalf("Parity %d: expected %v, got %v", parity, errInvalidParity, e)
+		}
+	}
+
+	w, e := xl.CreateFileParity(context.Background(), "bucket", "object", 2)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	partsMetadata, _ := xl.getPartsMetadata("bucket", "object")
+	if dataBlocks := partsMetadata[0].Get("file.xl.dataBlocks"); dataBlocks == nil || dataBlocks[0] != "6" {
+		t.Fatalf("Expected 6 data blocks, got %v", dataBlocks)
+	}
+
+	// Up to parity parts can be lost.
+	for _, index := range []int{1, 6} {
+		if e = xl.storageDisks[index].DeleteFile(context.Background(), "bucket", fmt.Sprintf("object/part.%d", index)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	readData, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	if e = xl.healFile("bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+	verification, e := xl.verifyFile("bucket", "object", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
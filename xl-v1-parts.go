@@ -0,0 +1,251 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	slashpath "path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Each disk holds a single metadata document per file describing the
+// file along with the checksums of all its erasure coded parts. Disks
+// written together hold the same document, it is encoded once per
+// write and any disk's copy can verify any part.
+//
+// The document is committed as a trailer of the erasure coded part of
+// each disk, a single file per disk synced once as its durability
+// requires. Files inlined in their metadata have no parts, and commit
+// the document as xl.json instead. Healed parts are committed without
+// a trailer along with an xl.json, which is read first.
+//
+// Files written before keep a part.json on each disk, holding the
+// checksum of the part of that disk only. It is read as long as the
+// disk has no xl.json, until the file is rewritten or migrated.
+const legacyMetadataFile = "part.json"
+
+// The trailer of a part is followed by a footer of the length of the
+// metadata, big endian, and partTrailerMagic.
+const (
+	partTrailerMagic = "xl.meta1"
+	partFooterSize   = 8 + len(partTrailerMagic)
+)
+
+// encodePartTrailer - returns trailer of a part holding metadataBytes.
+func encodePartTrailer(metadataBytes []byte) []byte {
+	trailer := make([]byte, len(metadataBytes)+partFooterSize)
+	n := copy(trailer, metadataBytes)
+	binary.BigEndian.PutUint64(trailer[n:], uint64(len(metadataBytes)))
+	copy(trailer[n+8:], partTrailerMagic)
+	return trailer
+}
+
+// readPartTrailer - opens metadata in the trailer of the erasure coded
+// part of the file at path on the disk of index. Parts without trailer
+// are reported as errFileNotFound.
+func (xl XL) readPartTrailer(index int, volume, path string) (io.ReadCloser, string, error) {
+	disk := xl.storageDisks[index]
+	erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
+	fileInfo, err := disk.StatFile(volume, erasurePart)
+	if err != nil {
+		return nil, erasurePart, err
+	}
+	if fileInfo.Size < int64(partFooterSize) {
+		return nil, erasurePart, errFileNotFound
+	}
+	reader, err := disk.ReadFile(context.Background(), volume, erasurePart, fileInfo.Size-int64(partFooterSize))
+	if err != nil {
+		return nil, erasurePart, err
+	}
+	footer := make([]byte, partFooterSize)
+	_, err = io.ReadFull(reader, footer)
+	reader.Close()
+	if err != nil {
+		return nil, erasurePart, err
+	}
+	if string(footer[8:]) != partTrailerMagic {
+		return nil, erasurePart, errFileNotFound
+	}
+	length := int64(binary.BigEndian.Uint64(footer[:8]))
+	if length > fileInfo.Size-int64(partFooterSize) {
+		return nil, erasurePart, errDataCorrupt
+	}
+	reader, err = disk.ReadFile(context.Background(), volume, erasurePart, fileInfo.Size-int64(partFooterSize)-length)
+	if err != nil {
+		return nil, erasurePart, err
+	}
+	return limitReadCloser(reader, length), erasurePart, nil
+}
+
+// partSize - returns size of the data of each erasure coded part of a
+// file of size, without its trailer.
+func (xl XL) partSize(size int64) int64 {
+	blocks := size / erasureBlockSize
+	partSize := blocks * int64(getEncodedBlockLen(erasureBlockSize, xl.DataBlocks))
+	if left := size - blocks*erasureBlockSize; left > 0 {
+		partSize += int64(getEncodedBlockLen(int(left), xl.DataBlocks))
+	}
+	return partSize
+}
+
+// SetPartSums - sets checksums of the erasure coded parts, indexed by
+// disk, parts not written have an empty checksum.
+func (f fileMetadata) SetPartSums(sums []string) {
+	f["file.xl.parts512Sum"] = sums
+	delete(f, "file.xl.block512Sum")
+}
+
+// GetPartSum - returns checksum of the part on the disk of index, empty
+// if not recorded. Legacy metadata only holds the checksum of the part
+// on its own disk, and is only asked for it.
+func (f fileMetadata) GetPartSum(index int) string {
+	if sums := f.Get("file.xl.parts512Sum"); sums != nil {
+		if index < len(sums) {
+			return sums[index]
+		}
+		return ""
+	}
+	if sums := f.Get("file.xl.block512Sum"); sums != nil {
+		return sums[0]
+	}
+	return ""
+}
+
+// isSameWrite - returns true if metadata a and b were written by the
+// same write of a file.
+func isSameWrite(a, b fileMetadata) bool {
+	return reflect.DeepEqual(a.Get("file.version"), b.Get("file.version")) &&
+		reflect.DeepEqual(a.Get("file.modTime"), b.Get("file.modTime"))
+}
+
+// consolidatePartSums - returns checksums of all parts of the write
+// described by metadata, gathered from the metadata of each disk.
+// Parts of disks holding another write have an empty checksum.
+func consolidatePartSums(metadata fileMetadata, partsMetadata []fileMetadata) []string {
+	sums := make([]string, len(partsMetadata))
+	for index, partMetadata := range partsMetadata {
+		if partMetadata != nil && isSameWrite(partMetadata, metadata) {
+			sums[index] = partMetadata.GetPartSum(index)
+		}
+	}
+	return sums
+}
+
+// getPartSums - returns checksums of all parts of the file at path
+// described by metadata, read from the metadata of each disk if it
+// is legacy metadata. Read lockNS() should be done by caller.
+func (xl XL) getPartSums(volume, path string, metadata fileMetadata) []string {
+	sums := make([]string, len(xl.storageDisks))
+	if partSums := metadata.Get("file.xl.parts512Sum"); len(partSums) == len(sums) {
+		copy(sums, partSums)
+		return sums
+	}
+	partsMetadata, _ := xl.getPartsMetadata(volume, path)
+	return consolidatePartSums(metadata, partsMetadata)
+}
+
+// readMetadataFile - opens metadata of the file at path on the disk of
+// index, from xl.json, the trailer of its part, or its legacy metadata
+// if the disk has no other. Returns path of the file read.
+func (xl XL) readMetadataFile(index int, volume, path string) (io.ReadCloser, string, error) {
+	disk := xl.storageDisks[index]
+	metadataFilePath := slashpath.Join(path, metadataFile)
+	reader, err := disk.ReadFile(context.Background(), volume, metadataFilePath, 0)
+	if err == nil || errorCause(err) != errFileNotFound {
+		return reader, metadataFilePath, err
+	}
+	trailerReader, erasurePart, trailerErr := xl.readPartTrailer(index, volume, path)
+	if trailerErr == nil || errorCause(trailerErr) != errFileNotFound {
+		return trailerReader, erasurePart, trailerErr
+	}
+	legacyFilePath := slashpath.Join(path, legacyMetadataFile)
+	legacyReader, legacyErr := disk.ReadFile(context.Background(), volume, legacyFilePath, 0)
+	if legacyErr != nil && errorCause(legacyErr) == errFileNotFound {
+		// Files are reported missing by their metadata file.
+		return nil, metadataFilePath, err
+	}
+	return legacyReader, legacyFilePath, legacyErr
+}
+
+// isMetadataFile - returns true if name is the metadata file of a file,
+// of either layout.
+func isMetadataFile(name string) bool {
+	base := slashpath.Base(name)
+	return base == metadataFile || base == legacyMetadataFile
+}
+
+// isErasurePart - returns true if name is an erasure coded part of a
+// file, holding its metadata in a trailer.
+func isErasurePart(name string) bool {
+	base := slashpath.Base(name)
+	if !strings.HasPrefix(base, "part.") {
+		return false
+	}
+	_, err := strconv.ParseUint(strings.TrimPrefix(base, "part."), 10, 32)
+	return err == nil
+}
+
+// isFileEntry - returns true if name is a file a file is listed by,
+// its metadata file or any of its parts.
+func isFileEntry(name string) bool {
+	return isMetadataFile(name) || isErasurePart(name)
+}
+
+// removeMetadataFile - removes xl.json of an earlier version of the file
+// at path from disks marked in written, it would be read instead of the
+// trailer of their parts. Write lockNS() should be done by caller.
+func (xl XL) removeMetadataFile(volume, path string, written []bool) {
+	metadataFilePath := slashpath.Join(path, metadataFile)
+	for index, isWritten := range written {
+		if !isWritten {
+			continue
+		}
+		if err := xl.storageDisks[index].DeleteFile(context.Background(), volume, metadataFilePath); err != nil && errorCause(err) != errFileNotFound {
+			log.WithFields(logrus.Fields{
+				"volume":    volume,
+				"path":      path,
+				"diskIndex": index,
+			}).Errorf("DeleteFile failed with %s", err)
+		}
+	}
+}
+
+// removeLegacyMetadata - removes legacy metadata of the file at path
+// from disks marked in legacy, once they hold its metadata in xl.json.
+// Write lockNS() should be done by caller.
+func (xl XL) removeLegacyMetadata(volume, path string, legacy []bool) {
+	legacyFilePath := slashpath.Join(path, legacyMetadataFile)
+	for index, isLegacy := range legacy {
+		if !isLegacy {
+			continue
+		}
+		if err := xl.storageDisks[index].DeleteFile(context.Background(), volume, legacyFilePath); err != nil && errorCause(err) != errFileNotFound {
+			log.WithFields(logrus.Fields{
+				"volume":    volume,
+				"path":      path,
+				"diskIndex": index,
+			}).Errorf("DeleteFile failed with %s", err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// writeTestFile - writes data to the file at path.
+func writeTestFile(t *testing.T, xl *XL, path string, data []byte) {
+	w, e := xl.CreateFile(context.Background(), "bucket", path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+// readMetadataBytes - returns metadata of the file at path as written
+// on the disk of index.
+func readMetadataBytes(t *testing.T, xl *XL, index int, path string) []byte {
+	r, _, e := xl.readMetadataFile(index, "bucket", path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	metadataBytes, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return metadataBytes
+}
+
+// detachMetadata - writes metadata of the file at path on the disk of
+// index to xl.json, so that it is still read once its part is removed
+// or corrupted.
+func detachMetadata(t *testing.T, xl *XL, index int, path string) {
+	metadataBytes := readMetadataBytes(t, xl, index, path)
+	w, e := xl.storageDisks[index].CreateFile(context.Background(), "bucket", path+"/"+metadataFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(metadataBytes); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+// Tests erasure coded parts are committed with their metadata, a
+// single file per disk, replacing xl.json of an inlined version.
+func TestXLPartTrailer(t *testing.T) {
+	xl := newMemXL(t, 4)
+	writeTestFile(t, xl, "object", []byte("small"))
+	data := testFaultyData()
+	writeTestFile(t, xl, "object", data)
+	for index, disk := range xl.storageDisks {
+		if _, e := disk.StatFile("bucket", "object/"+metadataFile); errorCause(e) != errFileNotFound {
+			t.Fatalf("Disk %d: expected no %s, got %v", index, metadataFile, e)
+		}
+		_, metadataPath, e := xl.readMetadataFile(index, "bucket", "object")
+		if e != nil || metadataPath != fmt.Sprintf("object/part.%d", index) {
+			t.Fatalf("Disk %d: expected metadata read from its part, got %s, %v", index, metadataPath, e)
+		}
+	}
+	if fileInfo, e := xl.StatFile("bucket", "object"); e != nil || fileInfo.Size != int64(len(data)) {
+		t.Fatalf("Expected size %d, got %v, %v", len(data), fileInfo, e)
+	}
+	filesInfo, eof, e := xl.ListFiles("bucket", "", "", true, 1)
+	if e != nil || !eof || len(filesInfo) != 1 || filesInfo[0].Name != "object" {
+		t.Fatalf("Expected the file listed once, got %v, %v, %v", filesInfo, eof, e)
+	}
+	verification, e := xl.verifyFile("bucket", "object", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
+
+// Tests all disks hold the same metadata, with the checksums of all
+// parts, before and after healing.
+func TestXLPartsMetadata(t *testing.T) {
+	xl := newMemXL(t, 4)
+	writeTestFile(t, xl, "object", testFaultyData())
+	checkMetadata := func() {
+		metadataBytes := readMetadataBytes(t, xl, 0, "object")
+		for index := range xl.storageDisks {
+			if !bytes.Equal(readMetadataBytes(t, xl, index, "object"), metadataBytes) {
+				t.Fatalf("Disk %d: expected the same metadata on all disks", index)
+			}
+		}
+		metadata, e := decodeFileMetadata(metadataBytes)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for index := range xl.storageDisks {
+			if metadata.GetPartSum(index) == "" {
+				t.Fatalf("Expected checksum of part %d", index)
+			}
+		}
+	}
+	checkMetadata()
+
+	if e := xl.storageDisks[1].DeleteFile(context.Background(), "bucket", "object/part.1"); e != nil {
+		t.Fatal(e)
+	}
+	if e := xl.healFile("bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+	checkMetadata()
+	verification, e := xl.verifyFile("bucket", "object", false)
+	if e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
+
+// Tests files with legacy metadata left next to their parts are listed
+// once, and legacy metadata is removed once the file is written again
+// or deleted.
+func TestXLLegacyMetadata(t *testing.T) {
+	xl := newMemXL(t, 4)
+	data := testFaultyData()
+	writeTestFile(t, xl, "object", data)
+	writeLegacy := func() {
+		for _, disk := range xl.storageDisks {
+			w, e := disk.CreateFile(context.Background(), "bucket", "object/"+legacyMetadataFile)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if _, e = w.Write([]byte(`{"file.size":["1"],"file.modTime":["2016-01-01T00:00:00.000Z"]}`)); e != nil {
+				t.Fatal(e)
+			}
+			if e = w.Close(); e != nil {
+				t.Fatal(e)
+			}
+		}
+	}
+	writeLegacy()
+
+	filesInfo, eof, e := xl.ListFiles("bucket", "", "", true, 10)
+	if e != nil || !eof || len(filesInfo) != 1 {
+		t.Fatalf("Expected the file listed once, got %v, %v", filesInfo, e)
+	}
+	if filesInfo[0].Size != int64(len(data)) {
+		t.Fatalf("Expected size %d from the part trailer, got %d", len(data), filesInfo[0].Size)
+	}
+
+	// Files written before have only legacy metadata.
+	for index, disk := range xl.storageDisks {
+		if e = disk.DeleteFile(context.Background(), "bucket", fmt.Sprintf("object/part.%d", index)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if fileInfo, e := xl.StatFile("bucket", "object"); e != nil || fileInfo.Size != 1 {
+		t.Fatalf("Expected size 1 from legacy metadata, got %v, %v", fileInfo, e)
+	}
+	writeTestFile(t, xl, "object", data)
+	if _, e = xl.storageDisks[0].ReadFile(context.Background(), "bucket", "object/"+legacyMetadataFile, 0); errorCause(e) != errFileNotFound {
+		t.Fatalf("Expected legacy metadata removed on write, got %v", e)
+	}
+
+	writeLegacy()
+	if e = xl.DeleteFile(context.Background(), "bucket", "object"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e = xl.StatFile("bucket", "object"); errorCause(e) != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+}
@@ -0,0 +1,260 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	slashpath "path"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Placement of pinned volumes is saved under this prefix in
+// minioMetaVolume of all disks, volume names cannot start with a '.'.
+const volumePlacementPrefix = ".placement"
+
+// Maximum size of saved placement.
+const maxPlacementSize = 1024
+
+// errInvalidPlacement - returned for placements which are not a valid
+// subset of disks.
+var errInvalidPlacement = errors.New("Placement should be an even number of distinct disks")
+
+// volumePlacement - disks a volume is pinned to, indexes of XL
+// storage disks.
+type volumePlacement struct {
+	Disks []int `json:"disks"`
+}
+
+// volumePlacements - cache of volume placements, maps volume to the XL
+// of its disks, nil for volumes which are not pinned.
+type volumePlacements struct {
+	mutex  *sync.RWMutex
+	pinned map[string]*XL
+}
+
+// newVolumePlacements - returns an empty placement cache.
+func newVolumePlacements() *volumePlacements {
+	return &volumePlacements{
+		mutex:  &sync.RWMutex{},
+		pinned: make(map[string]*XL),
+	}
+}
+
+// placementPath - returns placement path of volume in minioMetaVolume.
+func placementPath(volume string) string {
+	return slashpath.Join(volumePlacementPrefix, volume+".json")
+}
+
+// newPinnedXL - returns XL of a subset of disks, sharing the namespace
+// lock with xl. Quorum is computed over the subset.
+func (xl XL) newPinnedXL(disks []int) (*XL, error) {
+	if len(disks) == 0 || len(disks)%2 != 0 {
+		return nil, errInvalidPlacement
+	}
+	pinned := &XL{
+		nameSpaceLockMap:      xl.nameSpaceLockMap,
+		nameSpaceLockMapMutex: xl.nameSpaceLockMapMutex,
+		readCounters:          xl.readCounters,
+		blockCache:            xl.blockCache,
+		verifyWrites:          xl.verifyWrites,
+		codings:               xl.codings,
+	}
+	seen := make(map[int]bool)
+	for _, index := range disks {
+		if index < 0 || index >= len(xl.storageDisks) || seen[index] {
+			return nil, errInvalidPlacement
+		}
+		seen[index] = true
+		pinned.storageDisks = append(pinned.storageDisks, xl.storageDisks[index])
+		pinned.diskPaths = append(pinned.diskPaths, xl.diskPaths[index])
+	}
+	if err := pinned.initErasure(len(disks)); err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// readPlacement - reads saved placement of volume from the first disk
+// which has it, ok is false if volume is not pinned.
+func (xl XL) readPlacement(volume string) (placement volumePlacement, ok bool, err error) {
+	for _, disk := range xl.storageDisks {
+		reader, rErr := disk.ReadFile(context.Background(), minioMetaVolume, placementPath(volume), 0)
+		if rErr != nil {
+			if errorCause(rErr) != errFileNotFound && errorCause(rErr) != errVolumeNotFound {
+				err = rErr
+			}
+			continue
+		}
+		placementBytes, rErr := ioutil.ReadAll(io.LimitReader(reader, maxPlacementSize))
+		reader.Close()
+		if rErr != nil {
+			err = rErr
+			continue
+		}
+		if rErr = json.Unmarshal(placementBytes, &placement); rErr != nil {
+			err = rErr
+			continue
+		}
+		return placement, true, nil
+	}
+	return volumePlacement{}, false, err
+}
+
+// writePlacement - saves placement of volume on all disks, so that it
+// can be read as long as any disk is online.
+func (xl XL) writePlacement(volume string, placement volumePlacement) error {
+	placementBytes, err := json.Marshal(placement)
+	if err != nil {
+		return err
+	}
+	var errCount int
+	for index, disk := range xl.storageDisks {
+		err = disk.MakeVol(minioMetaVolume)
+		if err == nil || errorCause(err) == errVolumeExists {
+			var writer io.WriteCloser
+			if writer, err = disk.CreateFile(context.Background(), minioMetaVolume, placementPath(volume)); err == nil {
+				if _, err = writer.Write(placementBytes); err != nil {
+					safeCloseAndRemove(writer)
+				} else {
+					err = writer.Close()
+				}
+			}
+		}
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"disk":   index,
+			}).Errorf("Saving placement failed with %s", err)
+			errCount++
+		}
+	}
+	if errCount > len(xl.storageDisks)-xl.writeQuorum {
+		return errWriteQuorum
+	}
+	return nil
+}
+
+// removePlacement - removes saved placement of volume from all disks.
+func (xl XL) removePlacement(volume string) {
+	for index, disk := range xl.storageDisks {
+		err := disk.DeleteFile(context.Background(), minioMetaVolume, placementPath(volume))
+		if err != nil && errorCause(err) != errFileNotFound && errorCause(err) != errVolumeNotFound {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"disk":   index,
+			}).Errorf("Removing placement failed with %s", err)
+		}
+	}
+	xl.placements.mutex.Lock()
+	delete(xl.placements.pinned, volume)
+	xl.placements.mutex.Unlock()
+}
+
+// volumeDisks - returns XL of the disks serving volume. Pinned volumes
+// are served by the disks they are pinned to, other volumes by all
+// disks of xl. Operations on files and volumes go through it first.
+func (xl XL) volumeDisks(volume string) (XL, error) {
+	if xl.placements == nil || volume == minioMetaVolume {
+		return xl, nil
+	}
+	xl.placements.mutex.RLock()
+	pinned, cached := xl.placements.pinned[volume]
+	xl.placements.mutex.RUnlock()
+	if !cached {
+		placement, ok, err := xl.readPlacement(volume)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+			}).Errorf("Reading placement failed with %s", err)
+			return XL{}, err
+		}
+		if ok {
+			if pinned, err = xl.newPinnedXL(placement.Disks); err != nil {
+				return XL{}, err
+			}
+		}
+		xl.placements.mutex.Lock()
+		xl.placements.pinned[volume] = pinned
+		xl.placements.mutex.Unlock()
+	}
+	if pinned == nil {
+		return xl, nil
+	}
+	return *pinned, nil
+}
+
+// appendPinnedVols - appends pinned volumes listed by any disk but
+// missing in volsInfo, which is listed by a single disk.
+func (xl XL) appendPinnedVols(volsInfo []VolInfo, disksVolsInfo map[int][]VolInfo) []VolInfo {
+	listed := make(map[string]bool)
+	for _, volInfo := range volsInfo {
+		listed[volInfo.Name] = true
+	}
+	for _, diskVolsInfo := range disksVolsInfo {
+		for _, volInfo := range diskVolsInfo {
+			if listed[volInfo.Name] || !isValidVolname(volInfo.Name) {
+				continue
+			}
+			listed[volInfo.Name] = true
+			pinnedXL, err := xl.volumeDisks(volInfo.Name)
+			if err != nil || pinnedXL.placements != nil {
+				continue
+			}
+			if volInfo, err = pinnedXL.StatVol(volInfo.Name); err == nil {
+				volsInfo = append(volsInfo, volInfo)
+			}
+		}
+	}
+	return volsInfo
+}
+
+// MakeVolOnDisks - makes a volume pinned to a subset of disks, given
+// as indexes of the storage disks. Objects of the volume are erasure
+// coded over the subset only.
+func (xl XL) MakeVolOnDisks(volume string, disks []int) error {
+	if !isValidVolname(volume) {
+		return errInvalidArgument
+	}
+	pinned, err := xl.newPinnedXL(disks)
+	if err != nil {
+		return err
+	}
+	if _, err = xl.StatVol(volume); errorCause(err) != errVolumeNotFound {
+		if err == nil {
+			return errVolumeExists
+		}
+		return err
+	}
+	// Save placement first, volume is never seen without it.
+	if err = xl.writePlacement(volume, volumePlacement{Disks: disks}); err != nil {
+		return err
+	}
+	if err = pinned.MakeVol(volume); err != nil {
+		xl.removePlacement(volume)
+		return err
+	}
+	xl.placements.mutex.Lock()
+	xl.placements.pinned[volume] = pinned
+	xl.placements.mutex.Unlock()
+	return nil
+}
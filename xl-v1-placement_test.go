@@ -0,0 +1,129 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests buckets pinned to a subset of disks.
+func TestXLBucketPlacement(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-placement-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI := newObjectLayer(xl)
+
+	testCases := []struct {
+		disks []int
+		err   error
+	}{
+		{[]int{0}, InvalidPlacement{}},
+		{[]int{0, 0}, InvalidPlacement{}},
+		{[]int{0, 4}, InvalidPlacement{}},
+		{[]int{2, 3}, nil},
+		{[]int{0, 1}, BucketExists{Bucket: "fast"}},
+	}
+	for i, testCase := range testCases {
+		err := objAPI.MakeBucketOnDisks("fast", testCase.disks)
+		if testCase.err == nil && err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		if testCase.err != nil && (err == nil || err.ToGoError() != testCase.err) {
+			t.Fatalf("Test %d: Expected %s, got %v", i+1, testCase.err, err)
+		}
+	}
+	if err := objAPI.MakeBucket("fast"); err == nil {
+		t.Fatal("Expected error making existing pinned bucket")
+	}
+
+	// Large enough to be erasure coded.
+	data := bytes.Repeat([]byte("a"), xlInlineMaxSize+1)
+	if _, err := objAPI.PutObject("fast", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Data is only erasure coded over the pinned disks.
+	for index, disk := range disks {
+		_, e = os.Stat(filepath.Join(disk, "fast"))
+		if pinned := index >= 2; pinned != (e == nil) {
+			t.Fatalf("Unexpected bucket on disk %d, %v", index, e)
+		}
+	}
+	if _, e = os.Stat(filepath.Join(disks[3], "fast", "object", "part.1")); e != nil {
+		t.Fatal(e)
+	}
+
+	// Placement is read back after a restart.
+	xl, e = newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	objAPI = newObjectLayer(xl)
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].Name != "fast" {
+		t.Fatalf("Expected pinned bucket to be listed, got %v", buckets)
+	}
+	r, err := objAPI.GetObject("fast", "object", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readData, e := ioutil.ReadAll(r)
+	r.Close()
+	if e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected object data, %v", e)
+	}
+
+	// Deleted bucket can be made again on other disks.
+	if err = objAPI.DeleteObject("fast", "object", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = objAPI.DeleteBucket("fast"); err != nil {
+		t.Fatal(err)
+	}
+	if err = objAPI.MakeBucketOnDisks("fast", []int{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, e = os.Stat(filepath.Join(disks[0], "fast")); e != nil {
+		t.Fatal(e)
+	}
+
+	// FS cannot pin buckets.
+	fs, e := newFS(disks[0])
+	if e != nil {
+		t.Fatal(e)
+	}
+	err = newObjectLayer(fs).MakeBucketOnDisks("bucket", []int{0, 1})
+	if err == nil || err.ToGoError() != (PlacementNotSupported{}) {
+		t.Fatalf("Expected placement not supported, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Each block read ahead holds its encoded parts and decoded data in
+// memory, read ahead is limited to this many blocks per read.
+const xlMaxReadAhead = 16
+
+// xlDecodedBlock - decoded data of a block read ahead.
+type xlDecodedBlock struct {
+	block int64
+	data  []byte
+	err   error
+}
+
+// readAhead - reads encoded blocks of file of size from readers,
+// starting at block, and decodes up to count of them in background.
+// Results are sent in order of blocks, each on its own channel once
+// decoded. Blocks are read until the block holding the byte before
+// end, a failure, ctx is done or stop is closed, then blocks is
+// closed.
+func (xl XL) readAhead(ctx context.Context, volume, path string, readers []io.ReadCloser, block, end, size int64, count int) (blocks chan chan xlDecodedBlock, stop chan struct{}) {
+	blocks = make(chan chan xlDecodedBlock, count)
+	stop = make(chan struct{})
+	go func() {
+		defer close(blocks)
+		for ; block*erasureBlockSize < end; block++ {
+			result := make(chan xlDecodedBlock, 1)
+			select {
+			case blocks <- result:
+			case <-stop:
+				return
+			}
+			// Client went away or the request timed out.
+			if err := ctx.Err(); err != nil {
+				result <- xlDecodedBlock{block: block, err: err}
+				return
+			}
+			blockSize := erasureBlockSize
+			if left := size - block*erasureBlockSize; left < erasureBlockSize {
+				blockSize = int(left)
+			}
+			enBlocks, missing := readEncodedBlock(readers, getEncodedBlockLen(blockSize, xl.DataBlocks))
+			go func(block int64) {
+				decoded := bytes.NewBuffer(make([]byte, 0, blockSize))
+				err := xl.decodeBlock(volume, path, decoded, enBlocks, missing, blockSize)
+				result <- xlDecodedBlock{block: block, data: decoded.Bytes(), err: err}
+			}(block)
+		}
+	}()
+	return blocks, stop
+}
@@ -17,6 +17,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -25,8 +27,64 @@ import (
 	"github.com/Sirupsen/logrus"
 )
 
-// ReadFile - read file
-func (xl XL) ReadFile(volume, path string, offset int64) (io.ReadCloser, error) {
+// skipWriter - discards the first skip bytes written, writes the rest
+// to writer.
+type skipWriter struct {
+	writer io.Writer
+	skip   int64
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	if s.skip >= int64(len(p)) {
+		s.skip -= int64(len(p))
+		return len(p), nil
+	}
+	n, err := s.writer.Write(p[s.skip:])
+	n += int(s.skip)
+	s.skip = 0
+	return n, err
+}
+
+// limitWriter - writes the first left bytes written to writer,
+// discards the rest.
+type limitWriter struct {
+	writer io.Writer
+	left   int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.left <= 0 {
+		return len(p), nil
+	}
+	data := p
+	if int64(len(data)) > l.left {
+		data = data[:l.left]
+	}
+	n, err := l.writer.Write(data)
+	l.left -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return len(p), nil
+}
+
+// ReadFile - read file, decoding stops once ctx is done.
+func (xl XL) ReadFile(ctx context.Context, volume, path string, offset int64) (io.ReadCloser, error) {
+	return xl.readFile(ctx, volume, path, offset, -1)
+}
+
+// ReadFileRange - read length bytes of file at offset, only erasure
+// stripes holding them are read and decoded.
+func (xl XL) ReadFileRange(ctx context.Context, volume, path string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 {
+		return nil, errInvalidArgument
+	}
+	return xl.readFile(ctx, volume, path, offset, length)
+}
+
+// readFile - read length bytes of file at offset, all of it if length
+// is negative.
+func (xl XL) readFile(ctx context.Context, volume, path string, offset, length int64) (io.ReadCloser, error) {
 	// Input validation.
 	if !isValidVolname(volume) {
 		return nil, errInvalidArgument
@@ -35,11 +93,29 @@ func (xl XL) ReadFile(volume, path string, offset int64) (io.ReadCloser, error)
 		return nil, errInvalidArgument
 	}
 
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Hot files are served from the block cache, blocks decoded
+	// from now on are cached unless the file is written meanwhile.
+	generation := xl.blockCache.Generation()
+	if reader, ok := xl.blockCache.get(volume, path, offset); ok {
+		return limitReadCloser(reader, length), nil
+	}
+
 	// Acquire a read lock.
 	readLock := true
-	xl.lockNS(volume, path, readLock)
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return nil, err
+	}
 	onlineDisks, metadata, heal, err := xl.listOnlineDisks(volume, path)
-	xl.unlockNS(volume, path, readLock)
+	xl.unlockNS(holder)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
@@ -48,11 +124,16 @@ func (xl XL) ReadFile(volume, path string, offset int64) (io.ReadCloser, error)
 		return nil, err
 	}
 
+	// Files are decoded with the parity they were written with.
+	if xl, err = xl.forFile(metadata); err != nil {
+		return nil, err
+	}
+
 	if heal {
 		// Heal in background safely, since we already have read
 		// quorum disks. Let the reads continue.
 		go func() {
-			if err = xl.healFile(volume, path); err != nil {
+			if err := xl.healFile(volume, path); err != nil {
 				log.WithFields(logrus.Fields{
 					"volume": volume,
 					"path":   path,
@@ -71,142 +152,283 @@ func (xl XL) ReadFile(volume, path string, offset int64) (io.ReadCloser, error)
 		return nil, err
 	}
 
+	// Inlined files are served from the metadata already read.
+	if metadata.IsInline() {
+		xl.readCounters.addInline()
+		if xl.blockCache.cacheable(fileSize) {
+			if data, _, err := metadata.GetInlineData(); err == nil {
+				xl.blockCache.add(volume, path, fileSize, 0, data, generation)
+			}
+		}
+		reader, err := xl.readInline(volume, path, metadata, offset)
+		if err != nil {
+			return nil, err
+		}
+		return limitReadCloser(reader, length), nil
+	}
+	xl.readCounters.addErasure()
+
+	// Reading starts at the erasure stripe holding offset, parts are
+	// read from the same stripe and decoded data before offset is
+	// skipped.
+	stripeIndex := offset / erasureBlockSize
+	partOffset := stripeIndex * int64(getEncodedBlockLen(erasureBlockSize, xl.DataBlocks))
+	skipSize := offset % erasureBlockSize
+
+	// Reading ends with the stripe holding the last byte of the range.
+	endSize := fileSize
+	if length >= 0 && offset+length < fileSize {
+		endSize = offset + length
+	}
+
 	// Acquire read lock again.
-	xl.lockNS(volume, path, readLock)
+	if holder, err = xl.lockNS(volume, path, readLock); err != nil {
+		return nil, err
+	}
 	readers := make([]io.ReadCloser, len(xl.storageDisks))
-	for index, disk := range onlineDisks {
+	errs := make([]error, len(xl.storageDisks))
+	readableCount := 0
+	// Healthiest disks are read first, disks failing health checks
+	// are skipped once enough parts are readable to decode.
+	for _, index := range xl.readOrder() {
+		disk := onlineDisks[index]
 		if disk == nil {
+			errs[index] = errDiskNotOnline
+			continue
+		}
+		if readableCount >= xl.DataBlocks && globalDiskHealth.Score(xl.diskPaths[index]) < diskHealthReadMinScore {
 			continue
 		}
 		erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
-		// If disk.ReadFile returns error and we don't have read quorum it will be taken care as
-		// ReedSolomon.Reconstruct() will fail later.
+		// If disk.ReadFile returns error and we still have enough
+		// readable parts, missing blocks are reconstructed later.
 		var reader io.ReadCloser
-		if reader, err = disk.ReadFile(volume, erasurePart, offset); err == nil {
-			readers[index] = reader
+		if reader, err = disk.ReadFile(ctx, volume, erasurePart, partOffset); err != nil {
+			errs[index] = newDiskErr("ReadFile", index, volume, erasurePart, err)
+			continue
 		}
+		readers[index] = reader
+		readableCount++
+	}
+	xl.unlockNS(holder)
+
+	// Data cannot be reconstructed from fewer parts than data blocks,
+	// fail before streaming so that callers see why.
+	if readableCount < xl.DataBlocks {
+		for _, reader := range readers {
+			if reader != nil {
+				reader.Close()
+			}
+		}
+		log.WithFields(logrus.Fields{
+			"volume":          volume,
+			"path":            path,
+			"readableCount":   readableCount,
+			"dataBlocksCount": xl.DataBlocks,
+			"failedDisks":     xl.failedDisks(errs),
+		}).Errorf("%s", errReadQuorum)
+		return nil, xl.newReadQuorumError(xl.DataBlocks, errs)
+	}
+
+	// Blocks decoded ahead of the block being written.
+	readAhead := globalDiskIO.Get().ReadAhead
+	if readAhead > xlMaxReadAhead {
+		readAhead = xlMaxReadAhead
 	}
-	xl.unlockNS(volume, path, readLock)
 
 	// Initialize pipe.
 	pipeReader, pipeWriter := io.Pipe()
+	done := make(chan struct{})
 	go func() {
-		var totalLeft = fileSize
-		// Read until the totalLeft.
-		for totalLeft > 0 {
-			// Figure out the right blockSize as it was encoded before.
-			var curBlockSize int
-			if erasureBlockSize < totalLeft {
-				curBlockSize = erasureBlockSize
-			} else {
-				curBlockSize = int(totalLeft)
-			}
-			// Calculate the current encoded block size.
-			curEncBlockSize := getEncodedBlockLen(curBlockSize, xl.DataBlocks)
-			enBlocks := make([][]byte, len(xl.storageDisks))
-			// Loop through all readers and read.
-			for index, reader := range readers {
-				// Initialize shard slice and fill the data from each parts.
-				enBlocks[index] = make([]byte, curEncBlockSize)
-				if reader == nil {
-					continue
-				}
-				_, err = io.ReadFull(reader, enBlocks[index])
-				if err != nil && err != io.ErrUnexpectedEOF {
-					readers[index] = nil
+		defer close(done)
+		// Close all the underlying data readers, also on failures.
+		defer func() {
+			for _, reader := range readers {
+				if reader != nil {
+					reader.Close()
 				}
 			}
+		}()
+		// Writer discarding data of the first stripe before offset,
+		// and of the last stripe after the range.
+		writer := &skipWriter{writer: &limitWriter{writer: pipeWriter, left: endSize - offset}, skip: skipSize}
+		// Decoded blocks of hot files are cached.
+		cacheable := xl.blockCache.cacheable(fileSize)
 
-			// TODO need to verify block512Sum.
-
-			// Check blocks if they are all zero in length.
-			if checkBlockSize(enBlocks) == 0 {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("%s", errDataCorrupt)
-				pipeWriter.CloseWithError(errDataCorrupt)
-				return
-			}
-
-			// Verify the blocks.
-			var ok bool
-			ok, err = xl.ReedSolomon.Verify(enBlocks)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("ReedSolomon verify failed with %s", err)
-				pipeWriter.CloseWithError(err)
-				return
-			}
-
-			// Verification failed, blocks require reconstruction.
-			if !ok {
-				for index, reader := range readers {
-					if reader == nil {
-						// Reconstruct expects missing blocks to be nil.
-						enBlocks[index] = nil
-					}
+		// Following blocks are decoded in background while the
+		// current block is written.
+		if readAhead > 0 {
+			blocks, stop := xl.readAhead(ctx, volume, path, readers, stripeIndex, endSize, fileSize, readAhead)
+			defer func() {
+				// Wait for blocks being read before the readers are
+				// closed.
+				close(stop)
+				for range blocks {
 				}
-				err = xl.ReedSolomon.Reconstruct(enBlocks)
-				if err != nil {
-					log.WithFields(logrus.Fields{
-						"volume": volume,
-						"path":   path,
-					}).Errorf("ReedSolomon reconstruct failed with %s", err)
-					pipeWriter.CloseWithError(err)
-					return
+			}()
+			for result := range blocks {
+				decoded := <-result
+				if err = decoded.err; err == nil {
+					if cacheable {
+						xl.blockCache.add(volume, path, fileSize, decoded.block, decoded.data, generation)
+					}
+					_, err = writer.Write(decoded.data)
 				}
-				// Verify reconstructed blocks again.
-				ok, err = xl.ReedSolomon.Verify(enBlocks)
 				if err != nil {
-					log.WithFields(logrus.Fields{
-						"volume": volume,
-						"path":   path,
-					}).Errorf("ReedSolomon verify failed with %s", err)
-					pipeWriter.CloseWithError(err)
-					return
-				}
-				if !ok {
-					// Blocks cannot be reconstructed, corrupted data.
-					err = errors.New("Verification failed after reconstruction, data likely corrupted.")
-					log.WithFields(logrus.Fields{
-						"volume": volume,
-						"path":   path,
-					}).Errorf("%s", err)
 					pipeWriter.CloseWithError(err)
 					return
 				}
 			}
+			pipeWriter.Close()
+			return
+		}
+
+		var totalLeft = fileSize - stripeIndex*erasureBlockSize
+		block := stripeIndex
+		// Read until the totalLeft, or the end of the range.
+		for totalLeft > 0 && block*erasureBlockSize < endSize {
+			// Client went away or the request timed out.
+			if err = ctx.Err(); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			// Figure out the right blockSize as it was encoded before.
+			var curBlockSize int
+			if erasureBlockSize < totalLeft {
+				curBlockSize = erasureBlockSize
+			} else {
+				curBlockSize = int(totalLeft)
+			}
+			enBlocks, missing := readEncodedBlock(readers, getEncodedBlockLen(curBlockSize, xl.DataBlocks))
 
 			// Join the decoded blocks.
-			err = xl.ReedSolomon.Join(pipeWriter, enBlocks, curBlockSize)
+			if cacheable {
+				decoded := &bytes.Buffer{}
+				if err = xl.decodeBlock(volume, path, decoded, enBlocks, missing, curBlockSize); err == nil {
+					xl.blockCache.add(volume, path, fileSize, block, decoded.Bytes(), generation)
+					_, err = writer.Write(decoded.Bytes())
+				}
+			} else {
+				err = xl.decodeBlock(volume, path, writer, enBlocks, missing, curBlockSize)
+			}
 			if err != nil {
-				log.WithFields(logrus.Fields{
-					"volume": volume,
-					"path":   path,
-				}).Errorf("ReedSolomon joining decoded blocks failed with %s", err)
 				pipeWriter.CloseWithError(err)
 				return
 			}
 
 			// Save what's left after reading erasureBlockSize.
 			totalLeft = totalLeft - erasureBlockSize
+			block++
 		}
 
 		// Cleanly end the pipe after a successful decoding.
 		pipeWriter.Close()
+	}()
 
-		// Cleanly close all the underlying data readers.
-		for _, reader := range readers {
-			if reader == nil {
-				continue
-			}
-			reader.Close()
+	// Abort the pipe once ctx is done, unblocking a pending write.
+	go func() {
+		select {
+		case <-ctx.Done():
+			pipeWriter.CloseWithError(ctx.Err())
+		case <-done:
 		}
 	}()
 
 	// Return the pipe for the top level caller to start reading.
 	return pipeReader, nil
 }
+
+// readEncodedBlock - reads encoded block of size bytes from each part,
+// readers failing are closed and set to nil. Parts with no reader are
+// marked missing.
+func readEncodedBlock(readers []io.ReadCloser, size int) (enBlocks [][]byte, missing []bool) {
+	enBlocks = make([][]byte, len(readers))
+	missing = make([]bool, len(readers))
+	// Loop through all readers and read.
+	for index, reader := range readers {
+		// Initialize shard slice and fill the data from each parts.
+		enBlocks[index] = make([]byte, size)
+		if reader == nil {
+			missing[index] = true
+			continue
+		}
+		_, err := io.ReadFull(reader, enBlocks[index])
+		if err != nil && err != io.ErrUnexpectedEOF {
+			reader.Close()
+			readers[index] = nil
+			missing[index] = true
+		}
+	}
+	return enBlocks, missing
+}
+
+// decodeBlock - verifies encoded blocks, reconstructing missing parts
+// if needed, and writes size bytes of decoded data to writer.
+func (xl XL) decodeBlock(volume, path string, writer io.Writer, enBlocks [][]byte, missing []bool, size int) error {
+	// TODO need to verify block512Sum.
+
+	// Check blocks if they are all zero in length.
+	if checkBlockSize(enBlocks) == 0 {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("%s", errDataCorrupt)
+		return errDataCorrupt
+	}
+
+	// Verify the blocks.
+	ok, err := xl.ReedSolomon.Verify(enBlocks)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("ReedSolomon verify failed with %s", err)
+		return err
+	}
+
+	// Verification failed, blocks require reconstruction.
+	if !ok {
+		for index := range enBlocks {
+			if missing[index] {
+				// Reconstruct expects missing blocks to be nil.
+				enBlocks[index] = nil
+			}
+		}
+		err = xl.ReedSolomon.Reconstruct(enBlocks)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+			}).Errorf("ReedSolomon reconstruct failed with %s", err)
+			return err
+		}
+		// Verify reconstructed blocks again.
+		ok, err = xl.ReedSolomon.Verify(enBlocks)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+			}).Errorf("ReedSolomon verify failed with %s", err)
+			return err
+		}
+		if !ok {
+			// Blocks cannot be reconstructed, corrupted data.
+			err = errors.New("Verification failed after reconstruction, data likely corrupted.")
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+			}).Errorf("%s", err)
+			return err
+		}
+	}
+
+	// Join the decoded blocks.
+	if err = xl.ReedSolomon.Join(writer, enBlocks, size); err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("ReedSolomon joining decoded blocks failed with %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests read quorum errors carry diagnostics of the failed disks.
+func TestXLReadQuorumDiagnostics(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	for _, object := range []string{"missing", "quorum", "parts"} {
+		w, e := xl.CreateFile(context.Background(), "bucket", object)
+		if e != nil {
+			t.Fatal(e)
+		}
+		// Large enough to be erasure coded.
+		if _, e = w.Write(bytes.Repeat([]byte("a"), xlInlineMaxSize+1)); e != nil {
+			t.Fatal(e)
+		}
+		if e = w.Close(); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	// Object missing on all disks is not a quorum loss.
+	for index, disk := range disks {
+		os.Remove(filepath.Join(disk, "bucket", "missing", fmt.Sprintf("part.%d", index)))
+	}
+	if _, e = xl.StatFile("bucket", "missing"); e != errFileNotFound {
+		t.Fatalf("Expected %s, got %v", errFileNotFound, e)
+	}
+
+	// Metadata readable on fewer than read quorum disks.
+	for index, disk := range disks[:2] {
+		os.Remove(filepath.Join(disk, "bucket", "quorum", fmt.Sprintf("part.%d", index)))
+	}
+	_, e = xl.StatFile("bucket", "quorum")
+	qErr, ok := e.(readQuorumError)
+	if !ok {
+		t.Fatalf("Expected read quorum error, got %v", e)
+	}
+	if qErr.ReadQuorum != 3 || qErr.Available != 2 || len(qErr.Disks) != 2 {
+		t.Fatalf("Unexpected diagnostics %+v", qErr)
+	}
+	for i, diskErr := range qErr.Disks {
+		if diskErr.Disk != disks[i] || diskErr.Error != errFileNotFound.Error() {
+			t.Fatalf("Unexpected failed disk %+v", diskErr)
+		}
+	}
+	objErr, ok := toObjectErr(e, "bucket", "quorum").(StorageInsufficientReadResources)
+	if !ok || objErr.Available != 2 || len(objErr.Disks) != 2 {
+		t.Fatalf("Expected diagnostics in object error, got %v", objErr)
+	}
+
+	// Parts readable on fewer disks than data blocks, along with their
+	// metadata.
+	for index, disk := range disks[1:] {
+		detachMetadata(t, xl.(*XL), index+1, "parts")
+		os.Remove(filepath.Join(disk, "bucket", "parts", fmt.Sprintf("part.%d", index+1)))
+	}
+	_, e = xl.ReadFile(context.Background(), "bucket", "parts", 0)
+	if qErr, ok = e.(readQuorumError); !ok {
+		t.Fatalf("Expected read quorum error, got %v", e)
+	}
+	if qErr.ReadQuorum != 2 || qErr.Available != 1 || len(qErr.Disks) != 3 || qErr.Disks[0].Disk != disks[1] {
+		t.Fatalf("Unexpected diagnostics %+v", qErr)
+	}
+}
+
+// Tests reads starting at an offset within and across stripes.
+func TestXLReadFileOffset(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, 2*erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	for _, offset := range []int64{0, 7, erasureBlockSize, erasureBlockSize + 7, 2 * erasureBlockSize, int64(len(data)) - 1} {
+		r, e := xl.ReadFile(context.Background(), "bucket", "object", offset)
+		if e != nil {
+			t.Fatal(e)
+		}
+		readData, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(readData, data[offset:]) {
+			t.Fatalf("Unexpected data read at offset %d", offset)
+		}
+	}
+}
+
+// Tests reads of ranges within and across stripes, with and without
+// blocks decoded ahead.
+func TestXLReadFileRange(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, 3*erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	reader, ok := xl.(rangeReader)
+	if !ok {
+		t.Fatal("Expected XL to read ranges")
+	}
+	defer globalDiskIO.Set(globalDiskIO.Get())
+	testCases := []struct {
+		offset, length int64
+	}{
+		{0, 0},
+		{0, 10},
+		{7, erasureBlockSize - 7},
+		{erasureBlockSize - 7, 14},
+		{erasureBlockSize + 7, 2 * erasureBlockSize},
+		{int64(len(data)) - 10, 10},
+		// Ranges past the end of the file are cut short.
+		{int64(len(data)) - 10, 100},
+	}
+	for _, readAhead := range []int{0, 2} {
+		globalDiskIO.Set(diskIOConfig{ReadAhead: readAhead})
+		for i, testCase := range testCases {
+			r, e := reader.ReadFileRange(context.Background(), "bucket", "object", testCase.offset, testCase.length)
+			if e != nil {
+				t.Fatal(e)
+			}
+			readData, e := ioutil.ReadAll(r)
+			r.Close()
+			if e != nil {
+				t.Fatal(e)
+			}
+			end := testCase.offset + testCase.length
+			if end > int64(len(data)) {
+				end = int64(len(data))
+			}
+			if !bytes.Equal(readData, data[testCase.offset:end]) {
+				t.Fatalf("Test %d: Unexpected data read with read ahead %d, got %d bytes", i+1, readAhead, len(readData))
+			}
+		}
+	}
+	if _, e = reader.ReadFileRange(context.Background(), "bucket", "object", 0, -1); e != errInvalidArgument {
+		t.Fatalf("Expected %v, got %v", errInvalidArgument, e)
+	}
+}
+
+// Tests reads decoding blocks ahead return the same data, also when
+// parts need reconstruction.
+func TestXLReadAhead(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, 3*erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	defer globalDiskIO.Set(globalDiskIO.Get())
+	readAll := func(offset int64) {
+		r, e := xl.ReadFile(context.Background(), "bucket", "object", offset)
+		if e != nil {
+			t.Fatal(e)
+		}
+		readData, e := ioutil.ReadAll(r)
+		r.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(readData, data[offset:]) {
+			t.Fatalf("Unexpected data read at offset %d", offset)
+		}
+	}
+	for _, readAhead := range []int{1, 2, xlMaxReadAhead + 1} {
+		globalDiskIO.Set(diskIOConfig{ReadAhead: readAhead})
+		for _, offset := range []int64{0, erasureBlockSize + 7, int64(len(data)) - 1} {
+			readAll(offset)
+		}
+	}
+
+	// Blocks of missing parts are reconstructed.
+	os.Remove(filepath.Join(disks[0], "bucket", "object", "part.0"))
+	readAll(0)
+
+	// Reading stops once ctx is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	r, e := xl.ReadFile(ctx, "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	buffer := make([]byte, 1)
+	if _, e = io.ReadFull(r, buffer); e != nil {
+		t.Fatal(e)
+	}
+	cancel()
+	if _, e = ioutil.ReadAll(r); e != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, e)
+	}
+}
+
+// clientWriter - discards data written, taking as long as a client
+// receiving at about 1GiB/s, a millisecond for each MiB.
+type clientWriter struct {
+	n int
+}
+
+func (c *clientWriter) Write(p []byte) (int, error) {
+	for c.n += len(p); c.n >= 1024*1024; c.n -= 1024 * 1024 {
+		time.Sleep(time.Millisecond)
+	}
+	return len(p), nil
+}
+
+func BenchmarkXLReadFile(b *testing.B) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			b.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	xl, e := newXL(disks...)
+	if e != nil {
+		b.Fatal(e)
+	}
+	if e = xl.MakeVol("bucket"); e != nil {
+		b.Fatal(e)
+	}
+	data := bytes.Repeat([]byte("a"), 8*erasureBlockSize)
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		b.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		b.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		b.Fatal(e)
+	}
+	defer globalDiskIO.Set(globalDiskIO.Get())
+	for _, readAhead := range []int{0, 1, 4} {
+		b.Run(fmt.Sprintf("readahead-%d", readAhead), func(b *testing.B) {
+			globalDiskIO.Set(diskIOConfig{ReadAhead: readAhead})
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+				if e != nil {
+					b.Fatal(e)
+				}
+				if _, e = io.Copy(&clientWriter{}, r); e != nil {
+					b.Fatal(e)
+				}
+				r.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// This file describes every key of fileMetadata known to this server
+// as a typed field. Metadata is validated against the schema when it
+// is written and when it is read, so that metadata with half written
+// or mistyped values is never committed, and is seen as unreadable
+// on the disks it was found on. Keys not in the schema, added by
+// newer minor formats, are kept as is.
+
+// Types of file metadata values.
+const (
+	fileMetadataString = "string"
+	fileMetadataInt    = "int"
+	fileMetadataTime   = "time"
+	fileMetadataHex    = "hex"
+	fileMetadataBase64 = "base64"
+)
+
+// fileMetadataField - typed key of fileMetadata.
+type fileMetadataField struct {
+	Type string
+	// Minimum of int values.
+	Min int64
+	// Format metadata should have the key from, never required if
+	// zero.
+	Since xlFormat
+	// Key holds a value per disk instead of a single value.
+	PerDisk bool
+}
+
+// fileMetadataSchema - known keys of fileMetadata.
+var fileMetadataSchema = map[string]fileMetadataField{
+	"version":              {Type: fileMetadataString},
+	"format.major":         {Type: fileMetadataInt},
+	"format.minor":         {Type: fileMetadataInt},
+	"format.patch":         {Type: fileMetadataInt},
+	"file.size":            {Type: fileMetadataInt},
+	"file.modTime":         {Type: fileMetadataTime},
+	"file.version":         {Type: fileMetadataInt, Since: xlFormatV1FileVersion},
+	"file.xl.blockSize":    {Type: fileMetadataInt, Min: 1, Since: xlFormatV1},
+	"file.xl.dataBlocks":   {Type: fileMetadataInt, Min: 1},
+	"file.xl.parityBlocks": {Type: fileMetadataInt, Min: 1},
+	"file.xl.block512Sum":  {Type: fileMetadataHex},
+	"file.xl.parts512Sum":  {Type: fileMetadataHex, PerDisk: true},
+	"file.xl.inline":       {Type: fileMetadataBase64},
+	"file.xl.inline512Sum": {Type: fileMetadataHex},
+}
+
+// Keys required by all formats, the format itself is not recorded by
+// legacy metadata.
+var fileMetadataRequired = []string{"file.size", "file.modTime"}
+
+// fileMetadataError - returned for metadata not matching the schema.
+type fileMetadataError struct {
+	Key    string
+	Reason string
+}
+
+func (e fileMetadataError) Error() string {
+	return fmt.Sprintf("Invalid file metadata key %s, %s", e.Key, e.Reason)
+}
+
+// Validate - returns fileMetadataError if a known key has other than a
+// single value, or a value per disk, of its type, or a key required by the format of
+// metadata is missing.
+func (f fileMetadata) Validate() error {
+	format, err := f.GetFormat()
+	if err != nil {
+		return fileMetadataError{Key: "format", Reason: err.Error()}
+	}
+	for key, values := range f {
+		field, ok := fileMetadataSchema[key]
+		if !ok {
+			continue
+		}
+		if len(values) != 1 && !(field.PerDisk && len(values) > 0) {
+			return fileMetadataError{Key: key, Reason: fmt.Sprintf("expected a single value, got %d", len(values))}
+		}
+		for _, value := range values {
+			if err = field.validate(value); err != nil {
+				return fileMetadataError{Key: key, Reason: err.Error()}
+			}
+		}
+	}
+	for _, key := range fileMetadataRequired {
+		if f.Get(key) == nil {
+			return fileMetadataError{Key: key, Reason: "missing"}
+		}
+	}
+	for key, field := range fileMetadataSchema {
+		if field.Since != xlFormatLegacy && !format.before(field.Since) && f.Get(key) == nil {
+			return fileMetadataError{Key: key, Reason: "missing from format " + format.String()}
+		}
+	}
+	return nil
+}
+
+// validate - returns error if value is not of the type of field.
+func (field fileMetadataField) validate(value string) error {
+	var err error
+	switch field.Type {
+	case fileMetadataInt:
+		var n int64
+		if n, err = strconv.ParseInt(value, 10, 64); err == nil && n < field.Min {
+			err = fmt.Errorf("value %d is lower than %d", n, field.Min)
+		}
+	case fileMetadataTime:
+		_, err = time.Parse(timeFormatAMZ, value)
+	case fileMetadataHex:
+		_, err = hex.DecodeString(value)
+	case fileMetadataBase64:
+		_, err = base64.StdEncoding.DecodeString(value)
+	}
+	return err
+}
+
+// before - returns true if format f is older than format.
+func (f xlFormat) before(format xlFormat) bool {
+	if f.Major != format.Major {
+		return f.Major < format.Major
+	}
+	if f.Minor != format.Minor {
+		return f.Minor < format.Minor
+	}
+	return f.Patch < format.Patch
+}
@@ -0,0 +1,114 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testFileMetadata - returns valid metadata of the current format.
+func testFileMetadata() fileMetadata {
+	metadata := make(fileMetadata)
+	metadata.SetFormat(xlFormatCurrent)
+	metadata.SetSize(10)
+	metadata.SetModTime(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	metadata.SetFileVersion(1)
+	metadata.Set("file.xl.blockSize", "4194304")
+	return metadata
+}
+
+// Tests metadata is validated against its schema.
+func TestFileMetadataValidate(t *testing.T) {
+	testCases := []struct {
+		key    string
+		values []string
+		valid  bool
+	}{
+		{"file.xl.dataBlocks", []string{"2"}, true},
+		{"file.xl.dataBlocks", []string{"0"}, false},
+		{"file.size", []string{"-1"}, false},
+		{"file.size", []string{"1", "2"}, false},
+		{"file.size", nil, false},
+		{"file.modTime", []string{"yesterday"}, false},
+		{"file.version", nil, false},
+		{"file.xl.block512Sum", []string{"zz"}, false},
+		{"file.xl.block512Sum", []string{"ab", "cd"}, false},
+		{"file.xl.parts512Sum", []string{"ab", "", "cd"}, true},
+		{"file.xl.parts512Sum", []string{"ab", "zz"}, false},
+		{"file.xl.inline", []string{"aGVsbG8="}, true},
+		{"file.xl.inline", []string{"aGVsbG8"}, false},
+		{"format.minor", []string{"one"}, false},
+		// Keys of newer formats are not validated.
+		{"file.xl.unknown", []string{"1", "2"}, true},
+	}
+	for i, testCase := range testCases {
+		metadata := testFileMetadata()
+		if testCase.values == nil {
+			delete(metadata, testCase.key)
+		} else {
+			metadata[testCase.key] = testCase.values
+		}
+		if e := metadata.Validate(); (e == nil) != testCase.valid {
+			t.Errorf("Test %d: %s %v expected valid %v, got %v", i+1, testCase.key, testCase.values, testCase.valid, e)
+		}
+	}
+
+	// Keys are required from the format they were added in.
+	legacy := fileMetadata{"file.size": {"10"}, "file.modTime": {"2016-01-01T00:00:00.000Z"}}
+	if e := legacy.Validate(); e != nil {
+		t.Fatal(e)
+	}
+	legacy.SetFormat(xlFormatV1)
+	if e := legacy.Validate(); e == nil {
+		t.Fatal("Expected metadata without block size to be invalid")
+	}
+}
+
+// Tests metadata round trips through its codec, keeping unknown keys,
+// and metadata failing its schema is neither written nor read.
+func TestFileMetadataCodec(t *testing.T) {
+	metadata := testFileMetadata()
+	metadata.Set("file.xl.unknown", "kept")
+	metadataBytes, e := encodeFileMetadata(metadata)
+	if e != nil {
+		t.Fatal(e)
+	}
+	decoded, e := decodeFileMetadata(metadataBytes)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if unknown := decoded.Get("file.xl.unknown"); unknown == nil || unknown[0] != "kept" {
+		t.Fatalf("Expected unknown key kept, got %v", unknown)
+	}
+
+	// Half written metadata is not read.
+	if _, e = decodeFileMetadata(metadataBytes[:len(metadataBytes)/2]); e == nil {
+		t.Fatal("Expected truncated metadata to fail")
+	}
+	if _, e = decodeFileMetadata([]byte("garbage")); e != errUnknownMetadataCodec {
+		t.Fatalf("Expected %v, got %v", errUnknownMetadataCodec, e)
+	}
+	if _, e = decodeFileMetadata([]byte(`{"file.size":["10"]}`)); e == nil {
+		t.Fatal("Expected metadata without modTime to fail")
+	}
+
+	metadata.Add("file.size", "20")
+	if _, e = encodeFileMetadata(metadata); e == nil {
+		t.Fatal("Expected metadata with two sizes not to be written")
+	}
+}
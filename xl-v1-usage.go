@@ -0,0 +1,35 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "encoding/base64"
+
+// usedSize - returns space taken on all disks by a file of size in
+// volume. Erasure coded files take a part on each disk, inlined files
+// are replicated base64 encoded in the metadata of each disk. Metadata
+// of files is not counted.
+func (xl XL) usedSize(volume string, size int64) (int64, error) {
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return 0, err
+	}
+	totalBlocks := int64(xl.DataBlocks + xl.ParityBlocks)
+	if size <= xlInlineMaxSize {
+		return int64(base64.StdEncoding.EncodedLen(int(size))) * totalBlocks, nil
+	}
+	return xl.partSize(size) * totalBlocks, nil
+}
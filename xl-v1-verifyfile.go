@@ -0,0 +1,189 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	slashpath "path"
+
+	"github.com/Sirupsen/logrus"
+	fastSha512 "github.com/minio/minio/pkg/crypto/sha512"
+)
+
+// Status of the part of a file on a disk.
+const (
+	// Part matches its checksum.
+	partStatusHealthy = "healthy"
+	// Part, or its inlined data, does not match its checksum.
+	partStatusCorrupt = "corrupt"
+	// Part or its metadata is not on the disk.
+	partStatusMissing = "missing"
+	// Part is of an older version of the file.
+	partStatusOutdated = "outdated"
+	// Part or its metadata cannot be read.
+	partStatusUnreadable = "unreadable"
+)
+
+// partVerification - status of the part of a file on a disk.
+type partVerification struct {
+	Disk   string `json:"disk"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// fileVerification - status of the parts of a file on all disks, the
+// file is repaired if requested and not healthy.
+type fileVerification struct {
+	Size        int64              `json:"size"`
+	Inline      bool               `json:"inline"`
+	Healthy     bool               `json:"healthy"`
+	Parts       []partVerification `json:"parts"`
+	Repaired    bool               `json:"repaired"`
+	RepairError string             `json:"repairError,omitempty"`
+}
+
+// verifyFile - reads the parts of the file at path on all disks and
+// verifies them against their checksums. Parts which are not healthy
+// are rebuilt from the others if repair is set.
+func (xl XL) verifyFile(volume, path string, repair bool) (fileVerification, error) {
+	// Input validation.
+	if !isValidVolname(volume) {
+		return fileVerification{}, errInvalidArgument
+	}
+	if !isValidPath(path) {
+		return fileVerification{}, errInvalidArgument
+	}
+
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return fileVerification{}, err
+	}
+
+	// Acquire a read lock.
+	readLock := true
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return fileVerification{}, err
+	}
+	verification, err := xl.verifyParts(volume, path)
+	xl.unlockNS(holder)
+	if err != nil || verification.Healthy || !repair {
+		return verification, err
+	}
+
+	corrupt := make([]bool, len(verification.Parts))
+	for index, part := range verification.Parts {
+		corrupt[index] = part.Status != partStatusHealthy
+	}
+	if err = xl.repairFile(volume, path, corrupt); err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("Repair failed with %s", err)
+		verification.RepairError = err.Error()
+		return verification, nil
+	}
+	verification.Repaired = true
+	return verification, nil
+}
+
+// verifyParts - returns status of the parts of the file at path. Read
+// lockNS() should be done by caller.
+func (xl XL) verifyParts(volume, path string) (fileVerification, error) {
+	onlineDisks, metadata, _, err := xl.listOnlineDisks(volume, path)
+	if err != nil {
+		return fileVerification{}, err
+	}
+	size, err := metadata.GetSize()
+	if err != nil {
+		return fileVerification{}, err
+	}
+	verification := fileVerification{
+		Size:    size,
+		Inline:  metadata.IsInline(),
+		Healthy: true,
+		Parts:   make([]partVerification, len(xl.storageDisks)),
+	}
+
+	// Each disk holds the checksum of its own part.
+	partsMetadata, errs := xl.getPartsMetadata(volume, path)
+	for index := range xl.storageDisks {
+		part := &verification.Parts[index]
+		part.Disk = xl.diskPaths[index]
+		switch {
+		case errs[index] != nil:
+			part.Status, part.Error = partStatus(errs[index])
+		case onlineDisks[index] == nil:
+			part.Status = partStatusOutdated
+		case verification.Inline:
+			if _, _, err = partsMetadata[index].GetInlineData(); err != nil {
+				part.Status, part.Error = partStatusCorrupt, err.Error()
+			} else {
+				part.Status = partStatusHealthy
+			}
+		default:
+			part.Status, part.Error = xl.verifyPart(volume, path, index, partsMetadata[index])
+		}
+		if part.Status != partStatusHealthy {
+			verification.Healthy = false
+		}
+	}
+	return verification, nil
+}
+
+// verifyPart - reads the erasure coded part of the file at path on disk
+// of index and compares it against its checksum.
+func (xl XL) verifyPart(volume, path string, index int, metadata fileMetadata) (status, errMsg string) {
+	erasurePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
+	reader, err := xl.storageDisks[index].ReadFile(context.Background(), volume, erasurePart, 0)
+	if err != nil {
+		return partStatus(newDiskErr("ReadFile", index, volume, erasurePart, err))
+	}
+	defer reader.Close()
+	// The checksum covers data of the part only, not its trailer.
+	size, err := metadata.GetSize()
+	if err != nil {
+		return partStatusCorrupt, err.Error()
+	}
+	fileXL, err := xl.forFile(metadata)
+	if err != nil {
+		return partStatusCorrupt, err.Error()
+	}
+	hasher := fastSha512.New()
+	if _, err = io.Copy(hasher, io.LimitReader(reader, fileXL.partSize(size))); err != nil {
+		return partStatus(newDiskErr("ReadFile", index, volume, erasurePart, err))
+	}
+	partSum := metadata.GetPartSum(index)
+	if partSum == "" {
+		return partStatusCorrupt, "Part has no checksum"
+	}
+	if sum := hex.EncodeToString(hasher.Sum(nil)); sum != partSum {
+		return partStatusCorrupt, fmt.Sprintf("Part checksum %s does not match %s", sum, partSum)
+	}
+	return partStatusHealthy, ""
+}
+
+// partStatus - returns status of a part failing to be read with err.
+func partStatus(err error) (status, errMsg string) {
+	if errorCause(err) == errFileNotFound {
+		return partStatusMissing, ""
+	}
+	return partStatusUnreadable, err.Error()
+}
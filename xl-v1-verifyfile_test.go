@@ -0,0 +1,174 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests parts of files are verified against their checksums, and
+// repaired.
+func TestXLVerifyFile(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	data := make([]byte, erasureBlockSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "object")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write(data); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+	verify := func(repair bool, statuses ...string) fileVerification {
+		verification, e := xl.verifyFile("bucket", "object", repair)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for index, part := range verification.Parts {
+			if part.Disk != disks[index] || part.Status != statuses[index] {
+				t.Fatalf("Unexpected part %d %+v", index, part)
+			}
+		}
+		return verification
+	}
+
+	verification := verify(false, partStatusHealthy, partStatusHealthy, partStatusHealthy, partStatusHealthy)
+	if !verification.Healthy || verification.Inline || verification.Size != int64(len(data)) {
+		t.Fatalf("Unexpected verification %+v", verification)
+	}
+
+	// Corrupt and missing parts are reported, and not repaired
+	// unless requested.
+	part := filepath.Join(disks[1], "bucket", "object", "part.1")
+	partData, e := ioutil.ReadFile(part)
+	if e != nil {
+		t.Fatal(e)
+	}
+	partData[100] ^= 0xff
+	if e = ioutil.WriteFile(part, partData, 0600); e != nil {
+		t.Fatal(e)
+	}
+	if e = os.Remove(filepath.Join(disks[2], "bucket", "object", "part.2")); e != nil {
+		t.Fatal(e)
+	}
+	verification = verify(false, partStatusHealthy, partStatusCorrupt, partStatusMissing, partStatusHealthy)
+	if verification.Healthy || verification.Repaired || verification.Parts[1].Error == "" {
+		t.Fatalf("Unexpected verification %+v", verification)
+	}
+	verification = verify(true, partStatusHealthy, partStatusCorrupt, partStatusMissing, partStatusHealthy)
+	if !verification.Repaired || verification.RepairError != "" {
+		t.Fatalf("Unexpected verification %+v", verification)
+	}
+
+	// Repaired parts match the checksums of their metadata.
+	verify(false, partStatusHealthy, partStatusHealthy, partStatusHealthy, partStatusHealthy)
+	for _, disk := range disks[:2] {
+		os.Remove(filepath.Join(disk, "bucket", "object", "part.0"))
+		os.Remove(filepath.Join(disk, "bucket", "object", "part.1"))
+	}
+	r, e := xl.ReadFile(context.Background(), "bucket", "object", 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+	if readData, e := ioutil.ReadAll(r); e != nil || !bytes.Equal(readData, data) {
+		t.Fatalf("Unexpected data read, %v", e)
+	}
+
+	if _, e = xl.verifyFile("bucket", "missing", false); e != errFileNotFound {
+		t.Fatalf("Expected %v, got %v", errFileNotFound, e)
+	}
+}
+
+// Tests inlined data not matching its checksum is reported corrupt and
+// repaired from the metadata of other disks.
+func TestXLVerifyInlineFile(t *testing.T) {
+	var disks []string
+	for i := 0; i < 4; i++ {
+		disk, e := ioutil.TempDir("", "minio-xl-")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(disk)
+		disks = append(disks, disk)
+	}
+	storage, e := newXL(disks...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	xl := storage.(*XL)
+	if e = xl.MakeVol("bucket"); e != nil {
+		t.Fatal(e)
+	}
+	w, e := xl.CreateFile(context.Background(), "bucket", "small")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e = w.Write([]byte("hello, world")); e != nil {
+		t.Fatal(e)
+	}
+	if e = w.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	// Metadata of the last disk is the one read by default.
+	metadataPath := filepath.Join(disks[3], "bucket", "small", metadataFile)
+	metadataBytes, e := ioutil.ReadFile(metadataPath)
+	if e != nil {
+		t.Fatal(e)
+	}
+	metadataBytes = bytes.Replace(metadataBytes, []byte("aGVsbG8sIHdvcmxk"), []byte("aGVsbG8sIHdvcmxe"), 1)
+	if e = ioutil.WriteFile(metadataPath, metadataBytes, 0600); e != nil {
+		t.Fatal(e)
+	}
+	verification, e := xl.verifyFile("bucket", "small", true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !verification.Inline || verification.Parts[3].Status != partStatusCorrupt || !verification.Repaired {
+		t.Fatalf("Unexpected verification %+v", verification)
+	}
+	if verification, e = xl.verifyFile("bucket", "small", false); e != nil || !verification.Healthy {
+		t.Fatalf("Unexpected verification %+v, %v", verification, e)
+	}
+}
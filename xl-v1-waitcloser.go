@@ -26,8 +26,16 @@ import (
 // that all data is written and committed to disk on the end.
 // Additionally this also implements Write().
 type waitCloser struct {
-	wg     *sync.WaitGroup // Waitgroup for atomicity.
-	writer io.WriteCloser  // Embedded writer.
+	wg       *sync.WaitGroup   // Waitgroup for atomicity.
+	writer   io.WriteCloser    // Embedded writer.
+	err      error             // Error released with.
+	metadata map[string]string // Metadata committed along.
+}
+
+// SetMetadata - sets metadata of the object layer committed along
+// with the file, the reading end sees it once the writer is closed.
+func (b *waitCloser) SetMetadata(metadata map[string]string) {
+	b.metadata = metadata
 }
 
 // Write to the underlying writer.
@@ -40,12 +48,28 @@ func (b *waitCloser) Write(data []byte) (int, error) {
 func (b *waitCloser) Close() error {
 	err := b.writer.Close()
 	b.wg.Wait()
-	return err
+	if err != nil {
+		return err
+	}
+	return b.err
+}
+
+// CloseWithError aborts the write, the reading end sees err. Blocks
+// until released, so that the aborted write is cleaned up on return.
+func (b *waitCloser) CloseWithError(err error) error {
+	if pipeWriter, ok := b.writer.(*io.PipeWriter); ok {
+		pipeWriter.CloseWithError(err)
+	} else {
+		b.writer.Close()
+	}
+	b.wg.Wait()
+	return nil
 }
 
-// release the Close, causing it to unblock. Only call this
-// once. Calling it multiple times results in a panic.
-func (b *waitCloser) release() {
+// release the Close with err, causing it to unblock. Only call
+// this once. Calling it multiple times results in a panic.
+func (b *waitCloser) release(err error) {
+	b.err = err
 	b.wg.Done()
 	return
 }
@@ -17,20 +17,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	slashpath "path"
 	"sort"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/klauspost/reedsolomon"
 )
 
 const (
-	// Part metadata file.
-	metadataFile = "part.json"
+	// Metadata file of a file, describing all its parts.
+	metadataFile = "xl.json"
 	// Maximum erasure blocks.
 	maxErasureBlocks = 16
 )
@@ -41,61 +42,232 @@ type XL struct {
 	DataBlocks            int
 	ParityBlocks          int
 	storageDisks          []StorageAPI
+	diskPaths             []string
 	nameSpaceLockMap      map[nameSpaceParam]*nameSpaceLock
 	nameSpaceLockMapMutex *sync.Mutex
 	readQuorum            int
 	writeQuorum           int
+	// Volumes pinned to a subset of disks, nil for the XL of a
+	// pinned volume.
+	placements *volumePlacements
+	// Counts of inline and erasure coded file reads.
+	readCounters *xlReadCounters
+	// Decoded blocks of hot files.
+	blockCache *xlBlockCache
+	// Buffers of blocks being erasure coded.
+	buffers *erasureBuffers
+	// Committed files are read back and verified.
+	verifyWrites bool
+	// Erasure codings of files with a parity of their own.
+	codings *xlErasureCodings
 }
 
 // lockNS - locks the given resource, using a previously allocated
-// name space lock or initializing a new one.
-func (xl XL) lockNS(volume, path string, readLock bool) {
+// name space lock or initializing a new one. Returns errLockTimeout
+// once the configured acquire timeout passed, the holder returned is
+// passed to unlockNS.
+func (xl XL) lockNS(volume, path string, readLock bool) (*nameSpaceHolder, error) {
+	holder := &nameSpaceHolder{
+		param:    nameSpaceParam{volume, path},
+		readLock: readLock,
+		owner:    nameSpaceLockOwner(),
+	}
+	var timeout <-chan time.Time
+	if d := globalNameSpaceLocks.Get().acquireTimeout(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
 	xl.nameSpaceLockMapMutex.Lock()
 	defer xl.nameSpaceLockMapMutex.Unlock()
-
-	param := nameSpaceParam{volume, path}
-	nsLock, found := xl.nameSpaceLockMap[param]
+	nsLock, found := xl.nameSpaceLockMap[holder.param]
 	if !found {
 		nsLock = newNSLock()
+		xl.nameSpaceLockMap[holder.param] = nsLock
 	}
-
-	if readLock {
-		nsLock.RLock()
-	} else {
-		nsLock.Lock()
+	nsLock.count++
+	if !readLock {
+		nsLock.writersWaiting++
 	}
-
-	xl.nameSpaceLockMap[param] = nsLock
+	// Wait for the lock without holding the map mutex, so that the
+	// current holder can unlock meanwhile.
+	for !nsLock.available(readLock) {
+		released := nsLock.released
+		xl.nameSpaceLockMapMutex.Unlock()
+		select {
+		case <-released:
+			xl.nameSpaceLockMapMutex.Lock()
+		case <-timeout:
+			xl.nameSpaceLockMapMutex.Lock()
+			nsLock.count--
+			if !readLock {
+				// Readers waiting behind the writer may go ahead.
+				nsLock.writersWaiting--
+				close(nsLock.released)
+				nsLock.released = make(chan struct{})
+			}
+			if !nsLock.InUse() {
+				delete(xl.nameSpaceLockMap, holder.param)
+			}
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+				"owner":  holder.owner,
+			}).Errorf("%s", errLockTimeout)
+			return nil, errLockTimeout
+		}
+	}
+	if !readLock {
+		nsLock.writersWaiting--
+	}
+	holder.since = time.Now().UTC()
+	nsLock.take(holder)
+	return holder, nil
 }
 
-// unlockNS - unlocks any previously acquired read or write locks.
-func (xl XL) unlockNS(volume, path string, readLock bool) {
+// unlockNS - unlocks the lock of holder, locks no longer in use are
+// released. Locks already released on behalf of holder are left as
+// they are.
+func (xl XL) unlockNS(holder *nameSpaceHolder) {
+	if holder == nil {
+		return
+	}
 	xl.nameSpaceLockMapMutex.Lock()
 	defer xl.nameSpaceLockMapMutex.Unlock()
-
-	param := nameSpaceParam{volume, path}
-	if nsLock, found := xl.nameSpaceLockMap[param]; found {
-		if readLock {
-			nsLock.RUnlock()
-		} else {
-			nsLock.Unlock()
+	if holder.released {
+		return
+	}
+	if nsLock, found := xl.nameSpaceLockMap[holder.param]; found {
+		nsLock.release(holder)
+		if !nsLock.InUse() {
+			delete(xl.nameSpaceLockMap, holder.param)
 		}
+	}
+}
 
-		if nsLock.InUse() {
-			xl.nameSpaceLockMap[param] = nsLock
+// checkLocks - logs locks held longer than threshold once, and
+// releases them on behalf of their holders if forceRelease is set.
+// Returns number of locks released.
+func (xl XL) checkLocks(threshold time.Duration, forceRelease bool) int {
+	now := time.Now().UTC()
+	released := 0
+	xl.nameSpaceLockMapMutex.Lock()
+	defer xl.nameSpaceLockMapMutex.Unlock()
+	for param, nsLock := range xl.nameSpaceLockMap {
+		var expired []*nameSpaceHolder
+		for _, holder := range nsLock.holders {
+			if now.Sub(holder.since) >= threshold {
+				expired = append(expired, holder)
+			}
+		}
+		for _, holder := range expired {
+			fields := logrus.Fields{
+				"volume": param.volume,
+				"path":   param.path,
+				"owner":  holder.owner,
+				"since":  holder.since,
+				"read":   holder.readLock,
+			}
+			if forceRelease {
+				log.WithFields(fields).Errorf("Namespace lock held for %s, releasing it", now.Sub(holder.since))
+				nsLock.release(holder)
+				released++
+				continue
+			}
+			if !holder.reported {
+				log.WithFields(fields).Errorf("Namespace lock held for %s", now.Sub(holder.since))
+				holder.reported = true
+			}
+		}
+		if !nsLock.InUse() {
+			delete(xl.nameSpaceLockMap, param)
 		}
 	}
+	return released
 }
 
 // newXL instantiate a new XL.
 func newXL(disks ...string) (StorageAPI, error) {
+	// Initialize all storage disks.
+	storageDisks := make([]StorageAPI, len(disks))
+	for index, disk := range disks {
+		fsDisk, err := newFS(disk)
+		if err != nil {
+			return nil, err
+		}
+		// Operations are queued per bucket once the disk is busy.
+		storageDisks[index] = newScheduledDisk(fsDisk)
+	}
+	xl, err := newXLDisks(disks, storageDisks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disk errors are injected once disks are verified, in chaos mode.
+	chaos, err := xlChaosProbability(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	if chaos > 0 {
+		faults := newStorageFaults()
+		faults.Set(storageOpAny, storageFault{Err: errChaos, Probability: chaos})
+		for index, disk := range xl.storageDisks {
+			scheduled := disk.(scheduledDisk)
+			scheduled.StorageAPI = newFaultyDisk(scheduled.StorageAPI, faults)
+			xl.storageDisks[index] = scheduled
+		}
+	}
+	xl.verifyWrites = xlVerifyWrites(os.LookupEnv)
+	return xl, nil
+}
+
+// newXLDisks - instantiate a new XL on storageDisks, identified by
+// diskPaths.
+func newXLDisks(diskPaths []string, storageDisks []StorageAPI) (*XL, error) {
 	// Initialize XL.
 	xl := &XL{}
 
+	// Initialize erasure coding and quorum for all disks.
+	if err := xl.initErasure(len(storageDisks)); err != nil {
+		return nil, err
+	}
+
+	// Save all the initialized storage disks.
+	xl.storageDisks = storageDisks
+	xl.diskPaths = diskPaths
+
+	// Verify disks are in their order of first start.
+	if err := xl.loadDiskFormats(); err != nil {
+		return nil, err
+	}
+
+	// Initialize name space lock map.
+	xl.nameSpaceLockMap = make(map[nameSpaceParam]*nameSpaceLock)
+	xl.nameSpaceLockMapMutex = &sync.Mutex{}
+
+	// Initialize volume placements.
+	xl.placements = newVolumePlacements()
+
+	// Initialize read counters.
+	xl.readCounters = &xlReadCounters{}
+
+	// Initialize block cache, disabled until configured.
+	xl.blockCache = newXLBlockCache()
+
+	// Initialize erasure codings of other parities.
+	xl.codings = newXLErasureCodings()
+
+	// Return successfully initialized.
+	return xl, nil
+}
+
+// initErasure - initializes erasure coding and read and write quorum
+// for totalDisks disks.
+func (xl *XL) initErasure(totalDisks int) error {
 	// Verify disks.
-	totalDisks := len(disks)
 	if totalDisks > maxErasureBlocks {
-		return nil, errMaxDisks
+		return errMaxDisks
 	}
 
 	// isEven function to verify if a given number if even.
@@ -104,8 +276,8 @@ func newXL(disks ...string) (StorageAPI, error) {
 	}
 
 	// TODO: verify if this makes sense in future.
-	if !isEven(totalDisks) {
-		return nil, errNumDisks
+	if totalDisks == 0 || !isEven(totalDisks) {
+		return errNumDisks
 	}
 
 	// Calculate data and parity blocks.
@@ -114,45 +286,59 @@ func newXL(disks ...string) (StorageAPI, error) {
 	// Initialize reed solomon encoding.
 	rs, err := reedsolomon.New(dataBlocks, parityBlocks)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Save the reedsolomon.
 	xl.DataBlocks = dataBlocks
 	xl.ParityBlocks = parityBlocks
 	xl.ReedSolomon = rs
-
-	// Initialize all storage disks.
-	storageDisks := make([]StorageAPI, len(disks))
-	for index, disk := range disks {
-		var err error
-		storageDisks[index], err = newFS(disk)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Save all the initialized storage disks.
-	xl.storageDisks = storageDisks
-
-	// Initialize name space lock map.
-	xl.nameSpaceLockMap = make(map[nameSpaceParam]*nameSpaceLock)
-	xl.nameSpaceLockMapMutex = &sync.Mutex{}
+	xl.buffers = newErasureBuffers(dataBlocks, parityBlocks)
 
 	// Figure out read and write quorum based on number of storage disks.
 	// Read quorum should be always N/2 + 1 (due to Vandermonde matrix
 	// erasure requirements)
-	xl.readQuorum = len(xl.storageDisks)/2 + 1
+	xl.readQuorum = totalDisks/2 + 1
 
 	// Write quorum is assumed if we have total disks + 3
 	// parity. (Need to discuss this again)
-	xl.writeQuorum = len(xl.storageDisks)/2 + 3
-	if xl.writeQuorum > len(xl.storageDisks) {
-		xl.writeQuorum = len(xl.storageDisks)
+	xl.writeQuorum = totalDisks/2 + 3
+	if xl.writeQuorum > totalDisks {
+		xl.writeQuorum = totalDisks
 	}
+	return nil
+}
 
-	// Return successfully initialized.
-	return xl, nil
+// DiskStatus - returns status of all disks.
+func (xl XL) DiskStatus() []DiskStatus {
+	disksStatus := make([]DiskStatus, len(xl.diskPaths))
+	for index, diskPath := range xl.diskPaths {
+		disksStatus[index] = getDiskStatus(diskPath)
+	}
+	return disksStatus
+}
+
+// WriteQuorum - returns number of disks which need to be online to
+// write.
+func (xl XL) WriteQuorum() int {
+	return xl.writeQuorum
+}
+
+// DiskShares - returns operations in progress and share utilization
+// of each disk per bucket.
+func (xl XL) DiskShares() []diskSchedulerStatus {
+	statuses := []diskSchedulerStatus{}
+	for index, disk := range xl.storageDisks {
+		if scheduled, ok := disk.(scheduledDisk); ok {
+			statuses = append(statuses, scheduled.scheduler.status(xl.diskPaths[index]))
+		}
+	}
+	return statuses
+}
+
+// StripeSize - returns size of data erasure coded at a time.
+func (xl XL) StripeSize() int64 {
+	return erasureBlockSize
 }
 
 // MakeVol - make a volume.
@@ -160,6 +346,11 @@ func (xl XL) MakeVol(volume string) error {
 	if !isValidVolname(volume) {
 		return errInvalidArgument
 	}
+
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return err
+	}
 	// Collect if all disks report volume exists.
 	var volumeExistsMap = make(map[int]struct{})
 	// Make a volume entry on all underlying storage disks.
@@ -169,7 +360,7 @@ func (xl XL) MakeVol(volume string) error {
 				"volume": volume,
 			}).Errorf("MakeVol failed with %s", err)
 			// We ignore error if errVolumeExists and creating a volume again.
-			if err == errVolumeExists {
+			if errorCause(err) == errVolumeExists {
 				volumeExistsMap[index] = struct{}{}
 				continue
 			}
@@ -189,6 +380,20 @@ func (xl XL) DeleteVol(volume string) error {
 		return errInvalidArgument
 	}
 
+	// Pinned volumes are deleted from the disks they are pinned to,
+	// along with their placement.
+	pinnedXL, err := xl.volumeDisks(volume)
+	if err != nil {
+		return err
+	}
+	if xl.placements != nil && pinnedXL.placements == nil {
+		if err = pinnedXL.DeleteVol(volume); err != nil {
+			return err
+		}
+		xl.removePlacement(volume)
+		return nil
+	}
+
 	// Collect if all disks report volume not found.
 	var volumeNotFoundMap = make(map[int]struct{})
 
@@ -199,7 +404,7 @@ func (xl XL) DeleteVol(volume string) error {
 				"volume": volume,
 			}).Errorf("DeleteVol failed with %s", err)
 			// We ignore error if errVolumeNotFound.
-			if err == errVolumeNotFound {
+			if errorCause(err) == errVolumeNotFound {
 				volumeNotFoundMap[index] = struct{}{}
 				continue
 			}
@@ -210,6 +415,7 @@ func (xl XL) DeleteVol(volume string) error {
 	if len(volumeNotFoundMap) == len(xl.storageDisks) {
 		return errVolumeNotFound
 	}
+	xl.blockCache.invalidateVolume(volume)
 	return nil
 }
 
@@ -248,7 +454,7 @@ func (xl XL) ListVols() (volsInfo []VolInfo, err error) {
 			break
 		}
 	}
-	return volsInfo, nil
+	return xl.appendPinnedVols(volsInfo, successVolsMap), nil
 }
 
 // StatVol - get volume stat info.
@@ -256,6 +462,10 @@ func (xl XL) StatVol(volume string) (volInfo VolInfo, err error) {
 	if !isValidVolname(volume) {
 		return VolInfo{}, errInvalidArgument
 	}
+
+	if xl, err = xl.volumeDisks(volume); err != nil {
+		return VolInfo{}, err
+	}
 	var statVols []VolInfo
 	volumeNotFoundErrCnt := 0
 	for _, disk := range xl.storageDisks {
@@ -264,7 +474,7 @@ func (xl XL) StatVol(volume string) (volInfo VolInfo, err error) {
 			// Collect all the successful attempts to verify quorum
 			// subsequently.
 			statVols = append(statVols, volInfo)
-		} else if err == errVolumeNotFound {
+		} else if errorCause(err) == errVolumeNotFound {
 			// Count total amount of volume not found errors.
 			volumeNotFoundErrCnt++
 		} else if err != nil {
@@ -290,6 +500,44 @@ func (xl XL) StatVol(volume string) (volInfo VolInfo, err error) {
 	return volInfo, nil
 }
 
+// StatVFS - returns space of the disks holding volume. Each disk holds
+// one of DataBlocks data or parity blocks of a file, and a write needs
+// write quorum disks, files fit as long as their blocks fit on the
+// disks with the most free space making up the quorum.
+func (xl XL) StatVFS(volume string) (vfsInfo VFSInfo, err error) {
+	if !isValidVolname(volume) {
+		return VFSInfo{}, errInvalidArgument
+	}
+
+	if xl, err = xl.volumeDisks(volume); err != nil {
+		return VFSInfo{}, err
+	}
+	var free []int64
+	for index, disk := range xl.storageDisks {
+		var diskInfo VFSInfo
+		if diskInfo, err = disk.StatVFS(volume); err != nil {
+			log.WithFields(logrus.Fields{
+				"volume":    volume,
+				"diskIndex": index,
+			}).Debugf("StatVFS failed with %s", err)
+			continue
+		}
+		if diskInfo.Free < 0 {
+			// Disks without fixed capacity.
+			return VFSInfo{Total: -1, Free: -1}, nil
+		}
+		vfsInfo.Total += diskInfo.Total
+		free = append(free, diskInfo.Free)
+	}
+	if len(free) < xl.writeQuorum {
+		return VFSInfo{}, errWriteQuorum
+	}
+	sort.Slice(free, func(i, j int) bool { return free[i] > free[j] })
+	vfsInfo.Total = vfsInfo.Total / int64(len(free)) * int64(xl.DataBlocks)
+	vfsInfo.Free = free[xl.writeQuorum-1] * int64(xl.DataBlocks)
+	return vfsInfo, nil
+}
+
 // isLeafDirectory - check if a given path is leaf directory. i.e
 // there are no more directories inside it. Erasure code backend
 // format it means that the parent directory is the actual object name.
@@ -330,12 +578,10 @@ func (xl XL) isLeafDirectory(volume, leafPath string) (isLeaf bool) {
 
 // extractMetadata - extract file metadata.
 func (xl XL) extractMetadata(volume, path string) (fileMetadata, error) {
-	metadataFilePath := slashpath.Join(path, metadataFile)
 	// We are not going to read partial data from metadata file,
 	// read the whole file always.
 	offset := int64(0)
-	disk := xl.storageDisks[0]
-	metadataReader, err := disk.ReadFile(volume, metadataFilePath, offset)
+	metadataReader, metadataFilePath, err := xl.readMetadataFile(0, volume, path)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
@@ -348,6 +594,10 @@ func (xl XL) extractMetadata(volume, path string) (fileMetadata, error) {
 	defer metadataReader.Close()
 
 	metadata, err := fileMetadataDecode(metadataReader)
+	if err == nil {
+		// Metadata of older formats is read as the current one.
+		_, err = metadata.upgradeFormat()
+	}
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
@@ -392,6 +642,7 @@ func (xl XL) extractFileInfo(volume, path string) (FileInfo, error) {
 	fileInfo.Size = fileSize
 	fileInfo.Mode = os.FileMode(0644)
 	fileInfo.ModTime = fileModTime
+	fileInfo.Metadata = metadata.GetObjectMetadata()
 	return fileInfo, nil
 }
 
@@ -408,6 +659,10 @@ func (xl XL) ListFiles(volume, prefix, marker string, recursive bool, count int)
 		return nil, true, errInvalidArgument
 	}
 
+	if xl, err = xl.volumeDisks(volume); err != nil {
+		return nil, true, err
+	}
+
 	// TODO: Fix: If readQuorum is met, its assumed that disks are in consistent file list.
 	// exclude disks those are not in consistent file list and check count of remaining disks
 	// are met readQuorum.
@@ -457,6 +712,7 @@ func (xl XL) ListFiles(volume, prefix, marker string, recursive bool, count int)
 func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive bool, count int) (filesInfo []FileInfo, eof bool, err error) {
 	var fsFilesInfo []FileInfo
 	var markerPath = marker
+	var listedPath string
 	if marker != "" {
 		isLeaf := xl.isLeafDirectory(volume, retainSlash(marker))
 		if isLeaf {
@@ -479,8 +735,9 @@ func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive
 			return nil, true, err
 		}
 		for _, fsFileInfo := range fsFilesInfo {
-			// Skip metadata files.
-			if strings.HasSuffix(fsFileInfo.Name, metadataFile) {
+			// Files are listed by the first of their parts, holding
+			// their metadata, or by their metadata file.
+			if !fsFileInfo.Mode.IsDir() && !isFileEntry(fsFileInfo.Name) {
 				continue
 			}
 			var fileInfo FileInfo
@@ -492,6 +749,12 @@ func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive
 				// Extract the parent of leaf directory or file to get the
 				// actual name.
 				path := slashpath.Dir(fsFileInfo.Name)
+				// Files are listed once, not by each of their parts
+				// or metadata files.
+				if path == listedPath {
+					continue
+				}
+				listedPath = path
 				fileInfo, err = xl.extractFileInfo(volume, path)
 				if err != nil {
 					log.WithFields(logrus.Fields{
@@ -501,7 +764,7 @@ func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive
 					// For a leaf directory, if err is FileNotFound then
 					// perhaps has a missing metadata. Ignore it and let
 					// healing finish its job it will become available soon.
-					if err == errFileNotFound {
+					if errorCause(err) == errFileNotFound {
 						continue
 					}
 					// For any other errors return to the caller.
@@ -521,9 +784,9 @@ func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive
 			// markerPath for the next disk.ListFiles() iteration.
 			markerPath = fsFilesInfo[lenFsFilesInfo-1].Name
 		}
-		if count == 0 && recursive && !strings.HasSuffix(markerPath, metadataFile) {
-			// If last entry is not part.json then loop once more to check if we
-			// have reached eof.
+		if count == 0 && recursive && !isFileEntry(markerPath) {
+			// If last entry is not a part or xl.json then loop once more to
+			// check if we have reached eof.
 			fsFilesInfo, eof, err = disk.ListFiles(volume, prefix, markerPath, recursive, 1)
 			if err != nil {
 				log.WithFields(logrus.Fields{
@@ -536,17 +799,17 @@ func (xl XL) listFiles(disk StorageAPI, volume, prefix, marker string, recursive
 				return nil, true, err
 			}
 			if !eof {
-				// part.N and part.json are always in pairs and hence this
-				// entry has to be part.json. If not better to manually investigate
-				// and fix it.
-				// For the next ListFiles() call we can safely assume that the
-				// marker is "object/part.json"
-				if !strings.HasSuffix(fsFilesInfo[0].Name, metadataFile) {
+				// Entries of a file are its parts, holding its metadata,
+				// xl.json, or part.json of legacy files, and hence this
+				// entry has to be one of them. If not better to manually
+				// investigate and fix it. For the next ListFiles() call we
+				// can safely assume that the marker is "object/xl.json"
+				if !isFileEntry(fsFilesInfo[0].Name) {
 					log.WithFields(logrus.Fields{
 						"volume":          volume,
 						"prefix":          prefix,
 						"fsFileInfo.Name": fsFilesInfo[0].Name,
-					}).Errorf("ListFiles failed with %s, expected %s to be a part.json file.", err, fsFilesInfo[0].Name)
+					}).Errorf("ListFiles failed with %s, expected %s to be a metadata file.", err, fsFilesInfo[0].Name)
 					return nil, true, errUnexpected
 				}
 			}
@@ -571,11 +834,19 @@ func (xl XL) StatFile(volume, path string) (FileInfo, error) {
 		return FileInfo{}, errInvalidArgument
 	}
 
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
 	// Acquire read lock.
 	readLock := true
-	xl.lockNS(volume, path, readLock)
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return FileInfo{}, err
+	}
 	_, metadata, heal, err := xl.listOnlineDisks(volume, path)
-	xl.unlockNS(volume, path, readLock)
+	xl.unlockNS(holder)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"volume": volume,
@@ -617,42 +888,161 @@ func (xl XL) StatFile(volume, path string) (FileInfo, error) {
 
 	// Return file info.
 	return FileInfo{
-		Volume:  volume,
-		Name:    path,
-		Size:    size,
-		ModTime: modTime,
-		Mode:    os.FileMode(0644),
+		Volume:   volume,
+		Name:     path,
+		Size:     size,
+		ModTime:  modTime,
+		Mode:     os.FileMode(0644),
+		Metadata: metadata.GetObjectMetadata(),
 	}, nil
 }
 
-// DeleteFile - delete a file
-func (xl XL) DeleteFile(volume, path string) error {
+// SetFileMetadata - replaces metadata of the object layer kept with the
+// file at path. It is written as xl.json of the next version of the
+// file, disks missing it are outdated and healed along with the file.
+func (xl XL) SetFileMetadata(volume, path string, objectMetadata map[string]string) error {
 	if !isValidVolname(volume) {
 		return errInvalidArgument
 	}
 	if !isValidPath(path) {
 		return errInvalidArgument
 	}
-	// Loop through and delete each chunks.
-	for index, disk := range xl.storageDisks {
-		erasureFilePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
-		err := disk.DeleteFile(volume, erasureFilePart)
+
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return err
+	}
+
+	// Acquire a write lock, so that the file is not written meanwhile.
+	readLock := false
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return err
+	}
+	defer xl.unlockNS(holder)
+
+	onlineDisks, metadata, _, err := xl.listOnlineDisks(volume, path)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("listOnlineDisks failed with %s", err)
+		return err
+	}
+	if xl, err = xl.forFile(metadata); err != nil {
+		return err
+	}
+	version, err := metadata.GetFileVersion()
+	if err != nil {
+		return err
+	}
+	// xl.json is read before the trailer of parts, it holds the
+	// checksums of all of them.
+	if !metadata.IsInline() {
+		metadata.SetPartSums(xl.getPartSums(volume, path, metadata))
+	}
+	metadata.SetFileVersion(version + 1)
+	metadata.SetObjectMetadata(objectMetadata)
+
+	updateParts := make([]bool, len(xl.storageDisks))
+	for index, disk := range onlineDisks {
+		updateParts[index] = disk != nil
+	}
+	updated := 0
+	for index, err := range xl.setPartsMetadata(volume, path, metadata, updateParts) {
+		if !updateParts[index] {
+			continue
+		}
 		if err != nil {
 			log.WithFields(logrus.Fields{
-				"volume": volume,
-				"path":   path,
-			}).Errorf("DeleteFile failed with %s", err)
-			return err
+				"volume":    volume,
+				"path":      path,
+				"diskIndex": index,
+			}).Errorf("Writing metadata failed with %s", err)
+			continue
+		}
+		updated++
+	}
+	if updated < xl.writeQuorum {
+		return errWriteQuorum
+	}
+	return nil
+}
+
+// DeleteFile - delete a file, unless ctx is already done.
+func (xl XL) DeleteFile(ctx context.Context, volume, path string) error {
+	if !isValidVolname(volume) {
+		return errInvalidArgument
+	}
+	if !isValidPath(path) {
+		return errInvalidArgument
+	}
+
+	xl, err := xl.volumeDisks(volume)
+	if err != nil {
+		return err
+	}
+	// Shutdown waits for the delete to complete on all disks.
+	endCommit := globalInFlightCommits.begin()
+	defer endCommit()
+
+	// Hold write lock, so that readers never see a partially
+	// deleted file.
+	readLock := false
+	holder, err := xl.lockNS(volume, path, readLock)
+	if err != nil {
+		return err
+	}
+	defer xl.unlockNS(holder)
+	xl.blockCache.invalidate(volume, path)
+
+	// Once started deletion runs on all disks, ctx is not passed down
+	// so that a file is never left partially deleted.
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// Loop through and delete each chunks on all disks, failures on
+	// some disks are tolerated as long as write quorum is met.
+	var deleteErrCount, notFoundCount int
+	for index, disk := range xl.storageDisks {
+		erasureFilePart := slashpath.Join(path, fmt.Sprintf("part.%d", index))
+		err := disk.DeleteFile(context.Background(), volume, erasureFilePart)
+		// Always attempt to delete metadata, of both layouts, so that
+		// a left over metadata file is not treated as a valid object.
+		// Inlined files have no parts, only metadata.
+		for _, name := range []string{metadataFile, legacyMetadataFile} {
+			if err != nil && errorCause(err) != errFileNotFound {
+				break
+			}
+			mErr := disk.DeleteFile(context.Background(), volume, slashpath.Join(path, name))
+			if mErr == nil {
+				err = nil
+			} else if errorCause(mErr) != errFileNotFound {
+				err = mErr
+			}
+		}
+		if errorCause(err) == errFileNotFound {
+			notFoundCount++
+			continue
 		}
-		metadataFilePath := slashpath.Join(path, metadataFile)
-		err = disk.DeleteFile(volume, metadataFilePath)
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"volume": volume,
 				"path":   path,
+				"disk":   index,
 			}).Errorf("DeleteFile failed with %s", err)
-			return err
+			deleteErrCount++
 		}
 	}
+	// File was not found on any of the disks.
+	if notFoundCount == len(xl.storageDisks) {
+		return errFileNotFound
+	}
+	// Return failure if deletes failed on more disks than the
+	// write quorum allows.
+	if deleteErrCount > len(xl.storageDisks)-xl.writeQuorum {
+		return errWriteQuorum
+	}
 	return nil
 }